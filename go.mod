@@ -1,8 +1,22 @@
 module github.com/swinslow/peridot-db
 
-go 1.12
+go 1.17
 
 require (
 	github.com/DATA-DOG/go-sqlmock v1.3.3
-	github.com/lib/pq v1.2.0
+	github.com/jackc/pgconn v1.14.3
+	github.com/jackc/pgx/v4 v4.18.3
+	github.com/lib/pq v1.10.2
+	google.golang.org/protobuf v1.31.0
+)
+
+require (
+	github.com/jackc/chunkreader/v2 v2.0.1 // indirect
+	github.com/jackc/pgio v1.0.0 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgproto3/v2 v2.3.3 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
+	github.com/jackc/pgtype v1.14.0 // indirect
+	golang.org/x/crypto v0.20.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
@@ -4,14 +4,19 @@ package datastore
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 // Agent describes a separately-running service that is registered
 // with peridot for running Jobs.
 type Agent struct {
 	// ID is the unique ID for this agent.
-	ID uint32 `json:"id"`
+	ID AgentID `json:"id"`
 	// Name is this agent's short name. Must be unique among
 	// agents currenlty registered with peridot.
 	Name string `json:"name"`
@@ -40,11 +45,136 @@ type Agent struct {
 	// IsSpdxWriter indicates whether the Agent has the capability
 	// of generating and writing an SPDX document to disk.
 	IsSpdxWriter bool `json:"is_spdxwriter"`
+	// Health is the most recently recorded health of the agent.
+	// It is tracked separately from IsActive.
+	Health AgentHealth `json:"health"`
+	// Version starts at 1 and is incremented each time the Agent is
+	// updated. It is passed to UpdateAgentStatus to detect updates
+	// made since the Agent was read.
+	Version uint32 `json:"version,omitempty"`
+	// CreatedAt is when this agent was added. It is set by the
+	// database.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// UpdatedAt is when this agent was last changed. It is set by
+	// the database.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// MarshalJSON converts the Agent into a slice of bytes containing its
+// JSON encoding, leaving out created_at and updated_at if unset.
+func (a Agent) MarshalJSON() ([]byte, error) {
+	type agentAlias Agent
+	return json.Marshal(struct {
+		agentAlias
+		CreatedAt *time.Time `json:"created_at,omitempty"`
+		UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	}{agentAlias: agentAlias(a), CreatedAt: jsonTimePtr(a.CreatedAt), UpdatedAt: jsonTimePtr(a.UpdatedAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of an Agent into the Agent, leaving CreatedAt and UpdatedAt as the
+// zero time if they are missing or null.
+func (a *Agent) UnmarshalJSON(b []byte) error {
+	type agentAlias Agent
+	aux := struct {
+		*agentAlias
+		CreatedAt *time.Time `json:"created_at"`
+		UpdatedAt *time.Time `json:"updated_at"`
+	}{agentAlias: (*agentAlias)(a)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	a.CreatedAt = timeFromJSONPtr(aux.CreatedAt)
+	a.UpdatedAt = timeFromJSONPtr(aux.UpdatedAt)
+	return nil
+}
+
+// Validate checks that the Agent's fields are well-formed. It returns
+// nil if so, or a *ValidationError describing the first invalid field.
+func (a *Agent) Validate() error {
+	if err := requireNonEmpty("agent", "name", a.Name); err != nil {
+		return err
+	}
+	if err := validateAgentAddress(a.Address, a.Port); err != nil {
+		return err
+	}
+	if _, err := AgentHealthFromInt(int(a.Health)); err != nil {
+		return &ValidationError{Entity: "agent", Field: "health", Reason: err.Error()}
+	}
+	return nil
+}
+
+// Clone returns a copy of the Agent that can be modified without
+// affecting the original. It returns nil if a is nil.
+func (a *Agent) Clone() *Agent {
+	if a == nil {
+		return nil
+	}
+	c := *a
+	return &c
+}
+
+// Equal reports whether a and other describe the same Agent,
+// comparing times at microsecond precision. Two nil Agents are equal.
+func (a *Agent) Equal(other *Agent) bool {
+	if a == nil || other == nil {
+		return a == other
+	}
+	ac, oc := *a, *other
+	ac.CreatedAt, ac.UpdatedAt, oc.CreatedAt, oc.UpdatedAt = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	return ac == oc &&
+		equalTimes(a.CreatedAt, other.CreatedAt) &&
+		equalTimes(a.UpdatedAt, other.UpdatedAt)
+}
+
+// validateAgentAddress checks that port is a valid TCP port if
+// address is set, or is zero if it is not.
+func validateAgentAddress(address string, port int) error {
+	if address == "" {
+		if port != 0 {
+			return &ValidationError{Entity: "agent", Field: "port", Reason: "must be 0 when no address is set"}
+		}
+		return nil
+	}
+	if port < 1 || port > 65535 {
+		return &ValidationError{Entity: "agent", Field: "port", Reason: fmt.Sprintf("%d is not between 1 and 65535", port)}
+	}
+	return nil
+}
+
+const agentColumns = "id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter, health, version, created_at, updated_at"
+
+// scanAgent reads an Agent from a row selecting agentColumns. A NULL
+// address or port, as may be written by tools other than peridot, is
+// read as "" or 0.
+func scanAgent(rs rowScanner) (*Agent, error) {
+	a := &Agent{}
+	var address sql.NullString
+	var port sql.NullInt64
+	var ahInt int
+	err := rs.Scan(&a.ID, &a.Name, &a.IsActive, &address, &port, &a.IsCodeReader, &a.IsSpdxReader, &a.IsCodeWriter, &a.IsSpdxWriter, &ahInt, &a.Version, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// convert integer to AgentHealth
+	a.Health, err = AgentHealthFromInt(ahInt)
+	if err != nil {
+		return nil, err
+	}
+	a.CreatedAt = normalizeTime(a.CreatedAt)
+	a.UpdatedAt = normalizeTime(a.UpdatedAt)
+	a.Address = address.String
+	a.Port = int(port.Int64)
+	return a, nil
 }
 
 // GetAllAgents returns a slice of all agents in the database.
 func (db *DB) GetAllAgents() ([]*Agent, error) {
-	rows, err := db.sqldb.Query("SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter FROM peridot.agents ORDER BY id")
+	rows, err := db.sqldb.Query("SELECT " + agentColumns + " FROM peridot.agents ORDER BY id")
 	if err != nil {
 		return nil, err
 	}
@@ -52,8 +182,7 @@ func (db *DB) GetAllAgents() ([]*Agent, error) {
 
 	agents := []*Agent{}
 	for rows.Next() {
-		a := &Agent{}
-		err := rows.Scan(&a.ID, &a.Name, &a.IsActive, &a.Address, &a.Port, &a.IsCodeReader, &a.IsSpdxReader, &a.IsCodeWriter, &a.IsSpdxWriter)
+		a, err := scanAgent(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -66,138 +195,412 @@ func (db *DB) GetAllAgents() ([]*Agent, error) {
 	return agents, nil
 }
 
+// CountAllAgents returns the number of agents in the database.
+func (db *DB) CountAllAgents() (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.agents")
+}
+
+// AgentFilter describes the criteria used to select agents in
+// GetAgents. Zero values mean the corresponding
+// criterion is not applied.
+type AgentFilter struct {
+	// ActiveOnly limits results to agents where IsActive is true.
+	ActiveOnly bool
+	// CodeReaderOnly limits results to agents that are codereaders.
+	CodeReaderOnly bool
+	// SpdxReaderOnly limits results to agents that are spdxreaders.
+	SpdxReaderOnly bool
+	// CodeWriterOnly limits results to agents that are codewriters.
+	CodeWriterOnly bool
+	// SpdxWriterOnly limits results to agents that are spdxwriters.
+	SpdxWriterOnly bool
+	// NamePrefix limits results to agents whose name begins with
+	// this string.
+	NamePrefix string
+}
+
+// sqlWhere returns the WHERE clause selecting agents that match the
+// filter, or "" if it matches all agents, and the parameters for it.
+func (filter AgentFilter) sqlWhere() (string, []interface{}) {
+	conds := []string{}
+	args := []interface{}{}
+
+	if filter.ActiveOnly {
+		conds = append(conds, "is_active = true")
+	}
+	if filter.CodeReaderOnly {
+		conds = append(conds, "is_codereader = true")
+	}
+	if filter.SpdxReaderOnly {
+		conds = append(conds, "is_spdxreader = true")
+	}
+	if filter.CodeWriterOnly {
+		conds = append(conds, "is_codewriter = true")
+	}
+	if filter.SpdxWriterOnly {
+		conds = append(conds, "is_spdxwriter = true")
+	}
+	if filter.NamePrefix != "" {
+		args = append(args, escapeLikePattern(filter.NamePrefix)+"%")
+		conds = append(conds, fmt.Sprintf("name LIKE $%d", len(args)))
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// agentSortColumns lists the fields by which GetAgents can sort.
+var agentSortColumns = sortColumns{
+	"name":   "name",
+	"health": "health",
+}
+
+// GetAgents returns a slice of agents in the database matching the
+// given filter, sorted and paged as requested, along with a Page
+// describing the results. Agents can be sorted by id, name or
+// health.
+func (db *DB) GetAgents(filter AgentFilter, pr PageRequest) ([]*Agent, Page, error) {
+	where, args := filter.sqlWhere()
+	query := "SELECT " + agentColumns + " FROM peridot.agents" + where
+	clause, args, err := pr.sqlClause(agentSortColumns, args)
+	if err != nil {
+		return nil, Page{}, err
+	}
+	query += clause
+
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, Page{}, err
+	}
+	defer rows.Close()
+
+	agents := []*Agent{}
+	for rows.Next() {
+		a, err := scanAgent(rows)
+		if err != nil {
+			return nil, Page{}, err
+		}
+		agents = append(agents, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Page{}, err
+	}
+
+	page, n := pr.page(len(agents))
+	return agents[:n], page, nil
+}
+
+// CountAgents returns the number of agents in the database matching
+// the given filter.
+func (db *DB) CountAgents(filter AgentFilter) (int, error) {
+	where, args := filter.sqlWhere()
+	return db.count("SELECT COUNT(*) FROM peridot.agents"+where, args...)
+}
+
+// escapeLikePattern escapes the characters that have special
+// meaning in a SQL LIKE pattern, so that s is matched literally.
+func escapeLikePattern(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return r.Replace(s)
+}
+
 // GetAgentByID returns the Agent with the given ID, or nil
 // and an error if not found.
-func (db *DB) GetAgentByID(id uint32) (*Agent, error) {
-	var a Agent
-	err := db.sqldb.QueryRow("SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter FROM peridot.agents WHERE id = $1", id).
-		Scan(&a.ID, &a.Name, &a.IsActive, &a.Address, &a.Port, &a.IsCodeReader, &a.IsSpdxReader, &a.IsCodeWriter, &a.IsSpdxWriter)
+func (db *DB) GetAgentByID(id AgentID) (*Agent, error) {
+	a, err := scanAgent(db.sqldb.QueryRow("SELECT "+agentColumns+" FROM peridot.agents WHERE id = $1", id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no agent found with ID %v", id)
+		return nil, &NotFoundError{Entity: "agent", ID: fmt.Sprint(id)}
 	}
+	return a, err
+}
+
+// GetAgentsByIDs returns all of the agents in the database with the
+// given IDs, ordered by ID, in a single query. If any ID is not
+// present, it will be silently omitted (e.g., no error will be
+// returned); the caller should check to confirm the received agents
+// match those that were expected.
+func (db *DB) GetAgentsByIDs(ids []AgentID) ([]*Agent, error) {
+	rows, err := db.sqldb.Query("SELECT "+agentColumns+" FROM peridot.agents WHERE id = ANY ($1) ORDER BY id", pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &a, nil
+	agents := []*Agent{}
+	for rows.Next() {
+		a, err := scanAgent(rows)
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return agents, nil
 }
 
 // GetAgentByName returns the Agent with the given Name, or nil
 // and an error if not found.
 func (db *DB) GetAgentByName(name string) (*Agent, error) {
-	var a Agent
-	err := db.sqldb.QueryRow("SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter FROM peridot.agents WHERE name = $1", name).
-		Scan(&a.ID, &a.Name, &a.IsActive, &a.Address, &a.Port, &a.IsCodeReader, &a.IsSpdxReader, &a.IsCodeWriter, &a.IsSpdxWriter)
+	a, err := scanAgent(db.sqldb.QueryRow("SELECT "+agentColumns+" FROM peridot.agents WHERE name = $1", name))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no agent found with name %v", name)
-	}
-	if err != nil {
-		return nil, err
+		return nil, &NotFoundError{Entity: "agent", Key: "name", ID: name}
 	}
+	return a, err
+}
+
+// ExistsAgent reports whether an Agent with the given ID
+// exists, without retrieving it.
+func (db *DB) ExistsAgent(id AgentID) (bool, error) {
+	return db.exists("SELECT EXISTS (SELECT 1 FROM peridot.agents WHERE id = $1)", id)
+}
 
-	return &a, nil
+// ExistsAgentByName reports whether an Agent with the given
+// Name exists, without retrieving it.
+func (db *DB) ExistsAgentByName(name string) (bool, error) {
+	return db.exists("SELECT EXISTS (SELECT 1 FROM peridot.agents WHERE name = $1)", name)
 }
 
 // AddAgent adds a new Agent with the given data. It returns the new
 // agent's ID on success or an error if failing.
-func (db *DB) AddAgent(name string, isActive bool, address string, port int, isCodeReader bool, isSpdxReader bool, isCodeWriter bool, isSpdxWriter bool) (uint32, error) {
-	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("INSERT INTO peridot.agents(name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id")
-	if err != nil {
+func (db *DB) AddAgent(name string, isActive bool, address string, port int, isCodeReader bool, isSpdxReader bool, isCodeWriter bool, isSpdxWriter bool) (AgentID, error) {
+	a := &Agent{Name: name, IsActive: isActive, Address: address, Port: port, IsCodeReader: isCodeReader, IsSpdxReader: isSpdxReader, IsCodeWriter: isCodeWriter, IsSpdxWriter: isSpdxWriter}
+	if err := a.Validate(); err != nil {
 		return 0, err
 	}
 
-	var aID uint32
-	err = stmt.QueryRow(name, isActive, address, port, isCodeReader, isSpdxReader, isCodeWriter, isSpdxWriter).Scan(&aID)
+	tx, err := db.begin()
 	if err != nil {
 		return 0, err
 	}
-	return aID, nil
-}
 
-// UpdateAgentStatus updates an existing Agent with the given ID,
-// setting whether it is active and its address and port. It returns
-// nil on success or an error if failing.
-func (db *DB) UpdateAgentStatus(id uint32, isActive bool, address string, port int) error {
-	stmt, err := db.sqldb.Prepare("UPDATE peridot.agents SET is_active = $1, address = $2, port = $3 WHERE id = $4")
+	// FIXME consider whether to move out into one-time-prepared statement
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.agents(name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id")
 	if err != nil {
-		return err
+		tx.Rollback()
+		return 0, err
 	}
-	result, err := stmt.Exec(isActive, address, port, id)
 
-	// check error
+	err = stmt.QueryRow(name, isActive, address, port, isCodeReader, isSpdxReader, isCodeWriter, isSpdxWriter).Scan(&a.ID)
 	if err != nil {
-		return err
+		tx.Rollback()
+		return 0, translateConstraintError("agent", err)
 	}
 
-	// check that something was actually updated
-	rows, err := result.RowsAffected()
+	err = addOutboxEvent(tx, "agent", a.ID, AuditActionAdd, a)
 	if err != nil {
-		return err
+		tx.Rollback()
+		return 0, err
 	}
-	if rows == 0 {
-		return fmt.Errorf("no agent found with ID %v", id)
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
 	}
+	return a.ID, nil
+}
 
-	return nil
+// UpdateAgentStatus updates an existing Agent with the given ID,
+// setting whether it is active and its address and port. If version
+// is not 0, the update is only made if the agent's Version is still
+// version, and a *ConflictError is returned otherwise; a version of 0
+// updates the agent unconditionally. It returns nil on success or an
+// error if failing.
+func (db *DB) UpdateAgentStatus(id AgentID, version uint32, isActive bool, address string, port int) error {
+	if err := validateAgentAddress(address, port); err != nil {
+		return err
+	}
+
+	mismatch := func() error {
+		return db.versionMismatchError("agents", "agent", id, version)
+	}
+	return db.execWithOutboxEventOr(mismatch, "agent", id, AuditActionUpdate, map[string]interface{}{"id": id, "is_active": isActive, "address": address, "port": port},
+		"UPDATE peridot.agents SET is_active = $1, address = $2, port = $3, version = version + 1 WHERE id = $4 AND ($5 = 0 OR version = $5)", isActive, address, port, id, version)
 }
 
 // UpdateAgentAbilities updates an existing Agent with the given ID,
 // setting its abilities to read/write code/SPDX. It returns nil on
 // success or an error if failing.
-func (db *DB) UpdateAgentAbilities(id uint32, isCodeReader bool, isSpdxReader bool, isCodeWriter bool, isSpdxWriter bool) error {
-	stmt, err := db.sqldb.Prepare("UPDATE peridot.agents SET is_codereader = $1, is_spdxreader = $2, is_codewriter = $3, is_spdxwriter = $4 WHERE id = $5")
-	if err != nil {
-		return err
+func (db *DB) UpdateAgentAbilities(id AgentID, isCodeReader bool, isSpdxReader bool, isCodeWriter bool, isSpdxWriter bool) error {
+	payload := map[string]interface{}{"id": id, "is_codereader": isCodeReader, "is_spdxreader": isSpdxReader, "is_codewriter": isCodeWriter, "is_spdxwriter": isSpdxWriter}
+	return db.execWithOutboxEvent("agent", id, AuditActionUpdate, payload,
+		"UPDATE peridot.agents SET is_codereader = $1, is_spdxreader = $2, is_codewriter = $3, is_spdxwriter = $4, version = version + 1 WHERE id = $5", isCodeReader, isSpdxReader, isCodeWriter, isSpdxWriter, id)
+}
+
+// DeleteAgent deletes an existing Agent with the given ID.
+// Any jobs referencing the agent are deleted along with it; use
+// DeleteAgentWithPolicy to refuse or reassign instead. It returns
+// nil on success or an error if failing.
+func (db *DB) DeleteAgent(id AgentID) error {
+	// FIXME consider whether need to delete sub-elements first, or
+	// FIXME whether to set up sub-elements' schemas to delete on cascade
+
+	// FIXME consider whether to move out into one-time-prepared statement
+	return db.execWithOutboxEvent("agent", id, AuditActionDelete, map[string]interface{}{"id": id},
+		"DELETE FROM peridot.agents WHERE id = $1", id)
+}
+
+// AgentJobsPolicy defines how DeleteAgentWithPolicy handles jobs
+// that reference the agent being deleted.
+type AgentJobsPolicy int
+
+const (
+	// AgentJobsCascade means that jobs referencing the agent are
+	// deleted along with it. This is the behavior of DeleteAgent.
+	AgentJobsCascade AgentJobsPolicy = 0
+
+	// AgentJobsRefuse means that the agent is not deleted if any
+	// jobs reference it, and an *AgentInUseError is returned.
+	AgentJobsRefuse AgentJobsPolicy = 1
+
+	// AgentJobsReassign means that jobs referencing the agent are
+	// first reassigned to another agent, and then the agent is
+	// deleted.
+	AgentJobsReassign AgentJobsPolicy = 2
+)
+
+// AgentInUseError is returned when an agent cannot be deleted
+// because jobs still reference it.
+type AgentInUseError struct {
+	// AgentID is the ID of the agent that could not be deleted.
+	AgentID AgentID
+	// JobCount is the number of jobs referencing the agent.
+	JobCount int
+}
+
+func (e *AgentInUseError) Error() string {
+	return fmt.Sprintf("agent with ID %v is referenced by %d job(s)", e.AgentID, e.JobCount)
+}
+
+// DeleteAgentWithPolicy deletes an existing Agent with the given ID,
+// handling jobs that reference it according to the given policy. If
+// policy is AgentJobsReassign, those jobs are reassigned to the agent
+// with ID reassignToID; otherwise reassignToID is ignored. It returns
+// nil on success, an *AgentInUseError if refusing due to existing jobs,
+// a *NotFoundError if either agent does not exist, a *ValidationError
+// if the policy is unknown or the agent would be reassigned to itself,
+// or another error if failing.
+func (db *DB) DeleteAgentWithPolicy(id AgentID, policy AgentJobsPolicy, reassignToID AgentID) error {
+	switch policy {
+	case AgentJobsCascade:
+		return db.DeleteAgent(id)
+	case AgentJobsRefuse:
+		return db.deleteAgentIfUnused(id)
+	case AgentJobsReassign:
+		return db.deleteAgentAndReassignJobs(id, reassignToID)
+	}
+
+	return &ValidationError{Entity: "agent", Field: "jobs policy", Reason: fmt.Sprintf("unknown policy %d", policy)}
+}
+
+// deleteAgentIfUnused deletes the agent with the given ID only if
+// no jobs reference it.
+func (db *DB) deleteAgentIfUnused(id AgentID) error {
+	// nothing is deleted if the agent doesn't exist or if jobs
+	// reference it; find out which
+	inUse := func() error {
+		var jobCount int
+		err := db.sqldb.QueryRow("SELECT COUNT(*) FROM peridot.jobs WHERE agent_id = $1", id).Scan(&jobCount)
+		if err != nil {
+			return err
+		}
+		if jobCount > 0 {
+			return &AgentInUseError{AgentID: id, JobCount: jobCount}
+		}
+		return &NotFoundError{Entity: "agent", ID: fmt.Sprint(id)}
+	}
+	return db.execWithOutboxEventOr(inUse, "agent", id, AuditActionDelete, map[string]interface{}{"id": id},
+		"DELETE FROM peridot.agents WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM peridot.jobs WHERE agent_id = $1)", id)
+}
+
+// deleteAgentAndReassignJobs moves all jobs referencing the agent
+// with the given ID over to the agent with ID reassignToID, and then
+// deletes the original agent, all within a single transaction. The
+// agent with ID reassignToID must exist even if there are no jobs to
+// move.
+func (db *DB) deleteAgentAndReassignJobs(id AgentID, reassignToID AgentID) error {
+	if id == reassignToID {
+		return &ValidationError{Entity: "agent", Field: "reassign-to ID", Reason: fmt.Sprintf("cannot reassign jobs for agent with ID %v to itself", id)}
 	}
-	result, err := stmt.Exec(isCodeReader, isSpdxReader, isCodeWriter, isSpdxWriter, id)
 
-	// check error
+	tx, err := db.begin()
 	if err != nil {
 		return err
 	}
 
-	// check that something was actually updated
-	rows, err := result.RowsAffected()
+	// lock the target agent so that it cannot be deleted before
+	// the jobs are moved to it
+	var target AgentID
+	err = tx.QueryRow("SELECT id FROM peridot.agents WHERE id = $1 FOR KEY SHARE", reassignToID).Scan(&target)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return &NotFoundError{Entity: "agent", ID: fmt.Sprint(reassignToID)}
+	}
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
-	if rows == 0 {
-		return fmt.Errorf("no agent found with ID %v", id)
-	}
 
-	return nil
-}
-
-// DeleteAgent deletes an existing Agent with the given ID.
-// It returns nil on success or an error if failing.
-func (db *DB) DeleteAgent(id uint32) error {
-	var err error
-	var result sql.Result
-
-	// FIXME consider whether need to delete sub-elements first, or
-	// FIXME whether to set up sub-elements' schemas to delete on cascade
-
-	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("DELETE FROM peridot.agents WHERE id = $1")
+	jobIDs, err := reassignAgentJobs(tx, id, reassignToID)
 	if err != nil {
-		return err
+		tx.Rollback()
+		return translateConstraintError("job", err)
+	}
+	for _, jobID := range jobIDs {
+		err = addOutboxEvent(tx, "job", jobID, AuditActionUpdate, map[string]interface{}{"id": jobID, "agent_id": reassignToID})
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
-	result, err = stmt.Exec(id)
 
-	// check error
+	result, err := tx.Exec("DELETE FROM peridot.agents WHERE id = $1", id)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 
 	// check that something was actually deleted
 	rows, err := result.RowsAffected()
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 	if rows == 0 {
-		return fmt.Errorf("no agent found with ID %v", id)
+		tx.Rollback()
+		return &NotFoundError{Entity: "agent", ID: fmt.Sprint(id)}
 	}
 
-	return nil
+	err = addOutboxEvent(tx, "agent", id, AuditActionDelete, map[string]interface{}{"id": id})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// reassignAgentJobs moves all jobs referencing the agent with the
+// given ID over to the agent with ID reassignToID within tx, and
+// returns the IDs of the jobs that were moved.
+func reassignAgentJobs(tx sqlConn, id AgentID, reassignToID AgentID) ([]JobID, error) {
+	rows, err := tx.Query("UPDATE peridot.jobs SET agent_id = $1, version = version + 1 WHERE agent_id = $2 RETURNING id", reassignToID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobIDs := []JobID{}
+	for rows.Next() {
+		var jobID JobID
+		if err := rows.Scan(&jobID); err != nil {
+			return nil, err
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	return jobIDs, rows.Err()
 }
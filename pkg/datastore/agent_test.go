@@ -4,6 +4,7 @@ package datastore
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -18,12 +19,12 @@ func TestShouldGetAllAgents(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "name", "is_active", "address", "port", "is_codereader", "is_spdxreader", "is_codewriter", "is_spdxwriter"}).
-		AddRow(1, "retrieve_github", true, "localhost", 9001, false, false, true, false).
-		AddRow(2, "idsearcher", true, "localhost", 9002, true, false, false, true).
-		AddRow(3, "disabled", false, "", 0, false, false, false, false).
-		AddRow(4, "noticemaker", true, "localhost", 9030, false, true, true, false)
-	mock.ExpectQuery("SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter FROM peridot.agents ORDER BY id").WillReturnRows(sentRows)
+	sentRows := sqlmock.NewRows([]string{"id", "name", "is_active", "address", "port", "is_codereader", "is_spdxreader", "is_codewriter", "is_spdxwriter", "health", "version", "created_at", "updated_at"}).
+		AddRow(1, "retrieve_github", true, "localhost", 9001, false, false, true, false, AgentHealthOK, 1, testCreatedAt, testUpdatedAt).
+		AddRow(2, "idsearcher", true, "localhost", 9002, true, false, false, true, AgentHealthOK, 1, testCreatedAt, testUpdatedAt).
+		AddRow(3, "disabled", false, "", 0, false, false, false, false, AgentHealthUnreachable, 1, testCreatedAt, testUpdatedAt).
+		AddRow(4, "noticemaker", true, "localhost", 9030, false, true, true, false, AgentHealthDegraded, 1, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery("SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter, health, version, created_at, updated_at FROM peridot.agents ORDER BY id").WillReturnRows(sentRows)
 
 	// run the tested function
 	gotRows, err := db.GetAllAgents()
@@ -69,6 +70,89 @@ func TestShouldGetAllAgents(t *testing.T) {
 	if a3.IsSpdxWriter != false {
 		t.Errorf("expected %v, got %v", false, a3.IsSpdxWriter)
 	}
+	if a3.Health != AgentHealthDegraded {
+		t.Errorf("expected %v, got %v", AgentHealthDegraded, a3.Health)
+	}
+}
+
+func TestShouldGetAgentsWithFilter(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "name", "is_active", "address", "port", "is_codereader", "is_spdxreader", "is_codewriter", "is_spdxwriter", "health", "version", "created_at", "updated_at"}).
+		AddRow(2, "idsearcher", true, "localhost", 9002, true, false, false, true, AgentHealthOK, 1, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter, health, version, created_at, updated_at FROM peridot.agents WHERE is_active = true AND is_codereader = true AND name LIKE \$1 ORDER BY id LIMIT \$2 OFFSET \$3`).
+		WithArgs(`id\_%`, 11, 20).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	filter := AgentFilter{
+		ActiveOnly:     true,
+		CodeReaderOnly: true,
+		NamePrefix:     "id_",
+	}
+	gotRows, page, err := db.GetAgents(filter, PageRequest{Limit: 10, Offset: 20})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 1 {
+		t.Fatalf("expected len %d, got %d", 1, len(gotRows))
+	}
+	if page != (Page{Offset: 20, Count: 1, HasMore: false}) {
+		t.Errorf("expected %+v, got %+v", Page{Offset: 20, Count: 1, HasMore: false}, page)
+	}
+	a0 := gotRows[0]
+	if a0.ID != 2 {
+		t.Errorf("expected %v, got %v", 2, a0.ID)
+	}
+	if a0.Name != "idsearcher" {
+		t.Errorf("expected %v, got %v", "idsearcher", a0.Name)
+	}
+}
+
+func TestShouldGetAgentsWithEmptyFilter(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "name", "is_active", "address", "port", "is_codereader", "is_spdxreader", "is_codewriter", "is_spdxwriter", "health", "version", "created_at", "updated_at"}).
+		AddRow(1, "retrieve_github", true, "localhost", 9001, false, false, true, false, AgentHealthOK, 1, testCreatedAt, testUpdatedAt).
+		AddRow(3, "disabled", false, "", 0, false, false, false, false, AgentHealthOK, 1, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter, health, version, created_at, updated_at FROM peridot.agents ORDER BY id$`).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, _, err := db.GetAgents(AgentFilter{}, PageRequest{})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
 }
 
 func TestShouldGetAgentByID(t *testing.T) {
@@ -80,9 +164,9 @@ func TestShouldGetAgentByID(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "name", "is_active", "address", "port", "is_codereader", "is_spdxreader", "is_codewriter", "is_spdxwriter"}).
-		AddRow(2, "idsearcher", true, "localhost", 9002, true, false, false, true)
-	mock.ExpectQuery(`[SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter FROM peridot.agents WHERE id = \$1]`).
+	sentRows := sqlmock.NewRows([]string{"id", "name", "is_active", "address", "port", "is_codereader", "is_spdxreader", "is_codewriter", "is_spdxwriter", "health", "version", "created_at", "updated_at"}).
+		AddRow(2, "idsearcher", true, "localhost", 9002, true, false, false, true, AgentHealthOK, 1, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`[SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter, health, version, created_at, updated_at FROM peridot.agents WHERE id = \$1]`).
 		WithArgs(2).
 		WillReturnRows(sentRows)
 
@@ -128,6 +212,73 @@ func TestShouldGetAgentByID(t *testing.T) {
 	}
 }
 
+func TestShouldGetAgentByIDWithNullAddressAndPort(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "name", "is_active", "address", "port", "is_codereader", "is_spdxreader", "is_codewriter", "is_spdxwriter", "health", "version", "created_at", "updated_at"}).
+		AddRow(4, "reuse-lint", true, nil, nil, true, false, false, false, 1, 1, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter, health, version, created_at, updated_at FROM peridot.agents WHERE id = \$1`).
+		WithArgs(4).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	agent, err := db.GetAgentByID(4)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if agent.Address != "" {
+		t.Errorf("expected empty address, got %q", agent.Address)
+	}
+	if agent.Port != 0 {
+		t.Errorf("expected port 0, got %v", agent.Port)
+	}
+}
+
+func TestShouldFailGetAgentByIDWithInvalidHealth(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "name", "is_active", "address", "port", "is_codereader", "is_spdxreader", "is_codewriter", "is_spdxwriter", "health", "version", "created_at", "updated_at"}).
+		AddRow(4, "reuse-lint", true, "localhost", 9001, true, false, false, false, 7, 1, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter, health, version, created_at, updated_at FROM peridot.agents WHERE id = \$1`).
+		WithArgs(4).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	agent, err := db.GetAgentByID(4)
+	if agent != nil {
+		t.Fatalf("expected nil agent, got %v", agent)
+	}
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestShouldFailGetAgentByIDForUnknownID(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
@@ -137,7 +288,7 @@ func TestShouldFailGetAgentByIDForUnknownID(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	mock.ExpectQuery(`[SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter FROM peridot.agents WHERE id = \$1]`).
+	mock.ExpectQuery(`[SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter, health, version, created_at, updated_at FROM peridot.agents WHERE id = \$1]`).
 		WithArgs(413).
 		WillReturnRows(sqlmock.NewRows([]string{}))
 
@@ -157,6 +308,46 @@ func TestShouldFailGetAgentByIDForUnknownID(t *testing.T) {
 	}
 }
 
+func TestShouldGetAgentsByIDs(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "name", "is_active", "address", "port", "is_codereader", "is_spdxreader", "is_codewriter", "is_spdxwriter", "health", "version", "created_at", "updated_at"}).
+		AddRow(2, "idsearcher", true, "localhost", 9002, true, false, false, true, AgentHealthOK, 1, testCreatedAt, testUpdatedAt).
+		AddRow(5, "spdxwriter", true, "localhost", 9005, false, true, false, true, AgentHealthOK, 1, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter, health, version, created_at, updated_at FROM peridot.agents WHERE id = ANY \(\$1\) ORDER BY id`).
+		WithArgs("{5,2,17}").
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	agents, err := db.GetAgentsByIDs([]AgentID{5, 2, 17})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(agents) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(agents))
+	}
+	if agents[0].ID != 2 || agents[0].Name != "idsearcher" {
+		t.Errorf("expected %v, got %v", "2 idsearcher", agents[0])
+	}
+	if agents[1].ID != 5 || agents[1].Name != "spdxwriter" {
+		t.Errorf("expected %v, got %v", "5 spdxwriter", agents[1])
+	}
+}
+
 func TestShouldGetAgentByName(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
@@ -166,9 +357,9 @@ func TestShouldGetAgentByName(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "name", "is_active", "address", "port", "is_codereader", "is_spdxreader", "is_codewriter", "is_spdxwriter"}).
-		AddRow(2, "idsearcher", true, "localhost", 9002, true, false, false, true)
-	mock.ExpectQuery(`[SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter FROM peridot.agents WHERE name = \$1]`).
+	sentRows := sqlmock.NewRows([]string{"id", "name", "is_active", "address", "port", "is_codereader", "is_spdxreader", "is_codewriter", "is_spdxwriter", "health", "version", "created_at", "updated_at"}).
+		AddRow(2, "idsearcher", true, "localhost", 9002, true, false, false, true, AgentHealthOK, 1, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`[SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter, health, version, created_at, updated_at FROM peridot.agents WHERE name = \$1]`).
 		WithArgs("idsearcher").
 		WillReturnRows(sentRows)
 
@@ -223,7 +414,7 @@ func TestShouldFailGetAgentByNameForUnknownName(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	mock.ExpectQuery(`[SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter FROM peridot.agents WHERE name = \$1]`).
+	mock.ExpectQuery(`[SELECT id, name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter, health, version, created_at, updated_at FROM peridot.agents WHERE name = \$1]`).
 		WithArgs("oops").
 		WillReturnRows(sqlmock.NewRows([]string{}))
 
@@ -253,11 +444,15 @@ func TestShouldAddAgent(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[INSERT INTO peridot.agents(name, is_active, address, port, is_codereader, is_spdxreader, is_codewriter, is_spdxwriter) VALUES (\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "INSERT INTO peridot.agents"
 	mock.ExpectQuery(stmt).
 		WithArgs("whitelist-policy", true, "localhost", 9100, true, true, true, false).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	aID, err := db.AddAgent("whitelist-policy", true, "localhost", 9100, true, true, true, false)
@@ -286,15 +481,18 @@ func TestShouldUpdateAgentStatus(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	regexStmt := `[UPDATE peridot.agents SET is_active = \$1, address = \$2, port = \$3 WHERE id = \$4]`
+	regexStmt := `UPDATE peridot.agents SET is_active = \$1, address = \$2, port = \$3, version = version \+ 1 WHERE id = \$4 AND \(\$5 = 0 OR version = \$5\)`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
-	stmt := "UPDATE peridot.agents"
-	mock.ExpectExec(stmt).
-		WithArgs(true, "localhost", 9060, 3).
+	mock.ExpectExec(regexStmt).
+		WithArgs(true, "localhost", 9060, 3, 6).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
-	err = db.UpdateAgentStatus(3, true, "localhost", 9060)
+	err = db.UpdateAgentStatus(3, 6, true, "localhost", 9060)
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -306,6 +504,45 @@ func TestShouldUpdateAgentStatus(t *testing.T) {
 	}
 }
 
+func TestShouldFailUpdateAgentStatusWithStaleVersion(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE peridot.agents")
+	mock.ExpectExec("UPDATE peridot.agents").
+		WithArgs(false, "", 0, 3, 6).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT version FROM peridot.agents WHERE id = \$1`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(7))
+
+	// run the tested function
+	err = db.UpdateAgentStatus(3, 6, false, "", 0)
+	cerr, ok := err.(*ConflictError)
+	if !ok {
+		t.Fatalf("expected *ConflictError, got %v", err)
+	}
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("expected error to match ErrConflict")
+	}
+	if cerr.Version != 6 {
+		t.Errorf("expected version %v, got %v", 6, cerr.Version)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestShouldUpdateAgentAbilities(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
@@ -315,12 +552,16 @@ func TestShouldUpdateAgentAbilities(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	regexStmt := `[UPDATE peridot.agents SET is_codereader = \$1, is_spdxreader = \$2, is_codewriter = \$3, is_spdxwriter = \$4 WHERE id = \$5]`
+	regexStmt := `[UPDATE peridot.agents SET is_codereader = \$1, is_spdxreader = \$2, is_codewriter = \$3, is_spdxwriter = \$4, version = version \+ 1 WHERE id = \$5]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.agents"
 	mock.ExpectExec(stmt).
 		WithArgs(true, true, false, false, 3).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.UpdateAgentAbilities(3, true, true, false, false)
@@ -345,11 +586,15 @@ func TestShouldDeleteAgent(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[DELETE FROM peridot.agents WHERE id = \$1]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "DELETE FROM peridot.agents"
 	mock.ExpectExec(stmt).
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.DeleteAgent(1)
@@ -374,11 +619,13 @@ func TestShouldFailDeleteAgentWithUnknownID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[DELETE FROM peridot.agent WHERE id = \$1]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "DELETE FROM peridot.agent"
 	mock.ExpectExec(stmt).
 		WithArgs(413).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	// run the tested function
 	err = db.DeleteAgent(413)
@@ -393,6 +640,267 @@ func TestShouldFailDeleteAgentWithUnknownID(t *testing.T) {
 	}
 }
 
+func TestShouldDeleteAgentWithRefusePolicyWhenUnused(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `DELETE FROM peridot.agents WHERE id = \$1 AND NOT EXISTS \(SELECT 1 FROM peridot.jobs WHERE agent_id = \$1\)`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.DeleteAgentWithPolicy(1, AgentJobsRefuse, 0)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDeleteAgentWithRefusePolicyWhenJobsExist(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `DELETE FROM peridot.agents WHERE id = \$1 AND NOT EXISTS`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM peridot.jobs WHERE agent_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	// run the tested function
+	err = db.DeleteAgentWithPolicy(2, AgentJobsRefuse, 0)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+	inUseErr, ok := err.(*AgentInUseError)
+	if !ok {
+		t.Fatalf("expected *AgentInUseError, got %T %v", err, err)
+	}
+	if inUseErr.AgentID != 2 {
+		t.Errorf("expected %v, got %v", 2, inUseErr.AgentID)
+	}
+	if inUseErr.JobCount != 7 {
+		t.Errorf("expected %v, got %v", 7, inUseErr.JobCount)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDeleteAgentWithRefusePolicyWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `DELETE FROM peridot.agents WHERE id = \$1 AND NOT EXISTS`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM peridot.jobs WHERE agent_id = \$1`).
+		WithArgs(413).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	// run the tested function
+	err = db.DeleteAgentWithPolicy(413, AgentJobsRefuse, 0)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+	if _, ok := err.(*AgentInUseError); ok {
+		t.Errorf("expected non-AgentInUseError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldDeleteAgentWithReassignPolicy(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM peridot.agents WHERE id = \$1 FOR KEY SHARE`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery(`UPDATE peridot.jobs SET agent_id = \$1, version = version \+ 1 WHERE agent_id = \$2 RETURNING id`).
+		WithArgs(5, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8).AddRow(9))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("job", "8", AuditActionUpdate, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("job", "9", AuditActionUpdate, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM peridot.agents WHERE id = \$1`).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("agent", "2", AuditActionDelete, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.DeleteAgentWithPolicy(2, AgentJobsReassign, 5)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDeleteAgentWithReassignPolicyWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM peridot.agents WHERE id = \$1 FOR KEY SHARE`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery(`UPDATE peridot.jobs SET agent_id = \$1, version = version \+ 1 WHERE agent_id = \$2 RETURNING id`).
+		WithArgs(5, 413).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec(`DELETE FROM peridot.agents WHERE id = \$1`).
+		WithArgs(413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	// run the tested function
+	err = db.DeleteAgentWithPolicy(413, AgentJobsReassign, 5)
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDeleteAgentWithReassignPolicyToSameID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	err = db.DeleteAgentWithPolicy(2, AgentJobsReassign, 2)
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDeleteAgentWithReassignPolicyToUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// nothing is moved or deleted if the target agent doesn't exist
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM peridot.agents WHERE id = \$1 FOR KEY SHARE`).
+		WithArgs(413).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	// run the tested function
+	err = db.DeleteAgentWithPolicy(2, AgentJobsReassign, 413)
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDeleteAgentWithUnknownPolicy(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	err = db.DeleteAgentWithPolicy(2, AgentJobsPolicy(7), 0)
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 // ===== JSON marshalling and unmarshalling =====
 func TestCanMarshalAgentToJSON(t *testing.T) {
 	a := &Agent{
@@ -405,6 +913,7 @@ func TestCanMarshalAgentToJSON(t *testing.T) {
 		IsSpdxReader: true,
 		IsCodeWriter: true,
 		IsSpdxWriter: false,
+		Health:       AgentHealthDegraded,
 	}
 
 	js, err := json.Marshal(a)
@@ -450,12 +959,15 @@ func TestCanMarshalAgentToJSON(t *testing.T) {
 	if a.IsSpdxWriter != mGot["is_spdxwriter"].(bool) {
 		t.Errorf("expected %v, got %v", a.IsSpdxWriter, mGot["is_spdxwriter"].(bool))
 	}
+	if "degraded" != mGot["health"].(string) {
+		t.Errorf("expected %v, got %v", "degraded", mGot["health"].(string))
+	}
 
 }
 
 func TestCanUnmarshalAgentFromJSON(t *testing.T) {
 	a := &Agent{}
-	js := []byte(`{"id":17, "name":"wevs", "is_active":true, "address":"localhost", "port":9065, "is_codereader":true, "is_spdxreader":false, "is_codewriter":false, "is_spdxwriter":true}`)
+	js := []byte(`{"id":17, "name":"wevs", "is_active":true, "address":"localhost", "port":9065, "is_codereader":true, "is_spdxreader":false, "is_codewriter":false, "is_spdxwriter":true, "health":"ok"}`)
 
 	err := json.Unmarshal(js, a)
 	if err != nil {
@@ -490,6 +1002,9 @@ func TestCanUnmarshalAgentFromJSON(t *testing.T) {
 	if a.IsSpdxWriter != true {
 		t.Errorf("expected %v, got %v", true, a.IsSpdxWriter)
 	}
+	if a.Health != AgentHealthOK {
+		t.Errorf("expected %v, got %v", AgentHealthOK, a.Health)
+	}
 }
 
 func TestCannotUnmarshalAgentWithNegativeIDFromJSON(t *testing.T) {
@@ -501,3 +1016,110 @@ func TestCannotUnmarshalAgentWithNegativeIDFromJSON(t *testing.T) {
 		t.Fatalf("expected non-nil error, got nil")
 	}
 }
+
+func TestAgentValidate(t *testing.T) {
+	tests := []struct {
+		a       Agent
+		isError bool
+	}{
+		{Agent{Name: "idsearcher", Address: "localhost", Port: 9001}, false},
+		{Agent{Name: "idle", Address: "", Port: 0}, false},
+		// invalid values should fail
+		{Agent{Name: "", Address: "localhost", Port: 9001}, true},
+		{Agent{Name: "idsearcher", Address: "localhost", Port: 0}, true},
+		{Agent{Name: "idsearcher", Address: "localhost", Port: 70000}, true},
+		{Agent{Name: "idsearcher", Address: "", Port: 9001}, true},
+		{Agent{Name: "idsearcher", Address: "localhost", Port: 9001, Health: AgentHealth(57)}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.a.Validate()
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("for %+v: expected isError %v, got %v", tt.a, tt.isError, err)
+		}
+	}
+}
+
+func TestShouldFailUpdateAgentStatusWithInvalidPort(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function; no queries should be made
+	err = db.UpdateAgentStatus(3, 0, true, "localhost", -1)
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldCountAgentsWithFilter(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM peridot.agents WHERE is_active = true AND name LIKE \$1`).
+		WithArgs(`reuse\_%`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	// run the tested function
+	n, err := db.CountAgents(AgentFilter{ActiveOnly: true, NamePrefix: "reuse_"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if n != 2 {
+		t.Errorf("expected %v, got %v", 2, n)
+	}
+}
+
+func TestShouldReportAgentExistsByName(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM peridot.agents WHERE name = \$1\)`).
+		WithArgs("reuse-lint").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	// run the tested function
+	ok, err := db.ExistsAgentByName("reuse-lint")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if ok != true {
+		t.Errorf("expected %v, got %v", true, ok)
+	}
+}
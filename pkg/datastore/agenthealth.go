@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// AgentHealth defines the different health values that can
+// apply to an Agent. It is tracked separately from whether the
+// Agent is active.
+type AgentHealth int
+
+const (
+	// AgentHealthUnknown is a default zero value, and means
+	// that no health check has yet been recorded for the agent.
+	AgentHealthUnknown AgentHealth = 0
+
+	// AgentHealthOK means that the agent is reachable and
+	// responding normally.
+	AgentHealthOK AgentHealth = 1
+
+	// AgentHealthDegraded means that the agent is reachable,
+	// but is responding slowly or reporting problems.
+	AgentHealthDegraded AgentHealth = 2
+
+	// AgentHealthUnreachable means that the agent could not
+	// be reached at its registered address and port.
+	AgentHealthUnreachable AgentHealth = 3
+)
+
+// AgentHealthFromInt converts an integer to its corresponding
+// AgentHealth value. It returns that value or an error if the
+// integer is invalid.
+func AgentHealthFromInt(ahInt int) (AgentHealth, error) {
+	switch ahInt {
+	case 0:
+		return AgentHealthUnknown, nil
+	case 1:
+		return AgentHealthOK, nil
+	case 2:
+		return AgentHealthDegraded, nil
+	case 3:
+		return AgentHealthUnreachable, nil
+	}
+
+	return AgentHealthUnknown, fmt.Errorf("invalid agent health integer %d", ahInt)
+}
+
+// IntFromAgentHealth converts an AgentHealth value to its
+// corresponding integer value.
+func IntFromAgentHealth(ah AgentHealth) int {
+	switch ah {
+	case AgentHealthUnknown:
+		return 0
+	case AgentHealthOK:
+		return 1
+	case AgentHealthDegraded:
+		return 2
+	case AgentHealthUnreachable:
+		return 3
+	}
+
+	// shouldn't be possible to fall through since all values
+	// are captured above, but we'll return 0 here because go
+	// requires a final return
+	return 0
+}
+
+// AgentHealthFromString converts a string to its corresponding
+// AgentHealth value. It returns that value or an error if the
+// string is invalid.
+func AgentHealthFromString(ahStr string) (AgentHealth, error) {
+	switch ahStr {
+	case "unknown":
+		return AgentHealthUnknown, nil
+	case "ok":
+		return AgentHealthOK, nil
+	case "degraded":
+		return AgentHealthDegraded, nil
+	case "unreachable":
+		return AgentHealthUnreachable, nil
+	}
+
+	return AgentHealthUnknown, fmt.Errorf("invalid agent health string %s", ahStr)
+}
+
+// StringFromAgentHealth converts an AgentHealth value to its
+// corresponding string value.
+func StringFromAgentHealth(ah AgentHealth) string {
+	switch ah {
+	case AgentHealthUnknown:
+		return "unknown"
+	case AgentHealthOK:
+		return "ok"
+	case AgentHealthDegraded:
+		return "degraded"
+	case AgentHealthUnreachable:
+		return "unreachable"
+	}
+
+	// shouldn't be possible to fall through since all values
+	// are captured above, but we'll return 'unknown' here because
+	// go requires a final return
+	return "unknown"
+}
+
+// String returns the AgentHealth's string value, such as
+// "unreachable", as given by StringFromAgentHealth.
+func (ah AgentHealth) String() string {
+	return StringFromAgentHealth(ah)
+}
+
+// MarshalJSON converts the AgentHealth value into a slice of bytes
+// containing the string encoding of the agent health.
+func (ah AgentHealth) MarshalJSON() ([]byte, error) {
+	return json.Marshal(StringFromAgentHealth(ah))
+}
+
+// UnmarshalJSON converts a slice of bytes containing the string encoding
+// of the agent health into the corresponding AgentHealth value.
+func (ah *AgentHealth) UnmarshalJSON(b []byte) error {
+	var s string
+
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	ahVal, err := AgentHealthFromString(s)
+	if err != nil {
+		return err
+	}
+
+	*ah = ahVal
+	return nil
+}
@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestCanChangeIntToAgentHealth(t *testing.T) {
+	tests := []struct {
+		in      int
+		want    AgentHealth
+		isError bool
+	}{
+		{0, AgentHealthUnknown, false},
+		{1, AgentHealthOK, false},
+		{2, AgentHealthDegraded, false},
+		{3, AgentHealthUnreachable, false},
+		// invalid values should return AgentHealthUnknown
+		{7, AgentHealthUnknown, true},
+	}
+
+	for _, tt := range tests {
+		got, err := AgentHealthFromInt(tt.in)
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestCanChangeAgentHealthToInt(t *testing.T) {
+	tests := []struct {
+		in   AgentHealth
+		want int
+	}{
+		{AgentHealthUnknown, 0},
+		{AgentHealthOK, 1},
+		{AgentHealthDegraded, 2},
+		{AgentHealthUnreachable, 3},
+	}
+
+	for _, tt := range tests {
+		got := IntFromAgentHealth(tt.in)
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestCanChangeStringToAgentHealth(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    AgentHealth
+		isError bool
+	}{
+		{"unknown", AgentHealthUnknown, false},
+		{"ok", AgentHealthOK, false},
+		{"degraded", AgentHealthDegraded, false},
+		{"unreachable", AgentHealthUnreachable, false},
+		// invalid values should return AgentHealthUnknown
+		{"oops", AgentHealthUnknown, true},
+	}
+
+	for _, tt := range tests {
+		got, err := AgentHealthFromString(tt.in)
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestCanChangeAgentHealthToString(t *testing.T) {
+	tests := []struct {
+		in   AgentHealth
+		want string
+	}{
+		{AgentHealthUnknown, "unknown"},
+		{AgentHealthOK, "ok"},
+		{AgentHealthDegraded, "degraded"},
+		{AgentHealthUnreachable, "unreachable"},
+	}
+
+	for _, tt := range tests {
+		got := StringFromAgentHealth(tt.in)
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestCanMarshalAndUnmarshalAgentHealthJSON(t *testing.T) {
+	tests := []struct {
+		in   AgentHealth
+		want string
+	}{
+		{AgentHealthUnknown, "\"unknown\""},
+		{AgentHealthOK, "\"ok\""},
+		{AgentHealthDegraded, "\"degraded\""},
+		{AgentHealthUnreachable, "\"unreachable\""},
+	}
+
+	for _, tt := range tests {
+		gotBytes, err := json.Marshal(tt.in)
+		if err != nil {
+			t.Fatalf("got non-nil error: %v", err)
+		}
+		if string(gotBytes) != tt.want {
+			t.Errorf("expected %v, got %v", tt.want, string(gotBytes))
+		}
+
+		var got AgentHealth
+		err = json.Unmarshal([]byte(tt.want), &got)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if got != tt.in {
+			t.Errorf("expected %v, got %v", tt.in, got)
+		}
+	}
+
+	// and invalid strings should fail to unmarshal
+	var got AgentHealth
+	err := json.Unmarshal([]byte("\"oops\""), &got)
+	if err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+}
+
+func TestCanFormatAgentHealth(t *testing.T) {
+	tests := []struct {
+		in   AgentHealth
+		want string
+	}{
+		{AgentHealthUnknown, "unknown"},
+		{AgentHealthOK, "ok"},
+		{AgentHealthDegraded, "degraded"},
+		{AgentHealthUnreachable, "unreachable"},
+	}
+
+	for _, tt := range tests {
+		got := fmt.Sprintf("%v", tt.in)
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import "time"
+
+// AgentHealthEvent describes a single recorded change in the
+// health of an Agent. Taken together, an agent's events show
+// whether it has been flapping between health states.
+type AgentHealthEvent struct {
+	// ID is the unique ID for this health event.
+	ID uint32 `json:"id"`
+	// AgentID is the ID of the agent whose health was recorded.
+	AgentID AgentID `json:"agent_id"`
+	// Health is the health of the agent as of this event.
+	Health AgentHealth `json:"health"`
+	// Output is any message explaining the recorded health.
+	Output string `json:"output,omitempty"`
+	// RecordedAt is when this health event was recorded.
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// UpdateAgentHealth sets the current health of an existing Agent
+// with the given ID, and records the change with the given output
+// message in the agent's health history. It returns nil on success,
+// a *ValidationError if health is not a defined value, or another
+// error if failing.
+func (db *DB) UpdateAgentHealth(id AgentID, health AgentHealth, output string) error {
+	if _, err := AgentHealthFromInt(int(health)); err != nil {
+		return &ValidationError{Entity: "agent", Field: "health", Reason: err.Error()}
+	}
+
+	// update the agent and record the event in a single statement,
+	// so that the history cannot drift from the agent's current health
+	return db.execWithOutboxEvent("agent", id, AuditActionUpdate, map[string]interface{}{"id": id, "health": health},
+		`
+		WITH updated AS (
+			UPDATE peridot.agents SET health = $1, version = version + 1 WHERE id = $2 RETURNING id
+		)
+		INSERT INTO peridot.agent_health_events(agent_id, health, output, recorded_at)
+		SELECT id, $1, $3, $4 FROM updated`, IntFromAgentHealth(health), id, output, now())
+}
+
+// GetAgentHealthHistory returns a slice of the health events
+// recorded for the Agent with the given ID at or after the given
+// time, ordered from oldest to newest.
+func (db *DB) GetAgentHealthHistory(id AgentID, since time.Time) ([]*AgentHealthEvent, error) {
+	rows, err := db.sqldb.Query("SELECT id, agent_id, health, output, recorded_at FROM peridot.agent_health_events WHERE agent_id = $1 AND recorded_at >= $2 ORDER BY recorded_at, id", id, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*AgentHealthEvent{}
+	for rows.Next() {
+		ev := &AgentHealthEvent{}
+		var ahInt int
+		err := rows.Scan(&ev.ID, &ev.AgentID, &ahInt, &ev.Output, &ev.RecordedAt)
+		ev.RecordedAt = normalizeTime(ev.RecordedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		// convert integer to AgentHealth
+		ev.Health, err = AgentHealthFromInt(ahInt)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, ev)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldUpdateAgentHealth(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `UPDATE peridot.agents SET health = \$1, version = version \+ 1 WHERE id = \$2 RETURNING id`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	stmt := "INSERT INTO peridot.agent_health_events"
+	mock.ExpectExec(stmt).
+		WithArgs(IntFromAgentHealth(AgentHealthUnreachable), 3, "connection refused", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.UpdateAgentHealth(3, AgentHealthUnreachable, "connection refused")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailUpdateAgentHealthWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `UPDATE peridot.agents SET health = \$1, version = version \+ 1 WHERE id = \$2 RETURNING id`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	stmt := "INSERT INTO peridot.agent_health_events"
+	mock.ExpectExec(stmt).
+		WithArgs(IntFromAgentHealth(AgentHealthOK), 413, "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	// run the tested function
+	err = db.UpdateAgentHealth(413, AgentHealthOK, "")
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailUpdateAgentHealthWithInvalidHealth(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	err = db.UpdateAgentHealth(2, AgentHealth(17), "")
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldGetAgentHealthHistory(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	since := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	t1 := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	t2 := time.Date(2019, 5, 2, 13, 58, 2, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "agent_id", "health", "output", "recorded_at"}).
+		AddRow(8, 3, 3, "connection refused", t1).
+		AddRow(11, 3, 1, "", t2)
+	mock.ExpectQuery(`SELECT id, agent_id, health, output, recorded_at FROM peridot.agent_health_events WHERE agent_id = \$1 AND recorded_at >= \$2 ORDER BY recorded_at, id`).
+		WithArgs(3, since).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetAgentHealthHistory(3, since)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	ev0 := gotRows[0]
+	if ev0.ID != 8 {
+		t.Errorf("expected %v, got %v", 8, ev0.ID)
+	}
+	if ev0.AgentID != 3 {
+		t.Errorf("expected %v, got %v", 3, ev0.AgentID)
+	}
+	if ev0.Health != AgentHealthUnreachable {
+		t.Errorf("expected %v, got %v", AgentHealthUnreachable, ev0.Health)
+	}
+	if ev0.Output != "connection refused" {
+		t.Errorf("expected %v, got %v", "connection refused", ev0.Output)
+	}
+	if ev0.RecordedAt != t1 {
+		t.Errorf("expected %v, got %v", t1, ev0.RecordedAt)
+	}
+	ev1 := gotRows[1]
+	if ev1.Health != AgentHealthOK {
+		t.Errorf("expected %v, got %v", AgentHealthOK, ev1.Health)
+	}
+}
+
+func TestShouldFailGetAgentHealthHistoryWithInvalidHealth(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	since := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "agent_id", "health", "output", "recorded_at"}).
+		AddRow(8, 3, 17, "", since)
+	mock.ExpectQuery(`SELECT id, agent_id, health, output, recorded_at FROM peridot.agent_health_events`).
+		WithArgs(3, since).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetAgentHealthHistory(3, since)
+	if gotRows != nil {
+		t.Fatalf("expected nil rows, got %v", gotRows)
+	}
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+}
+
+// ===== JSON marshalling and unmarshalling =====
+func TestCanMarshalAgentHealthEventToJSON(t *testing.T) {
+	ev := &AgentHealthEvent{
+		ID:         8,
+		AgentID:    3,
+		Health:     AgentHealthDegraded,
+		Output:     "slow response",
+		RecordedAt: time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC),
+	}
+
+	js, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+
+	// read back in as empty interface to check values
+	var mapGot interface{}
+	err = json.Unmarshal(js, &mapGot)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	mGot := mapGot.(map[string]interface{})
+
+	// check for expected values
+	if float64(ev.ID) != mGot["id"].(float64) {
+		t.Errorf("expected %v, got %v", float64(ev.ID), mGot["id"].(float64))
+	}
+	if float64(ev.AgentID) != mGot["agent_id"].(float64) {
+		t.Errorf("expected %v, got %v", float64(ev.AgentID), mGot["agent_id"].(float64))
+	}
+	if "degraded" != mGot["health"].(string) {
+		t.Errorf("expected %v, got %v", "degraded", mGot["health"].(string))
+	}
+	if ev.Output != mGot["output"].(string) {
+		t.Errorf("expected %v, got %v", ev.Output, mGot["output"].(string))
+	}
+	if "2019-05-02T13:53:41Z" != mGot["recorded_at"].(string) {
+		t.Errorf("expected %v, got %v", "2019-05-02T13:53:41Z", mGot["recorded_at"].(string))
+	}
+}
@@ -0,0 +1,230 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Verification statuses of an Attestation.
+const (
+	// AttestationUnverified means the attestation's signature and
+	// contents have not yet been checked.
+	AttestationUnverified = "unverified"
+	// AttestationVerified means the attestation was verified
+	// successfully.
+	AttestationVerified = "verified"
+	// AttestationFailed means the attestation failed verification;
+	// see VerificationMessage.
+	AttestationFailed = "failed"
+)
+
+// Attestation describes a build provenance or other attestation
+// document, such as an in-toto statement, about an artifact built
+// from a RepoPull. The statement is either stored inline or kept in
+// external storage, and can be found by the digest of the artifact
+// it is about, for supply-chain verification.
+type Attestation struct {
+	// ID is the unique ID for this attestation.
+	ID uint32 `json:"id"`
+	// RepoPullID is the ID of the RepoPull the artifact was built
+	// from.
+	RepoPullID RepoPullID `json:"repopull_id"`
+	// Type identifies the kind of statement, typically its
+	// predicate type, e.g. "https://slsa.dev/provenance/v0.2".
+	Type string `json:"type"`
+	// SubjectDigest is the digest of the artifact the statement is
+	// about, as an algorithm and lowercase hex value separated by a
+	// colon, e.g. "sha256:e3b0c4...".
+	SubjectDigest string `json:"subject_digest"`
+	// Statement is the statement document itself, or nil if it is
+	// stored externally at URI.
+	Statement []byte `json:"statement,omitempty"`
+	// URI is where the statement is stored, e.g. an s3:// URI, or
+	// "" if it is stored inline as Statement.
+	URI string `json:"uri,omitempty"`
+	// VerificationStatus is one of the Attestation status values.
+	VerificationStatus string `json:"verification_status"`
+	// VerificationMessage explains why verification failed, or is
+	// "" if it has not.
+	VerificationMessage string `json:"verification_message,omitempty"`
+	// RecordedAt is when the attestation was recorded.
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// Validate checks that the Attestation's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (at *Attestation) Validate() error {
+	if err := requireNonEmpty("attestation", "type", at.Type); err != nil {
+		return err
+	}
+	if err := validateSubjectDigest(at.SubjectDigest); err != nil {
+		return err
+	}
+	if (len(at.Statement) == 0) == (at.URI == "") {
+		return &ValidationError{Entity: "attestation", Field: "statement", Reason: "exactly one of statement and uri must be set"}
+	}
+	return validateAttestationStatus(at.VerificationStatus)
+}
+
+// validateSubjectDigest returns a *ValidationError if digest is not
+// an algorithm name and a lowercase hex value separated by a colon.
+func validateSubjectDigest(digest string) error {
+	i := strings.Index(digest, ":")
+	if i <= 0 || i == len(digest)-1 || !isLowerHex(digest[i+1:], len(digest)-i-1) {
+		return &ValidationError{Entity: "attestation", Field: "subject_digest", Reason: "must be an algorithm and lowercase hex digest, e.g. sha256:<hex>"}
+	}
+	return nil
+}
+
+// validateAttestationStatus returns a *ValidationError if status is
+// not one of the Attestation status values.
+func validateAttestationStatus(status string) error {
+	switch status {
+	case AttestationUnverified, AttestationVerified, AttestationFailed:
+		return nil
+	}
+	return &ValidationError{Entity: "attestation", Field: "verification_status", Reason: "must be unverified, verified or failed"}
+}
+
+const attestationColumns = "id, repopull_id, type, subject_digest, statement, uri, verification_status, verification_message, recorded_at"
+
+// scanAttestation reads an Attestation from a row selecting
+// attestationColumns.
+func scanAttestation(rs rowScanner) (*Attestation, error) {
+	at := &Attestation{}
+	err := rs.Scan(&at.ID, &at.RepoPullID, &at.Type, &at.SubjectDigest, &at.Statement, &at.URI, &at.VerificationStatus, &at.VerificationMessage, &at.RecordedAt)
+	if err != nil {
+		return nil, err
+	}
+	at.RecordedAt = normalizeTime(at.RecordedAt)
+	return at, nil
+}
+
+// queryAttestations runs query, which selects attestationColumns,
+// and returns the attestations it finds.
+func (db *DB) queryAttestations(query string, args ...interface{}) ([]*Attestation, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attestations := []*Attestation{}
+	for rows.Next() {
+		at, err := scanAttestation(rows)
+		if err != nil {
+			return nil, err
+		}
+		attestations = append(attestations, at)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return attestations, nil
+}
+
+// GetAttestationsForRepoPull returns a slice of all attestations
+// recorded for the RepoPull with the given ID, ordered by ID.
+func (db *DB) GetAttestationsForRepoPull(rpID RepoPullID) ([]*Attestation, error) {
+	return db.queryAttestations("SELECT "+attestationColumns+" FROM peridot.attestations WHERE repopull_id = $1 ORDER BY id", rpID)
+}
+
+// GetAttestationsBySubjectDigest returns a slice of all attestations
+// about the artifact with the given digest, from any RepoPull,
+// ordered by ID.
+func (db *DB) GetAttestationsBySubjectDigest(digest string) ([]*Attestation, error) {
+	return db.queryAttestations("SELECT "+attestationColumns+" FROM peridot.attestations WHERE subject_digest = $1 ORDER BY id", digest)
+}
+
+// GetAttestationByID returns the Attestation with the given ID, or
+// nil and an error if not found.
+func (db *DB) GetAttestationByID(id uint32) (*Attestation, error) {
+	at, err := scanAttestation(db.sqldb.QueryRow("SELECT "+attestationColumns+" FROM peridot.attestations WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "attestation", ID: fmt.Sprint(id)}
+	}
+	return at, err
+}
+
+// AddAttestation records an attestation of the given type about the
+// artifact with the given digest, built from the RepoPull with the
+// given ID. Exactly one of statement, the document itself, and uri,
+// where it is stored, must be given. The attestation starts with
+// verification status AttestationUnverified. It returns the new
+// attestation's ID on success or an error if failing.
+func (db *DB) AddAttestation(rpID RepoPullID, attType string, subjectDigest string, statement []byte, uri string) (uint32, error) {
+	at := &Attestation{RepoPullID: rpID, Type: attType, SubjectDigest: subjectDigest, Statement: statement, URI: uri, VerificationStatus: AttestationUnverified}
+	if err := at.Validate(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.attestations(repopull_id, type, subject_digest, statement, uri, verification_status, verification_message, recorded_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	// store an external statement's column as NULL, not as empty
+	var inlineStatement interface{}
+	if len(statement) > 0 {
+		inlineStatement = statement
+	}
+
+	var attestationID uint32
+	err = stmt.QueryRow(rpID, attType, subjectDigest, inlineStatement, uri, AttestationUnverified, "", now()).Scan(&attestationID)
+	if err != nil {
+		return 0, err
+	}
+	return attestationID, nil
+}
+
+// UpdateAttestationVerification sets the verification status of the
+// existing Attestation with the given ID, with a message explaining
+// any failure. It returns nil on success or an error if failing.
+func (db *DB) UpdateAttestationVerification(id uint32, status string, message string) error {
+	if err := validateAttestationStatus(status); err != nil {
+		return err
+	}
+
+	result, err := db.sqldb.Exec("UPDATE peridot.attestations SET verification_status = $1, verification_message = $2 WHERE id = $3", status, message, id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "attestation", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// DeleteAttestation deletes the existing Attestation with the given
+// ID. Any externally stored statement is not affected. It returns
+// nil on success or an error if failing.
+func (db *DB) DeleteAttestation(id uint32) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.attestations WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "attestation", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const testSubjectDigest = "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func TestShouldGetAttestationsBySubjectDigest(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	recordedAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "repopull_id", "type", "subject_digest", "statement", "uri", "verification_status", "verification_message", "recorded_at"}).
+		AddRow(1, 36, "https://slsa.dev/provenance/v0.2", testSubjectDigest, nil, "s3://attestations/36/build.intoto.jsonl", "verified", "", recordedAt).
+		AddRow(4, 41, "https://in-toto.io/attestation/vulns", testSubjectDigest, []byte(`{"_type":"https://in-toto.io/Statement/v0.1"}`), "", "failed", "signature mismatch", recordedAt)
+	mock.ExpectQuery(`SELECT id, repopull_id, type, subject_digest, statement, uri, verification_status, verification_message, recorded_at FROM peridot.attestations WHERE subject_digest = \$1 ORDER BY id`).
+		WithArgs(testSubjectDigest).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetAttestationsBySubjectDigest(testSubjectDigest)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*Attestation{
+		{ID: 1, RepoPullID: 36, Type: "https://slsa.dev/provenance/v0.2", SubjectDigest: testSubjectDigest, URI: "s3://attestations/36/build.intoto.jsonl", VerificationStatus: AttestationVerified, RecordedAt: recordedAt},
+		{ID: 4, RepoPullID: 41, Type: "https://in-toto.io/attestation/vulns", SubjectDigest: testSubjectDigest, Statement: []byte(`{"_type":"https://in-toto.io/Statement/v0.1"}`), VerificationStatus: AttestationFailed, VerificationMessage: "signature mismatch", RecordedAt: recordedAt},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldAddAttestationAsUnverified(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.attestations\(repopull_id, type, subject_digest, statement, uri, verification_status, verification_message, recorded_at\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(36, "https://slsa.dev/provenance/v0.2", testSubjectDigest, nilArg{}, "s3://attestations/36/build.intoto.jsonl", "unverified", "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	// run the tested function
+	id, err := db.AddAttestation(36, "https://slsa.dev/provenance/v0.2", testSubjectDigest, nil, "s3://attestations/36/build.intoto.jsonl")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 5 {
+		t.Errorf("expected %v, got %v", 5, id)
+	}
+}
+
+func TestShouldFailAddAttestationWithInvalidFields(t *testing.T) {
+	tests := []struct {
+		name          string
+		attType       string
+		subjectDigest string
+		statement     []byte
+		uri           string
+		field         string
+	}{
+		{"no type", "", testSubjectDigest, nil, "s3://x", "type"},
+		{"no algorithm", "slsa", ":e3b0c442", nil, "s3://x", "subject_digest"},
+		{"no digest", "slsa", "sha256:", nil, "s3://x", "subject_digest"},
+		{"uppercase digest", "slsa", "sha256:E3B0C442", nil, "s3://x", "subject_digest"},
+		{"neither statement nor uri", "slsa", testSubjectDigest, nil, "", "statement"},
+		{"both statement and uri", "slsa", testSubjectDigest, []byte("{}"), "s3://x", "statement"},
+	}
+	for _, tc := range tests {
+		_, err := (&DB{}).AddAttestation(36, tc.attType, tc.subjectDigest, tc.statement, tc.uri)
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%s: expected *ValidationError, got %v", tc.name, err)
+			continue
+		}
+		if verr.Field != tc.field {
+			t.Errorf("%s: expected field %v, got %v", tc.name, tc.field, verr.Field)
+		}
+	}
+}
+
+func TestShouldUpdateAttestationVerification(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`UPDATE peridot.attestations SET verification_status = \$1, verification_message = \$2 WHERE id = \$3`).
+		WithArgs("failed", "signature mismatch", 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.UpdateAttestationVerification(5, AttestationFailed, "signature mismatch")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDeleteAttestationWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`DELETE FROM peridot.attestations WHERE id = \$1`).
+		WithArgs(413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.DeleteAttestation(413)
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Audit actions recorded in AuditEntry.Action.
+const (
+	// AuditActionAdd means the entity was created.
+	AuditActionAdd = "add"
+	// AuditActionUpdate means the entity was modified.
+	AuditActionUpdate = "update"
+	// AuditActionDelete means the entity was deleted.
+	AuditActionDelete = "delete"
+)
+
+// AuditEntry describes a single recorded mutation of data in
+// peridot: who made it, to what, and what the data looked like
+// before and after.
+type AuditEntry struct {
+	// ID is the unique ID for this audit entry.
+	ID uint64 `json:"id"`
+	// ActorID is the ID of the user who made the change. It is
+	// not a foreign key, so entries remain after the user is
+	// deleted.
+	ActorID UserID `json:"actor_id"`
+	// Entity is the kind of entity that was changed, such as
+	// "project" or "job".
+	Entity string `json:"entity"`
+	// EntityID identifies the changed entity within its kind.
+	// It is a string because some entities (e.g. repo branches)
+	// do not have a single integer ID.
+	EntityID string `json:"entity_id"`
+	// Action is one of the AuditAction values.
+	Action string `json:"action"`
+	// Before is the JSON encoding of the entity before the
+	// change, or nil if not applicable or not available.
+	Before json.RawMessage `json:"before,omitempty"`
+	// After is the JSON encoding of the entity after the
+	// change, or nil if not applicable or not available.
+	After json.RawMessage `json:"after,omitempty"`
+	// CreatedAt is when the change was recorded.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// marshalAuditSnapshot converts a before/after snapshot to JSON for
+// storage, returning an untyped nil (i.e., SQL NULL) for nil.
+func marshalAuditSnapshot(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// AddAuditEntry records that the user with ID actorID performed the
+// given action on the entity identified by entity and entityID. The
+// before and after snapshots are stored as JSON, and may be nil. It
+// returns nil on success or an error if failing.
+func (db *DB) AddAuditEntry(actorID UserID, entity string, entityID string, action string, before interface{}, after interface{}) error {
+	beforeJSON, err := marshalAuditSnapshot(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalAuditSnapshot(after)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.audit_log(actor_id, entity, entity_id, action, before, after, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)")
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(actorID, entity, entityID, action, beforeJSON, afterJSON, now())
+	return err
+}
+
+// GetAuditEntries returns a slice of audit entries for the given
+// entity kind, recorded at or after since and before until, ordered
+// from oldest to newest. If entityID is non-empty, only entries for
+// that particular entity are returned. If until is the zero value,
+// there is no upper bound.
+func (db *DB) GetAuditEntries(entity string, entityID string, since time.Time, until time.Time) ([]*AuditEntry, error) {
+	conds := []string{"entity = $1", "created_at >= $2"}
+	args := []interface{}{entity, since}
+	if entityID != "" {
+		args = append(args, entityID)
+		conds = append(conds, fmt.Sprintf("entity_id = $%d", len(args)))
+	}
+	if !until.IsZero() {
+		args = append(args, until)
+		conds = append(conds, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	query := "SELECT id, actor_id, entity, entity_id, action, before, after, created_at FROM peridot.audit_log WHERE " + strings.Join(conds, " AND ") + " ORDER BY created_at, id"
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAuditEntryRows(rows)
+}
+
+// GetAuditEntriesByActor returns a slice of all audit entries for
+// changes made by the user with the given ID, ordered from oldest
+// to newest.
+func (db *DB) GetAuditEntriesByActor(actorID UserID) ([]*AuditEntry, error) {
+	rows, err := db.sqldb.Query("SELECT id, actor_id, entity, entity_id, action, before, after, created_at FROM peridot.audit_log WHERE actor_id = $1 ORDER BY created_at, id", actorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAuditEntryRows(rows)
+}
+
+// scanAuditEntryRows collects AuditEntry values from rows selecting
+// all audit_log columns.
+func scanAuditEntryRows(rows *sql.Rows) ([]*AuditEntry, error) {
+	entries := []*AuditEntry{}
+	for rows.Next() {
+		ae := &AuditEntry{}
+		var before, after []byte
+		err := rows.Scan(&ae.ID, &ae.ActorID, &ae.Entity, &ae.EntityID, &ae.Action, &before, &after, &ae.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ae.CreatedAt = normalizeTime(ae.CreatedAt)
+		if before != nil {
+			ae.Before = json.RawMessage(before)
+		}
+		if after != nil {
+			ae.After = json.RawMessage(after)
+		}
+		entries = append(entries, ae)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
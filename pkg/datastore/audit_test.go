@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldAddAuditEntry(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.audit_log\(actor_id, entity, entity_id, action, before, after, created_at\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\)`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(8103918, "project", "3", AuditActionUpdate, []byte(`{"id":3,"name":"p","fullname":"old"}`), []byte(`{"id":3,"name":"p","fullname":"new"}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	before := &Project{ID: 3, Name: "p", Fullname: "old"}
+	after := &Project{ID: 3, Name: "p", Fullname: "new"}
+	err = db.AddAuditEntry(8103918, "project", "3", AuditActionUpdate, before, after)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldAddAuditEntryWithNilSnapshots(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.audit_log`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(8103918, "user_token", "5", AuditActionDelete, nil, nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.AddAuditEntry(8103918, "user_token", "5", AuditActionDelete, nil, nil)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldGetAuditEntriesForEntityInTimeRange(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	since := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	t1 := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "actor_id", "entity", "entity_id", "action", "before", "after", "created_at"}).
+		AddRow(17, 8103918, "project", "3", "update", []byte(`{"id":3}`), []byte(`{"id":3,"name":"x"}`), t1).
+		AddRow(18, 8103918, "project", "3", "delete", []byte(`{"id":3,"name":"x"}`), nil, t1)
+	mock.ExpectQuery(`SELECT id, actor_id, entity, entity_id, action, before, after, created_at FROM peridot.audit_log WHERE entity = \$1 AND created_at >= \$2 AND entity_id = \$3 AND created_at < \$4 ORDER BY created_at, id`).
+		WithArgs("project", since, "3", until).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetAuditEntries("project", "3", since, until)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	ae0 := gotRows[0]
+	if ae0.ID != 17 {
+		t.Errorf("expected %v, got %v", 17, ae0.ID)
+	}
+	if ae0.ActorID != 8103918 {
+		t.Errorf("expected %v, got %v", 8103918, ae0.ActorID)
+	}
+	if ae0.Action != AuditActionUpdate {
+		t.Errorf("expected %v, got %v", AuditActionUpdate, ae0.Action)
+	}
+	if string(ae0.After) != `{"id":3,"name":"x"}` {
+		t.Errorf("expected %v, got %v", `{"id":3,"name":"x"}`, string(ae0.After))
+	}
+	ae1 := gotRows[1]
+	if ae1.After != nil {
+		t.Errorf("expected nil, got %v", string(ae1.After))
+	}
+}
+
+func TestShouldGetAuditEntriesForAllOfEntityKind(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	since := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT id, actor_id, entity, entity_id, action, before, after, created_at FROM peridot.audit_log WHERE entity = \$1 AND created_at >= \$2 ORDER BY created_at, id`).
+		WithArgs("agent", since).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor_id", "entity", "entity_id", "action", "before", "after", "created_at"}))
+
+	// run the tested function
+	gotRows, err := db.GetAuditEntries("agent", "", since, time.Time{})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if len(gotRows) != 0 {
+		t.Fatalf("expected len %d, got %d", 0, len(gotRows))
+	}
+}
@@ -0,0 +1,1486 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditedDatastore wraps another Datastore, recording an entry in
+// the audit log for each successful Add, Update or Delete call made
+// on behalf of the user with ID ActorID. Read-only calls are passed
+// through unchanged, as are writes of scanner and evaluation results,
+// such as AddFindings, AddComponents and AddPolicyResult, and bulk
+// loads such as BulkAddFileInstances and batched deletes such as
+// DeleteFileInstancesInBatches, which would swamp the log.
+//
+// Where a getter is available, the entity is fetched before and/or
+// after the change to record snapshots. If recording the audit entry
+// fails after the change itself succeeded, the change is NOT rolled
+// back; the audit error is returned so that the caller knows the
+// log is incomplete.
+type AuditedDatastore struct {
+	Datastore
+
+	// ActorID is the ID of the user to whom changes are attributed.
+	ActorID UserID
+}
+
+// NewAuditedDatastore returns an AuditedDatastore that records
+// changes made through ds as having been made by the user with
+// ID actorID.
+func NewAuditedDatastore(ds Datastore, actorID UserID) *AuditedDatastore {
+	return &AuditedDatastore{Datastore: ds, ActorID: actorID}
+}
+
+// InTx calls fn within a transaction as the wrapped Datastore's InTx
+// does, with a Datastore that records changes in the audit log as
+// part of the same transaction.
+func (a *AuditedDatastore) InTx(ctx context.Context, fn func(ds Datastore) error) error {
+	return a.Datastore.InTx(ctx, func(ds Datastore) error {
+		return fn(&AuditedDatastore{Datastore: ds, ActorID: a.ActorID})
+	})
+}
+
+// record adds an audit entry attributed to the wrapper's actor.
+func (a *AuditedDatastore) record(entity string, entityID interface{}, action string, before interface{}, after interface{}) error {
+	err := a.Datastore.AddAuditEntry(a.ActorID, entity, fmt.Sprint(entityID), action, before, after)
+	if err != nil {
+		return fmt.Errorf("change succeeded but audit entry failed: %v", err)
+	}
+	return nil
+}
+
+// snapshot returns v if err is nil, or nil otherwise. It is used to
+// take best-effort snapshots where a failed lookup should not block
+// the change itself.
+func snapshot(v interface{}, err error) interface{} {
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// ===== Users =====
+
+// AddUser adds a new User and records it in the audit log.
+func (a *AuditedDatastore) AddUser(id UserID, name string, github string, email string, accessLevel UserAccessLevel) error {
+	err := a.Datastore.AddUser(id, name, github, email, accessLevel)
+	if err != nil {
+		return err
+	}
+	return a.record("user", id, AuditActionAdd, nil, snapshot(a.Datastore.GetUserByID(id)))
+}
+
+// AddUserAutoID adds a new User with a generated ID and records it
+// in the audit log.
+func (a *AuditedDatastore) AddUserAutoID(name string, github string, email string, accessLevel UserAccessLevel, kind UserKind) (UserID, error) {
+	id, err := a.Datastore.AddUserAutoID(name, github, email, accessLevel, kind)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("user", id, AuditActionAdd, nil, snapshot(a.Datastore.GetUserByID(id)))
+}
+
+// AddServiceAccount adds a new service account User and records it
+// in the audit log.
+func (a *AuditedDatastore) AddServiceAccount(id UserID, name string, accessLevel UserAccessLevel) error {
+	err := a.Datastore.AddServiceAccount(id, name, accessLevel)
+	if err != nil {
+		return err
+	}
+	return a.record("user", id, AuditActionAdd, nil, snapshot(a.Datastore.GetUserByID(id)))
+}
+
+// UpdateUser updates an existing User and records it in the audit log.
+func (a *AuditedDatastore) UpdateUser(id UserID, newName string, newGithub string, newEmail string, newAccessLevel UserAccessLevel) error {
+	before := snapshot(a.Datastore.GetUserByID(id))
+	err := a.Datastore.UpdateUser(id, newName, newGithub, newEmail, newAccessLevel)
+	if err != nil {
+		return err
+	}
+	return a.record("user", id, AuditActionUpdate, before, snapshot(a.Datastore.GetUserByID(id)))
+}
+
+// UpdateUserNameOnly updates an existing User's name and records it
+// in the audit log.
+func (a *AuditedDatastore) UpdateUserNameOnly(id UserID, newName string) error {
+	before := snapshot(a.Datastore.GetUserByID(id))
+	err := a.Datastore.UpdateUserNameOnly(id, newName)
+	if err != nil {
+		return err
+	}
+	return a.record("user", id, AuditActionUpdate, before, snapshot(a.Datastore.GetUserByID(id)))
+}
+
+// UpdateUserProfile updates an existing User's profile fields and
+// records it in the audit log.
+func (a *AuditedDatastore) UpdateUserProfile(id UserID, avatarURL string, pronouns string, title string, organization string) error {
+	before := snapshot(a.Datastore.GetUserByID(id))
+	err := a.Datastore.UpdateUserProfile(id, avatarURL, pronouns, title, organization)
+	if err != nil {
+		return err
+	}
+	return a.record("user", id, AuditActionUpdate, before, snapshot(a.Datastore.GetUserByID(id)))
+}
+
+// UpdateUserAvatarOnly updates an existing User's avatar URL and
+// records it in the audit log.
+func (a *AuditedDatastore) UpdateUserAvatarOnly(id UserID, avatarURL string) error {
+	before := snapshot(a.Datastore.GetUserByID(id))
+	err := a.Datastore.UpdateUserAvatarOnly(id, avatarURL)
+	if err != nil {
+		return err
+	}
+	return a.record("user", id, AuditActionUpdate, before, snapshot(a.Datastore.GetUserByID(id)))
+}
+
+// SyncUsers syncs users from specs and records each added, updated
+// and disabled user in the audit log.
+func (a *AuditedDatastore) SyncUsers(specs []UserSpec, disableAbsent bool) (*SyncUsersResult, error) {
+	result, err := a.Datastore.SyncUsers(specs, disableAbsent)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range result.Added {
+		if err := a.record("user", id, AuditActionAdd, nil, snapshot(a.Datastore.GetUserByID(id))); err != nil {
+			return result, err
+		}
+	}
+	for _, ids := range [][]UserID{result.Updated, result.Disabled} {
+		for _, id := range ids {
+			if err := a.record("user", id, AuditActionUpdate, nil, snapshot(a.Datastore.GetUserByID(id))); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// DeleteUser deletes an existing User and records it in the audit log.
+func (a *AuditedDatastore) DeleteUser(id UserID) error {
+	before := snapshot(a.Datastore.GetUserByID(id))
+	err := a.Datastore.DeleteUser(id)
+	if err != nil {
+		return err
+	}
+	return a.record("user", id, AuditActionDelete, before, nil)
+}
+
+// AnonymizeUser anonymizes an existing User and records it in the
+// audit log. Only the anonymized state is recorded, so that the
+// personal fields being removed are not copied into the log.
+func (a *AuditedDatastore) AnonymizeUser(id UserID) error {
+	err := a.Datastore.AnonymizeUser(id)
+	if err != nil {
+		return err
+	}
+	return a.record("user", id, AuditActionUpdate, nil, snapshot(a.Datastore.GetUserByID(id)))
+}
+
+// ===== UserTokens =====
+
+// CreateToken creates a new API token and records it in the audit
+// log. The token itself is never recorded.
+func (a *AuditedDatastore) CreateToken(userID UserID, scopes []string, expiresAt time.Time) (uint32, string, error) {
+	tokenID, token, err := a.Datastore.CreateToken(userID, scopes, expiresAt)
+	if err != nil {
+		return 0, "", err
+	}
+	after := &UserToken{ID: tokenID, UserID: userID, Scopes: scopes, ExpiresAt: normalizeTime(expiresAt)}
+	return tokenID, token, a.record("user_token", tokenID, AuditActionAdd, nil, after)
+}
+
+// RevokeToken deletes an API token and records it in the audit log.
+func (a *AuditedDatastore) RevokeToken(id uint32) error {
+	err := a.Datastore.RevokeToken(id)
+	if err != nil {
+		return err
+	}
+	return a.record("user_token", id, AuditActionDelete, nil, nil)
+}
+
+// ===== UserIdentities =====
+
+// AddIdentity links a new identity to a User and records it in the
+// audit log.
+func (a *AuditedDatastore) AddIdentity(userID UserID, provider string, subject string, email string) (uint32, error) {
+	id, err := a.Datastore.AddIdentity(userID, provider, subject, email)
+	if err != nil {
+		return 0, err
+	}
+	after := &UserIdentity{ID: id, UserID: userID, Provider: provider, Subject: subject, Email: email}
+	return id, a.record("user_identity", id, AuditActionAdd, nil, after)
+}
+
+// DeleteIdentity unlinks an identity and records it in the audit log.
+func (a *AuditedDatastore) DeleteIdentity(id uint32) error {
+	err := a.Datastore.DeleteIdentity(id)
+	if err != nil {
+		return err
+	}
+	return a.record("user_identity", id, AuditActionDelete, nil, nil)
+}
+
+// ===== UserPreferences =====
+
+// SetUserPreference stores a user preference and records it in the
+// audit log.
+func (a *AuditedDatastore) SetUserPreference(userID UserID, key string, value interface{}) error {
+	before := snapshot(a.Datastore.GetUserPreference(userID, key))
+	err := a.Datastore.SetUserPreference(userID, key, value)
+	if err != nil {
+		return err
+	}
+	action := AuditActionUpdate
+	if before == nil {
+		action = AuditActionAdd
+	}
+	return a.record("user_preference", fmt.Sprintf("%d/%s", userID, key), action, before, value)
+}
+
+// DeleteUserPreference removes a user preference and records it in
+// the audit log.
+func (a *AuditedDatastore) DeleteUserPreference(userID UserID, key string) error {
+	before := snapshot(a.Datastore.GetUserPreference(userID, key))
+	err := a.Datastore.DeleteUserPreference(userID, key)
+	if err != nil {
+		return err
+	}
+	return a.record("user_preference", fmt.Sprintf("%d/%s", userID, key), AuditActionDelete, before, nil)
+}
+
+// ===== Invitations =====
+
+// CreateInvitation creates a new invitation and records it in the
+// audit log. The invitation token is never recorded.
+func (a *AuditedDatastore) CreateInvitation(inviterID UserID, email string, github string, accessLevel UserAccessLevel, expiresAt time.Time) (uint32, string, error) {
+	id, token, err := a.Datastore.CreateInvitation(inviterID, email, github, accessLevel, expiresAt)
+	if err != nil {
+		return 0, "", err
+	}
+	return id, token, a.record("invitation", id, AuditActionAdd, nil, snapshot(a.Datastore.GetInvitationByID(id)))
+}
+
+// AcceptInvitation accepts an invitation and records the User it
+// creates in the audit log.
+func (a *AuditedDatastore) AcceptInvitation(token string, userID UserID, name string, github string) (*User, error) {
+	u, err := a.Datastore.AcceptInvitation(token, userID, name, github)
+	if err != nil {
+		return nil, err
+	}
+	return u, a.record("user", u.ID, AuditActionAdd, nil, snapshot(a.Datastore.GetUserByID(u.ID)))
+}
+
+// ExpireInvitation expires a pending invitation and records it in
+// the audit log.
+func (a *AuditedDatastore) ExpireInvitation(id uint32) error {
+	before := snapshot(a.Datastore.GetInvitationByID(id))
+	err := a.Datastore.ExpireInvitation(id)
+	if err != nil {
+		return err
+	}
+	return a.record("invitation", id, AuditActionUpdate, before, snapshot(a.Datastore.GetInvitationByID(id)))
+}
+
+// ===== Projects =====
+
+// AddProject adds a new Project and records it in the audit log.
+func (a *AuditedDatastore) AddProject(name string, fullname string) (ProjectID, error) {
+	id, err := a.Datastore.AddProject(name, fullname)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("project", id, AuditActionAdd, nil, snapshot(a.Datastore.GetProjectByID(id)))
+}
+
+// GetOrCreateProject gets or adds a Project and, if it was added,
+// records it in the audit log.
+func (a *AuditedDatastore) GetOrCreateProject(name string, fullname string) (*Project, bool, error) {
+	p, created, err := a.Datastore.GetOrCreateProject(name, fullname)
+	if err != nil || !created {
+		return p, created, err
+	}
+	return p, created, a.record("project", p.ID, AuditActionAdd, nil, p)
+}
+
+// UpdateProject updates an existing Project and records it in the
+// audit log.
+func (a *AuditedDatastore) UpdateProject(id ProjectID, newName string, newFullname string) error {
+	before := snapshot(a.Datastore.GetProjectByID(id))
+	err := a.Datastore.UpdateProject(id, newName, newFullname)
+	if err != nil {
+		return err
+	}
+	return a.record("project", id, AuditActionUpdate, before, snapshot(a.Datastore.GetProjectByID(id)))
+}
+
+// SetProjectOrganization moves an existing Project between
+// organizations and records it in the audit log.
+func (a *AuditedDatastore) SetProjectOrganization(id ProjectID, orgID OrgID) error {
+	before := snapshot(a.Datastore.GetProjectByID(id))
+	err := a.Datastore.SetProjectOrganization(id, orgID)
+	if err != nil {
+		return err
+	}
+	return a.record("project", id, AuditActionUpdate, before, snapshot(a.Datastore.GetProjectByID(id)))
+}
+
+// DeleteProject deletes an existing Project and records it in the
+// audit log.
+func (a *AuditedDatastore) DeleteProject(id ProjectID) error {
+	before := snapshot(a.Datastore.GetProjectByID(id))
+	err := a.Datastore.DeleteProject(id)
+	if err != nil {
+		return err
+	}
+	return a.record("project", id, AuditActionDelete, before, nil)
+}
+
+// RestoreProject restores a soft-deleted Project and records it in
+// the audit log.
+func (a *AuditedDatastore) RestoreProject(id ProjectID) error {
+	err := a.Datastore.RestoreProject(id)
+	if err != nil {
+		return err
+	}
+	return a.record("project", id, AuditActionAdd, nil, snapshot(a.Datastore.GetProjectByID(id)))
+}
+
+// PurgeProject permanently deletes a soft-deleted Project and
+// records it in the audit log. No snapshot is recorded, since
+// soft-deleted projects cannot be retrieved.
+func (a *AuditedDatastore) PurgeProject(id ProjectID) error {
+	err := a.Datastore.PurgeProject(id)
+	if err != nil {
+		return err
+	}
+	return a.record("project", id, AuditActionDelete, nil, nil)
+}
+
+// PurgeDeletedBefore permanently deletes all projects, subprojects
+// and repos soft-deleted before the given time, and records a single
+// entry in the audit log with the cutoff and the number purged.
+func (a *AuditedDatastore) PurgeDeletedBefore(before time.Time) (int64, error) {
+	purged, err := a.Datastore.PurgeDeletedBefore(before)
+	if err != nil {
+		return purged, err
+	}
+	return purged, a.record("soft_deleted", before.UTC().Format(time.RFC3339), AuditActionDelete,
+		nil, map[string]interface{}{"purged": purged})
+}
+
+// ===== ProjectAccess =====
+
+// GrantProjectAccess grants project access and records it in the
+// audit log.
+func (a *AuditedDatastore) GrantProjectAccess(userID UserID, projectID ProjectID, accessLevel UserAccessLevel) error {
+	err := a.Datastore.GrantProjectAccess(userID, projectID, accessLevel)
+	if err != nil {
+		return err
+	}
+	after := &ProjectAccess{UserID: userID, ProjectID: projectID, AccessLevel: accessLevel}
+	return a.record("project_access", fmt.Sprintf("%d/%d", userID, projectID), AuditActionUpdate, nil, after)
+}
+
+// RevokeProjectAccess revokes project access and records it in the
+// audit log.
+func (a *AuditedDatastore) RevokeProjectAccess(userID UserID, projectID ProjectID) error {
+	err := a.Datastore.RevokeProjectAccess(userID, projectID)
+	if err != nil {
+		return err
+	}
+	return a.record("project_access", fmt.Sprintf("%d/%d", userID, projectID), AuditActionDelete, nil, nil)
+}
+
+// ===== ProjectPipelines =====
+
+// SetProjectPipeline stores a project's default pipeline and records
+// it in the audit log.
+func (a *AuditedDatastore) SetProjectPipeline(projectID ProjectID, pipeline []JobTemplate) error {
+	before := snapshot(a.Datastore.GetProjectPipeline(projectID))
+	err := a.Datastore.SetProjectPipeline(projectID, pipeline)
+	if err != nil {
+		return err
+	}
+	action := AuditActionUpdate
+	if before == nil {
+		action = AuditActionAdd
+	}
+	return a.record("project_pipeline", projectID, action, before, snapshot(a.Datastore.GetProjectPipeline(projectID)))
+}
+
+// DeleteProjectPipeline removes a project's default pipeline and
+// records it in the audit log.
+func (a *AuditedDatastore) DeleteProjectPipeline(projectID ProjectID) error {
+	before := snapshot(a.Datastore.GetProjectPipeline(projectID))
+	err := a.Datastore.DeleteProjectPipeline(projectID)
+	if err != nil {
+		return err
+	}
+	return a.record("project_pipeline", projectID, AuditActionDelete, before, nil)
+}
+
+// ===== Subprojects =====
+
+// AddSubproject adds a new Subproject and records it in the audit log.
+func (a *AuditedDatastore) AddSubproject(projectID ProjectID, name string, fullname string) (uint32, error) {
+	id, err := a.Datastore.AddSubproject(projectID, name, fullname)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("subproject", id, AuditActionAdd, nil, snapshot(a.Datastore.GetSubprojectByID(id)))
+}
+
+// GetOrCreateSubproject gets or adds a Subproject and, if it was
+// added, records it in the audit log.
+func (a *AuditedDatastore) GetOrCreateSubproject(projectID ProjectID, name string, fullname string) (*Subproject, bool, error) {
+	sp, created, err := a.Datastore.GetOrCreateSubproject(projectID, name, fullname)
+	if err != nil || !created {
+		return sp, created, err
+	}
+	return sp, created, a.record("subproject", sp.ID, AuditActionAdd, nil, sp)
+}
+
+// UpdateSubproject updates an existing Subproject and records it in
+// the audit log.
+func (a *AuditedDatastore) UpdateSubproject(id uint32, newName string, newFullname string) error {
+	before := snapshot(a.Datastore.GetSubprojectByID(id))
+	err := a.Datastore.UpdateSubproject(id, newName, newFullname)
+	if err != nil {
+		return err
+	}
+	return a.record("subproject", id, AuditActionUpdate, before, snapshot(a.Datastore.GetSubprojectByID(id)))
+}
+
+// UpdateSubprojectProjectID moves an existing Subproject to another
+// Project and records it in the audit log.
+func (a *AuditedDatastore) UpdateSubprojectProjectID(id uint32, newProjectID ProjectID) error {
+	before := snapshot(a.Datastore.GetSubprojectByID(id))
+	err := a.Datastore.UpdateSubprojectProjectID(id, newProjectID)
+	if err != nil {
+		return err
+	}
+	return a.record("subproject", id, AuditActionUpdate, before, snapshot(a.Datastore.GetSubprojectByID(id)))
+}
+
+// DeleteSubproject deletes an existing Subproject and records it in
+// the audit log.
+func (a *AuditedDatastore) DeleteSubproject(id uint32) error {
+	before := snapshot(a.Datastore.GetSubprojectByID(id))
+	err := a.Datastore.DeleteSubproject(id)
+	if err != nil {
+		return err
+	}
+	return a.record("subproject", id, AuditActionDelete, before, nil)
+}
+
+// RestoreSubproject restores a soft-deleted Subproject and records
+// it in the audit log.
+func (a *AuditedDatastore) RestoreSubproject(id uint32) error {
+	err := a.Datastore.RestoreSubproject(id)
+	if err != nil {
+		return err
+	}
+	return a.record("subproject", id, AuditActionAdd, nil, snapshot(a.Datastore.GetSubprojectByID(id)))
+}
+
+// PurgeSubproject permanently deletes a soft-deleted Subproject and
+// records it in the audit log.
+func (a *AuditedDatastore) PurgeSubproject(id uint32) error {
+	err := a.Datastore.PurgeSubproject(id)
+	if err != nil {
+		return err
+	}
+	return a.record("subproject", id, AuditActionDelete, nil, nil)
+}
+
+// ===== Repos =====
+
+// AddRepo adds a new Repo and records it in the audit log.
+func (a *AuditedDatastore) AddRepo(subprojectID uint32, name string, address string) (RepoID, error) {
+	id, err := a.Datastore.AddRepo(subprojectID, name, address)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("repo", id, AuditActionAdd, nil, snapshot(a.Datastore.GetRepoByID(id)))
+}
+
+// GetOrCreateRepo gets or adds a Repo and, if it was added, records
+// it in the audit log.
+func (a *AuditedDatastore) GetOrCreateRepo(subprojectID uint32, name string, address string) (*Repo, bool, error) {
+	r, created, err := a.Datastore.GetOrCreateRepo(subprojectID, name, address)
+	if err != nil || !created {
+		return r, created, err
+	}
+	return r, created, a.record("repo", r.ID, AuditActionAdd, nil, r)
+}
+
+// AddRepoWithUUID adds a new Repo with a client-supplied UUID and,
+// if it was not already added, records it in the audit log.
+func (a *AuditedDatastore) AddRepoWithUUID(externalUUID string, subprojectID uint32, name string, address string) (RepoID, bool, error) {
+	id, created, err := a.Datastore.AddRepoWithUUID(externalUUID, subprojectID, name, address)
+	if err != nil || !created {
+		return id, created, err
+	}
+	return id, created, a.record("repo", id, AuditActionAdd, nil, snapshot(a.Datastore.GetRepoByID(id)))
+}
+
+// UpdateRepo updates an existing Repo and records it in the audit log.
+func (a *AuditedDatastore) UpdateRepo(id RepoID, newName string, newAddress string) error {
+	before := snapshot(a.Datastore.GetRepoByID(id))
+	err := a.Datastore.UpdateRepo(id, newName, newAddress)
+	if err != nil {
+		return err
+	}
+	return a.record("repo", id, AuditActionUpdate, before, snapshot(a.Datastore.GetRepoByID(id)))
+}
+
+// UpdateRepoSubprojectID moves an existing Repo to another Subproject
+// and records it in the audit log.
+func (a *AuditedDatastore) UpdateRepoSubprojectID(id RepoID, newSubprojectID uint32) error {
+	before := snapshot(a.Datastore.GetRepoByID(id))
+	err := a.Datastore.UpdateRepoSubprojectID(id, newSubprojectID)
+	if err != nil {
+		return err
+	}
+	return a.record("repo", id, AuditActionUpdate, before, snapshot(a.Datastore.GetRepoByID(id)))
+}
+
+// DeleteRepo deletes an existing Repo and records it in the audit log.
+func (a *AuditedDatastore) DeleteRepo(id RepoID) error {
+	before := snapshot(a.Datastore.GetRepoByID(id))
+	err := a.Datastore.DeleteRepo(id)
+	if err != nil {
+		return err
+	}
+	return a.record("repo", id, AuditActionDelete, before, nil)
+}
+
+// RestoreRepo restores a soft-deleted Repo and records it in the
+// audit log.
+func (a *AuditedDatastore) RestoreRepo(id RepoID) error {
+	err := a.Datastore.RestoreRepo(id)
+	if err != nil {
+		return err
+	}
+	return a.record("repo", id, AuditActionAdd, nil, snapshot(a.Datastore.GetRepoByID(id)))
+}
+
+// PurgeRepo permanently deletes a soft-deleted Repo and records it
+// in the audit log.
+func (a *AuditedDatastore) PurgeRepo(id RepoID) error {
+	err := a.Datastore.PurgeRepo(id)
+	if err != nil {
+		return err
+	}
+	return a.record("repo", id, AuditActionDelete, nil, nil)
+}
+
+// ===== RepoBranches =====
+
+// AddRepoBranch adds a new RepoBranch and records it in the audit log.
+func (a *AuditedDatastore) AddRepoBranch(repoID RepoID, branch string) error {
+	err := a.Datastore.AddRepoBranch(repoID, branch)
+	if err != nil {
+		return err
+	}
+	after := &RepoBranch{RepoID: repoID, Branch: branch}
+	return a.record("repo_branch", fmt.Sprintf("%d/%s", repoID, branch), AuditActionAdd, nil, after)
+}
+
+// UpsertRepoBranch adds a RepoBranch if it is not already present
+// and, if it was added, records it in the audit log.
+func (a *AuditedDatastore) UpsertRepoBranch(repoID RepoID, branch string) (bool, error) {
+	added, err := a.Datastore.UpsertRepoBranch(repoID, branch)
+	if err != nil || !added {
+		return added, err
+	}
+	after := &RepoBranch{RepoID: repoID, Branch: branch}
+	return added, a.record("repo_branch", fmt.Sprintf("%d/%s", repoID, branch), AuditActionAdd, nil, after)
+}
+
+// DeleteRepoBranch deletes an existing RepoBranch and records it in
+// the audit log.
+func (a *AuditedDatastore) DeleteRepoBranch(repoID RepoID, branch string) error {
+	err := a.Datastore.DeleteRepoBranch(repoID, branch)
+	if err != nil {
+		return err
+	}
+	before := &RepoBranch{RepoID: repoID, Branch: branch}
+	return a.record("repo_branch", fmt.Sprintf("%d/%s", repoID, branch), AuditActionDelete, before, nil)
+}
+
+// ===== RepoPulls =====
+
+// AddRepoPull adds a new RepoPull and records it in the audit log.
+func (a *AuditedDatastore) AddRepoPull(repoID RepoID, branch string, commit string, tag string, spdxID string) (RepoPullID, error) {
+	id, err := a.Datastore.AddRepoPull(repoID, branch, commit, tag, spdxID)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("repo_pull", id, AuditActionAdd, nil, snapshot(a.Datastore.GetRepoPullByID(id)))
+}
+
+// AddFullRepoPull adds a new RepoPull with full data and records it
+// in the audit log.
+func (a *AuditedDatastore) AddFullRepoPull(repoID RepoID, branch string, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string, commit string, tag string, spdxID string) (RepoPullID, error) {
+	id, err := a.Datastore.AddFullRepoPull(repoID, branch, startedAt, finishedAt, status, health, output, commit, tag, spdxID)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("repo_pull", id, AuditActionAdd, nil, snapshot(a.Datastore.GetRepoPullByID(id)))
+}
+
+// AddRepoPullWithUUID adds a new RepoPull with a client-supplied UUID
+// and, if it was not already added, records it in the audit log.
+func (a *AuditedDatastore) AddRepoPullWithUUID(externalUUID string, repoID RepoID, branch string, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string, commit string, tag string, spdxID string) (RepoPullID, bool, error) {
+	id, created, err := a.Datastore.AddRepoPullWithUUID(externalUUID, repoID, branch, startedAt, finishedAt, status, health, output, commit, tag, spdxID)
+	if err != nil || !created {
+		return id, created, err
+	}
+	return id, created, a.record("repo_pull", id, AuditActionAdd, nil, snapshot(a.Datastore.GetRepoPullByID(id)))
+}
+
+// AddRepoPullWithPipeline adds a new RepoPull with its pipeline of
+// Jobs and records each of them in the audit log.
+func (a *AuditedDatastore) AddRepoPullWithPipeline(repoID RepoID, branch string, commit string, tag string, spdxID string, pipeline []JobTemplate) (RepoPullID, []JobID, error) {
+	return addRepoPullWithPipeline(a, repoID, branch, commit, tag, spdxID, pipeline)
+}
+
+// AddRepoPullWithDefaultPipeline adds a new RepoPull with the
+// default pipeline of Jobs of its project and records each of them
+// in the audit log.
+func (a *AuditedDatastore) AddRepoPullWithDefaultPipeline(repoID RepoID, branch string, commit string, tag string, spdxID string) (RepoPullID, []JobID, error) {
+	return addRepoPullWithDefaultPipeline(a, repoID, branch, commit, tag, spdxID)
+}
+
+// UpdateRepoPullStatus updates an existing RepoPull's status and
+// records it in the audit log.
+func (a *AuditedDatastore) UpdateRepoPullStatus(id RepoPullID, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string) error {
+	before := snapshot(a.Datastore.GetRepoPullByID(id))
+	err := a.Datastore.UpdateRepoPullStatus(id, startedAt, finishedAt, status, health, output)
+	if err != nil {
+		return err
+	}
+	return a.record("repo_pull", id, AuditActionUpdate, before, snapshot(a.Datastore.GetRepoPullByID(id)))
+}
+
+// DeleteRepoPull deletes an existing RepoPull and records it in the
+// audit log.
+func (a *AuditedDatastore) DeleteRepoPull(id RepoPullID) error {
+	before := snapshot(a.Datastore.GetRepoPullByID(id))
+	err := a.Datastore.DeleteRepoPull(id)
+	if err != nil {
+		return err
+	}
+	return a.record("repo_pull", id, AuditActionDelete, before, nil)
+}
+
+// DeleteRepoPullInBatches deletes an existing RepoPull in batches
+// and records it in the audit log.
+func (a *AuditedDatastore) DeleteRepoPullInBatches(id RepoPullID, batchSize int, progress DeleteProgressFunc) error {
+	before := snapshot(a.Datastore.GetRepoPullByID(id))
+	err := a.Datastore.DeleteRepoPullInBatches(id, batchSize, progress)
+	if err != nil {
+		return err
+	}
+	return a.record("repo_pull", id, AuditActionDelete, before, nil)
+}
+
+// ===== FileHashes =====
+
+// AddFileHash adds a new FileHash and records it in the audit log.
+func (a *AuditedDatastore) AddFileHash(sha256 string, sha1 string) (uint64, error) {
+	id, err := a.Datastore.AddFileHash(sha256, sha1)
+	if err != nil {
+		return 0, err
+	}
+	after := &FileHash{ID: id, HashSHA256: sha256, HashSHA1: sha1}
+	return id, a.record("file_hash", id, AuditActionAdd, nil, after)
+}
+
+// DeleteFileHash deletes an existing FileHash and records it in the
+// audit log.
+func (a *AuditedDatastore) DeleteFileHash(id uint64) error {
+	before := snapshot(a.Datastore.GetFileHashByID(id))
+	err := a.Datastore.DeleteFileHash(id)
+	if err != nil {
+		return err
+	}
+	return a.record("file_hash", id, AuditActionDelete, before, nil)
+}
+
+// ===== FileInstances =====
+
+// AddFileInstance adds a new FileInstance and records it in the
+// audit log.
+func (a *AuditedDatastore) AddFileInstance(repoPullID RepoPullID, fileHashID uint64, path string) (uint64, error) {
+	id, err := a.Datastore.AddFileInstance(repoPullID, fileHashID, path)
+	if err != nil {
+		return 0, err
+	}
+	after := &FileInstance{ID: id, RepoPullID: repoPullID, FileHashID: fileHashID, Path: path}
+	return id, a.record("file_instance", id, AuditActionAdd, nil, after)
+}
+
+// DeleteFileInstance deletes an existing FileInstance and records it
+// in the audit log.
+func (a *AuditedDatastore) DeleteFileInstance(id uint64) error {
+	before := snapshot(a.Datastore.GetFileInstanceByID(id))
+	err := a.Datastore.DeleteFileInstance(id)
+	if err != nil {
+		return err
+	}
+	return a.record("file_instance", id, AuditActionDelete, before, nil)
+}
+
+// ===== Agents =====
+
+// AddAgent adds a new Agent and records it in the audit log.
+func (a *AuditedDatastore) AddAgent(name string, isActive bool, address string, port int, isCodeReader bool, isSpdxReader bool, isCodeWriter bool, isSpdxWriter bool) (AgentID, error) {
+	id, err := a.Datastore.AddAgent(name, isActive, address, port, isCodeReader, isSpdxReader, isCodeWriter, isSpdxWriter)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("agent", id, AuditActionAdd, nil, snapshot(a.Datastore.GetAgentByID(id)))
+}
+
+// UpdateAgentStatus updates an existing Agent's status and records it
+// in the audit log.
+func (a *AuditedDatastore) UpdateAgentStatus(id AgentID, version uint32, isActive bool, address string, port int) error {
+	before := snapshot(a.Datastore.GetAgentByID(id))
+	err := a.Datastore.UpdateAgentStatus(id, version, isActive, address, port)
+	if err != nil {
+		return err
+	}
+	return a.record("agent", id, AuditActionUpdate, before, snapshot(a.Datastore.GetAgentByID(id)))
+}
+
+// UpdateAgentAbilities updates an existing Agent's abilities and
+// records it in the audit log.
+func (a *AuditedDatastore) UpdateAgentAbilities(id AgentID, isCodeReader bool, isSpdxReader bool, isCodeWriter bool, isSpdxWriter bool) error {
+	before := snapshot(a.Datastore.GetAgentByID(id))
+	err := a.Datastore.UpdateAgentAbilities(id, isCodeReader, isSpdxReader, isCodeWriter, isSpdxWriter)
+	if err != nil {
+		return err
+	}
+	return a.record("agent", id, AuditActionUpdate, before, snapshot(a.Datastore.GetAgentByID(id)))
+}
+
+// UpdateAgentHealth updates an existing Agent's health and records it
+// in the audit log.
+func (a *AuditedDatastore) UpdateAgentHealth(id AgentID, health AgentHealth, output string) error {
+	before := snapshot(a.Datastore.GetAgentByID(id))
+	err := a.Datastore.UpdateAgentHealth(id, health, output)
+	if err != nil {
+		return err
+	}
+	return a.record("agent", id, AuditActionUpdate, before, snapshot(a.Datastore.GetAgentByID(id)))
+}
+
+// DeleteAgent deletes an existing Agent and records it in the audit log.
+func (a *AuditedDatastore) DeleteAgent(id AgentID) error {
+	before := snapshot(a.Datastore.GetAgentByID(id))
+	err := a.Datastore.DeleteAgent(id)
+	if err != nil {
+		return err
+	}
+	return a.record("agent", id, AuditActionDelete, before, nil)
+}
+
+// DeleteAgentWithPolicy deletes an existing Agent according to the
+// given jobs policy and records it in the audit log.
+func (a *AuditedDatastore) DeleteAgentWithPolicy(id AgentID, policy AgentJobsPolicy, reassignToID AgentID) error {
+	before := snapshot(a.Datastore.GetAgentByID(id))
+	err := a.Datastore.DeleteAgentWithPolicy(id, policy, reassignToID)
+	if err != nil {
+		return err
+	}
+	return a.record("agent", id, AuditActionDelete, before, nil)
+}
+
+// ===== Jobs =====
+
+// AddJob adds a new Job and records it in the audit log.
+func (a *AuditedDatastore) AddJob(repoPullID RepoPullID, agentID AgentID, priorJobIDs []JobID) (JobID, error) {
+	id, err := a.Datastore.AddJob(repoPullID, agentID, priorJobIDs)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("job", id, AuditActionAdd, nil, snapshot(a.Datastore.GetJobByID(id)))
+}
+
+// AddJobWithConfigs adds a new Job with configs and records it in the
+// audit log.
+func (a *AuditedDatastore) AddJobWithConfigs(repoPullID RepoPullID, agentID AgentID, priorJobIDs []JobID, configKV map[string]string, configCodeReader map[string]JobPathConfig, configSpdxReader map[string]JobPathConfig) (JobID, error) {
+	id, err := a.Datastore.AddJobWithConfigs(repoPullID, agentID, priorJobIDs, configKV, configCodeReader, configSpdxReader)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("job", id, AuditActionAdd, nil, snapshot(a.Datastore.GetJobByID(id)))
+}
+
+// AddJobWithUUID adds a new Job with a client-supplied UUID and, if
+// it was not already added, records it in the audit log.
+func (a *AuditedDatastore) AddJobWithUUID(externalUUID string, repoPullID RepoPullID, agentID AgentID, priorJobIDs []JobID, configKV map[string]string, configCodeReader map[string]JobPathConfig, configSpdxReader map[string]JobPathConfig) (JobID, bool, error) {
+	id, created, err := a.Datastore.AddJobWithUUID(externalUUID, repoPullID, agentID, priorJobIDs, configKV, configCodeReader, configSpdxReader)
+	if err != nil || !created {
+		return id, created, err
+	}
+	return id, created, a.record("job", id, AuditActionAdd, nil, snapshot(a.Datastore.GetJobByID(id)))
+}
+
+// UpdateJobIsReady updates an existing Job's readiness and records it
+// in the audit log.
+func (a *AuditedDatastore) UpdateJobIsReady(id JobID, ready bool) error {
+	before := snapshot(a.Datastore.GetJobByID(id))
+	err := a.Datastore.UpdateJobIsReady(id, ready)
+	if err != nil {
+		return err
+	}
+	return a.record("job", id, AuditActionUpdate, before, snapshot(a.Datastore.GetJobByID(id)))
+}
+
+// UpdateJobStatus updates an existing Job's status and records it in
+// the audit log.
+func (a *AuditedDatastore) UpdateJobStatus(id JobID, version uint32, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string) error {
+	before := snapshot(a.Datastore.GetJobByID(id))
+	err := a.Datastore.UpdateJobStatus(id, version, startedAt, finishedAt, status, health, output)
+	if err != nil {
+		return err
+	}
+	return a.record("job", id, AuditActionUpdate, before, snapshot(a.Datastore.GetJobByID(id)))
+}
+
+// UpdateJobStatuses updates several existing Jobs' statuses and
+// records each job that was updated in the audit log.
+func (a *AuditedDatastore) UpdateJobStatuses(updates []JobStatusUpdate) (map[JobID]error, error) {
+	ids := make([]JobID, 0, len(updates))
+	for _, u := range updates {
+		ids = append(ids, u.ID)
+	}
+	before := a.jobsByID(ids)
+	failed, err := a.Datastore.UpdateJobStatuses(updates)
+	if err != nil {
+		return failed, err
+	}
+	after := a.jobsByID(ids)
+	for _, id := range ids {
+		if _, ok := failed[id]; ok {
+			continue
+		}
+		if err = a.record("job", id, AuditActionUpdate, before[id], after[id]); err != nil {
+			return failed, err
+		}
+	}
+	return failed, nil
+}
+
+// jobsByID returns a best-effort snapshot of the jobs with the given
+// IDs, keyed by ID. Jobs that cannot be retrieved are left out.
+func (a *AuditedDatastore) jobsByID(ids []JobID) map[JobID]interface{} {
+	jobs := map[JobID]interface{}{}
+	js, err := a.Datastore.GetJobsByIDs(ids)
+	if err != nil {
+		return jobs
+	}
+	for _, j := range js {
+		jobs[j.ID] = j
+	}
+	return jobs
+}
+
+// DeleteJob deletes an existing Job and records it in the audit log.
+func (a *AuditedDatastore) DeleteJob(id JobID) error {
+	before := snapshot(a.Datastore.GetJobByID(id))
+	err := a.Datastore.DeleteJob(id)
+	if err != nil {
+		return err
+	}
+	return a.record("job", id, AuditActionDelete, before, nil)
+}
+
+// DeleteJobInBatches deletes an existing Job in batches and records
+// it in the audit log.
+func (a *AuditedDatastore) DeleteJobInBatches(id JobID, batchSize int, progress DeleteProgressFunc) error {
+	before := snapshot(a.Datastore.GetJobByID(id))
+	err := a.Datastore.DeleteJobInBatches(id, batchSize, progress)
+	if err != nil {
+		return err
+	}
+	return a.record("job", id, AuditActionDelete, before, nil)
+}
+
+// ===== Licenses =====
+
+// AddCustomLicense adds a new custom License and records it in the
+// audit log.
+func (a *AuditedDatastore) AddCustomLicense(spdxID string, name string) (uint32, error) {
+	id, err := a.Datastore.AddCustomLicense(spdxID, name)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("license", id, AuditActionAdd, nil, snapshot(a.Datastore.GetLicenseByID(id)))
+}
+
+// UpdateCustomLicense updates an existing custom License and records
+// it in the audit log.
+func (a *AuditedDatastore) UpdateCustomLicense(id uint32, newName string) error {
+	before := snapshot(a.Datastore.GetLicenseByID(id))
+	err := a.Datastore.UpdateCustomLicense(id, newName)
+	if err != nil {
+		return err
+	}
+	return a.record("license", id, AuditActionUpdate, before, snapshot(a.Datastore.GetLicenseByID(id)))
+}
+
+// DeleteCustomLicense deletes an existing custom License and records
+// it in the audit log.
+func (a *AuditedDatastore) DeleteCustomLicense(id uint32) error {
+	before := snapshot(a.Datastore.GetLicenseByID(id))
+	err := a.Datastore.DeleteCustomLicense(id)
+	if err != nil {
+		return err
+	}
+	return a.record("license", id, AuditActionDelete, before, nil)
+}
+
+// ===== Conclusions =====
+
+// SetConclusion sets the Conclusion for a FileHash and records it in
+// the audit log.
+func (a *AuditedDatastore) SetConclusion(fileHashID uint64, licenseExpression string, concludedBy UserID, justification string) (uint64, error) {
+	before := snapshot(a.Datastore.GetConclusionForFileHash(fileHashID))
+	id, err := a.Datastore.SetConclusion(fileHashID, licenseExpression, concludedBy, justification)
+	if err != nil {
+		return 0, err
+	}
+	action := AuditActionUpdate
+	if before == nil {
+		action = AuditActionAdd
+	}
+	return id, a.record("conclusion", fileHashID, action, before, snapshot(a.Datastore.GetConclusionForFileHash(fileHashID)))
+}
+
+// DeleteConclusion deletes the Conclusion for a FileHash and records
+// it in the audit log.
+func (a *AuditedDatastore) DeleteConclusion(fileHashID uint64) error {
+	before := snapshot(a.Datastore.GetConclusionForFileHash(fileHashID))
+	err := a.Datastore.DeleteConclusion(fileHashID)
+	if err != nil {
+		return err
+	}
+	return a.record("conclusion", fileHashID, AuditActionDelete, before, nil)
+}
+
+// ===== Components =====
+
+// UpdateComponent updates an existing Component and records it in
+// the audit log.
+func (a *AuditedDatastore) UpdateComponent(c *Component) error {
+	before := snapshot(a.Datastore.GetComponentByID(c.ID))
+	err := a.Datastore.UpdateComponent(c)
+	if err != nil {
+		return err
+	}
+	return a.record("component", c.ID, AuditActionUpdate, before, snapshot(a.Datastore.GetComponentByID(c.ID)))
+}
+
+// DeleteComponent deletes an existing Component and records it in
+// the audit log.
+func (a *AuditedDatastore) DeleteComponent(id uint64) error {
+	before := snapshot(a.Datastore.GetComponentByID(id))
+	err := a.Datastore.DeleteComponent(id)
+	if err != nil {
+		return err
+	}
+	return a.record("component", id, AuditActionDelete, before, nil)
+}
+
+// ===== Policies =====
+
+// AddPolicy adds a new Policy and records it in the audit log.
+func (a *AuditedDatastore) AddPolicy(projectID ProjectID, name string, rules PolicyRules, createdBy UserID) (uint32, error) {
+	id, err := a.Datastore.AddPolicy(projectID, name, rules, createdBy)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("policy", id, AuditActionAdd, nil, snapshot(a.Datastore.GetPolicyByID(id)))
+}
+
+// UpdatePolicyRules creates a new version of an existing Policy and
+// records it in the audit log.
+func (a *AuditedDatastore) UpdatePolicyRules(id uint32, rules PolicyRules, updatedBy UserID) (uint32, error) {
+	before := snapshot(a.Datastore.GetPolicyByID(id))
+	version, err := a.Datastore.UpdatePolicyRules(id, rules, updatedBy)
+	if err != nil {
+		return 0, err
+	}
+	return version, a.record("policy", id, AuditActionUpdate, before, snapshot(a.Datastore.GetPolicyByID(id)))
+}
+
+// DeletePolicy deletes an existing Policy and records it in the
+// audit log.
+func (a *AuditedDatastore) DeletePolicy(id uint32) error {
+	before := snapshot(a.Datastore.GetPolicyByID(id))
+	err := a.Datastore.DeletePolicy(id)
+	if err != nil {
+		return err
+	}
+	return a.record("policy", id, AuditActionDelete, before, nil)
+}
+
+// ===== NoticeDocuments =====
+
+// DeleteNoticeDocument deletes the record of an existing
+// NoticeDocument and records it in the audit log.
+func (a *AuditedDatastore) DeleteNoticeDocument(id uint64) error {
+	before := snapshot(a.Datastore.GetNoticeDocumentByID(id))
+	err := a.Datastore.DeleteNoticeDocument(id)
+	if err != nil {
+		return err
+	}
+	return a.record("notice_document", id, AuditActionDelete, before, nil)
+}
+
+// ===== Webhooks =====
+
+// AddWebhook adds a new Webhook and records it in the audit log.
+func (a *AuditedDatastore) AddWebhook(projectID ProjectID, url string, secret string, eventTypes []string) (uint32, error) {
+	id, err := a.Datastore.AddWebhook(projectID, url, secret, eventTypes)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("webhook", id, AuditActionAdd, nil, snapshot(a.Datastore.GetWebhookByID(id)))
+}
+
+// UpdateWebhook updates an existing Webhook and records it in the
+// audit log.
+func (a *AuditedDatastore) UpdateWebhook(w *Webhook) error {
+	before := snapshot(a.Datastore.GetWebhookByID(w.ID))
+	err := a.Datastore.UpdateWebhook(w)
+	if err != nil {
+		return err
+	}
+	return a.record("webhook", w.ID, AuditActionUpdate, before, snapshot(a.Datastore.GetWebhookByID(w.ID)))
+}
+
+// DeleteWebhook deletes an existing Webhook and records it in the
+// audit log.
+func (a *AuditedDatastore) DeleteWebhook(id uint32) error {
+	before := snapshot(a.Datastore.GetWebhookByID(id))
+	err := a.Datastore.DeleteWebhook(id)
+	if err != nil {
+		return err
+	}
+	return a.record("webhook", id, AuditActionDelete, before, nil)
+}
+
+// ===== Comments =====
+
+// AddComment adds a new Comment and records it in the audit log.
+func (a *AuditedDatastore) AddComment(authorID UserID, targetType string, targetID uint64, body string) (uint64, error) {
+	id, err := a.Datastore.AddComment(authorID, targetType, targetID, body)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("comment", id, AuditActionAdd, nil, snapshot(a.Datastore.GetCommentByID(id)))
+}
+
+// UpdateCommentBody updates the body of an existing Comment and
+// records it in the audit log.
+func (a *AuditedDatastore) UpdateCommentBody(id uint64, body string) error {
+	before := snapshot(a.Datastore.GetCommentByID(id))
+	err := a.Datastore.UpdateCommentBody(id, body)
+	if err != nil {
+		return err
+	}
+	return a.record("comment", id, AuditActionUpdate, before, snapshot(a.Datastore.GetCommentByID(id)))
+}
+
+// SetCommentResolved marks an existing Comment as resolved or
+// unresolved and records it in the audit log.
+func (a *AuditedDatastore) SetCommentResolved(id uint64, resolved bool) error {
+	before := snapshot(a.Datastore.GetCommentByID(id))
+	err := a.Datastore.SetCommentResolved(id, resolved)
+	if err != nil {
+		return err
+	}
+	return a.record("comment", id, AuditActionUpdate, before, snapshot(a.Datastore.GetCommentByID(id)))
+}
+
+// DeleteComment deletes an existing Comment and records it in the
+// audit log.
+func (a *AuditedDatastore) DeleteComment(id uint64) error {
+	before := snapshot(a.Datastore.GetCommentByID(id))
+	err := a.Datastore.DeleteComment(id)
+	if err != nil {
+		return err
+	}
+	return a.record("comment", id, AuditActionDelete, before, nil)
+}
+
+// ===== Reviews =====
+
+// RequestReview requests a new Review and records it in the audit
+// log.
+func (a *AuditedDatastore) RequestReview(repoPullID RepoPullID, reviewerID UserID) (uint32, error) {
+	id, err := a.Datastore.RequestReview(repoPullID, reviewerID)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("review", id, AuditActionAdd, nil, snapshot(a.Datastore.GetReviewByID(id)))
+}
+
+// DecideReview approves or rejects an existing Review and records
+// it in the audit log.
+func (a *AuditedDatastore) DecideReview(id uint32, state ReviewState, notes string) error {
+	before := snapshot(a.Datastore.GetReviewByID(id))
+	err := a.Datastore.DecideReview(id, state, notes)
+	if err != nil {
+		return err
+	}
+	return a.record("review", id, AuditActionUpdate, before, snapshot(a.Datastore.GetReviewByID(id)))
+}
+
+// ===== Reports =====
+
+// AddReport adds a new Report request and records it in the audit
+// log.
+func (a *AuditedDatastore) AddReport(reportType string, parameters json.RawMessage, requestedBy UserID) (uint32, error) {
+	id, err := a.Datastore.AddReport(reportType, parameters, requestedBy)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("report", id, AuditActionAdd, nil, snapshot(a.Datastore.GetReportByID(id)))
+}
+
+// UpdateReportStatus updates the status of an existing Report and
+// records it in the audit log.
+func (a *AuditedDatastore) UpdateReportStatus(id uint32, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string, artifactURI string) error {
+	before := snapshot(a.Datastore.GetReportByID(id))
+	err := a.Datastore.UpdateReportStatus(id, startedAt, finishedAt, status, health, output, artifactURI)
+	if err != nil {
+		return err
+	}
+	return a.record("report", id, AuditActionUpdate, before, snapshot(a.Datastore.GetReportByID(id)))
+}
+
+// DeleteReport deletes an existing Report and records it in the
+// audit log.
+func (a *AuditedDatastore) DeleteReport(id uint32) error {
+	before := snapshot(a.Datastore.GetReportByID(id))
+	err := a.Datastore.DeleteReport(id)
+	if err != nil {
+		return err
+	}
+	return a.record("report", id, AuditActionDelete, before, nil)
+}
+
+// ===== IssueLinks =====
+
+// AddIssueLink adds a new IssueLink and records it in the audit log.
+func (a *AuditedDatastore) AddIssueLink(entity string, entityID string, issueURL string) (uint32, error) {
+	id, err := a.Datastore.AddIssueLink(entity, entityID, issueURL)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("issue_link", id, AuditActionAdd, nil, snapshot(a.Datastore.GetIssueLinkByID(id)))
+}
+
+// DeleteIssueLink deletes an existing IssueLink and records it in
+// the audit log.
+func (a *AuditedDatastore) DeleteIssueLink(id uint32) error {
+	before := snapshot(a.Datastore.GetIssueLinkByID(id))
+	err := a.Datastore.DeleteIssueLink(id)
+	if err != nil {
+		return err
+	}
+	return a.record("issue_link", id, AuditActionDelete, before, nil)
+}
+
+// ===== Labels =====
+
+// SetLabel sets a label on an entity and records it in the audit
+// log.
+func (a *AuditedDatastore) SetLabel(target Labelable, key string, value string) error {
+	before := snapshot(a.Datastore.GetLabel(target, key))
+	err := a.Datastore.SetLabel(target, key, value)
+	if err != nil {
+		return err
+	}
+	action := AuditActionUpdate
+	if before == nil {
+		action = AuditActionAdd
+	}
+	return a.record(target.labelEntity()+"_label", fmt.Sprintf("%d/%s", target, key), action, before, value)
+}
+
+// DeleteLabel removes a label from an entity and records it in the
+// audit log.
+func (a *AuditedDatastore) DeleteLabel(target Labelable, key string) error {
+	before := snapshot(a.Datastore.GetLabel(target, key))
+	err := a.Datastore.DeleteLabel(target, key)
+	if err != nil {
+		return err
+	}
+	return a.record(target.labelEntity()+"_label", fmt.Sprintf("%d/%s", target, key), AuditActionDelete, before, nil)
+}
+
+// ===== RetentionPolicies =====
+
+// SetRetentionPolicy creates or replaces a RetentionPolicy and
+// records it in the audit log.
+func (a *AuditedDatastore) SetRetentionPolicy(scope string, keepLatestN uint32, maxAge time.Duration) error {
+	before := snapshot(a.Datastore.GetRetentionPolicy(scope))
+	err := a.Datastore.SetRetentionPolicy(scope, keepLatestN, maxAge)
+	if err != nil {
+		return err
+	}
+	action := AuditActionUpdate
+	if before == nil {
+		action = AuditActionAdd
+	}
+	return a.record("retention_policy", scope, action, before, snapshot(a.Datastore.GetRetentionPolicy(scope)))
+}
+
+// DeleteRetentionPolicy deletes an existing RetentionPolicy and
+// records it in the audit log.
+func (a *AuditedDatastore) DeleteRetentionPolicy(scope string) error {
+	before := snapshot(a.Datastore.GetRetentionPolicy(scope))
+	err := a.Datastore.DeleteRetentionPolicy(scope)
+	if err != nil {
+		return err
+	}
+	return a.record("retention_policy", scope, AuditActionDelete, before, nil)
+}
+
+// ===== Organizations =====
+
+// AddOrganization adds a new Organization and records it in the
+// audit log.
+func (a *AuditedDatastore) AddOrganization(name string, fullname string) (OrgID, error) {
+	id, err := a.Datastore.AddOrganization(name, fullname)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("organization", id, AuditActionAdd, nil, snapshot(a.Datastore.GetOrganizationByID(id)))
+}
+
+// UpdateOrganization updates an existing Organization and records it
+// in the audit log.
+func (a *AuditedDatastore) UpdateOrganization(id OrgID, newName string, newFullname string) error {
+	before := snapshot(a.Datastore.GetOrganizationByID(id))
+	err := a.Datastore.UpdateOrganization(id, newName, newFullname)
+	if err != nil {
+		return err
+	}
+	return a.record("organization", id, AuditActionUpdate, before, snapshot(a.Datastore.GetOrganizationByID(id)))
+}
+
+// DeleteOrganization deletes an existing Organization and records it
+// in the audit log.
+func (a *AuditedDatastore) DeleteOrganization(id OrgID) error {
+	before := snapshot(a.Datastore.GetOrganizationByID(id))
+	err := a.Datastore.DeleteOrganization(id)
+	if err != nil {
+		return err
+	}
+	return a.record("organization", id, AuditActionDelete, before, nil)
+}
+
+// AddOrganizationMember adds or updates an organization membership
+// and records it in the audit log.
+func (a *AuditedDatastore) AddOrganizationMember(orgID OrgID, userID UserID, accessLevel UserAccessLevel) error {
+	before := snapshot(a.Datastore.GetOrganizationMember(orgID, userID))
+	err := a.Datastore.AddOrganizationMember(orgID, userID, accessLevel)
+	if err != nil {
+		return err
+	}
+	action := AuditActionUpdate
+	if before == nil {
+		action = AuditActionAdd
+	}
+	after := &OrganizationMember{OrgID: orgID, UserID: userID, AccessLevel: accessLevel}
+	return a.record("organization_member", fmt.Sprintf("%d/%d", orgID, userID), action, before, after)
+}
+
+// RemoveOrganizationMember removes an organization membership and
+// records it in the audit log.
+func (a *AuditedDatastore) RemoveOrganizationMember(orgID OrgID, userID UserID) error {
+	before := snapshot(a.Datastore.GetOrganizationMember(orgID, userID))
+	err := a.Datastore.RemoveOrganizationMember(orgID, userID)
+	if err != nil {
+		return err
+	}
+	return a.record("organization_member", fmt.Sprintf("%d/%d", orgID, userID), AuditActionDelete, before, nil)
+}
+
+// ===== Quotas =====
+
+// quotaFor returns the current Quota for q's Project or
+// Organization.
+func (a *AuditedDatastore) quotaFor(q *Quota) (*Quota, error) {
+	if q.OrgID != 0 {
+		return a.Datastore.GetQuotaForOrganization(q.OrgID)
+	}
+	return a.Datastore.GetQuotaForProject(q.ProjectID)
+}
+
+// SetQuota creates or replaces a Quota and records it in the audit
+// log.
+func (a *AuditedDatastore) SetQuota(q *Quota) error {
+	before := snapshot(a.quotaFor(q))
+	err := a.Datastore.SetQuota(q)
+	if err != nil {
+		return err
+	}
+	after, err := a.quotaFor(q)
+	if err != nil {
+		return fmt.Errorf("change succeeded but audit entry failed: %v", err)
+	}
+	action := AuditActionUpdate
+	if before == nil {
+		action = AuditActionAdd
+	}
+	return a.record("quota", after.ID, action, before, after)
+}
+
+// DeleteQuota deletes an existing Quota and records it in the audit
+// log.
+func (a *AuditedDatastore) DeleteQuota(id uint32) error {
+	err := a.Datastore.DeleteQuota(id)
+	if err != nil {
+		return err
+	}
+	return a.record("quota", id, AuditActionDelete, nil, nil)
+}
+
+// ===== Obligations =====
+
+// AddObligation adds a new Obligation and records it in the audit
+// log.
+func (a *AuditedDatastore) AddObligation(licenseID uint32, kind string, description string) (uint32, error) {
+	id, err := a.Datastore.AddObligation(licenseID, kind, description)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("obligation", id, AuditActionAdd, nil, snapshot(a.Datastore.GetObligationByID(id)))
+}
+
+// UpdateObligationDescription updates an existing Obligation and
+// records it in the audit log.
+func (a *AuditedDatastore) UpdateObligationDescription(id uint32, description string) error {
+	before := snapshot(a.Datastore.GetObligationByID(id))
+	err := a.Datastore.UpdateObligationDescription(id, description)
+	if err != nil {
+		return err
+	}
+	return a.record("obligation", id, AuditActionUpdate, before, snapshot(a.Datastore.GetObligationByID(id)))
+}
+
+// DeleteObligation deletes an existing Obligation and records it in
+// the audit log.
+func (a *AuditedDatastore) DeleteObligation(id uint32) error {
+	before := snapshot(a.Datastore.GetObligationByID(id))
+	err := a.Datastore.DeleteObligation(id)
+	if err != nil {
+		return err
+	}
+	return a.record("obligation", id, AuditActionDelete, before, nil)
+}
+
+// ===== FindingOverrides =====
+
+// AddFindingOverride adds a new FindingOverride and records it in
+// the audit log.
+func (a *AuditedDatastore) AddFindingOverride(o *FindingOverride) (uint64, error) {
+	id, err := a.Datastore.AddFindingOverride(o)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("finding_override", id, AuditActionAdd, nil, snapshot(a.Datastore.GetFindingOverrideByID(id)))
+}
+
+// DeleteFindingOverride deletes an existing FindingOverride and
+// records it in the audit log.
+func (a *AuditedDatastore) DeleteFindingOverride(id uint64) error {
+	before := snapshot(a.Datastore.GetFindingOverrideByID(id))
+	err := a.Datastore.DeleteFindingOverride(id)
+	if err != nil {
+		return err
+	}
+	return a.record("finding_override", id, AuditActionDelete, before, nil)
+}
+
+// ===== SBOMImports =====
+
+// AddSBOMImport adds a new SBOMImport and records it in the audit
+// log.
+func (a *AuditedDatastore) AddSBOMImport(rpID RepoPullID, jobID JobID, format string, formatVersion string, uri string) (uint32, error) {
+	id, err := a.Datastore.AddSBOMImport(rpID, jobID, format, formatVersion, uri)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("sbom_import", id, AuditActionAdd, nil, snapshot(a.Datastore.GetSBOMImportByID(id)))
+}
+
+// UpdateSBOMImportValidation updates an existing SBOMImport and
+// records it in the audit log.
+func (a *AuditedDatastore) UpdateSBOMImportValidation(id uint32, status string, message string) error {
+	before := snapshot(a.Datastore.GetSBOMImportByID(id))
+	err := a.Datastore.UpdateSBOMImportValidation(id, status, message)
+	if err != nil {
+		return err
+	}
+	return a.record("sbom_import", id, AuditActionUpdate, before, snapshot(a.Datastore.GetSBOMImportByID(id)))
+}
+
+// DeleteSBOMImport deletes an existing SBOMImport and records it in
+// the audit log.
+func (a *AuditedDatastore) DeleteSBOMImport(id uint32) error {
+	before := snapshot(a.Datastore.GetSBOMImportByID(id))
+	err := a.Datastore.DeleteSBOMImport(id)
+	if err != nil {
+		return err
+	}
+	return a.record("sbom_import", id, AuditActionDelete, before, nil)
+}
+
+// ===== Attestations =====
+
+// AddAttestation adds a new Attestation and records it in the audit
+// log.
+func (a *AuditedDatastore) AddAttestation(rpID RepoPullID, attType string, subjectDigest string, statement []byte, uri string) (uint32, error) {
+	id, err := a.Datastore.AddAttestation(rpID, attType, subjectDigest, statement, uri)
+	if err != nil {
+		return 0, err
+	}
+	return id, a.record("attestation", id, AuditActionAdd, nil, snapshot(a.Datastore.GetAttestationByID(id)))
+}
+
+// UpdateAttestationVerification updates an existing Attestation and
+// records it in the audit log.
+func (a *AuditedDatastore) UpdateAttestationVerification(id uint32, status string, message string) error {
+	before := snapshot(a.Datastore.GetAttestationByID(id))
+	err := a.Datastore.UpdateAttestationVerification(id, status, message)
+	if err != nil {
+		return err
+	}
+	return a.record("attestation", id, AuditActionUpdate, before, snapshot(a.Datastore.GetAttestationByID(id)))
+}
+
+// DeleteAttestation deletes an existing Attestation and records it
+// in the audit log.
+func (a *AuditedDatastore) DeleteAttestation(id uint32) error {
+	before := snapshot(a.Datastore.GetAttestationByID(id))
+	err := a.Datastore.DeleteAttestation(id)
+	if err != nil {
+		return err
+	}
+	return a.record("attestation", id, AuditActionDelete, before, nil)
+}
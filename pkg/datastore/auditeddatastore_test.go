@@ -0,0 +1,264 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAuditedDatastoreShouldRecordAddProject(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := &DB{sqldb: sqldb}
+	ads := NewAuditedDatastore(db, 8103918)
+
+	// expect the project to be added
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO peridot.projects")
+	mock.ExpectQuery("INSERT INTO peridot.projects").
+		WithArgs("xyzzy", "Project XYZZY").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// then fetched for the after snapshot
+	mock.ExpectQuery(`SELECT id, name, fullname, org_id, created_at, updated_at FROM peridot.projects WHERE id = \$1`).
+		WithArgs(6).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "fullname", "org_id", "created_at", "updated_at"}).AddRow(6, "xyzzy", "Project XYZZY", nil, testCreatedAt, testUpdatedAt))
+
+	// and then recorded
+	mock.ExpectPrepare("INSERT INTO peridot.audit_log")
+	mock.ExpectExec("INSERT INTO peridot.audit_log").
+		WithArgs(8103918, "project", "6", AuditActionAdd, nil, []byte(`{"id":6,"name":"xyzzy","fullname":"Project XYZZY","created_at":"2019-04-01T09:00:00Z","updated_at":"2019-05-01T09:00:00Z"}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	projectID, err := ads.AddProject("xyzzy", "Project XYZZY")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if projectID != 6 {
+		t.Errorf("expected %v, got %v", 6, projectID)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAuditedDatastoreShouldRecordDeleteProject(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := &DB{sqldb: sqldb}
+	ads := NewAuditedDatastore(db, 8103918)
+
+	// expect the project to be fetched for the before snapshot
+	mock.ExpectQuery(`SELECT id, name, fullname, org_id, created_at, updated_at FROM peridot.projects WHERE id = \$1`).
+		WithArgs(6).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "fullname", "org_id", "created_at", "updated_at"}).AddRow(6, "xyzzy", "Project XYZZY", nil, testCreatedAt, testUpdatedAt))
+
+	// then deleted
+	mock.ExpectBegin()
+	mock.ExpectPrepare("DELETE FROM peridot.projects")
+	mock.ExpectExec("DELETE FROM peridot.projects").
+		WithArgs(6).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// and then recorded
+	mock.ExpectPrepare("INSERT INTO peridot.audit_log")
+	mock.ExpectExec("INSERT INTO peridot.audit_log").
+		WithArgs(8103918, "project", "6", AuditActionDelete, []byte(`{"id":6,"name":"xyzzy","fullname":"Project XYZZY","created_at":"2019-04-01T09:00:00Z","updated_at":"2019-05-01T09:00:00Z"}`), nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = ads.DeleteProject(6)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAuditedDatastoreShouldNotRecordFailedDelete(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := &DB{sqldb: sqldb}
+	ads := NewAuditedDatastore(db, 8103918)
+
+	// before snapshot finds nothing
+	mock.ExpectQuery(`SELECT id, name, fullname, org_id, created_at, updated_at FROM peridot.projects WHERE id = \$1`).
+		WithArgs(413).
+		WillReturnRows(sqlmock.NewRows([]string{}))
+
+	// and delete fails; no audit entry should follow
+	mock.ExpectBegin()
+	mock.ExpectPrepare("DELETE FROM peridot.projects")
+	mock.ExpectExec("DELETE FROM peridot.projects").
+		WithArgs(413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	// run the tested function
+	err = ads.DeleteProject(413)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAuditedDatastoreShouldRecordPurgeRepo(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := &DB{sqldb: sqldb}
+	ads := NewAuditedDatastore(db, 8103918)
+
+	// expect the repo to be purged
+	mock.ExpectExec(`DELETE FROM peridot.repos WHERE id = \$1 AND deleted_at IS NOT NULL`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// and then recorded, without snapshots
+	mock.ExpectPrepare("INSERT INTO peridot.audit_log")
+	mock.ExpectExec("INSERT INTO peridot.audit_log").
+		WithArgs(8103918, "repo", "4", AuditActionDelete, nil, nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = ads.PurgeRepo(4)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAuditedDatastoreShouldRecordPurgeDeletedBefore(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := &DB{sqldb: sqldb}
+	ads := NewAuditedDatastore(db, 8103918)
+
+	// expect soft-deleted rows to be purged
+	before := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	mock.ExpectExec(`DELETE FROM peridot.repos WHERE deleted_at < \$1`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM peridot.subprojects WHERE deleted_at < \$1`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM peridot.projects WHERE deleted_at < \$1`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// and then recorded as a single entry
+	mock.ExpectPrepare("INSERT INTO peridot.audit_log")
+	mock.ExpectExec("INSERT INTO peridot.audit_log").
+		WithArgs(8103918, "soft_deleted", "2019-05-02T13:53:41Z", AuditActionDelete, nil, []byte(`{"purged":3}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	n, err := ads.PurgeDeletedBefore(before)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if n != 3 {
+		t.Errorf("expected %v, got %v", 3, n)
+	}
+}
+
+func TestAuditedDatastoreShouldRecordUserFromAcceptedInvitation(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := &DB{sqldb: sqldb}
+	ads := NewAuditedDatastore(db, 8103918)
+
+	// expect the invitation to be accepted
+	token := "0123456789abcdef"
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE peridot.invitations SET accepted_at").
+		WithArgs(tokenHashArg{token: &token}, sqlmock.AnyArg(), 192304).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "github", "access_level"}).AddRow("johndoe@example.com", nil, 20))
+	mock.ExpectExec("INSERT INTO peridot.users").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// then the new user fetched for the after snapshot
+	mock.ExpectQuery(`SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users WHERE id = \$1`).
+		WithArgs(192304).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+			AddRow(192304, "johndoe", "John Doe", "johndoe@example.com", 20, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt))
+
+	// and then recorded as an added user
+	mock.ExpectPrepare("INSERT INTO peridot.audit_log")
+	mock.ExpectExec("INSERT INTO peridot.audit_log").
+		WithArgs(8103918, "user", "192304", AuditActionAdd, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	_, err = ads.AcceptInvitation(token, 192304, "John Doe", "johndoe")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import "fmt"
+
+// DefaultDeleteBatchSize is the number of rows deleted per statement
+// by the batched deletes if a batch size of 0 is given.
+const DefaultDeleteBatchSize = 10000
+
+// DeleteProgress describes how far a batched delete has got.
+type DeleteProgress struct {
+	// Table is the table from which the latest batch was deleted,
+	// e.g. "file_instances".
+	Table string
+	// Deleted is the total number of rows deleted from Table so
+	// far. Rows in other tables removed by cascading from them are
+	// not counted.
+	Deleted int64
+}
+
+// DeleteProgressFunc is called after each batch of a batched delete.
+// If it returns a non-nil error, the delete stops and returns that
+// error; the batches already deleted remain deleted.
+type DeleteProgressFunc func(p DeleteProgress) error
+
+// deleteInBatches deletes the rows of the given table whose column
+// col equals value, at most batchSize rows per statement, calling
+// progress after each batch. Each statement runs in its own
+// transaction, so that locks are only held for one batch at a time.
+func (db *DB) deleteInBatches(table string, col string, value interface{}, batchSize int, progress DeleteProgressFunc) error {
+	if batchSize <= 0 {
+		batchSize = DefaultDeleteBatchSize
+	}
+
+	query := fmt.Sprintf("DELETE FROM peridot.%[1]s WHERE id IN (SELECT id FROM peridot.%[1]s WHERE %[2]s = $1 LIMIT $2)", table, col)
+	var deleted int64
+	for {
+		result, err := db.sqldb.Exec(query, value, batchSize)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return nil
+		}
+
+		deleted += rows
+		if progress != nil {
+			if err = progress(DeleteProgress{Table: table, Deleted: deleted}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// DeleteFileInstancesInBatches deletes all file instances in the
+// RepoPull with the given ID, along with their findings, copyrights
+// and other dependent rows, deleting at most batchSize instances per
+// statement and calling progress, if not nil, after each batch. If
+// batchSize is 0, DefaultDeleteBatchSize is used. It returns a
+// *NotFoundError, without deleting anything, if there is no such
+// RepoPull or it is hidden by soft deletion. It returns nil on
+// success or an error if failing.
+func (db *DB) DeleteFileInstancesInBatches(rpID RepoPullID, batchSize int, progress DeleteProgressFunc) error {
+	exists, err := db.ExistsRepoPull(rpID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &NotFoundError{Entity: "repo pull", ID: fmt.Sprint(rpID)}
+	}
+	return db.deleteInBatches("file_instances", "repopull_id", rpID, batchSize, progress)
+}
+
+// DeleteJobInBatches deletes an existing Job with the given ID, as
+// DeleteJob does, but first deletes its findings, copyrights and
+// snippet matches in batches of at most batchSize rows, calling
+// progress, if not nil, after each batch. If batchSize is 0,
+// DefaultDeleteBatchSize is used. It returns nil on success or an
+// error if failing.
+func (db *DB) DeleteJobInBatches(id JobID, batchSize int, progress DeleteProgressFunc) error {
+	for _, table := range []string{"findings", "copyrights", "snippet_matches"} {
+		if err := db.deleteInBatches(table, "job_id", id, batchSize, progress); err != nil {
+			return err
+		}
+	}
+	return db.DeleteJob(id)
+}
+
+// DeleteRepoPullInBatches deletes an existing RepoPull with the given
+// ID, as DeleteRepoPull does, but first deletes its file instances
+// and components in batches of at most batchSize rows, calling
+// progress, if not nil, after each batch. If batchSize is 0,
+// DefaultDeleteBatchSize is used. If it fails or is stopped partway,
+// the RepoPull itself remains but may have lost some of its file
+// instances and components. Like DeleteFileInstancesInBatches, it
+// returns a *NotFoundError, without deleting anything, if there is no
+// such RepoPull or it is hidden by soft deletion. It returns nil on
+// success or an error if failing.
+func (db *DB) DeleteRepoPullInBatches(id RepoPullID, batchSize int, progress DeleteProgressFunc) error {
+	if err := db.DeleteFileInstancesInBatches(id, batchSize, progress); err != nil {
+		return err
+	}
+	if err := db.deleteInBatches("components", "repopull_id", id, batchSize, progress); err != nil {
+		return err
+	}
+	return db.DeleteRepoPull(id)
+}
@@ -0,0 +1,169 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldDeleteRepoPullInBatches(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM peridot.repo_pulls WHERE id = \$1 AND repo_id IN \(` + liveRepoIDsRegex + `\)\)`).
+		WithArgs(36).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	fiStmt := `DELETE FROM peridot.file_instances WHERE id IN \(SELECT id FROM peridot.file_instances WHERE repopull_id = \$1 LIMIT \$2\)`
+	for _, n := range []int64{2, 1, 0} {
+		mock.ExpectExec(fiStmt).
+			WithArgs(36, 2).
+			WillReturnResult(sqlmock.NewResult(0, n))
+	}
+	mock.ExpectExec(`DELETE FROM peridot.components WHERE id IN \(SELECT id FROM peridot.components WHERE repopull_id = \$1 LIMIT \$2\)`).
+		WithArgs(36, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectPrepare("DELETE FROM peridot.repo_pulls")
+	mock.ExpectExec("DELETE FROM peridot.repo_pulls").
+		WithArgs(36).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	got := []DeleteProgress{}
+	err = db.DeleteRepoPullInBatches(36, 2, func(p DeleteProgress) error {
+		got = append(got, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check reported progress
+	want := []DeleteProgress{
+		{Table: "file_instances", Deleted: 2},
+		{Table: "file_instances", Deleted: 3},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected %#v, got %#v", want, got)
+	}
+}
+
+func TestShouldStopDeleteRepoPullInBatchesWhenProgressFails(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM peridot.repo_pulls WHERE id = \$1 AND repo_id IN \(` + liveRepoIDsRegex + `\)\)`).
+		WithArgs(36).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec(`DELETE FROM peridot.file_instances`).
+		WithArgs(36, DefaultDeleteBatchSize).
+		WillReturnResult(sqlmock.NewResult(0, DefaultDeleteBatchSize))
+
+	// run the tested function
+	stop := fmt.Errorf("cancelled")
+	err = db.DeleteRepoPullInBatches(36, 0, func(p DeleteProgress) error {
+		return stop
+	})
+	if err != stop {
+		t.Fatalf("expected %v, got %v", stop, err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldNotDeleteRepoPullInBatchesIfNotFound(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// the repo pull is missing or hidden, so no batch is deleted
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM peridot.repo_pulls WHERE id = \$1 AND repo_id IN \(` + liveRepoIDsRegex + `\)\)`).
+		WithArgs(36).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	// run the tested function
+	err = db.DeleteRepoPullInBatches(36, 2, nil)
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldDeleteJobInBatches(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`DELETE FROM peridot.findings WHERE id IN \(SELECT id FROM peridot.findings WHERE job_id = \$1 LIMIT \$2\)`).
+		WithArgs(12, 500).
+		WillReturnResult(sqlmock.NewResult(0, 340))
+	mock.ExpectExec(`DELETE FROM peridot.findings`).
+		WithArgs(12, 500).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM peridot.copyrights`).
+		WithArgs(12, 500).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM peridot.snippet_matches`).
+		WithArgs(12, 500).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectPrepare("DELETE FROM peridot.jobs")
+	mock.ExpectExec("DELETE FROM peridot.jobs").
+		WithArgs(12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.DeleteJobInBatches(12, 500, nil)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// bulkLoadBatchSize is the maximum number of rows sent in a single
+// COPY or INSERT statement by the bulk loader.
+const bulkLoadBatchSize = 10000
+
+// bulkLoad runs load in a single transaction, committing if it
+// returns nil and rolling back otherwise, so that a bulk add is all
+// or nothing.
+func (db *DB) bulkLoad(load func(tx sqlConn) error) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+
+	if err = load(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// reserveIDs returns n new values from the sequence behind the id
+// column of the given table, so that rows can be copied in with
+// their IDs already known.
+func reserveIDs(tx sqlConn, table string, n int) ([]uint64, error) {
+	rows, err := tx.Query("SELECT nextval(pg_get_serial_sequence($1, 'id')) FROM generate_series(1, $2)", "peridot."+table, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uint64, 0, n)
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// copyRows copies n rows into the given columns of the given table
+// using COPY, in batches of at most bulkLoadBatchSize rows. row
+// returns the values for the i'th row, in column order. With
+// DriverPGX, which cannot COPY through database/sql, the rows are
+// added with insertRows instead.
+func (db *DB) copyRows(tx sqlConn, table string, columns []string, n int, row func(i int) []interface{}) error {
+	if db.driver == DriverPGX {
+		return insertRows(tx, table, columns, n, row)
+	}
+
+	for start := 0; start < n; start += bulkLoadBatchSize {
+		end := start + bulkLoadBatchSize
+		if end > n {
+			end = n
+		}
+
+		// lib/pq runs COPY as a simple query rather than preparing
+		// it on the server, so this is unaffected by SetNoPrepare
+		stmt, err := tx.Prepare(pq.CopyInSchema(db.schemaName(), table, columns...))
+		if err != nil {
+			return err
+		}
+		for i := start; i < end; i++ {
+			if _, err = stmt.Exec(row(i)...); err != nil {
+				stmt.Close()
+				return err
+			}
+		}
+
+		// an Exec with no values flushes the batch
+		if _, err = stmt.Exec(); err != nil {
+			stmt.Close()
+			return err
+		}
+		if err = stmt.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertRows adds n rows to the given columns of the given table as
+// copyRows does, but using multi-row INSERT statements, each with at
+// most bulkLoadBatchSize rows and within Postgres's limit of 65535
+// parameters per statement.
+func insertRows(tx sqlConn, table string, columns []string, n int, row func(i int) []interface{}) error {
+	batchSize := 65535 / len(columns)
+	if batchSize > bulkLoadBatchSize {
+		batchSize = bulkLoadBatchSize
+	}
+
+	for start := 0; start < n; start += batchSize {
+		end := start + batchSize
+		if end > n {
+			end = n
+		}
+
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*len(columns))
+		for i := start; i < end; i++ {
+			params := make([]string, len(columns))
+			for j := range columns {
+				params[j] = fmt.Sprintf("$%d", len(args)+j+1)
+			}
+			values = append(values, "("+strings.Join(params, ", ")+")")
+			args = append(args, row(i)...)
+		}
+
+		_, err := tx.Exec("INSERT INTO peridot."+table+"("+strings.Join(columns, ", ")+") VALUES "+strings.Join(values, ", "), args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BulkAddFileHashes adds the given file hashes using COPY, in a
+// single transaction, so that either all or none of them are added.
+// The hashes' ID fields are ignored. It returns the new hashes' IDs,
+// in the same order, on success or an error if failing.
+func (db *DB) BulkAddFileHashes(hashes []*FileHash) ([]uint64, error) {
+	for _, fh := range hashes {
+		if err := fh.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if len(hashes) == 0 {
+		return []uint64{}, nil
+	}
+
+	var ids []uint64
+	err := db.bulkLoad(func(tx sqlConn) error {
+		var err error
+		ids, err = reserveIDs(tx, "file_hashes", len(hashes))
+		if err != nil {
+			return err
+		}
+		return db.copyRows(tx, "file_hashes", []string{"id", "hash_s256", "hash_s1"}, len(hashes), func(i int) []interface{} {
+			return []interface{}{ids[i], hashes[i].HashSHA256, hashes[i].HashSHA1}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// BulkAddFileInstances adds the given file instances using COPY, in
+// a single transaction, so that either all or none of them are
+// added. The instances' ID fields are ignored. It returns the new
+// instances' IDs, in the same order, on success or an error if
+// failing.
+func (db *DB) BulkAddFileInstances(instances []*FileInstance) ([]uint64, error) {
+	for _, fi := range instances {
+		if err := fi.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if len(instances) == 0 {
+		return []uint64{}, nil
+	}
+
+	var ids []uint64
+	err := db.bulkLoad(func(tx sqlConn) error {
+		var err error
+		ids, err = reserveIDs(tx, "file_instances", len(instances))
+		if err != nil {
+			return err
+		}
+		return db.copyRows(tx, "file_instances", []string{"id", "repopull_id", "filehash_id", "path"}, len(instances), func(i int) []interface{} {
+			return []interface{}{ids[i], instances[i].RepoPullID, instances[i].FileHashID, instances[i].Path}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// BulkAddFindings adds the given findings and their license IDs
+// using COPY, in a single transaction, so that either all or none of
+// them are added. The findings' ID fields are ignored. Unlike
+// AddFindings, duplicate license IDs within a finding are an error.
+// It returns the new findings' IDs, in the same order, on success or
+// an error if failing.
+func (db *DB) BulkAddFindings(findings []*Finding) ([]uint64, error) {
+	for _, f := range findings {
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if len(findings) == 0 {
+		return []uint64{}, nil
+	}
+
+	var ids []uint64
+	err := db.bulkLoad(func(tx sqlConn) error {
+		var err error
+		ids, err = reserveIDs(tx, "findings", len(findings))
+		if err != nil {
+			return err
+		}
+		err = db.copyRows(tx, "findings", []string{"id", "fileinstance_id", "job_id", "license_expression", "score", "start_line", "end_line", "snippet"}, len(findings), func(i int) []interface{} {
+			f := findings[i]
+			return []interface{}{ids[i], f.FileInstanceID, f.JobID, f.LicenseExpression, f.Score, f.StartLine, f.EndLine, f.Snippet}
+		})
+		if err != nil {
+			return err
+		}
+
+		// flatten license IDs into (finding ID, license ID) pairs
+		pairs := [][2]uint64{}
+		for i, f := range findings {
+			for _, lid := range f.LicenseIDs {
+				pairs = append(pairs, [2]uint64{ids[i], uint64(lid)})
+			}
+		}
+		return db.copyRows(tx, "finding_licenses", []string{"finding_id", "license_id"}, len(pairs), func(i int) []interface{} {
+			return []interface{}{pairs[i][0], pairs[i][1]}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// BulkAddJobConfigs adds the given configs, keyed by the ID of the
+// existing Job each belongs to, using COPY, in a single transaction,
+// so that either all or none of them are added. Configs that a job
+// already has are not replaced; adding one again is an error. It
+// returns nil on success or an error if failing.
+func (db *DB) BulkAddJobConfigs(configs map[JobID]JobConfig) error {
+	jobIDs := []JobID{}
+	for jobID, jc := range configs {
+		j := &Job{Status: StatusStartup, Health: HealthOK, Config: jc}
+		if err := j.Validate(); err != nil {
+			return err
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	sort.Slice(jobIDs, func(i, j int) bool { return jobIDs[i] < jobIDs[j] })
+
+	stmtVals := []*configStmtValue{}
+	for _, jobID := range jobIDs {
+		jc := configs[jobID]
+		stmtVals = append(stmtVals, configStmtValues(jobID, jc.KV, jc.CodeReader, jc.SpdxReader)...)
+	}
+	if len(stmtVals) == 0 {
+		return nil
+	}
+
+	return db.bulkLoad(func(tx sqlConn) error {
+		return db.copyRows(tx, "jobpathconfigs", []string{"job_id", "type", "key", "value", "priorjob_id"}, len(stmtVals), func(i int) []interface{} {
+			stv := stmtVals[i]
+			return []interface{}{stv.jobID, stv.configType, stv.key, stv.value, sql.NullInt64{Int64: int64(stv.priorjobID), Valid: stv.priorjobID != 0}}
+		})
+	})
+}
@@ -0,0 +1,238 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldBulkAddFileInstances(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	copyStmt := `COPY "peridot"."file_instances" \("id", "repopull_id", "filehash_id", "path"\) FROM STDIN`
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT nextval\(pg_get_serial_sequence\(\$1, 'id'\)\) FROM generate_series\(1, \$2\)`).
+		WithArgs("peridot.file_instances", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(501).AddRow(502))
+	mock.ExpectPrepare(copyStmt)
+	mock.ExpectExec(copyStmt).
+		WithArgs(501, 36, 17, "/README.md").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(copyStmt).
+		WithArgs(502, 36, 18, "/src/main.c").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(copyStmt).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	// run the tested function
+	ids, err := db.BulkAddFileInstances([]*FileInstance{
+		{RepoPullID: 36, FileHashID: 17, Path: "/README.md"},
+		{RepoPullID: 36, FileHashID: 18, Path: "/src/main.c"},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if !reflect.DeepEqual([]uint64{501, 502}, ids) {
+		t.Errorf("expected %v, got %v", []uint64{501, 502}, ids)
+	}
+}
+
+func TestShouldBulkAddFileInstancesWithInsertForPGX(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb, driver: DriverPGX}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT nextval\(pg_get_serial_sequence\(\$1, 'id'\)\) FROM generate_series\(1, \$2\)`).
+		WithArgs("peridot.file_instances", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(501).AddRow(502))
+	mock.ExpectExec(`INSERT INTO peridot.file_instances\(id, repopull_id, filehash_id, path\) VALUES \(\$1, \$2, \$3, \$4\), \(\$5, \$6, \$7, \$8\)`).
+		WithArgs(501, 36, 17, "/README.md", 502, 36, 18, "/src/main.c").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	// run the tested function
+	ids, err := db.BulkAddFileInstances([]*FileInstance{
+		{RepoPullID: 36, FileHashID: 17, Path: "/README.md"},
+		{RepoPullID: 36, FileHashID: 18, Path: "/src/main.c"},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if !reflect.DeepEqual([]uint64{501, 502}, ids) {
+		t.Errorf("expected %v, got %v", []uint64{501, 502}, ids)
+	}
+}
+
+func TestShouldBulkAddFindingsWithLicenses(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	findingStmt := `COPY "peridot"."findings" \("id", "fileinstance_id", "job_id", "license_expression", "score", "start_line", "end_line", "snippet"\) FROM STDIN`
+	licenseStmt := `COPY "peridot"."finding_licenses" \("finding_id", "license_id"\) FROM STDIN`
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT nextval`).
+		WithArgs("peridot.findings", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(101).AddRow(102))
+	mock.ExpectPrepare(findingStmt)
+	mock.ExpectExec(findingStmt).
+		WithArgs(101, 7, 12, "MIT OR Apache-2.0", 100.0, 0, 0, "").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(findingStmt).
+		WithArgs(102, 8, 12, "LicenseRef-acme", 60.0, 3, 14, "Acme").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(findingStmt).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectPrepare(licenseStmt)
+	mock.ExpectExec(licenseStmt).
+		WithArgs(101, 12).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(licenseStmt).
+		WithArgs(101, 340).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(licenseStmt).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	// run the tested function
+	ids, err := db.BulkAddFindings([]*Finding{
+		{FileInstanceID: 7, JobID: 12, LicenseExpression: "MIT OR Apache-2.0", LicenseIDs: []uint32{12, 340}, Score: 100},
+		{FileInstanceID: 8, JobID: 12, LicenseExpression: "LicenseRef-acme", Score: 60, StartLine: 3, EndLine: 14, Snippet: "Acme"},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if !reflect.DeepEqual([]uint64{101, 102}, ids) {
+		t.Errorf("expected %v, got %v", []uint64{101, 102}, ids)
+	}
+}
+
+func TestShouldBulkAddJobConfigsInJobAndKeyOrder(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	copyStmt := `COPY "peridot"."jobpathconfigs" \("job_id", "type", "key", "value", "priorjob_id"\) FROM STDIN`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(copyStmt)
+	mock.ExpectExec(copyStmt).
+		WithArgs(4, 0, "hello", "world", nilArg{}).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(copyStmt).
+		WithArgs(4, 0, "hi", "there", nilArg{}).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(copyStmt).
+		WithArgs(7, 1, "primary", "", 4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(copyStmt).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.BulkAddJobConfigs(map[JobID]JobConfig{
+		7: JobConfig{CodeReader: map[string]JobPathConfig{"primary": JobPathConfig{PriorJobID: 4}}},
+		4: JobConfig{KV: map[string]string{"hi": "there", "hello": "world"}},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldRollBackBulkAddFileHashesOnCopyError(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sha256 := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	sha1 := "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed"
+	copyStmt := `COPY "peridot"."file_hashes" \("id", "hash_s256", "hash_s1"\) FROM STDIN`
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT nextval`).
+		WithArgs("peridot.file_hashes", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(9))
+	mock.ExpectPrepare(copyStmt)
+	mock.ExpectExec(copyStmt).
+		WithArgs(9, sha256, sha1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(copyStmt).
+		WillReturnError(fmt.Errorf("duplicate key value"))
+	mock.ExpectRollback()
+
+	// run the tested function
+	_, err = db.BulkAddFileHashes([]*FileHash{{HashSHA256: sha256, HashSHA1: sha1}})
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailBulkAddFileInstancesWithInvalidInstance(t *testing.T) {
+	_, err := (&DB{}).BulkAddFileInstances([]*FileInstance{{RepoPullID: 36, FileHashID: 17}})
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if verr.Field != "path" {
+		t.Errorf("expected field %v, got %v", "path", verr.Field)
+	}
+}
@@ -0,0 +1,476 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is the TTL used by a CachedDatastore whose
+// CacheOptions do not set one.
+const DefaultCacheTTL = 30 * time.Second
+
+// CacheOptions configures a CachedDatastore.
+type CacheOptions struct {
+	// TTL is how long a cached result is used before it is read
+	// again from the wrapped Datastore. If zero, DefaultCacheTTL is
+	// used.
+	TTL time.Duration
+	// Agents enables caching of agents.
+	Agents bool
+	// Projects enables caching of projects.
+	Projects bool
+	// Subprojects enables caching of subprojects.
+	Subprojects bool
+}
+
+// Groups of cached results, each invalidated as a whole.
+const (
+	cacheGroupAgents      = "agents"
+	cacheGroupProjects    = "projects"
+	cacheGroupSubprojects = "subprojects"
+)
+
+// cacheEntry is a cached result and the time after which it is
+// stale.
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// CachedDatastore wraps another Datastore, caching the results of
+// the Get calls for agents, projects and subprojects, which change
+// rarely but are read constantly by the dispatcher. Each result is
+// kept for the configured TTL, and every cached result for an entity
+// type is discarded when a write to that type is made through the
+// CachedDatastore. All other calls are passed through unchanged.
+//
+// Writes made by other processes, or through other Datastores
+// sharing the same database, are not seen until the TTL expires, so
+// the TTL bounds how stale a result can be. Failed lookups are not
+// cached.
+//
+// It can be combined with AuditedDatastore and QuotaDatastore by
+// wrapping one in the other; to keep the cache coherent, writes
+// must go through the CachedDatastore, so it should be the inner
+// wrapper.
+type CachedDatastore struct {
+	Datastore
+
+	opts CacheOptions
+	// clock returns the current time; it is replaced in tests.
+	clock func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]map[string]cacheEntry
+	// generations counts the invalidations of each group, so that a
+	// result loaded before an invalidation is not cached after it.
+	generations map[string]uint64
+}
+
+// NewCachedDatastore returns a CachedDatastore that caches reads
+// made through ds as configured by opts.
+func NewCachedDatastore(ds Datastore, opts CacheOptions) *CachedDatastore {
+	if opts.TTL == 0 {
+		opts.TTL = DefaultCacheTTL
+	}
+	return &CachedDatastore{
+		Datastore:   ds,
+		opts:        opts,
+		clock:       time.Now,
+		entries:     map[string]map[string]cacheEntry{},
+		generations: map[string]uint64{},
+	}
+}
+
+// get returns the cached result for key in group if there is a
+// fresh one, or else calls load and caches its result if it
+// succeeds.
+func (c *CachedDatastore) get(group string, key string, load func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	e, ok := c.entries[group][key]
+	gen := c.generations[group]
+	c.mu.Unlock()
+	if ok && c.clock().Before(e.expires) {
+		return e.value, nil
+	}
+
+	v, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generations[group] != gen {
+		// a write was made while loading, so v may be stale
+		return v, nil
+	}
+	if c.entries[group] == nil {
+		c.entries[group] = map[string]cacheEntry{}
+	}
+	c.entries[group][key] = cacheEntry{value: v, expires: c.clock().Add(c.opts.TTL)}
+	return v, nil
+}
+
+// invalidate discards every cached result in the given groups.
+func (c *CachedDatastore) invalidate(groups ...string) {
+	c.mu.Lock()
+	for _, group := range groups {
+		delete(c.entries, group)
+		c.generations[group]++
+	}
+	c.mu.Unlock()
+}
+
+// Flush discards every cached result, so that the next Get call for
+// each reads from the wrapped Datastore. It can be used after
+// changes are made outside the CachedDatastore.
+func (c *CachedDatastore) Flush() {
+	c.invalidate(cacheGroupAgents, cacheGroupProjects, cacheGroupSubprojects)
+}
+
+// InTx calls fn within a transaction as the wrapped Datastore's InTx
+// does. The Datastore passed to fn is not cached, so that reads see
+// the transaction's own changes, and since any cached entity type
+// may have been changed, every cached result is discarded once the
+// transaction ends.
+func (c *CachedDatastore) InTx(ctx context.Context, fn func(ds Datastore) error) error {
+	defer c.Flush()
+	return c.Datastore.InTx(ctx, fn)
+}
+
+// ResetDB drops and recreates the schema as the wrapped Datastore's
+// ResetDB does, and then discards every cached result, since none of
+// the cached entities exist any more.
+func (c *CachedDatastore) ResetDB() error {
+	defer c.Flush()
+	return c.Datastore.ResetDB()
+}
+
+// ===== Agents =====
+
+// copyAgents returns copies of the given agents, so that callers
+// cannot modify the cached values.
+func copyAgents(agents []*Agent) []*Agent {
+	cp := make([]*Agent, 0, len(agents))
+	for _, a := range agents {
+		ac := *a
+		cp = append(cp, &ac)
+	}
+	return cp
+}
+
+// getAgent returns a copy of the agent cached under key, loading it
+// with load if needed.
+func (c *CachedDatastore) getAgent(key string, load func() (*Agent, error)) (*Agent, error) {
+	if !c.opts.Agents {
+		return load()
+	}
+	v, err := c.get(cacheGroupAgents, key, func() (interface{}, error) { return load() })
+	if err != nil {
+		return nil, err
+	}
+	a := *v.(*Agent)
+	return &a, nil
+}
+
+// GetAllAgents returns a slice of all agents, from the cache if
+// fresh.
+func (c *CachedDatastore) GetAllAgents() ([]*Agent, error) {
+	if !c.opts.Agents {
+		return c.Datastore.GetAllAgents()
+	}
+	v, err := c.get(cacheGroupAgents, "all", func() (interface{}, error) { return c.Datastore.GetAllAgents() })
+	if err != nil {
+		return nil, err
+	}
+	return copyAgents(v.([]*Agent)), nil
+}
+
+// GetAgentByID returns the Agent with the given ID, from the cache
+// if fresh.
+func (c *CachedDatastore) GetAgentByID(id AgentID) (*Agent, error) {
+	return c.getAgent(fmt.Sprintf("id:%d", id), func() (*Agent, error) { return c.Datastore.GetAgentByID(id) })
+}
+
+// GetAgentsByIDs returns the Agents with the given IDs, picked out
+// of all agents from the cache if fresh.
+func (c *CachedDatastore) GetAgentsByIDs(ids []AgentID) ([]*Agent, error) {
+	if !c.opts.Agents {
+		return c.Datastore.GetAgentsByIDs(ids)
+	}
+	all, err := c.GetAllAgents()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[AgentID]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	agents := []*Agent{}
+	for _, a := range all {
+		if wanted[a.ID] {
+			agents = append(agents, a)
+		}
+	}
+	return agents, nil
+}
+
+// GetAgentByName returns the Agent with the given name, from the
+// cache if fresh.
+func (c *CachedDatastore) GetAgentByName(name string) (*Agent, error) {
+	return c.getAgent("name:"+name, func() (*Agent, error) { return c.Datastore.GetAgentByName(name) })
+}
+
+// AddAgent adds a new Agent and invalidates cached agents.
+func (c *CachedDatastore) AddAgent(name string, isActive bool, address string, port int, isCodeReader bool, isSpdxReader bool, isCodeWriter bool, isSpdxWriter bool) (AgentID, error) {
+	defer c.invalidate(cacheGroupAgents)
+	return c.Datastore.AddAgent(name, isActive, address, port, isCodeReader, isSpdxReader, isCodeWriter, isSpdxWriter)
+}
+
+// UpdateAgentStatus updates an existing Agent's status and
+// invalidates cached agents.
+func (c *CachedDatastore) UpdateAgentStatus(id AgentID, version uint32, isActive bool, address string, port int) error {
+	defer c.invalidate(cacheGroupAgents)
+	return c.Datastore.UpdateAgentStatus(id, version, isActive, address, port)
+}
+
+// UpdateAgentAbilities updates an existing Agent's abilities and
+// invalidates cached agents.
+func (c *CachedDatastore) UpdateAgentAbilities(id AgentID, isCodeReader bool, isSpdxReader bool, isCodeWriter bool, isSpdxWriter bool) error {
+	defer c.invalidate(cacheGroupAgents)
+	return c.Datastore.UpdateAgentAbilities(id, isCodeReader, isSpdxReader, isCodeWriter, isSpdxWriter)
+}
+
+// UpdateAgentHealth sets an existing Agent's health and invalidates
+// cached agents.
+func (c *CachedDatastore) UpdateAgentHealth(id AgentID, health AgentHealth, output string) error {
+	defer c.invalidate(cacheGroupAgents)
+	return c.Datastore.UpdateAgentHealth(id, health, output)
+}
+
+// DeleteAgent deletes an existing Agent and invalidates cached
+// agents.
+func (c *CachedDatastore) DeleteAgent(id AgentID) error {
+	defer c.invalidate(cacheGroupAgents)
+	return c.Datastore.DeleteAgent(id)
+}
+
+// DeleteAgentWithPolicy deletes an existing Agent according to the
+// given policy and invalidates cached agents.
+func (c *CachedDatastore) DeleteAgentWithPolicy(id AgentID, policy AgentJobsPolicy, reassignToID AgentID) error {
+	defer c.invalidate(cacheGroupAgents)
+	return c.Datastore.DeleteAgentWithPolicy(id, policy, reassignToID)
+}
+
+// ===== Organizations =====
+
+// DeleteOrganization deletes an existing Organization and, since its
+// projects and their subprojects are deleted with it, invalidates
+// cached projects and subprojects.
+func (c *CachedDatastore) DeleteOrganization(id OrgID) error {
+	defer c.invalidate(cacheGroupProjects, cacheGroupSubprojects)
+	return c.Datastore.DeleteOrganization(id)
+}
+
+// ===== Projects =====
+
+// GetAllProjects returns a slice of all projects, from the cache if
+// fresh.
+func (c *CachedDatastore) GetAllProjects() ([]*Project, error) {
+	if !c.opts.Projects {
+		return c.Datastore.GetAllProjects()
+	}
+	v, err := c.get(cacheGroupProjects, "all", func() (interface{}, error) { return c.Datastore.GetAllProjects() })
+	if err != nil {
+		return nil, err
+	}
+	projects := v.([]*Project)
+	cp := make([]*Project, 0, len(projects))
+	for _, p := range projects {
+		pc := *p
+		cp = append(cp, &pc)
+	}
+	return cp, nil
+}
+
+// GetProjectByID returns the Project with the given ID, from the
+// cache if fresh.
+func (c *CachedDatastore) GetProjectByID(id ProjectID) (*Project, error) {
+	if !c.opts.Projects {
+		return c.Datastore.GetProjectByID(id)
+	}
+	v, err := c.get(cacheGroupProjects, fmt.Sprintf("id:%d", id), func() (interface{}, error) { return c.Datastore.GetProjectByID(id) })
+	if err != nil {
+		return nil, err
+	}
+	p := *v.(*Project)
+	return &p, nil
+}
+
+// AddProject adds a new Project and invalidates cached projects.
+func (c *CachedDatastore) AddProject(name string, fullname string) (ProjectID, error) {
+	defer c.invalidate(cacheGroupProjects)
+	return c.Datastore.AddProject(name, fullname)
+}
+
+// GetOrCreateProject gets or adds a Project and, if it was added,
+// invalidates cached projects.
+func (c *CachedDatastore) GetOrCreateProject(name string, fullname string) (*Project, bool, error) {
+	p, created, err := c.Datastore.GetOrCreateProject(name, fullname)
+	if created {
+		c.invalidate(cacheGroupProjects)
+	}
+	return p, created, err
+}
+
+// UpdateProject updates an existing Project and invalidates cached
+// projects.
+func (c *CachedDatastore) UpdateProject(id ProjectID, newName string, newFullname string) error {
+	defer c.invalidate(cacheGroupProjects)
+	return c.Datastore.UpdateProject(id, newName, newFullname)
+}
+
+// SetProjectOrganization moves an existing Project into an
+// Organization and invalidates cached projects.
+func (c *CachedDatastore) SetProjectOrganization(id ProjectID, orgID OrgID) error {
+	defer c.invalidate(cacheGroupProjects)
+	return c.Datastore.SetProjectOrganization(id, orgID)
+}
+
+// DeleteProject deletes an existing Project and, since its
+// subprojects are deleted with it, invalidates cached projects and
+// subprojects.
+func (c *CachedDatastore) DeleteProject(id ProjectID) error {
+	defer c.invalidate(cacheGroupProjects, cacheGroupSubprojects)
+	return c.Datastore.DeleteProject(id)
+}
+
+// RestoreProject restores a soft-deleted Project and, since its
+// subprojects are listed again with it, invalidates cached projects
+// and subprojects.
+func (c *CachedDatastore) RestoreProject(id ProjectID) error {
+	defer c.invalidate(cacheGroupProjects, cacheGroupSubprojects)
+	return c.Datastore.RestoreProject(id)
+}
+
+// PurgeProject permanently deletes a soft-deleted Project and, since
+// its subprojects are deleted with it, invalidates cached projects
+// and subprojects.
+func (c *CachedDatastore) PurgeProject(id ProjectID) error {
+	defer c.invalidate(cacheGroupProjects, cacheGroupSubprojects)
+	return c.Datastore.PurgeProject(id)
+}
+
+// ===== Subprojects =====
+
+// getSubprojects returns copies of the subprojects cached under key,
+// loading them with load if needed.
+func (c *CachedDatastore) getSubprojects(key string, load func() ([]*Subproject, error)) ([]*Subproject, error) {
+	if !c.opts.Subprojects {
+		return load()
+	}
+	v, err := c.get(cacheGroupSubprojects, key, func() (interface{}, error) { return load() })
+	if err != nil {
+		return nil, err
+	}
+	subprojects := v.([]*Subproject)
+	cp := make([]*Subproject, 0, len(subprojects))
+	for _, sp := range subprojects {
+		spc := *sp
+		cp = append(cp, &spc)
+	}
+	return cp, nil
+}
+
+// GetAllSubprojects returns a slice of all subprojects, from the
+// cache if fresh.
+func (c *CachedDatastore) GetAllSubprojects() ([]*Subproject, error) {
+	return c.getSubprojects("all", c.Datastore.GetAllSubprojects)
+}
+
+// GetAllSubprojectsForProjectID returns a slice of all subprojects
+// for the Project with the given ID, from the cache if fresh.
+func (c *CachedDatastore) GetAllSubprojectsForProjectID(projectID ProjectID) ([]*Subproject, error) {
+	return c.getSubprojects(fmt.Sprintf("project:%d", projectID), func() ([]*Subproject, error) {
+		return c.Datastore.GetAllSubprojectsForProjectID(projectID)
+	})
+}
+
+// GetSubprojectByID returns the Subproject with the given ID, from
+// the cache if fresh.
+func (c *CachedDatastore) GetSubprojectByID(id uint32) (*Subproject, error) {
+	if !c.opts.Subprojects {
+		return c.Datastore.GetSubprojectByID(id)
+	}
+	v, err := c.get(cacheGroupSubprojects, fmt.Sprintf("id:%d", id), func() (interface{}, error) { return c.Datastore.GetSubprojectByID(id) })
+	if err != nil {
+		return nil, err
+	}
+	sp := *v.(*Subproject)
+	return &sp, nil
+}
+
+// AddSubproject adds a new Subproject and invalidates cached
+// subprojects.
+func (c *CachedDatastore) AddSubproject(projectID ProjectID, name string, fullname string) (uint32, error) {
+	defer c.invalidate(cacheGroupSubprojects)
+	return c.Datastore.AddSubproject(projectID, name, fullname)
+}
+
+// GetOrCreateSubproject gets or adds a Subproject and, if it was
+// added, invalidates cached subprojects.
+func (c *CachedDatastore) GetOrCreateSubproject(projectID ProjectID, name string, fullname string) (*Subproject, bool, error) {
+	sp, created, err := c.Datastore.GetOrCreateSubproject(projectID, name, fullname)
+	if created {
+		c.invalidate(cacheGroupSubprojects)
+	}
+	return sp, created, err
+}
+
+// UpdateSubproject updates an existing Subproject and invalidates
+// cached subprojects.
+func (c *CachedDatastore) UpdateSubproject(id uint32, newName string, newFullname string) error {
+	defer c.invalidate(cacheGroupSubprojects)
+	return c.Datastore.UpdateSubproject(id, newName, newFullname)
+}
+
+// UpdateSubprojectProjectID moves an existing Subproject to another
+// Project and invalidates cached subprojects.
+func (c *CachedDatastore) UpdateSubprojectProjectID(id uint32, newProjectID ProjectID) error {
+	defer c.invalidate(cacheGroupSubprojects)
+	return c.Datastore.UpdateSubprojectProjectID(id, newProjectID)
+}
+
+// DeleteSubproject deletes an existing Subproject and invalidates
+// cached subprojects.
+func (c *CachedDatastore) DeleteSubproject(id uint32) error {
+	defer c.invalidate(cacheGroupSubprojects)
+	return c.Datastore.DeleteSubproject(id)
+}
+
+// RestoreSubproject restores a soft-deleted Subproject and
+// invalidates cached subprojects.
+func (c *CachedDatastore) RestoreSubproject(id uint32) error {
+	defer c.invalidate(cacheGroupSubprojects)
+	return c.Datastore.RestoreSubproject(id)
+}
+
+// PurgeSubproject permanently deletes a soft-deleted Subproject and
+// invalidates cached subprojects.
+func (c *CachedDatastore) PurgeSubproject(id uint32) error {
+	defer c.invalidate(cacheGroupSubprojects)
+	return c.Datastore.PurgeSubproject(id)
+}
+
+// PurgeDeletedBefore permanently deletes soft-deleted projects,
+// subprojects and repos and invalidates cached projects and
+// subprojects.
+func (c *CachedDatastore) PurgeDeletedBefore(before time.Time) (int64, error) {
+	defer c.invalidate(cacheGroupProjects, cacheGroupSubprojects)
+	return c.Datastore.PurgeDeletedBefore(before)
+}
@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var agentTestColumns = []string{"id", "name", "is_active", "address", "port", "is_codereader", "is_spdxreader", "is_codewriter", "is_spdxwriter", "health", "version", "created_at", "updated_at"}
+
+func TestCachedDatastoreShouldServeRepeatedGetFromCache(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := &DB{sqldb: sqldb}
+	cds := NewCachedDatastore(db, CacheOptions{Agents: true})
+
+	// expect the agent to be read only once
+	mock.ExpectQuery(`SELECT (.+) FROM peridot.agents WHERE id = \$1`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(agentTestColumns).AddRow(3, "reuse-lint", true, "localhost", 9060, true, false, false, false, 1, 1, testCreatedAt, testUpdatedAt))
+
+	// run the tested function
+	for i := 0; i < 2; i++ {
+		agent, err := cds.GetAgentByID(3)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if agent.Name != "reuse-lint" {
+			t.Errorf("expected %v, got %v", "reuse-lint", agent.Name)
+		}
+
+		// modifying the result must not affect the cached value
+		agent.Name = "changed"
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCachedDatastoreShouldInvalidateOnWrite(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := &DB{sqldb: sqldb}
+	cds := NewCachedDatastore(db, CacheOptions{Agents: true})
+
+	mock.ExpectQuery(`SELECT (.+) FROM peridot.agents WHERE id = \$1`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(agentTestColumns).AddRow(3, "reuse-lint", true, "localhost", 9060, true, false, false, false, 1, 1, testCreatedAt, testUpdatedAt))
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE peridot.agents")
+	mock.ExpectExec("UPDATE peridot.agents").
+		WithArgs(false, "localhost", 9060, 3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT (.+) FROM peridot.agents WHERE id = \$1`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(agentTestColumns).AddRow(3, "reuse-lint", false, "localhost", 9060, true, false, false, false, 1, 2, testCreatedAt, testUpdatedAt))
+
+	// run the tested function
+	if _, err = cds.GetAgentByID(3); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if err = cds.UpdateAgentStatus(3, 1, false, "localhost", 9060); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	agent, err := cds.GetAgentByID(3)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if agent.IsActive {
+		t.Errorf("expected agent read after update to be inactive")
+	}
+}
+
+// resetStubDB is a DB whose ResetDB succeeds without running any
+// statements, for checking what CachedDatastore does around it.
+type resetStubDB struct {
+	*DB
+}
+
+func (resetStubDB) ResetDB() error {
+	return nil
+}
+
+func TestCachedDatastoreShouldFlushOnResetDB(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	cds := NewCachedDatastore(resetStubDB{&DB{sqldb: sqldb}}, CacheOptions{Agents: true})
+
+	// expect the agent to be read again after the reset
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(`SELECT (.+) FROM peridot.agents WHERE id = \$1`).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(agentTestColumns).AddRow(3, "reuse-lint", true, "localhost", 9060, true, false, false, false, 1, 1, testCreatedAt, testUpdatedAt))
+	}
+
+	// run the tested function
+	if _, err = cds.GetAgentByID(3); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if err = cds.ResetDB(); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if _, err = cds.GetAgentByID(3); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCachedDatastoreShouldReloadAfterTTL(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := &DB{sqldb: sqldb}
+	cds := NewCachedDatastore(db, CacheOptions{TTL: time.Minute, Projects: true})
+	clock := time.Date(2019, 5, 2, 13, 0, 0, 0, time.UTC)
+	cds.clock = func() time.Time { return clock }
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(`SELECT id, name, fullname, org_id, created_at, updated_at FROM peridot.projects WHERE deleted_at IS NULL ORDER BY id`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "fullname", "org_id", "created_at", "updated_at"}).AddRow(1, "xyzzy", "Project XYZZY", nil, testCreatedAt, testUpdatedAt))
+	}
+
+	// run the tested function, reading once within the TTL and
+	// once after it has passed
+	for _, elapsed := range []time.Duration{0, 30 * time.Second, 61 * time.Second} {
+		clock = time.Date(2019, 5, 2, 13, 0, 0, 0, time.UTC).Add(elapsed)
+		projects, err := cds.GetAllProjects()
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if len(projects) != 1 {
+			t.Fatalf("expected %d projects, got %d", 1, len(projects))
+		}
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCachedDatastoreShouldPassThroughDisabledEntities(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := &DB{sqldb: sqldb}
+	cds := NewCachedDatastore(db, CacheOptions{Agents: true})
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(`SELECT id, project_id, name, fullname, created_at, updated_at FROM peridot.subprojects WHERE id = \$1`).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "fullname", "created_at", "updated_at"}).AddRow(4, 1, "frotz", "Subproject FROTZ", testCreatedAt, testUpdatedAt))
+	}
+
+	// run the tested function
+	for i := 0; i < 2; i++ {
+		if _, err = cds.GetSubprojectByID(4); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCachedDatastoreShouldNotCacheNotFound(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := &DB{sqldb: sqldb}
+	cds := NewCachedDatastore(db, CacheOptions{Agents: true})
+
+	mock.ExpectQuery(`SELECT (.+) FROM peridot.agents WHERE name = \$1`).
+		WithArgs("reuse-lint").
+		WillReturnRows(sqlmock.NewRows(agentTestColumns))
+	mock.ExpectQuery(`SELECT (.+) FROM peridot.agents WHERE name = \$1`).
+		WithArgs("reuse-lint").
+		WillReturnRows(sqlmock.NewRows(agentTestColumns).AddRow(3, "reuse-lint", true, "localhost", 9060, true, false, false, false, 1, 1, testCreatedAt, testUpdatedAt))
+
+	// run the tested function
+	_, err = cds.GetAgentByName("reuse-lint")
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+	agent, err := cds.GetAgentByName("reuse-lint")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if agent.ID != 3 {
+		t.Errorf("expected %v, got %v", 3, agent.ID)
+	}
+}
@@ -0,0 +1,260 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Entity types on which a Comment can be made.
+const (
+	// CommentTargetRepoPull means the comment is on a RepoPull.
+	CommentTargetRepoPull = "repopull"
+	// CommentTargetFileInstance means the comment is on a
+	// FileInstance.
+	CommentTargetFileInstance = "fileinstance"
+)
+
+// Comment describes a comment or annotation made by a User on a
+// RepoPull or FileInstance, such as a note explaining a conclusion.
+// Users with AccessCommenter or above for a project are expected to
+// be able to comment on its repo pulls and files; checking this is
+// left to callers, e.g. with EffectiveAccess.
+type Comment struct {
+	// ID is the unique ID for this comment.
+	ID uint64 `json:"id"`
+	// AuthorID is the ID of the user who wrote the comment, or 0
+	// if that user has since been deleted.
+	AuthorID UserID `json:"author_id,omitempty"`
+	// TargetType is the kind of entity commented on, e.g.
+	// CommentTargetRepoPull.
+	TargetType string `json:"target_type"`
+	// TargetID is the ID of the entity commented on.
+	TargetID uint64 `json:"target_id"`
+	// Body is the text of the comment.
+	Body string `json:"body"`
+	// CreatedAt is when the comment was made.
+	CreatedAt time.Time `json:"created_at"`
+	// EditedAt is when the comment's body was last changed. Should
+	// be zero value if it has never been edited.
+	EditedAt time.Time `json:"edited_at,omitempty"`
+	// IsResolved is true if the comment has been marked as
+	// resolved.
+	IsResolved bool `json:"is_resolved"`
+}
+
+// MarshalJSON converts the Comment into a slice of bytes containing
+// its JSON encoding, leaving out edited_at for a comment that has
+// never been edited.
+func (c Comment) MarshalJSON() ([]byte, error) {
+	type commentAlias Comment
+	return json.Marshal(struct {
+		commentAlias
+		EditedAt *time.Time `json:"edited_at,omitempty"`
+	}{commentAlias: commentAlias(c), EditedAt: jsonTimePtr(c.EditedAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of a Comment into the Comment. A missing or null edited_at means the
+// comment has never been edited.
+func (c *Comment) UnmarshalJSON(b []byte) error {
+	type commentAlias Comment
+	aux := struct {
+		*commentAlias
+		EditedAt *time.Time `json:"edited_at"`
+	}{commentAlias: (*commentAlias)(c)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	c.EditedAt = timeFromJSONPtr(aux.EditedAt)
+	return nil
+}
+
+// Validate checks that the Comment's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (c *Comment) Validate() error {
+	switch c.TargetType {
+	case CommentTargetRepoPull, CommentTargetFileInstance:
+	default:
+		return &ValidationError{Entity: "comment", Field: "target_type", Reason: fmt.Sprintf("unknown target type %q", c.TargetType)}
+	}
+	if c.TargetID == 0 {
+		return &ValidationError{Entity: "comment", Field: "target_id", Reason: "must not be zero"}
+	}
+	return requireNonEmpty("comment", "body", c.Body)
+}
+
+// comments store their target in the repopull_id or fileinstance_id
+// column according to target_type, so that they are deleted along
+// with it
+const commentColumns = "id, author_id, target_type, COALESCE(repopull_id, fileinstance_id), body, created_at, edited_at, is_resolved"
+
+// scanComment reads a Comment from a row selecting commentColumns.
+func scanComment(rs rowScanner) (*Comment, error) {
+	c := &Comment{}
+	var authorID sql.NullInt64
+	var editedAt pq.NullTime
+	err := rs.Scan(&c.ID, &authorID, &c.TargetType, &c.TargetID, &c.Body, &c.CreatedAt, &editedAt, &c.IsResolved)
+	if err != nil {
+		return nil, err
+	}
+	c.AuthorID = UserID(authorID.Int64)
+	c.CreatedAt = normalizeTime(c.CreatedAt)
+	if editedAt.Valid {
+		c.EditedAt = normalizeTime(editedAt.Time)
+	}
+	return c, nil
+}
+
+// queryComments runs a query selecting commentColumns and returns
+// the resulting comments.
+func (db *DB) queryComments(query string, args ...interface{}) ([]*Comment, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// GetCommentsForRepoPull returns a slice of all comments on the
+// RepoPull with the given ID, ordered from oldest to newest. It does
+// not include comments on the pull's file instances.
+func (db *DB) GetCommentsForRepoPull(repoPullID RepoPullID) ([]*Comment, error) {
+	return db.queryComments("SELECT "+commentColumns+" FROM peridot.comments WHERE repopull_id = $1 ORDER BY created_at, id", repoPullID)
+}
+
+// GetCommentsForFileInstance returns a slice of all comments on the
+// FileInstance with the given ID, ordered from oldest to newest.
+func (db *DB) GetCommentsForFileInstance(fileInstanceID uint64) ([]*Comment, error) {
+	return db.queryComments("SELECT "+commentColumns+" FROM peridot.comments WHERE fileinstance_id = $1 ORDER BY created_at, id", fileInstanceID)
+}
+
+// GetCommentByID returns the Comment with the given ID, or nil and
+// an error if not found.
+func (db *DB) GetCommentByID(id uint64) (*Comment, error) {
+	c, err := scanComment(db.sqldb.QueryRow("SELECT "+commentColumns+" FROM peridot.comments WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "comment", ID: fmt.Sprint(id)}
+	}
+	return c, err
+}
+
+// AddComment adds a new, unresolved comment by the User with ID
+// authorID on the entity of the given target type and ID. It returns
+// the new comment's ID on success or an error if failing.
+func (db *DB) AddComment(authorID UserID, targetType string, targetID uint64, body string) (uint64, error) {
+	if authorID == 0 {
+		return 0, &ValidationError{Entity: "comment", Field: "author_id", Reason: "must not be zero"}
+	}
+	c := &Comment{AuthorID: authorID, TargetType: targetType, TargetID: targetID, Body: body}
+	if err := c.Validate(); err != nil {
+		return 0, err
+	}
+
+	var repoPullID, fileInstanceID sql.NullInt64
+	if targetType == CommentTargetRepoPull {
+		repoPullID = sql.NullInt64{Int64: int64(targetID), Valid: true}
+	} else {
+		fileInstanceID = sql.NullInt64{Int64: int64(targetID), Valid: true}
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.comments(author_id, target_type, repopull_id, fileinstance_id, body, created_at, is_resolved) VALUES ($1, $2, $3, $4, $5, $6, false) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var commentID uint64
+	err = stmt.QueryRow(authorID, targetType, repoPullID, fileInstanceID, body, now()).Scan(&commentID)
+	if err != nil {
+		return 0, err
+	}
+	return commentID, nil
+}
+
+// UpdateCommentBody replaces the body of the Comment with the given
+// ID and sets its edit time. It returns nil on success or an error
+// if failing.
+func (db *DB) UpdateCommentBody(id uint64, body string) error {
+	if err := requireNonEmpty("comment", "body", body); err != nil {
+		return err
+	}
+
+	result, err := db.sqldb.Exec("UPDATE peridot.comments SET body = $1, edited_at = $2 WHERE id = $3", body, now(), id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "comment", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// SetCommentResolved marks the Comment with the given ID as resolved
+// or unresolved. It returns nil on success or an error if failing.
+func (db *DB) SetCommentResolved(id uint64, resolved bool) error {
+	result, err := db.sqldb.Exec("UPDATE peridot.comments SET is_resolved = $1 WHERE id = $2", resolved, id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "comment", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// DeleteComment deletes the Comment with the given ID. It returns
+// nil on success or an error if failing.
+func (db *DB) DeleteComment(id uint64) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.comments WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "comment", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
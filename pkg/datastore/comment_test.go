@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetCommentsForRepoPull(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	editedAt := time.Date(2019, 5, 3, 9, 12, 5, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "author_id", "target_type", "target_id", "body", "created_at", "edited_at", "is_resolved"}).
+		AddRow(3, 10, "repopull", 36, "Needs another look", createdAt, nil, false).
+		AddRow(4, nil, "repopull", 36, "Fixed upstream", createdAt, editedAt, true)
+	mock.ExpectQuery(`SELECT id, author_id, target_type, COALESCE\(repopull_id, fileinstance_id\), body, created_at, edited_at, is_resolved FROM peridot.comments WHERE repopull_id = \$1 ORDER BY created_at, id`).
+		WithArgs(36).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetCommentsForRepoPull(36)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantRows := []*Comment{
+		{ID: 3, AuthorID: 10, TargetType: CommentTargetRepoPull, TargetID: 36, Body: "Needs another look", CreatedAt: createdAt},
+		{ID: 4, TargetType: CommentTargetRepoPull, TargetID: 36, Body: "Fixed upstream", CreatedAt: createdAt, EditedAt: editedAt, IsResolved: true},
+	}
+	if !reflect.DeepEqual(wantRows, gotRows) {
+		t.Errorf("expected %#v, got %#v", wantRows, gotRows)
+	}
+}
+
+func TestShouldAddCommentOnFileInstance(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.comments\(author_id, target_type, repopull_id, fileinstance_id, body, created_at, is_resolved\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, false\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(10, "fileinstance", nilArg{}, 812, "License header is wrong", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	// run the tested function
+	id, err := db.AddComment(10, CommentTargetFileInstance, 812, "License header is wrong")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 5 {
+		t.Errorf("expected %v, got %v", 5, id)
+	}
+}
+
+func TestShouldFailAddInvalidComment(t *testing.T) {
+	tests := []struct {
+		name       string
+		authorID   UserID
+		targetType string
+		targetID   uint64
+		body       string
+		wantField  string
+	}{
+		{"no author", 0, CommentTargetRepoPull, 36, "hi", "author_id"},
+		{"unknown target type", 10, "job", 36, "hi", "target_type"},
+		{"no target ID", 10, CommentTargetRepoPull, 0, "hi", "target_id"},
+		{"empty body", 10, CommentTargetRepoPull, 36, "", "body"},
+	}
+	for _, tc := range tests {
+		_, err := (&DB{}).AddComment(tc.authorID, tc.targetType, tc.targetID, tc.body)
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%s: expected *ValidationError, got %v", tc.name, err)
+			continue
+		}
+		if verr.Field != tc.wantField {
+			t.Errorf("%s: expected field %v, got %v", tc.name, tc.wantField, verr.Field)
+		}
+	}
+}
+
+func TestShouldUpdateCommentBody(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`UPDATE peridot.comments SET body = \$1, edited_at = \$2 WHERE id = \$3`).
+		WithArgs("Needs another look, see #12", sqlmock.AnyArg(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.UpdateCommentBody(3, "Needs another look, see #12")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailSetCommentResolvedWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`UPDATE peridot.comments SET is_resolved = \$1 WHERE id = \$2`).
+		WithArgs(true, 413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.SetCommentResolved(413, true)
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailGetCommentByIDWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`FROM peridot.comments WHERE id = \$1`).
+		WithArgs(413).
+		WillReturnError(sql.ErrNoRows)
+
+	// run the tested function
+	_, err = db.GetCommentByID(413)
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Component describes a package or other software component
+// discovered in a RepoPull, e.g. by a dependency scanner Job.
+type Component struct {
+	// ID is the unique ID for this component.
+	ID uint64 `json:"id"`
+	// RepoPullID is the ID of the RepoPull in which the component
+	// was discovered.
+	RepoPullID RepoPullID `json:"repopull_id"`
+	// Name is the component's name, e.g. "lodash".
+	Name string `json:"name"`
+	// Version is the component's version, if known.
+	Version string `json:"version,omitempty"`
+	// PURL is the component's package URL, if known, e.g.
+	// "pkg:npm/lodash@4.17.21".
+	PURL string `json:"purl,omitempty"`
+	// Supplier is the person or organization that distributes the
+	// component, if known.
+	Supplier string `json:"supplier,omitempty"`
+}
+
+// Validate checks that the Component's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (c *Component) Validate() error {
+	if err := requireNonEmpty("component", "name", c.Name); err != nil {
+		return err
+	}
+	if c.PURL != "" && (!strings.HasPrefix(c.PURL, "pkg:") || !strings.Contains(c.PURL, "/")) {
+		return &ValidationError{Entity: "component", Field: "purl", Reason: "must be a package URL of the form pkg:type/name"}
+	}
+	return nil
+}
+
+// GetComponentsForRepoPull returns a slice of all components
+// discovered in the RepoPull with the given ID, ordered by ID.
+func (db *DB) GetComponentsForRepoPull(rpID RepoPullID) ([]*Component, error) {
+	return db.queryComponents("SELECT id, repopull_id, name, version, purl, supplier FROM peridot.components WHERE repopull_id = $1 ORDER BY id", rpID)
+}
+
+// GetComponentsByPURL returns a slice of all components, across all
+// RepoPulls, whose package URL matches purl, ordered by ID. If purl
+// has no version, components with any version of that package are
+// returned as well.
+func (db *DB) GetComponentsByPURL(purl string) ([]*Component, error) {
+	if purl == "" {
+		return nil, fmt.Errorf("empty purl passed to GetComponentsByPURL")
+	}
+	return db.queryComponents(`SELECT id, repopull_id, name, version, purl, supplier FROM peridot.components WHERE purl = $1 OR purl LIKE $2 ESCAPE '\' ORDER BY id`, purl, escapeLike(purl)+"@%")
+}
+
+// escapeLike escapes the LIKE wildcard characters in s, which are
+// common in package URLs due to percent-encoding.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// queryComponents runs a query selecting component columns and
+// returns the resulting components.
+func (db *DB) queryComponents(query string, args ...interface{}) ([]*Component, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	components := []*Component{}
+	for rows.Next() {
+		c := &Component{}
+		err := rows.Scan(&c.ID, &c.RepoPullID, &c.Name, &c.Version, &c.PURL, &c.Supplier)
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return components, nil
+}
+
+// GetComponentByID returns the Component with the given ID, or nil
+// and an error if not found.
+func (db *DB) GetComponentByID(id uint64) (*Component, error) {
+	var c Component
+	err := db.sqldb.QueryRow("SELECT id, repopull_id, name, version, purl, supplier FROM peridot.components WHERE id = $1", id).
+		Scan(&c.ID, &c.RepoPullID, &c.Name, &c.Version, &c.PURL, &c.Supplier)
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "component", ID: fmt.Sprint(id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// AddComponents adds the given components in a single transaction,
+// so that either all or none of them are added. The components' ID
+// fields are ignored. It returns the new components' IDs, in the
+// same order, on success or an error if failing.
+func (db *DB) AddComponents(components []*Component) ([]uint64, error) {
+	for _, c := range components {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.components(repopull_id, name, version, purl, supplier) VALUES ($1, $2, $3, $4, $5) RETURNING id")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	ids := make([]uint64, 0, len(components))
+	for _, c := range components {
+		var id uint64
+		err = stmt.QueryRow(c.RepoPullID, c.Name, c.Version, c.PURL, c.Supplier).Scan(&id)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// UpdateComponent updates the existing Component with c's ID,
+// replacing its name, version, package URL and supplier with c's.
+// Its RepoPullID cannot be changed. It returns nil on success or an
+// error if failing.
+func (db *DB) UpdateComponent(c *Component) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	result, err := db.sqldb.Exec("UPDATE peridot.components SET name = $1, version = $2, purl = $3, supplier = $4 WHERE id = $5", c.Name, c.Version, c.PURL, c.Supplier, c.ID)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "component", ID: fmt.Sprint(c.ID)}
+	}
+
+	return nil
+}
+
+// DeleteComponent deletes the existing Component with the given ID.
+// It returns nil on success or an error if failing.
+func (db *DB) DeleteComponent(id uint64) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.components WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "component", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
@@ -0,0 +1,230 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetComponentsByPURLWithoutVersion(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "repopull_id", "name", "version", "purl", "supplier"}).
+		AddRow(8, 36, "lodash", "4.17.15", "pkg:npm/lodash@4.17.15", "").
+		AddRow(14, 41, "lodash", "4.17.21", "pkg:npm/lodash@4.17.21", "OpenJS Foundation")
+	mock.ExpectQuery(`SELECT id, repopull_id, name, version, purl, supplier FROM peridot.components WHERE purl = \$1 OR purl LIKE \$2 ESCAPE '\\' ORDER BY id`).
+		WithArgs("pkg:npm/lodash", "pkg:npm/lodash@%").
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetComponentsByPURL("pkg:npm/lodash")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*Component{
+		&Component{ID: 8, RepoPullID: 36, Name: "lodash", Version: "4.17.15", PURL: "pkg:npm/lodash@4.17.15"},
+		&Component{ID: 14, RepoPullID: 41, Name: "lodash", Version: "4.17.21", PURL: "pkg:npm/lodash@4.17.21", Supplier: "OpenJS Foundation"},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldEscapeWildcardsWhenGettingComponentsByPURL(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "repopull_id", "name", "version", "purl", "supplier"})
+	mock.ExpectQuery(`SELECT id, repopull_id, name, version, purl, supplier FROM peridot.components WHERE purl = \$1 OR purl LIKE \$2`).
+		WithArgs("pkg:npm/%40angular/core_x", `pkg:npm/\%40angular/core\_x@%`).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	_, err = db.GetComponentsByPURL("pkg:npm/%40angular/core_x")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldGetComponentByID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "repopull_id", "name", "version", "purl", "supplier"}).
+		AddRow(14, 41, "lodash", "4.17.21", "pkg:npm/lodash@4.17.21", "OpenJS Foundation")
+	mock.ExpectQuery(`SELECT id, repopull_id, name, version, purl, supplier FROM peridot.components WHERE id = \$1`).
+		WithArgs(14).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	c, err := db.GetComponentByID(14)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := &Component{ID: 14, RepoPullID: 41, Name: "lodash", Version: "4.17.21", PURL: "pkg:npm/lodash@4.17.21", Supplier: "OpenJS Foundation"}
+	if !reflect.DeepEqual(want, c) {
+		t.Errorf("expected %#v, got %#v", want, c)
+	}
+}
+
+func TestShouldAddComponents(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.components\(repopull_id, name, version, purl, supplier\) VALUES \(\$1, \$2, \$3, \$4, \$5\) RETURNING id`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(41, "lodash", "4.17.21", "pkg:npm/lodash@4.17.21", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(14))
+	mock.ExpectQuery(regexStmt).
+		WithArgs(41, "left-pad", "", "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(15))
+	mock.ExpectCommit()
+
+	// run the tested function
+	ids, err := db.AddComponents([]*Component{
+		&Component{RepoPullID: 41, Name: "lodash", Version: "4.17.21", PURL: "pkg:npm/lodash@4.17.21"},
+		&Component{RepoPullID: 41, Name: "left-pad"},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if !reflect.DeepEqual([]uint64{14, 15}, ids) {
+		t.Errorf("expected %v, got %v", []uint64{14, 15}, ids)
+	}
+}
+
+func TestShouldFailAddComponentsWithInvalidPURL(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	_, err = db.AddComponents([]*Component{
+		&Component{RepoPullID: 41, Name: "lodash", PURL: "npm/lodash"},
+	})
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if verr.Field != "purl" {
+		t.Errorf("expected field %v, got %v", "purl", verr.Field)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldUpdateComponent(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`UPDATE peridot.components SET name = \$1, version = \$2, purl = \$3, supplier = \$4 WHERE id = \$5`).
+		WithArgs("lodash", "4.17.21", "pkg:npm/lodash@4.17.21", "OpenJS Foundation", 14).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.UpdateComponent(&Component{ID: 14, Name: "lodash", Version: "4.17.21", PURL: "pkg:npm/lodash@4.17.21", Supplier: "OpenJS Foundation"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDeleteComponentWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`DELETE FROM peridot.components WHERE id = \$1`).
+		WithArgs(413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.DeleteComponent(413)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
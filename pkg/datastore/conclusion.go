@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Conclusion records the license concluded for a FileHash, either by
+// a human reviewer or automatically by a policy. Because it applies
+// to the file hash rather than a single FileInstance, a conclusion
+// carries over to every RepoPull containing the same file.
+type Conclusion struct {
+	// ID is the unique ID for this conclusion.
+	ID uint64 `json:"id"`
+	// FileHashID is the ID of the FileHash the conclusion applies
+	// to. Each file hash has at most one conclusion.
+	FileHashID uint64 `json:"filehash_id"`
+	// LicenseExpression is the concluded SPDX license expression.
+	LicenseExpression string `json:"license_expression"`
+	// ConcludedBy is the ID of the user who made the conclusion,
+	// or 0 if it was made by a policy or the user has since been
+	// deleted.
+	ConcludedBy UserID `json:"concluded_by,omitempty"`
+	// ConcludedAt is when the conclusion was made.
+	ConcludedAt time.Time `json:"concluded_at"`
+	// Justification explains why the license was concluded.
+	Justification string `json:"justification"`
+}
+
+// Validate checks that the Conclusion's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (c *Conclusion) Validate() error {
+	if err := requireNonEmpty("conclusion", "license_expression", c.LicenseExpression); err != nil {
+		return err
+	}
+	return requireNonEmpty("conclusion", "justification", c.Justification)
+}
+
+const conclusionColumns = "c.id, c.filehash_id, c.license_expression, c.concluded_by, c.concluded_at, c.justification"
+
+// scanConclusion scans a single conclusion row from rs, followed by
+// any extra destinations.
+func scanConclusion(rs rowScanner, extra ...interface{}) (*Conclusion, error) {
+	c := &Conclusion{}
+	var concludedBy sql.NullInt64
+	dest := append([]interface{}{&c.ID, &c.FileHashID, &c.LicenseExpression, &concludedBy, &c.ConcludedAt, &c.Justification}, extra...)
+	err := rs.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	c.ConcludedBy = UserID(concludedBy.Int64)
+	c.ConcludedAt = normalizeTime(c.ConcludedAt)
+	return c, nil
+}
+
+// GetConclusionForFileHash returns the Conclusion for the FileHash
+// with the given ID, or nil and an error if there is none.
+func (db *DB) GetConclusionForFileHash(fileHashID uint64) (*Conclusion, error) {
+	c, err := scanConclusion(db.sqldb.QueryRow("SELECT "+conclusionColumns+" FROM peridot.conclusions c WHERE c.filehash_id = $1", fileHashID))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "conclusion", Key: "file hash ID", ID: fmt.Sprint(fileHashID)}
+	}
+	return c, err
+}
+
+// GetConclusionsForRepoPull returns the existing Conclusions that
+// apply to files in the RepoPull with the given ID, keyed by file
+// instance ID. Files without a conclusion are omitted. This is how
+// conclusions made for earlier pulls are applied to a new one.
+func (db *DB) GetConclusionsForRepoPull(rpID RepoPullID) (map[uint64]*Conclusion, error) {
+	rows, err := db.sqldb.Query("SELECT "+conclusionColumns+", fi.id FROM peridot.conclusions c JOIN peridot.file_instances fi ON fi.filehash_id = c.filehash_id WHERE fi.repopull_id = $1", rpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cs := map[uint64]*Conclusion{}
+	for rows.Next() {
+		var fiID uint64
+		c, err := scanConclusion(rows, &fiID)
+		if err != nil {
+			return nil, err
+		}
+		cs[fiID] = c
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// GetUnconcludedFileInstances returns a slice of the FileInstances in
+// the RepoPull with the given ID whose file hashes have no
+// conclusion yet, ordered by path.
+func (db *DB) GetUnconcludedFileInstances(rpID RepoPullID) ([]*FileInstance, error) {
+	rows, err := db.sqldb.Query("SELECT fi.id, fi.repopull_id, fi.filehash_id, fi.path FROM peridot.file_instances fi WHERE fi.repopull_id = $1 AND NOT EXISTS (SELECT 1 FROM peridot.conclusions c WHERE c.filehash_id = fi.filehash_id) ORDER BY fi.path", rpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fis := []*FileInstance{}
+	for rows.Next() {
+		fi := &FileInstance{}
+		err := rows.Scan(&fi.ID, &fi.RepoPullID, &fi.FileHashID, &fi.Path)
+		if err != nil {
+			return nil, err
+		}
+		fis = append(fis, fi)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return fis, nil
+}
+
+// SetConclusion records the license concluded for the FileHash with
+// the given ID, replacing any existing conclusion for it. concludedBy
+// is the ID of the user making the conclusion, or 0 for a policy. It
+// returns the conclusion's ID on success or an error if failing.
+func (db *DB) SetConclusion(fileHashID uint64, licenseExpression string, concludedBy UserID, justification string) (uint64, error) {
+	c := &Conclusion{FileHashID: fileHashID, LicenseExpression: licenseExpression, ConcludedBy: concludedBy, Justification: justification}
+	if err := c.Validate(); err != nil {
+		return 0, err
+	}
+
+	concludedByNullable := sql.NullInt64{Int64: int64(concludedBy), Valid: concludedBy != 0}
+
+	stmt, err := db.prepare("INSERT INTO peridot.conclusions(filehash_id, license_expression, concluded_by, concluded_at, justification) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (filehash_id) DO UPDATE SET license_expression = EXCLUDED.license_expression, concluded_by = EXCLUDED.concluded_by, concluded_at = EXCLUDED.concluded_at, justification = EXCLUDED.justification RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var conclusionID uint64
+	err = stmt.QueryRow(fileHashID, licenseExpression, concludedByNullable, now(), justification).Scan(&conclusionID)
+	if err != nil {
+		return 0, err
+	}
+	return conclusionID, nil
+}
+
+// DeleteConclusion deletes the Conclusion for the FileHash with the
+// given ID. It returns nil on success or an error if failing.
+func (db *DB) DeleteConclusion(fileHashID uint64) error {
+	stmt, err := db.prepare("DELETE FROM peridot.conclusions WHERE filehash_id = $1")
+	if err != nil {
+		return err
+	}
+	result, err := stmt.Exec(fileHashID)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "conclusion", Key: "file hash ID", ID: fmt.Sprint(fileHashID)}
+	}
+
+	return nil
+}
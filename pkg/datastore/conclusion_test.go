@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var conclusionTestColumns = []string{"id", "filehash_id", "license_expression", "concluded_by", "concluded_at", "justification"}
+
+func TestShouldGetConclusionForFileHash(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	ca := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	sentRows := sqlmock.NewRows(conclusionTestColumns).
+		AddRow(5, 81, "MIT", nil, ca, "matched by policy no-notice-mit")
+	mock.ExpectQuery(`SELECT c.id, c.filehash_id, c.license_expression, c.concluded_by, c.concluded_at, c.justification FROM peridot.conclusions c WHERE c.filehash_id = \$1`).
+		WithArgs(81).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	c, err := db.GetConclusionForFileHash(81)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := &Conclusion{ID: 5, FileHashID: 81, LicenseExpression: "MIT", ConcludedAt: ca, Justification: "matched by policy no-notice-mit"}
+	if *c != *want {
+		t.Errorf("expected %#v, got %#v", want, c)
+	}
+}
+
+func TestShouldFailGetConclusionForFileHashWithoutConclusion(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT .* FROM peridot.conclusions c WHERE c.filehash_id = \$1`).
+		WithArgs(413).
+		WillReturnRows(sqlmock.NewRows(conclusionTestColumns))
+
+	// run the tested function
+	c, err := db.GetConclusionForFileHash(413)
+	if c != nil {
+		t.Fatalf("expected nil conclusion, got %v", c)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldGetConclusionsForRepoPull(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	ca := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	sentRows := sqlmock.NewRows(append(conclusionTestColumns, "id")).
+		AddRow(5, 81, "MIT", nil, ca, "matched by policy", 1001).
+		AddRow(6, 82, "Apache-2.0", 10, ca, "reviewed header", 1002)
+	mock.ExpectQuery(`SELECT c.id, .*, fi.id FROM peridot.conclusions c JOIN peridot.file_instances fi ON fi.filehash_id = c.filehash_id WHERE fi.repopull_id = \$1`).
+		WithArgs(36).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	cs, err := db.GetConclusionsForRepoPull(36)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(cs) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(cs))
+	}
+	if cs[1001].FileHashID != 81 || cs[1001].ConcludedBy != 0 {
+		t.Errorf("got unexpected conclusion %#v", cs[1001])
+	}
+	if cs[1002].FileHashID != 82 || cs[1002].ConcludedBy != 10 {
+		t.Errorf("got unexpected conclusion %#v", cs[1002])
+	}
+}
+
+func TestShouldSetConclusion(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.conclusions\(filehash_id, license_expression, concluded_by, concluded_at, justification\) VALUES \(\$1, \$2, \$3, \$4, \$5\) ON CONFLICT \(filehash_id\) DO UPDATE SET`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(82, "Apache-2.0", 10, sqlmock.AnyArg(), "reviewed header").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+
+	// run the tested function
+	id, err := db.SetConclusion(82, "Apache-2.0", 10, "reviewed header")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 6 {
+		t.Errorf("expected %v, got %v", 6, id)
+	}
+}
+
+func TestShouldSetPolicyConclusionWithNullUser(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.conclusions`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(81, "MIT", nilArg{}, sqlmock.AnyArg(), "matched by policy").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	// run the tested function
+	_, err = db.SetConclusion(81, "MIT", 0, "matched by policy")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailSetConclusionWithoutJustification(t *testing.T) {
+	// set up mock; no queries are expected
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	_, err = db.SetConclusion(81, "MIT", 10, "")
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("expected *ValidationError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// nilArg matches a NULL query argument.
+type nilArg struct{}
+
+func (nilArg) Match(v driver.Value) bool {
+	return v == nil
+}
@@ -0,0 +1,240 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config describes how to connect to the database, for
+// NewDBFromConfig.
+type Config struct {
+	// DSN is the Postgres connection string, either a postgres://
+	// URL or a list of key=value settings. It is required.
+	DSN string
+	// Schema is the Postgres schema that holds the tables, such as
+	// "peridot_staging" to keep a second deployment in the same
+	// database. It must be lowercase letters, digits and
+	// underscores. If empty, DefaultSchema is used.
+	Schema string
+	// Driver is the driver to connect with. If empty, DriverPQ is
+	// used.
+	Driver Driver
+	// SSLMode, if set, overrides any sslmode given in DSN. It must
+	// be one of disable, allow, prefer, require, verify-ca or
+	// verify-full.
+	SSLMode string
+	// MaxOpenConns is the maximum number of open connections to
+	// the database, or 0 for no limit.
+	MaxOpenConns int
+	// MaxIdleConns is the maximum number of idle connections kept
+	// for reuse, or 0 to use the database/sql default of 2.
+	MaxIdleConns int
+	// ConnMaxLifetime is the longest that a connection is reused
+	// for, or 0 to reuse connections indefinitely.
+	ConnMaxLifetime time.Duration
+	// InitialAdminGithub is the GitHub user name of the admin user
+	// that InitNewDB adds if there are no users yet. If empty, the
+	// INITIALADMINGITHUB environment variable is used instead.
+	InitialAdminGithub string
+	// SoftDelete enables soft deletion; see SetSoftDelete.
+	SoftDelete bool
+	// NoPrepare disables server-side prepared statements, for
+	// connecting through PgBouncer in transaction pooling mode;
+	// see SetNoPrepare.
+	NoPrepare bool
+}
+
+// Environment variables read by ConfigFromEnv.
+const (
+	EnvDSN                = "PERIDOT_DB_DSN"
+	EnvSchema             = "PERIDOT_DB_SCHEMA"
+	EnvDriver             = "PERIDOT_DB_DRIVER"
+	EnvSSLMode            = "PERIDOT_DB_SSLMODE"
+	EnvMaxOpenConns       = "PERIDOT_DB_MAX_OPEN_CONNS"
+	EnvMaxIdleConns       = "PERIDOT_DB_MAX_IDLE_CONNS"
+	EnvConnMaxLifetime    = "PERIDOT_DB_CONN_MAX_LIFETIME"
+	EnvSoftDelete         = "PERIDOT_DB_SOFT_DELETE"
+	EnvNoPrepare          = "PERIDOT_DB_NO_PREPARE"
+	EnvInitialAdminGithub = "INITIALADMINGITHUB"
+)
+
+// validSSLModes lists the values accepted for Config.SSLMode.
+var validSSLModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Validate checks that the Config can be used to connect, returning
+// a *ValidationError for the first problem found.
+func (c Config) Validate() error {
+	if c.DSN == "" {
+		return &ValidationError{Entity: "config", Field: "DSN", Reason: "must not be empty"}
+	}
+	if c.Schema != "" && !validSchemaName(c.Schema) {
+		return &ValidationError{Entity: "config", Field: "schema", Reason: "must be lowercase letters, digits and underscores, not starting with a digit"}
+	}
+	if c.Driver != "" && c.Driver != DriverPQ && c.Driver != DriverPGX {
+		return &ValidationError{Entity: "config", Field: "driver", Reason: "must be " + string(DriverPQ) + " or " + string(DriverPGX)}
+	}
+	if c.SSLMode != "" && !containsString(validSSLModes, c.SSLMode) {
+		return &ValidationError{Entity: "config", Field: "SSL mode", Reason: "must be one of " + strings.Join(validSSLModes, ", ")}
+	}
+	if c.MaxOpenConns < 0 {
+		return &ValidationError{Entity: "config", Field: "max open connections", Reason: "must not be negative"}
+	}
+	if c.MaxIdleConns < 0 {
+		return &ValidationError{Entity: "config", Field: "max idle connections", Reason: "must not be negative"}
+	}
+	if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+		return &ValidationError{Entity: "config", Field: "max idle connections", Reason: "must not exceed max open connections"}
+	}
+	if c.ConnMaxLifetime < 0 {
+		return &ValidationError{Entity: "config", Field: "connection max lifetime", Reason: "must not be negative"}
+	}
+	return nil
+}
+
+// containsString reports whether ss contains s.
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// dataSourceName returns DSN with SSLMode applied to it, and with
+// pgx's statement cache set to describe statements rather than
+// prepare them if NoPrepare is set.
+func (c Config) dataSourceName() (string, error) {
+	dsn := c.DSN
+	var err error
+	if c.SSLMode != "" {
+		if dsn, err = setDSNParam(dsn, "sslmode", c.SSLMode); err != nil {
+			return "", err
+		}
+	}
+	if c.NoPrepare && c.Driver == DriverPGX {
+		if dsn, err = setDSNParam(dsn, "statement_cache_mode", "describe"); err != nil {
+			return "", err
+		}
+	}
+	return dsn, nil
+}
+
+// setDSNParam sets the given parameter in dsn, overriding any value
+// that it already has.
+func setDSNParam(dsn string, key string, value string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", &ValidationError{Entity: "config", Field: "DSN", Reason: "must be a valid URL"}
+		}
+		q := u.Query()
+		q.Set(key, value)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	// in key=value form, a later setting overrides an earlier one
+	return dsn + " " + key + "=" + value, nil
+}
+
+// ConfigFromEnv returns a Config read from the environment variables
+// named by the Env constants, such as PERIDOT_DB_DSN. Unset
+// variables leave the corresponding fields at their zero values.
+// PERIDOT_DB_CONN_MAX_LIFETIME is a duration such as "30m", and
+// PERIDOT_DB_SOFT_DELETE and PERIDOT_DB_NO_PREPARE are booleans such
+// as "true". The Config is validated before it is returned.
+func ConfigFromEnv() (Config, error) {
+	c := Config{
+		DSN:                os.Getenv(EnvDSN),
+		Schema:             os.Getenv(EnvSchema),
+		Driver:             Driver(os.Getenv(EnvDriver)),
+		SSLMode:            os.Getenv(EnvSSLMode),
+		InitialAdminGithub: os.Getenv(EnvInitialAdminGithub),
+	}
+
+	var err error
+	if v := os.Getenv(EnvMaxOpenConns); v != "" {
+		if c.MaxOpenConns, err = strconv.Atoi(v); err != nil {
+			return Config{}, &ValidationError{Entity: "config", Field: EnvMaxOpenConns, Reason: "must be an integer"}
+		}
+	}
+	if v := os.Getenv(EnvMaxIdleConns); v != "" {
+		if c.MaxIdleConns, err = strconv.Atoi(v); err != nil {
+			return Config{}, &ValidationError{Entity: "config", Field: EnvMaxIdleConns, Reason: "must be an integer"}
+		}
+	}
+	if v := os.Getenv(EnvConnMaxLifetime); v != "" {
+		if c.ConnMaxLifetime, err = time.ParseDuration(v); err != nil {
+			return Config{}, &ValidationError{Entity: "config", Field: EnvConnMaxLifetime, Reason: "must be a duration"}
+		}
+	}
+	if v := os.Getenv(EnvSoftDelete); v != "" {
+		if c.SoftDelete, err = strconv.ParseBool(v); err != nil {
+			return Config{}, &ValidationError{Entity: "config", Field: EnvSoftDelete, Reason: "must be a boolean"}
+		}
+	}
+	if v := os.Getenv(EnvNoPrepare); v != "" {
+		if c.NoPrepare, err = strconv.ParseBool(v); err != nil {
+			return Config{}, &ValidationError{Entity: "config", Field: EnvNoPrepare, Reason: "must be a boolean"}
+		}
+	}
+
+	if err = c.Validate(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// NewDBFromConfig validates c, then opens and returns an initialized
+// DB object connected as it describes. It checks that the database
+// can be reached before returning.
+func NewDBFromConfig(c Config) (*DB, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if c.Driver == "" {
+		c.Driver = DriverPQ
+	}
+	dsn, err := c.dataSourceName()
+	if err != nil {
+		return nil, err
+	}
+
+	var sqldb *sql.DB
+	if c.Schema != "" && c.Schema != DefaultSchema {
+		sqldb, err = openInSchema(string(c.Driver), dsn, c.Schema)
+	} else {
+		sqldb, err = sql.Open(string(c.Driver), dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sqldb.SetMaxOpenConns(c.MaxOpenConns)
+	if c.MaxIdleConns > 0 {
+		sqldb.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	sqldb.SetConnMaxLifetime(c.ConnMaxLifetime)
+	if err = sqldb.Ping(); err != nil {
+		sqldb.Close()
+		return nil, err
+	}
+
+	db := &DB{sqldb: sqldb, driver: c.Driver, schema: c.Schema, softDelete: c.SoftDelete, noPrepare: c.NoPrepare, initialAdminGithub: c.InitialAdminGithub}
+	return db, nil
+}
+
+// NewDBFromEnv opens and returns an initialized DB object configured
+// by the environment variables read by ConfigFromEnv.
+func NewDBFromEnv() (*DB, error) {
+	c, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewDBFromConfig(c)
+}
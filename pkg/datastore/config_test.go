@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldFailValidatingInvalidConfigs(t *testing.T) {
+	dsn := "postgres://peridot@localhost/peridot"
+	configs := []Config{
+		{},
+		{DSN: dsn, Driver: "mysql"},
+		{DSN: dsn, Schema: "Peridot"},
+		{DSN: dsn, Schema: "peridot; DROP TABLE users"},
+		{DSN: dsn, SSLMode: "on"},
+		{DSN: dsn, MaxOpenConns: -1},
+		{DSN: dsn, MaxIdleConns: -1},
+		{DSN: dsn, MaxOpenConns: 4, MaxIdleConns: 8},
+		{DSN: dsn, ConnMaxLifetime: -time.Minute},
+	}
+
+	for _, c := range configs {
+		err := c.Validate()
+		if _, ok := err.(*ValidationError); !ok {
+			t.Errorf("expected *ValidationError for %+v, got %v", c, err)
+		}
+	}
+}
+
+func TestShouldFailNewDBFromConfigWithoutConnectingIfInvalid(t *testing.T) {
+	db, err := NewDBFromConfig(Config{DSN: "host=localhost", Driver: "mysql"})
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if db != nil {
+		t.Errorf("expected nil DB, got %v", db)
+	}
+}
+
+func TestShouldApplySSLModeToDSN(t *testing.T) {
+	tests := []struct {
+		dsn     string
+		sslMode string
+		want    string
+	}{
+		{"host=localhost dbname=peridot", "", "host=localhost dbname=peridot"},
+		{"host=localhost sslmode=disable", "verify-full", "host=localhost sslmode=disable sslmode=verify-full"},
+		{"postgres://peridot@localhost/peridot", "require", "postgres://peridot@localhost/peridot?sslmode=require"},
+		{"postgres://peridot@localhost/peridot?sslmode=disable", "require", "postgres://peridot@localhost/peridot?sslmode=require"},
+	}
+
+	for _, tt := range tests {
+		got, err := Config{DSN: tt.dsn, SSLMode: tt.sslMode}.dataSourceName()
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestShouldDisablePgxStatementCacheForNoPrepare(t *testing.T) {
+	got, err := Config{DSN: "postgres://localhost/peridot", Driver: DriverPGX, NoPrepare: true}.dataSourceName()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	want := "postgres://localhost/peridot?statement_cache_mode=describe"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	got, err = Config{DSN: "host=localhost", Driver: DriverPQ, NoPrepare: true}.dataSourceName()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got != "host=localhost" {
+		t.Errorf("expected %q, got %q", "host=localhost", got)
+	}
+}
+
+func TestShouldReadConfigFromEnv(t *testing.T) {
+	env := map[string]string{
+		EnvDSN:                "host=localhost dbname=peridot",
+		EnvSchema:             "peridot_staging",
+		EnvDriver:             "pgx",
+		EnvSSLMode:            "require",
+		EnvMaxOpenConns:       "20",
+		EnvMaxIdleConns:       "5",
+		EnvConnMaxLifetime:    "30m",
+		EnvSoftDelete:         "true",
+		EnvNoPrepare:          "1",
+		EnvInitialAdminGithub: "admin",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	c, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	want := Config{
+		DSN:                "host=localhost dbname=peridot",
+		Schema:             "peridot_staging",
+		Driver:             DriverPGX,
+		SSLMode:            "require",
+		MaxOpenConns:       20,
+		MaxIdleConns:       5,
+		ConnMaxLifetime:    30 * time.Minute,
+		InitialAdminGithub: "admin",
+		SoftDelete:         true,
+		NoPrepare:          true,
+	}
+	if c != want {
+		t.Errorf("expected %+v, got %+v", want, c)
+	}
+}
+
+func TestShouldFailConfigFromEnvWithUnparseableValues(t *testing.T) {
+	os.Setenv(EnvDSN, "host=localhost")
+	defer os.Unsetenv(EnvDSN)
+
+	for _, k := range []string{EnvMaxOpenConns, EnvMaxIdleConns, EnvConnMaxLifetime, EnvSoftDelete, EnvNoPrepare} {
+		os.Setenv(k, "lots")
+		_, err := ConfigFromEnv()
+		os.Unsetenv(k)
+		if _, ok := err.(*ValidationError); !ok {
+			t.Errorf("expected *ValidationError for %s, got %v", k, err)
+		}
+	}
+}
+
+func TestShouldRunStatementsInConfiguredSchema(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.NewWithDSN("schema_test")
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	schemadb, err := openInSchema("sqlmock", "schema_test", "peridot_staging")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	defer schemadb.Close()
+	db := DB{sqldb: schemadb, schema: "peridot_staging"}
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM peridot_staging.projects WHERE id = \$1 AND deleted_at IS NULL\)`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	// run the tested function
+	exists, err := db.ExistsProject(3)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if !exists {
+		t.Errorf("expected project to exist")
+	}
+}
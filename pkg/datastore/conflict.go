@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrConflict is matched by errors.Is for every *ConflictError, so
+// that callers can detect a lost update of any kind with a single
+// check, e.g. to respond with HTTP 409.
+var ErrConflict = errors.New("conflict")
+
+// ConflictError is returned when an update that checks an entity's
+// version is rejected because the entity has been changed since that
+// version was read, in which case the caller should read the entity
+// again and retry with its current state. It is also returned when
+// a write is rejected because it would duplicate the unique key of
+// an existing entity, in which case Constraint is set.
+type ConflictError struct {
+	// Entity is the kind of entity that was changed, such as "job"
+	// or "agent".
+	Entity string
+	// ID identifies the entity that was changed.
+	ID string
+	// Version is the version that the caller expected the entity
+	// to have.
+	Version uint32
+	// Constraint is the name of the violated unique constraint, if
+	// the conflict is with an existing entity's unique key.
+	Constraint string
+	// Detail is the database's description of a unique violation,
+	// e.g. naming the duplicated key.
+	Detail string
+	// Err is the underlying database error for a unique violation.
+	Err error
+}
+
+func (e *ConflictError) Error() string {
+	if e.Constraint != "" {
+		if e.Detail != "" {
+			return fmt.Sprintf("%s violates unique constraint %s: %s", e.Entity, e.Constraint, e.Detail)
+		}
+		return fmt.Sprintf("%s violates unique constraint %s", e.Entity, e.Constraint)
+	}
+	if e.Version == 0 {
+		return fmt.Sprintf("%s with ID %s was changed concurrently", e.Entity, e.ID)
+	}
+	return fmt.Sprintf("%s with ID %s has changed since version %d", e.Entity, e.ID, e.Version)
+}
+
+// Is reports whether target is ErrConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// Unwrap returns the underlying database error, if any.
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// versionMismatchError returns the error for a versioned update of
+// the row with the given ID in the given table that affected no
+// rows: a *NotFoundError if the row does not exist, or a
+// *ConflictError if its version is no longer the expected one.
+func (db *DB) versionMismatchError(table string, entity string, id interface{}, version uint32) error {
+	var current uint32
+	err := db.sqldb.QueryRow("SELECT version FROM peridot."+table+" WHERE id = $1", id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return &NotFoundError{Entity: entity, ID: fmt.Sprint(id)}
+	}
+	if err != nil {
+		return err
+	}
+	return &ConflictError{Entity: entity, ID: fmt.Sprint(id), Version: version}
+}
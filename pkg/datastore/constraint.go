@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgconn"
+	"github.com/lib/pq"
+)
+
+// ErrForeignKey is matched by errors.Is for every *ForeignKeyError,
+// so that callers can detect a reference to a missing entity of any
+// kind with a single check, e.g. to respond with HTTP 422.
+var ErrForeignKey = errors.New("foreign key violation")
+
+// Postgres error codes translated by translateConstraintError.
+const (
+	pgCodeForeignKeyViolation = "23503"
+	pgCodeUniqueViolation     = "23505"
+)
+
+// ForeignKeyError is returned when a write is rejected because it
+// references an entity that does not exist, or because it would
+// delete an entity that is still referenced.
+type ForeignKeyError struct {
+	// Entity is the kind of entity that was being written, such as
+	// "repo" or "job".
+	Entity string
+	// Table is the table whose foreign key constraint was violated.
+	Table string
+	// Constraint is the name of the violated constraint.
+	Constraint string
+	// Detail is the database's description of the violation, e.g.
+	// naming the missing key.
+	Detail string
+	// Err is the underlying database error.
+	Err error
+}
+
+func (e *ForeignKeyError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("%s violates foreign key constraint %s: %s", e.Entity, e.Constraint, e.Detail)
+	}
+	return fmt.Sprintf("%s violates foreign key constraint %s", e.Entity, e.Constraint)
+}
+
+// Is reports whether target is ErrForeignKey.
+func (e *ForeignKeyError) Is(target error) bool {
+	return target == ErrForeignKey
+}
+
+// Unwrap returns the underlying database error.
+func (e *ForeignKeyError) Unwrap() error {
+	return e.Err
+}
+
+// pgErrorFields returns the SQLSTATE code, table, constraint and
+// detail of err if it is an error reported by Postgres through
+// either driver, and false otherwise.
+func pgErrorFields(err error) (code string, table string, constraint string, detail string, ok bool) {
+	switch e := err.(type) {
+	case *pq.Error:
+		return string(e.Code), e.Table, e.Constraint, e.Detail, true
+	case *pgconn.PgError:
+		return e.Code, e.TableName, e.ConstraintName, e.Detail, true
+	}
+	return "", "", "", "", false
+}
+
+// translateConstraintError converts a Postgres foreign key or unique
+// violation that occurred while writing the given entity into a
+// *ForeignKeyError or *ConflictError respectively. Any other error,
+// including nil, is returned unchanged.
+func translateConstraintError(entity string, err error) error {
+	code, table, constraint, detail, ok := pgErrorFields(err)
+	if !ok {
+		return err
+	}
+	switch code {
+	case pgCodeForeignKeyViolation:
+		return &ForeignKeyError{Entity: entity, Table: table, Constraint: constraint, Detail: detail, Err: err}
+	case pgCodeUniqueViolation:
+		return &ConflictError{Entity: entity, Constraint: constraint, Detail: detail, Err: err}
+	}
+	return err
+}
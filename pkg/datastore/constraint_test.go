@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgconn"
+	"github.com/lib/pq"
+)
+
+func TestShouldTranslateConstraintErrors(t *testing.T) {
+	fkErr := &pq.Error{Code: "23503", Table: "repos", Constraint: "repos_subproject_id_fkey", Detail: "Key (subproject_id)=(17) is not present in table \"subprojects\"."}
+	uniqueErr := &pq.Error{Code: "23505", Table: "agents", Constraint: "agents_name_key"}
+	otherErr := &pq.Error{Code: "42P01"}
+	plainErr := fmt.Errorf("pq: some other failure")
+
+	got := translateConstraintError("repo", fkErr)
+	fke, ok := got.(*ForeignKeyError)
+	if !ok {
+		t.Fatalf("expected *ForeignKeyError, got %T", got)
+	}
+	if fke.Entity != "repo" || fke.Table != "repos" || fke.Constraint != "repos_subproject_id_fkey" {
+		t.Errorf("got unexpected ForeignKeyError %+v", fke)
+	}
+	if !errors.Is(got, ErrForeignKey) {
+		t.Errorf("expected errors.Is(err, ErrForeignKey) to hold")
+	}
+	var pqErr *pq.Error
+	if !errors.As(got, &pqErr) || pqErr != fkErr {
+		t.Errorf("expected underlying *pq.Error to be unwrapped")
+	}
+
+	got = translateConstraintError("agent", uniqueErr)
+	ce, ok := got.(*ConflictError)
+	if !ok {
+		t.Fatalf("expected *ConflictError, got %T", got)
+	}
+	if ce.Entity != "agent" || ce.Constraint != "agents_name_key" {
+		t.Errorf("got unexpected ConflictError %+v", ce)
+	}
+	if !errors.Is(got, ErrConflict) {
+		t.Errorf("expected errors.Is(err, ErrConflict) to hold")
+	}
+	if ce.Error() != "agent violates unique constraint agents_name_key" {
+		t.Errorf("got unexpected message %q", ce.Error())
+	}
+
+	pgxErr := &pgconn.PgError{Code: "23505", TableName: "agents", ConstraintName: "agents_name_key"}
+	got = translateConstraintError("agent", pgxErr)
+	ce, ok = got.(*ConflictError)
+	if !ok {
+		t.Fatalf("expected *ConflictError from pgx error, got %T", got)
+	}
+	if ce.Constraint != "agents_name_key" || ce.Err != pgxErr {
+		t.Errorf("got unexpected ConflictError %+v", ce)
+	}
+
+	if got = translateConstraintError("repo", otherErr); got != otherErr {
+		t.Errorf("expected other pq error to be returned unchanged, got %v", got)
+	}
+	if got = translateConstraintError("repo", plainErr); got != plainErr {
+		t.Errorf("expected non-pq error to be returned unchanged, got %v", got)
+	}
+	if got = translateConstraintError("repo", nil); got != nil {
+		t.Errorf("expected nil, got %v", got)
+	}
+}
+
+func TestShouldReturnForeignKeyErrorForAddRepoWithUnknownSubprojectID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO peridot.repos")
+	mock.ExpectQuery("INSERT INTO peridot.repos").
+		WithArgs(17, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git").
+		WillReturnError(&pq.Error{Code: "23503", Table: "repos", Constraint: "repos_subproject_id_fkey"})
+	mock.ExpectRollback()
+
+	// run the tested function
+	_, err = db.AddRepo(17, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git")
+	var fke *ForeignKeyError
+	if !errors.As(err, &fke) {
+		t.Fatalf("expected *ForeignKeyError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if fke.Entity != "repo" {
+		t.Errorf("expected %v, got %v", "repo", fke.Entity)
+	}
+	if fke.Constraint != "repos_subproject_id_fkey" {
+		t.Errorf("expected %v, got %v", "repos_subproject_id_fkey", fke.Constraint)
+	}
+}
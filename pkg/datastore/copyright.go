@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import "github.com/lib/pq"
+
+// Copyright describes a copyright notice detected in a FileInstance
+// by a scanner Job.
+type Copyright struct {
+	// ID is the unique ID for this copyright notice.
+	ID uint64 `json:"id"`
+	// FileInstanceID is the ID of the FileInstance in which the
+	// notice was found.
+	FileInstanceID uint64 `json:"fileinstance_id"`
+	// JobID is the ID of the Job that detected the notice.
+	JobID JobID `json:"job_id"`
+	// Text is the full text of the notice as detected, e.g.
+	// "Copyright (c) 2015-2019 The Linux Foundation".
+	Text string `json:"text"`
+	// Holder is the copyright holder named in the notice, if the
+	// scanner could identify one.
+	Holder string `json:"holder,omitempty"`
+	// Years is the year or years given in the notice, if any, as
+	// written, e.g. "2015-2019".
+	Years string `json:"years,omitempty"`
+}
+
+// Validate checks that the Copyright's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (c *Copyright) Validate() error {
+	return requireNonEmpty("copyright", "text", c.Text)
+}
+
+// CopyrightSummary aggregates the copyright notices for a single
+// holder across a RepoPull, e.g. for generating a notice file.
+type CopyrightSummary struct {
+	// Holder is the copyright holder, or "" for notices whose
+	// holder could not be identified.
+	Holder string `json:"holder"`
+	// Statements are the distinct notice texts for the holder, in
+	// sorted order.
+	Statements []string `json:"statements"`
+	// FileCount is the number of files containing a notice for
+	// the holder.
+	FileCount int `json:"file_count"`
+}
+
+// GetCopyrightsForRepoPull returns a slice of all copyright notices
+// for files in the RepoPull with the given ID, ordered by file
+// instance and then by ID.
+func (db *DB) GetCopyrightsForRepoPull(rpID RepoPullID) ([]*Copyright, error) {
+	return db.queryCopyrights("SELECT c.id, c.fileinstance_id, c.job_id, c.text, c.holder, c.years FROM peridot.copyrights c JOIN peridot.file_instances fi ON fi.id = c.fileinstance_id WHERE fi.repopull_id = $1 ORDER BY c.fileinstance_id, c.id", rpID)
+}
+
+// GetCopyrightsForFileInstance returns a slice of all copyright
+// notices for the FileInstance with the given ID, ordered by ID.
+func (db *DB) GetCopyrightsForFileInstance(fileInstanceID uint64) ([]*Copyright, error) {
+	return db.queryCopyrights("SELECT c.id, c.fileinstance_id, c.job_id, c.text, c.holder, c.years FROM peridot.copyrights c WHERE c.fileinstance_id = $1 ORDER BY c.id", fileInstanceID)
+}
+
+// queryCopyrights runs a query selecting copyright columns and
+// returns the resulting copyright notices.
+func (db *DB) queryCopyrights(query string, args ...interface{}) ([]*Copyright, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cs := []*Copyright{}
+	for rows.Next() {
+		c := &Copyright{}
+		err := rows.Scan(&c.ID, &c.FileInstanceID, &c.JobID, &c.Text, &c.Holder, &c.Years)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// GetCopyrightSummariesForRepoPull returns the copyright notices for
+// files in the RepoPull with the given ID, aggregated by holder and
+// ordered by holder.
+func (db *DB) GetCopyrightSummariesForRepoPull(rpID RepoPullID) ([]*CopyrightSummary, error) {
+	rows, err := db.sqldb.Query("SELECT c.holder, array_agg(DISTINCT c.text ORDER BY c.text), COUNT(DISTINCT c.fileinstance_id) FROM peridot.copyrights c JOIN peridot.file_instances fi ON fi.id = c.fileinstance_id WHERE fi.repopull_id = $1 GROUP BY c.holder ORDER BY c.holder", rpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	css := []*CopyrightSummary{}
+	for rows.Next() {
+		cs := &CopyrightSummary{}
+		err := rows.Scan(&cs.Holder, pq.Array(&cs.Statements), &cs.FileCount)
+		if err != nil {
+			return nil, err
+		}
+		css = append(css, cs)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return css, nil
+}
+
+// AddCopyrights adds the given copyright notices in a single
+// transaction, so that either all or none of them are added. The
+// notices' ID fields are ignored. It returns the new notices' IDs,
+// in the same order, on success or an error if failing.
+func (db *DB) AddCopyrights(copyrights []*Copyright) ([]uint64, error) {
+	for _, c := range copyrights {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.copyrights(fileinstance_id, job_id, text, holder, years) VALUES ($1, $2, $3, $4, $5) RETURNING id")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	ids := make([]uint64, 0, len(copyrights))
+	for _, c := range copyrights {
+		var id uint64
+		err = stmt.QueryRow(c.FileInstanceID, c.JobID, c.Text, c.Holder, c.Years).Scan(&id)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// DeleteCopyrightsForJob deletes all copyright notices detected by
+// the Job with the given ID, e.g. before re-running it. It returns
+// the number of notices deleted on success or an error if failing.
+func (db *DB) DeleteCopyrightsForJob(jobID JobID) (int64, error) {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.copyrights WHERE job_id = $1", jobID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetCopyrightsForFileInstance(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "fileinstance_id", "job_id", "text", "holder", "years"}).
+		AddRow(201, 7, 12, "Copyright (c) 2015-2019 The Linux Foundation", "The Linux Foundation", "2015-2019").
+		AddRow(202, 7, 12, "(C) Jane Doe", "Jane Doe", "")
+	mock.ExpectQuery(`SELECT c.id, c.fileinstance_id, c.job_id, c.text, c.holder, c.years FROM peridot.copyrights c WHERE c.fileinstance_id = \$1 ORDER BY c.id`).
+		WithArgs(7).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetCopyrightsForFileInstance(7)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*Copyright{
+		&Copyright{ID: 201, FileInstanceID: 7, JobID: 12, Text: "Copyright (c) 2015-2019 The Linux Foundation", Holder: "The Linux Foundation", Years: "2015-2019"},
+		&Copyright{ID: 202, FileInstanceID: 7, JobID: 12, Text: "(C) Jane Doe", Holder: "Jane Doe"},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldGetCopyrightSummariesForRepoPull(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"holder", "array_agg", "count"}).
+		AddRow("Jane Doe", `{"(C) Jane Doe","Copyright 2018 Jane Doe"}`, 3).
+		AddRow("The Linux Foundation", `{"Copyright (c) 2015-2019 The Linux Foundation"}`, 41)
+	mock.ExpectQuery(`SELECT c.holder, array_agg\(DISTINCT c.text ORDER BY c.text\), COUNT\(DISTINCT c.fileinstance_id\) FROM peridot.copyrights c JOIN peridot.file_instances fi ON fi.id = c.fileinstance_id WHERE fi.repopull_id = \$1 GROUP BY c.holder ORDER BY c.holder`).
+		WithArgs(36).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetCopyrightSummariesForRepoPull(36)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*CopyrightSummary{
+		&CopyrightSummary{Holder: "Jane Doe", Statements: []string{"(C) Jane Doe", "Copyright 2018 Jane Doe"}, FileCount: 3},
+		&CopyrightSummary{Holder: "The Linux Foundation", Statements: []string{"Copyright (c) 2015-2019 The Linux Foundation"}, FileCount: 41},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldAddCopyrights(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.copyrights\(fileinstance_id, job_id, text, holder, years\) VALUES \(\$1, \$2, \$3, \$4, \$5\) RETURNING id`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(7, 12, "(C) Jane Doe", "Jane Doe", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(202))
+	mock.ExpectCommit()
+
+	// run the tested function
+	ids, err := db.AddCopyrights([]*Copyright{
+		&Copyright{FileInstanceID: 7, JobID: 12, Text: "(C) Jane Doe", Holder: "Jane Doe"},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if !reflect.DeepEqual([]uint64{202}, ids) {
+		t.Errorf("expected %v, got %v", []uint64{202}, ids)
+	}
+}
+
+func TestShouldRollbackAddCopyrightsOnError(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.copyrights`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(7, 12, "(C) Jane Doe", "Jane Doe", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(202))
+	mock.ExpectQuery(regexStmt).
+		WithArgs(413, 12, "(C) John Doe", "John Doe", "").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	// run the tested function
+	_, err = db.AddCopyrights([]*Copyright{
+		&Copyright{FileInstanceID: 7, JobID: 12, Text: "(C) Jane Doe", Holder: "Jane Doe"},
+		&Copyright{FileInstanceID: 413, JobID: 12, Text: "(C) John Doe", Holder: "John Doe"},
+	})
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
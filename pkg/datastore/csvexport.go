@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvTime formats a time for CSV export, leaving zero times empty.
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// csvFormulaPrefixes are the leading characters that make
+// spreadsheet applications treat a cell as a formula.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvCell neutralises a value that a spreadsheet would otherwise
+// evaluate as a formula, such as a user name of "=HYPERLINK(...)",
+// by prefixing it with a single quote.
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune(csvFormulaPrefixes, rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// writeCSV writes header and then one record per row to w as CSV,
+// streaming from the database cursor rather than loading all rows
+// first. record is called after each rows.Next() to scan the
+// current row and convert it to a CSV record, whose cells are then
+// passed through csvCell.
+func writeCSV(w io.Writer, rows *sql.Rows, header []string, record func(*sql.Rows) ([]string, error)) error {
+	defer rows.Close()
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for rows.Next() {
+		rec, err := record(rows)
+		if err != nil {
+			return err
+		}
+		for i := range rec {
+			rec[i] = csvCell(rec[i])
+		}
+		if err = cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteUsersCSV writes all users to w as CSV, with a header row,
+// ordered by ID. It returns nil on success or an error if failing.
+func (db *DB) WriteUsersCSV(w io.Writer) error {
+	rows, err := db.sqldb.Query("SELECT " + userColumns + " FROM peridot.users ORDER BY id")
+	if err != nil {
+		return err
+	}
+
+	header := []string{"id", "name", "github", "email", "access", "kind", "last_login_at", "login_count"}
+	return writeCSV(w, rows, header, func(rows *sql.Rows) ([]string, error) {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		return []string{
+			fmt.Sprint(user.ID),
+			user.Name,
+			user.Github,
+			user.Email,
+			user.AccessLevel.String(),
+			user.Kind.String(),
+			csvTime(user.LastLoginAt),
+			fmt.Sprint(user.LoginCount),
+		}, nil
+	})
+}
+
+// WriteReposCSV writes all repos to w as CSV, with a header row,
+// ordered by ID. It returns nil on success or an error if failing.
+func (db *DB) WriteReposCSV(w io.Writer) error {
+	rows, err := db.sqldb.Query("SELECT id, subproject_id, name, address FROM peridot.repos WHERE " + liveRepoCondition + " ORDER BY id")
+	if err != nil {
+		return err
+	}
+
+	header := []string{"id", "subproject_id", "name", "address"}
+	return writeCSV(w, rows, header, func(rows *sql.Rows) ([]string, error) {
+		repo := &Repo{}
+		err := rows.Scan(&repo.ID, &repo.SubprojectID, &repo.Name, &repo.Address)
+		if err != nil {
+			return nil, err
+		}
+		return []string{fmt.Sprint(repo.ID), fmt.Sprint(repo.SubprojectID), repo.Name, repo.Address}, nil
+	})
+}
+
+// WriteRepoPullsCSV writes all repo pulls to w as CSV, with a header
+// row, ordered by ID. The pulls' output is not included. It returns
+// nil on success or an error if failing.
+func (db *DB) WriteRepoPullsCSV(w io.Writer) error {
+	rows, err := db.sqldb.Query("SELECT " + repoPullColumns + " FROM peridot.repo_pulls WHERE repo_id IN (" + liveRepoIDs + ") ORDER BY id")
+	if err != nil {
+		return err
+	}
+
+	header := []string{"id", "repo_id", "branch", "commit", "tag", "status", "health", "started_at", "finished_at"}
+	return writeCSV(w, rows, header, func(rows *sql.Rows) ([]string, error) {
+		rp, err := scanRepoPull(rows)
+		if err != nil {
+			return nil, err
+		}
+		return []string{
+			fmt.Sprint(rp.ID),
+			fmt.Sprint(rp.RepoID),
+			rp.Branch,
+			rp.Commit,
+			rp.Tag,
+			rp.Status.String(),
+			rp.Health.String(),
+			csvTime(rp.StartedAt),
+			csvTime(rp.FinishedAt),
+		}, nil
+	})
+}
+
+// WriteJobsCSV writes all jobs to w as CSV, with a header row,
+// ordered by ID. The jobs' output, prior job IDs and configs are not
+// included. It returns nil on success or an error if failing.
+func (db *DB) WriteJobsCSV(w io.Writer) error {
+	rows, err := db.sqldb.Query("SELECT " + jobColumns + " FROM peridot.jobs WHERE repopull_id IN (" + liveRepoPullIDs + ") ORDER BY id")
+	if err != nil {
+		return err
+	}
+
+	header := []string{"id", "repopull_id", "agent_id", "status", "health", "is_ready", "started_at", "finished_at"}
+	return writeCSV(w, rows, header, func(rows *sql.Rows) ([]string, error) {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		return []string{
+			fmt.Sprint(j.ID),
+			fmt.Sprint(j.RepoPullID),
+			fmt.Sprint(j.AgentID),
+			j.Status.String(),
+			j.Health.String(),
+			strconv.FormatBool(j.IsReady),
+			csvTime(j.StartedAt),
+			csvTime(j.FinishedAt),
+		}, nil
+	})
+}
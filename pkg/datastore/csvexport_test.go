@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldWriteUsersCSV(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	lastLogin := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(410952, "johndoe", "Doe, John", "johndoe@example.com", AccessCommenter, 0, lastLogin, 3, "", "", "", "", testCreatedAt, testUpdatedAt).
+		AddRow(2000000001, "", "ci-bot", nil, AccessOperator, 1, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery("SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users ORDER BY id").WillReturnRows(sentRows)
+
+	// run the tested function
+	var buf bytes.Buffer
+	err = db.WriteUsersCSV(&buf)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check written values
+	want := "id,name,github,email,access,kind,last_login_at,login_count\n" +
+		"410952,\"Doe, John\",johndoe,johndoe@example.com,commenter,human,2019-05-02T13:53:41Z,3\n" +
+		"2000000001,ci-bot,,,operator,service,,0\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestShouldNeutraliseFormulasInReposCSV(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "subproject_id", "name", "address"}).
+		AddRow(1, 5, "=HYPERLINK(\"https://evil.example.com\")", "@SUM(A1:A2)").
+		AddRow(2, 5, "+cmd", "-2+3").
+		AddRow(3, 5, "kubernetes", "https://github.com/kubernetes/kubernetes")
+	mock.ExpectQuery("SELECT id, subproject_id, name, address FROM peridot.repos").WillReturnRows(sentRows)
+
+	// run the tested function
+	var buf bytes.Buffer
+	err = db.WriteReposCSV(&buf)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check written values
+	want := "id,subproject_id,name,address\n" +
+		"1,5,\"'=HYPERLINK(\"\"https://evil.example.com\"\")\",'@SUM(A1:A2)\n" +
+		"2,5,'+cmd,'-2+3\n" +
+		"3,5,kubernetes,https://github.com/kubernetes/kubernetes\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestShouldWriteJobsCSV(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sa := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "repopull_id", "agent_id", "started_at", "finished_at", "status", "health", "output", "is_ready", "version", "created_at", "updated_at"}).
+		AddRow(4, 14, 6, sa, time.Time{}, StatusRunning, HealthOK, nil, true, 2, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready, version, created_at, updated_at FROM peridot.jobs WHERE repopull_id IN \(` + liveRepoPullIDsRegex + `\) ORDER BY id`).WillReturnRows(sentRows)
+
+	// run the tested function
+	var buf bytes.Buffer
+	err = db.WriteJobsCSV(&buf)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	want := "id,repopull_id,agent_id,status,health,is_ready,started_at,finished_at\n" +
+		"4,14,6,running,ok,true,2019-05-02T13:53:41Z,\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestShouldFailWriteRepoPullsCSVWithInvalidStatus(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "repo_id", "branch", "started_at", "finished_at", "status", "health", "output", "commit", "tag", "spdx_id", "created_at", "updated_at"}).
+		AddRow(36, 15, "master", time.Time{}, time.Time{}, 57, HealthOK, nil, nil, nil, nil, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id, created_at, updated_at FROM peridot.repo_pulls WHERE repo_id IN \(` + liveRepoIDsRegex + `\) ORDER BY id`).WillReturnRows(sentRows)
+
+	// run the tested function
+	var buf bytes.Buffer
+	err = db.WriteRepoPullsCSV(&buf)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldWriteRepoPullsCSVWithNullCommitAndTag(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sa := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "repo_id", "branch", "started_at", "finished_at", "status", "health", "output", "commit", "tag", "spdx_id", "created_at", "updated_at"}).
+		AddRow(36, 15, "master", sa, time.Time{}, StatusRunning, HealthOK, nil, nil, nil, nil, testCreatedAt, testUpdatedAt).
+		AddRow(37, 15, "master", time.Time{}, time.Time{}, StatusStartup, HealthOK, nil, "4b825dc642cb6eb9a060e54bf8d69288fbee4904", "v1.0", nil, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT (.+) FROM peridot.repo_pulls WHERE repo_id IN \(` + liveRepoIDsRegex + `\) ORDER BY id`).WillReturnRows(sentRows)
+
+	// run the tested function
+	var buf bytes.Buffer
+	err = db.WriteRepoPullsCSV(&buf)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	want := "id,repo_id,branch,commit,tag,status,health,started_at,finished_at\n" +
+		"36,15,master,,,running,ok,2019-05-02T13:53:41Z,\n" +
+		"37,15,master,4b825dc642cb6eb9a060e54bf8d69288fbee4904,v1.0,startup,ok,,\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
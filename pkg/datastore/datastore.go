@@ -1,76 +1,532 @@
 // Package datastore defines the database and in-memory models for all
 // data in peridot.
+//
+// Values returned by the Datastore are owned by the caller: the
+// datastore keeps no references to them, and does not retain the
+// values passed to it. Copying a struct that contains maps or slices,
+// such as a Job, still shares them with the original, so use its
+// Clone method to take an independent copy before modifying it.
+//
+// Timestamps are stored and returned in UTC, truncated to microsecond
+// precision, so a time passed to an Add or Update method compares
+// equal, with ==, to the time later read back. The zero time.Time
+// means "not set" and is left unchanged.
 // SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
 package datastore
 
-import "time"
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
 
 // Datastore defines the interface to be implemented by models
 // for database tables, using either a backing database (production)
-// or mocks (test).
+// or mocks (test). It is the union of the smaller interfaces below,
+// so code that only needs part of it, and mocks for that code, can
+// depend on just the parts it uses.
 type Datastore interface {
+	AdminStore
+	TxStore
+	UserStore
+	OrganizationStore
+	ProjectStore
+	RepoPullStore
+	FileStore
+	AgentStore
+	JobStore
+	FindingStore
+	PolicyStore
+	ReportStore
+	CollaborationStore
+	EventStore
+	BulkStore
+}
+
+// AdminStore is the part of Datastore for administering the
+// database as a whole: resetting it, encryption keys, statistics,
+// exports, the audit log and retention policies.
+type AdminStore interface {
 	// ===== Administrative actions =====
 	// ResetDB drops the current schema and initializes a new one.
 	// NOTE that if the initial Github user is not defined in an
 	// environment variable, the new DB will not have an admin user!
 	ResetDB() error
 
+	// ===== Encryption =====
+	// RotateSecrets re-encrypts, with the current key, every stored
+	// secret that is plaintext or encrypted with an older key, in
+	// batches of up to batchSize rows. It returns the number of
+	// secrets re-encrypted on success or an error if failing.
+	RotateSecrets(batchSize uint32) (int64, error)
+
+	// ===== Database statistics =====
+	// GetDBStats returns the approximate row counts and on-disk
+	// sizes of the tables in the peridot schema.
+	GetDBStats() (*DBStats, error)
+
+	// ===== CSV export =====
+	// WriteUsersCSV writes all users to w as CSV, with a header row,
+	// ordered by ID. It returns nil on success or an error if failing.
+	WriteUsersCSV(w io.Writer) error
+	// WriteReposCSV writes all repos to w as CSV, with a header row,
+	// ordered by ID. It returns nil on success or an error if failing.
+	WriteReposCSV(w io.Writer) error
+	// WriteRepoPullsCSV writes all repo pulls to w as CSV, with a
+	// header row, ordered by ID. It returns nil on success or an
+	// error if failing.
+	WriteRepoPullsCSV(w io.Writer) error
+	// WriteJobsCSV writes all jobs to w as CSV, with a header row,
+	// ordered by ID. It returns nil on success or an error if failing.
+	WriteJobsCSV(w io.Writer) error
+
+	// ===== Audit log =====
+	// AddAuditEntry records that the user with ID actorID performed
+	// the given action on the entity identified by entity and
+	// entityID, with optional before and after snapshots. It returns
+	// nil on success or an error if failing.
+	AddAuditEntry(actorID UserID, entity string, entityID string, action string, before interface{}, after interface{}) error
+	// GetAuditEntries returns a slice of audit entries for the given
+	// entity kind, recorded at or after since and before until. If
+	// entityID is non-empty, only entries for that entity are
+	// returned. If until is the zero value, there is no upper bound.
+	GetAuditEntries(entity string, entityID string, since time.Time, until time.Time) ([]*AuditEntry, error)
+	// GetAuditEntriesByActor returns a slice of all audit entries for
+	// changes made by the user with the given ID.
+	GetAuditEntriesByActor(actorID UserID) ([]*AuditEntry, error)
+
+	// ===== RetentionPolicies =====
+	// GetRetentionPolicies returns a slice of all retention
+	// policies.
+	GetRetentionPolicies() ([]*RetentionPolicy, error)
+	// GetRetentionPolicy returns the RetentionPolicy for the given
+	// scope, or nil and an error if not found.
+	GetRetentionPolicy(scope string) (*RetentionPolicy, error)
+	// SetRetentionPolicy creates or replaces the RetentionPolicy for
+	// the given scope. It returns nil on success or an error if
+	// failing.
+	SetRetentionPolicy(scope string, keepLatestN uint32, maxAge time.Duration) error
+	// DeleteRetentionPolicy deletes the RetentionPolicy for the
+	// given scope. It returns nil on success or an error if failing.
+	DeleteRetentionPolicy(scope string) error
+	// ApplyRetentionPolicies removes all data that has expired under
+	// the current retention policies. It returns a map from scope to
+	// the number of rows removed on success or an error if failing.
+	ApplyRetentionPolicies() (map[string]int64, error)
+}
+
+// TxStore is the part of Datastore for grouping calls into
+// transactions.
+type TxStore interface {
+	// ===== Transactions =====
+	// InTx calls fn with a Datastore whose methods all run within
+	// a single transaction, committing it if fn returns nil and
+	// rolling it back otherwise.
+	InTx(ctx context.Context, fn func(ds Datastore) error) error
+	// InSnapshot calls fn with a Datastore whose methods all run
+	// within a single read-only transaction, so that they see a
+	// consistent snapshot of the database.
+	InSnapshot(ctx context.Context, fn func(ds Datastore) error) error
+	// Savepoint marks the current point in the transaction that
+	// the Datastore is bound to with the given name. It returns
+	// ErrNotInTx if the Datastore is not bound to a transaction.
+	Savepoint(name string) error
+	// RollbackToSavepoint undoes the changes made since the
+	// savepoint with the given name was set, so that the
+	// transaction can continue. It returns ErrNotInTx if the
+	// Datastore is not bound to a transaction.
+	RollbackToSavepoint(name string) error
+	// ReleaseSavepoint forgets the savepoint with the given name,
+	// keeping the changes made since it was set. It returns
+	// ErrNotInTx if the Datastore is not bound to a transaction.
+	ReleaseSavepoint(name string) error
+}
+
+// UserStore is the part of Datastore for users, their tokens,
+// identities and preferences, and invitations.
+type UserStore interface {
 	// ===== Users =====
 	// GetAllUsers returns a slice of all users in the database.
 	GetAllUsers() ([]*User, error)
+	// CountAllUsers returns the number of users in the database.
+	CountAllUsers() (int, error)
+	// GetUsers returns a slice of users in the database matching
+	// the given filter, sorted and paged as requested, along with
+	// a Page describing the results. Users can be sorted by id,
+	// name, github or last_login_at.
+	GetUsers(filter UserFilter, pr PageRequest) ([]*User, Page, error)
+	// CountUsers returns the number of users in the database
+	// matching the given filter.
+	CountUsers(filter UserFilter) (int, error)
+	// GetUsersByAccessLevel returns a slice of all users in the
+	// database with the given access level.
+	GetUsersByAccessLevel(accessLevel UserAccessLevel) ([]*User, error)
+	// GetInactiveUsers returns a slice of all human users who are
+	// not disabled and who have not logged in at or after the given
+	// time, including users who have never logged in.
+	GetInactiveUsers(since time.Time) ([]*User, error)
+	// GetServiceAccounts returns a slice of all service accounts in
+	// the database.
+	GetServiceAccounts() ([]*User, error)
 	// GetUserByID returns the User with the given user ID, or nil
 	// and an error if not found.
-	GetUserByID(id uint32) (*User, error)
+	GetUserByID(id UserID) (*User, error)
+	// ExistsUser reports whether a User with the given user ID
+	// exists, without retrieving it.
+	ExistsUser(id UserID) (bool, error)
 	// GetUserByGithub returns the User with the given Github user
 	// name, or nil and an error if not found.
 	GetUserByGithub(github string) (*User, error)
-	// AddUser adds a new User with the given user ID, name, github
-	// user name, and access level. It returns nil on success or an
-	// error if failing.
-	AddUser(id uint32, name string, github string, accessLevel UserAccessLevel) error
+	// AddUser adds a new human User with the given user ID, name, github
+	// user name, email address, and access level. It returns nil on
+	// success or an error if failing.
+	AddUser(id UserID, name string, github string, email string, accessLevel UserAccessLevel) error
+	// AddUserAutoID adds a new User of the given kind with the given
+	// name, Github user name, email address and access level,
+	// assigning it the next free ID from a sequence reserved above
+	// the Github ID range. It returns the new user's ID on success or
+	// an error if failing.
+	AddUserAutoID(name string, github string, email string, accessLevel UserAccessLevel, kind UserKind) (UserID, error)
+	// AddServiceAccount adds a new service account User with the
+	// given user ID, name and access level. It returns nil on success
+	// or an error if failing.
+	AddServiceAccount(id UserID, name string, accessLevel UserAccessLevel) error
 	// UpdateUser updates an existing User with the given ID,
-	// changing to the specified username, Github ID and and access
-	// level. It returns nil on success or an error if failing.
-	UpdateUser(id uint32, newName string, newGithub string, newAccessLevel UserAccessLevel) error
+	// changing to the specified username, Github ID, email address
+	// and access level. It returns nil on success or an error if
+	// failing.
+	UpdateUser(id UserID, newName string, newGithub string, newEmail string, newAccessLevel UserAccessLevel) error
 	// UpdateUserNameOnly updates an existing User with the given ID,
 	// changing to the specified username. It returns nil on success
 	// or an error if failing.
-	UpdateUserNameOnly(id uint32, newName string) error
+	UpdateUserNameOnly(id UserID, newName string) error
+	// UpdateUserProfile updates an existing User with the given ID,
+	// changing to the specified avatar URL, pronouns, title and
+	// organization. It returns nil on success or an error if failing.
+	UpdateUserProfile(id UserID, avatarURL string, pronouns string, title string, organization string) error
+	// UpdateUserAvatarOnly updates an existing User with the given ID,
+	// changing to the specified avatar URL. It returns nil on success
+	// or an error if failing.
+	UpdateUserAvatarOnly(id UserID, avatarURL string) error
+	// RecordUserLogin records that the User with the given ID has
+	// just logged in, updating the user's last login time and login
+	// count. It returns nil on success or an error if failing.
+	RecordUserLogin(id UserID) error
+	// SyncUsers brings the users table in line with the given specs
+	// in a single transaction, creating missing users, updating
+	// changed details and, if disableAbsent is true, disabling
+	// non-admin human users not in specs. It returns the changes made
+	// on success or an error if failing.
+	SyncUsers(specs []UserSpec, disableAbsent bool) (*SyncUsersResult, error)
+	// DeleteUser deletes an existing User with the given ID, along
+	// with the user's API tokens, project access grants and
+	// notifications. Deleting the only remaining admin user is
+	// refused with a *UserDeleteBlockedError. It returns nil on
+	// success or an error if failing.
+	DeleteUser(id UserID) error
+
+	// ExportUserData returns all records tied to the User with the
+	// given ID, or nil and an error if failing.
+	ExportUserData(id UserID) (*UserDataExport, error)
+	// AnonymizeUser scrubs the personal fields of the User with the
+	// given ID, while keeping its ID so that references to it remain
+	// valid. It returns nil on success or an error if failing.
+	AnonymizeUser(id UserID) error
+
+	// ===== UserTokens =====
+	// CreateToken creates a new API token for the User with the given
+	// ID, granting the given scopes. If expiresAt is the zero value,
+	// the token does not expire. It returns the new token's ID and the
+	// token itself on success, or an error if failing.
+	CreateToken(userID UserID, scopes []string, expiresAt time.Time) (uint32, string, error)
+	// ValidateToken looks up the given API token, and if it exists and
+	// has not expired, records that it was used and returns it. It
+	// returns nil and an error if the token is unknown or expired.
+	ValidateToken(token string) (*UserToken, error)
+	// ListTokensForUser returns a slice of all API tokens issued to
+	// the User with the given ID, including expired tokens.
+	ListTokensForUser(userID UserID) ([]*UserToken, error)
+	// RevokeToken deletes the API token with the given ID. It returns
+	// nil on success or an error if failing.
+	RevokeToken(id uint32) error
+
+	// ===== TokenRateLimits =====
+	// IncrementAndCheck counts one request made with the API token
+	// with the given ID in the current rate limit window, and reports
+	// whether the token is still within limit requests for that
+	// window, along with the number of requests counted so far.
+	IncrementAndCheck(tokenID uint32, limit uint32, window time.Duration) (bool, uint32, error)
+	// PruneTokenRateLimits deletes rate limit counts for all windows
+	// that started before the given time. It returns the number of
+	// windows deleted on success or an error if failing.
+	PruneTokenRateLimits(before time.Time) (int64, error)
+
+	// ===== UserIdentities =====
+	// GetIdentitiesForUser returns a slice of all identities linked
+	// to the User with the given ID.
+	GetIdentitiesForUser(userID UserID) ([]*UserIdentity, error)
+	// GetUserByIdentity returns the User to whom the identity with
+	// the given provider and subject is linked, or nil and an error
+	// if not found.
+	GetUserByIdentity(provider string, subject string) (*User, error)
+	// AddIdentity links a new identity with the given provider,
+	// subject and email to the User with the given ID. It returns
+	// the new identity's ID on success or an error if failing.
+	AddIdentity(userID UserID, provider string, subject string, email string) (uint32, error)
+	// DeleteIdentity unlinks the identity with the given ID from its
+	// user. It returns nil on success or an error if failing.
+	DeleteIdentity(id uint32) error
+
+	// ===== UserPreferences =====
+	// GetUserPreferences returns all preferences stored for the User
+	// with the given ID, as a map from preference key to JSON value.
+	GetUserPreferences(userID UserID) (map[string]json.RawMessage, error)
+	// GetUserPreference returns the JSON value of the preference with
+	// the given key for the User with the given ID, or nil and an
+	// error if not found.
+	GetUserPreference(userID UserID, key string) (json.RawMessage, error)
+	// SetUserPreference stores the JSON encoding of value as the
+	// preference with the given key for the User with the given ID,
+	// replacing any existing value. It returns nil on success or an
+	// error if failing.
+	SetUserPreference(userID UserID, key string, value interface{}) error
+	// DeleteUserPreference removes the preference with the given key
+	// for the User with the given ID. It returns nil on success or
+	// an error if failing.
+	DeleteUserPreference(userID UserID, key string) error
 
+	// ===== Invitations =====
+	// GetPendingInvitations returns a slice of all invitations that
+	// have been neither accepted nor expired.
+	GetPendingInvitations() ([]*Invitation, error)
+	// GetInvitationByID returns the Invitation with the given ID, or
+	// nil and an error if not found.
+	GetInvitationByID(id uint32) (*Invitation, error)
+	// GetInvitationsForUser returns a slice of all invitations
+	// created by, or accepted by, the User with the given ID.
+	GetInvitationsForUser(userID UserID) ([]*Invitation, error)
+	// CreateInvitation creates a new invitation from the user with ID
+	// inviterID for the invitee with the given email address and/or
+	// Github user name, proposing the given access level and expiring
+	// at expiresAt. It returns the new invitation's ID and the
+	// invitation token on success, or an error if failing.
+	CreateInvitation(inviterID UserID, email string, github string, accessLevel UserAccessLevel, expiresAt time.Time) (uint32, string, error)
+	// AcceptInvitation accepts the pending invitation with the given
+	// token, creating a new User with the given ID, name and Github
+	// user name. It returns the new User on success, or nil and an
+	// error if failing.
+	AcceptInvitation(token string, userID UserID, name string, github string) (*User, error)
+	// ExpireInvitation expires the pending invitation with the given
+	// ID immediately. It returns nil on success or an error if failing.
+	ExpireInvitation(id uint32) error
+}
+
+// OrganizationStore is the part of Datastore for organizations
+// and their quotas.
+type OrganizationStore interface {
+	// ===== Organizations =====
+	// GetAllOrganizations returns a slice of all organizations.
+	GetAllOrganizations() ([]*Organization, error)
+	// CountAllOrganizations returns the number of organizations in
+	// the database.
+	CountAllOrganizations() (int, error)
+	// GetOrganizationsForUser returns a slice of all organizations
+	// of which the User with the given ID is a member.
+	GetOrganizationsForUser(userID UserID) ([]*Organization, error)
+	// CountOrganizationsForUser returns the number of organizations
+	// of which the User with the given ID is a member.
+	CountOrganizationsForUser(userID UserID) (int, error)
+	// GetOrganizationByID returns the Organization with the given
+	// ID, or nil and an error if not found.
+	GetOrganizationByID(id OrgID) (*Organization, error)
+	// ExistsOrganization reports whether an Organization with the
+	// given ID exists, without retrieving it.
+	ExistsOrganization(id OrgID) (bool, error)
+	// AddOrganization adds a new Organization with the given short
+	// name and full name. It returns the new organization's ID on
+	// success or an error if failing.
+	AddOrganization(name string, fullname string) (OrgID, error)
+	// UpdateOrganization updates an existing Organization with the
+	// given ID, changing to the specified short name and full name.
+	// If an empty string is passed, the existing value will remain
+	// unchanged. It returns nil on success or an error if failing.
+	UpdateOrganization(id OrgID, newName string, newFullname string) error
+	// DeleteOrganization deletes the existing Organization with the
+	// given ID, together with all of its projects. It returns nil
+	// on success or an error if failing.
+	DeleteOrganization(id OrgID) error
+	// GetOrganizationMembers returns a slice of all members of the
+	// Organization with the given ID.
+	GetOrganizationMembers(orgID OrgID) ([]*OrganizationMember, error)
+	// GetOrganizationMember returns the membership of the User with
+	// the given ID in the Organization with the given ID, or nil and
+	// an error if the user is not a member.
+	GetOrganizationMember(orgID OrgID, userID UserID) (*OrganizationMember, error)
+	// AddOrganizationMember makes the User with the given ID a
+	// member of the Organization with the given ID at the given
+	// access level, replacing the access level if the user is
+	// already a member. It returns nil on success or an error if
+	// failing.
+	AddOrganizationMember(orgID OrgID, userID UserID, accessLevel UserAccessLevel) error
+	// RemoveOrganizationMember removes the User with the given ID
+	// from the Organization with the given ID. It returns nil on
+	// success or an error if failing.
+	RemoveOrganizationMember(orgID OrgID, userID UserID) error
+	// GetAllProjectsForOrganization returns a slice of all projects
+	// owned by the Organization with the given ID.
+	GetAllProjectsForOrganization(orgID OrgID) ([]*Project, error)
+	// CountProjectsForOrganization returns the number of projects
+	// owned by the Organization with the given ID.
+	CountProjectsForOrganization(orgID OrgID) (int, error)
+	// GetAllSubprojectsForOrganization returns a slice of all
+	// subprojects in projects owned by the Organization with the
+	// given ID.
+	GetAllSubprojectsForOrganization(orgID OrgID) ([]*Subproject, error)
+	// CountSubprojectsForOrganization returns the number of
+	// subprojects in projects owned by the Organization with the
+	// given ID.
+	CountSubprojectsForOrganization(orgID OrgID) (int, error)
+	// GetAllReposForOrganization returns a slice of all repos in
+	// projects owned by the Organization with the given ID.
+	GetAllReposForOrganization(orgID OrgID) ([]*Repo, error)
+	// CountReposForOrganization returns the number of repos in
+	// projects owned by the Organization with the given ID.
+	CountReposForOrganization(orgID OrgID) (int, error)
+
+	// ===== Quotas =====
+	// GetQuotaForProject returns the Quota for the Project with the
+	// given ID, or nil and an error if it has none.
+	GetQuotaForProject(projectID ProjectID) (*Quota, error)
+	// GetQuotaForOrganization returns the Quota for the Organization
+	// with the given ID, or nil and an error if it has none.
+	GetQuotaForOrganization(orgID OrgID) (*Quota, error)
+	// SetQuota creates or replaces the Quota for q's Project or
+	// Organization. It returns nil on success or an error if
+	// failing.
+	SetQuota(q *Quota) error
+	// DeleteQuota deletes the Quota with the given ID. It returns
+	// nil on success or an error if failing.
+	DeleteQuota(id uint32) error
+	// CheckQuota reports whether one more of the given resource can
+	// be added to the Project with the given ID without exceeding
+	// its quota or its organization's quota. It returns nil if so,
+	// a *QuotaExceededError if not, or another error if failing.
+	CheckQuota(projectID ProjectID, resource string) error
+}
+
+// ProjectStore is the part of Datastore for projects,
+// subprojects, repos and repo branches, and access to projects.
+type ProjectStore interface {
 	// ===== Projects =====
 	// GetAllProjects returns a slice of all projects in the database.
 	GetAllProjects() ([]*Project, error)
+	// CountAllProjects returns the number of projects in the
+	// database.
+	CountAllProjects() (int, error)
 	// GetProjectByID returns the Project with the given ID, or nil
 	// and an error if not found.
-	GetProjectByID(id uint32) (*Project, error)
+	GetProjectByID(id ProjectID) (*Project, error)
+	// ExistsProject reports whether a Project with the given ID
+	// exists, without retrieving it.
+	ExistsProject(id ProjectID) (bool, error)
 	// AddProject adds a new Project with the given short name and
 	// full name. It returns the new project's ID on success or an
 	// error if failing.
-	AddProject(name string, fullname string) (uint32, error)
+	AddProject(name string, fullname string) (ProjectID, error)
+	// GetOrCreateProject returns the Project with the given name,
+	// adding it with the given full name if it does not exist.
+	// Concurrent calls with the same name all return the same
+	// project. It also reports whether the project was added.
+	GetOrCreateProject(name string, fullname string) (*Project, bool, error)
 	// UpdateProject updates an existing Project with the given ID,
 	// changing to the specified short name and full name. If an
 	// empty string is passed, the existing value will remain
 	// unchanged. It returns nil on success or an error if failing.
-	UpdateProject(id uint32, newName string, newFullname string) error
-	// DeleteProject deletes an existing Project with the given ID.
-	// It returns nil on success or an error if failing.
-	DeleteProject(id uint32) error
+	UpdateProject(id ProjectID, newName string, newFullname string) error
+	// SetProjectOrganization moves the existing Project with the
+	// given ID into the Organization with the given ID, or out of
+	// any organization if orgID is 0. It returns nil on success or an
+	// error if failing.
+	SetProjectOrganization(id ProjectID, orgID OrgID) error
+	// DeleteProject deletes an existing Project with the given ID,
+	// or only marks it as deleted if soft deletion is enabled. It
+	// returns nil on success or an error if failing.
+	DeleteProject(id ProjectID) error
+	// RestoreProject restores the soft-deleted Project with the
+	// given ID. It returns nil on success or an error if failing.
+	RestoreProject(id ProjectID) error
+	// PurgeProject permanently deletes the soft-deleted Project
+	// with the given ID. It returns nil on success or an error if
+	// failing.
+	PurgeProject(id ProjectID) error
+
+	// ===== ProjectAccess =====
+	// GetProjectAccessForUser returns a slice of all project access
+	// grants for the User with the given ID.
+	GetProjectAccessForUser(userID UserID) ([]*ProjectAccess, error)
+	// GetProjectAccessForProject returns a slice of all project
+	// access grants for the Project with the given ID.
+	GetProjectAccessForProject(projectID ProjectID) ([]*ProjectAccess, error)
+	// GrantProjectAccess grants the User with the given ID the given
+	// access level for the Project with the given ID, replacing any
+	// existing grant. It returns nil on success or an error if failing.
+	GrantProjectAccess(userID UserID, projectID ProjectID, accessLevel UserAccessLevel) error
+	// RevokeProjectAccess removes the project access grant for the
+	// given user and project. It returns nil on success or an error
+	// if failing.
+	RevokeProjectAccess(userID UserID, projectID ProjectID) error
+	// EffectiveAccess returns the access level that the User with the
+	// given ID has for the Project with the given ID, taking into
+	// account any project access grant and falling back to the
+	// user's global access level.
+	EffectiveAccess(userID UserID, projectID ProjectID) (UserAccessLevel, error)
+
+	// ===== ProjectPipelines =====
+	// GetProjectPipeline returns the default pipeline of the
+	// Project with the given ID, or nil and a *NotFoundError if it
+	// has none.
+	GetProjectPipeline(projectID ProjectID) ([]JobTemplate, error)
+	// SetProjectPipeline stores pipeline as the default pipeline of
+	// the Project with the given ID, replacing any it already has,
+	// for AddRepoPullWithDefaultPipeline to instantiate. It returns
+	// a *ValidationError if a step refers to a step that does not
+	// come before it, nil on success or another error if failing.
+	SetProjectPipeline(projectID ProjectID, pipeline []JobTemplate) error
+	// DeleteProjectPipeline removes the default pipeline of the
+	// Project with the given ID. It returns a *NotFoundError if it
+	// has none, nil on success or another error if failing.
+	DeleteProjectPipeline(projectID ProjectID) error
 
 	// ===== Subprojects =====
 	// GetAllSubprojects returns a slice of all subprojects in the
 	// database.
 	GetAllSubprojects() ([]*Subproject, error)
+	// CountAllSubprojects returns the number of subprojects in the
+	// database.
+	CountAllSubprojects() (int, error)
 	// GetAllSubprojectsForProjectID returns a slice of all
 	// subprojects in the database for the given project ID.
-	GetAllSubprojectsForProjectID(projectID uint32) ([]*Subproject, error)
+	GetAllSubprojectsForProjectID(projectID ProjectID) ([]*Subproject, error)
+	// CountSubprojectsForProjectID returns the number of
+	// subprojects in the Project with the given ID.
+	CountSubprojectsForProjectID(projectID ProjectID) (int, error)
 	// GetSubprojectByID returns the Subproject with the given ID, or nil
 	// and an error if not found.
 	GetSubprojectByID(id uint32) (*Subproject, error)
+	// ExistsSubproject reports whether a Subproject with the given
+	// ID exists, without retrieving it.
+	ExistsSubproject(id uint32) (bool, error)
 	// AddSubproject adds a new subproject with the given short
 	// name and full name, referencing the designated Project. It
 	// returns the new subproject's ID on success or an error if
 	// failing.
-	AddSubproject(projectID uint32, name string, fullname string) (uint32, error)
+	AddSubproject(projectID ProjectID, name string, fullname string) (uint32, error)
+	// GetOrCreateSubproject returns the Subproject with the given
+	// name in the Project with the given ID, adding it with the
+	// given full name if it does not exist. It also reports
+	// whether the subproject was added.
+	GetOrCreateSubproject(projectID ProjectID, name string, fullname string) (*Subproject, bool, error)
 	// UpdateSubproject updates an existing Subproject with the
 	// given ID, changing to the specified short name and full
 	// name. If an empty string is passed, the existing value will
@@ -80,73 +536,188 @@ type Datastore interface {
 	// UpdateSubprojectProjectID updates an existing Subproject
 	// with the given ID, changing its corresponding Project ID.
 	// It returns nil on success or an error if failing.
-	UpdateSubprojectProjectID(id uint32, newProjectID uint32) error
+	UpdateSubprojectProjectID(id uint32, newProjectID ProjectID) error
 	// DeleteSubproject deletes an existing Subproject with the
-	// given ID. It returns nil on success or an error if failing.
+	// given ID, or only marks it as deleted if soft deletion is
+	// enabled. It returns nil on success or an error if failing.
 	DeleteSubproject(id uint32) error
+	// RestoreSubproject restores the soft-deleted Subproject with
+	// the given ID. It returns nil on success or an error if
+	// failing.
+	RestoreSubproject(id uint32) error
+	// PurgeSubproject permanently deletes the soft-deleted
+	// Subproject with the given ID. It returns nil on success or an
+	// error if failing.
+	PurgeSubproject(id uint32) error
 
 	// ===== Repos =====
 	// GetAllRepos returns a slice of all repos in the database.
 	GetAllRepos() ([]*Repo, error)
+	// CountAllRepos returns the number of repos in the database.
+	CountAllRepos() (int, error)
 	// GetAllReposForSubprojectID returns a slice of all repos in
 	// the database for the given subproject ID.
 	GetAllReposForSubprojectID(subprojectID uint32) ([]*Repo, error)
+	// CountReposForSubprojectID returns the number of repos in the
+	// Subproject with the given ID.
+	CountReposForSubprojectID(subprojectID uint32) (int, error)
 	// GetRepoByID returns the Repo with the given ID, or nil
 	// and an error if not found.
-	GetRepoByID(id uint32) (*Repo, error)
+	GetRepoByID(id RepoID) (*Repo, error)
+	// ExistsRepo reports whether a Repo with the given ID exists,
+	// without retrieving it.
+	ExistsRepo(id RepoID) (bool, error)
 	// AddRepo adds a new repo with the given name and address,
 	// referencing the designated Subproject. It returns the new
 	// repo's ID on success or an error if failing.
-	AddRepo(subprojectID uint32, name string, address string) (uint32, error)
+	AddRepo(subprojectID uint32, name string, address string) (RepoID, error)
+	// GetOrCreateRepo returns the Repo with the given name in the
+	// Subproject with the given ID, adding it with the given
+	// address if it does not exist. It also reports whether the
+	// repo was added.
+	GetOrCreateRepo(subprojectID uint32, name string, address string) (*Repo, bool, error)
+	// AddRepoWithUUID adds a new repo as in AddRepo, recording the
+	// client-supplied externalUUID with it. If a repo was already
+	// added with that UUID, nothing is added and the existing repo's
+	// ID is returned. created reports whether a new repo was added.
+	AddRepoWithUUID(externalUUID string, subprojectID uint32, name string, address string) (id RepoID, created bool, err error)
+	// GetRepoIDByExternalUUID returns the ID of the Repo that was
+	// added with the given external UUID, or a *NotFoundError if
+	// there is none.
+	GetRepoIDByExternalUUID(externalUUID string) (RepoID, error)
 	// UpdateRepo updates an existing Repo with the given ID,
 	// changing to the specified name and address. If an empty
 	// string is passed, the existing value will remain unchanged.
 	// It returns nil on success or an error if failing.
-	UpdateRepo(id uint32, newName string, newAddress string) error
+	UpdateRepo(id RepoID, newName string, newAddress string) error
 	// UpdateRepoSubprojectID updates an existing Repo with the
 	// given ID, changing its corresponding Subproject ID.
 	// It returns nil on success or an error if failing.
-	UpdateRepoSubprojectID(id uint32, newSubprojectID uint32) error
-	// DeleteRepo deletes an existing Repo with the given ID.
-	// It returns nil on success or an error if failing.
-	DeleteRepo(id uint32) error
+	UpdateRepoSubprojectID(id RepoID, newSubprojectID uint32) error
+	// DeleteRepo deletes an existing Repo with the given ID, or
+	// only marks it as deleted if soft deletion is enabled. It
+	// returns nil on success or an error if failing.
+	DeleteRepo(id RepoID) error
+	// RestoreRepo restores the soft-deleted Repo with the given
+	// ID. It returns nil on success or an error if failing.
+	RestoreRepo(id RepoID) error
+	// PurgeRepo permanently deletes the soft-deleted Repo with the
+	// given ID. It returns nil on success or an error if failing.
+	PurgeRepo(id RepoID) error
+	// PurgeDeletedBefore permanently deletes all projects,
+	// subprojects and repos that were soft-deleted before the given
+	// time. It returns the number deleted on success or an error if
+	// failing.
+	PurgeDeletedBefore(before time.Time) (int64, error)
 
 	// ===== RepoBranches =====
 	// GetAllRepoBranchesForRepoID returns a slice of all repo
 	// branches in the database for the given Repo ID.
-	GetAllRepoBranchesForRepoID(repoID uint32) ([]*RepoBranch, error)
+	GetAllRepoBranchesForRepoID(repoID RepoID) ([]*RepoBranch, error)
+	// CountRepoBranchesForRepoID returns the number of branches of
+	// the Repo with the given ID.
+	CountRepoBranchesForRepoID(repoID RepoID) (int, error)
+	// ExistsRepoBranch reports whether the Repo with the given ID
+	// has a branch with the given name.
+	ExistsRepoBranch(repoID RepoID, branch string) (bool, error)
 	// AddRepoBranch adds a new repo branch as specified,
 	// referencing the designated Repo. It returns nil on
 	// success or an error if failing.
-	AddRepoBranch(repoID uint32, branch string) error
+	AddRepoBranch(repoID RepoID, branch string) error
+	// UpsertRepoBranch adds a new repo branch as specified,
+	// referencing the designated Repo, unless the branch is
+	// already present, in which case it does nothing. It
+	// returns whether the branch was added, or an error if
+	// failing.
+	UpsertRepoBranch(repoID RepoID, branch string) (bool, error)
 	// DeleteRepoBranch deletes an existing RepoBranch with
 	// the given branch name for the given repo ID.
 	// It returns nil on success or an error if failing.
-	DeleteRepoBranch(repoID uint32, branch string) error
+	DeleteRepoBranch(repoID RepoID, branch string) error
+}
 
+// RepoPullStore is the part of Datastore for repo pulls.
+type RepoPullStore interface {
 	// ===== RepoPulls =====
 	// GetAllRepoPullsForRepoBranch returns a slice of all repo
 	// pulls in the database for the given Repo ID and branch.
-	GetAllRepoPullsForRepoBranch(repoID uint32, branch string) ([]*RepoPull, error)
+	GetAllRepoPullsForRepoBranch(repoID RepoID, branch string) ([]*RepoPull, error)
+	// GetRepoPulls returns a slice of repo pulls of the given
+	// branch of the Repo with the given ID, sorted and paged as
+	// requested, along with a Page describing the results. Repo
+	// pulls can be sorted by id, started_at, finished_at, status
+	// or health.
+	GetRepoPulls(repoID RepoID, branch string, pr PageRequest) ([]*RepoPull, Page, error)
+	// GetRepoPullsModifiedSince returns up to limit repo pulls
+	// that were added or updated after the position given by
+	// cursor, ordered by modification time and then by ID. Unlike
+	// the other keyset-paginated methods, it returns a non-nil
+	// cursor to pass to the next call whenever it succeeds, which
+	// is a copy of cursor if there were no results.
+	GetRepoPullsModifiedSince(cursor KeysetCursor, limit uint32) ([]*RepoPull, *KeysetCursor, error)
+	// CountRepoPullsForRepoBranch returns the number of repo pulls
+	// of the given branch of the Repo with the given ID.
+	CountRepoPullsForRepoBranch(repoID RepoID, branch string) (int, error)
 	// GetRepoPullByID returns the RepoPull with the given ID,
 	// or nil and an error if not found.
-	GetRepoPullByID(id uint32) (*RepoPull, error)
+	GetRepoPullByID(id RepoPullID) (*RepoPull, error)
+	// ExistsRepoPull reports whether a RepoPull with the given ID
+	// exists, without retrieving it.
+	ExistsRepoPull(id RepoPullID) (bool, error)
+	// GetRepoPullProgress returns the RepoPullProgress for the
+	// RepoPull with the given ID, counting its jobs within the
+	// database. It returns a *NotFoundError if there is no such
+	// repo pull.
+	GetRepoPullProgress(rpID RepoPullID) (*RepoPullProgress, error)
 	// AddRepoPull adds a new repo pull as specified,
 	// referencing the designated Repo, branch and other data,
 	// filling in nil start/finish times and output, and
 	// default startup status / health. It returns the new
 	// repo pull's ID on success or an error if failing.
-	AddRepoPull(repoID uint32, branch string, commit string, tag string, spdxID string) (uint32, error)
+	AddRepoPull(repoID RepoID, branch string, commit string, tag string, spdxID string) (RepoPullID, error)
 	// AddFullRepoPull adds a new repo pull with full specified
 	// data, referencing the designated Repo, branch and other
 	// data. It returns the new repo pull's ID on success or an
 	// error if failing.
-	AddFullRepoPull(repoID uint32, branch string, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string, commit string, tag string, spdxID string) (uint32, error)
+	AddFullRepoPull(repoID RepoID, branch string, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string, commit string, tag string, spdxID string) (RepoPullID, error)
+	// AddRepoPullWithUUID adds a new repo pull as in
+	// AddFullRepoPull, recording the client-supplied externalUUID
+	// with it. If a repo pull was already added with that UUID,
+	// nothing is added and the existing repo pull's ID is returned.
+	// created reports whether a new repo pull was added.
+	AddRepoPullWithUUID(externalUUID string, repoID RepoID, branch string, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string, commit string, tag string, spdxID string) (id RepoPullID, created bool, err error)
+	// GetRepoPullIDByExternalUUID returns the ID of the RepoPull
+	// that was added with the given external UUID, or a
+	// *NotFoundError if there is none.
+	GetRepoPullIDByExternalUUID(externalUUID string) (RepoPullID, error)
+	// AddRepoPullWithPipeline adds a new repo pull as in
+	// AddRepoPull, together with a Job for each step of pipeline,
+	// all in a single transaction. It returns the new repo pull's
+	// ID and the IDs of its jobs, in pipeline order, on success or
+	// an error if failing.
+	AddRepoPullWithPipeline(repoID RepoID, branch string, commit string, tag string, spdxID string, pipeline []JobTemplate) (RepoPullID, []JobID, error)
+	// AddRepoPullWithDefaultPipeline adds a new repo pull as in
+	// AddRepoPull, together with a Job for each step of the
+	// default pipeline of the repo's project, all in a single
+	// transaction. If the project has no default pipeline, the pull
+	// is added without any jobs. It returns the new repo pull's ID
+	// and the IDs of its jobs, in pipeline order, on success or an
+	// error if failing.
+	AddRepoPullWithDefaultPipeline(repoID RepoID, branch string, commit string, tag string, spdxID string) (RepoPullID, []JobID, error)
+	// UpdateRepoPullStatus sets the status variables for the
+	// RepoPull with the given ID. It returns a *TransitionError
+	// if the repo pull's current status cannot move to status,
+	// nil on success or another error if failing.
+	UpdateRepoPullStatus(id RepoPullID, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string) error
 	// DeleteRepoPull deletes an existing RepoPull with the
 	// given ID. It returns nil on success or an error if
 	// failing.
-	DeleteRepoPull(id uint32) error
+	DeleteRepoPull(id RepoPullID) error
+}
 
+// FileStore is the part of Datastore for file hashes and file
+// instances.
+type FileStore interface {
 	// ===== FileHashes =====
 	// GetFileHashByID returns the FileHash with the given ID,
 	// or nil and an error if not found.
@@ -168,55 +739,143 @@ type Datastore interface {
 	// failing.
 	DeleteFileHash(id uint64) error
 
-	// ===== FileInstancees =====
+	// ===== FileInstances =====
 	// GetFileInstanceByID returns the FileInstance with the given ID,
 	// or nil and an error if not found.
 	GetFileInstanceByID(id uint64) (*FileInstance, error)
+	// GetFileInstances returns a slice of file instances in the
+	// RepoPull with the given ID, sorted and paged as requested,
+	// along with a Page describing the results. File instances
+	// can be sorted by id or path.
+	GetFileInstances(rpID RepoPullID, pr PageRequest) ([]*FileInstance, Page, error)
+	// GetFileInstancesForRepoPullAfter returns up to limit file
+	// instances in the RepoPull with the given ID, ordered by ID
+	// and starting after cursor.AfterID. It also returns the
+	// cursor for the next page, or nil if there are no more
+	// results.
+	GetFileInstancesForRepoPullAfter(rpID RepoPullID, cursor KeysetCursor, limit uint32) ([]*FileInstance, *KeysetCursor, error)
 	// AddFileInstance adds a new file instance as specified,
 	// requiring its parent RepoPull ID and path within it,
 	// and the corresponding FileHash ID. It returns the new
 	// file instance's ID on success or an error if failing.
-	AddFileInstance(repoPullID uint32, fileHashID uint64, path string) (uint64, error)
+	AddFileInstance(repoPullID RepoPullID, fileHashID uint64, path string) (uint64, error)
 	// DeleteFileInstance deletes an existing file instance
 	// with the given ID. It returns nil on success or an
 	// if failing.
 	DeleteFileInstance(id uint64) error
+}
 
+// AgentStore is the part of Datastore for agents.
+type AgentStore interface {
 	// ===== Agents =====
 	// GetAllAgents returns a slice of all agents in the database.
 	GetAllAgents() ([]*Agent, error)
+	// CountAllAgents returns the number of agents in the database.
+	CountAllAgents() (int, error)
+	// GetAgents returns a slice of agents in the database matching
+	// the given filter, sorted and paged as requested, along with
+	// a Page describing the results. Agents can be sorted by id,
+	// name or health.
+	GetAgents(filter AgentFilter, pr PageRequest) ([]*Agent, Page, error)
+	// CountAgents returns the number of agents in the database
+	// matching the given filter.
+	CountAgents(filter AgentFilter) (int, error)
 	// GetAgentByID returns the Agent with the given ID, or nil
 	// and an error if not found.
-	GetAgentByID(id uint32) (*Agent, error)
+	GetAgentByID(id AgentID) (*Agent, error)
+	// GetAgentsByIDs returns all of the agents in the database
+	// with the given IDs, ordered by ID, in a single query. If any
+	// ID is not present, it will be silently omitted.
+	GetAgentsByIDs(ids []AgentID) ([]*Agent, error)
 	// GetAgentByName returns the Agent with the given Name, or nil
 	// and an error if not found.
 	GetAgentByName(name string) (*Agent, error)
+	// ExistsAgent reports whether an Agent with the given ID
+	// exists, without retrieving it.
+	ExistsAgent(id AgentID) (bool, error)
+	// ExistsAgentByName reports whether an Agent with the given
+	// Name exists, without retrieving it.
+	ExistsAgentByName(name string) (bool, error)
 	// AddAgent adds a new Agent with the given data. It returns the new
 	// agent's ID on success or an error if failing.
-	AddAgent(name string, isActive bool, address string, port int, isCodeReader bool, isSpdxReader bool, isCodeWriter bool, isSpdxWriter bool) (uint32, error)
+	AddAgent(name string, isActive bool, address string, port int, isCodeReader bool, isSpdxReader bool, isCodeWriter bool, isSpdxWriter bool) (AgentID, error)
 	// UpdateAgentStatus updates an existing Agent with the given ID,
-	// setting whether it is active and its address and port. It returns
-	// nil on success or an error if failing.
-	UpdateAgentStatus(id uint32, isActive bool, address string, port int) error
+	// setting whether it is active and its address and port. If
+	// version is not 0, the update is only made if the agent's
+	// Version is still version, and a *ConflictError is returned
+	// otherwise. It returns nil on success or an error if failing.
+	UpdateAgentStatus(id AgentID, version uint32, isActive bool, address string, port int) error
 	// UpdateAgentAbilities updates an existing Agent with the given ID,
 	// setting its abilities to read/write code/SPDX. It returns nil on
 	// success or an error if failing.
-	UpdateAgentAbilities(id uint32, isCodeReader bool, isSpdxReader bool, isCodeWriter bool, isSpdxWriter bool) error
+	UpdateAgentAbilities(id AgentID, isCodeReader bool, isSpdxReader bool, isCodeWriter bool, isSpdxWriter bool) error
+	// UpdateAgentHealth sets the current health of an existing Agent
+	// with the given ID, and records the change with the given output
+	// message in the agent's health history. It returns nil on success
+	// or an error if failing.
+	UpdateAgentHealth(id AgentID, health AgentHealth, output string) error
+	// GetAgentHealthHistory returns a slice of the health events
+	// recorded for the Agent with the given ID at or after the given
+	// time, ordered from oldest to newest.
+	GetAgentHealthHistory(id AgentID, since time.Time) ([]*AgentHealthEvent, error)
 	// DeleteAgent deletes an existing Agent with the given ID.
-	// It returns nil on success or an error if failing.
-	DeleteAgent(id uint32) error
+	// Any jobs referencing the agent are deleted along with it; use
+	// DeleteAgentWithPolicy to refuse or reassign instead. It returns
+	// nil on success or an error if failing.
+	DeleteAgent(id AgentID) error
+	// DeleteAgentWithPolicy deletes an existing Agent with the given
+	// ID, handling jobs that reference it according to the given
+	// policy. If policy is AgentJobsReassign, those jobs are
+	// reassigned to the agent with ID reassignToID. It returns nil on
+	// success, an *AgentInUseError if refusing due to existing jobs,
+	// or another error if failing.
+	DeleteAgentWithPolicy(id AgentID, policy AgentJobsPolicy, reassignToID AgentID) error
+}
 
+// JobStore is the part of Datastore for jobs.
+type JobStore interface {
 	// ===== Jobs =====
 	// GetAllJobsForRepoPull returns a slice of all jobs
 	// in the database for the given RepoPull ID.
-	GetAllJobsForRepoPull(rpID uint32) ([]*Job, error)
+	GetAllJobsForRepoPull(rpID RepoPullID) ([]*Job, error)
+	// GetJobs returns a slice of the jobs for the RepoPull with
+	// the given ID, sorted and paged as requested, along with a
+	// Page describing the results. Jobs can be sorted by id,
+	// agent_id, started_at, finished_at, status or health.
+	GetJobs(rpID RepoPullID, pr PageRequest) ([]*Job, Page, error)
+	// GetJobGraphForRepoPull returns the JobGraph of the jobs for
+	// the RepoPull with the given ID, fetching the jobs and their
+	// prior job IDs in a single query.
+	GetJobGraphForRepoPull(rpID RepoPullID) (*JobGraph, error)
+	// CountJobsForRepoPull returns the number of jobs for the
+	// RepoPull with the given ID.
+	CountJobsForRepoPull(rpID RepoPullID) (int, error)
 	// GetJobByID returns the job in the database with the given ID.
-	GetJobByID(id uint32) (*Job, error)
+	GetJobByID(id JobID) (*Job, error)
+	// ExistsJob reports whether a Job with the given ID exists,
+	// without retrieving it or its configs.
+	ExistsJob(id JobID) (bool, error)
 	// GetJobsByIDs returns all of the jobs in the database with the given
 	// IDs. If any ID is not present, it will be silently omitted (e.g.,
 	// no error will be returned); the caller should check to confirm the
 	// received jobs match those that were expected.
-	GetJobsByIDs(ids []uint32) ([]*Job, error)
+	GetJobsByIDs(ids []JobID) ([]*Job, error)
+	// GetJobsAfter returns up to limit jobs, ordered by ID and
+	// starting after cursor.AfterID. It also returns the cursor
+	// for the next page, or nil if there are no more results.
+	GetJobsAfter(cursor KeysetCursor, limit uint32) ([]*Job, *KeysetCursor, error)
+	// GetJobsStartedAfter returns up to limit jobs, ordered by
+	// start time and then by ID, starting after the job that
+	// started at cursor.AfterTimestamp with ID cursor.AfterID. It
+	// also returns the cursor for the next page, or nil if there
+	// are no more results.
+	GetJobsStartedAfter(cursor KeysetCursor, limit uint32) ([]*Job, *KeysetCursor, error)
+	// GetJobsModifiedSince returns up to limit jobs that were added
+	// or updated after the position given by cursor, ordered by
+	// modification time and then by ID. Unlike GetJobsAfter, it
+	// returns a non-nil cursor to pass to the next call whenever it
+	// succeeds, which is a copy of cursor if there were no results.
+	GetJobsModifiedSince(cursor KeysetCursor, limit uint32) ([]*Job, *KeysetCursor, error)
 	// GetReadyJobs returns up to n jobs that are "ready", where "ready"
 	// means that BOTH (1) IsReady is true and (2) all jobs from its
 	// PriorJobIDs are StatusStopped and either HealthOK or HealthDegraded.
@@ -224,19 +883,666 @@ type Datastore interface {
 	GetReadyJobs(n uint32) ([]*Job, error)
 	// AddJob adds a new job as specified, with empty configs.
 	// It returns the new job's ID on success or an error if failing.
-	AddJob(repoPullID uint32, agentID uint32, priorJobIDs []uint32) (uint32, error)
+	AddJob(repoPullID RepoPullID, agentID AgentID, priorJobIDs []JobID) (JobID, error)
 	// AddJobWithConfigs adds a new job as specified, with the
 	// noted configuration values. It returns the new job's ID
 	// on success or an error if failing.
-	AddJobWithConfigs(repoPullID uint32, agentID uint32, priorJobIDs []uint32, configKV map[string]string, configCodeReader map[string]JobPathConfig, configSpdxReader map[string]JobPathConfig) (uint32, error)
+	AddJobWithConfigs(repoPullID RepoPullID, agentID AgentID, priorJobIDs []JobID, configKV map[string]string, configCodeReader map[string]JobPathConfig, configSpdxReader map[string]JobPathConfig) (JobID, error)
+	// AddJobWithUUID adds a new job as in AddJobWithConfigs,
+	// recording the client-supplied externalUUID with it. If a job
+	// was already added with that UUID, nothing is added and the
+	// existing job's ID is returned. created reports whether a new
+	// job was added.
+	AddJobWithUUID(externalUUID string, repoPullID RepoPullID, agentID AgentID, priorJobIDs []JobID, configKV map[string]string, configCodeReader map[string]JobPathConfig, configSpdxReader map[string]JobPathConfig) (id JobID, created bool, err error)
+	// GetJobIDByExternalUUID returns the ID of the Job that was
+	// added with the given external UUID, or a *NotFoundError if
+	// there is none.
+	GetJobIDByExternalUUID(externalUUID string) (JobID, error)
 	// UpdateJobIsReady sets the boolean value to specify
 	// whether the Job with the gievn ID is ready to be run.
 	// It does _not_ actually run the Job. It returns nil on
 	// success or an error if failing.
-	UpdateJobIsReady(id uint32, ready bool) error
-	// UpdateJobStatus sets the status variables for this job.
-	UpdateJobStatus(id uint32, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string) error
+	UpdateJobIsReady(id JobID, ready bool) error
+	// UpdateJobStatus sets the status variables for this job. If
+	// version is not 0, the update is only made if the job's
+	// Version is still version, and a *ConflictError is returned
+	// otherwise. A *TransitionError is returned if the job's
+	// current status cannot move to status.
+	UpdateJobStatus(id JobID, version uint32, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string) error
+	// UpdateJobStatuses sets the status variables for several jobs
+	// in a single transaction. It returns a map from the ID of each
+	// job that could not be updated to the error that
+	// UpdateJobStatus would have returned for it, or an error if
+	// none of the updates were made.
+	UpdateJobStatuses(updates []JobStatusUpdate) (map[JobID]error, error)
 	// DeleteJob deletes an existing Job with the given ID.
 	// It returns nil on success or an error if failing.
-	DeleteJob(id uint32) error
+	DeleteJob(id JobID) error
+}
+
+// FindingStore is the part of Datastore for licenses and for the
+// findings, copyrights, conclusions, components and other results
+// that jobs record about files.
+type FindingStore interface {
+	// ===== Licenses =====
+	// GetAllLicenses returns a slice of all licenses in the catalog,
+	// ordered by SPDX identifier.
+	GetAllLicenses() ([]*License, error)
+	// CountAllLicenses returns the number of licenses in the
+	// database.
+	CountAllLicenses() (int, error)
+	// GetLicenseByID returns the License with the given ID, or nil
+	// and an error if not found.
+	GetLicenseByID(id uint32) (*License, error)
+	// GetLicenseBySPDXID returns the License with the given SPDX
+	// identifier, matched case-insensitively, or nil and an error if
+	// not found.
+	GetLicenseBySPDXID(spdxID string) (*License, error)
+	// AddCustomLicense adds a new custom license to the catalog,
+	// with the given LicenseRef- identifier and name. It returns
+	// the new license's ID on success or an error if failing.
+	AddCustomLicense(spdxID string, name string) (uint32, error)
+	// UpdateCustomLicense updates the name of an existing custom
+	// license. It returns nil on success or an error if failing.
+	UpdateCustomLicense(id uint32, newName string) error
+	// DeleteCustomLicense deletes an existing custom license. It
+	// returns nil on success or an error if failing.
+	DeleteCustomLicense(id uint32) error
+
+	// ===== Findings =====
+	// GetFindingsForRepoPull returns a slice of all findings for
+	// files in the RepoPull with the given ID, ordered by file
+	// instance and then by finding ID. Active overrides are applied,
+	// and suppressed findings are omitted unless includeSuppressed
+	// is true.
+	GetFindingsForRepoPull(rpID RepoPullID, includeSuppressed bool) ([]*Finding, error)
+	// GetFindingsForFileInstance returns a slice of all findings for
+	// the FileInstance with the given ID, ordered by ID, with
+	// overrides applied as for GetFindingsForRepoPull.
+	GetFindingsForFileInstance(fileInstanceID uint64, includeSuppressed bool) ([]*Finding, error)
+	// GetFindingsForLicense returns a slice of all findings whose
+	// license expression references the License with the given ID,
+	// ordered by ID, with overrides applied as for
+	// GetFindingsForRepoPull.
+	GetFindingsForLicense(licenseID uint32, includeSuppressed bool) ([]*Finding, error)
+	// AddFindings adds the given findings in a single transaction.
+	// It returns the new findings' IDs, in the same order, on
+	// success or an error if failing.
+	AddFindings(findings []*Finding) ([]uint64, error)
+	// DeleteFindingsForJob deletes all findings produced by the Job
+	// with the given ID. It returns the number of findings deleted
+	// on success or an error if failing.
+	DeleteFindingsForJob(jobID JobID) (int64, error)
+
+	// ===== Copyrights =====
+	// GetCopyrightsForRepoPull returns a slice of all copyright
+	// notices for files in the RepoPull with the given ID, ordered
+	// by file instance and then by ID.
+	GetCopyrightsForRepoPull(rpID RepoPullID) ([]*Copyright, error)
+	// GetCopyrightsForFileInstance returns a slice of all copyright
+	// notices for the FileInstance with the given ID, ordered by ID.
+	GetCopyrightsForFileInstance(fileInstanceID uint64) ([]*Copyright, error)
+	// GetCopyrightSummariesForRepoPull returns the copyright notices
+	// for files in the RepoPull with the given ID, aggregated by
+	// holder and ordered by holder.
+	GetCopyrightSummariesForRepoPull(rpID RepoPullID) ([]*CopyrightSummary, error)
+	// AddCopyrights adds the given copyright notices in a single
+	// transaction. It returns the new notices' IDs, in the same
+	// order, on success or an error if failing.
+	AddCopyrights(copyrights []*Copyright) ([]uint64, error)
+	// DeleteCopyrightsForJob deletes all copyright notices detected
+	// by the Job with the given ID. It returns the number of notices
+	// deleted on success or an error if failing.
+	DeleteCopyrightsForJob(jobID JobID) (int64, error)
+
+	// ===== Conclusions =====
+	// GetConclusionForFileHash returns the Conclusion for the
+	// FileHash with the given ID, or nil and an error if there is
+	// none.
+	GetConclusionForFileHash(fileHashID uint64) (*Conclusion, error)
+	// GetConclusionsForRepoPull returns the existing Conclusions
+	// that apply to files in the RepoPull with the given ID, keyed
+	// by file instance ID.
+	GetConclusionsForRepoPull(rpID RepoPullID) (map[uint64]*Conclusion, error)
+	// GetUnconcludedFileInstances returns a slice of the
+	// FileInstances in the RepoPull with the given ID whose file
+	// hashes have no conclusion yet, ordered by path.
+	GetUnconcludedFileInstances(rpID RepoPullID) ([]*FileInstance, error)
+	// SetConclusion records the license concluded for the FileHash
+	// with the given ID, replacing any existing conclusion for it.
+	// concludedBy is the ID of the user making the conclusion, or 0
+	// for a policy. It returns the conclusion's ID on success or an
+	// error if failing.
+	SetConclusion(fileHashID uint64, licenseExpression string, concludedBy UserID, justification string) (uint64, error)
+	// DeleteConclusion deletes the Conclusion for the FileHash with
+	// the given ID. It returns nil on success or an error if failing.
+	DeleteConclusion(fileHashID uint64) error
+
+	// ===== Components =====
+	// GetComponentsForRepoPull returns a slice of all components
+	// discovered in the RepoPull with the given ID, ordered by ID.
+	GetComponentsForRepoPull(rpID RepoPullID) ([]*Component, error)
+	// GetComponentsByPURL returns a slice of all components, across
+	// all RepoPulls, whose package URL matches purl. If purl has no
+	// version, components with any version of that package are
+	// returned as well.
+	GetComponentsByPURL(purl string) ([]*Component, error)
+	// GetComponentByID returns the Component with the given ID, or
+	// nil and an error if not found.
+	GetComponentByID(id uint64) (*Component, error)
+	// AddComponents adds the given components in a single
+	// transaction. It returns the new components' IDs, in the same
+	// order, on success or an error if failing.
+	AddComponents(components []*Component) ([]uint64, error)
+	// UpdateComponent updates the existing Component with c's ID,
+	// replacing its name, version, package URL and supplier. It
+	// returns nil on success or an error if failing.
+	UpdateComponent(c *Component) error
+	// DeleteComponent deletes the existing Component with the given
+	// ID. It returns nil on success or an error if failing.
+	DeleteComponent(id uint64) error
+
+	// ===== Relationships =====
+	// GetRelationshipsForRepoPull returns a slice of all
+	// relationships between components in the RepoPull with the
+	// given ID.
+	GetRelationshipsForRepoPull(rpID RepoPullID) ([]*Relationship, error)
+	// GetRelatedComponents returns a slice of all components
+	// reachable from the Component with the given ID by following
+	// one or more relationships of the given type.
+	GetRelatedComponents(componentID uint64, relType RelationshipType) ([]*Component, error)
+	// GetRootComponentsForRepoPull returns a slice of all
+	// components in the RepoPull with the given ID that are not on
+	// the "to" side of any relationship.
+	GetRootComponentsForRepoPull(rpID RepoPullID) ([]*Component, error)
+	// AddRelationships adds the given relationships in a single
+	// transaction. It returns the new relationships' IDs, in the
+	// same order, on success or an error if failing.
+	AddRelationships(relationships []*Relationship) ([]uint64, error)
+
+	// ===== Obligations =====
+	// GetObligationsForLicense returns a slice of all obligations
+	// imposed by the License with the given ID.
+	GetObligationsForLicense(licenseID uint32) ([]*Obligation, error)
+	// GetObligationByID returns the Obligation with the given ID, or
+	// nil and an error if not found.
+	GetObligationByID(id uint32) (*Obligation, error)
+	// AddObligation records that the License with the given ID
+	// imposes an obligation of the given kind, as explained in
+	// description. It returns the new obligation's ID on success or
+	// an error if failing.
+	AddObligation(licenseID uint32, kind string, description string) (uint32, error)
+	// UpdateObligationDescription replaces the description of the
+	// existing Obligation with the given ID. It returns nil on
+	// success or an error if failing.
+	UpdateObligationDescription(id uint32, description string) error
+	// DeleteObligation deletes the existing Obligation with the given
+	// ID. It returns nil on success or an error if failing.
+	DeleteObligation(id uint32) error
+	// GetObligationsForRepoPull returns a slice of all obligations
+	// triggered by the files in the RepoPull with the given ID.
+	GetObligationsForRepoPull(rpID RepoPullID) ([]*TriggeredObligation, error)
+
+	// ===== FindingOverrides =====
+	// GetFindingOverridesForFileInstance returns a slice of all
+	// overrides for the FileInstance with the given ID, whether
+	// scoped to it or to its FileHash, ordered by ID.
+	GetFindingOverridesForFileInstance(fileInstanceID uint64) ([]*FindingOverride, error)
+	// GetFindingOverrideByID returns the FindingOverride with the
+	// given ID, or nil and an error if not found.
+	GetFindingOverrideByID(id uint64) (*FindingOverride, error)
+	// AddFindingOverride adds a new override of the findings for a
+	// file. It returns the new override's ID on success or an error
+	// if failing.
+	AddFindingOverride(o *FindingOverride) (uint64, error)
+	// DeleteFindingOverride deletes an existing FindingOverride. It
+	// returns nil on success or an error if failing.
+	DeleteFindingOverride(id uint64) error
+
+	// ===== SnippetMatches =====
+	// GetSnippetMatchesForFileInstance returns a slice of all snippet
+	// matches for the FileInstance with the given ID, ordered by
+	// their position in the file.
+	GetSnippetMatchesForFileInstance(fileInstanceID uint64) ([]*SnippetMatch, error)
+	// AddSnippetMatches adds the given snippet matches in a single
+	// transaction. It returns the new matches' IDs, in the same
+	// order, on success or an error if failing.
+	AddSnippetMatches(matches []*SnippetMatch) ([]uint64, error)
+	// DeleteSnippetMatchesForJob deletes all snippet matches found by
+	// the Job with the given ID. It returns the number of matches
+	// deleted on success or an error if failing.
+	DeleteSnippetMatchesForJob(jobID JobID) (int64, error)
+}
+
+// PolicyStore is the part of Datastore for policies and their
+// results.
+type PolicyStore interface {
+	// ===== Policies =====
+	// GetAllPolicies returns a slice of the current version of all
+	// policies, global and per-project.
+	GetAllPolicies() ([]*Policy, error)
+	// CountAllPolicies returns the number of policies in the
+	// database.
+	CountAllPolicies() (int, error)
+	// GetPoliciesForProject returns a slice of the current version
+	// of all policies that apply to the Project with the given ID,
+	// both global and the project's own.
+	GetPoliciesForProject(projectID ProjectID) ([]*Policy, error)
+	// GetPolicyByID returns the current version of the Policy with
+	// the given ID, or nil and an error if not found.
+	GetPolicyByID(id uint32) (*Policy, error)
+	// GetPolicyVersion returns the given version of the Policy with
+	// the given ID, or nil and an error if not found.
+	GetPolicyVersion(id uint32, version uint32) (*Policy, error)
+	// GetPolicyHistory returns a slice of all versions of the
+	// Policy with the given ID, ordered from oldest to newest.
+	GetPolicyHistory(id uint32) ([]*Policy, error)
+	// AddPolicy adds a new policy with the given name and rules,
+	// scoped to the given Project or, if projectID is 0, globally.
+	// It returns the new policy's ID on success or an error if
+	// failing.
+	AddPolicy(projectID ProjectID, name string, rules PolicyRules, createdBy UserID) (uint32, error)
+	// UpdatePolicyRules creates a new version of the existing
+	// Policy with the given ID, with the given rules. It returns
+	// the new version number on success or an error if failing.
+	UpdatePolicyRules(id uint32, rules PolicyRules, updatedBy UserID) (uint32, error)
+	// DeletePolicy deletes the existing Policy with the given ID,
+	// together with all of its versions. It returns nil on success
+	// or an error if failing.
+	DeletePolicy(id uint32) error
+
+	// ===== PolicyResults =====
+	// GetPolicyResultsForRepoPull returns a slice of all policy
+	// results recorded for the RepoPull with the given ID, ordered
+	// from oldest to newest.
+	GetPolicyResultsForRepoPull(rpID RepoPullID) ([]*PolicyResult, error)
+	// GetLatestPolicyResultsForRepo returns the most recent result
+	// for each Policy that has been evaluated against any RepoPull
+	// of the Repo with the given ID.
+	GetLatestPolicyResultsForRepo(repoID RepoID) ([]*PolicyResult, error)
+	// GetLatestPolicyResultsForProject returns, for each Repo in
+	// the Project with the given ID, the most recent result for
+	// each Policy that has been evaluated against it, keyed by
+	// repo ID.
+	GetLatestPolicyResultsForProject(projectID ProjectID) (map[RepoID][]*PolicyResult, error)
+	// AddPolicyResult records the result of evaluating a Policy
+	// against a RepoPull. It returns the new result's ID on
+	// success or an error if failing.
+	AddPolicyResult(pr *PolicyResult) (uint64, error)
+}
+
+// ReportStore is the part of Datastore for reports and the other
+// documents and summaries produced from scan results.
+type ReportStore interface {
+	// ===== Reports =====
+	// GetReportsForUser returns a slice of all reports requested by
+	// the User with the given ID, from newest to oldest.
+	GetReportsForUser(userID UserID) ([]*Report, error)
+	// GetPendingReports returns up to n reports that are waiting to
+	// be generated, from oldest to newest. If n is 0 then all
+	// pending reports are returned.
+	GetPendingReports(n uint32) ([]*Report, error)
+	// GetReportByID returns the Report with the given ID, or nil and
+	// an error if not found.
+	GetReportByID(id uint32) (*Report, error)
+	// AddReport adds a new report request of the given type and
+	// parameters by the User with ID requestedBy. It returns the new
+	// report's ID on success or an error if failing.
+	AddReport(reportType string, parameters json.RawMessage, requestedBy UserID) (uint32, error)
+	// UpdateReportStatus sets the status variables for the Report
+	// with the given ID, including the URI of its generated
+	// artifact, if any. It returns nil on success or an error if
+	// failing.
+	UpdateReportStatus(id uint32, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string, artifactURI string) error
+	// DeleteReport deletes the Report with the given ID. It returns
+	// nil on success or an error if failing.
+	DeleteReport(id uint32) error
+
+	// ===== ScanDeltas =====
+	// GetScanDelta returns the ScanDelta comparing the RepoPull
+	// with ID headRPID against the one with ID baseRPID, or nil and
+	// an error if none has been stored.
+	GetScanDelta(baseRPID RepoPullID, headRPID RepoPullID) (*ScanDelta, error)
+	// GetScanDeltasForHeadRepoPull returns a slice of all
+	// ScanDeltas comparing the RepoPull with the given ID against
+	// an earlier one.
+	GetScanDeltasForHeadRepoPull(headRPID RepoPullID) ([]*ScanDelta, error)
+	// SetScanDelta stores sd, replacing any existing ScanDelta for
+	// the same pair of RepoPulls. It returns the delta's ID on
+	// success or an error if failing.
+	SetScanDelta(sd *ScanDelta) (uint64, error)
+
+	// ===== NoticeDocuments =====
+	// GetNoticeDocumentByID returns the NoticeDocument with the
+	// given ID, or nil and an error if not found.
+	GetNoticeDocumentByID(id uint64) (*NoticeDocument, error)
+	// GetNoticeDocumentsForRepoPull returns a slice of all notice
+	// documents for the RepoPull with the given ID.
+	GetNoticeDocumentsForRepoPull(rpID RepoPullID) ([]*NoticeDocument, error)
+	// GetNoticeDocumentsForProject returns a slice of all notice
+	// documents for RepoPulls in the Project with the given ID,
+	// limited to RepoPulls with the given git tag if non-empty.
+	GetNoticeDocumentsForProject(projectID ProjectID, tag string) ([]*NoticeDocument, error)
+	// AddNoticeDocument records a generated notice document. It
+	// returns the new document's ID on success or an error if
+	// failing.
+	AddNoticeDocument(nd *NoticeDocument) (uint64, error)
+	// DeleteNoticeDocument deletes the record of the existing
+	// NoticeDocument with the given ID. It returns nil on success
+	// or an error if failing.
+	DeleteNoticeDocument(id uint64) error
+
+	// ===== MetricsSnapshots =====
+	// RecordMetricsSnapshot computes the current aggregate metrics
+	// for the Project with the given ID and stores them as a new
+	// snapshot. It returns the new snapshot on success or an error
+	// if failing.
+	RecordMetricsSnapshot(projectID ProjectID) (*MetricsSnapshot, error)
+	// GetMetricsSnapshots returns a slice of all snapshots for the
+	// Project with the given ID recorded at or after from and before
+	// to, from oldest to newest.
+	GetMetricsSnapshots(projectID ProjectID, from time.Time, to time.Time) ([]*MetricsSnapshot, error)
+
+	// ===== SBOMImports =====
+	// GetSBOMImportsForRepoPull returns a slice of all SBOMs imported
+	// for the RepoPull with the given ID, ordered by ID.
+	GetSBOMImportsForRepoPull(rpID RepoPullID) ([]*SBOMImport, error)
+	// GetSBOMImportByID returns the SBOMImport with the given ID, or
+	// nil and an error if not found.
+	GetSBOMImportByID(id uint32) (*SBOMImport, error)
+	// AddSBOMImport records that a Job imported an SBOM for a
+	// RepoPull, with validation pending. It returns the new import's
+	// ID on success or an error if failing.
+	AddSBOMImport(rpID RepoPullID, jobID JobID, format string, formatVersion string, uri string) (uint32, error)
+	// UpdateSBOMImportValidation sets the validation status and
+	// message of an existing SBOMImport. It returns nil on success
+	// or an error if failing.
+	UpdateSBOMImportValidation(id uint32, status string, message string) error
+	// DeleteSBOMImport deletes the record of an existing SBOMImport
+	// and its links. It returns nil on success or an error if
+	// failing.
+	DeleteSBOMImport(id uint32) error
+	// GetSBOMImportLinks returns a slice of all links from elements
+	// of the SBOMImport with the given ID, ordered by element ID.
+	GetSBOMImportLinks(id uint32) ([]*SBOMImportLink, error)
+	// AddSBOMImportLinks adds the given links from SBOM elements to
+	// components or file instances in a single transaction. It
+	// returns nil on success or an error if failing.
+	AddSBOMImportLinks(links []*SBOMImportLink) error
+
+	// ===== Attestations =====
+	// GetAttestationsForRepoPull returns a slice of all attestations
+	// recorded for the RepoPull with the given ID, ordered by ID.
+	GetAttestationsForRepoPull(rpID RepoPullID) ([]*Attestation, error)
+	// GetAttestationsBySubjectDigest returns a slice of all
+	// attestations about the artifact with the given digest, from
+	// any RepoPull, ordered by ID.
+	GetAttestationsBySubjectDigest(digest string) ([]*Attestation, error)
+	// GetAttestationByID returns the Attestation with the given ID,
+	// or nil and an error if not found.
+	GetAttestationByID(id uint32) (*Attestation, error)
+	// AddAttestation records an attestation about an artifact built
+	// from a RepoPull, with verification pending. Exactly one of
+	// statement and uri must be given. It returns the new
+	// attestation's ID on success or an error if failing.
+	AddAttestation(rpID RepoPullID, attType string, subjectDigest string, statement []byte, uri string) (uint32, error)
+	// UpdateAttestationVerification sets the verification status
+	// and message of an existing Attestation. It returns nil on
+	// success or an error if failing.
+	UpdateAttestationVerification(id uint32, status string, message string) error
+	// DeleteAttestation deletes an existing Attestation. It returns
+	// nil on success or an error if failing.
+	DeleteAttestation(id uint32) error
+}
+
+// CollaborationStore is the part of Datastore for comments,
+// reviews, issue links and labels.
+type CollaborationStore interface {
+	// ===== Comments =====
+	// GetCommentsForRepoPull returns a slice of all comments on the
+	// RepoPull with the given ID, from oldest to newest.
+	GetCommentsForRepoPull(repoPullID RepoPullID) ([]*Comment, error)
+	// GetCommentsForFileInstance returns a slice of all comments on
+	// the FileInstance with the given ID, from oldest to newest.
+	GetCommentsForFileInstance(fileInstanceID uint64) ([]*Comment, error)
+	// GetCommentByID returns the Comment with the given ID, or nil
+	// and an error if not found.
+	GetCommentByID(id uint64) (*Comment, error)
+	// AddComment adds a new, unresolved comment by the User with ID
+	// authorID on the entity of the given target type and ID. It
+	// returns the new comment's ID on success or an error if
+	// failing.
+	AddComment(authorID UserID, targetType string, targetID uint64, body string) (uint64, error)
+	// UpdateCommentBody replaces the body of the Comment with the
+	// given ID and sets its edit time. It returns nil on success or
+	// an error if failing.
+	UpdateCommentBody(id uint64, body string) error
+	// SetCommentResolved marks the Comment with the given ID as
+	// resolved or unresolved. It returns nil on success or an error
+	// if failing.
+	SetCommentResolved(id uint64, resolved bool) error
+	// DeleteComment deletes the Comment with the given ID. It
+	// returns nil on success or an error if failing.
+	DeleteComment(id uint64) error
+
+	// ===== Reviews =====
+	// GetReviewsForRepoPull returns a slice of all reviews of the
+	// RepoPull with the given ID.
+	GetReviewsForRepoPull(repoPullID RepoPullID) ([]*Review, error)
+	// GetRequestedReviewsForReviewer returns a slice of all reviews
+	// that the User with the given ID has been asked for and has
+	// not yet decided.
+	GetRequestedReviewsForReviewer(reviewerID UserID) ([]*Review, error)
+	// GetReviewByID returns the Review with the given ID, or nil and
+	// an error if not found.
+	GetReviewByID(id uint32) (*Review, error)
+	// RequestReview asks the User with ID reviewerID to review the
+	// RepoPull with ID repoPullID. It returns the new review's ID on
+	// success or an error if failing.
+	RequestReview(repoPullID RepoPullID, reviewerID UserID) (uint32, error)
+	// DecideReview approves or rejects the requested Review with the
+	// given ID, with the given notes. It returns nil on success or
+	// an error if failing.
+	DecideReview(id uint32, state ReviewState, notes string) error
+
+	// ===== IssueLinks =====
+	// GetIssueLinksForEntity returns a slice of all issue links for
+	// the entity of the given kind and ID.
+	GetIssueLinksForEntity(entity string, entityID string) ([]*IssueLink, error)
+	// GetIssueLinksSyncedBefore returns a slice of up to limit issue
+	// links that have not been synced since the given time,
+	// including those never synced.
+	GetIssueLinksSyncedBefore(before time.Time, limit uint32) ([]*IssueLink, error)
+	// GetIssueLinkByID returns the IssueLink with the given ID, or
+	// nil and an error if not found.
+	GetIssueLinkByID(id uint32) (*IssueLink, error)
+	// AddIssueLink links the entity of the given kind and ID to the
+	// external issue at issueURL. It returns the new link's ID on
+	// success or an error if failing.
+	AddIssueLink(entity string, entityID string, issueURL string) (uint32, error)
+	// UpdateIssueLinkState records the state of the external issue
+	// for the IssueLink with the given ID, as just fetched from its
+	// tracker. It returns nil on success or an error if failing.
+	UpdateIssueLinkState(id uint32, state string) error
+	// DeleteIssueLink deletes the IssueLink with the given ID. It
+	// returns nil on success or an error if failing.
+	DeleteIssueLink(id uint32) error
+
+	// ===== Labels =====
+	// GetLabels returns all labels on the entity identified by
+	// target, as a map from label key to value.
+	GetLabels(target Labelable) (map[string]string, error)
+	// GetLabel returns the value of the label with the given key on
+	// the entity identified by target, or "" and an error if not
+	// found.
+	GetLabel(target Labelable, key string) (string, error)
+	// SetLabel sets the label with the given key on the entity
+	// identified by target to value, replacing any existing value.
+	// It returns nil on success or an error if failing.
+	SetLabel(target Labelable, key string, value string) error
+	// DeleteLabel removes the label with the given key from the
+	// entity identified by target. It returns nil on success or an
+	// error if failing.
+	DeleteLabel(target Labelable, key string) error
+	// GetRepoIDsWithLabel returns the IDs of all repos that have
+	// the label key set to value.
+	GetRepoIDsWithLabel(key string, value string) ([]RepoID, error)
+	// GetRepoPullIDsWithLabel returns the IDs of all repo pulls that
+	// have the label key set to value.
+	GetRepoPullIDsWithLabel(key string, value string) ([]RepoPullID, error)
+	// GetJobIDsWithLabel returns the IDs of all jobs that have the
+	// label key set to value.
+	GetJobIDsWithLabel(key string, value string) ([]JobID, error)
+	// GetAgentIDsWithLabel returns the IDs of all agents that have
+	// the label key set to value.
+	GetAgentIDsWithLabel(key string, value string) ([]AgentID, error)
+}
+
+// EventStore is the part of Datastore for webhooks,
+// notifications and the outbox of change events.
+type EventStore interface {
+	// ===== Webhooks =====
+	// GetAllWebhooks returns a slice of all webhooks.
+	GetAllWebhooks() ([]*Webhook, error)
+	// CountAllWebhooks returns the number of webhooks in the
+	// database.
+	CountAllWebhooks() (int, error)
+	// GetWebhooksForEvent returns a slice of all enabled webhooks
+	// that subscribe to the given event type for the Project with
+	// the given ID, including those for all projects.
+	GetWebhooksForEvent(projectID ProjectID, eventType string) ([]*Webhook, error)
+	// GetWebhookByID returns the Webhook with the given ID, or nil
+	// and an error if not found.
+	GetWebhookByID(id uint32) (*Webhook, error)
+	// AddWebhook adds a new, enabled webhook posting the given
+	// event types to url, signed with secret. It returns the new
+	// webhook's ID on success or an error if failing.
+	AddWebhook(projectID ProjectID, url string, secret string, eventTypes []string) (uint32, error)
+	// UpdateWebhook updates the existing Webhook with w's ID,
+	// replacing its URL, event types and enabled flag, and its
+	// secret if non-empty. It returns nil on success or an error
+	// if failing.
+	UpdateWebhook(w *Webhook) error
+	// DeleteWebhook deletes the existing Webhook with the given ID,
+	// together with its delivery log. It returns nil on success or
+	// an error if failing.
+	DeleteWebhook(id uint32) error
+
+	// ===== WebhookDeliveries =====
+	// GetWebhookDeliveries returns a slice of all deliveries queued
+	// for the Webhook with the given ID, from newest to oldest.
+	GetWebhookDeliveries(webhookID uint32) ([]*WebhookDelivery, error)
+	// GetPendingWebhookDeliveries returns a slice of up to limit
+	// deliveries to enabled webhooks that have not yet succeeded
+	// and are due to be attempted.
+	GetPendingWebhookDeliveries(limit uint32) ([]*WebhookDelivery, error)
+	// AddWebhookDelivery queues the delivery of an event of the
+	// given type, with the given JSON payload, to the Webhook with
+	// the given ID. It returns the new delivery's ID on success or
+	// an error if failing.
+	AddWebhookDelivery(webhookID uint32, eventType string, payload json.RawMessage) (uint64, error)
+	// RecordWebhookDeliveryAttempt records an attempt to deliver
+	// the WebhookDelivery with the given ID, scheduling a retry at
+	// nextAttemptAt if it did not succeed. It returns nil on
+	// success or an error if failing.
+	RecordWebhookDeliveryAttempt(id uint64, responseCode int, succeeded bool, nextAttemptAt time.Time) error
+
+	// ===== Notifications =====
+	// CreateNotification creates a new, unread notification of the
+	// given type for the User with ID userID, about the entity
+	// identified by entity and entityID. It returns the new
+	// notification's ID on success or an error if failing.
+	CreateNotification(userID UserID, notificationType string, entity string, entityID string, message string) (uint64, error)
+	// GetUnreadNotificationsForUser returns a slice of all unread
+	// notifications for the User with the given ID, from newest to
+	// oldest.
+	GetUnreadNotificationsForUser(userID UserID) ([]*Notification, error)
+	// MarkNotificationRead marks the notification with the given
+	// ID, which must belong to the User with ID userID, as read. It
+	// returns nil on success or an error if failing.
+	MarkNotificationRead(userID UserID, id uint64) error
+	// MarkAllNotificationsRead marks all unread notifications for
+	// the User with the given ID as read. It returns the number of
+	// notifications marked on success or an error if failing.
+	MarkAllNotificationsRead(userID UserID) (int64, error)
+
+	// ===== Outbox =====
+	// Outbox events are recorded for projects, subprojects, repos
+	// and repo pulls when one is added, updated, deleted or
+	// soft-deleted, or restored, and for jobs, agents and users when
+	// one is added, updated or deleted. This includes jobs updated
+	// by UpdateJobStatuses or reassigned by DeleteAgentWithPolicy,
+	// users added or changed by AcceptInvitation, SyncUsers and
+	// AnonymizeUser, and agent health changes. User logins, findings
+	// and every other entity do not record events, and neither do
+	// rows deleted by cascading, purged or removed by a retention
+	// policy. Consumers that need to follow those changes must use
+	// the audit log or poll the matching ModifiedSince and keyset
+	// methods instead.
+	// GetUnpublishedEvents returns a slice of up to limit outbox
+	// events that have not yet been marked as published, ordered by
+	// ID.
+	GetUnpublishedEvents(limit uint32) ([]*OutboxEvent, error)
+	// GetEventsSince returns a slice of up to limit outbox events
+	// that come after cursor, whether or not they have been
+	// published, ordered by transaction and then by ID, for
+	// consumers that mirror changes incrementally from a
+	// checkpoint. Only events from transactions older than every
+	// transaction still in progress are returned, so that none can
+	// later appear behind the cursor. It also returns the cursor to
+	// pass to the next call, which is a copy of cursor if there were
+	// no results.
+	GetEventsSince(cursor EventCursor, limit uint32) ([]*OutboxEvent, *EventCursor, error)
+	// MarkEventsPublished marks the outbox events with the given IDs
+	// as published. It returns nil on success or an error if
+	// failing.
+	MarkEventsPublished(ids []uint64) error
+	// PrunePublishedEvents deletes all outbox events that were
+	// published before the given time. It returns the number of
+	// events deleted on success or an error if failing.
+	PrunePublishedEvents(before time.Time) (int64, error)
+}
+
+// BulkStore is the part of Datastore for loading and deleting
+// large numbers of rows at once.
+type BulkStore interface {
+	// ===== Bulk loading =====
+	// BulkAddFileHashes adds the given file hashes using COPY, in a
+	// single transaction. It returns the new hashes' IDs, in the same
+	// order, on success or an error if failing.
+	BulkAddFileHashes(hashes []*FileHash) ([]uint64, error)
+	// BulkAddFileInstances adds the given file instances using COPY,
+	// in a single transaction. It returns the new instances' IDs, in
+	// the same order, on success or an error if failing.
+	BulkAddFileInstances(instances []*FileInstance) ([]uint64, error)
+	// BulkAddFindings adds the given findings and their license IDs
+	// using COPY, in a single transaction. It returns the new
+	// findings' IDs, in the same order, on success or an error if
+	// failing.
+	BulkAddFindings(findings []*Finding) ([]uint64, error)
+	// BulkAddJobConfigs adds the given configs, keyed by job ID,
+	// using COPY, in a single transaction. It returns nil on success
+	// or an error if failing.
+	BulkAddJobConfigs(configs map[JobID]JobConfig) error
+
+	// ===== Batched deletes =====
+	// DeleteFileInstancesInBatches deletes all file instances in
+	// the RepoPull with the given ID, along with their dependent
+	// rows, deleting at most batchSize instances per statement and
+	// calling progress, if not nil, after each batch. If batchSize
+	// is 0, DefaultDeleteBatchSize is used. It returns nil on
+	// success or an error if failing.
+	DeleteFileInstancesInBatches(rpID RepoPullID, batchSize int, progress DeleteProgressFunc) error
+	// DeleteJobInBatches deletes an existing Job with the given ID,
+	// first deleting its findings, copyrights and snippet matches
+	// in batches of at most batchSize rows and calling progress, if
+	// not nil, after each batch. It returns nil on success or an
+	// error if failing.
+	DeleteJobInBatches(id JobID, batchSize int, progress DeleteProgressFunc) error
+	// DeleteRepoPullInBatches deletes an existing RepoPull with the
+	// given ID, first deleting its file instances and components in
+	// batches of at most batchSize rows and calling progress, if
+	// not nil, after each batch. It returns nil on success or an
+	// error if failing.
+	DeleteRepoPullInBatches(id RepoPullID, batchSize int, progress DeleteProgressFunc) error
 }
@@ -3,37 +3,76 @@
 package datastore
 
 import (
-	"database/sql"
-
-	// postgres driver
+	// postgres drivers
+	_ "github.com/jackc/pgx/v4/stdlib"
 	_ "github.com/lib/pq"
 )
 
+// Driver selects the database/sql driver used to connect to
+// Postgres.
+type Driver string
+
+const (
+	// DriverPQ connects using github.com/lib/pq. It is the default.
+	DriverPQ Driver = "postgres"
+	// DriverPGX connects using github.com/jackc/pgx, which uses
+	// Postgres's binary protocol and so is faster for large
+	// results. Bulk loads use multi-row INSERTs rather than COPY,
+	// since pgx's COPY support is not available through
+	// database/sql.
+	DriverPGX Driver = "pgx"
+)
+
 // DB holds the actual database/sql object as well as its related
 // database statements.
 type DB struct {
-	sqldb *sql.DB
+	// sqldb runs the DB's statements. It is a *sql.DB, or a *sql.Tx
+	// if the DB is bound to a transaction by InTx.
+	sqldb sqlConn
+	// keys encrypts secrets at rest, or is nil if secrets are
+	// stored as plaintext. See SetKeyProvider.
+	keys KeyProvider
+	// softDelete is true if deleting a project, subproject or repo
+	// only marks it as deleted. See SetSoftDelete.
+	softDelete bool
+	// driver is the driver that sqldb uses.
+	driver Driver
+	// initialAdminGithub is the GitHub user name of the initial
+	// admin user added by InitNewDB, or empty to use the
+	// INITIALADMINGITHUB environment variable. See Config.
+	initialAdminGithub string
+	// noPrepare is true if statements are run without being
+	// prepared first. See SetNoPrepare.
+	noPrepare bool
+	// schema is the schema holding the tables, or empty for
+	// DefaultSchema. See Config.
+	schema string
 }
 
-// NewDB opens and returns an initialized DB object.
+// NewDB opens and returns an initialized DB object, connecting with
+// the default driver, DriverPQ. To also configure the connection
+// pool, TLS or the initial admin user, use NewDBFromConfig or
+// NewDBFromEnv instead.
 func NewDB(srcName string) (*DB, error) {
-	sqldb, err := sql.Open("postgres", srcName)
-	if err != nil {
-		return nil, err
-	}
-	if err = sqldb.Ping(); err != nil {
-		return nil, err
-	}
+	return NewDBWithDriver(DriverPQ, srcName)
+}
 
-	db := &DB{sqldb: sqldb}
-	return db, nil
+// NewDBWithDriver opens and returns an initialized DB object,
+// connecting with the given driver. srcName is passed to the driver
+// unchanged; both drivers accept a postgres:// URL or a list of
+// key=value settings.
+func NewDBWithDriver(driver Driver, srcName string) (*DB, error) {
+	if driver == "" {
+		return nil, &ValidationError{Entity: "config", Field: "driver", Reason: "must not be empty"}
+	}
+	return NewDBFromConfig(Config{DSN: srcName, Driver: driver})
 }
 
 // InitNewDB creates all the peridot database tables. It returns
 // nil on success or any error encountered.
 func InitNewDB(db *DB) error {
 	// create schema
-	_, err := db.sqldb.Exec(`CREATE SCHEMA IF NOT EXISTS peridot`)
+	_, err := db.sqldb.Exec(`CREATE SCHEMA IF NOT EXISTS ` + db.schemaName())
 	if err != nil {
 		return err
 	}
@@ -50,6 +89,6 @@ func InitNewDB(db *DB) error {
 // or any error encountered. Use extreme caution when calling!
 func ClearDB(db *DB) error {
 	// create schema
-	_, err := db.sqldb.Exec(`DROP SCHEMA peridot CASCADE`)
+	_, err := db.sqldb.Exec(`DROP SCHEMA ` + db.schemaName() + ` CASCADE`)
 	return err
 }
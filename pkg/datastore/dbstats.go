@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+// TableStats reports the approximate size of one table in the
+// peridot schema.
+type TableStats struct {
+	// Table is the table's name, without the schema.
+	Table string `json:"table"`
+	// ApproxRows is Postgres's estimate of the number of rows in the
+	// table, as of its last VACUUM or ANALYZE. It is 0 for a table
+	// that has not yet been analyzed.
+	ApproxRows int64 `json:"approx_rows"`
+	// TotalBytes is the on-disk size of the table, including its
+	// indexes and TOAST data.
+	TotalBytes int64 `json:"total_bytes"`
+	// IndexBytes is the on-disk size of the table's indexes.
+	IndexBytes int64 `json:"index_bytes"`
+}
+
+// DBStats reports the approximate size of the peridot database.
+type DBStats struct {
+	// Tables holds the statistics for each table, largest first.
+	Tables []*TableStats `json:"tables"`
+	// TotalBytes is the sum of the tables' TotalBytes.
+	TotalBytes int64 `json:"total_bytes"`
+}
+
+// getDBStatsQuery selects each table in schema $1 with its estimated
+// row count and on-disk sizes, largest first.
+const getDBStatsQuery = `
+SELECT c.relname, GREATEST(c.reltuples, 0)::BIGINT, pg_total_relation_size(c.oid), pg_indexes_size(c.oid)
+FROM pg_class c
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = $1 AND c.relkind = 'r'
+ORDER BY pg_total_relation_size(c.oid) DESC, c.relname
+`
+
+// GetDBStats returns the approximate row counts and on-disk sizes of
+// the tables in the peridot schema. Row counts are estimates taken
+// from the Postgres catalog, so the call is cheap even for very
+// large tables such as file_instances.
+func (db *DB) GetDBStats() (*DBStats, error) {
+	rows, err := db.sqldb.Query(getDBStatsQuery, db.schemaName())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &DBStats{Tables: []*TableStats{}}
+	for rows.Next() {
+		ts := &TableStats{}
+		err := rows.Scan(&ts.Table, &ts.ApproxRows, &ts.TotalBytes, &ts.IndexBytes)
+		if err != nil {
+			return nil, err
+		}
+		stats.Tables = append(stats.Tables, ts)
+		stats.TotalBytes += ts.TotalBytes
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
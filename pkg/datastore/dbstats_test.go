@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetDBStats(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"relname", "reltuples", "total_bytes", "index_bytes"}).
+		AddRow("file_instances", 1250000, 402653184, 134217728).
+		AddRow("jobs", 5200, 1589248, 540672).
+		AddRow("users", 0, 16384, 16384)
+	mock.ExpectQuery(`SELECT c.relname, GREATEST\(c.reltuples, 0\)::BIGINT, pg_total_relation_size\(c.oid\), pg_indexes_size\(c.oid\) FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = \$1 AND c.relkind = 'r'`).
+		WithArgs("peridot").
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	stats, err := db.GetDBStats()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(stats.Tables) != 3 {
+		t.Fatalf("expected %d tables, got %d", 3, len(stats.Tables))
+	}
+	ts := stats.Tables[0]
+	if ts.Table != "file_instances" {
+		t.Errorf("expected %v, got %v", "file_instances", ts.Table)
+	}
+	if ts.ApproxRows != 1250000 {
+		t.Errorf("expected %v, got %v", 1250000, ts.ApproxRows)
+	}
+	if ts.IndexBytes != 134217728 {
+		t.Errorf("expected %v, got %v", 134217728, ts.IndexBytes)
+	}
+	if stats.TotalBytes != 402653184+1589248+16384 {
+		t.Errorf("expected %v, got %v", 402653184+1589248+16384, stats.TotalBytes)
+	}
+}
@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// encryptedPrefix marks a stored value as encrypted. It is followed
+// by the ID of the key used, a colon, and the base64 encoding of the
+// nonce and ciphertext. Stored values without this prefix are
+// plaintext written before encryption was enabled.
+const encryptedPrefix = "enc:"
+
+// KeyProvider supplies the keys used to encrypt secrets at rest. It
+// can be implemented on top of an external key management service.
+// Keys are identified by IDs that must not contain a colon, and
+// must be 32 bytes long, for AES-256.
+type KeyProvider interface {
+	// CurrentKeyID returns the ID of the key with which new values
+	// should be encrypted.
+	CurrentKeyID() string
+	// Key returns the key with the given ID, or an error if it is
+	// not available.
+	Key(id string) ([]byte, error)
+}
+
+// StaticKeyProvider is a KeyProvider with a fixed set of keys, such
+// as keys read from configuration at startup.
+type StaticKeyProvider struct {
+	// CurrentID is the ID of the key used for new values.
+	CurrentID string
+	// Keys maps each key ID to its key, including the current key
+	// and any older keys still needed to decrypt existing values.
+	Keys map[string][]byte
+}
+
+// CurrentKeyID returns the ID of the key used for new values.
+func (skp *StaticKeyProvider) CurrentKeyID() string {
+	return skp.CurrentID
+}
+
+// Key returns the key with the given ID, or an error if unknown.
+func (skp *StaticKeyProvider) Key(id string) ([]byte, error) {
+	key, ok := skp.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %q", id)
+	}
+	return key, nil
+}
+
+// SetKeyProvider sets the KeyProvider used to encrypt secrets before
+// they are stored. Webhook secrets are currently the only secrets
+// encrypted this way; API tokens and invitation tokens are stored
+// only as hashes, and no other column is encrypted. If no
+// KeyProvider is set, secrets are stored as plaintext, and a secret
+// beginning with "enc:" is refused, since it could not be told apart
+// from an encrypted value when read back. Existing plaintext secrets
+// remain readable once a KeyProvider is set, and are encrypted the
+// next time RotateSecrets is called.
+func (db *DB) SetKeyProvider(kp KeyProvider) {
+	db.keys = kp
+}
+
+// aeadForKey returns an AES-GCM cipher for the key with the given
+// ID.
+func (db *DB) aeadForKey(id string) (cipher.AEAD, error) {
+	key, err := db.keys.Key(id)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key %q: %v", id, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptSecret returns the value to store for the given secret: the
+// secret encrypted with the current key, or the secret unchanged if
+// it is empty or no KeyProvider is set. It returns a
+// *ValidationError if the secret would be stored unchanged but
+// begins with encryptedPrefix.
+func (db *DB) encryptSecret(plaintext string) (string, error) {
+	if db.keys == nil || plaintext == "" {
+		if strings.HasPrefix(plaintext, encryptedPrefix) {
+			return "", &ValidationError{Entity: "secret", Field: "value", Reason: "must not begin with " + encryptedPrefix + " unless a key provider is set"}
+		}
+		return plaintext, nil
+	}
+
+	id := db.keys.CurrentKeyID()
+	if id == "" || strings.Contains(id, ":") {
+		return "", fmt.Errorf("invalid encryption key ID %q", id)
+	}
+	aead, err := db.aeadForKey(id)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret returns the secret for the given stored value. Values
+// stored as plaintext are returned unchanged.
+func (db *DB) decryptSecret(stored string) (string, error) {
+	if !strings.HasPrefix(stored, encryptedPrefix) {
+		return stored, nil
+	}
+	if db.keys == nil {
+		return "", fmt.Errorf("cannot decrypt secret: no key provider set")
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(stored, encryptedPrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("malformed encrypted secret")
+	}
+	aead, err := db.aeadForKey(parts[0])
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted secret: %v", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted secret")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt secret with key %q: %v", parts[0], err)
+	}
+	return string(plaintext), nil
+}
+
+// encryptedColumns lists the table and column of every secret
+// stored encrypted, for RotateSecrets. Each table must have an id
+// primary key. A column added here must also be written with
+// encryptSecret and read with decryptSecret.
+var encryptedColumns = []struct {
+	table  string
+	column string
+}{
+	{"webhooks", "secret"},
+}
+
+// RotateSecrets re-encrypts, with the current key, every stored
+// secret that is plaintext or encrypted with an older key. It works
+// through each table in batches of up to batchSize rows, each in its
+// own transaction, so older keys can be retired once it completes.
+// It returns the number of secrets re-encrypted on success, or the
+// number so far and an error if failing.
+func (db *DB) RotateSecrets(batchSize uint32) (int64, error) {
+	if db.keys == nil {
+		return 0, fmt.Errorf("cannot rotate secrets: no key provider set")
+	}
+	if batchSize == 0 {
+		return 0, fmt.Errorf("batch size must be positive")
+	}
+
+	var rotated int64
+	current := escapeLikePattern(encryptedPrefix+db.keys.CurrentKeyID()+":") + "%"
+	for _, ec := range encryptedColumns {
+		for {
+			n, err := db.rotateSecretsBatch(ec.table, ec.column, current, batchSize)
+			rotated += n
+			if err != nil {
+				return rotated, err
+			}
+			if n < int64(batchSize) {
+				break
+			}
+		}
+	}
+
+	return rotated, nil
+}
+
+// rotateSecretsBatch re-encrypts up to batchSize secrets in the
+// given table and column that do not match the current key pattern.
+// It returns the number of secrets re-encrypted.
+func (db *DB) rotateSecretsBatch(table string, column string, current string, batchSize uint32) (int64, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(fmt.Sprintf("SELECT id, %[1]s FROM peridot.%[2]s WHERE %[1]s <> '' AND %[1]s NOT LIKE $1 ORDER BY id LIMIT $2 FOR UPDATE", column, table), current, batchSize)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	type storedSecret struct {
+		id    uint64
+		value string
+	}
+	secrets := []storedSecret{}
+	for rows.Next() {
+		var s storedSecret
+		if err := rows.Scan(&s.id, &s.value); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return 0, err
+		}
+		secrets = append(secrets, s)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	for _, s := range secrets {
+		plaintext, err := db.decryptSecret(s.value)
+		if err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("%s %d: %v", table, s.id, err)
+		}
+		value, err := db.encryptSecret(plaintext)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		_, err = tx.Exec(fmt.Sprintf("UPDATE peridot.%s SET %s = $1 WHERE id = $2", table, column), value, s.id)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int64(len(secrets)), nil
+}
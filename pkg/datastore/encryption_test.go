@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// encryptedArg matches a stored secret encrypted with the given key.
+type encryptedArg struct {
+	keyID string
+}
+
+func (ea encryptedArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	return ok && strings.HasPrefix(s, encryptedPrefix+ea.keyID+":")
+}
+
+func testKeyProvider(currentID string) *StaticKeyProvider {
+	return &StaticKeyProvider{
+		CurrentID: currentID,
+		Keys: map[string][]byte{
+			"k1": []byte("0123456789abcdef0123456789abcdef"),
+			"k2": []byte("fedcba9876543210fedcba9876543210"),
+		},
+	}
+}
+
+func TestCanEncryptAndDecryptSecret(t *testing.T) {
+	db := &DB{}
+	db.SetKeyProvider(testKeyProvider("k1"))
+
+	stored, err := db.encryptSecret("s3cr3t")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !strings.HasPrefix(stored, "enc:k1:") || strings.Contains(stored, "s3cr3t") {
+		t.Errorf("expected secret encrypted with k1, got %v", stored)
+	}
+
+	// older keys remain usable for decryption after rotation
+	db.SetKeyProvider(testKeyProvider("k2"))
+	got, err := db.decryptSecret(stored)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got != "s3cr3t" {
+		t.Errorf("expected %v, got %v", "s3cr3t", got)
+	}
+}
+
+func TestShouldReadPlaintextSecretsUnchanged(t *testing.T) {
+	db := &DB{}
+	db.SetKeyProvider(testKeyProvider("k1"))
+
+	got, err := db.decryptSecret("hunter2")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got != "hunter2" {
+		t.Errorf("expected %v, got %v", "hunter2", got)
+	}
+}
+
+func TestShouldRefusePlaintextSecretThatLooksEncrypted(t *testing.T) {
+	db := &DB{}
+
+	_, err := db.encryptSecret("enc:k1:not-really")
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	// with a key provider set, the same secret is encrypted and
+	// reads back unchanged
+	db.SetKeyProvider(testKeyProvider("k1"))
+	stored, err := db.encryptSecret("enc:k1:not-really")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	got, err := db.decryptSecret(stored)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got != "enc:k1:not-really" {
+		t.Errorf("expected %v, got %v", "enc:k1:not-really", got)
+	}
+}
+
+func TestShouldFailDecryptSecretWithUnknownKey(t *testing.T) {
+	db := &DB{}
+	db.SetKeyProvider(testKeyProvider("k1"))
+	stored, err := db.encryptSecret("s3cr3t")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	db.SetKeyProvider(&StaticKeyProvider{CurrentID: "k3", Keys: map[string][]byte{"k3": []byte("0123456789abcdef0123456789abcdef")}})
+	_, err = db.decryptSecret(stored)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+}
+
+func TestShouldEncryptWebhookSecretWhenAdding(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+	db.SetKeyProvider(testKeyProvider("k1"))
+
+	regexStmt := `INSERT INTO peridot.webhooks\(project_id, url, secret, event_types, is_enabled, created_at\)`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(nilArg{}, "https://ci.example.com/hooks/peridot", encryptedArg{"k1"}, "{\"policy.failed\"}", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	// run the tested function
+	_, err = db.AddWebhook(0, "https://ci.example.com/hooks/peridot", "s3cr3t", []string{WebhookEventPolicyFailed})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldRotateSecretsInBatches(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+	db.SetKeyProvider(testKeyProvider("k1"))
+	oldSecret, err := db.encryptSecret("s3cr3t")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	db.SetKeyProvider(testKeyProvider("k2"))
+
+	regexSelect := `SELECT id, secret FROM peridot.webhooks WHERE secret <> '' AND secret NOT LIKE \$1 ORDER BY id LIMIT \$2 FOR UPDATE`
+	regexUpdate := `UPDATE peridot.webhooks SET secret = \$1 WHERE id = \$2`
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexSelect).
+		WithArgs("enc:k2:%", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "secret"}).AddRow(1, oldSecret).AddRow(4, "hunter2"))
+	mock.ExpectExec(regexUpdate).
+		WithArgs(encryptedArg{"k2"}, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexUpdate).
+		WithArgs(encryptedArg{"k2"}, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexSelect).
+		WithArgs("enc:k2:%", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "secret"}))
+	mock.ExpectCommit()
+
+	// run the tested function
+	n, err := db.RotateSecrets(2)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if n != 2 {
+		t.Errorf("expected %v, got %v", 2, n)
+	}
+}
+
+func TestShouldMatchCurrentKeyIDLiterallyWhenRotatingSecrets(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+	db.SetKeyProvider(&StaticKeyProvider{CurrentID: "key_100%", Keys: map[string][]byte{"key_100%": []byte("0123456789abcdef0123456789abcdef")}})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, secret FROM peridot.webhooks WHERE secret <> '' AND secret NOT LIKE \$1`).
+		WithArgs(`enc:key\_100\%:%`, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "secret"}))
+	mock.ExpectCommit()
+
+	// run the tested function
+	n, err := db.RotateSecrets(10)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if n != 0 {
+		t.Errorf("expected %v, got %v", 0, n)
+	}
+}
@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"sort"
+	"time"
+)
+
+// equalTimes reports whether a and b represent the same instant at
+// microsecond precision, which is the precision stored by Postgres.
+func equalTimes(a time.Time, b time.Time) bool {
+	return a.Truncate(time.Microsecond).Equal(b.Truncate(time.Microsecond))
+}
+
+// equalIDSets reports whether a and b contain the same IDs,
+// regardless of order. A nil slice is equal to an empty one.
+func equalIDSets(a []JobID, b []JobID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	as := append([]JobID{}, a...)
+	bs := append([]JobID{}, b...)
+	sort.Slice(as, func(i, j int) bool { return as[i] < as[j] })
+	sort.Slice(bs, func(i, j int) bool { return bs[i] < bs[j] })
+	for i := range as {
+		if as[i] != bs[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// normalizeExternalUUID returns the lowercase canonical form of a
+// client-supplied UUID, or a *ValidationError if it is not of the
+// form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx.
+func normalizeExternalUUID(entity string, externalUUID string) (string, error) {
+	u := strings.ToLower(externalUUID)
+	parts := strings.Split(u, "-")
+	if len(parts) != 5 || !isLowerHex(parts[0], 8) || !isLowerHex(parts[1], 4) ||
+		!isLowerHex(parts[2], 4) || !isLowerHex(parts[3], 4) || !isLowerHex(parts[4], 12) {
+		return "", &ValidationError{Entity: entity, Field: "external UUID", Reason: "must be a UUID"}
+	}
+	return u, nil
+}
+
+// idForExternalUUID returns the ID of the row in the given table
+// that was created with the given external UUID and matches
+// condition. It returns a *NotFoundError if that row does not match
+// condition, e.g. because it or its parent has been soft-deleted.
+func (db *DB) idForExternalUUID(entity string, table string, condition string, externalUUID string) (uint32, error) {
+	var id uint32
+	err := db.sqldb.QueryRow("SELECT id FROM peridot."+table+" WHERE external_uuid = $1 AND "+condition, externalUUID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, &NotFoundError{Entity: entity, ID: externalUUID, Key: "external UUID"}
+	}
+	return id, err
+}
+
+// GetRepoIDByExternalUUID returns the ID of the Repo that was added
+// with the given external UUID, or a *NotFoundError if there is none
+// or it has been soft-deleted.
+func (db *DB) GetRepoIDByExternalUUID(externalUUID string) (RepoID, error) {
+	externalUUID, err := normalizeExternalUUID("repo", externalUUID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := db.idForExternalUUID("repo", "repos", liveRepoCondition, externalUUID)
+	return RepoID(id), err
+}
+
+// GetRepoPullIDByExternalUUID returns the ID of the RepoPull that
+// was added with the given external UUID, or a *NotFoundError if
+// there is none or its repo has been soft-deleted.
+func (db *DB) GetRepoPullIDByExternalUUID(externalUUID string) (RepoPullID, error) {
+	externalUUID, err := normalizeExternalUUID("repo pull", externalUUID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := db.idForExternalUUID("repo pull", "repo_pulls", "repo_id IN ("+liveRepoIDs+")", externalUUID)
+	return RepoPullID(id), err
+}
+
+// GetJobIDByExternalUUID returns the ID of the Job that was added
+// with the given external UUID, or a *NotFoundError if there is none
+// or its repo has been soft-deleted.
+func (db *DB) GetJobIDByExternalUUID(externalUUID string) (JobID, error) {
+	externalUUID, err := normalizeExternalUUID("job", externalUUID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := db.idForExternalUUID("job", "jobs", "repopull_id IN ("+liveRepoPullIDs+")", externalUUID)
+	return JobID(id), err
+}
+
+// AddRepoWithUUID adds a new repo as in AddRepo, recording the
+// client-supplied externalUUID with it. If a repo was already added
+// with that UUID, nothing is added and the existing repo's ID is
+// returned, so that a caller can safely retry a request that may
+// or may not have succeeded. created reports whether a new repo was
+// added.
+func (db *DB) AddRepoWithUUID(externalUUID string, subprojectID uint32, name string, address string) (id RepoID, created bool, err error) {
+	externalUUID, err = normalizeExternalUUID("repo", externalUUID)
+	if err != nil {
+		return 0, false, err
+	}
+	r := &Repo{SubprojectID: subprojectID, Name: name, Address: address}
+	if err = r.Validate(); err != nil {
+		return 0, false, err
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return 0, false, err
+	}
+
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.repos(subproject_id, name, address, external_uuid) VALUES ($1, $2, $3, $4) ON CONFLICT (external_uuid) DO NOTHING RETURNING id")
+	if err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+
+	err = stmt.QueryRow(subprojectID, name, address, externalUUID).Scan(&r.ID)
+	if err == sql.ErrNoRows {
+		// already added with this UUID
+		tx.Rollback()
+		existing, err := db.GetRepoIDByExternalUUID(externalUUID)
+		return existing, false, err
+	}
+	if err != nil {
+		tx.Rollback()
+		return 0, false, translateConstraintError("repo", err)
+	}
+
+	err = addOutboxEvent(tx, "repo", r.ID, AuditActionAdd, r)
+	if err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	return r.ID, true, nil
+}
+
+// AddRepoPullWithUUID adds a new repo pull as in AddFullRepoPull,
+// recording the client-supplied externalUUID with it. If a repo pull
+// was already added with that UUID, nothing is added and the existing
+// repo pull's ID is returned. created reports whether a new repo pull
+// was added.
+func (db *DB) AddRepoPullWithUUID(externalUUID string, repoID RepoID, branch string, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string, commit string, tag string, spdxID string) (id RepoPullID, created bool, err error) {
+	externalUUID, err = normalizeExternalUUID("repo pull", externalUUID)
+	if err != nil {
+		return 0, false, err
+	}
+	startedAt = normalizeTime(startedAt)
+	finishedAt = normalizeTime(finishedAt)
+	rp := &RepoPull{RepoID: repoID, Branch: branch, StartedAt: startedAt, FinishedAt: finishedAt, Status: status, Health: health, Output: output, Commit: commit, Tag: tag, SPDXID: spdxID}
+	if err = rp.Validate(); err != nil {
+		return 0, false, err
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return 0, false, err
+	}
+
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.repo_pulls(repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id, external_uuid) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) ON CONFLICT (external_uuid) DO NOTHING RETURNING id")
+	if err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+
+	err = stmt.QueryRow(repoID, branch, startedAt, finishedAt, status, health, output, commit, tag, spdxID, externalUUID).Scan(&rp.ID)
+	if err == sql.ErrNoRows {
+		// already added with this UUID
+		tx.Rollback()
+		existing, err := db.GetRepoPullIDByExternalUUID(externalUUID)
+		return existing, false, err
+	}
+	if err != nil {
+		tx.Rollback()
+		return 0, false, translateConstraintError("repo pull", err)
+	}
+
+	err = addOutboxEvent(tx, "repo_pull", rp.ID, AuditActionAdd, rp)
+	if err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	return rp.ID, true, nil
+}
+
+// AddJobWithUUID adds a new job as in AddJobWithConfigs, recording
+// the client-supplied externalUUID with it. If a job was already
+// added with that UUID, nothing is added and the existing job's ID
+// is returned. created reports whether a new job was added.
+func (db *DB) AddJobWithUUID(externalUUID string, repoPullID RepoPullID, agentID AgentID, priorJobIDs []JobID, configKV map[string]string, configCodeReader map[string]JobPathConfig, configSpdxReader map[string]JobPathConfig) (id JobID, created bool, err error) {
+	externalUUID, err = normalizeExternalUUID("job", externalUUID)
+	if err != nil {
+		return 0, false, err
+	}
+	j := &Job{RepoPullID: repoPullID, AgentID: agentID, PriorJobIDs: priorJobIDs, Status: StatusStartup, Health: HealthOK, Config: JobConfig{KV: configKV, CodeReader: configCodeReader, SpdxReader: configSpdxReader}}
+	if err = j.Validate(); err != nil {
+		return 0, false, err
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return 0, false, err
+	}
+
+	jobStmt, err := db.prepareIn(tx, "INSERT INTO peridot.jobs(repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready, external_uuid) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (external_uuid) DO NOTHING RETURNING id")
+	if err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+
+	err = jobStmt.QueryRow(repoPullID, agentID, time.Time{}, time.Time{}, StatusStartup, HealthOK, "", false, externalUUID).Scan(&j.ID)
+	if err == sql.ErrNoRows {
+		// already added with this UUID
+		tx.Rollback()
+		existing, err := db.GetJobIDByExternalUUID(externalUUID)
+		return existing, false, err
+	}
+	if err != nil {
+		tx.Rollback()
+		return 0, false, translateConstraintError("job", err)
+	}
+
+	if err = db.addJobPriorsAndConfigs(tx, j.ID, priorJobIDs, configKV, configCodeReader, configSpdxReader); err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+
+	if err = addOutboxEvent(tx, "job", j.ID, AuditActionAdd, j); err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	return j.ID, true, nil
+}
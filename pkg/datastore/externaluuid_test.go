@@ -0,0 +1,265 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldAddRepoWithUUID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO peridot.repos")
+	mock.ExpectQuery(`INSERT INTO peridot.repos\(subproject_id, name, address, external_uuid\) VALUES \(\$1, \$2, \$3, \$4\) ON CONFLICT \(external_uuid\) DO NOTHING RETURNING id`).
+		WithArgs(1, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git", "3f2b8c1e-6a5d-4e0f-9b7a-1c2d3e4f5a6b").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function, with an uppercase UUID to check that
+	// it is normalized
+	repoID, created, err := db.AddRepoWithUUID("3F2B8C1E-6A5D-4E0F-9B7A-1C2D3E4F5A6B", 1, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if repoID != 6 {
+		t.Errorf("expected %v, got %v", 6, repoID)
+	}
+	if !created {
+		t.Errorf("expected repo to be created")
+	}
+}
+
+func TestShouldReturnExistingRepoForRepeatedUUID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO peridot.repos")
+	mock.ExpectQuery("INSERT INTO peridot.repos").
+		WithArgs(1, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git", "3f2b8c1e-6a5d-4e0f-9b7a-1c2d3e4f5a6b").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT id FROM peridot.repos WHERE external_uuid = \$1`).
+		WithArgs("3f2b8c1e-6a5d-4e0f-9b7a-1c2d3e4f5a6b").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+
+	// run the tested function
+	repoID, created, err := db.AddRepoWithUUID("3f2b8c1e-6a5d-4e0f-9b7a-1c2d3e4f5a6b", 1, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if repoID != 6 {
+		t.Errorf("expected %v, got %v", 6, repoID)
+	}
+	if created {
+		t.Errorf("expected existing repo to be returned, not created")
+	}
+}
+
+func TestShouldFailRepeatedUUIDForSoftDeletedRepo(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO peridot.repos")
+	mock.ExpectQuery("INSERT INTO peridot.repos").
+		WithArgs(1, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git", "3f2b8c1e-6a5d-4e0f-9b7a-1c2d3e4f5a6b").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT id FROM peridot.repos WHERE external_uuid = \$1 AND ` + liveRepoConditionRegex).
+		WithArgs("3f2b8c1e-6a5d-4e0f-9b7a-1c2d3e4f5a6b").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	// run the tested function
+	_, created, err := db.AddRepoWithUUID("3f2b8c1e-6a5d-4e0f-9b7a-1c2d3e4f5a6b", 1, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git")
+	var nfe *NotFoundError
+	if !errors.As(err, &nfe) || nfe.Key != "external UUID" {
+		t.Fatalf("expected *NotFoundError by external UUID, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if created {
+		t.Errorf("expected no repo to be created")
+	}
+}
+
+func TestShouldReturnExistingRepoPullForRepeatedUUID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO peridot.repo_pulls")
+	mock.ExpectQuery(`INSERT INTO peridot.repo_pulls(.+) ON CONFLICT \(external_uuid\) DO NOTHING RETURNING id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT id FROM peridot.repo_pulls WHERE external_uuid = \$1`).
+		WithArgs("0c9d8e7f-1a2b-4c3d-8e9f-a0b1c2d3e4f5").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+
+	// run the tested function
+	rpID, created, err := db.AddRepoPullWithUUID("0c9d8e7f-1a2b-4c3d-8e9f-a0b1c2d3e4f5", 4, "master", time.Time{}, time.Time{}, StatusStartup, HealthOK, "", "4b825dc642cb6eb9a060e54bf8d69288fbee4904", "", "")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if rpID != 12 {
+		t.Errorf("expected %v, got %v", 12, rpID)
+	}
+	if created {
+		t.Errorf("expected existing repo pull to be returned, not created")
+	}
+}
+
+func TestShouldAddJobWithUUIDAndConfigs(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO peridot.jobs")
+	mock.ExpectQuery(`INSERT INTO peridot.jobs(.+) ON CONFLICT \(external_uuid\) DO NOTHING RETURNING id`).
+		WithArgs(1, 4, sqlmock.AnyArg(), sqlmock.AnyArg(), StatusStartup, HealthOK, "", false, "5d4c3b2a-0f9e-4d8c-b7a6-958473625140").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectPrepare("INSERT INTO peridot.jobpathconfigs")
+	mock.ExpectExec("INSERT INTO peridot.jobpathconfigs").
+		WithArgs(9, IntFromJobConfigType(JobConfigKV), "hi", "there", nilArg{}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	jobID, created, err := db.AddJobWithUUID("5d4c3b2a-0f9e-4d8c-b7a6-958473625140", 1, 4, nil, map[string]string{"hi": "there"}, nil, nil)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if jobID != 9 {
+		t.Errorf("expected %v, got %v", 9, jobID)
+	}
+	if !created {
+		t.Errorf("expected job to be created")
+	}
+}
+
+func TestShouldReturnExistingJobForRepeatedUUIDWithoutAddingConfigs(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO peridot.jobs")
+	mock.ExpectQuery("INSERT INTO peridot.jobs").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT id FROM peridot.jobs WHERE external_uuid = \$1`).
+		WithArgs("5d4c3b2a-0f9e-4d8c-b7a6-958473625140").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+
+	// run the tested function
+	jobID, created, err := db.AddJobWithUUID("5d4c3b2a-0f9e-4d8c-b7a6-958473625140", 1, 4, nil, map[string]string{"hi": "there"}, nil, nil)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if jobID != 9 {
+		t.Errorf("expected %v, got %v", 9, jobID)
+	}
+	if created {
+		t.Errorf("expected existing job to be returned, not created")
+	}
+}
+
+func TestShouldFailAddWithMalformedUUID(t *testing.T) {
+	db := &DB{}
+	for _, u := range []string{"", "not-a-uuid", "3f2b8c1e6a5d4e0f9b7a1c2d3e4f5a6b", "3f2b8c1e-6a5d-4e0f-9b7a-1c2d3e4f5a6g"} {
+		_, _, err := db.AddRepoWithUUID(u, 1, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git")
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%q: expected *ValidationError, got %v", u, err)
+			continue
+		}
+		if verr.Field != "external UUID" {
+			t.Errorf("%q: expected %v, got %v", u, "external UUID", verr.Field)
+		}
+	}
+}
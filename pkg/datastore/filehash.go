@@ -21,6 +21,19 @@ type FileHash struct {
 	HashSHA1 string `json:"sha1"`
 }
 
+// Validate checks that the FileHash's hashes are full-length
+// lowercase hex strings. It returns nil if so, or a *ValidationError
+// describing the first invalid field.
+func (fh *FileHash) Validate() error {
+	if !isLowerHex(fh.HashSHA256, 64) {
+		return &ValidationError{Entity: "file hash", Field: "sha256", Reason: fmt.Sprintf("%q is not a 64-character hex hash", fh.HashSHA256)}
+	}
+	if !isLowerHex(fh.HashSHA1, 40) {
+		return &ValidationError{Entity: "file hash", Field: "sha1", Reason: fmt.Sprintf("%q is not a 40-character hex hash", fh.HashSHA1)}
+	}
+	return nil
+}
+
 // GetFileHashByID returns the FileHash with the given ID,
 // or nil and an error if not found.
 func (db *DB) GetFileHashByID(id uint64) (*FileHash, error) {
@@ -28,7 +41,7 @@ func (db *DB) GetFileHashByID(id uint64) (*FileHash, error) {
 	err := db.sqldb.QueryRow("SELECT id, hash_s256, hash_s1 FROM peridot.file_hashes WHERE id = $1", id).
 		Scan(&fh.ID, &fh.HashSHA256, &fh.HashSHA1)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no file hash found with ID %v", id)
+		return nil, &NotFoundError{Entity: "file hash", ID: fmt.Sprint(id)}
 	}
 	if err != nil {
 		return nil, err
@@ -70,7 +83,12 @@ func (db *DB) GetFileHashesByIDs(ids []uint64) ([]*FileHash, error) {
 // requiring its SHA256 and SHA1 values. It returns the
 // new file hash's ID on success or an error if failing.
 func (db *DB) AddFileHash(sha256 string, sha1 string) (uint64, error) {
-	stmt, err := db.sqldb.Prepare("INSERT INTO peridot.file_hashes(hash_s256, hash_s1) VALUES ($1, $2) RETURNING id")
+	fh := &FileHash{HashSHA256: sha256, HashSHA1: sha1}
+	if err := fh.Validate(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.file_hashes(hash_s256, hash_s1) VALUES ($1, $2) RETURNING id")
 	if err != nil {
 		return 0, err
 	}
@@ -90,7 +108,7 @@ func (db *DB) DeleteFileHash(id uint64) error {
 	var err error
 	var result sql.Result
 
-	stmt, err := db.sqldb.Prepare("DELETE FROM peridot.file_hashes WHERE id = $1")
+	stmt, err := db.prepare("DELETE FROM peridot.file_hashes WHERE id = $1")
 	if err != nil {
 		return err
 	}
@@ -107,7 +125,7 @@ func (db *DB) DeleteFileHash(id uint64) error {
 		return err
 	}
 	if rows == 0 {
-		return fmt.Errorf("no file hash found with ID %v", id)
+		return &NotFoundError{Entity: "file hash", ID: fmt.Sprint(id)}
 	}
 
 	return nil
@@ -330,3 +330,26 @@ func TestCannotUnmarshalFileHashWithNegativeIDFromJSON(t *testing.T) {
 		t.Fatalf("expected non-nil error, got nil")
 	}
 }
+
+func TestFileHashValidate(t *testing.T) {
+	s256 := "32b91a0bee702768018a1cb0df2d144c6b2ce806e504067216f44ab0fb839051"
+	s1 := "065165f810135a27c39327ce66d4df870d868e52"
+	tests := []struct {
+		fh      FileHash
+		isError bool
+	}{
+		{FileHash{HashSHA256: s256, HashSHA1: s1}, false},
+		// invalid values should fail
+		{FileHash{HashSHA256: s1, HashSHA1: s1}, true},
+		{FileHash{HashSHA256: s256, HashSHA1: s256}, true},
+		{FileHash{HashSHA256: s256, HashSHA1: "065165F810135A27C39327CE66D4DF870D868E52"}, true},
+		{FileHash{HashSHA256: s256, HashSHA1: "zz5165f810135a27c39327ce66d4df870d868e52"}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.fh.Validate()
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("for %+v: expected isError %v, got %v", tt.fh, tt.isError, err)
+		}
+	}
+}
@@ -16,7 +16,7 @@ type FileInstance struct {
 	ID uint64 `json:"id"`
 	// RepoPullID is the ID of the RepoPull containing this
 	// file instance.
-	RepoPullID uint32 `json:"repopull_id"`
+	RepoPullID RepoPullID `json:"repopull_id"`
 	// FileHashID is the ID of the FileHash that represents
 	// this file.
 	FileHashID uint64 `json:"filehash_id"`
@@ -24,6 +24,13 @@ type FileInstance struct {
 	Path string `json:"path"`
 }
 
+// Validate checks that the FileInstance's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (fi *FileInstance) Validate() error {
+	return requireNonEmpty("file instance", "path", fi.Path)
+}
+
 // GetFileInstanceByID returns the FileInstance with the given ID,
 // or nil and an error if not found.
 func (db *DB) GetFileInstanceByID(id uint64) (*FileInstance, error) {
@@ -31,7 +38,7 @@ func (db *DB) GetFileInstanceByID(id uint64) (*FileInstance, error) {
 	err := db.sqldb.QueryRow("SELECT id, repopull_id, filehash_id, path FROM peridot.file_instances WHERE id = $1", id).
 		Scan(&fi.ID, &fi.RepoPullID, &fi.FileHashID, &fi.Path)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no file instance found with ID %v", id)
+		return nil, &NotFoundError{Entity: "file instance", ID: fmt.Sprint(id)}
 	}
 	if err != nil {
 		return nil, err
@@ -40,12 +47,57 @@ func (db *DB) GetFileInstanceByID(id uint64) (*FileInstance, error) {
 	return &fi, nil
 }
 
+// fileInstanceSortColumns lists the fields by which GetFileInstances
+// can sort.
+var fileInstanceSortColumns = sortColumns{
+	"path": "path",
+}
+
+// GetFileInstances returns a slice of file instances in the RepoPull
+// with the given ID, sorted and paged as requested, along with a Page
+// describing the results. File instances can be sorted by id or
+// path.
+func (db *DB) GetFileInstances(rpID RepoPullID, pr PageRequest) ([]*FileInstance, Page, error) {
+	clause, args, err := pr.sqlClause(fileInstanceSortColumns, []interface{}{rpID})
+	if err != nil {
+		return nil, Page{}, err
+	}
+
+	rows, err := db.sqldb.Query("SELECT id, repopull_id, filehash_id, path FROM peridot.file_instances WHERE repopull_id = $1"+clause, args...)
+	if err != nil {
+		return nil, Page{}, err
+	}
+	defer rows.Close()
+
+	fis := []*FileInstance{}
+	for rows.Next() {
+		fi := &FileInstance{}
+		err := rows.Scan(&fi.ID, &fi.RepoPullID, &fi.FileHashID, &fi.Path)
+		if err != nil {
+			return nil, Page{}, err
+		}
+		fis = append(fis, fi)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Page{}, err
+	}
+
+	page, n := pr.page(len(fis))
+	return fis[:n], page, nil
+}
+
 // AddFileInstance adds a new file instance as specified,
 // requiring its parent RepoPull ID and path within it,
 // and the corresponding FileHash ID. It returns the new
 // file instance's ID on success or an error if failing.
-func (db *DB) AddFileInstance(repoPullID uint32, fileHashID uint64, path string) (uint64, error) {
-	stmt, err := db.sqldb.Prepare("INSERT INTO peridot.file_instances(repopull_id, filehash_id, path) VALUES ($1, $2, $3) RETURNING id")
+func (db *DB) AddFileInstance(repoPullID RepoPullID, fileHashID uint64, path string) (uint64, error) {
+	fi := &FileInstance{RepoPullID: repoPullID, FileHashID: fileHashID, Path: path}
+	if err := fi.Validate(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.file_instances(repopull_id, filehash_id, path) VALUES ($1, $2, $3) RETURNING id")
 	if err != nil {
 		return 0, err
 	}
@@ -65,7 +117,7 @@ func (db *DB) DeleteFileInstance(id uint64) error {
 	var err error
 	var result sql.Result
 
-	stmt, err := db.sqldb.Prepare("DELETE FROM peridot.file_instances WHERE id = $1")
+	stmt, err := db.prepare("DELETE FROM peridot.file_instances WHERE id = $1")
 	if err != nil {
 		return err
 	}
@@ -82,7 +134,7 @@ func (db *DB) DeleteFileInstance(id uint64) error {
 		return err
 	}
 	if rows == 0 {
-		return fmt.Errorf("no file instance found with ID %v", id)
+		return &NotFoundError{Entity: "file instance", ID: fmt.Sprint(id)}
 	}
 
 	return nil
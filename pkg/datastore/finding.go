@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Finding describes a license detected in a FileInstance by a
+// scanner Job. A file may have many findings, e.g. one for each
+// license notice or matched snippet within it.
+type Finding struct {
+	// ID is the unique ID for this finding.
+	ID uint64 `json:"id"`
+	// FileInstanceID is the ID of the FileInstance in which the
+	// license was found.
+	FileInstanceID uint64 `json:"fileinstance_id"`
+	// JobID is the ID of the Job that produced this finding. The
+	// Job's AgentID identifies the scanner.
+	JobID JobID `json:"job_id"`
+	// LicenseExpression is the SPDX license expression that was
+	// found, e.g. "MIT OR Apache-2.0".
+	LicenseExpression string `json:"license_expression"`
+	// LicenseIDs are the catalog IDs of the licenses referenced in
+	// LicenseExpression, as resolved by the scanner. Licenses that
+	// are not in the catalog are omitted.
+	LicenseIDs []uint32 `json:"license_ids,omitempty"`
+	// Score is the scanner's confidence in the finding, from 0 to
+	// 100.
+	Score float64 `json:"score"`
+	// StartLine is the first line of the matched text, counting
+	// from 1, or 0 if the finding applies to the whole file.
+	StartLine int `json:"start_line,omitempty"`
+	// EndLine is the last line of the matched text, or 0 if the
+	// finding applies to the whole file.
+	EndLine int `json:"end_line,omitempty"`
+	// Snippet is the matched text, if the scanner recorded it.
+	Snippet string `json:"snippet,omitempty"`
+	// OverrideID is the ID of the FindingOverride applied to this
+	// finding when it was retrieved, or 0 if none applied.
+	OverrideID uint64 `json:"override_id,omitempty"`
+	// IsSuppressed is true if a FindingOverride suppresses this
+	// finding.
+	IsSuppressed bool `json:"is_suppressed,omitempty"`
+	// ScannedExpression is the license expression found by the
+	// scanner, if a FindingOverride has replaced it in
+	// LicenseExpression, or "" otherwise.
+	ScannedExpression string `json:"scanned_expression,omitempty"`
+}
+
+// Validate checks that the Finding's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (f *Finding) Validate() error {
+	if err := requireNonEmpty("finding", "license_expression", f.LicenseExpression); err != nil {
+		return err
+	}
+	if f.Score < 0 || f.Score > 100 {
+		return &ValidationError{Entity: "finding", Field: "score", Reason: fmt.Sprintf("%v is not between 0 and 100", f.Score)}
+	}
+	if f.StartLine < 0 || f.EndLine < f.StartLine || (f.StartLine == 0) != (f.EndLine == 0) {
+		return &ValidationError{Entity: "finding", Field: "start_line", Reason: fmt.Sprintf("lines %d to %d are not a valid range", f.StartLine, f.EndLine)}
+	}
+	return nil
+}
+
+const findingColumns = "f.id, f.fileinstance_id, f.job_id, f.license_expression, f.score, f.start_line, f.end_line, f.snippet"
+
+// queryFindings runs a query selecting findingColumns from
+// peridot.findings as f, and returns the resulting findings with
+// their license IDs filled in and any active overrides applied.
+// Suppressed findings are omitted unless includeSuppressed is true.
+func (db *DB) queryFindings(includeSuppressed bool, query string, args ...interface{}) ([]*Finding, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fs := []*Finding{}
+	// also index findings by ID so we can fill in license IDs below
+	byID := map[uint64]*Finding{}
+	ids := []uint64{}
+	for rows.Next() {
+		f := &Finding{LicenseIDs: []uint32{}}
+		err := rows.Scan(&f.ID, &f.FileInstanceID, &f.JobID, &f.LicenseExpression, &f.Score, &f.StartLine, &f.EndLine, &f.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+		byID[f.ID] = f
+		ids = append(ids, f.ID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(fs) == 0 {
+		return fs, nil
+	}
+
+	licRows, err := db.sqldb.Query("SELECT finding_id, license_id FROM peridot.finding_licenses WHERE finding_id = ANY ($1) ORDER BY finding_id, license_id", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer licRows.Close()
+
+	for licRows.Next() {
+		var fid uint64
+		var lid uint32
+		err := licRows.Scan(&fid, &lid)
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := byID[fid]; ok {
+			f.LicenseIDs = append(f.LicenseIDs, lid)
+		}
+	}
+	if err = licRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return db.applyFindingOverrides(fs, includeSuppressed)
+}
+
+// GetFindingsForRepoPull returns a slice of all findings for files
+// in the RepoPull with the given ID, ordered by file instance and
+// then by finding ID. Active overrides are applied, and suppressed
+// findings are omitted unless includeSuppressed is true.
+func (db *DB) GetFindingsForRepoPull(rpID RepoPullID, includeSuppressed bool) ([]*Finding, error) {
+	return db.queryFindings(includeSuppressed, "SELECT "+findingColumns+" FROM peridot.findings f JOIN peridot.file_instances fi ON fi.id = f.fileinstance_id WHERE fi.repopull_id = $1 ORDER BY f.fileinstance_id, f.id", rpID)
+}
+
+// GetFindingsForFileInstance returns a slice of all findings for the
+// FileInstance with the given ID, ordered by ID. Active overrides
+// are applied, and suppressed findings are omitted unless
+// includeSuppressed is true.
+func (db *DB) GetFindingsForFileInstance(fileInstanceID uint64, includeSuppressed bool) ([]*Finding, error) {
+	return db.queryFindings(includeSuppressed, "SELECT "+findingColumns+" FROM peridot.findings f WHERE f.fileinstance_id = $1 ORDER BY f.id", fileInstanceID)
+}
+
+// GetFindingsForLicense returns a slice of all findings whose
+// license expression references the License with the given ID,
+// ordered by ID. Active overrides are applied, and suppressed
+// findings are omitted unless includeSuppressed is true. Licenses
+// are matched as resolved by the scanner, so a finding whose
+// expression was corrected is still returned for the licenses it
+// originally referenced.
+func (db *DB) GetFindingsForLicense(licenseID uint32, includeSuppressed bool) ([]*Finding, error) {
+	return db.queryFindings(includeSuppressed, "SELECT "+findingColumns+" FROM peridot.findings f WHERE f.id IN (SELECT finding_id FROM peridot.finding_licenses WHERE license_id = $1) ORDER BY f.id", licenseID)
+}
+
+// AddFindings adds the given findings in a single transaction, so
+// that either all or none of them are added. The findings' ID fields
+// are ignored. It returns the new findings' IDs, in the same order,
+// on success or an error if failing.
+func (db *DB) AddFindings(findings []*Finding) ([]uint64, error) {
+	for _, f := range findings {
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+
+	findingStmt, err := db.prepareIn(tx, "INSERT INTO peridot.findings(fileinstance_id, job_id, license_expression, score, start_line, end_line, snippet) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	licenseStmt, err := db.prepareIn(tx, "INSERT INTO peridot.finding_licenses(finding_id, license_id) VALUES ($1, $2) ON CONFLICT DO NOTHING")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	ids := make([]uint64, 0, len(findings))
+	for _, f := range findings {
+		var id uint64
+		err = findingStmt.QueryRow(f.FileInstanceID, f.JobID, f.LicenseExpression, f.Score, f.StartLine, f.EndLine, f.Snippet).Scan(&id)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		for _, lid := range f.LicenseIDs {
+			_, err = licenseStmt.Exec(id, lid)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+		ids = append(ids, id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// DeleteFindingsForJob deletes all findings produced by the Job with
+// the given ID, e.g. before re-running it. It returns the number of
+// findings deleted on success or an error if failing.
+func (db *DB) DeleteFindingsForJob(jobID JobID) (int64, error) {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.findings WHERE job_id = $1", jobID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
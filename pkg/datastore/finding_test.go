@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestShouldGetFindingsForRepoPull(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "fileinstance_id", "job_id", "license_expression", "score", "start_line", "end_line", "snippet"}).
+		AddRow(101, 7, 12, "MIT", 100.0, 0, 0, "").
+		AddRow(102, 8, 12, "MIT OR Apache-2.0", 87.5, 3, 14, "Licensed under either of")
+	mock.ExpectQuery(`SELECT f.id, f.fileinstance_id, f.job_id, f.license_expression, f.score, f.start_line, f.end_line, f.snippet FROM peridot.findings f JOIN peridot.file_instances fi ON fi.id = f.fileinstance_id WHERE fi.repopull_id = \$1 ORDER BY f.fileinstance_id, f.id`).
+		WithArgs(36).
+		WillReturnRows(sentRows)
+	mock.ExpectQuery(`SELECT finding_id, license_id FROM peridot.finding_licenses WHERE finding_id = ANY \(\$1\)`).
+		WithArgs(pq.Array([]uint64{101, 102})).
+		WillReturnRows(sqlmock.NewRows([]string{"finding_id", "license_id"}).
+			AddRow(101, 340).
+			AddRow(102, 12).
+			AddRow(102, 340))
+	mock.ExpectQuery(`SELECT o.id, fi.id, o.match_expression, o.action, o.corrected_expression FROM peridot.finding_overrides o .* WHERE fi.id = ANY \(\$1\) AND \(o.expires_at IS NULL OR o.expires_at > \$2\)`).
+		WithArgs(pq.Array([]uint64{7, 8}), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "fileinstance_id", "match_expression", "action", "corrected_expression"}))
+
+	// run the tested function
+	gotRows, err := db.GetFindingsForRepoPull(36, false)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*Finding{
+		&Finding{ID: 101, FileInstanceID: 7, JobID: 12, LicenseExpression: "MIT", LicenseIDs: []uint32{340}, Score: 100},
+		&Finding{ID: 102, FileInstanceID: 8, JobID: 12, LicenseExpression: "MIT OR Apache-2.0", LicenseIDs: []uint32{12, 340}, Score: 87.5, StartLine: 3, EndLine: 14, Snippet: "Licensed under either of"},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldGetNoFindingsForLicenseWithoutQueryingLicenses(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT .* FROM peridot.findings f WHERE f.id IN \(SELECT finding_id FROM peridot.finding_licenses WHERE license_id = \$1\) ORDER BY f.id`).
+		WithArgs(340).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "fileinstance_id", "job_id", "license_expression", "score", "start_line", "end_line", "snippet"}))
+
+	// run the tested function
+	gotRows, err := db.GetFindingsForLicense(340, false)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if len(gotRows) != 0 {
+		t.Errorf("expected len %d, got %d", 0, len(gotRows))
+	}
+}
+
+func TestShouldAddFindings(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	findingStmt := `INSERT INTO peridot.findings\(fileinstance_id, job_id, license_expression, score, start_line, end_line, snippet\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\) RETURNING id`
+	licenseStmt := `INSERT INTO peridot.finding_licenses\(finding_id, license_id\) VALUES \(\$1, \$2\) ON CONFLICT DO NOTHING`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(findingStmt)
+	mock.ExpectPrepare(licenseStmt)
+	mock.ExpectQuery(findingStmt).
+		WithArgs(7, 12, "MIT", 100.0, 0, 0, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(101))
+	mock.ExpectExec(licenseStmt).
+		WithArgs(101, 340).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(findingStmt).
+		WithArgs(8, 12, "LicenseRef-acme", 60.0, 3, 14, "Acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(102))
+	mock.ExpectCommit()
+
+	// run the tested function
+	ids, err := db.AddFindings([]*Finding{
+		&Finding{FileInstanceID: 7, JobID: 12, LicenseExpression: "MIT", LicenseIDs: []uint32{340}, Score: 100},
+		&Finding{FileInstanceID: 8, JobID: 12, LicenseExpression: "LicenseRef-acme", Score: 60, StartLine: 3, EndLine: 14, Snippet: "Acme"},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if !reflect.DeepEqual([]uint64{101, 102}, ids) {
+		t.Errorf("expected %v, got %v", []uint64{101, 102}, ids)
+	}
+}
+
+func TestShouldFailAddFindingsWithInvalidFinding(t *testing.T) {
+	tests := []*Finding{
+		&Finding{FileInstanceID: 7, JobID: 12, Score: 100},
+		&Finding{FileInstanceID: 7, JobID: 12, LicenseExpression: "MIT", Score: 101},
+		&Finding{FileInstanceID: 7, JobID: 12, LicenseExpression: "MIT", Score: 100, StartLine: 14, EndLine: 3},
+		&Finding{FileInstanceID: 7, JobID: 12, LicenseExpression: "MIT", Score: 100, StartLine: 3},
+	}
+
+	for _, f := range tests {
+		// set up mock; no queries are expected
+		sqldb, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("got error when creating db mock: %v", err)
+		}
+		db := DB{sqldb: sqldb}
+
+		_, err = db.AddFindings([]*Finding{f})
+		if _, ok := err.(*ValidationError); !ok {
+			t.Errorf("for %#v: expected *ValidationError, got %v", f, err)
+		}
+		if err = mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+		sqldb.Close()
+	}
+}
@@ -0,0 +1,292 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Actions that a FindingOverride can take.
+const (
+	// FindingOverrideSuppress means matching findings are hidden
+	// from finding queries unless suppressed findings are requested.
+	FindingOverrideSuppress = "suppress"
+	// FindingOverrideCorrect means matching findings are reported
+	// with the override's corrected license expression.
+	FindingOverrideCorrect = "correct"
+)
+
+// FindingOverride describes an operator's manual curation of the
+// findings for a file, either suppressing them or correcting their
+// license expression. An override is scoped either to a single
+// FileInstance or to every instance of a FileHash, and applies to
+// findings whether they were added before or after it.
+type FindingOverride struct {
+	// ID is the unique ID for this override.
+	ID uint64 `json:"id"`
+	// FileInstanceID is the ID of the FileInstance whose findings
+	// are overridden, or 0 if the override is scoped to a FileHash.
+	FileInstanceID uint64 `json:"fileinstance_id,omitempty"`
+	// FileHashID is the ID of the FileHash whose findings, in every
+	// instance of the file, are overridden, or 0 if the override is
+	// scoped to a FileInstance.
+	FileHashID uint64 `json:"filehash_id,omitempty"`
+	// MatchExpression is the license expression of the findings to
+	// override, or "" to override all findings for the file.
+	MatchExpression string `json:"match_expression,omitempty"`
+	// Action is one of the FindingOverride action values.
+	Action string `json:"action"`
+	// CorrectedExpression is the license expression reported in
+	// place of the scanned one, if Action is FindingOverrideCorrect.
+	CorrectedExpression string `json:"corrected_expression,omitempty"`
+	// Justification explains why the override was made.
+	Justification string `json:"justification"`
+	// CreatedBy is the ID of the User who made the override, or 0
+	// if unknown.
+	CreatedBy UserID `json:"created_by,omitempty"`
+	// CreatedAt is when the override was made.
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is when the override stops applying. Should be zero
+	// value if it does not expire.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// MarshalJSON converts the FindingOverride into a slice of bytes
+// containing its JSON encoding, leaving out expires_at for an
+// override that does not expire.
+func (o FindingOverride) MarshalJSON() ([]byte, error) {
+	type findingOverrideAlias FindingOverride
+	return json.Marshal(struct {
+		findingOverrideAlias
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	}{findingOverrideAlias: findingOverrideAlias(o), ExpiresAt: jsonTimePtr(o.ExpiresAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of a FindingOverride into the FindingOverride. A missing or null
+// expires_at means the override does not expire.
+func (o *FindingOverride) UnmarshalJSON(b []byte) error {
+	type findingOverrideAlias FindingOverride
+	aux := struct {
+		*findingOverrideAlias
+		ExpiresAt *time.Time `json:"expires_at"`
+	}{findingOverrideAlias: (*findingOverrideAlias)(o)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	o.ExpiresAt = timeFromJSONPtr(aux.ExpiresAt)
+	return nil
+}
+
+// Validate checks that the FindingOverride's fields are well-formed.
+// It returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (o *FindingOverride) Validate() error {
+	if (o.FileInstanceID == 0) == (o.FileHashID == 0) {
+		return &ValidationError{Entity: "finding override", Field: "fileinstance_id", Reason: "exactly one of fileinstance_id and filehash_id must be set"}
+	}
+	switch o.Action {
+	case FindingOverrideSuppress:
+		if o.CorrectedExpression != "" {
+			return &ValidationError{Entity: "finding override", Field: "corrected_expression", Reason: "must be empty when suppressing"}
+		}
+	case FindingOverrideCorrect:
+		if err := requireNonEmpty("finding override", "corrected_expression", o.CorrectedExpression); err != nil {
+			return err
+		}
+	default:
+		return &ValidationError{Entity: "finding override", Field: "action", Reason: "must be suppress or correct"}
+	}
+	return requireNonEmpty("finding override", "justification", o.Justification)
+}
+
+const findingOverrideColumns = "o.id, o.fileinstance_id, o.filehash_id, o.match_expression, o.action, o.corrected_expression, o.justification, o.created_by, o.created_at, o.expires_at"
+
+// scanFindingOverride reads a FindingOverride from a row selecting
+// findingOverrideColumns.
+func scanFindingOverride(rs rowScanner) (*FindingOverride, error) {
+	o := &FindingOverride{}
+	var fileInstanceID, fileHashID, createdBy sql.NullInt64
+	var expiresAt pq.NullTime
+	err := rs.Scan(&o.ID, &fileInstanceID, &fileHashID, &o.MatchExpression, &o.Action, &o.CorrectedExpression, &o.Justification, &createdBy, &o.CreatedAt, &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	o.FileInstanceID = uint64(fileInstanceID.Int64)
+	o.FileHashID = uint64(fileHashID.Int64)
+	o.CreatedBy = UserID(createdBy.Int64)
+	o.CreatedAt = normalizeTime(o.CreatedAt)
+	if expiresAt.Valid {
+		o.ExpiresAt = normalizeTime(expiresAt.Time)
+	}
+	return o, nil
+}
+
+// GetFindingOverridesForFileInstance returns a slice of all overrides
+// for the FileInstance with the given ID, whether scoped to it or to
+// its FileHash and including expired ones, ordered by ID.
+func (db *DB) GetFindingOverridesForFileInstance(fileInstanceID uint64) ([]*FindingOverride, error) {
+	rows, err := db.sqldb.Query("SELECT "+findingOverrideColumns+" FROM peridot.finding_overrides o JOIN peridot.file_instances fi ON (o.fileinstance_id = fi.id OR o.filehash_id = fi.filehash_id) WHERE fi.id = $1 ORDER BY o.id", fileInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := []*FindingOverride{}
+	for rows.Next() {
+		o, err := scanFindingOverride(rows)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// GetFindingOverrideByID returns the FindingOverride with the given
+// ID, or nil and an error if not found.
+func (db *DB) GetFindingOverrideByID(id uint64) (*FindingOverride, error) {
+	o, err := scanFindingOverride(db.sqldb.QueryRow("SELECT "+findingOverrideColumns+" FROM peridot.finding_overrides o WHERE o.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "finding override", ID: fmt.Sprint(id)}
+	}
+	return o, err
+}
+
+// AddFindingOverride adds a new override of the findings for a file.
+// o's ID and CreatedAt fields are ignored. It returns the new
+// override's ID on success or an error if failing.
+func (db *DB) AddFindingOverride(o *FindingOverride) (uint64, error) {
+	if err := o.Validate(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.finding_overrides(fileinstance_id, filehash_id, match_expression, action, corrected_expression, justification, created_by, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var overrideID uint64
+	err = stmt.QueryRow(
+		sql.NullInt64{Int64: int64(o.FileInstanceID), Valid: o.FileInstanceID != 0},
+		sql.NullInt64{Int64: int64(o.FileHashID), Valid: o.FileHashID != 0},
+		o.MatchExpression, o.Action, o.CorrectedExpression, o.Justification,
+		sql.NullInt64{Int64: int64(o.CreatedBy), Valid: o.CreatedBy != 0},
+		now(), nullTimeFromTime(o.ExpiresAt),
+	).Scan(&overrideID)
+	if err != nil {
+		return 0, err
+	}
+	return overrideID, nil
+}
+
+// DeleteFindingOverride deletes the existing FindingOverride with the
+// given ID, so that the findings it covered are reported as scanned.
+// It returns nil on success or an error if failing.
+func (db *DB) DeleteFindingOverride(id uint64) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.finding_overrides WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "finding override", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// activeFindingOverridesQuery selects the unexpired overrides that
+// apply to each of the file instances in $1 as of time $2, with
+// overrides scoped to the instance ahead of those scoped to its
+// hash, and newer overrides ahead of older ones.
+const activeFindingOverridesQuery = `
+SELECT o.id, fi.id, o.match_expression, o.action, o.corrected_expression
+FROM peridot.finding_overrides o
+JOIN peridot.file_instances fi ON (o.fileinstance_id = fi.id OR o.filehash_id = fi.filehash_id)
+WHERE fi.id = ANY ($1) AND (o.expires_at IS NULL OR o.expires_at > $2)
+ORDER BY fi.id, o.fileinstance_id IS NULL, o.id DESC
+`
+
+// applyFindingOverrides applies the active overrides to the given
+// findings, and returns those that remain: suppressed findings are
+// dropped unless includeSuppressed is true. Only the first matching
+// override, in the order of activeFindingOverridesQuery, is applied
+// to each finding.
+func (db *DB) applyFindingOverrides(fs []*Finding, includeSuppressed bool) ([]*Finding, error) {
+	fileInstanceIDs := []uint64{}
+	seen := map[uint64]bool{}
+	for _, f := range fs {
+		if !seen[f.FileInstanceID] {
+			seen[f.FileInstanceID] = true
+			fileInstanceIDs = append(fileInstanceIDs, f.FileInstanceID)
+		}
+	}
+
+	rows, err := db.sqldb.Query(activeFindingOverridesQuery, pq.Array(fileInstanceIDs), now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := map[uint64][]*FindingOverride{}
+	for rows.Next() {
+		o := &FindingOverride{}
+		var fileInstanceID uint64
+		err := rows.Scan(&o.ID, &fileInstanceID, &o.MatchExpression, &o.Action, &o.CorrectedExpression)
+		if err != nil {
+			return nil, err
+		}
+		overrides[fileInstanceID] = append(overrides[fileInstanceID], o)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	kept := make([]*Finding, 0, len(fs))
+	for _, f := range fs {
+		if o := matchFindingOverride(f, overrides[f.FileInstanceID]); o != nil {
+			f.OverrideID = o.ID
+			switch o.Action {
+			case FindingOverrideSuppress:
+				f.IsSuppressed = true
+			case FindingOverrideCorrect:
+				f.ScannedExpression = f.LicenseExpression
+				f.LicenseExpression = o.CorrectedExpression
+			}
+		}
+		if f.IsSuppressed && !includeSuppressed {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept, nil
+}
+
+// matchFindingOverride returns the first of overrides that matches
+// the license expression of f, or nil if none do.
+func matchFindingOverride(f *Finding, overrides []*FindingOverride) *FindingOverride {
+	for _, o := range overrides {
+		if o.MatchExpression == "" || o.MatchExpression == f.LicenseExpression {
+			return o
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestShouldApplyFindingOverridesForFileInstance(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT .* FROM peridot.findings f WHERE f.fileinstance_id = \$1 ORDER BY f.id`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "fileinstance_id", "job_id", "license_expression", "score", "start_line", "end_line", "snippet"}).
+			AddRow(101, 7, 12, "MIT", 100.0, 0, 0, "").
+			AddRow(102, 7, 12, "GPL-2.0-only", 40.0, 3, 4, "").
+			AddRow(103, 7, 12, "BSD-3-Clause", 90.0, 10, 30, ""))
+	mock.ExpectQuery(`SELECT finding_id, license_id FROM peridot.finding_licenses WHERE finding_id = ANY \(\$1\)`).
+		WithArgs(pq.Array([]uint64{101, 102, 103})).
+		WillReturnRows(sqlmock.NewRows([]string{"finding_id", "license_id"}).
+			AddRow(101, 340).
+			AddRow(102, 150).
+			AddRow(103, 40))
+	// instance-scoped override 9 is ahead of hash-scoped override 11
+	mock.ExpectQuery(`SELECT o.id, fi.id, o.match_expression, o.action, o.corrected_expression FROM peridot.finding_overrides o .* ORDER BY fi.id, o.fileinstance_id IS NULL, o.id DESC`).
+		WithArgs(pq.Array([]uint64{7}), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "fileinstance_id", "match_expression", "action", "corrected_expression"}).
+			AddRow(9, 7, "GPL-2.0-only", "suppress", "").
+			AddRow(11, 7, "", "correct", "BSD-2-Clause"))
+
+	// run the tested function
+	gotRows, err := db.GetFindingsForFileInstance(7, false)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*Finding{
+		{ID: 101, FileInstanceID: 7, JobID: 12, LicenseExpression: "BSD-2-Clause", LicenseIDs: []uint32{340}, Score: 100, OverrideID: 11, ScannedExpression: "MIT"},
+		{ID: 103, FileInstanceID: 7, JobID: 12, LicenseExpression: "BSD-2-Clause", LicenseIDs: []uint32{40}, Score: 90, StartLine: 10, EndLine: 30, OverrideID: 11, ScannedExpression: "BSD-3-Clause"},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldIncludeSuppressedFindingsWhenRequested(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT .* FROM peridot.findings f WHERE f.fileinstance_id = \$1 ORDER BY f.id`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "fileinstance_id", "job_id", "license_expression", "score", "start_line", "end_line", "snippet"}).
+			AddRow(102, 7, 12, "GPL-2.0-only", 40.0, 3, 4, ""))
+	mock.ExpectQuery(`SELECT finding_id, license_id FROM peridot.finding_licenses`).
+		WithArgs(pq.Array([]uint64{102})).
+		WillReturnRows(sqlmock.NewRows([]string{"finding_id", "license_id"}))
+	mock.ExpectQuery(`FROM peridot.finding_overrides o`).
+		WithArgs(pq.Array([]uint64{7}), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "fileinstance_id", "match_expression", "action", "corrected_expression"}).
+			AddRow(9, 7, "", "suppress", ""))
+
+	// run the tested function
+	gotRows, err := db.GetFindingsForFileInstance(7, true)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*Finding{
+		{ID: 102, FileInstanceID: 7, JobID: 12, LicenseExpression: "GPL-2.0-only", LicenseIDs: []uint32{}, Score: 40, StartLine: 3, EndLine: 4, OverrideID: 9, IsSuppressed: true},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldAddFindingOverride(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	expiresAt := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	regexStmt := `INSERT INTO peridot.finding_overrides\(fileinstance_id, filehash_id, match_expression, action, corrected_expression, justification, created_by, created_at, expires_at\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(nilArg{}, 21, "MIT", "correct", "Apache-2.0", "Header is a false positive", 3, sqlmock.AnyArg(), expiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(14))
+
+	// run the tested function
+	id, err := db.AddFindingOverride(&FindingOverride{
+		FileHashID:          21,
+		MatchExpression:     "MIT",
+		Action:              FindingOverrideCorrect,
+		CorrectedExpression: "Apache-2.0",
+		Justification:       "Header is a false positive",
+		CreatedBy:           3,
+		ExpiresAt:           expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 14 {
+		t.Errorf("expected %v, got %v", 14, id)
+	}
+}
+
+func TestShouldFailAddFindingOverrideWithInvalidFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		o     *FindingOverride
+		field string
+	}{
+		{"no scope", &FindingOverride{Action: FindingOverrideSuppress, Justification: "x"}, "fileinstance_id"},
+		{"both scopes", &FindingOverride{FileInstanceID: 7, FileHashID: 21, Action: FindingOverrideSuppress, Justification: "x"}, "fileinstance_id"},
+		{"unknown action", &FindingOverride{FileInstanceID: 7, Action: "ignore", Justification: "x"}, "action"},
+		{"correct without expression", &FindingOverride{FileInstanceID: 7, Action: FindingOverrideCorrect, Justification: "x"}, "corrected_expression"},
+		{"suppress with expression", &FindingOverride{FileInstanceID: 7, Action: FindingOverrideSuppress, CorrectedExpression: "MIT", Justification: "x"}, "corrected_expression"},
+		{"no justification", &FindingOverride{FileInstanceID: 7, Action: FindingOverrideSuppress}, "justification"},
+	}
+	for _, tc := range tests {
+		_, err := (&DB{}).AddFindingOverride(tc.o)
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%s: expected *ValidationError, got %v", tc.name, err)
+			continue
+		}
+		if verr.Field != tc.field {
+			t.Errorf("%s: expected field %v, got %v", tc.name, tc.field, verr.Field)
+		}
+	}
+}
+
+func TestShouldFailDeleteFindingOverrideWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`DELETE FROM peridot.finding_overrides WHERE id = \$1`).
+		WithArgs(413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.DeleteFindingOverride(413)
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
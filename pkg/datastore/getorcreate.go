@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// getOrCreateAttempts is the number of times a GetOrCreate method
+// tries to insert or find its entity before giving up. More than one
+// attempt is only needed if the entity is deleted between a failed
+// insert and the lookup that follows it.
+const getOrCreateAttempts = 3
+
+// RetriesExhaustedError is returned by a GetOrCreate method when,
+// on every attempt, the insert found the entity already present but
+// the entity was deleted again before it could be looked up. The
+// caller may retry the call later.
+type RetriesExhaustedError struct {
+	// Entity is the kind of entity that was being retrieved or
+	// added, such as "project".
+	Entity string
+	// Name is the name of that entity.
+	Name string
+	// Attempts is the number of attempts that were made.
+	Attempts int
+}
+
+func (e *RetriesExhaustedError) Error() string {
+	return fmt.Sprintf("could not get or create %s %q: it was deleted concurrently on each of %d attempts", e.Entity, e.Name, e.Attempts)
+}
+
+// insertIfAbsent runs query, an INSERT ... ON CONFLICT DO NOTHING
+// RETURNING id, in a transaction that also records an outbox event
+// for the new row with the payload returned by payload. If the row
+// already existed, nothing is written and created is false.
+func (db *DB) insertIfAbsent(entity string, payload func(id uint32) interface{}, query string, args ...interface{}) (id uint32, created bool, err error) {
+	tx, err := db.begin()
+	if err != nil {
+		return 0, false, err
+	}
+
+	err = tx.QueryRow(query, args...).Scan(&id)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return 0, false, nil
+	}
+	if err != nil {
+		tx.Rollback()
+		return 0, false, translateConstraintError(strings.ReplaceAll(entity, "_", " "), err)
+	}
+
+	err = addOutboxEvent(tx, entity, id, AuditActionAdd, payload(id))
+	if err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	return id, true, nil
+}
+
+// GetOrCreateProject returns the Project with the given name, adding
+// it with the given full name if it does not exist. Concurrent calls
+// with the same name all return the same project. It also reports
+// whether the project was added; if it was not, the existing
+// project's full name is left unchanged.
+func (db *DB) GetOrCreateProject(name string, fullname string) (*Project, bool, error) {
+	p := &Project{Name: name, Fullname: fullname}
+	if err := p.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	for attempt := 0; attempt < getOrCreateAttempts; attempt++ {
+		id, created, err := db.insertIfAbsent("project", func(id uint32) interface{} { p.ID = ProjectID(id); return p },
+			"INSERT INTO peridot.projects(name, fullname) VALUES ($1, $2) ON CONFLICT (name) WHERE deleted_at IS NULL DO NOTHING RETURNING id", name, fullname)
+		if err != nil {
+			return nil, false, err
+		}
+		if created {
+			p.ID = ProjectID(id)
+			return p, true, nil
+		}
+
+		existing, err := scanProject(db.sqldb.QueryRow("SELECT "+projectColumns+" FROM peridot.projects WHERE name = $1 AND deleted_at IS NULL", name))
+		if err == sql.ErrNoRows {
+			// deleted since the insert was attempted; try again
+			continue
+		}
+		return existing, false, err
+	}
+	return nil, false, &RetriesExhaustedError{Entity: "project", Name: name, Attempts: getOrCreateAttempts}
+}
+
+// GetOrCreateSubproject returns the Subproject with the given name in
+// the Project with the given ID, adding it with the given full name
+// if it does not exist. Concurrent calls with the same project and
+// name all return the same subproject. It also reports whether the
+// subproject was added.
+func (db *DB) GetOrCreateSubproject(projectID ProjectID, name string, fullname string) (*Subproject, bool, error) {
+	sp := &Subproject{ProjectID: projectID, Name: name, Fullname: fullname}
+	if err := sp.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	for attempt := 0; attempt < getOrCreateAttempts; attempt++ {
+		id, created, err := db.insertIfAbsent("subproject", func(id uint32) interface{} { sp.ID = id; return sp },
+			"INSERT INTO peridot.subprojects(project_id, name, fullname) VALUES ($1, $2, $3) ON CONFLICT (project_id, name) WHERE deleted_at IS NULL DO NOTHING RETURNING id", projectID, name, fullname)
+		if err != nil {
+			return nil, false, err
+		}
+		if created {
+			sp.ID = id
+			return sp, true, nil
+		}
+
+		existing, err := scanSubproject(db.sqldb.QueryRow("SELECT "+subprojectColumns+" FROM peridot.subprojects WHERE project_id = $1 AND name = $2 AND deleted_at IS NULL", projectID, name))
+		if err == sql.ErrNoRows {
+			// deleted since the insert was attempted; try again
+			continue
+		}
+		return existing, false, err
+	}
+	return nil, false, &RetriesExhaustedError{Entity: "subproject", Name: name, Attempts: getOrCreateAttempts}
+}
+
+// GetOrCreateRepo returns the Repo with the given name in the
+// Subproject with the given ID, adding it with the given address if
+// it does not exist. Concurrent calls with the same subproject and
+// name all return the same repo. It also reports whether the repo
+// was added; if it was not, the existing repo's address is left
+// unchanged.
+func (db *DB) GetOrCreateRepo(subprojectID uint32, name string, address string) (*Repo, bool, error) {
+	r := &Repo{SubprojectID: subprojectID, Name: name, Address: address}
+	if err := r.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	for attempt := 0; attempt < getOrCreateAttempts; attempt++ {
+		id, created, err := db.insertIfAbsent("repo", func(id uint32) interface{} { r.ID = RepoID(id); return r },
+			"INSERT INTO peridot.repos(subproject_id, name, address) VALUES ($1, $2, $3) ON CONFLICT (subproject_id, name) WHERE deleted_at IS NULL DO NOTHING RETURNING id", subprojectID, name, address)
+		if err != nil {
+			return nil, false, err
+		}
+		if created {
+			r.ID = RepoID(id)
+			return r, true, nil
+		}
+
+		existing, err := scanRepo(db.sqldb.QueryRow("SELECT "+repoColumns+" FROM peridot.repos WHERE subproject_id = $1 AND name = $2 AND deleted_at IS NULL", subprojectID, name))
+		if err == sql.ErrNoRows {
+			// deleted since the insert was attempted; try again
+			continue
+		}
+		return existing, false, err
+	}
+	return nil, false, &RetriesExhaustedError{Entity: "repo", Name: name, Attempts: getOrCreateAttempts}
+}
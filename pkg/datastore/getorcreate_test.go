@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetOrCreateProjectWhenAbsent(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO peridot.projects\(name, fullname\) VALUES \(\$1, \$2\) ON CONFLICT \(name\) WHERE deleted_at IS NULL DO NOTHING RETURNING id`).
+		WithArgs("xyzzy", "The Xyzzy Project").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	p, created, err := db.GetOrCreateProject("xyzzy", "The Xyzzy Project")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if !created {
+		t.Errorf("expected project to be created")
+	}
+	if p.ID != 3 {
+		t.Errorf("expected %v, got %v", 3, p.ID)
+	}
+	if p.Fullname != "The Xyzzy Project" {
+		t.Errorf("expected %v, got %v", "The Xyzzy Project", p.Fullname)
+	}
+}
+
+func TestShouldGetOrCreateRepoWhenPresent(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO peridot.repos\(subproject_id, name, address\) VALUES \(\$1, \$2, \$3\) ON CONFLICT \(subproject_id, name\) WHERE deleted_at IS NULL DO NOTHING RETURNING id`).
+		WithArgs(2, "kubernetes/kubernetes", "https://github.com/kubernetes/kubernetes.git").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT id, subproject_id, name, address, created_at, updated_at FROM peridot.repos WHERE subproject_id = \$1 AND name = \$2`).
+		WithArgs(2, "kubernetes/kubernetes").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subproject_id", "name", "address", "created_at", "updated_at"}).
+			AddRow(6, 2, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git", testCreatedAt, testUpdatedAt))
+
+	// run the tested function
+	r, created, err := db.GetOrCreateRepo(2, "kubernetes/kubernetes", "https://github.com/kubernetes/kubernetes.git")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if created {
+		t.Errorf("expected existing repo to be returned, not created")
+	}
+	if r.ID != 6 {
+		t.Errorf("expected %v, got %v", 6, r.ID)
+	}
+	if r.Address != "git@github.com:kubernetes/kubernetes.git" {
+		t.Errorf("expected existing address to be unchanged, got %v", r.Address)
+	}
+}
+
+func TestShouldRetryGetOrCreateSubprojectIfDeletedBeforeLookup(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO peridot.subprojects").
+		WithArgs(1, "sub", "Subproject").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT id, project_id, name, fullname, created_at, updated_at FROM peridot.subprojects WHERE project_id = \$1 AND name = \$2`).
+		WithArgs(1, "sub").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "fullname", "created_at", "updated_at"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO peridot.subprojects").
+		WithArgs(1, "sub", "Subproject").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	sp, created, err := db.GetOrCreateSubproject(1, "sub", "Subproject")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if !created {
+		t.Errorf("expected subproject to be created on retry")
+	}
+	if sp.ID != 8 {
+		t.Errorf("expected %v, got %v", 8, sp.ID)
+	}
+}
+
+func TestShouldFailGetOrCreateRepoIfDeletedOnEveryAttempt(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	for i := 0; i < getOrCreateAttempts; i++ {
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO peridot.repos").
+			WithArgs(2, "kubernetes", "https://github.com/kubernetes/kubernetes").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+		mock.ExpectQuery(`SELECT (.+) FROM peridot.repos WHERE subproject_id = \$1 AND name = \$2`).
+			WithArgs(2, "kubernetes").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "subproject_id", "name", "address", "created_at", "updated_at"}))
+	}
+
+	// run the tested function
+	_, _, err = db.GetOrCreateRepo(2, "kubernetes", "https://github.com/kubernetes/kubernetes")
+	ree, ok := err.(*RetriesExhaustedError)
+	if !ok {
+		t.Fatalf("expected *RetriesExhaustedError, got %v", err)
+	}
+	if ree.Entity != "repo" || ree.Name != "kubernetes" || ree.Attempts != getOrCreateAttempts {
+		t.Errorf("got unexpected error %+v", ree)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+// OrgID identifies an Organization.
+type OrgID uint32
+
+// ProjectID identifies a Project.
+type ProjectID uint32
+
+// RepoID identifies a Repo.
+type RepoID uint32
+
+// RepoPullID identifies a RepoPull.
+type RepoPullID uint32
+
+// JobID identifies a Job.
+type JobID uint32
+
+// AgentID identifies an Agent.
+type AgentID uint32
+
+// UserID identifies a User.
+type UserID uint32
+
+// Each ID type converts to and from uint32 with an ordinary type
+// conversion, e.g. JobID(17) or uint32(id). The helpers below cover
+// the cases a conversion can't, for callers still using uint32.
+
+// JobIDsFromUint32s converts a slice of uint32 to a slice of JobIDs.
+// A nil slice is returned as nil.
+func JobIDsFromUint32s(ids []uint32) []JobID {
+	if ids == nil {
+		return nil
+	}
+	jobIDs := make([]JobID, len(ids))
+	for i, id := range ids {
+		jobIDs[i] = JobID(id)
+	}
+	return jobIDs
+}
+
+// Uint32sFromJobIDs converts a slice of JobIDs to a slice of uint32.
+// A nil slice is returned as nil.
+func Uint32sFromJobIDs(ids []JobID) []uint32 {
+	if ids == nil {
+		return nil
+	}
+	u := make([]uint32, len(ids))
+	for i, id := range ids {
+		u[i] = uint32(id)
+	}
+	return u
+}
@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCanConvertJobIDSlices(t *testing.T) {
+	ids := []uint32{4, 17, 3}
+	jobIDs := JobIDsFromUint32s(ids)
+	if !reflect.DeepEqual(jobIDs, []JobID{4, 17, 3}) {
+		t.Errorf("expected %v, got %v", ids, jobIDs)
+	}
+	if got := Uint32sFromJobIDs(jobIDs); !reflect.DeepEqual(got, ids) {
+		t.Errorf("expected %v, got %v", ids, got)
+	}
+
+	if JobIDsFromUint32s(nil) != nil {
+		t.Errorf("expected nil slice")
+	}
+	if Uint32sFromJobIDs(nil) != nil {
+		t.Errorf("expected nil slice")
+	}
+}
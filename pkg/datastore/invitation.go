@@ -0,0 +1,269 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Invitation describes an offer of access to the platform, made by
+// an existing user to someone identified by email address and/or
+// Github user name. Accepting the invitation creates the User with
+// the proposed access level. As with API tokens, only a hash of the
+// invitation token is stored.
+type Invitation struct {
+	// ID is the unique ID for this invitation.
+	ID uint32 `json:"id"`
+	// Email is the invitee's email address, or empty if not known.
+	Email string `json:"email"`
+	// Github is the invitee's Github user name, or empty if not known.
+	Github string `json:"github"`
+	// AccessLevel is the access level the invitee will receive.
+	AccessLevel UserAccessLevel `json:"access"`
+	// InviterID is the ID of the user who created the invitation.
+	InviterID UserID `json:"inviter_id"`
+	// CreatedAt is when this invitation was created.
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is when this invitation expires if not accepted.
+	ExpiresAt time.Time `json:"expires_at"`
+	// AcceptedAt is when this invitation was accepted. Should be
+	// zero value if it has not been accepted.
+	AcceptedAt time.Time `json:"accepted_at,omitempty"`
+	// AcceptedUserID is the ID of the user created on acceptance.
+	// Should be 0 if it has not been accepted.
+	AcceptedUserID UserID `json:"accepted_user_id"`
+}
+
+// MarshalJSON converts the Invitation into a slice of bytes containing
+// its JSON encoding, leaving out accepted_at while the invitation is
+// pending.
+func (inv Invitation) MarshalJSON() ([]byte, error) {
+	type invitationAlias Invitation
+	return json.Marshal(struct {
+		invitationAlias
+		AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	}{invitationAlias: invitationAlias(inv), AcceptedAt: jsonTimePtr(inv.AcceptedAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of an Invitation into the Invitation. A missing or null accepted_at
+// means the invitation has not been accepted.
+func (inv *Invitation) UnmarshalJSON(b []byte) error {
+	type invitationAlias Invitation
+	aux := struct {
+		*invitationAlias
+		AcceptedAt *time.Time `json:"accepted_at"`
+	}{invitationAlias: (*invitationAlias)(inv)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	inv.AcceptedAt = timeFromJSONPtr(aux.AcceptedAt)
+	return nil
+}
+
+// GetPendingInvitations returns a slice of all invitations that have
+// been neither accepted nor expired, ordered by ID.
+func (db *DB) GetPendingInvitations() ([]*Invitation, error) {
+	rows, err := db.sqldb.Query("SELECT id, email, github, access_level, inviter_id, created_at, expires_at, accepted_at, accepted_user_id FROM peridot.invitations WHERE accepted_at IS NULL AND expires_at > $1 ORDER BY id", now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanInvitationRows(rows)
+}
+
+// GetInvitationsForUser returns a slice of all invitations created
+// by, or accepted by, the User with the given ID, ordered by ID.
+func (db *DB) GetInvitationsForUser(userID UserID) ([]*Invitation, error) {
+	rows, err := db.sqldb.Query("SELECT id, email, github, access_level, inviter_id, created_at, expires_at, accepted_at, accepted_user_id FROM peridot.invitations WHERE inviter_id = $1 OR accepted_user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanInvitationRows(rows)
+}
+
+// scanInvitationRows collects Invitation values from rows selecting
+// all invitation columns.
+func scanInvitationRows(rows *sql.Rows) ([]*Invitation, error) {
+	invs := []*Invitation{}
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invs = append(invs, inv)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return invs, nil
+}
+
+// GetInvitationByID returns the Invitation with the given ID, or nil
+// and an error if not found.
+func (db *DB) GetInvitationByID(id uint32) (*Invitation, error) {
+	return scanInvitation(db.sqldb.QueryRow("SELECT id, email, github, access_level, inviter_id, created_at, expires_at, accepted_at, accepted_user_id FROM peridot.invitations WHERE id = $1", id))
+}
+
+// scanInvitation reads an Invitation from a row selecting its
+// columns in the order used by GetInvitationByID.
+func scanInvitation(rs rowScanner) (*Invitation, error) {
+	inv := &Invitation{}
+	var email, github sql.NullString
+	var acceptedAt pq.NullTime
+	var acceptedUserID sql.NullInt64
+	err := rs.Scan(&inv.ID, &email, &github, &inv.AccessLevel, &inv.InviterID, &inv.CreatedAt, &inv.ExpiresAt, &acceptedAt, &acceptedUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	inv.Email = email.String
+	inv.Github = github.String
+	inv.CreatedAt = normalizeTime(inv.CreatedAt)
+	inv.ExpiresAt = normalizeTime(inv.ExpiresAt)
+	inv.AcceptedAt = normalizeTime(acceptedAt.Time)
+	inv.AcceptedUserID = UserID(acceptedUserID.Int64)
+	return inv, nil
+}
+
+// CreateInvitation creates a new invitation from the user with ID
+// inviterID for the invitee with the given email address and/or
+// Github user name, proposing the given access level and expiring at
+// expiresAt. It returns the new invitation's ID and the invitation
+// token on success, or an error if failing. The token is not
+// retrievable again after this call returns.
+func (db *DB) CreateInvitation(inviterID UserID, email string, github string, accessLevel UserAccessLevel, expiresAt time.Time) (uint32, string, error) {
+	if email == "" && github == "" {
+		return 0, "", fmt.Errorf("invitation needs an email address or Github user name")
+	}
+	if err := validateEmail(email); err != nil {
+		return 0, "", err
+	}
+	if expiresAt.IsZero() {
+		return 0, "", fmt.Errorf("invitation needs an expiry time")
+	}
+
+	b := make([]byte, tokenByteLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return 0, "", err
+	}
+	token := hex.EncodeToString(b)
+
+	stmt, err := db.prepare("INSERT INTO peridot.invitations(email, github, access_level, inviter_id, token_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id")
+	if err != nil {
+		return 0, "", err
+	}
+
+	var invitationID uint32
+	err = stmt.QueryRow(nullStringFromString(email), nullStringFromString(github), IntFromUserAccessLevel(accessLevel), inviterID, hashToken(token), now(), normalizeTime(expiresAt)).Scan(&invitationID)
+	if err != nil {
+		return 0, "", err
+	}
+	return invitationID, token, nil
+}
+
+// AcceptInvitation accepts the pending invitation with the given
+// token, creating a new User with the given ID, name and Github user
+// name and with the invitation's email address and access level. If
+// github is empty, the invitation's Github user name is used. It
+// returns the new User on success, or nil and an error if failing:
+// a *NotFoundError if the invitation is unknown, expired or already
+// accepted, or a *ValidationError if the new User would be invalid.
+func (db *DB) AcceptInvitation(token string, userID UserID, name string, github string) (*User, error) {
+	if err := checkCallerUserID(userID); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+
+	// claim the invitation first, so that it can only be accepted once
+	var email, invGithub sql.NullString
+	var accessLevel UserAccessLevel
+	err = tx.QueryRow("UPDATE peridot.invitations SET accepted_at = $2, accepted_user_id = $3 WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > $2 RETURNING email, github, access_level", hashToken(token), now(), userID).
+		Scan(&email, &invGithub, &accessLevel)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return nil, &NotFoundError{Entity: "valid invitation", Key: "token hash", ID: hashToken(token)}
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if github == "" {
+		github = invGithub.String
+	}
+	if github == "" {
+		tx.Rollback()
+		return nil, &ValidationError{Entity: "user", Field: "github", Reason: "Github user name is required for human users"}
+	}
+
+	u := &User{ID: userID, Name: name, Github: github, Email: email.String, AccessLevel: accessLevel, Kind: UserKindHuman}
+	if err = u.Validate(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	_, err = tx.Exec("INSERT INTO peridot.users(id, github, name, email, access_level) VALUES ($1, $2, $3, $4, $5)", userID, github, name, email, IntFromUserAccessLevel(accessLevel))
+	if err != nil {
+		tx.Rollback()
+		return nil, translateConstraintError("user", err)
+	}
+
+	err = addOutboxEvent(tx, "user", u.ID, AuditActionAdd, u)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// ExpireInvitation expires the pending invitation with the given ID
+// immediately, so that it can no longer be accepted. It returns nil
+// on success or an error if failing.
+func (db *DB) ExpireInvitation(id uint32) error {
+	stmt, err := db.prepare("UPDATE peridot.invitations SET expires_at = $1 WHERE id = $2 AND accepted_at IS NULL AND expires_at > $1")
+	if err != nil {
+		return err
+	}
+	result, err := stmt.Exec(now(), id)
+
+	// check error
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "pending invitation", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
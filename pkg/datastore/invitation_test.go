@@ -0,0 +1,388 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestShouldGetPendingInvitations(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "email", "github", "access_level", "inviter_id", "created_at", "expires_at", "accepted_at", "accepted_user_id"}).
+		AddRow(1, "johndoe@example.com", nil, 20, 8103918, createdAt, expiresAt, nil, nil).
+		AddRow(3, nil, "janedoe", 10, 8103918, createdAt, expiresAt, nil, nil)
+	mock.ExpectQuery(`SELECT id, email, github, access_level, inviter_id, created_at, expires_at, accepted_at, accepted_user_id FROM peridot.invitations WHERE accepted_at IS NULL AND expires_at > \$1 ORDER BY id`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetPendingInvitations()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	inv0 := gotRows[0]
+	if inv0.Email != "johndoe@example.com" {
+		t.Errorf("expected %v, got %v", "johndoe@example.com", inv0.Email)
+	}
+	if inv0.Github != "" {
+		t.Errorf("expected empty github, got %v", inv0.Github)
+	}
+	if inv0.AccessLevel != AccessCommenter {
+		t.Errorf("expected %v, got %v", AccessCommenter, inv0.AccessLevel)
+	}
+	if inv0.ExpiresAt != expiresAt {
+		t.Errorf("expected %v, got %v", expiresAt, inv0.ExpiresAt)
+	}
+	if !inv0.AcceptedAt.IsZero() {
+		t.Errorf("expected zero time, got %v", inv0.AcceptedAt)
+	}
+	inv1 := gotRows[1]
+	if inv1.Github != "janedoe" {
+		t.Errorf("expected %v, got %v", "janedoe", inv1.Github)
+	}
+}
+
+func TestShouldCreateInvitation(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	regexStmt := `INSERT INTO peridot.invitations\(email, github, access_level, inviter_id, token_hash, created_at, expires_at\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery("INSERT INTO peridot.invitations").
+		WithArgs(sql.NullString{String: "johndoe@example.com", Valid: true}, sql.NullString{}, 20, 8103918, sqlmock.AnyArg(), sqlmock.AnyArg(), expiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	// run the tested function
+	invitationID, token, err := db.CreateInvitation(8103918, "johndoe@example.com", "", AccessCommenter, expiresAt)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// check returned values
+	if invitationID != 1 {
+		t.Errorf("expected %v, got %v", 1, invitationID)
+	}
+	if len(token) != tokenByteLength*2 {
+		t.Errorf("expected token of length %d, got %d", tokenByteLength*2, len(token))
+	}
+}
+
+func TestShouldNotCreateInvalidInvitation(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// no invitee
+	_, _, err = db.CreateInvitation(8103918, "", "", AccessCommenter, expiresAt)
+	if err == nil {
+		t.Errorf("expected non-nil error for missing invitee, got nil")
+	}
+	// bad email
+	_, _, err = db.CreateInvitation(8103918, "johndoe", "", AccessCommenter, expiresAt)
+	if err == nil {
+		t.Errorf("expected non-nil error for invalid email, got nil")
+	}
+	// no expiry
+	_, _, err = db.CreateInvitation(8103918, "johndoe@example.com", "", AccessCommenter, time.Time{})
+	if err == nil {
+		t.Errorf("expected non-nil error for missing expiry, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldAcceptInvitation(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	token := "0123456789abcdef"
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE peridot.invitations SET accepted_at = \$2, accepted_user_id = \$3 WHERE token_hash = \$1 AND accepted_at IS NULL AND expires_at > \$2 RETURNING email, github, access_level`).
+		WithArgs(tokenHashArg{token: &token}, sqlmock.AnyArg(), 192304).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "github", "access_level"}).AddRow("johndoe@example.com", nil, 20))
+	mock.ExpectExec(`INSERT INTO peridot.users\(id, github, name, email, access_level\) VALUES \(\$1, \$2, \$3, \$4, \$5\)`).
+		WithArgs(192304, "johndoe", "John Doe", sql.NullString{String: "johndoe@example.com", Valid: true}, 20).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("user", "192304", AuditActionAdd, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	user, err := db.AcceptInvitation(token, 192304, "John Doe", "johndoe")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if user.ID != 192304 {
+		t.Errorf("expected %v, got %v", 192304, user.ID)
+	}
+	if user.Email != "johndoe@example.com" {
+		t.Errorf("expected %v, got %v", "johndoe@example.com", user.Email)
+	}
+	if user.AccessLevel != AccessCommenter {
+		t.Errorf("expected %v, got %v", AccessCommenter, user.AccessLevel)
+	}
+}
+
+func TestShouldFailAcceptInvitationForUnknownOrUsedToken(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	token := "oops"
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE peridot.invitations SET accepted_at`).
+		WithArgs(tokenHashArg{token: &token}, sqlmock.AnyArg(), 192304).
+		WillReturnRows(sqlmock.NewRows([]string{}))
+	mock.ExpectRollback()
+
+	// run the tested function
+	user, err := db.AcceptInvitation(token, 192304, "John Doe", "johndoe")
+	if user != nil {
+		t.Fatalf("expected nil user, got %v", user)
+	}
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailAcceptInvitationWithInvalidEmail(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	token := "0123456789abcdef"
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE peridot.invitations SET accepted_at`).
+		WithArgs(tokenHashArg{token: &token}, sqlmock.AnyArg(), 192304).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "github", "access_level"}).AddRow("not an email", nil, 20))
+	mock.ExpectRollback()
+
+	// run the tested function
+	user, err := db.AcceptInvitation(token, 192304, "John Doe", "johndoe")
+	if user != nil {
+		t.Fatalf("expected nil user, got %v", user)
+	}
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailAcceptInvitationForExistingUser(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	token := "0123456789abcdef"
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE peridot.invitations SET accepted_at`).
+		WithArgs(tokenHashArg{token: &token}, sqlmock.AnyArg(), 192304).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "github", "access_level"}).AddRow("johndoe@example.com", nil, 20))
+	mock.ExpectExec(`INSERT INTO peridot.users`).
+		WillReturnError(&pq.Error{Code: "23505", Table: "users", Constraint: "users_pkey"})
+	mock.ExpectRollback()
+
+	// run the tested function
+	user, err := db.AcceptInvitation(token, 192304, "John Doe", "johndoe")
+	if user != nil {
+		t.Fatalf("expected nil user, got %v", user)
+	}
+	if _, ok := err.(*ConflictError); !ok {
+		t.Fatalf("expected *ConflictError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldExpireInvitation(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `UPDATE peridot.invitations SET expires_at = \$1 WHERE id = \$2 AND accepted_at IS NULL AND expires_at > \$1`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.ExpireInvitation(1)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailExpireInvitationIfNotPending(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `UPDATE peridot.invitations SET expires_at = \$1 WHERE id = \$2 AND accepted_at IS NULL AND expires_at > \$1`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(sqlmock.AnyArg(), 413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.ExpireInvitation(413)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestInvitationJSONLeavesOutAcceptanceTimeWhilePending(t *testing.T) {
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	inv := Invitation{ID: 1, Email: "johndoe@example.com", AccessLevel: AccessCommenter, InviterID: 8103918, CreatedAt: createdAt, ExpiresAt: createdAt.Add(7 * 24 * time.Hour)}
+
+	js, err := json.Marshal(inv)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+
+	var mGot map[string]interface{}
+	err = json.Unmarshal(js, &mGot)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if _, ok := mGot["accepted_at"]; ok {
+		t.Errorf("expected no accepted_at key, got %v", mGot["accepted_at"])
+	}
+	if mGot["expires_at"] != "2019-05-09T13:53:41Z" {
+		t.Errorf("expected %v, got %v", "2019-05-09T13:53:41Z", mGot["expires_at"])
+	}
+}
+
+func TestInvitationJSONRoundTripsAcceptedInvitation(t *testing.T) {
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	acceptedAt := time.Date(2019, 5, 3, 9, 12, 0, 0, time.UTC)
+	inv := Invitation{ID: 1, Github: "johndoe", AccessLevel: AccessCommenter, InviterID: 8103918, CreatedAt: createdAt, ExpiresAt: createdAt.Add(7 * 24 * time.Hour), AcceptedAt: acceptedAt, AcceptedUserID: 192304}
+
+	js, err := json.Marshal(inv)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+
+	inv2 := &Invitation{}
+	err = json.Unmarshal(js, inv2)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if !inv2.AcceptedAt.Equal(acceptedAt) {
+		t.Errorf("expected %v, got %v", acceptedAt, inv2.AcceptedAt)
+	}
+	if !inv2.CreatedAt.Equal(createdAt) {
+		t.Errorf("expected %v, got %v", createdAt, inv2.CreatedAt)
+	}
+	if inv2.AcceptedUserID != 192304 {
+		t.Errorf("expected %v, got %v", 192304, inv2.AcceptedUserID)
+	}
+}
@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Entity types that can be linked to an external issue.
+const (
+	// IssueLinkEntityRepoPull means the link is for a RepoPull.
+	IssueLinkEntityRepoPull = "repopull"
+	// IssueLinkEntityFinding means the link is for a Finding.
+	IssueLinkEntityFinding = "finding"
+	// IssueLinkEntityPolicyResult means the link is for the
+	// violations in a PolicyResult.
+	IssueLinkEntityPolicyResult = "policyresult"
+)
+
+// IssueLink describes a reference from an entity in peridot to an
+// issue in an external tracker, such as a JIRA ticket or GitHub
+// issue, for tracking remediation of a problem.
+type IssueLink struct {
+	// ID is the unique ID for this link.
+	ID uint32 `json:"id"`
+	// Entity is the kind of entity linked, e.g.
+	// IssueLinkEntityFinding.
+	Entity string `json:"entity"`
+	// EntityID identifies the linked entity within its kind, as in
+	// AuditEntry.
+	EntityID string `json:"entity_id"`
+	// URL is the http or https URL of the external issue.
+	URL string `json:"url"`
+	// State is the issue's state in the external tracker as of the
+	// last sync, e.g. "open" or "closed", or "" if it has never
+	// been synced.
+	State string `json:"state,omitempty"`
+	// CreatedAt is when the link was added.
+	CreatedAt time.Time `json:"created_at"`
+	// LastSyncedAt is when State was last fetched from the
+	// tracker. Should be zero value if it has never been synced.
+	LastSyncedAt time.Time `json:"last_synced_at,omitempty"`
+}
+
+// MarshalJSON converts the IssueLink into a slice of bytes containing
+// its JSON encoding, leaving out last_synced_at until the link has
+// been synced.
+func (il IssueLink) MarshalJSON() ([]byte, error) {
+	type issueLinkAlias IssueLink
+	return json.Marshal(struct {
+		issueLinkAlias
+		LastSyncedAt *time.Time `json:"last_synced_at,omitempty"`
+	}{issueLinkAlias: issueLinkAlias(il), LastSyncedAt: jsonTimePtr(il.LastSyncedAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of an IssueLink into the IssueLink. A missing or null
+// last_synced_at means the link has never been synced.
+func (il *IssueLink) UnmarshalJSON(b []byte) error {
+	type issueLinkAlias IssueLink
+	aux := struct {
+		*issueLinkAlias
+		LastSyncedAt *time.Time `json:"last_synced_at"`
+	}{issueLinkAlias: (*issueLinkAlias)(il)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	il.LastSyncedAt = timeFromJSONPtr(aux.LastSyncedAt)
+	return nil
+}
+
+// Validate checks that the IssueLink's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (il *IssueLink) Validate() error {
+	switch il.Entity {
+	case IssueLinkEntityRepoPull, IssueLinkEntityFinding, IssueLinkEntityPolicyResult:
+	default:
+		return &ValidationError{Entity: "issue link", Field: "entity", Reason: fmt.Sprintf("unknown entity type %q", il.Entity)}
+	}
+	if err := requireNonEmpty("issue link", "entity_id", il.EntityID); err != nil {
+		return err
+	}
+	u, err := url.Parse(il.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &ValidationError{Entity: "issue link", Field: "url", Reason: "must be an absolute http or https URL"}
+	}
+	return nil
+}
+
+const issueLinkColumns = "id, entity, entity_id, url, state, created_at, last_synced_at"
+
+// scanIssueLink reads an IssueLink from a row selecting
+// issueLinkColumns.
+func scanIssueLink(rs rowScanner) (*IssueLink, error) {
+	il := &IssueLink{}
+	var lastSyncedAt pq.NullTime
+	err := rs.Scan(&il.ID, &il.Entity, &il.EntityID, &il.URL, &il.State, &il.CreatedAt, &lastSyncedAt)
+	if err != nil {
+		return nil, err
+	}
+	il.CreatedAt = normalizeTime(il.CreatedAt)
+	if lastSyncedAt.Valid {
+		il.LastSyncedAt = normalizeTime(lastSyncedAt.Time)
+	}
+	return il, nil
+}
+
+// queryIssueLinks runs a query selecting issueLinkColumns and
+// returns the resulting links.
+func (db *DB) queryIssueLinks(query string, args ...interface{}) ([]*IssueLink, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*IssueLink{}
+	for rows.Next() {
+		il, err := scanIssueLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, il)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// GetIssueLinksForEntity returns a slice of all issue links for the
+// entity of the given kind and ID, ordered by ID.
+func (db *DB) GetIssueLinksForEntity(entity string, entityID string) ([]*IssueLink, error) {
+	return db.queryIssueLinks("SELECT "+issueLinkColumns+" FROM peridot.issue_links WHERE entity = $1 AND entity_id = $2 ORDER BY id", entity, entityID)
+}
+
+// GetIssueLinksSyncedBefore returns a slice of up to limit issue
+// links that have not been synced since the given time, including
+// those never synced, ordered from least to most recently synced.
+func (db *DB) GetIssueLinksSyncedBefore(before time.Time, limit uint32) ([]*IssueLink, error) {
+	return db.queryIssueLinks("SELECT "+issueLinkColumns+" FROM peridot.issue_links WHERE last_synced_at IS NULL OR last_synced_at < $1 ORDER BY last_synced_at NULLS FIRST, id LIMIT $2", normalizeTime(before), limit)
+}
+
+// GetIssueLinkByID returns the IssueLink with the given ID, or nil
+// and an error if not found.
+func (db *DB) GetIssueLinkByID(id uint32) (*IssueLink, error) {
+	il, err := scanIssueLink(db.sqldb.QueryRow("SELECT "+issueLinkColumns+" FROM peridot.issue_links WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "issue link", ID: fmt.Sprint(id)}
+	}
+	return il, err
+}
+
+// AddIssueLink links the entity of the given kind and ID to the
+// external issue at issueURL. An entity can be linked to a given
+// issue only once. It returns the new link's ID on success or an
+// error if failing.
+func (db *DB) AddIssueLink(entity string, entityID string, issueURL string) (uint32, error) {
+	il := &IssueLink{Entity: entity, EntityID: entityID, URL: issueURL}
+	if err := il.Validate(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.issue_links(entity, entity_id, url, state, created_at) VALUES ($1, $2, $3, '', $4) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var linkID uint32
+	err = stmt.QueryRow(entity, entityID, issueURL, now()).Scan(&linkID)
+	if err != nil {
+		return 0, err
+	}
+	return linkID, nil
+}
+
+// UpdateIssueLinkState records the state of the external issue for
+// the IssueLink with the given ID, as just fetched from its tracker.
+// It returns nil on success or an error if failing.
+func (db *DB) UpdateIssueLinkState(id uint32, state string) error {
+	result, err := db.sqldb.Exec("UPDATE peridot.issue_links SET state = $1, last_synced_at = $2 WHERE id = $3", state, now(), id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "issue link", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// DeleteIssueLink deletes the IssueLink with the given ID. It
+// returns nil on success or an error if failing.
+func (db *DB) DeleteIssueLink(id uint32) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.issue_links WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "issue link", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetIssueLinksForEntity(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	syncedAt := time.Date(2019, 5, 3, 9, 12, 5, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "entity", "entity_id", "url", "state", "created_at", "last_synced_at"}).
+		AddRow(1, "finding", "3012", "https://github.com/example/repo/issues/12", "open", createdAt, syncedAt).
+		AddRow(2, "finding", "3012", "https://jira.example.com/browse/OSS-4", "", createdAt, nil)
+	mock.ExpectQuery(`SELECT id, entity, entity_id, url, state, created_at, last_synced_at FROM peridot.issue_links WHERE entity = \$1 AND entity_id = \$2 ORDER BY id`).
+		WithArgs("finding", "3012").
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetIssueLinksForEntity(IssueLinkEntityFinding, "3012")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantRows := []*IssueLink{
+		{ID: 1, Entity: IssueLinkEntityFinding, EntityID: "3012", URL: "https://github.com/example/repo/issues/12", State: "open", CreatedAt: createdAt, LastSyncedAt: syncedAt},
+		{ID: 2, Entity: IssueLinkEntityFinding, EntityID: "3012", URL: "https://jira.example.com/browse/OSS-4", CreatedAt: createdAt},
+	}
+	if !reflect.DeepEqual(wantRows, gotRows) {
+		t.Errorf("expected %#v, got %#v", wantRows, gotRows)
+	}
+}
+
+func TestShouldGetIssueLinksSyncedBefore(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	before := time.Date(2019, 5, 3, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM peridot.issue_links WHERE last_synced_at IS NULL OR last_synced_at < \$1 ORDER BY last_synced_at NULLS FIRST, id LIMIT \$2`).
+		WithArgs(before, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "entity", "entity_id", "url", "state", "created_at", "last_synced_at"}))
+
+	// run the tested function
+	gotRows, err := db.GetIssueLinksSyncedBefore(before, 50)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if len(gotRows) != 0 {
+		t.Errorf("expected no links, got %#v", gotRows)
+	}
+}
+
+func TestShouldAddIssueLink(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.issue_links\(entity, entity_id, url, state, created_at\) VALUES \(\$1, \$2, \$3, '', \$4\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs("repopull", "36", "https://github.com/example/repo/issues/13", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	// run the tested function
+	id, err := db.AddIssueLink(IssueLinkEntityRepoPull, "36", "https://github.com/example/repo/issues/13")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 3 {
+		t.Errorf("expected %v, got %v", 3, id)
+	}
+}
+
+func TestShouldFailAddInvalidIssueLink(t *testing.T) {
+	tests := []struct {
+		name      string
+		entity    string
+		entityID  string
+		issueURL  string
+		wantField string
+	}{
+		{"unknown entity", "job", "4", "https://github.com/example/repo/issues/13", "entity"},
+		{"no entity ID", IssueLinkEntityRepoPull, "", "https://github.com/example/repo/issues/13", "entity_id"},
+		{"relative URL", IssueLinkEntityRepoPull, "36", "/issues/13", "url"},
+	}
+	for _, tc := range tests {
+		_, err := (&DB{}).AddIssueLink(tc.entity, tc.entityID, tc.issueURL)
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%s: expected *ValidationError, got %v", tc.name, err)
+			continue
+		}
+		if verr.Field != tc.wantField {
+			t.Errorf("%s: expected field %v, got %v", tc.name, tc.wantField, verr.Field)
+		}
+	}
+}
+
+func TestShouldUpdateIssueLinkState(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`UPDATE peridot.issue_links SET state = \$1, last_synced_at = \$2 WHERE id = \$3`).
+		WithArgs("closed", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.UpdateIssueLinkState(1, "closed")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
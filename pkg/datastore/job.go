@@ -4,8 +4,11 @@ package datastore
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/lib/pq"
@@ -18,26 +21,26 @@ type Job struct {
 	// ===== identity variables =====
 
 	// ID is the unique ID for this job.
-	ID uint32 `json:"id"`
+	ID JobID `json:"id"`
 	// RepoPullID is the unique ID for the repo pull this job
 	// relates to.
-	RepoPullID uint32 `json:"repopull_id"`
+	RepoPullID RepoPullID `json:"repopull_id"`
 	// AgentID is the ID of the agent that will run this job.
-	AgentID uint32 `json:"agent_id"`
+	AgentID AgentID `json:"agent_id"`
 	// PriorJobIDs is a slice of IDs for jobs that must finish
 	// without erroring before this job can be run.
-	PriorJobIDs []uint32 `json:"priorjob_ids,omitempty"`
+	PriorJobIDs []JobID `json:"priorjob_ids,omitempty"`
 
 	// ===== status variables =====
 
 	// StartedAt is when peridot asked an Agent to start
 	// running this job. Should be zero value if job has not
 	// yet been started.
-	StartedAt time.Time `json:"started_at"`
+	StartedAt time.Time `json:"started_at,omitempty"`
 	// FinishedAt is when the Agent finished this job. Should
 	// be zero value if code pull has not yet been completed
 	// (or will not complete due to error).
-	FinishedAt time.Time `json:"finished_at"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
 	// Status is the run status of the job.
 	Status Status `json:"status"`
 	// Health is the health of the job.
@@ -57,6 +60,59 @@ type Job struct {
 
 	// Config is the collection of configurations for this job.
 	Config JobConfig `json:"config,omitempty"`
+
+	// Version starts at 1 and is incremented each time the Job is
+	// updated. It is passed to UpdateJobStatus to detect updates
+	// made since the Job was read.
+	Version uint32 `json:"version,omitempty"`
+
+	// ===== bookkeeping variables =====
+
+	// CreatedAt is when this job was added. It is set by the
+	// database.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// UpdatedAt is when this job was last changed. It is set by
+	// the database.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// MarshalJSON converts the Job into a slice of bytes containing its
+// JSON encoding, leaving out started_at and finished_at until the job
+// has started and finished, and created_at and updated_at if unset.
+func (j Job) MarshalJSON() ([]byte, error) {
+	type jobAlias Job
+	return json.Marshal(struct {
+		jobAlias
+		StartedAt  *time.Time `json:"started_at,omitempty"`
+		FinishedAt *time.Time `json:"finished_at,omitempty"`
+		CreatedAt  *time.Time `json:"created_at,omitempty"`
+		UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	}{jobAlias: jobAlias(j), StartedAt: jsonTimePtr(j.StartedAt), FinishedAt: jsonTimePtr(j.FinishedAt), CreatedAt: jsonTimePtr(j.CreatedAt), UpdatedAt: jsonTimePtr(j.UpdatedAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of a Job into the Job, leaving any of its four timestamps that are
+// missing or null as the zero time.
+func (j *Job) UnmarshalJSON(b []byte) error {
+	type jobAlias Job
+	aux := struct {
+		*jobAlias
+		StartedAt  *time.Time `json:"started_at"`
+		FinishedAt *time.Time `json:"finished_at"`
+		CreatedAt  *time.Time `json:"created_at"`
+		UpdatedAt  *time.Time `json:"updated_at"`
+	}{jobAlias: (*jobAlias)(j)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	j.StartedAt = timeFromJSONPtr(aux.StartedAt)
+	j.FinishedAt = timeFromJSONPtr(aux.FinishedAt)
+	j.CreatedAt = timeFromJSONPtr(aux.CreatedAt)
+	j.UpdatedAt = timeFromJSONPtr(aux.UpdatedAt)
+	return nil
 }
 
 // JobConfig contains the three available types of configurations
@@ -86,39 +142,187 @@ type JobPathConfig struct {
 	// passed along to the agent as part of the input path.
 	// If PriorJobID is 0, then the Value will be passed along
 	// instead.
-	PriorJobID uint32 `json:"priorjob_id,omitempty"`
+	PriorJobID JobID `json:"priorjob_id,omitempty"`
+}
+
+// Validate checks that exactly one of the JobPathConfig's Value and
+// PriorJobID is set. It returns nil if so, or a *ValidationError if
+// not.
+func (pc *JobPathConfig) Validate() error {
+	if pc.Value != "" && pc.PriorJobID != 0 {
+		return &ValidationError{Entity: "job path config", Field: "path", Reason: "cannot set both a path and a prior job ID"}
+	}
+	if pc.Value == "" && pc.PriorJobID == 0 {
+		return &ValidationError{Entity: "job path config", Field: "path", Reason: "must set either a path or a prior job ID"}
+	}
+	return nil
+}
+
+// Validate checks that the Job's status and health are defined
+// values and that each of its path configs is valid. It returns nil
+// if so, or a *ValidationError describing the first invalid field.
+func (j *Job) Validate() error {
+	if err := validateStatusHealth("job", j.Status, j.Health); err != nil {
+		return err
+	}
+	for _, pcs := range []map[string]JobPathConfig{j.Config.CodeReader, j.Config.SpdxReader} {
+		for _, pc := range pcs {
+			if err := pc.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Clone returns a deep copy of the Job, including its PriorJobIDs and
+// Config maps, so that the copy can be modified without affecting
+// the original. It returns nil if j is nil.
+func (j *Job) Clone() *Job {
+	if j == nil {
+		return nil
+	}
+
+	c := *j
+	if j.PriorJobIDs != nil {
+		c.PriorJobIDs = append([]JobID{}, j.PriorJobIDs...)
+	}
+	c.Config = j.Config.Clone()
+	return &c
+}
+
+// Clone returns a deep copy of the JobConfig's maps. Nil maps remain
+// nil in the copy.
+func (jc JobConfig) Clone() JobConfig {
+	c := JobConfig{}
+	if jc.KV != nil {
+		c.KV = make(map[string]string, len(jc.KV))
+		for k, v := range jc.KV {
+			c.KV[k] = v
+		}
+	}
+	c.CodeReader = clonePathConfigs(jc.CodeReader)
+	c.SpdxReader = clonePathConfigs(jc.SpdxReader)
+	return c
+}
+
+// clonePathConfigs returns a copy of pcs, or nil if pcs is nil.
+func clonePathConfigs(pcs map[string]JobPathConfig) map[string]JobPathConfig {
+	if pcs == nil {
+		return nil
+	}
+	c := make(map[string]JobPathConfig, len(pcs))
+	for k, pc := range pcs {
+		c[k] = pc
+	}
+	return c
+}
+
+// Equal reports whether j and other describe the same Job. Prior job
+// IDs are compared regardless of order, times are compared at
+// microsecond precision, and nil and empty config maps are treated
+// as equal. Two nil Jobs are equal.
+func (j *Job) Equal(other *Job) bool {
+	if j == nil || other == nil {
+		return j == other
+	}
+	return j.ID == other.ID &&
+		j.RepoPullID == other.RepoPullID &&
+		j.AgentID == other.AgentID &&
+		equalIDSets(j.PriorJobIDs, other.PriorJobIDs) &&
+		equalTimes(j.StartedAt, other.StartedAt) &&
+		equalTimes(j.FinishedAt, other.FinishedAt) &&
+		j.Status == other.Status &&
+		j.Health == other.Health &&
+		j.Output == other.Output &&
+		j.IsReady == other.IsReady &&
+		j.Config.Equal(other.Config) &&
+		j.Version == other.Version &&
+		equalTimes(j.CreatedAt, other.CreatedAt) &&
+		equalTimes(j.UpdatedAt, other.UpdatedAt)
+}
+
+// Equal reports whether jc and other contain the same configuration
+// values. Nil and empty maps are treated as equal.
+func (jc JobConfig) Equal(other JobConfig) bool {
+	if len(jc.KV) != len(other.KV) {
+		return false
+	}
+	for k, v := range jc.KV {
+		if ov, ok := other.KV[k]; !ok || ov != v {
+			return false
+		}
+	}
+	return equalPathConfigs(jc.CodeReader, other.CodeReader) &&
+		equalPathConfigs(jc.SpdxReader, other.SpdxReader)
+}
+
+// equalPathConfigs reports whether a and b contain the same path
+// configs.
+func equalPathConfigs(a map[string]JobPathConfig, b map[string]JobPathConfig) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, pc := range a {
+		if opc, ok := b[k]; !ok || opc != pc {
+			return false
+		}
+	}
+	return true
+}
+
+const jobColumns = "id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready, version, created_at, updated_at"
+
+// scanJob reads a Job from a row selecting jobColumns followed by
+// any extra columns, which are scanned into extra. The job's prior
+// job IDs and configs are left empty for the caller to fill in. A
+// NULL output, as may be written by tools other than peridot, is
+// read as "".
+func scanJob(rs rowScanner, extra ...interface{}) (*Job, error) {
+	j := &Job{}
+	var output sql.NullString
+	dest := append([]interface{}{&j.ID, &j.RepoPullID, &j.AgentID, &j.StartedAt, &j.FinishedAt, &j.Status, &j.Health, &output, &j.IsReady, &j.Version, &j.CreatedAt, &j.UpdatedAt}, extra...)
+	err := rs.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	j.StartedAt = normalizeTime(j.StartedAt)
+	j.FinishedAt = normalizeTime(j.FinishedAt)
+	j.CreatedAt = normalizeTime(j.CreatedAt)
+	j.UpdatedAt = normalizeTime(j.UpdatedAt)
+	j.Output = output.String
+
+	// create slices for bits that'll (possibly) get filled in later
+	j.PriorJobIDs = []JobID{}
+	j.Config.KV = map[string]string{}
+	j.Config.CodeReader = map[string]JobPathConfig{}
+	j.Config.SpdxReader = map[string]JobPathConfig{}
+	return j, nil
 }
 
 // GetAllJobsForRepoPull returns a slice of all jobs
 // in the database for the given RepoPull ID.
-func (db *DB) GetAllJobsForRepoPull(rpID uint32) ([]*Job, error) {
+func (db *DB) GetAllJobsForRepoPull(rpID RepoPullID) ([]*Job, error) {
 	// note that we can't rely on a SQL query to order by id, because
 	// we're storing jobs in a map (so we can added in config etc. details)
 	// and we're converting it to a slice further below.
-	jobRows, err := db.sqldb.Query("SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready FROM peridot.jobs WHERE repopull_id = $1", rpID)
+	jobRows, err := db.sqldb.Query("SELECT "+jobColumns+" FROM peridot.jobs WHERE repopull_id = $1 AND repopull_id IN ("+liveRepoPullIDs+")", rpID)
 	if err != nil {
 		return nil, err
 	}
 	defer jobRows.Close()
 
 	// collect jobs as a map for now, so we can find and add data based on ID
-	js := map[uint32]*Job{}
+	js := map[JobID]*Job{}
 	// also collect job IDs as we go so we'll have them for the next queries
-	jobIDs := []uint32{}
+	jobIDs := []JobID{}
 
 	for jobRows.Next() {
-		j := &Job{}
-		err := jobRows.Scan(&j.ID, &j.RepoPullID, &j.AgentID, &j.StartedAt, &j.FinishedAt, &j.Status, &j.Health, &j.Output, &j.IsReady)
+		j, err := scanJob(jobRows)
 		if err != nil {
 			return nil, err
 		}
 
-		// create slices for bits that'll (possibly) get filled in below
-		j.PriorJobIDs = []uint32{}
-		j.Config.KV = map[string]string{}
-		j.Config.CodeReader = map[string]JobPathConfig{}
-		j.Config.SpdxReader = map[string]JobPathConfig{}
-
 		js[j.ID] = j
 		jobIDs = append(jobIDs, j.ID)
 	}
@@ -126,68 +330,33 @@ func (db *DB) GetAllJobsForRepoPull(rpID uint32) ([]*Job, error) {
 		return nil, err
 	}
 
-	// next, query job configs and fill in those details
-	jpcRows, err := db.sqldb.Query("SELECT job_id, type, key, value, priorjob_id FROM peridot.jobpathconfigs WHERE job_id = ANY ($1)", pq.Array(jobIDs))
-	if err != nil {
-		return nil, err
+	// next, fill in the job configs and prior job IDs. the two queries
+	// are independent and fill in different fields, so run them at the
+	// same time rather than paying for two round trips in a row
+	var configErr, priorErr error
+	if db.inTx() {
+		// a transaction's statements share one connection, so they
+		// have to take turns
+		configErr = db.fillJobConfigs(js, jobIDs)
+		priorErr = db.fillJobPriorIDs(js, jobIDs)
+	} else {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			configErr = db.fillJobConfigs(js, jobIDs)
+		}()
+		go func() {
+			defer wg.Done()
+			priorErr = db.fillJobPriorIDs(js, jobIDs)
+		}()
+		wg.Wait()
 	}
-	defer jpcRows.Close()
-
-	for jpcRows.Next() {
-		var jid uint32
-		var typeInt int
-		var key, value string
-		var pjidNullable sql.NullInt64
-		err := jpcRows.Scan(&jid, &typeInt, &key, &value, &pjidNullable)
-		if err != nil {
-			return nil, err
-		}
-
-		var pjid uint32
-		if pjidNullable.Valid {
-			pjid = uint32(pjidNullable.Int64)
-		} else {
-			pjid = 0
-		}
-
-		// update the applicable job depending on ID and type
-		jcType, err := JobConfigTypeFromInt(typeInt)
-		if err != nil {
-			return nil, err
-		}
-		switch jcType {
-		case JobConfigKV:
-			js[jid].Config.KV[key] = value
-		case JobConfigCodeReader:
-			if pjid > 0 {
-				js[jid].Config.CodeReader[key] = JobPathConfig{PriorJobID: pjid}
-			} else {
-				js[jid].Config.CodeReader[key] = JobPathConfig{Value: value}
-			}
-		case JobConfigSpdxReader:
-			if pjid > 0 {
-				js[jid].Config.SpdxReader[key] = JobPathConfig{PriorJobID: pjid}
-			} else {
-				js[jid].Config.SpdxReader[key] = JobPathConfig{Value: value}
-			}
-		}
-	}
-
-	// and then query the prior jobs IDs table to get that data too
-	priorRows, err := db.sqldb.Query("SELECT job_id, priorjob_id FROM peridot.jobpriorids WHERE job_id = ANY ($1)", pq.Array(jobIDs))
-	if err != nil {
-		return nil, err
+	if configErr != nil {
+		return nil, configErr
 	}
-	defer priorRows.Close()
-
-	for priorRows.Next() {
-		var jid, pjid uint32
-		err := priorRows.Scan(&jid, &pjid)
-		if err != nil {
-			return nil, err
-		}
-
-		js[jid].PriorJobIDs = append(js[jid].PriorJobIDs, pjid)
+	if priorErr != nil {
+		return nil, priorErr
 	}
 
 	// all data is now filled in. now we need to convert the jobs map
@@ -202,65 +371,98 @@ func (db *DB) GetAllJobsForRepoPull(rpID uint32) ([]*Job, error) {
 	return jsSlice, nil
 }
 
-// GetJobsByIDs returns all of the jobs in the database with the given
-// IDs. If any ID is not present, it will be silently omitted (e.g.,
-// no error will be returned); the caller should check to confirm the
-// received jobs match those that were expected.
-func (db *DB) GetJobsByIDs(ids []uint32) ([]*Job, error) {
-	// note that we can't rely on a SQL query to order by id, because
-	// we're storing jobs in a map (so we can added in config etc. details)
-	// and we're converting it to a slice further below.
-	jobRows, err := db.sqldb.Query("SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready FROM peridot.jobs WHERE id = ANY ($1)", pq.Array(ids))
+// jobSortColumns lists the fields by which GetJobs can sort.
+var jobSortColumns = sortColumns{
+	"agent_id":    "agent_id",
+	"started_at":  "started_at",
+	"finished_at": "finished_at",
+	"status":      "status",
+	"health":      "health",
+}
+
+// GetJobs returns a slice of the jobs for the RepoPull with the given
+// ID, sorted and paged as requested, along with a Page describing the
+// results. Jobs can be sorted by id, agent_id, started_at,
+// finished_at, status or health.
+func (db *DB) GetJobs(rpID RepoPullID, pr PageRequest) ([]*Job, Page, error) {
+	clause, args, err := pr.sqlClause(jobSortColumns, []interface{}{rpID})
 	if err != nil {
-		return nil, err
+		return nil, Page{}, err
 	}
-	defer jobRows.Close()
 
-	// collect jobs as a map for now, so we can find and add data based on ID
-	js := map[uint32]*Job{}
-	// also collect job IDs as we go so we'll have them for the next queries
-	jobIDs := []uint32{}
+	// find the page's job IDs first, then fetch the jobs with their
+	// configs and prior job IDs
+	rows, err := db.sqldb.Query("SELECT id FROM peridot.jobs WHERE repopull_id = $1 AND repopull_id IN ("+liveRepoPullIDs+")"+clause, args...)
+	if err != nil {
+		return nil, Page{}, err
+	}
+	defer rows.Close()
 
-	for jobRows.Next() {
-		j := &Job{}
-		err := jobRows.Scan(&j.ID, &j.RepoPullID, &j.AgentID, &j.StartedAt, &j.FinishedAt, &j.Status, &j.Health, &j.Output, &j.IsReady)
-		if err != nil {
-			return nil, err
+	ids := []JobID{}
+	for rows.Next() {
+		var id JobID
+		if err := rows.Scan(&id); err != nil {
+			return nil, Page{}, err
 		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Page{}, err
+	}
 
-		// create slices for bits that'll (possibly) get filled in below
-		j.PriorJobIDs = []uint32{}
-		j.Config.KV = map[string]string{}
-		j.Config.CodeReader = map[string]JobPathConfig{}
-		j.Config.SpdxReader = map[string]JobPathConfig{}
-
-		js[j.ID] = j
-		jobIDs = append(jobIDs, j.ID)
+	page, n := pr.page(len(ids))
+	js, err := db.getJobsInOrder(ids[:n])
+	if err != nil {
+		return nil, Page{}, err
 	}
-	if err = jobRows.Err(); err != nil {
+	return js, page, nil
+}
+
+// getJobsInOrder returns the jobs with the given IDs as GetJobsByIDs
+// does, but in the order of ids rather than ordered by ID.
+func (db *DB) getJobsInOrder(ids []JobID) ([]*Job, error) {
+	byID, err := db.GetJobsByIDs(ids)
+	if err != nil {
 		return nil, err
 	}
+	jobs := map[JobID]*Job{}
+	for _, j := range byID {
+		jobs[j.ID] = j
+	}
+	js := []*Job{}
+	for _, id := range ids {
+		if j, ok := jobs[id]; ok {
+			js = append(js, j)
+		}
+	}
+	return js, nil
+}
 
-	// next, query job configs and fill in those details
+// fillJobConfigs queries the path configs for the jobs with the
+// given IDs and fills them in to the corresponding jobs in js. It
+// only touches each job's Config, so it is safe to run alongside
+// fillJobPriorIDs.
+func (db *DB) fillJobConfigs(js map[JobID]*Job, jobIDs []JobID) error {
 	jpcRows, err := db.sqldb.Query("SELECT job_id, type, key, value, priorjob_id FROM peridot.jobpathconfigs WHERE job_id = ANY ($1)", pq.Array(jobIDs))
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer jpcRows.Close()
 
 	for jpcRows.Next() {
-		var jid uint32
+		var jid JobID
 		var typeInt int
-		var key, value string
+		var keyNullable, valueNullable sql.NullString
 		var pjidNullable sql.NullInt64
-		err := jpcRows.Scan(&jid, &typeInt, &key, &value, &pjidNullable)
+		err := jpcRows.Scan(&jid, &typeInt, &keyNullable, &valueNullable, &pjidNullable)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		key, value := keyNullable.String, valueNullable.String
 
-		var pjid uint32
+		var pjid JobID
 		if pjidNullable.Valid {
-			pjid = uint32(pjidNullable.Int64)
+			pjid = JobID(pjidNullable.Int64)
 		} else {
 			pjid = 0
 		}
@@ -268,7 +470,7 @@ func (db *DB) GetJobsByIDs(ids []uint32) ([]*Job, error) {
 		// update the applicable job depending on ID and type
 		jcType, err := JobConfigTypeFromInt(typeInt)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		switch jcType {
 		case JobConfigKV:
@@ -287,54 +489,132 @@ func (db *DB) GetJobsByIDs(ids []uint32) ([]*Job, error) {
 			}
 		}
 	}
+	return jpcRows.Err()
+}
 
-	// and then query the prior jobs IDs table to get that data too
+// fillJobPriorIDs queries the prior job IDs for the jobs with the
+// given IDs and fills them in to the corresponding jobs in js. It
+// only touches each job's PriorJobIDs, so it is safe to run alongside
+// fillJobConfigs.
+func (db *DB) fillJobPriorIDs(js map[JobID]*Job, jobIDs []JobID) error {
 	priorRows, err := db.sqldb.Query("SELECT job_id, priorjob_id FROM peridot.jobpriorids WHERE job_id = ANY ($1)", pq.Array(jobIDs))
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer priorRows.Close()
 
 	for priorRows.Next() {
-		var jid, pjid uint32
+		var jid, pjid JobID
 		err := priorRows.Scan(&jid, &pjid)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		js[jid].PriorJobIDs = append(js[jid].PriorJobIDs, pjid)
 	}
+	return priorRows.Err()
+}
 
-	// all data is now filled in. now we need to convert the jobs map
-	// to a slice, sort it, and return it
-	jsSlice := []*Job{}
-	for _, j := range js {
-		jsSlice = append(jsSlice, j)
+// CountJobsForRepoPull returns the number of jobs for the RepoPull
+// with the given ID.
+func (db *DB) CountJobsForRepoPull(rpID RepoPullID) (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.jobs WHERE repopull_id = $1 AND repopull_id IN ("+liveRepoPullIDs+")", rpID)
+}
+
+// jobsByIDsQuery selects the jobs with IDs in $1, ordered by ID,
+// with each job's prior job IDs and path configs aggregated into
+// arrays so that a single round trip fetches everything. The config
+// arrays are ordered alike so that their elements line up, and NULL
+// keys, values and prior job IDs are returned as "" or 0.
+const jobsByIDsQuery = `
+SELECT j.id, j.repopull_id, j.agent_id, j.started_at, j.finished_at, j.status, j.health, j.output, j.is_ready, j.version, j.created_at, j.updated_at,
+	COALESCE(p.priorjob_ids, '{}'), COALESCE(c.types, '{}'), COALESCE(c.keys, '{}'), COALESCE(c.vals, '{}'), COALESCE(c.priorjob_ids, '{}')
+FROM peridot.jobs j
+LEFT JOIN LATERAL (
+	SELECT array_agg(priorjob_id ORDER BY priorjob_id) AS priorjob_ids
+	FROM peridot.jobpriorids
+	WHERE job_id = j.id
+) p ON true
+LEFT JOIN LATERAL (
+	SELECT
+		array_agg(type ORDER BY type, key) AS types,
+		array_agg(COALESCE(key, '') ORDER BY type, key) AS keys,
+		array_agg(COALESCE(value, '') ORDER BY type, key) AS vals,
+		array_agg(COALESCE(priorjob_id, 0) ORDER BY type, key) AS priorjob_ids
+	FROM peridot.jobpathconfigs
+	WHERE job_id = j.id
+) c ON true
+WHERE j.id = ANY ($1) AND j.repopull_id IN (` + liveRepoPullIDs + `)
+ORDER BY j.id
+`
+
+// GetJobsByIDs returns all of the jobs in the database with the given
+// IDs. If any ID is not present, or its job's repo pull is hidden by
+// soft deletion, it will be silently omitted (e.g.,
+// no error will be returned); the caller should check to confirm the
+// received jobs match those that were expected.
+func (db *DB) GetJobsByIDs(ids []JobID) ([]*Job, error) {
+	rows, err := db.sqldb.Query(jobsByIDsQuery, pq.Array(ids))
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	sort.Slice(jsSlice, func(i, j int) bool { return jsSlice[i].ID < jsSlice[j].ID })
+	js := []*Job{}
+	for rows.Next() {
+		var priorJobIDs, configTypes, configPriorJobIDs []int64
+		var configKeys, configValues []string
+		j, err := scanJob(rows, pq.Array(&priorJobIDs), pq.Array(&configTypes), pq.Array(&configKeys), pq.Array(&configValues), pq.Array(&configPriorJobIDs))
+		if err != nil {
+			return nil, err
+		}
 
-	return jsSlice, nil
+		for _, pjid := range priorJobIDs {
+			j.PriorJobIDs = append(j.PriorJobIDs, JobID(pjid))
+		}
+		for i, typeInt := range configTypes {
+			jcType, err := JobConfigTypeFromInt(int(typeInt))
+			if err != nil {
+				return nil, err
+			}
+			key, value, pjid := configKeys[i], configValues[i], JobID(configPriorJobIDs[i])
+			switch jcType {
+			case JobConfigKV:
+				j.Config.KV[key] = value
+			case JobConfigCodeReader:
+				if pjid > 0 {
+					j.Config.CodeReader[key] = JobPathConfig{PriorJobID: pjid}
+				} else {
+					j.Config.CodeReader[key] = JobPathConfig{Value: value}
+				}
+			case JobConfigSpdxReader:
+				if pjid > 0 {
+					j.Config.SpdxReader[key] = JobPathConfig{PriorJobID: pjid}
+				} else {
+					j.Config.SpdxReader[key] = JobPathConfig{Value: value}
+				}
+			}
+		}
+
+		js = append(js, j)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return js, nil
 }
 
 // GetJobByID returns the job in the database with the given ID.
-func (db *DB) GetJobByID(id uint32) (*Job, error) {
-	j := &Job{}
-	err := db.sqldb.QueryRow("SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready FROM peridot.jobs WHERE id = $1", id).
-		Scan(&j.ID, &j.RepoPullID, &j.AgentID, &j.StartedAt, &j.FinishedAt, &j.Status, &j.Health, &j.Output, &j.IsReady)
+func (db *DB) GetJobByID(id JobID) (*Job, error) {
+	j, err := scanJob(db.sqldb.QueryRow("SELECT "+jobColumns+" FROM peridot.jobs WHERE id = $1 AND repopull_id IN ("+liveRepoPullIDs+")", id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no job found with ID %v", id)
+		return nil, &NotFoundError{Entity: "job", ID: fmt.Sprint(id)}
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	// create slices for bits that'll (possibly) get filled in below
-	j.PriorJobIDs = []uint32{}
-	j.Config.KV = map[string]string{}
-	j.Config.CodeReader = map[string]JobPathConfig{}
-	j.Config.SpdxReader = map[string]JobPathConfig{}
-
 	// next, query job configs and fill in those details
 	jpcRows, err := db.sqldb.Query("SELECT job_id, type, key, value, priorjob_id FROM peridot.jobpathconfigs WHERE job_id = $1", id)
 	if err != nil {
@@ -343,18 +623,19 @@ func (db *DB) GetJobByID(id uint32) (*Job, error) {
 	defer jpcRows.Close()
 
 	for jpcRows.Next() {
-		var jid uint32
+		var jid JobID
 		var typeInt int
-		var key, value string
+		var keyNullable, valueNullable sql.NullString
 		var pjidNullable sql.NullInt64
-		err := jpcRows.Scan(&jid, &typeInt, &key, &value, &pjidNullable)
+		err := jpcRows.Scan(&jid, &typeInt, &keyNullable, &valueNullable, &pjidNullable)
 		if err != nil {
 			return nil, err
 		}
+		key, value := keyNullable.String, valueNullable.String
 
-		var pjid uint32
+		var pjid JobID
 		if pjidNullable.Valid {
-			pjid = uint32(pjidNullable.Int64)
+			pjid = JobID(pjidNullable.Int64)
 		} else {
 			pjid = 0
 		}
@@ -390,7 +671,7 @@ func (db *DB) GetJobByID(id uint32) (*Job, error) {
 	defer priorRows.Close()
 
 	for priorRows.Next() {
-		var jid, pjid uint32
+		var jid, pjid JobID
 		err := priorRows.Scan(&jid, &pjid)
 		if err != nil {
 			return nil, err
@@ -402,37 +683,54 @@ func (db *DB) GetJobByID(id uint32) (*Job, error) {
 	return j, nil
 }
 
-// GetReadyJobs returns up to n jobs that are "ready", where "ready"
-// means that BOTH (1) IsReady is true and (2) all jobs from its
-// PriorJobIDs are StatusStopped and either HealthOK or HealthDegraded.
-// If n is 0 then all "ready" jobs are returned.
-func (db *DB) GetReadyJobs(n uint32) ([]*Job, error) {
-	readyJobsQuery := `
-SELECT id
-FROM (
-	SELECT id, (CASE WHEN any_prior_unready IS NULL THEN false ELSE any_prior_unready END) AS any_prior_unready, status, health, is_ready
+// ExistsJob reports whether a Job with the given ID exists,
+// without retrieving it or its configs.
+func (db *DB) ExistsJob(id JobID) (bool, error) {
+	return db.exists("SELECT EXISTS (SELECT 1 FROM peridot.jobs WHERE id = $1 AND repopull_id IN ("+liveRepoPullIDs+"))", id)
+}
+
+// readyJobsQuery selects the IDs of up to $1 "ready" jobs, as
+// defined for GetReadyJobs, ordered by ID; if $1 is 0 there is no
+// limit. The status and health values are inlined: candidates are
+// StatusStartup (1) or StatusQueued (4) with HealthOK (1), and a
+// prior job blocks its dependents unless it is StatusStopped (3)
+// with neither HealthError (3) nor HealthUnknown (4).
+//
+// Expected plan: the candidates CTE is an index scan on
+// jobs_status_health_is_ready, since few jobs are waiting at any
+// time. For each candidate, the NOT EXISTS anti-join probes
+// jobpriorids through its (job_id, priorjob_id) unique index and
+// each prior job through the jobs primary key, so neither table is
+// scanned in full. The jobpriorids_priorjob_id index is not used
+// here, but serves the reverse lookup of a job's dependents,
+// including the cascade when a job is deleted.
+const readyJobsQuery = `
+WITH candidates AS (
+	SELECT id
 	FROM peridot.jobs
-	LEFT JOIN (
-		SELECT DISTINCT id, ((priorjob_status != 3) OR (priorjob_health = 3)) AS any_prior_unready
-		FROM (
-			SELECT id, priorjob_id, any_prior_unready
-			FROM (
-				SELECT
-					peridot.jobpriorids.id AS id,
-					peridot.jobpriorids.priorjob_id AS priorjob_id,
-					peridot.jobs.status AS priorjob_status,
-					peridot.jobs.health AS priorjob_health
-				FROM peridot.jobpriorids
-				LEFT JOIN peridot.jobs ON peridot.jobpriorids.priorjob_id=peridot.jobs.id) calc1
-			) calc2
-		WHERE EXISTS(SELECT 1 WHERE any_prior_unready = true)
-	) calc3 ON peridot.jobs.id = id
-) calc4
-WHERE any_prior_unready = false AND status = 1 AND health = 1 AND is_ready = true
-ORDER BY id
-LIMIT $1;
+	WHERE status IN (1, 4) AND health = 1 AND is_ready = true
+		AND repopull_id IN (` + liveRepoPullIDs + `)
+)
+SELECT c.id
+FROM candidates c
+WHERE NOT EXISTS (
+	SELECT 1
+	FROM peridot.jobpriorids p
+	JOIN peridot.jobs pj ON pj.id = p.priorjob_id
+	WHERE p.job_id = c.id AND (pj.status <> 3 OR pj.health IN (3, 4))
+)
+ORDER BY c.id
+LIMIT NULLIF($1, 0)
 `
 
+// GetReadyJobs returns up to n jobs that are "ready", where "ready"
+// means that (1) IsReady is true, (2) the job itself is StatusStartup
+// or StatusQueued with HealthOK, and (3) all jobs from its
+// PriorJobIDs are StatusStopped and not HealthError or HealthUnknown.
+// A StatusCancelled prior job is not stopped, so its dependents never
+// become ready. Jobs of soft-deleted projects, subprojects and repos
+// are never ready. If n is 0 then all "ready" jobs are returned.
+func (db *DB) GetReadyJobs(n uint32) ([]*Job, error) {
 	jobRows, err := db.sqldb.Query(readyJobsQuery, n)
 	if err != nil {
 		return nil, err
@@ -440,10 +738,10 @@ LIMIT $1;
 	defer jobRows.Close()
 
 	// collect job IDs so we can query them in follow-up call
-	jobIDs := []uint32{}
+	jobIDs := []JobID{}
 
 	for jobRows.Next() {
-		var id uint32
+		var id JobID
 		err := jobRows.Scan(&id)
 		if err != nil {
 			return nil, err
@@ -460,116 +758,155 @@ LIMIT $1;
 
 // AddJob adds a new job as specified, with empty configs.
 // It returns the new job's ID on success or an error if failing.
-func (db *DB) AddJob(repoPullID uint32, agentID uint32, priorJobIDs []uint32) (uint32, error) {
+func (db *DB) AddJob(repoPullID RepoPullID, agentID AgentID, priorJobIDs []JobID) (JobID, error) {
 	return db.AddJobWithConfigs(repoPullID, agentID, priorJobIDs, nil, nil, nil)
 }
 
-// used in AddJobWithConfigs below
+// used in AddJobWithConfigs and BulkAddJobConfigs
 type configStmtValue struct {
-	jobID      uint32
+	jobID      JobID
 	configType int
 	key        string
 	value      string
-	priorjobID uint32
+	priorjobID JobID
+}
+
+// configStmtValues returns the rows to insert into jobpathconfigs
+// for the given job's configs, ordered by config type and then by
+// key.
+func configStmtValues(jobID JobID, configKV map[string]string, configCodeReader map[string]JobPathConfig, configSpdxReader map[string]JobPathConfig) []*configStmtValue {
+	stmtVals := []*configStmtValue{}
+
+	keys := []string{}
+	for k := range configKV {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sv := configStmtValue{jobID: jobID, configType: IntFromJobConfigType(JobConfigKV), key: k, value: configKV[k], priorjobID: 0}
+		stmtVals = append(stmtVals, &sv)
+	}
+
+	keys = []string{}
+	for k := range configCodeReader {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var sv configStmtValue
+		pc := configCodeReader[k]
+		if pc.PriorJobID > 0 {
+			sv = configStmtValue{jobID: jobID, configType: IntFromJobConfigType(JobConfigCodeReader), key: k, value: "", priorjobID: pc.PriorJobID}
+		} else {
+			sv = configStmtValue{jobID: jobID, configType: IntFromJobConfigType(JobConfigCodeReader), key: k, value: pc.Value, priorjobID: 0}
+		}
+		stmtVals = append(stmtVals, &sv)
+	}
+
+	keys = []string{}
+	for k := range configSpdxReader {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var sv configStmtValue
+		pc := configSpdxReader[k]
+		if pc.PriorJobID > 0 {
+			sv = configStmtValue{jobID: jobID, configType: IntFromJobConfigType(JobConfigSpdxReader), key: k, value: "", priorjobID: pc.PriorJobID}
+		} else {
+			sv = configStmtValue{jobID: jobID, configType: IntFromJobConfigType(JobConfigSpdxReader), key: k, value: pc.Value, priorjobID: 0}
+		}
+		stmtVals = append(stmtVals, &sv)
+	}
+
+	return stmtVals
 }
 
 // AddJobWithConfigs adds a new job as specified, with the
 // noted configuration values. It returns the new job's ID
 // on success or an error if failing.
-func (db *DB) AddJobWithConfigs(repoPullID uint32, agentID uint32, priorJobIDs []uint32, configKV map[string]string, configCodeReader map[string]JobPathConfig, configSpdxReader map[string]JobPathConfig) (uint32, error) {
+func (db *DB) AddJobWithConfigs(repoPullID RepoPullID, agentID AgentID, priorJobIDs []JobID, configKV map[string]string, configCodeReader map[string]JobPathConfig, configSpdxReader map[string]JobPathConfig) (JobID, error) {
+	j := &Job{RepoPullID: repoPullID, AgentID: agentID, PriorJobIDs: priorJobIDs, Status: StatusStartup, Health: HealthOK, Config: JobConfig{KV: configKV, CodeReader: configCodeReader, SpdxReader: configSpdxReader}}
+	if err := j.Validate(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+
 	// FIXME consider whether to move out into one-time-prepared statement
 	// first create the job
-	jobStmt, err := db.sqldb.Prepare("INSERT INTO peridot.jobs(repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id")
+	jobStmt, err := db.prepareIn(tx, "INSERT INTO peridot.jobs(repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id")
 	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
 
 	// and get its ID
-	var jobID uint32
-	err = jobStmt.QueryRow(repoPullID, agentID, time.Time{}, time.Time{}, StatusStartup, HealthOK, "", false).Scan(&jobID)
+	err = jobStmt.QueryRow(repoPullID, agentID, time.Time{}, time.Time{}, StatusStartup, HealthOK, "", false).Scan(&j.ID)
 	if err != nil {
+		tx.Rollback()
+		return 0, translateConstraintError("job", err)
+	}
+
+	if err = db.addJobPriorsAndConfigs(tx, j.ID, priorJobIDs, configKV, configCodeReader, configSpdxReader); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err = addOutboxEvent(tx, "job", j.ID, AuditActionAdd, j); err != nil {
+		tx.Rollback()
 		return 0, err
 	}
 
-	// now, if we have any prior job IDs, add those to that table
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return j.ID, nil
+}
+
+// addJobPriorsAndConfigs adds the prior job IDs and configuration
+// values for the newly-created job with the given ID within tx. It
+// returns nil on success or an error if failing; the caller is
+// responsible for rolling back tx on error.
+func (db *DB) addJobPriorsAndConfigs(tx sqlConn, jobID JobID, priorJobIDs []JobID, configKV map[string]string, configCodeReader map[string]JobPathConfig, configSpdxReader map[string]JobPathConfig) error {
+	// if we have any prior job IDs, add those to that table
 	if len(priorJobIDs) > 0 {
-		priorJobStmt, err := db.sqldb.Prepare("INSERT INTO peridot.jobpriorids(job_id, priorjob_id) VALUES ($1, $2)")
+		priorJobStmt, err := db.prepareIn(tx, "INSERT INTO peridot.jobpriorids(job_id, priorjob_id) VALUES ($1, $2)")
 		if err != nil {
-			return 0, err
+			return err
 		}
 
 		for _, pjID := range priorJobIDs {
 			res, err := priorJobStmt.Exec(jobID, pjID)
 			// check error
 			if err != nil {
-				return 0, err
+				return translateConstraintError("prior job", err)
 			}
 
 			// check that something was actually inserted
 			rows, err := res.RowsAffected()
 			if err != nil {
-				return 0, err
+				return err
 			}
 			if rows == 0 {
 				// problem should have been caused by bad prior job ID,
 				// because we just created the current job ID
-				return 0, fmt.Errorf("no prior job found with ID %v", pjID)
+				return &NotFoundError{Entity: "prior job", ID: fmt.Sprint(pjID)}
 			}
 		}
 	}
 
 	// and now, if we have any job configs, add those to that table
 	if len(configKV) > 0 || len(configCodeReader) > 0 || len(configSpdxReader) > 0 {
-		// cycle through each config map, sorting to order by keys,
-		// and build slice of statement values to insert
-		stmtVals := []*configStmtValue{}
-
-		keys := []string{}
-		for k := range configKV {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			sv := configStmtValue{jobID: jobID, configType: IntFromJobConfigType(JobConfigKV), key: k, value: configKV[k], priorjobID: 0}
-			stmtVals = append(stmtVals, &sv)
-		}
-
-		keys = []string{}
-		for k := range configCodeReader {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			var sv configStmtValue
-			pc := configCodeReader[k]
-			if pc.PriorJobID > 0 {
-				sv = configStmtValue{jobID: jobID, configType: IntFromJobConfigType(JobConfigCodeReader), key: k, value: "", priorjobID: pc.PriorJobID}
-			} else {
-				sv = configStmtValue{jobID: jobID, configType: IntFromJobConfigType(JobConfigCodeReader), key: k, value: pc.Value, priorjobID: 0}
-			}
-			stmtVals = append(stmtVals, &sv)
-		}
-
-		keys = []string{}
-		for k := range configSpdxReader {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			var sv configStmtValue
-			pc := configSpdxReader[k]
-			if pc.PriorJobID > 0 {
-				sv = configStmtValue{jobID: jobID, configType: IntFromJobConfigType(JobConfigSpdxReader), key: k, value: "", priorjobID: pc.PriorJobID}
-			} else {
-				sv = configStmtValue{jobID: jobID, configType: IntFromJobConfigType(JobConfigSpdxReader), key: k, value: pc.Value, priorjobID: 0}
-			}
-			stmtVals = append(stmtVals, &sv)
-		}
+		stmtVals := configStmtValues(jobID, configKV, configCodeReader, configSpdxReader)
 
 		// prepare statement
-		configStmt, err := db.sqldb.Prepare("INSERT INTO peridot.jobpathconfigs(job_id, type, key, value, priorjob_id) VALUES ($1, $2, $3, $4, $5)")
+		configStmt, err := db.prepareIn(tx, "INSERT INTO peridot.jobpathconfigs(job_id, type, key, value, priorjob_id) VALUES ($1, $2, $3, $4, $5)")
 		if err != nil {
-			return 0, err
+			return err
 		}
 
 		// and cycle through statement values, adding them
@@ -581,115 +918,232 @@ func (db *DB) AddJobWithConfigs(repoPullID uint32, agentID uint32, priorJobIDs [
 			res, err := configStmt.Exec(stv.jobID, stv.configType, stv.key, stv.value, nullablePriorJobID)
 			// check error
 			if err != nil {
-				return 0, err
+				return translateConstraintError("job config", err)
 			}
 
 			// check that something was actually inserted
 			rows, err := res.RowsAffected()
 			if err != nil {
-				return 0, err
+				return err
 			}
 			if rows == 0 {
 				// problem should have been caused by bad prior job ID,
 				// because we just created the current job ID
-				return 0, fmt.Errorf("error adding values for job %v, config %v, %v, %v, %v", stv.jobID, stv.configType, stv.key, stv.value, stv.priorjobID)
+				return fmt.Errorf("error adding values for job %v, config %v, %v, %v, %v", stv.jobID, stv.configType, stv.key, stv.value, stv.priorjobID)
 			}
 		}
 	}
 
-	return jobID, nil
+	return nil
 }
 
 // UpdateJobIsReady sets the boolean value to specify
 // whether the Job with the gievn ID is ready to be run.
 // It does _not_ actually run the Job. It returns nil on
 // success or an error if failing.
-func (db *DB) UpdateJobIsReady(id uint32, ready bool) error {
-	var err error
-	var result sql.Result
-
+func (db *DB) UpdateJobIsReady(id JobID, ready bool) error {
 	// FIXME consider whether to move out into one-time-prepared statements
-	stmt, err := db.sqldb.Prepare("UPDATE peridot.jobs SET is_ready = $1 WHERE id = $2")
-	if err != nil {
+	return db.execWithOutboxEvent("job", id, AuditActionUpdate, map[string]interface{}{"id": id, "is_ready": ready},
+		"UPDATE peridot.jobs SET is_ready = $1, version = version + 1 WHERE id = $2", ready, id)
+}
+
+// UpdateJobStatus sets the status variables for this job. If version
+// is not 0, the update is only made if the job's Version is still
+// version, and a *ConflictError is returned otherwise; a version of 0
+// skips this check. A *TransitionError is returned if the job's
+// current status cannot move to status, e.g. from stopped back to
+// running.
+func (db *DB) UpdateJobStatus(id JobID, version uint32, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string) error {
+	if err := validateStatusHealth("job", status, health); err != nil {
 		return err
 	}
-	result, err = stmt.Exec(ready, id)
 
-	// check error
-	if err != nil {
-		return err
+	startedAt = normalizeTime(startedAt)
+	finishedAt = normalizeTime(finishedAt)
+
+	// FIXME consider whether to move out into one-time-prepared statements
+	payload := map[string]interface{}{"id": id, "started_at": startedAt, "finished_at": finishedAt, "status": status, "health": health, "output": output}
+	mismatch := func() error {
+		return db.statusUpdateError("jobs", "job", id, version, status)
 	}
+	return db.execWithOutboxEventOr(mismatch, "job", id, AuditActionUpdate, payload,
+		"UPDATE peridot.jobs SET started_at = $1, finished_at = $2, status = $3, health = $4, output = $5, version = version + 1 WHERE id = $6 AND ($7 = 0 OR version = $7) AND COALESCE(status, 0) = ANY($8)",
+		startedAt, finishedAt, status, health, output, id, version, statusPredecessors(status))
+}
 
-	// check that something was actually updated
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
+// JobStatusUpdate is a new set of status variables for one job, as
+// passed to UpdateJobStatuses. Its fields have the same meanings as
+// the corresponding arguments to UpdateJobStatus.
+type JobStatusUpdate struct {
+	ID         JobID
+	Version    uint32
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Status     Status
+	Health     Health
+	Output     string
+}
+
+// jobStatusUpdateParams is the number of query parameters that
+// UpdateJobStatuses uses for each update.
+const jobStatusUpdateParams = 8
+
+// MaxJobStatusUpdates is the largest number of updates that can be
+// passed to UpdateJobStatuses at once, since PostgreSQL accepts at
+// most 65535 parameters per statement.
+const MaxJobStatusUpdates = 65535 / jobStatusUpdateParams
+
+// UpdateJobStatuses sets the status variables for several jobs at
+// once, in a single statement and transaction, as if UpdateJobStatus
+// were called for each of updates. Each job may only appear once.
+//
+// Updates that cannot be made, e.g. because the job does not exist
+// or its version has changed, do not prevent the others from being
+// made. Instead, the returned map holds, for the ID of each job that
+// was not updated, the error that UpdateJobStatus would have
+// returned for it. A non-nil error is only returned if none of the
+// updates were made.
+func (db *DB) UpdateJobStatuses(updates []JobStatusUpdate) (map[JobID]error, error) {
+	failed := map[JobID]error{}
+	if len(updates) == 0 {
+		return failed, nil
 	}
-	if rows == 0 {
-		return fmt.Errorf("no job found with ID %v", id)
+	if len(updates) > MaxJobStatusUpdates {
+		return nil, &ValidationError{Entity: "job", Field: "updates", Reason: fmt.Sprintf("at most %d may be made at once", MaxJobStatusUpdates)}
 	}
 
-	return nil
-}
+	values := make([]string, 0, len(updates))
+	args := make([]interface{}, 0, len(updates)*jobStatusUpdateParams)
+	seen := map[JobID]bool{}
+	for _, u := range updates {
+		if err := validateStatusHealth("job", u.Status, u.Health); err != nil {
+			return nil, err
+		}
+		if seen[u.ID] {
+			return nil, &ValidationError{Entity: "job", Field: "ID", Reason: fmt.Sprintf("%d is updated more than once", u.ID)}
+		}
+		seen[u.ID] = true
 
-// UpdateJobStatus sets the status variables for this job.
-func (db *DB) UpdateJobStatus(id uint32, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string) error {
-	var err error
-	var result sql.Result
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d::integer, $%d::integer, $%d::timestamptz, $%d::timestamptz, $%d::integer, $%d::integer, $%d::text, $%d::integer[])",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8))
+		args = append(args, u.ID, u.Version, normalizeTime(u.StartedAt), normalizeTime(u.FinishedAt), u.Status, u.Health, u.Output, statusPredecessors(u.Status))
+	}
 
-	// FIXME consider whether to move out into one-time-prepared statements
-	stmt, err := db.sqldb.Prepare("UPDATE peridot.jobs SET started_at = $1, finished_at = $2, status = $3, health = $4, output = $5 WHERE id = $6")
+	tx, err := db.begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	result, err = stmt.Exec(startedAt, finishedAt, status, health, output, id)
 
-	// check error
+	updated, err := updateJobStatusesInTx(tx, values, args)
 	if err != nil {
-		return err
+		tx.Rollback()
+		return nil, err
 	}
 
-	// check that something was actually updated
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
+	// record an outbox event for each job that was updated, as
+	// UpdateJobStatus does
+	for _, u := range updates {
+		if !updated[u.ID] {
+			continue
+		}
+		payload := map[string]interface{}{"id": u.ID, "started_at": normalizeTime(u.StartedAt), "finished_at": normalizeTime(u.FinishedAt), "status": u.Status, "health": u.Health, "output": u.Output}
+		if err = addOutboxEvent(tx, "job", u.ID, AuditActionUpdate, payload); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
 	}
-	if rows == 0 {
-		return fmt.Errorf("no job found with ID %v", id)
+
+	// work out why the remaining jobs were not updated, as
+	// statusUpdateError does for a single job
+	missed := []int64{}
+	for _, u := range updates {
+		if !updated[u.ID] {
+			missed = append(missed, int64(u.ID))
+		}
+	}
+	if len(missed) > 0 {
+		current, err := currentJobStatuses(tx, missed)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		for _, u := range updates {
+			if updated[u.ID] {
+				continue
+			}
+			from, ok := current[u.ID]
+			switch {
+			case !ok:
+				failed[u.ID] = &NotFoundError{Entity: "job", ID: fmt.Sprint(u.ID)}
+			case !IsValidStatusTransition(from, u.Status):
+				failed[u.ID] = &TransitionError{Entity: "job", ID: fmt.Sprint(u.ID), From: from, To: u.Status}
+			default:
+				failed[u.ID] = &ConflictError{Entity: "job", ID: fmt.Sprint(u.ID), Version: u.Version}
+			}
+		}
 	}
 
-	return nil
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return failed, nil
 }
 
-// DeleteJob deletes an existing Job with the given ID.
-// It returns nil on success or an error if failing.
-func (db *DB) DeleteJob(id uint32) error {
-	var err error
-	var result sql.Result
-
-	// FIXME consider whether need to delete sub-elements first, or
-	// FIXME whether to set up sub-elements' schemas to delete on cascade
-
-	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("DELETE FROM peridot.jobs WHERE id = $1")
+// updateJobStatusesInTx runs the UPDATE for UpdateJobStatuses, with
+// one row of values for each job, and returns the IDs of the jobs
+// that were updated.
+func updateJobStatusesInTx(tx sqlConn, values []string, args []interface{}) (map[JobID]bool, error) {
+	rows, err := tx.Query(`
+		UPDATE peridot.jobs j
+		SET started_at = v.started_at, finished_at = v.finished_at, status = v.status, health = v.health, output = v.output, version = j.version + 1
+		FROM (VALUES `+strings.Join(values, ", ")+`) AS v(id, version, started_at, finished_at, status, health, output, preds)
+		WHERE j.id = v.id AND (v.version = 0 OR j.version = v.version) AND COALESCE(j.status, 0) = ANY(v.preds)
+		RETURNING j.id`, args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	result, err = stmt.Exec(id)
+	defer rows.Close()
 
-	// check error
-	if err != nil {
-		return err
+	updated := map[JobID]bool{}
+	for rows.Next() {
+		var id JobID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		updated[id] = true
 	}
+	return updated, rows.Err()
+}
 
-	// check that something was actually deleted
-	rows, err := result.RowsAffected()
+// currentJobStatuses returns the current status of each of the jobs
+// with the given IDs that exists.
+func currentJobStatuses(tx sqlConn, ids []int64) (map[JobID]Status, error) {
+	rows, err := tx.Query("SELECT id, COALESCE(status, 0) FROM peridot.jobs WHERE id = ANY($1)", pq.Array(ids))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if rows == 0 {
-		return fmt.Errorf("no job found with ID %v", id)
+	defer rows.Close()
+
+	statuses := map[JobID]Status{}
+	for rows.Next() {
+		var id JobID
+		var st int
+		if err := rows.Scan(&id, &st); err != nil {
+			return nil, err
+		}
+		statuses[id] = Status(st)
 	}
+	return statuses, rows.Err()
+}
 
-	return nil
+// DeleteJob deletes an existing Job with the given ID.
+// It returns nil on success or an error if failing.
+func (db *DB) DeleteJob(id JobID) error {
+	// FIXME consider whether need to delete sub-elements first, or
+	// FIXME whether to set up sub-elements' schemas to delete on cascade
+
+	// FIXME consider whether to move out into one-time-prepared statement
+	return db.execWithOutboxEvent("job", id, AuditActionDelete, map[string]interface{}{"id": id},
+		"DELETE FROM peridot.jobs WHERE id = $1", id)
 }
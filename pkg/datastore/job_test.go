@@ -5,6 +5,8 @@ package datastore
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"reflect"
 	"testing"
 	"time"
 
@@ -12,7 +14,13 @@ import (
 	"github.com/lib/pq"
 )
 
-func TestShouldGetAllJobsForOneRepoPull(t *testing.T) {
+// jobsByIDsColumns are the columns returned by jobsByIDsQuery.
+var jobsByIDsColumns = []string{"id", "repopull_id", "agent_id", "started_at", "finished_at", "status", "health", "output", "is_ready", "version", "created_at", "updated_at", "priorjob_ids", "types", "keys", "vals", "config_priorjob_ids"}
+
+// jobsByIDsRegex matches jobsByIDsQuery.
+var jobsByIDsRegex = `SELECT j.id, j.repopull_id, .* FROM peridot.jobs j LEFT JOIN LATERAL \(.*FROM peridot.jobpriorids.*\) p ON true LEFT JOIN LATERAL \(.*FROM peridot.jobpathconfigs.*\) c ON true WHERE j.id = ANY \(\$1\) AND j.repopull_id IN \(` + liveRepoPullIDsRegex + `\) ORDER BY j.id`
+
+func TestShouldGetJobsWithMultipleIDs(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
 	if err != nil {
@@ -25,9 +33,9 @@ func TestShouldGetAllJobsForOneRepoPull(t *testing.T) {
 		ID:          4,
 		RepoPullID:  14,
 		AgentID:     6,
-		PriorJobIDs: []uint32{},
-		StartedAt:   time.Date(2019, 5, 2, 13, 53, 41, 671764, time.UTC),
-		FinishedAt:  time.Date(2019, 5, 2, 13, 54, 17, 386417, time.UTC),
+		PriorJobIDs: []JobID{},
+		StartedAt:   time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC),
+		FinishedAt:  time.Date(2019, 5, 2, 13, 54, 17, 386417000, time.UTC),
 		Status:      StatusStopped,
 		Health:      HealthOK,
 		Output:      "success, 2930 files scanned",
@@ -43,7 +51,7 @@ func TestShouldGetAllJobsForOneRepoPull(t *testing.T) {
 		ID:          7,
 		RepoPullID:  14,
 		AgentID:     2,
-		PriorJobIDs: []uint32{4},
+		PriorJobIDs: []JobID{4},
 		StartedAt:   time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC),
 		FinishedAt:  time.Date(2019, 5, 4, 12, 0, 1, 0, time.UTC),
 		Status:      StatusRunning,
@@ -59,29 +67,17 @@ func TestShouldGetAllJobsForOneRepoPull(t *testing.T) {
 		},
 	}
 
-	// expect first call to get jobs, without configs or prior job IDs
-	sentRows1 := sqlmock.NewRows([]string{"id", "repopull_id", "agent_id", "started_at", "finished_at", "status", "health", "output", "is_ready"}).
-		AddRow(j4.ID, j4.RepoPullID, j4.AgentID, j4.StartedAt, j4.FinishedAt, j4.Status, j4.Health, j4.Output, j4.IsReady).
-		AddRow(j7.ID, j7.RepoPullID, j7.AgentID, j7.StartedAt, j7.FinishedAt, j7.Status, j7.Health, j7.Output, j7.IsReady)
-	mock.ExpectQuery(`SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready FROM peridot.jobs WHERE repopull_id = \$1`).
-		WillReturnRows(sentRows1)
-
-	// expect second call to get job configs for found job IDs
-	sentRows2 := sqlmock.NewRows([]string{"job_id", "type", "key", "value", "priorjob_id"}).
-		AddRow(4, 0, "hi", "there", 0).
-		AddRow(4, 0, "hello", "world", 0).
-		AddRow(7, 1, "primary", "", 4)
-	mock.ExpectQuery(`SELECT job_id, type, key, value, priorjob_id FROM peridot.jobpathconfigs WHERE job_id = ANY \(\$1\)`).
-		WillReturnRows(sentRows2)
-
-	// and expect third call to get prior job IDs for found job IDs
-	sentRows3 := sqlmock.NewRows([]string{"job_id", "priorjob_id"}).
-		AddRow(7, 4)
-	mock.ExpectQuery(`SELECT job_id, priorjob_id FROM peridot.jobpriorids WHERE job_id = ANY \(\$1\)`).
-		WillReturnRows(sentRows3)
+	// expect a single call to get jobs, with configs and prior job IDs
+	// aggregated into arrays
+	sentRows := sqlmock.NewRows(jobsByIDsColumns).
+		AddRow(j4.ID, j4.RepoPullID, j4.AgentID, j4.StartedAt, j4.FinishedAt, j4.Status, j4.Health, j4.Output, j4.IsReady, j4.Version, testCreatedAt, testUpdatedAt, "{}", "{0,0}", "{hello,hi}", "{world,there}", "{0,0}").
+		AddRow(j7.ID, j7.RepoPullID, j7.AgentID, j7.StartedAt, j7.FinishedAt, j7.Status, j7.Health, j7.Output, j7.IsReady, j7.Version, testCreatedAt, testUpdatedAt, "{4}", "{1}", "{primary}", `{""}`, "{4}")
+	mock.ExpectQuery(jobsByIDsRegex).
+		WithArgs(pq.Array([]JobID{4, 7})).
+		WillReturnRows(sentRows)
 
 	// run the tested function
-	gotRows, err := db.GetAllJobsForRepoPull(14)
+	gotRows, err := db.GetJobsByIDs([]JobID{4, 7})
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -103,7 +99,7 @@ func TestShouldGetAllJobsForOneRepoPull(t *testing.T) {
 	helperCompareJobs(t, &j7, job1)
 }
 
-func TestShouldGetJobsWithMultipleIDs(t *testing.T) {
+func TestShouldGetJobByID(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
 	if err != nil {
@@ -112,29 +108,11 @@ func TestShouldGetJobsWithMultipleIDs(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	j4 := Job{
-		ID:          4,
-		RepoPullID:  7,
-		AgentID:     6,
-		PriorJobIDs: []uint32{},
-		StartedAt:   time.Date(2019, 5, 2, 13, 53, 41, 671764, time.UTC),
-		FinishedAt:  time.Date(2019, 5, 2, 13, 54, 17, 386417, time.UTC),
-		Status:      StatusStopped,
-		Health:      HealthOK,
-		Output:      "success, 2930 files scanned",
-		IsReady:     true,
-		Config: JobConfig{
-			KV:         map[string]string{"hi": "there", "hello": "world"},
-			CodeReader: map[string]JobPathConfig{},
-			SpdxReader: map[string]JobPathConfig{},
-		},
-	}
-
 	j7 := Job{
 		ID:          7,
-		RepoPullID:  12,
+		RepoPullID:  14,
 		AgentID:     2,
-		PriorJobIDs: []uint32{4},
+		PriorJobIDs: []JobID{4},
 		StartedAt:   time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC),
 		FinishedAt:  time.Date(2019, 5, 4, 12, 0, 1, 0, time.UTC),
 		Status:      StatusRunning,
@@ -151,31 +129,26 @@ func TestShouldGetJobsWithMultipleIDs(t *testing.T) {
 	}
 
 	// expect first call to get jobs, without configs or prior job IDs
-	sentRows1 := sqlmock.NewRows([]string{"id", "repopull_id", "agent_id", "started_at", "finished_at", "status", "health", "output", "is_ready"}).
-		AddRow(j4.ID, j4.RepoPullID, j4.AgentID, j4.StartedAt, j4.FinishedAt, j4.Status, j4.Health, j4.Output, j4.IsReady).
-		AddRow(j7.ID, j7.RepoPullID, j7.AgentID, j7.StartedAt, j7.FinishedAt, j7.Status, j7.Health, j7.Output, j7.IsReady)
-	mock.ExpectQuery(`SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready FROM peridot.jobs WHERE id = ANY \(\$1\)`).
-		WithArgs(pq.Array([]uint32{4, 7})).
+	sentRows1 := sqlmock.NewRows([]string{"id", "repopull_id", "agent_id", "started_at", "finished_at", "status", "health", "output", "is_ready", "version", "created_at", "updated_at"}).
+		AddRow(j7.ID, j7.RepoPullID, j7.AgentID, j7.StartedAt, j7.FinishedAt, j7.Status, j7.Health, j7.Output, j7.IsReady, j7.Version, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready, version, created_at, updated_at FROM peridot.jobs WHERE id = \$1`).
+		WithArgs(7).
 		WillReturnRows(sentRows1)
 
 	// expect second call to get job configs for found job IDs
 	sentRows2 := sqlmock.NewRows([]string{"job_id", "type", "key", "value", "priorjob_id"}).
-		AddRow(4, 0, "hi", "there", 0).
-		AddRow(4, 0, "hello", "world", 0).
 		AddRow(7, 1, "primary", "", 4)
-	mock.ExpectQuery(`SELECT job_id, type, key, value, priorjob_id FROM peridot.jobpathconfigs WHERE job_id = ANY \(\$1\)`).
-		WithArgs(pq.Array([]uint32{4, 7})).
+	mock.ExpectQuery(`SELECT job_id, type, key, value, priorjob_id FROM peridot.jobpathconfigs WHERE job_id = \$1`).
 		WillReturnRows(sentRows2)
 
 	// and expect third call to get prior job IDs for found job IDs
 	sentRows3 := sqlmock.NewRows([]string{"job_id", "priorjob_id"}).
 		AddRow(7, 4)
-	mock.ExpectQuery(`SELECT job_id, priorjob_id FROM peridot.jobpriorids WHERE job_id = ANY \(\$1\)`).
-		WithArgs(pq.Array([]uint32{4, 7})).
+	mock.ExpectQuery(`SELECT job_id, priorjob_id FROM peridot.jobpriorids WHERE job_id = \$1`).
 		WillReturnRows(sentRows3)
 
 	// run the tested function
-	gotRows, err := db.GetJobsByIDs([]uint32{4, 7})
+	job, err := db.GetJobByID(7)
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -186,18 +159,70 @@ func TestShouldGetJobsWithMultipleIDs(t *testing.T) {
 		t.Errorf("unfulfilled expectations: %v", err)
 	}
 
-	// and check returned values; should be ordered by job ID
+	// and check returned values
+	helperCompareJobs(t, &j7, job)
+}
+
+func TestShouldGetAllJobsForOneRepoPull(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// the configs and prior job IDs queries run concurrently, so they
+	// may arrive in either order
+	mock.MatchExpectationsInOrder(false)
+
+	startedAt := time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready, version, created_at, updated_at FROM peridot.jobs WHERE repopull_id = \$1 AND repopull_id IN \(` + liveRepoPullIDsRegex + `\)`).
+		WithArgs(14).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "repopull_id", "agent_id", "started_at", "finished_at", "status", "health", "output", "is_ready", "version", "created_at", "updated_at"}).
+			AddRow(7, 14, 2, startedAt, startedAt, StatusRunning, HealthOK, "", true, 1, testCreatedAt, testUpdatedAt).
+			AddRow(4, 14, 1, startedAt, startedAt, StatusStopped, HealthOK, "", true, 1, testCreatedAt, testUpdatedAt))
+	mock.ExpectQuery(`SELECT job_id, type, key, value, priorjob_id FROM peridot.jobpathconfigs WHERE job_id = ANY \(\$1\)`).
+		WithArgs("{7,4}").
+		WillReturnRows(sqlmock.NewRows([]string{"job_id", "type", "key", "value", "priorjob_id"}).
+			AddRow(4, 0, "mode", "fast", nil).
+			AddRow(7, 1, "primary", nil, 4))
+	mock.ExpectQuery(`SELECT job_id, priorjob_id FROM peridot.jobpriorids WHERE job_id = ANY \(\$1\)`).
+		WithArgs("{7,4}").
+		WillReturnRows(sqlmock.NewRows([]string{"job_id", "priorjob_id"}).
+			AddRow(7, 4))
+
+	// run the tested function
+	gotRows, err := db.GetAllJobsForRepoPull(14)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
 	if len(gotRows) != 2 {
 		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
 	}
-	job0 := gotRows[0]
-	helperCompareJobs(t, &j4, job0)
-
-	job1 := gotRows[1]
-	helperCompareJobs(t, &j7, job1)
+	if gotRows[0].ID != 4 || gotRows[1].ID != 7 {
+		t.Errorf("expected jobs sorted by ID, got %v and %v", gotRows[0].ID, gotRows[1].ID)
+	}
+	if gotRows[0].Config.KV["mode"] != "fast" {
+		t.Errorf("expected %v, got %v", "fast", gotRows[0].Config.KV["mode"])
+	}
+	if gotRows[1].Config.CodeReader["primary"].PriorJobID != 4 {
+		t.Errorf("expected %v, got %v", 4, gotRows[1].Config.CodeReader["primary"].PriorJobID)
+	}
+	if len(gotRows[1].PriorJobIDs) != 1 || gotRows[1].PriorJobIDs[0] != 4 {
+		t.Errorf("expected prior job IDs [4], got %v", gotRows[1].PriorJobIDs)
+	}
 }
 
-func TestShouldGetJobByID(t *testing.T) {
+func TestShouldGetJobByIDWithNullColumns(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
 	if err != nil {
@@ -206,49 +231,75 @@ func TestShouldGetJobByID(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	j7 := Job{
+	startedAt := time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready, version, created_at, updated_at FROM peridot.jobs WHERE id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "repopull_id", "agent_id", "started_at", "finished_at", "status", "health", "output", "is_ready", "version", "created_at", "updated_at"}).
+			AddRow(7, 14, 2, startedAt, startedAt, StatusRunning, HealthOK, nil, true, 1, testCreatedAt, testUpdatedAt))
+	mock.ExpectQuery(`SELECT job_id, type, key, value, priorjob_id FROM peridot.jobpathconfigs WHERE job_id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"job_id", "type", "key", "value", "priorjob_id"}).
+			AddRow(7, 0, "mode", nil, nil).
+			AddRow(7, 1, "primary", nil, 4))
+	mock.ExpectQuery(`SELECT job_id, priorjob_id FROM peridot.jobpriorids WHERE job_id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"job_id", "priorjob_id"}).AddRow(7, 4))
+
+	// run the tested function
+	job, err := db.GetJobByID(7)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := &Job{
 		ID:          7,
 		RepoPullID:  14,
 		AgentID:     2,
-		PriorJobIDs: []uint32{4},
-		StartedAt:   time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC),
-		FinishedAt:  time.Date(2019, 5, 4, 12, 0, 1, 0, time.UTC),
+		PriorJobIDs: []JobID{4},
+		StartedAt:   startedAt,
+		FinishedAt:  startedAt,
 		Status:      StatusRunning,
-		Health:      HealthDegraded,
-		Output:      "unable to read file abc.xyz; skipping and continuing",
+		Health:      HealthOK,
 		IsReady:     true,
 		Config: JobConfig{
-			KV: map[string]string{},
-			CodeReader: map[string]JobPathConfig{
-				"primary": JobPathConfig{PriorJobID: 4},
-			},
+			KV:         map[string]string{"mode": ""},
+			CodeReader: map[string]JobPathConfig{"primary": JobPathConfig{PriorJobID: 4}},
 			SpdxReader: map[string]JobPathConfig{},
 		},
 	}
+	helperCompareJobs(t, want, job)
+}
 
-	// expect first call to get jobs, without configs or prior job IDs
-	sentRows1 := sqlmock.NewRows([]string{"id", "repopull_id", "agent_id", "started_at", "finished_at", "status", "health", "output", "is_ready"}).
-		AddRow(j7.ID, j7.RepoPullID, j7.AgentID, j7.StartedAt, j7.FinishedAt, j7.Status, j7.Health, j7.Output, j7.IsReady)
-	mock.ExpectQuery(`SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready FROM peridot.jobs WHERE id = \$1`).
-		WithArgs(7).
-		WillReturnRows(sentRows1)
-
-	// expect second call to get job configs for found job IDs
-	sentRows2 := sqlmock.NewRows([]string{"job_id", "type", "key", "value", "priorjob_id"}).
-		AddRow(7, 1, "primary", "", 4)
-	mock.ExpectQuery(`SELECT job_id, type, key, value, priorjob_id FROM peridot.jobpathconfigs WHERE job_id = \$1`).
-		WillReturnRows(sentRows2)
+func TestShouldFailGetJobByIDWithInvalidStatus(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
 
-	// and expect third call to get prior job IDs for found job IDs
-	sentRows3 := sqlmock.NewRows([]string{"job_id", "priorjob_id"}).
-		AddRow(7, 4)
-	mock.ExpectQuery(`SELECT job_id, priorjob_id FROM peridot.jobpriorids WHERE job_id = \$1`).
-		WillReturnRows(sentRows3)
+	startedAt := time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "repopull_id", "agent_id", "started_at", "finished_at", "status", "health", "output", "is_ready", "version", "created_at", "updated_at"}).
+		AddRow(7, 14, 2, startedAt, startedAt, 57, 1, "", true, 1, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready, version, created_at, updated_at FROM peridot.jobs WHERE id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sentRows)
 
 	// run the tested function
 	job, err := db.GetJobByID(7)
-	if err != nil {
-		t.Fatalf("expected nil error, got %v", err)
+	if job != nil {
+		t.Fatalf("expected nil job, got %v", job)
+	}
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
 	}
 
 	// check sqlmock expectations
@@ -256,9 +307,6 @@ func TestShouldGetJobByID(t *testing.T) {
 	if err != nil {
 		t.Errorf("unfulfilled expectations: %v", err)
 	}
-
-	// and check returned values
-	helperCompareJobs(t, &j7, job)
 }
 
 func TestShouldFailGetJobByIDForUnknownID(t *testing.T) {
@@ -270,7 +318,7 @@ func TestShouldFailGetJobByIDForUnknownID(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	mock.ExpectQuery(`SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready FROM peridot.jobs WHERE id = \$1`).
+	mock.ExpectQuery(`SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready, version, created_at, updated_at FROM peridot.jobs WHERE id = \$1`).
 		WithArgs(413).
 		WillReturnRows(sqlmock.NewRows([]string{}))
 
@@ -304,7 +352,7 @@ func TestShouldGetAllReadyJobs(t *testing.T) {
 		ID:          7,
 		RepoPullID:  12,
 		AgentID:     2,
-		PriorJobIDs: []uint32{4},
+		PriorJobIDs: []JobID{4},
 		StartedAt:   time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC),
 		FinishedAt:  time.Date(2019, 5, 4, 12, 0, 1, 0, time.UTC),
 		Status:      StatusStartup,
@@ -321,59 +369,20 @@ func TestShouldGetAllReadyJobs(t *testing.T) {
 	}
 
 	// expect actual first call to get job IDs only, for "ready" jobs
-	// note that the query matches job.go but has backslashes inserted where needed
-	readyJobsQuery := `
-SELECT id
-FROM \(
-	SELECT id, \(CASE WHEN any_prior_unready IS NULL THEN false ELSE any_prior_unready END\) AS any_prior_unready, status, health, is_ready
-	FROM peridot.jobs
-	LEFT JOIN \(
-		SELECT DISTINCT id, \(\(priorjob_status != 3\) OR \(priorjob_health = 3\)\) AS any_prior_unready
-		FROM \(
-			SELECT id, priorjob_id, any_prior_unready
-			FROM \(
-				SELECT
-					peridot.jobpriorids.id AS id,
-					peridot.jobpriorids.priorjob_id AS priorjob_id,
-					peridot.jobs.status AS priorjob_status,
-					peridot.jobs.health AS priorjob_health
-				FROM peridot.jobpriorids
-				LEFT JOIN peridot.jobs ON peridot.jobpriorids.priorjob_id=peridot.jobs.id\) calc1
-			\) calc2
-		WHERE EXISTS\(SELECT 1 WHERE any_prior_unready = true\)
-	\) calc3 ON peridot.jobs.id = id
-\) calc4
-WHERE any_prior_unready = false AND status = 1 AND health = 1 AND is_ready = true
-ORDER BY id
-LIMIT \$1;
-`
+	readyJobsQuery := `WITH candidates AS \( SELECT id FROM peridot.jobs WHERE status IN \(1, 4\) AND health = 1 AND is_ready = true AND repopull_id IN \(` + liveRepoPullIDsRegex + `\) \) SELECT c.id FROM candidates c WHERE NOT EXISTS \( SELECT 1 FROM peridot.jobpriorids p JOIN peridot.jobs pj ON pj.id = p.priorjob_id WHERE p.job_id = c.id AND \(pj.status <> 3 OR pj.health IN \(3, 4\)\) \) ORDER BY c.id LIMIT NULLIF\(\$1, 0\)`
 	sentRows0 := sqlmock.NewRows([]string{"id"}).
 		AddRow(j7.ID)
 	mock.ExpectQuery(readyJobsQuery).
 		WithArgs(0).
 		WillReturnRows(sentRows0)
 
-	// expect next call to get jobs, without configs or prior job IDs
-	sentRows1 := sqlmock.NewRows([]string{"id", "repopull_id", "agent_id", "started_at", "finished_at", "status", "health", "output", "is_ready"}).
-		AddRow(j7.ID, j7.RepoPullID, j7.AgentID, j7.StartedAt, j7.FinishedAt, j7.Status, j7.Health, j7.Output, j7.IsReady)
-	mock.ExpectQuery(`SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready FROM peridot.jobs WHERE id = ANY \(\$1\)`).
-		WithArgs(pq.Array([]uint32{7})).
+	// expect next call to get jobs, with configs and prior job IDs
+	sentRows1 := sqlmock.NewRows(jobsByIDsColumns).
+		AddRow(j7.ID, j7.RepoPullID, j7.AgentID, j7.StartedAt, j7.FinishedAt, j7.Status, j7.Health, j7.Output, j7.IsReady, j7.Version, testCreatedAt, testUpdatedAt, "{4}", "{1}", "{primary}", `{""}`, "{4}")
+	mock.ExpectQuery(jobsByIDsRegex).
+		WithArgs(pq.Array([]JobID{7})).
 		WillReturnRows(sentRows1)
 
-	// expect next call to get job configs for found job IDs
-	sentRows2 := sqlmock.NewRows([]string{"job_id", "type", "key", "value", "priorjob_id"}).
-		AddRow(7, 1, "primary", "", 4)
-	mock.ExpectQuery(`SELECT job_id, type, key, value, priorjob_id FROM peridot.jobpathconfigs WHERE job_id = ANY \(\$1\)`).
-		WithArgs(pq.Array([]uint32{7})).
-		WillReturnRows(sentRows2)
-
-	// and expect last call to get prior job IDs for found job IDs
-	sentRows3 := sqlmock.NewRows([]string{"job_id", "priorjob_id"}).
-		AddRow(7, 4)
-	mock.ExpectQuery(`SELECT job_id, priorjob_id FROM peridot.jobpriorids WHERE job_id = ANY \(\$1\)`).
-		WithArgs(pq.Array([]uint32{7})).
-		WillReturnRows(sentRows3)
-
 	// run the tested function
 	gotRows, err := db.GetReadyJobs(0)
 	if err != nil {
@@ -408,7 +417,7 @@ func TestShouldGetUpToNReadyJobs(t *testing.T) {
 		ID:          7,
 		RepoPullID:  12,
 		AgentID:     2,
-		PriorJobIDs: []uint32{4},
+		PriorJobIDs: []JobID{4},
 		StartedAt:   time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC),
 		FinishedAt:  time.Date(2019, 5, 4, 12, 0, 1, 0, time.UTC),
 		Status:      StatusStartup,
@@ -425,59 +434,20 @@ func TestShouldGetUpToNReadyJobs(t *testing.T) {
 	}
 
 	// expect actual first call to get job IDs only, for "ready" jobs
-	// note that the query matches job.go but has backslashes inserted where needed
-	readyJobsQuery := `
-SELECT id
-FROM \(
-	SELECT id, \(CASE WHEN any_prior_unready IS NULL THEN false ELSE any_prior_unready END\) AS any_prior_unready, status, health, is_ready
-	FROM peridot.jobs
-	LEFT JOIN \(
-		SELECT DISTINCT id, \(\(priorjob_status != 3\) OR \(priorjob_health = 3\)\) AS any_prior_unready
-		FROM \(
-			SELECT id, priorjob_id, any_prior_unready
-			FROM \(
-				SELECT
-					peridot.jobpriorids.id AS id,
-					peridot.jobpriorids.priorjob_id AS priorjob_id,
-					peridot.jobs.status AS priorjob_status,
-					peridot.jobs.health AS priorjob_health
-				FROM peridot.jobpriorids
-				LEFT JOIN peridot.jobs ON peridot.jobpriorids.priorjob_id=peridot.jobs.id\) calc1
-			\) calc2
-		WHERE EXISTS\(SELECT 1 WHERE any_prior_unready = true\)
-	\) calc3 ON peridot.jobs.id = id
-\) calc4
-WHERE any_prior_unready = false AND status = 1 AND health = 1 AND is_ready = true
-ORDER BY id
-LIMIT \$1;
-`
+	readyJobsQuery := `WITH candidates AS \( SELECT id FROM peridot.jobs WHERE status IN \(1, 4\) AND health = 1 AND is_ready = true AND repopull_id IN \(` + liveRepoPullIDsRegex + `\) \) SELECT c.id FROM candidates c WHERE NOT EXISTS \( SELECT 1 FROM peridot.jobpriorids p JOIN peridot.jobs pj ON pj.id = p.priorjob_id WHERE p.job_id = c.id AND \(pj.status <> 3 OR pj.health IN \(3, 4\)\) \) ORDER BY c.id LIMIT NULLIF\(\$1, 0\)`
 	sentRows0 := sqlmock.NewRows([]string{"id"}).
 		AddRow(j7.ID)
 	mock.ExpectQuery(readyJobsQuery).
 		WithArgs(3).
 		WillReturnRows(sentRows0)
 
-	// expect next call to get jobs, without configs or prior job IDs
-	sentRows1 := sqlmock.NewRows([]string{"id", "repopull_id", "agent_id", "started_at", "finished_at", "status", "health", "output", "is_ready"}).
-		AddRow(j7.ID, j7.RepoPullID, j7.AgentID, j7.StartedAt, j7.FinishedAt, j7.Status, j7.Health, j7.Output, j7.IsReady)
-	mock.ExpectQuery(`SELECT id, repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready FROM peridot.jobs WHERE id = ANY \(\$1\)`).
-		WithArgs(pq.Array([]uint32{7})).
+	// expect next call to get jobs, with configs and prior job IDs
+	sentRows1 := sqlmock.NewRows(jobsByIDsColumns).
+		AddRow(j7.ID, j7.RepoPullID, j7.AgentID, j7.StartedAt, j7.FinishedAt, j7.Status, j7.Health, j7.Output, j7.IsReady, j7.Version, testCreatedAt, testUpdatedAt, "{4}", "{1}", "{primary}", `{""}`, "{4}")
+	mock.ExpectQuery(jobsByIDsRegex).
+		WithArgs(pq.Array([]JobID{7})).
 		WillReturnRows(sentRows1)
 
-	// expect next call to get job configs for found job IDs
-	sentRows2 := sqlmock.NewRows([]string{"job_id", "type", "key", "value", "priorjob_id"}).
-		AddRow(7, 1, "primary", "", 4)
-	mock.ExpectQuery(`SELECT job_id, type, key, value, priorjob_id FROM peridot.jobpathconfigs WHERE job_id = ANY \(\$1\)`).
-		WithArgs(pq.Array([]uint32{7})).
-		WillReturnRows(sentRows2)
-
-	// and expect last call to get prior job IDs for found job IDs
-	sentRows3 := sqlmock.NewRows([]string{"job_id", "priorjob_id"}).
-		AddRow(7, 4)
-	mock.ExpectQuery(`SELECT job_id, priorjob_id FROM peridot.jobpriorids WHERE job_id = ANY \(\$1\)`).
-		WithArgs(pq.Array([]uint32{7})).
-		WillReturnRows(sentRows3)
-
 	// run the tested function
 	gotRows, err := db.GetReadyJobs(3)
 	if err != nil {
@@ -508,10 +478,14 @@ func TestShouldAddJobWithNoPriorJobs(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	jobStmt := `[INSERT INTO peridot.jobs(repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready) VALUES (\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(jobStmt)
 	mock.ExpectQuery(jobStmt).
 		WithArgs(15, 3, time.Time{}, time.Time{}, StatusStartup, HealthOK, "", false).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(24))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	jobID, err := db.AddJob(15, 3, nil)
@@ -542,6 +516,7 @@ func TestShouldAddJobWithPriorJobs(t *testing.T) {
 
 	// add to jobs table
 	jobStmt := `[INSERT INTO peridot.jobs(repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready) VALUES (\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(jobStmt)
 	mock.ExpectQuery(jobStmt).
 		WithArgs(15, 3, time.Time{}, time.Time{}, StatusStartup, HealthOK, "", false).
@@ -559,9 +534,12 @@ func TestShouldAddJobWithPriorJobs(t *testing.T) {
 	mock.ExpectExec(priorJobStmt).
 		WithArgs(24, 21).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
-	jobID, err := db.AddJob(15, 3, []uint32{18, 20, 21})
+	jobID, err := db.AddJob(15, 3, []JobID{18, 20, 21})
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -589,6 +567,7 @@ func TestShouldAddJobWithNoPriorJobsWithConfigs(t *testing.T) {
 
 	// add to jobs table
 	jobStmt := `[INSERT INTO peridot.jobs(repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready) VALUES (\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(jobStmt)
 	mock.ExpectQuery(jobStmt).
 		WithArgs(15, 3, time.Time{}, time.Time{}, StatusStartup, HealthOK, "", false).
@@ -612,6 +591,9 @@ func TestShouldAddJobWithNoPriorJobsWithConfigs(t *testing.T) {
 	mock.ExpectExec(configStmt).
 		WithArgs(24, 2, "primary", "", 4).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// set configs
 	configKV := map[string]string{
@@ -655,6 +637,7 @@ func TestShouldAddJobWithPriorJobsAndConfigs(t *testing.T) {
 
 	// add to jobs table
 	jobStmt := `[INSERT INTO peridot.jobs(repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready) VALUES (\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(jobStmt)
 	mock.ExpectQuery(jobStmt).
 		WithArgs(15, 3, time.Time{}, time.Time{}, StatusStartup, HealthOK, "", false).
@@ -691,6 +674,9 @@ func TestShouldAddJobWithPriorJobsAndConfigs(t *testing.T) {
 	mock.ExpectExec(configStmt).
 		WithArgs(24, 2, "primary", "", 4).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// set configs
 	configKV := map[string]string{
@@ -706,7 +692,7 @@ func TestShouldAddJobWithPriorJobsAndConfigs(t *testing.T) {
 	}
 
 	// run the tested function
-	jobID, err := db.AddJobWithConfigs(15, 3, []uint32{18, 20, 21}, configKV, configCodeReader, configSpdxReader)
+	jobID, err := db.AddJobWithConfigs(15, 3, []JobID{18, 20, 21}, configKV, configCodeReader, configSpdxReader)
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -734,6 +720,7 @@ func TestShouldAddJobWithPriorJobsAndOnlySomeConfigs(t *testing.T) {
 
 	// add to jobs table
 	jobStmt := `[INSERT INTO peridot.jobs(repopull_id, agent_id, started_at, finished_at, status, health, output, is_ready) VALUES (\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(jobStmt)
 	mock.ExpectQuery(jobStmt).
 		WithArgs(15, 3, time.Time{}, time.Time{}, StatusStartup, HealthOK, "", false).
@@ -764,6 +751,9 @@ func TestShouldAddJobWithPriorJobsAndOnlySomeConfigs(t *testing.T) {
 	mock.ExpectExec(configStmt).
 		WithArgs(24, 2, "primary", "", 4).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// set configs
 	configKV := map[string]string{
@@ -776,7 +766,7 @@ func TestShouldAddJobWithPriorJobsAndOnlySomeConfigs(t *testing.T) {
 	}
 
 	// run the tested function
-	jobID, err := db.AddJobWithConfigs(15, 3, []uint32{18, 20, 21}, configKV, configCodeReader, configSpdxReader)
+	jobID, err := db.AddJobWithConfigs(15, 3, []JobID{18, 20, 21}, configKV, configCodeReader, configSpdxReader)
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -803,11 +793,15 @@ func TestShouldUpdateJobIsReady(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.job SET is_ready = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.jobs"
 	mock.ExpectExec(stmt).
 		WithArgs(true, 12).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.UpdateJobIsReady(12, true)
@@ -831,12 +825,14 @@ func TestShouldFailUpdateJobIsReadyWithUnknownID(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	regexStmt := `[UPDATE peridot.jobs SET is_ready = \$1 WHERE id = \$2]`
+	regexStmt := `[UPDATE peridot.jobs SET is_ready = \$1, version = version \+ 1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.jobs"
 	mock.ExpectExec(stmt).
 		WithArgs(false, 413).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	// run the tested function with an unknown project ID number
 	err = db.UpdateJobIsReady(413, false)
@@ -863,15 +859,18 @@ func TestShouldUpdateJobStatus(t *testing.T) {
 	start := time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC)
 	finish := time.Date(2019, 5, 4, 12, 0, 1, 0, time.UTC)
 
-	regexStmt := `[UPDATE peridot.job SET started_at = \$1, finished_at = \$2, status = \$3, health = \$4, output = \$5 WHERE id = \$6]`
+	regexStmt := `UPDATE peridot.jobs SET started_at = \$1, finished_at = \$2, status = \$3, health = \$4, output = \$5, version = version \+ 1 WHERE id = \$6 AND \(\$7 = 0 OR version = \$7\) AND COALESCE\(status, 0\) = ANY\(\$8\)`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
-	stmt := "UPDATE peridot.jobs"
-	mock.ExpectExec(stmt).
-		WithArgs(start, finish, StatusRunning, HealthDegraded, "unable to open some files", 12).
+	mock.ExpectExec(regexStmt).
+		WithArgs(start, finish, StatusRunning, HealthDegraded, "unable to open some files", 12, 0, "{0,1,2,4}").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
-	err = db.UpdateJobStatus(12, start, finish, StatusRunning, HealthDegraded, "unable to open some files")
+	err = db.UpdateJobStatus(12, 0, start, finish, StatusRunning, HealthDegraded, "unable to open some files")
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -896,16 +895,54 @@ func TestShouldFailUpdateJobStatusWithUnknownID(t *testing.T) {
 	finish := time.Date(2019, 5, 4, 12, 0, 1, 0, time.UTC)
 
 	regexStmt := `[UPDATE peridot.job SET started_at = \$1, finished_at = \$2, status = \$3, health = \$4, output = \$5 WHERE id = \$6]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.jobs"
 	mock.ExpectExec(stmt).
-		WithArgs(start, finish, StatusRunning, HealthDegraded, "unable to open some files", 413).
+		WithArgs(start, finish, StatusRunning, HealthDegraded, "unable to open some files", 413, 2, "{0,1,2,4}").
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT COALESCE\(status, 0\) FROM peridot.jobs WHERE id = \$1`).
+		WithArgs(413).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}))
 
 	// run the tested function with an unknown project ID number
-	err = db.UpdateJobStatus(413, start, finish, StatusRunning, HealthDegraded, "unable to open some files")
-	if err == nil {
-		t.Fatalf("expected non-nil error, got nil")
+	err = db.UpdateJobStatus(413, 2, start, finish, StatusRunning, HealthDegraded, "unable to open some files")
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+func TestShouldFailUpdateJobStatusWithStaleVersion(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	start := time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE peridot.jobs")
+	mock.ExpectExec("UPDATE peridot.jobs").
+		WithArgs(start, time.Time{}, StatusRunning, HealthOK, "", 12, 2, "{0,1,2,4}").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT COALESCE\(status, 0\) FROM peridot.jobs WHERE id = \$1`).
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(2))
+
+	// run the tested function
+	err = db.UpdateJobStatus(12, 2, start, time.Time{}, StatusRunning, HealthOK, "")
+	if _, ok := err.(*ConflictError); !ok {
+		t.Fatalf("expected *ConflictError, got %v", err)
 	}
 
 	// check sqlmock expectations
@@ -914,6 +951,126 @@ func TestShouldFailUpdateJobStatusWithUnknownID(t *testing.T) {
 		t.Errorf("unfulfilled expectations: %v", err)
 	}
 }
+
+func TestShouldFailUpdateJobStatusWithInvalidTransition(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	start := time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE peridot.jobs")
+	mock.ExpectExec("UPDATE peridot.jobs").
+		WithArgs(start, time.Time{}, StatusRunning, HealthOK, "", 12, 0, "{0,1,2,4}").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT COALESCE\(status, 0\) FROM peridot.jobs WHERE id = \$1`).
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(3))
+
+	// run the tested function, trying to restart a stopped job
+	err = db.UpdateJobStatus(12, 0, start, time.Time{}, StatusRunning, HealthOK, "")
+	te, ok := err.(*TransitionError)
+	if !ok {
+		t.Fatalf("expected *TransitionError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if te.From != StatusStopped || te.To != StatusRunning {
+		t.Errorf("expected transition %v to %v, got %v to %v", StatusStopped, StatusRunning, te.From, te.To)
+	}
+}
+
+func TestShouldUpdateJobStatusesAndReportFailures(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	start := time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC)
+	finish := time.Date(2019, 5, 4, 12, 0, 1, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE peridot.jobs j SET (.+) FROM \(VALUES \(\$1::integer, (.+)\), \((.+), \$24::integer\[\]\)\) AS v\(id, version, started_at, finished_at, status, health, output, preds\) WHERE j.id = v.id (.+) RETURNING j.id`).
+		WithArgs(12, 0, start, finish, StatusStopped, HealthOK, "done", "{0,1,2,3}",
+			413, 0, start, finish, StatusStopped, HealthOK, "done", "{0,1,2,3}",
+			14, 0, start, time.Time{}, StatusRunning, HealthOK, "", "{0,1,2,4}").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("job", "12", AuditActionUpdate, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT id, COALESCE\(status, 0\) FROM peridot.jobs WHERE id = ANY\(\$1\)`).
+		WithArgs("{413,14}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(14, 3))
+	mock.ExpectCommit()
+
+	// run the tested function
+	failed, err := db.UpdateJobStatuses([]JobStatusUpdate{
+		{ID: 12, StartedAt: start, FinishedAt: finish, Status: StatusStopped, Health: HealthOK, Output: "done"},
+		{ID: 413, StartedAt: start, FinishedAt: finish, Status: StatusStopped, Health: HealthOK, Output: "done"},
+		{ID: 14, StartedAt: start, Status: StatusRunning, Health: HealthOK},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(failed) != 2 {
+		t.Fatalf("expected %d failures, got %d: %v", 2, len(failed), failed)
+	}
+	if !errors.Is(failed[413], ErrNotFound) {
+		t.Errorf("expected not found error for job 413, got %v", failed[413])
+	}
+	if !errors.Is(failed[14], ErrInvalidTransition) {
+		t.Errorf("expected invalid transition error for job 14, got %v", failed[14])
+	}
+}
+
+func TestShouldFailUpdateJobStatusesWithDuplicateID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	_, err = db.UpdateJobStatuses([]JobStatusUpdate{
+		{ID: 12, Status: StatusRunning, Health: HealthOK},
+		{ID: 12, Status: StatusStopped, Health: HealthOK},
+	})
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestShouldDeleteJob(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
@@ -924,11 +1081,15 @@ func TestShouldDeleteJob(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[DELETE FROM peridot.jobs WHERE id = \$1]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "DELETE FROM peridot.jobs"
 	mock.ExpectExec(stmt).
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.DeleteJob(1)
@@ -953,11 +1114,13 @@ func TestShouldFailDeleteJobWithUnknownID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[DELETE FROM peridot.jobs WHERE id = \$1]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "DELETE FROM peridot.jobs"
 	mock.ExpectExec(stmt).
 		WithArgs(413).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	// run the tested function
 	err = db.DeleteJob(413)
@@ -978,7 +1141,7 @@ func TestCanMarshalJobWithEmptyConfigsAndNoPriorJobIDsToJSON(t *testing.T) {
 		ID:          4,
 		RepoPullID:  14,
 		AgentID:     6,
-		PriorJobIDs: []uint32{},
+		PriorJobIDs: []JobID{},
 		StartedAt:   time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC),
 		FinishedAt:  time.Date(2019, 5, 2, 13, 54, 17, 0, time.UTC),
 		Status:      StatusStopped,
@@ -1042,7 +1205,7 @@ func TestCanMarshalJobWithConfigsAndPriorJobIDsToJSON(t *testing.T) {
 		ID:          4,
 		RepoPullID:  14,
 		AgentID:     6,
-		PriorJobIDs: []uint32{2, 3},
+		PriorJobIDs: []JobID{2, 3},
 		StartedAt:   time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC),
 		FinishedAt:  time.Date(2019, 5, 2, 13, 54, 17, 0, time.UTC),
 		Status:      StatusStopped,
@@ -1111,10 +1274,10 @@ func TestCanMarshalJobWithConfigsAndPriorJobIDsToJSON(t *testing.T) {
 	if len(j.PriorJobIDs) != len(priorJobIDs) {
 		t.Errorf("expected len %v, got %v", len(j.PriorJobIDs), len(priorJobIDs))
 	}
-	if j.PriorJobIDs[0] != uint32(priorJobIDs[0].(float64)) {
+	if j.PriorJobIDs[0] != JobID(priorJobIDs[0].(float64)) {
 		t.Errorf("expected len %v, got %v", j.PriorJobIDs[0], uint32(priorJobIDs[0].(float64)))
 	}
-	if j.PriorJobIDs[1] != uint32(priorJobIDs[1].(float64)) {
+	if j.PriorJobIDs[1] != JobID(priorJobIDs[1].(float64)) {
 		t.Errorf("expected len %v, got %v", j.PriorJobIDs[1], uint32(priorJobIDs[1].(float64)))
 	}
 
@@ -1437,3 +1600,290 @@ func helperCompareJobs(t *testing.T, expected *Job, got *Job) {
 		}
 	}
 }
+
+func TestJobPathConfigValidate(t *testing.T) {
+	tests := []struct {
+		pc      JobPathConfig
+		isError bool
+	}{
+		{JobPathConfig{Value: "/spdx/prior.spdx"}, false},
+		{JobPathConfig{PriorJobID: 4}, false},
+		// invalid values should fail
+		{JobPathConfig{Value: "/spdx/prior.spdx", PriorJobID: 4}, true},
+		{JobPathConfig{}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.pc.Validate()
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("for %+v: expected isError %v, got %v", tt.pc, tt.isError, err)
+		}
+	}
+}
+
+func TestShouldFailAddJobWithConfigsWithAmbiguousPathConfig(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	configSpdxReader := map[string]JobPathConfig{
+		"primary": JobPathConfig{Value: "/spdx/prior.spdx", PriorJobID: 4},
+	}
+
+	// run the tested function; no queries should be made
+	_, err = db.AddJobWithConfigs(1, 3, nil, nil, nil, configSpdxReader)
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestJobJSONRoundTripsStartedButUnfinishedJob(t *testing.T) {
+	startedAt := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	j := Job{ID: 4, RepoPullID: 14, AgentID: 6, StartedAt: startedAt, Status: StatusRunning, Health: HealthOK}
+
+	js, err := json.Marshal(j)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+
+	// a running job has a start time but no finish time yet
+	var mGot map[string]interface{}
+	err = json.Unmarshal(js, &mGot)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if mGot["started_at"] != "2019-05-02T13:53:41Z" {
+		t.Errorf("expected %v, got %v", "2019-05-02T13:53:41Z", mGot["started_at"])
+	}
+	if _, ok := mGot["finished_at"]; ok {
+		t.Errorf("expected no finished_at key, got %v", mGot["finished_at"])
+	}
+
+	// and reading it back gives the same times
+	j2 := &Job{}
+	err = json.Unmarshal(js, j2)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if !j2.StartedAt.Equal(startedAt) {
+		t.Errorf("expected %v, got %v", startedAt, j2.StartedAt)
+	}
+	if !j2.FinishedAt.IsZero() {
+		t.Errorf("expected zero time, got %v", j2.FinishedAt)
+	}
+}
+
+func TestCanUnmarshalJobWithNullStartedAt(t *testing.T) {
+	j := &Job{}
+	js := []byte(`{"id":17, "repopull_id":3, "agent_id":8, "started_at":null, "status":"startup", "health":"ok"}`)
+
+	err := json.Unmarshal(js, j)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if !j.StartedAt.IsZero() {
+		t.Errorf("expected zero time, got %v", j.StartedAt)
+	}
+	if j.ID != 17 || j.AgentID != 8 {
+		t.Errorf("expected job 17 for agent 8, got %+v", j)
+	}
+}
+
+func TestCanCloneJob(t *testing.T) {
+	j := &Job{
+		ID:          4,
+		PriorJobIDs: []JobID{1, 2},
+		Config: JobConfig{
+			KV:         map[string]string{"hello": "world"},
+			CodeReader: map[string]JobPathConfig{"primary": JobPathConfig{PriorJobID: 1}},
+		},
+	}
+
+	c := j.Clone()
+	if !reflect.DeepEqual(j, c) {
+		t.Fatalf("expected %#v, got %#v", j, c)
+	}
+
+	// modifying the clone should not affect the original
+	c.PriorJobIDs[0] = 17
+	c.Config.KV["hello"] = "there"
+	c.Config.CodeReader["primary"] = JobPathConfig{Value: "/code/"}
+	if j.PriorJobIDs[0] != 1 {
+		t.Errorf("expected %v, got %v", 1, j.PriorJobIDs[0])
+	}
+	if j.Config.KV["hello"] != "world" {
+		t.Errorf("expected %v, got %v", "world", j.Config.KV["hello"])
+	}
+	if j.Config.CodeReader["primary"].PriorJobID != 1 {
+		t.Errorf("expected %v, got %v", 1, j.Config.CodeReader["primary"].PriorJobID)
+	}
+
+	// nil maps should remain nil
+	if c.Config.SpdxReader != nil {
+		t.Errorf("expected nil map, got %v", c.Config.SpdxReader)
+	}
+
+	var nilJob *Job
+	if nilJob.Clone() != nil {
+		t.Errorf("expected nil clone of nil job")
+	}
+}
+
+func TestJobEqual(t *testing.T) {
+	sa := time.Date(2019, 5, 2, 13, 53, 41, 1234000, time.UTC)
+	base := &Job{
+		ID:          4,
+		RepoPullID:  14,
+		AgentID:     6,
+		PriorJobIDs: []JobID{1, 2},
+		StartedAt:   sa,
+		Status:      StatusRunning,
+		Health:      HealthOK,
+		Config: JobConfig{
+			KV:         map[string]string{"hello": "world"},
+			CodeReader: map[string]JobPathConfig{"primary": JobPathConfig{PriorJobID: 1}},
+		},
+	}
+
+	// reordered prior IDs, sub-microsecond time differences, other
+	// time zones and empty rather than nil maps should be equal
+	same := base.Clone()
+	same.PriorJobIDs = []JobID{2, 1}
+	same.StartedAt = sa.Add(300 * time.Nanosecond).In(time.FixedZone("EST", -5*60*60))
+	same.Config.SpdxReader = map[string]JobPathConfig{}
+	if !base.Equal(same) {
+		t.Errorf("expected %#v to equal %#v", base, same)
+	}
+
+	tests := []func(j *Job){
+		func(j *Job) { j.PriorJobIDs = []JobID{1, 3} },
+		func(j *Job) { j.StartedAt = sa.Add(time.Microsecond) },
+		func(j *Job) { j.Status = StatusStopped },
+		func(j *Job) { j.Config.KV["hello"] = "there" },
+		func(j *Job) { j.Config.CodeReader["primary"] = JobPathConfig{Value: "/code/"} },
+		func(j *Job) { j.Config.SpdxReader = map[string]JobPathConfig{"primary": JobPathConfig{PriorJobID: 1}} },
+	}
+	for i, modify := range tests {
+		other := base.Clone()
+		modify(other)
+		if base.Equal(other) {
+			t.Errorf("for test %d: expected %#v not to equal %#v", i, base, other)
+		}
+	}
+
+	var nilJob *Job
+	if !nilJob.Equal(nil) {
+		t.Errorf("expected nil jobs to be equal")
+	}
+	if base.Equal(nil) {
+		t.Errorf("expected non-nil job not to equal nil")
+	}
+}
+
+// BenchmarkGetJobsByIDs measures GetJobsByIDs for a dispatch-sized
+// batch of jobs, with each query delayed to simulate a database
+// round trip, so that the number of round trips dominates.
+func BenchmarkGetJobsByIDs(b *testing.B) {
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		b.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	const roundTrip = time.Millisecond
+	ids := []JobID{}
+	for i := 1; i <= 20; i++ {
+		ids = append(ids, JobID(i))
+	}
+	startedAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		sentRows := sqlmock.NewRows(jobsByIDsColumns)
+		for _, id := range ids {
+			sentRows.AddRow(id, 7, 6, startedAt, startedAt, StatusQueued, HealthOK, "", true, 1, "{1}", "{0,1}", "{hello,primary}", `{world,""}`, "{0,1}")
+		}
+		mock.ExpectQuery(jobsByIDsRegex).
+			WillDelayFor(roundTrip).
+			WillReturnRows(sentRows)
+		b.StartTimer()
+
+		_, err := db.GetJobsByIDs(ids)
+		if err != nil {
+			b.Fatalf("expected nil error, got %v", err)
+		}
+	}
+}
+
+func TestShouldCountJobsForRepoPull(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM peridot.jobs WHERE repopull_id = \$1 AND repopull_id IN \(` + liveRepoPullIDsRegex + `\)`).
+		WithArgs(14).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	// run the tested function
+	n, err := db.CountJobsForRepoPull(14)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if n != 3 {
+		t.Errorf("expected %v, got %v", 3, n)
+	}
+}
+
+func TestShouldReportJobDoesNotExist(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM peridot.jobs WHERE id = \$1 AND repopull_id IN \(` + liveRepoPullIDsRegex + `\)\)`).
+		WithArgs(413).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	// run the tested function
+	ok, err := db.ExistsJob(413)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if ok != false {
+		t.Errorf("expected %v, got %v", false, ok)
+	}
+}
@@ -2,10 +2,19 @@
 
 package datastore
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
 
 // JobConfigType defines whether the JobConfig is a key-value
-// config, or a codereader or spdxreader input.
+// config, or a codereader or spdxreader input. Agents may also
+// register their own config types with RegisterJobConfigType.
+//
+// A JobConfigType is encoded in JSON as its string value, e.g.
+// "codereader".
 type JobConfigType int
 
 const (
@@ -19,8 +28,66 @@ const (
 	// JobConfigSpdxReader means this JobConfig entry is
 	// for an spdxreader value.
 	JobConfigSpdxReader JobConfigType = 2
+
+	// FirstCustomJobConfigType is the lowest value that can be
+	// registered with RegisterJobConfigType. Lower values are
+	// reserved for the types defined by peridot.
+	FirstCustomJobConfigType JobConfigType = 100
+)
+
+// customJobConfigTypes holds the types registered with
+// RegisterJobConfigType, mapped to their string values.
+var (
+	customJobConfigTypesMu sync.RWMutex
+	customJobConfigTypes   = map[JobConfigType]string{}
 )
 
+// RegisterJobConfigType registers a custom JobConfigType with the
+// given value and string encoding, so that it can be converted to
+// and from integers, strings and JSON like the built-in types. The
+// value must be at least FirstCustomJobConfigType, and neither the
+// value nor the name may already be in use. It is intended to be
+// called during initialization, e.g. from an agent's init function.
+//
+// Note that Job.Config only holds the built-in types; custom types
+// are for agents exchanging configuration of their own.
+func RegisterJobConfigType(jct JobConfigType, name string) error {
+	if jct < FirstCustomJobConfigType {
+		return fmt.Errorf("custom job config type %d must be at least %d", jct, FirstCustomJobConfigType)
+	}
+	if name == "" {
+		return fmt.Errorf("custom job config type %d must have a name", jct)
+	}
+
+	customJobConfigTypesMu.Lock()
+	defer customJobConfigTypesMu.Unlock()
+
+	if existing, ok := customJobConfigTypes[jct]; ok {
+		return fmt.Errorf("job config type %d is already registered as %s", jct, existing)
+	}
+	if _, err := jobConfigTypeFromStringLocked(name); err == nil {
+		return fmt.Errorf("job config type string %s is already in use", name)
+	}
+	customJobConfigTypes[jct] = name
+	return nil
+}
+
+// JobConfigTypes returns all valid JobConfigType values, built-in
+// and registered, in increasing order.
+func JobConfigTypes() []JobConfigType {
+	jcts := []JobConfigType{JobConfigKV, JobConfigCodeReader, JobConfigSpdxReader}
+
+	customJobConfigTypesMu.RLock()
+	custom := make([]JobConfigType, 0, len(customJobConfigTypes))
+	for jct := range customJobConfigTypes {
+		custom = append(custom, jct)
+	}
+	customJobConfigTypesMu.RUnlock()
+
+	sort.Slice(custom, func(i, j int) bool { return custom[i] < custom[j] })
+	return append(jcts, custom...)
+}
+
 // JobConfigTypeFromInt converts an integer to its corresponding
 // JobConfigType value. It returns that value or an error if the
 // integer is invalid.
@@ -34,6 +101,12 @@ func JobConfigTypeFromInt(jctInt int) (JobConfigType, error) {
 		return JobConfigSpdxReader, nil
 	}
 
+	customJobConfigTypesMu.RLock()
+	defer customJobConfigTypesMu.RUnlock()
+	if _, ok := customJobConfigTypes[JobConfigType(jctInt)]; ok {
+		return JobConfigType(jctInt), nil
+	}
+
 	return JobConfigKV, fmt.Errorf("invalid job config type integer %d", jctInt)
 }
 
@@ -49,8 +122,102 @@ func IntFromJobConfigType(jct JobConfigType) int {
 		return 2
 	}
 
-	// shouldn't be possible to fall through since all values
-	// are captured above, but we'll return 0 here because go
-	// requires a final return
+	customJobConfigTypesMu.RLock()
+	defer customJobConfigTypesMu.RUnlock()
+	if _, ok := customJobConfigTypes[jct]; ok {
+		return int(jct)
+	}
+
+	// unregistered values fall through to 0, as for the other
+	// enumerated types
 	return 0
 }
+
+// JobConfigTypeFromString converts a string to its corresponding
+// JobConfigType value. It returns that value or an error if the
+// string is invalid.
+func JobConfigTypeFromString(jctStr string) (JobConfigType, error) {
+	customJobConfigTypesMu.RLock()
+	defer customJobConfigTypesMu.RUnlock()
+	return jobConfigTypeFromStringLocked(jctStr)
+}
+
+// jobConfigTypeFromStringLocked implements JobConfigTypeFromString.
+// The caller must hold customJobConfigTypesMu.
+func jobConfigTypeFromStringLocked(jctStr string) (JobConfigType, error) {
+	switch jctStr {
+	case "kv":
+		return JobConfigKV, nil
+	case "codereader":
+		return JobConfigCodeReader, nil
+	case "spdxreader":
+		return JobConfigSpdxReader, nil
+	}
+
+	for jct, name := range customJobConfigTypes {
+		if name == jctStr {
+			return jct, nil
+		}
+	}
+
+	return JobConfigKV, fmt.Errorf("invalid job config type string %s", jctStr)
+}
+
+// StringFromJobConfigType converts a JobConfigType value to its
+// corresponding string value.
+func StringFromJobConfigType(jct JobConfigType) string {
+	switch jct {
+	case JobConfigKV:
+		return "kv"
+	case JobConfigCodeReader:
+		return "codereader"
+	case JobConfigSpdxReader:
+		return "spdxreader"
+	}
+
+	customJobConfigTypesMu.RLock()
+	defer customJobConfigTypesMu.RUnlock()
+	if name, ok := customJobConfigTypes[jct]; ok {
+		return name
+	}
+
+	// unregistered values fall through to kv, as for the other
+	// enumerated types
+	return "kv"
+}
+
+// String returns the name of the config section, such as
+// "codereader", as given by StringFromJobConfigType.
+func (jct JobConfigType) String() string {
+	return StringFromJobConfigType(jct)
+}
+
+// MarshalJSON converts the JobConfigType value into a slice of bytes
+// containing the string encoding of the job config type. It returns
+// an error if the value is neither built-in nor registered.
+func (jct JobConfigType) MarshalJSON() ([]byte, error) {
+	if _, err := JobConfigTypeFromInt(int(jct)); err != nil {
+		return nil, err
+	}
+	return json.Marshal(StringFromJobConfigType(jct))
+}
+
+// UnmarshalJSON converts a slice of bytes containing the string
+// encoding of the job config type into the corresponding
+// JobConfigType value.
+func (jct *JobConfigType) UnmarshalJSON(b []byte) error {
+	var s string
+
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	jctVal, err := JobConfigTypeFromString(s)
+	if err != nil {
+		return err
+	}
+
+	*jct = jctVal
+	return nil
+}
@@ -2,7 +2,11 @@
 
 package datastore
 
-import "testing"
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
 
 func TestCanChangeIntToJobConfigType(t *testing.T) {
 	tests := []struct {
@@ -45,3 +49,158 @@ func TestCanChangeJobConfigTypeToInt(t *testing.T) {
 		}
 	}
 }
+
+func TestCanChangeJobConfigTypeToString(t *testing.T) {
+	tests := []struct {
+		in   JobConfigType
+		want string
+	}{
+		{JobConfigKV, "kv"},
+		{JobConfigCodeReader, "codereader"},
+		{JobConfigSpdxReader, "spdxreader"},
+	}
+
+	for _, tt := range tests {
+		got := StringFromJobConfigType(tt.in)
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestCanFormatJobConfigType(t *testing.T) {
+	tests := []struct {
+		in   JobConfigType
+		want string
+	}{
+		{JobConfigKV, "kv"},
+		{JobConfigSpdxReader, "spdxreader"},
+	}
+
+	for _, tt := range tests {
+		got := fmt.Sprintf("%v", tt.in)
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestCanChangeStringToJobConfigType(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    JobConfigType
+		isError bool
+	}{
+		{"kv", JobConfigKV, false},
+		{"codereader", JobConfigCodeReader, false},
+		{"spdxreader", JobConfigSpdxReader, false},
+		// invalid values should return JobConfigKV
+		{"oops", JobConfigKV, true},
+	}
+
+	for _, tt := range tests {
+		got, err := JobConfigTypeFromString(tt.in)
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestCanMarshalJobConfigTypeToJSON(t *testing.T) {
+	gotBytes, err := json.Marshal(JobConfigCodeReader)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	got := string(gotBytes)
+	want := "\"codereader\""
+	if got != want {
+		t.Errorf("expected %T %v, got %T %v", want, want, got, got)
+	}
+
+	_, err = json.Marshal(JobConfigType(57))
+	if err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+}
+
+func TestCanUnmarshalJSONToJobConfigType(t *testing.T) {
+	var got JobConfigType
+	err := json.Unmarshal([]byte(`"spdxreader"`), &got)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if got != JobConfigSpdxReader {
+		t.Errorf("expected %v, got %v", JobConfigSpdxReader, got)
+	}
+
+	err = json.Unmarshal([]byte(`"oops"`), &got)
+	if err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+}
+
+func TestCanRegisterJobConfigType(t *testing.T) {
+	custom := JobConfigType(117)
+	err := RegisterJobConfigType(custom, "licensescanner")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	got, err := JobConfigTypeFromInt(117)
+	if err != nil || got != custom {
+		t.Errorf("expected %v, nil; got %v, %v", custom, got, err)
+	}
+	if IntFromJobConfigType(custom) != 117 {
+		t.Errorf("expected %v, got %v", 117, IntFromJobConfigType(custom))
+	}
+	got, err = JobConfigTypeFromString("licensescanner")
+	if err != nil || got != custom {
+		t.Errorf("expected %v, nil; got %v, %v", custom, got, err)
+	}
+
+	gotBytes, err := json.Marshal(custom)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if string(gotBytes) != `"licensescanner"` {
+		t.Errorf("expected %v, got %v", `"licensescanner"`, string(gotBytes))
+	}
+
+	jcts := JobConfigTypes()
+	if len(jcts) < 4 || jcts[0] != JobConfigKV || jcts[len(jcts)-1] < custom {
+		t.Errorf("expected built-in types followed by %v, got %v", custom, jcts)
+	}
+}
+
+func TestShouldFailRegisterInvalidJobConfigType(t *testing.T) {
+	err := RegisterJobConfigType(118, "duplicate")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	tests := []struct {
+		jct  JobConfigType
+		name string
+	}{
+		// reserved value
+		{JobConfigType(3), "reserved"},
+		// empty name
+		{JobConfigType(119), ""},
+		// value already registered
+		{JobConfigType(118), "another"},
+		// name already registered
+		{JobConfigType(120), "duplicate"},
+		// name of a built-in type
+		{JobConfigType(121), "codereader"},
+	}
+
+	for _, tt := range tests {
+		err := RegisterJobConfigType(tt.jct, tt.name)
+		if err == nil {
+			t.Errorf("expected non-nil error for %d %q, got nil", tt.jct, tt.name)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"github.com/lib/pq"
+)
+
+// JobGraph is the dependency graph of the Jobs for a RepoPull, as a
+// list of nodes and a list of edges, the form that graph
+// visualization libraries generally take.
+type JobGraph struct {
+	// RepoPullID is the ID of the repo pull.
+	RepoPullID RepoPullID `json:"repopull_id"`
+	// Nodes are the repo pull's jobs, ordered by ID.
+	Nodes []*JobGraphNode `json:"nodes"`
+	// Edges are the dependencies between the jobs, ordered by
+	// the job that depends on the other and then by the other.
+	Edges []JobGraphEdge `json:"edges"`
+}
+
+// JobGraphNode is a Job within a JobGraph, with just enough of the
+// job's details to label and color it.
+type JobGraphNode struct {
+	// ID is the unique ID for the job.
+	ID JobID `json:"id"`
+	// AgentID is the ID of the agent that runs the job.
+	AgentID AgentID `json:"agent_id"`
+	// Status is the run status of the job.
+	Status Status `json:"status"`
+	// Health is the health of the job.
+	Health Health `json:"health"`
+	// IsReady is whether the job is ready to be run.
+	IsReady bool `json:"is_ready"`
+}
+
+// JobGraphEdge is a dependency within a JobGraph, pointing from a
+// prior job to a job that must wait for it to finish.
+type JobGraphEdge struct {
+	// From is the ID of the prior job.
+	From JobID `json:"from"`
+	// To is the ID of the job that depends on it.
+	To JobID `json:"to"`
+}
+
+// GetJobGraphForRepoPull returns the JobGraph of the jobs for the
+// RepoPull with the given ID, fetching the jobs and their prior job
+// IDs in a single query. A repo pull with no jobs, or none at all,
+// has a graph with no nodes or edges.
+func (db *DB) GetJobGraphForRepoPull(rpID RepoPullID) (*JobGraph, error) {
+	rows, err := db.sqldb.Query(`
+		SELECT j.id, j.agent_id, j.status, j.health, j.is_ready, COALESCE(p.priorjob_ids, '{}')
+		FROM peridot.jobs j
+		LEFT JOIN LATERAL (
+			SELECT array_agg(priorjob_id ORDER BY priorjob_id) AS priorjob_ids
+			FROM peridot.jobpriorids
+			WHERE job_id = j.id
+		) p ON true
+		WHERE j.repopull_id = $1 AND j.repopull_id IN (`+liveRepoPullIDs+`)
+		ORDER BY j.id`, rpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	g := &JobGraph{RepoPullID: rpID, Nodes: []*JobGraphNode{}, Edges: []JobGraphEdge{}}
+	for rows.Next() {
+		n := &JobGraphNode{}
+		var priorJobIDs []int64
+		err = rows.Scan(&n.ID, &n.AgentID, &n.Status, &n.Health, &n.IsReady, pq.Array(&priorJobIDs))
+		if err != nil {
+			return nil, err
+		}
+		g.Nodes = append(g.Nodes, n)
+		for _, pjid := range priorJobIDs {
+			g.Edges = append(g.Edges, JobGraphEdge{From: JobID(pjid), To: n.ID})
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetJobGraphForRepoPull(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "agent_id", "status", "health", "is_ready", "priorjob_ids"}).
+		AddRow(4, 1, StatusStopped, HealthOK, true, "{}").
+		AddRow(5, 1, StatusStopped, HealthOK, true, "{}").
+		AddRow(7, 2, StatusRunning, HealthOK, true, "{4,5}")
+	mock.ExpectQuery(`SELECT j.id, j.agent_id, j.status, j.health, j.is_ready, COALESCE\(p.priorjob_ids, '{}'\) FROM peridot.jobs j LEFT JOIN LATERAL \(.*FROM peridot.jobpriorids.*\) p ON true WHERE j.repopull_id = \$1 AND j.repopull_id IN \(` + liveRepoPullIDsRegex + `\) ORDER BY j.id`).
+		WithArgs(14).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	g, err := db.GetJobGraphForRepoPull(14)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantNodes := []*JobGraphNode{
+		{ID: 4, AgentID: 1, Status: StatusStopped, Health: HealthOK, IsReady: true},
+		{ID: 5, AgentID: 1, Status: StatusStopped, Health: HealthOK, IsReady: true},
+		{ID: 7, AgentID: 2, Status: StatusRunning, Health: HealthOK, IsReady: true},
+	}
+	if !reflect.DeepEqual(g.Nodes, wantNodes) {
+		t.Errorf("expected nodes %+v, got %+v", wantNodes, g.Nodes)
+	}
+	wantEdges := []JobGraphEdge{{From: 4, To: 7}, {From: 5, To: 7}}
+	if !reflect.DeepEqual(g.Edges, wantEdges) {
+		t.Errorf("expected edges %+v, got %+v", wantEdges, g.Edges)
+	}
+	if g.RepoPullID != 14 {
+		t.Errorf("expected %v, got %v", 14, g.RepoPullID)
+	}
+}
@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import "time"
+
+// jsonTimePtr converts a time.Time for JSON marshalling, returning
+// nil for the zero value so that unset times can be omitted rather
+// than encoded as "0001-01-01T00:00:00Z".
+func jsonTimePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// timeFromJSONPtr is the inverse of jsonTimePtr, returning the zero
+// value if p is nil, i.e. if the time was omitted or null.
+func timeFromJSONPtr(p *time.Time) time.Time {
+	if p == nil {
+		return time.Time{}
+	}
+	return *p
+}
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import "time"
+
+// KeysetCursor marks where the next page of a keyset-paginated list
+// method begins. Unlike the offset in a PageRequest, a cursor lets
+// the database seek directly to the next page using an index, so
+// fetching a late page of a very large table is as cheap as the
+// first. The zero value requests the first page.
+type KeysetCursor struct {
+	// AfterTimestamp is, for list methods ordered by a timestamp,
+	// the timestamp of the last result already returned.
+	AfterTimestamp time.Time `json:"after_timestamp,omitempty"`
+	// AfterID is the ID of the last result already returned.
+	AfterID uint64 `json:"after_id"`
+}
+
+// validateKeysetLimit returns a *ValidationError if limit is 0,
+// since keyset-paginated list methods always return bounded pages.
+func validateKeysetLimit(limit uint32) error {
+	if limit == 0 {
+		return &ValidationError{Entity: "page request", Field: "limit", Reason: "must be greater than 0"}
+	}
+	return nil
+}
+
+// zeroTimestamp is the value stored for timestamps that are not set,
+// and is used in place of NULL when comparing them to a cursor.
+const zeroTimestamp = "'0001-01-01 00:00:00+00'"
+
+// GetFileInstancesForRepoPullAfter returns up to limit file instances
+// in the RepoPull with the given ID, ordered by ID and starting after
+// cursor.AfterID. It also returns the cursor for the next page, or
+// nil if there are no more results.
+func (db *DB) GetFileInstancesForRepoPullAfter(rpID RepoPullID, cursor KeysetCursor, limit uint32) ([]*FileInstance, *KeysetCursor, error) {
+	if err := validateKeysetLimit(limit); err != nil {
+		return nil, nil, err
+	}
+
+	// request one extra row to tell whether there is another page
+	rows, err := db.sqldb.Query("SELECT id, repopull_id, filehash_id, path FROM peridot.file_instances WHERE repopull_id = $1 AND id > $2 ORDER BY id LIMIT $3", rpID, cursor.AfterID, limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	fis := []*FileInstance{}
+	for rows.Next() {
+		fi := &FileInstance{}
+		err := rows.Scan(&fi.ID, &fi.RepoPullID, &fi.FileHashID, &fi.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		fis = append(fis, fi)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(fis) <= int(limit) {
+		return fis, nil, nil
+	}
+	fis = fis[:limit]
+	return fis, &KeysetCursor{AfterID: fis[limit-1].ID}, nil
+}
+
+// GetJobsAfter returns up to limit jobs, ordered by ID and starting
+// after cursor.AfterID. It also returns the cursor for the next page,
+// or nil if there are no more results.
+func (db *DB) GetJobsAfter(cursor KeysetCursor, limit uint32) ([]*Job, *KeysetCursor, error) {
+	if err := validateKeysetLimit(limit); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.sqldb.Query("SELECT id FROM peridot.jobs WHERE id > $1 AND repopull_id IN ("+liveRepoPullIDs+") ORDER BY id LIMIT $2", cursor.AfterID, limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	ids := []JobID{}
+	for rows.Next() {
+		var id JobID
+		if err := rows.Scan(&id); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var next *KeysetCursor
+	if len(ids) > int(limit) {
+		ids = ids[:limit]
+		next = &KeysetCursor{AfterID: uint64(ids[limit-1])}
+	}
+
+	js, err := db.GetJobsByIDs(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	return js, next, nil
+}
+
+// GetJobsStartedAfter returns up to limit jobs, ordered by start time
+// and then by ID, starting after the job that started at
+// cursor.AfterTimestamp with ID cursor.AfterID. Jobs that have not
+// started sort first. It also returns the cursor for the next page,
+// or nil if there are no more results.
+func (db *DB) GetJobsStartedAfter(cursor KeysetCursor, limit uint32) ([]*Job, *KeysetCursor, error) {
+	if err := validateKeysetLimit(limit); err != nil {
+		return nil, nil, err
+	}
+
+	startedAt := "COALESCE(started_at, " + zeroTimestamp + ")"
+	rows, err := db.sqldb.Query("SELECT id, "+startedAt+" FROM peridot.jobs WHERE ("+startedAt+", id) > ($1, $2) AND repopull_id IN ("+liveRepoPullIDs+") ORDER BY "+startedAt+", id LIMIT $3", cursor.AfterTimestamp, cursor.AfterID, limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	ids := []JobID{}
+	times := []time.Time{}
+	for rows.Next() {
+		var id JobID
+		var t time.Time
+		if err := rows.Scan(&id, &t); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+		times = append(times, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var next *KeysetCursor
+	if len(ids) > int(limit) {
+		ids = ids[:limit]
+		next = &KeysetCursor{AfterTimestamp: times[limit-1], AfterID: uint64(ids[limit-1])}
+	}
+
+	js, err := db.getJobsInOrder(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	return js, next, nil
+}
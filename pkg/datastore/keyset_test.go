@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestShouldGetFileInstancesForRepoPullAfterWithNextCursor(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// one more row than the limit is returned, indicating more results
+	sentRows := sqlmock.NewRows([]string{"id", "repopull_id", "filehash_id", "path"}).
+		AddRow(1001, 5, 17, "/README.md").
+		AddRow(1002, 5, 18, "/LICENSE").
+		AddRow(1005, 5, 19, "/main.go")
+	mock.ExpectQuery(`SELECT id, repopull_id, filehash_id, path FROM peridot.file_instances WHERE repopull_id = \$1 AND id > \$2 ORDER BY id LIMIT \$3`).
+		WithArgs(5, 1000, 3).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, next, err := db.GetFileInstancesForRepoPullAfter(5, KeysetCursor{AfterID: 1000}, 2)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	if next == nil || next.AfterID != 1002 {
+		t.Errorf("expected next cursor after %v, got %+v", 1002, next)
+	}
+}
+
+func TestShouldGetJobsStartedAfterInStartTimeOrder(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	after := time.Date(2019, 5, 2, 13, 0, 0, 0, time.UTC)
+	t9 := time.Date(2019, 5, 2, 13, 5, 0, 0, time.UTC)
+	t4 := time.Date(2019, 5, 2, 13, 10, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT id, COALESCE\(started_at, '0001-01-01 00:00:00\+00'\) FROM peridot.jobs WHERE \(COALESCE\(started_at, '0001-01-01 00:00:00\+00'\), id\) > \(\$1, \$2\) AND repopull_id IN \(`+liveRepoPullIDsRegex+`\) ORDER BY COALESCE\(started_at, '0001-01-01 00:00:00\+00'\), id LIMIT \$3`).
+		WithArgs(after, 0, 11).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "started_at"}).AddRow(9, t9).AddRow(4, t4))
+	mock.ExpectQuery(jobsByIDsRegex).
+		WithArgs(pq.Array([]JobID{9, 4})).
+		WillReturnRows(sqlmock.NewRows(jobsByIDsColumns).
+			AddRow(4, 1, 2, t4, time.Time{}, StatusRunning, HealthOK, "", true, 1, testCreatedAt, testUpdatedAt, "{}", "{}", "{}", "{}", "{}").
+			AddRow(9, 1, 3, t9, time.Time{}, StatusRunning, HealthOK, "", true, 1, testCreatedAt, testUpdatedAt, "{}", "{}", "{}", "{}", "{}"))
+
+	// run the tested function
+	gotRows, next, err := db.GetJobsStartedAfter(KeysetCursor{AfterTimestamp: after}, 10)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	if gotRows[0].ID != 9 || gotRows[1].ID != 4 {
+		t.Errorf("expected jobs in start time order [9 4], got [%v %v]", gotRows[0].ID, gotRows[1].ID)
+	}
+	if next != nil {
+		t.Errorf("expected nil next cursor, got %+v", next)
+	}
+}
+
+func TestShouldFailKeysetPaginationWithZeroLimit(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function; no queries should be made
+	_, _, err = db.GetJobsAfter(KeysetCursor{}, 0)
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
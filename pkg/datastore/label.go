@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Labelable is implemented by the ID types of entities that can
+// carry labels: RepoID, RepoPullID, JobID and AgentID. Labels are
+// free-form key/value pairs, such as "team" => "platform", stored
+// in a single table shared by all of these entities.
+//
+// Labels are not removed when the entity they are on is deleted;
+// callers that delete labeled entities should delete their labels
+// too.
+type Labelable interface {
+	// labelEntity returns the entity type under which labels for
+	// this ID are stored.
+	labelEntity() string
+}
+
+func (id RepoID) labelEntity() string     { return "repo" }
+func (id RepoPullID) labelEntity() string { return "repopull" }
+func (id JobID) labelEntity() string      { return "job" }
+func (id AgentID) labelEntity() string    { return "agent" }
+
+// GetLabels returns all labels on the entity identified by target,
+// as a map from label key to value. The map is empty if the entity
+// has no labels.
+func (db *DB) GetLabels(target Labelable) (map[string]string, error) {
+	rows, err := db.sqldb.Query("SELECT key, value FROM peridot.labels WHERE entity_type = $1 AND entity_id = $2 ORDER BY key", target.labelEntity(), target)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		err := rows.Scan(&key, &value)
+		if err != nil {
+			return nil, err
+		}
+		labels[key] = value
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// GetLabel returns the value of the label with the given key on the
+// entity identified by target, or "" and an error if not found.
+func (db *DB) GetLabel(target Labelable, key string) (string, error) {
+	var value string
+	err := db.sqldb.QueryRow("SELECT value FROM peridot.labels WHERE entity_type = $1 AND entity_id = $2 AND key = $3", target.labelEntity(), target, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", &NotFoundError{Entity: target.labelEntity() + " label", Key: "ID/key", ID: fmt.Sprintf("%d/%s", target, key)}
+	}
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// SetLabel sets the label with the given key on the entity
+// identified by target to value, replacing any existing value. It
+// returns nil on success or an error if failing.
+func (db *DB) SetLabel(target Labelable, key string, value string) error {
+	if err := requireNonEmpty("label", "key", key); err != nil {
+		return err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.labels(entity_type, entity_id, key, value) VALUES ($1, $2, $3, $4) ON CONFLICT (entity_type, entity_id, key) DO UPDATE SET value = EXCLUDED.value")
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(target.labelEntity(), target, key, value)
+	return err
+}
+
+// DeleteLabel removes the label with the given key from the entity
+// identified by target. It returns nil on success or an error if
+// failing.
+func (db *DB) DeleteLabel(target Labelable, key string) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.labels WHERE entity_type = $1 AND entity_id = $2 AND key = $3", target.labelEntity(), target, key)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: target.labelEntity() + " label", Key: "ID/key", ID: fmt.Sprintf("%d/%s", target, key)}
+	}
+
+	return nil
+}
+
+// getLabeledIDs returns the IDs of all entities of the given type
+// that have the label key set to value, in ascending order.
+func (db *DB) getLabeledIDs(entityType string, key string, value string) ([]uint32, error) {
+	rows, err := db.sqldb.Query("SELECT entity_id FROM peridot.labels WHERE entity_type = $1 AND key = $2 AND value = $3 ORDER BY entity_id", entityType, key, value)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []uint32{}
+	for rows.Next() {
+		var id uint32
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// GetRepoIDsWithLabel returns the IDs of all repos that have the
+// label key set to value, in ascending order.
+func (db *DB) GetRepoIDsWithLabel(key string, value string) ([]RepoID, error) {
+	ids, err := db.getLabeledIDs(RepoID(0).labelEntity(), key, value)
+	if err != nil {
+		return nil, err
+	}
+	repoIDs := make([]RepoID, len(ids))
+	for i, id := range ids {
+		repoIDs[i] = RepoID(id)
+	}
+	return repoIDs, nil
+}
+
+// GetRepoPullIDsWithLabel returns the IDs of all repo pulls that
+// have the label key set to value, in ascending order.
+func (db *DB) GetRepoPullIDsWithLabel(key string, value string) ([]RepoPullID, error) {
+	ids, err := db.getLabeledIDs(RepoPullID(0).labelEntity(), key, value)
+	if err != nil {
+		return nil, err
+	}
+	repoPullIDs := make([]RepoPullID, len(ids))
+	for i, id := range ids {
+		repoPullIDs[i] = RepoPullID(id)
+	}
+	return repoPullIDs, nil
+}
+
+// GetJobIDsWithLabel returns the IDs of all jobs that have the label
+// key set to value, in ascending order.
+func (db *DB) GetJobIDsWithLabel(key string, value string) ([]JobID, error) {
+	ids, err := db.getLabeledIDs(JobID(0).labelEntity(), key, value)
+	if err != nil {
+		return nil, err
+	}
+	return JobIDsFromUint32s(ids), nil
+}
+
+// GetAgentIDsWithLabel returns the IDs of all agents that have the
+// label key set to value, in ascending order.
+func (db *DB) GetAgentIDsWithLabel(key string, value string) ([]AgentID, error) {
+	ids, err := db.getLabeledIDs(AgentID(0).labelEntity(), key, value)
+	if err != nil {
+		return nil, err
+	}
+	agentIDs := make([]AgentID, len(ids))
+	for i, id := range ids {
+		agentIDs[i] = AgentID(id)
+	}
+	return agentIDs, nil
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetLabels(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"key", "value"}).
+		AddRow("env", "prod").
+		AddRow("team", "platform")
+	mock.ExpectQuery(`SELECT key, value FROM peridot.labels WHERE entity_type = \$1 AND entity_id = \$2 ORDER BY key`).
+		WithArgs("agent", 6).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotLabels, err := db.GetLabels(AgentID(6))
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantLabels := map[string]string{"env": "prod", "team": "platform"}
+	if !reflect.DeepEqual(wantLabels, gotLabels) {
+		t.Errorf("expected %v, got %v", wantLabels, gotLabels)
+	}
+}
+
+func TestShouldSetLabel(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.labels\(entity_type, entity_id, key, value\) VALUES \(\$1, \$2, \$3, \$4\) ON CONFLICT \(entity_type, entity_id, key\) DO UPDATE SET value = EXCLUDED.value`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs("repo", 3, "team", "platform").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.SetLabel(RepoID(3), "team", "platform")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailSetLabelWithEmptyKey(t *testing.T) {
+	err := (&DB{}).SetLabel(JobID(4), "", "x")
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if verr.Field != "key" {
+		t.Errorf("expected field %v, got %v", "key", verr.Field)
+	}
+}
+
+func TestShouldFailDeleteLabelWithUnknownKey(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`DELETE FROM peridot.labels WHERE entity_type = \$1 AND entity_id = \$2 AND key = \$3`).
+		WithArgs("repopull", 36, "release").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.DeleteLabel(RepoPullID(36), "release")
+	nfe, ok := err.(*NotFoundError)
+	if !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+	if nfe.ID != "36/release" {
+		t.Errorf("expected %v, got %v", "36/release", nfe.ID)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldGetRepoIDsWithLabel(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"entity_id"}).
+		AddRow(3).
+		AddRow(8)
+	mock.ExpectQuery(`SELECT entity_id FROM peridot.labels WHERE entity_type = \$1 AND key = \$2 AND value = \$3 ORDER BY entity_id`).
+		WithArgs("repo", "team", "platform").
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotIDs, err := db.GetRepoIDsWithLabel("team", "platform")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantIDs := []RepoID{3, 8}
+	if !reflect.DeepEqual(wantIDs, gotIDs) {
+		t.Errorf("expected %v, got %v", wantIDs, gotIDs)
+	}
+}
@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+)
+
+// License describes a license in peridot's license catalog. The
+// catalog is seeded with the SPDX License List, and custom licenses
+// can be added with LicenseRef- identifiers. Findings and policies
+// refer to licenses by their catalog ID.
+type License struct {
+	// ID is the unique ID for this license.
+	ID uint32 `json:"id"`
+	// SPDXID is the license's SPDX identifier, e.g. "Apache-2.0"
+	// or "LicenseRef-acme-proprietary".
+	SPDXID string `json:"spdx_id"`
+	// Name is the license's full name.
+	Name string `json:"name"`
+	// IsOSIApproved is true if the OSI has approved the license.
+	IsOSIApproved bool `json:"is_osi_approved"`
+	// IsDeprecated is true if the SPDX identifier is deprecated.
+	IsDeprecated bool `json:"is_deprecated"`
+	// IsCustom is true if the license was added to the catalog
+	// rather than seeded from the SPDX License List.
+	IsCustom bool `json:"is_custom"`
+}
+
+// licenseRefPrefix begins the SPDX identifier of every custom license.
+const licenseRefPrefix = "LicenseRef-"
+
+// licenseRefRegexp matches valid custom license identifiers, which
+// may contain only letters, numbers, "." and "-" after the prefix.
+var licenseRefRegexp = regexp.MustCompile(`^LicenseRef-[A-Za-z0-9.\-]+$`)
+
+// Validate checks that the License's fields are well-formed, and
+// that a custom license has a LicenseRef- identifier. It returns nil
+// if so, or a *ValidationError describing the first invalid field.
+func (l *License) Validate() error {
+	if err := requireNonEmpty("license", "spdx_id", l.SPDXID); err != nil {
+		return err
+	}
+	if l.IsCustom && !licenseRefRegexp.MatchString(l.SPDXID) {
+		return &ValidationError{Entity: "license", Field: "spdx_id", Reason: fmt.Sprintf("%q is not a valid %sidstring", l.SPDXID, licenseRefPrefix)}
+	}
+	return requireNonEmpty("license", "name", l.Name)
+}
+
+const licenseColumns = "id, spdx_id, name, is_osi_approved, is_deprecated, is_custom"
+
+// scanLicense scans a single license row from rs.
+func scanLicense(rs rowScanner) (*License, error) {
+	l := &License{}
+	err := rs.Scan(&l.ID, &l.SPDXID, &l.Name, &l.IsOSIApproved, &l.IsDeprecated, &l.IsCustom)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// GetAllLicenses returns a slice of all licenses in the catalog,
+// ordered by SPDX identifier.
+func (db *DB) GetAllLicenses() ([]*License, error) {
+	rows, err := db.sqldb.Query("SELECT " + licenseColumns + " FROM peridot.licenses ORDER BY lower(spdx_id)")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ls := []*License{}
+	for rows.Next() {
+		l, err := scanLicense(rows)
+		if err != nil {
+			return nil, err
+		}
+		ls = append(ls, l)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ls, nil
+}
+
+// CountAllLicenses returns the number of licenses in the database.
+func (db *DB) CountAllLicenses() (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.licenses")
+}
+
+// GetLicenseByID returns the License with the given ID, or nil and
+// an error if not found.
+func (db *DB) GetLicenseByID(id uint32) (*License, error) {
+	l, err := scanLicense(db.sqldb.QueryRow("SELECT "+licenseColumns+" FROM peridot.licenses WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "license", ID: fmt.Sprint(id)}
+	}
+	return l, err
+}
+
+// GetLicenseBySPDXID returns the License with the given SPDX
+// identifier, or nil and an error if not found. As in SPDX license
+// expressions, the identifier is matched case-insensitively.
+func (db *DB) GetLicenseBySPDXID(spdxID string) (*License, error) {
+	l, err := scanLicense(db.sqldb.QueryRow("SELECT "+licenseColumns+" FROM peridot.licenses WHERE lower(spdx_id) = lower($1)", spdxID))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "license", Key: "SPDX ID", ID: spdxID}
+	}
+	return l, err
+}
+
+// AddCustomLicense adds a new custom license to the catalog, with
+// the given LicenseRef- identifier and name. It returns the new
+// license's ID on success or an error if failing.
+func (db *DB) AddCustomLicense(spdxID string, name string) (uint32, error) {
+	l := &License{SPDXID: spdxID, Name: name, IsCustom: true}
+	if err := l.Validate(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.licenses(spdx_id, name, is_osi_approved, is_deprecated, is_custom) VALUES ($1, $2, false, false, true) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var licenseID uint32
+	err = stmt.QueryRow(spdxID, name).Scan(&licenseID)
+	if err != nil {
+		return 0, err
+	}
+	return licenseID, nil
+}
+
+// UpdateCustomLicense updates the name of an existing custom
+// license. Licenses seeded from the SPDX License List cannot be
+// updated. It returns nil on success or an error if failing.
+func (db *DB) UpdateCustomLicense(id uint32, newName string) error {
+	if err := requireNonEmpty("license", "name", newName); err != nil {
+		return err
+	}
+
+	stmt, err := db.prepare("UPDATE peridot.licenses SET name = $1 WHERE id = $2 AND is_custom")
+	if err != nil {
+		return err
+	}
+	result, err := stmt.Exec(newName, id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "custom license", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// DeleteCustomLicense deletes an existing custom license. Licenses
+// seeded from the SPDX License List cannot be deleted. It returns
+// nil on success or an error if failing.
+func (db *DB) DeleteCustomLicense(id uint32) error {
+	stmt, err := db.prepare("DELETE FROM peridot.licenses WHERE id = $1 AND is_custom")
+	if err != nil {
+		return err
+	}
+	result, err := stmt.Exec(id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "custom license", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// seedLicenses adds every license in the SPDX License List that is
+// not already in the catalog. Existing entries are left unchanged.
+func seedLicenses(db *DB) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.licenses(spdx_id, name, is_osi_approved, is_deprecated, is_custom) VALUES ($1, $2, $3, $4, false) ON CONFLICT (spdx_id) DO NOTHING")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, l := range spdxLicenseList {
+		_, err = stmt.Exec(l.SPDXID, l.Name, l.IsOSIApproved, l.IsDeprecated)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,246 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var licenseTestColumns = []string{"id", "spdx_id", "name", "is_osi_approved", "is_deprecated", "is_custom"}
+
+func TestShouldGetAllLicenses(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows(licenseTestColumns).
+		AddRow(12, "Apache-2.0", "Apache License 2.0", true, false, false).
+		AddRow(731, "LicenseRef-acme", "Acme Proprietary License", false, false, true)
+	mock.ExpectQuery(`SELECT id, spdx_id, name, is_osi_approved, is_deprecated, is_custom FROM peridot.licenses ORDER BY lower\(spdx_id\)`).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetAllLicenses()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	l0 := gotRows[0]
+	if l0.ID != 12 || l0.SPDXID != "Apache-2.0" || !l0.IsOSIApproved || l0.IsCustom {
+		t.Errorf("got unexpected license %#v", l0)
+	}
+	l1 := gotRows[1]
+	if l1.ID != 731 || l1.SPDXID != "LicenseRef-acme" || l1.IsOSIApproved || !l1.IsCustom {
+		t.Errorf("got unexpected license %#v", l1)
+	}
+}
+
+func TestShouldGetLicenseBySPDXID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows(licenseTestColumns).
+		AddRow(12, "Apache-2.0", "Apache License 2.0", true, false, false)
+	mock.ExpectQuery(`SELECT id, spdx_id, name, is_osi_approved, is_deprecated, is_custom FROM peridot.licenses WHERE lower\(spdx_id\) = lower\(\$1\)`).
+		WithArgs("apache-2.0").
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	l, err := db.GetLicenseBySPDXID("apache-2.0")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if l.ID != 12 {
+		t.Errorf("expected %v, got %v", 12, l.ID)
+	}
+	if l.SPDXID != "Apache-2.0" {
+		t.Errorf("expected %v, got %v", "Apache-2.0", l.SPDXID)
+	}
+}
+
+func TestShouldFailGetLicenseBySPDXIDForUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT .* FROM peridot.licenses WHERE lower\(spdx_id\) = lower\(\$1\)`).
+		WithArgs("Oops-1.0").
+		WillReturnRows(sqlmock.NewRows(licenseTestColumns))
+
+	// run the tested function
+	l, err := db.GetLicenseBySPDXID("Oops-1.0")
+	if l != nil {
+		t.Fatalf("expected nil license, got %v", l)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldAddCustomLicense(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.licenses\(spdx_id, name, is_osi_approved, is_deprecated, is_custom\) VALUES \(\$1, \$2, false, false, true\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs("LicenseRef-acme", "Acme Proprietary License").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(731))
+
+	// run the tested function
+	licenseID, err := db.AddCustomLicense("LicenseRef-acme", "Acme Proprietary License")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if licenseID != 731 {
+		t.Errorf("expected %v, got %v", 731, licenseID)
+	}
+}
+
+func TestShouldFailAddCustomLicenseWithInvalidID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	for _, spdxID := range []string{"", "Apache-2.0", "LicenseRef-", "LicenseRef-acme corp"} {
+		_, err = db.AddCustomLicense(spdxID, "Acme Proprietary License")
+		if _, ok := err.(*ValidationError); !ok {
+			t.Errorf("for %q: expected *ValidationError, got %v", spdxID, err)
+		}
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailUpdateSeededLicense(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `UPDATE peridot.licenses SET name = \$1 WHERE id = \$2 AND is_custom`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs("Not Apache", 12).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.UpdateCustomLicense(12, "Not Apache")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldDeleteCustomLicense(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `DELETE FROM peridot.licenses WHERE id = \$1 AND is_custom`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(731).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.DeleteCustomLicense(731)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSPDXLicenseListIsWellFormed(t *testing.T) {
+	seen := map[string]bool{}
+	for _, l := range spdxLicenseList {
+		if err := l.Validate(); err != nil {
+			t.Errorf("invalid seeded license %v: %v", l.SPDXID, err)
+		}
+		if seen[l.SPDXID] {
+			t.Errorf("duplicate seeded license %v", l.SPDXID)
+		}
+		seen[l.SPDXID] = true
+	}
+	if !seen["Apache-2.0"] || !seen["GPL-2.0-or-later"] {
+		t.Errorf("expected common licenses to be seeded")
+	}
+}
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import "time"
+
+// MetricsSnapshot records aggregate metrics for a Project at a point
+// in time, so that trends can be charted without recomputing
+// historical aggregates.
+type MetricsSnapshot struct {
+	// ID is the unique ID for this snapshot.
+	ID uint64 `json:"id"`
+	// ProjectID is the ID of the project the metrics are for.
+	ProjectID ProjectID `json:"project_id"`
+	// RecordedAt is when the metrics were computed.
+	RecordedAt time.Time `json:"recorded_at"`
+	// ReposScanned is the number of the project's repos with at
+	// least one stopped (i.e., completed) RepoPull.
+	ReposScanned uint32 `json:"repos_scanned"`
+	// FailingPulls is the number of the project's repos whose most
+	// recent stopped RepoPull has HealthError.
+	FailingPulls uint32 `json:"failing_pulls"`
+	// OpenViolations is the total number of violations in the most
+	// recent PolicyResult for each Policy and repo in the project.
+	OpenViolations uint32 `json:"open_violations"`
+}
+
+// recordMetricsSnapshotQuery computes and stores a MetricsSnapshot
+// for project $1 at time $2, given StatusStopped as $3 and
+// HealthError as $4.
+const recordMetricsSnapshotQuery = `
+WITH project_repos AS (
+	SELECT r.id
+	FROM peridot.repos r
+	JOIN peridot.subprojects sp ON sp.id = r.subproject_id
+	WHERE sp.project_id = $1 AND r.id IN (` + liveRepoIDs + `)
+), latest_pulls AS (
+	SELECT DISTINCT ON (rp.repo_id) rp.repo_id, rp.health
+	FROM peridot.repo_pulls rp
+	WHERE rp.repo_id IN (SELECT id FROM project_repos) AND rp.status = $3
+	ORDER BY rp.repo_id, rp.finished_at DESC, rp.id DESC
+), latest_results AS (
+	SELECT DISTINCT ON (rp.repo_id, pr.policy_id) pr.violations
+	FROM peridot.policy_results pr
+	JOIN peridot.repo_pulls rp ON rp.id = pr.repopull_id
+	WHERE rp.repo_id IN (SELECT id FROM project_repos)
+	ORDER BY rp.repo_id, pr.policy_id, pr.evaluated_at DESC, pr.id DESC
+)
+INSERT INTO peridot.metrics_snapshots(project_id, recorded_at, repos_scanned, failing_pulls, open_violations)
+SELECT $1, $2,
+	(SELECT COUNT(*) FROM latest_pulls),
+	(SELECT COUNT(*) FROM latest_pulls WHERE health = $4),
+	(SELECT COALESCE(SUM(jsonb_array_length(violations)), 0) FROM latest_results WHERE jsonb_typeof(violations) = 'array')
+RETURNING id, repos_scanned, failing_pulls, open_violations
+`
+
+// RecordMetricsSnapshot computes the current aggregate metrics for
+// the Project with the given ID and stores them as a new snapshot.
+// It returns the new snapshot on success or an error if failing.
+func (db *DB) RecordMetricsSnapshot(projectID ProjectID) (*MetricsSnapshot, error) {
+	ms := &MetricsSnapshot{ProjectID: projectID, RecordedAt: now()}
+	err := db.sqldb.QueryRow(recordMetricsSnapshotQuery, projectID, ms.RecordedAt, StatusStopped, HealthError).
+		Scan(&ms.ID, &ms.ReposScanned, &ms.FailingPulls, &ms.OpenViolations)
+	if err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// GetMetricsSnapshots returns a slice of all snapshots for the
+// Project with the given ID recorded at or after from and before
+// to, ordered from oldest to newest.
+func (db *DB) GetMetricsSnapshots(projectID ProjectID, from time.Time, to time.Time) ([]*MetricsSnapshot, error) {
+	rows, err := db.sqldb.Query("SELECT id, project_id, recorded_at, repos_scanned, failing_pulls, open_violations FROM peridot.metrics_snapshots WHERE project_id = $1 AND recorded_at >= $2 AND recorded_at < $3 ORDER BY recorded_at, id", projectID, normalizeTime(from), normalizeTime(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []*MetricsSnapshot{}
+	for rows.Next() {
+		ms := &MetricsSnapshot{}
+		err := rows.Scan(&ms.ID, &ms.ProjectID, &ms.RecordedAt, &ms.ReposScanned, &ms.FailingPulls, &ms.OpenViolations)
+		if err != nil {
+			return nil, err
+		}
+		ms.RecordedAt = normalizeTime(ms.RecordedAt)
+		snapshots = append(snapshots, ms)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldRecordMetricsSnapshot(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`INSERT INTO peridot.metrics_snapshots\(project_id, recorded_at, repos_scanned, failing_pulls, open_violations\)`).
+		WithArgs(2, sqlmock.AnyArg(), StatusStopped, HealthError).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "repos_scanned", "failing_pulls", "open_violations"}).AddRow(41, 12, 2, 7))
+
+	// run the tested function
+	ms, err := db.RecordMetricsSnapshot(2)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if ms.ID != 41 || ms.ProjectID != 2 || ms.ReposScanned != 12 || ms.FailingPulls != 2 || ms.OpenViolations != 7 {
+		t.Errorf("got unexpected snapshot %#v", ms)
+	}
+	if ms.RecordedAt.IsZero() {
+		t.Errorf("expected non-zero recorded_at")
+	}
+}
+
+func TestShouldGetMetricsSnapshots(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	from := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	recordedAt1 := time.Date(2019, 5, 2, 0, 0, 0, 0, time.UTC)
+	recordedAt2 := time.Date(2019, 5, 3, 0, 0, 0, 671764000, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "project_id", "recorded_at", "repos_scanned", "failing_pulls", "open_violations"}).
+		AddRow(40, 2, recordedAt1, 11, 3, 9).
+		AddRow(41, 2, recordedAt2, 12, 2, 7)
+	mock.ExpectQuery(`SELECT id, project_id, recorded_at, repos_scanned, failing_pulls, open_violations FROM peridot.metrics_snapshots WHERE project_id = \$1 AND recorded_at >= \$2 AND recorded_at < \$3 ORDER BY recorded_at, id`).
+		WithArgs(2, from, to).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetMetricsSnapshots(2, from, to)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantRows := []*MetricsSnapshot{
+		{ID: 40, ProjectID: 2, RecordedAt: recordedAt1, ReposScanned: 11, FailingPulls: 3, OpenViolations: 9},
+		{ID: 41, ProjectID: 2, RecordedAt: recordedAt2, ReposScanned: 12, FailingPulls: 2, OpenViolations: 7},
+	}
+	if !reflect.DeepEqual(wantRows, gotRows) {
+		t.Errorf("expected %#v, got %#v", wantRows, gotRows)
+	}
+}
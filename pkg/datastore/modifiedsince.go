@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import "time"
+
+// GetJobsModifiedSince returns up to limit jobs that were added or
+// updated after the position given by cursor, ordered by when they
+// were last modified and then by ID. Pass a cursor whose
+// AfterTimestamp is the time to sync from, or the zero cursor to
+// fetch every job.
+//
+// Unlike the other keyset-paginated list methods, it returns a non-nil
+// cursor to pass to the next call whenever it succeeds: the position
+// of the last job returned, or a copy of cursor if none were. A caller that polls for
+// changes can therefore keep the cursor between polls. Fewer than
+// limit results means the caller has caught up.
+//
+// Modification times are taken when a row is written rather than
+// when its transaction commits, so a write that commits slowly may
+// appear behind the cursor. Callers that must not miss any change
+// should move the cursor's timestamp back by a short margin before
+// polling again, and tolerate seeing some jobs twice.
+func (db *DB) GetJobsModifiedSince(cursor KeysetCursor, limit uint32) ([]*Job, *KeysetCursor, error) {
+	if err := validateKeysetLimit(limit); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.sqldb.Query("SELECT id, updated_at FROM peridot.jobs WHERE (updated_at, id) > ($1, $2) AND repopull_id IN ("+liveRepoPullIDs+") ORDER BY updated_at, id LIMIT $3", cursor.AfterTimestamp, cursor.AfterID, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	ids := []JobID{}
+	next := cursor
+	for rows.Next() {
+		var id JobID
+		var t time.Time
+		if err := rows.Scan(&id, &t); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+		next = KeysetCursor{AfterTimestamp: t, AfterID: uint64(id)}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(ids) == 0 {
+		// nothing has changed, which is the common case when polling
+		return []*Job{}, &next, nil
+	}
+
+	js, err := db.getJobsInOrder(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	return js, &next, nil
+}
+
+// GetRepoPullsModifiedSince returns up to limit repo pulls that were
+// added or updated after the position given by cursor, ordered by
+// when they were last modified and then by ID. It returns the cursor
+// to pass to the next call as GetJobsModifiedSince does, and the same
+// caveat about slowly committing writes applies.
+func (db *DB) GetRepoPullsModifiedSince(cursor KeysetCursor, limit uint32) ([]*RepoPull, *KeysetCursor, error) {
+	if err := validateKeysetLimit(limit); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.sqldb.Query("SELECT "+repoPullColumns+" FROM peridot.repo_pulls WHERE (updated_at, id) > ($1, $2) AND repo_id IN ("+liveRepoIDs+") ORDER BY updated_at, id LIMIT $3", cursor.AfterTimestamp, cursor.AfterID, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	rps := []*RepoPull{}
+	next := cursor
+	for rows.Next() {
+		rp, err := scanRepoPull(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		rps = append(rps, rp)
+		next = KeysetCursor{AfterTimestamp: rp.UpdatedAt, AfterID: uint64(rp.ID)}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return rps, &next, nil
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestShouldGetJobsModifiedSinceInModificationOrder(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	since := time.Date(2019, 5, 2, 13, 0, 0, 0, time.UTC)
+	t9 := time.Date(2019, 5, 2, 13, 5, 0, 0, time.UTC)
+	t4 := time.Date(2019, 5, 2, 13, 10, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT id, updated_at FROM peridot.jobs WHERE \(updated_at, id\) > \(\$1, \$2\) AND repopull_id IN \(`+liveRepoPullIDsRegex+`\) ORDER BY updated_at, id LIMIT \$3`).
+		WithArgs(since, 0, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "updated_at"}).AddRow(9, t9).AddRow(4, t4))
+	mock.ExpectQuery(jobsByIDsRegex).
+		WithArgs(pq.Array([]JobID{9, 4})).
+		WillReturnRows(sqlmock.NewRows(jobsByIDsColumns).
+			AddRow(4, 1, 2, t4, time.Time{}, StatusRunning, HealthOK, "", true, 2, testCreatedAt, testUpdatedAt, "{}", "{}", "{}", "{}", "{}").
+			AddRow(9, 1, 3, t9, time.Time{}, StatusStopped, HealthOK, "", true, 3, testCreatedAt, testUpdatedAt, "{}", "{}", "{}", "{}", "{}"))
+
+	// run the tested function
+	gotRows, next, err := db.GetJobsModifiedSince(KeysetCursor{AfterTimestamp: since}, 10)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	if gotRows[0].ID != 9 || gotRows[1].ID != 4 {
+		t.Errorf("expected jobs in modification order [9 4], got [%v %v]", gotRows[0].ID, gotRows[1].ID)
+	}
+	if next == nil {
+		t.Fatalf("expected non-nil next cursor, got nil")
+	}
+	if !next.AfterTimestamp.Equal(t4) || next.AfterID != 4 {
+		t.Errorf("expected next cursor at %v/%v, got %+v", t4, 4, next)
+	}
+}
+
+func TestShouldKeepCursorWhenNoJobsModifiedSince(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	cursor := KeysetCursor{AfterTimestamp: time.Date(2019, 5, 2, 13, 10, 0, 0, time.UTC), AfterID: 4}
+	mock.ExpectQuery("SELECT id, updated_at FROM peridot.jobs").
+		WithArgs(cursor.AfterTimestamp, 4, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "updated_at"}))
+
+	// run the tested function; no jobs should be fetched
+	gotRows, next, err := db.GetJobsModifiedSince(cursor, 10)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 0 {
+		t.Errorf("expected len %d, got %d", 0, len(gotRows))
+	}
+	if next == nil || *next != cursor {
+		t.Errorf("expected cursor %+v to be returned unchanged, got %+v", cursor, next)
+	}
+}
+
+func TestShouldGetRepoPullsModifiedSince(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	startedAt := time.Date(2019, 5, 2, 12, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(2019, 5, 2, 13, 5, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT id, repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id, created_at, updated_at FROM peridot.repo_pulls WHERE \(updated_at, id\) > \(\$1, \$2\) AND repo_id IN \(`+liveRepoIDsRegex+`\) ORDER BY updated_at, id LIMIT \$3`).
+		WithArgs(time.Time{}, 0, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "repo_id", "branch", "started_at", "finished_at", "status", "health", "output", "commit", "tag", "spdx_id", "created_at", "updated_at"}).
+			AddRow(12, 4, "master", startedAt, startedAt, StatusRunning, HealthOK, nil, "4b825dc642cb6eb9a060e54bf8d69288fbee4904", nil, nil, testCreatedAt, updatedAt))
+
+	// run the tested function
+	gotRows, next, err := db.GetRepoPullsModifiedSince(KeysetCursor{}, 5)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 1 {
+		t.Fatalf("expected len %d, got %d", 1, len(gotRows))
+	}
+	if gotRows[0].ID != 12 || gotRows[0].Commit != "4b825dc642cb6eb9a060e54bf8d69288fbee4904" {
+		t.Errorf("got unexpected repo pull %+v", gotRows[0])
+	}
+	if next == nil {
+		t.Fatalf("expected non-nil next cursor, got nil")
+	}
+	if !next.AfterTimestamp.Equal(updatedAt) || next.AfterID != 12 {
+		t.Errorf("expected next cursor at %v/%v, got %+v", updatedAt, 12, next)
+	}
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is matched by errors.Is for every *NotFoundError, so
+// that callers can detect a missing entity of any kind with a single
+// check, e.g. to respond with HTTP 404.
+var ErrNotFound = errors.New("not found")
+
+// NotFoundError is returned when the entity being retrieved, updated
+// or deleted does not exist.
+type NotFoundError struct {
+	// Entity is the kind of entity that was not found, such as
+	// "job" or "repo pull".
+	Entity string
+	// ID identifies the entity that was not found. For entities
+	// identified by more than one value, the values are separated
+	// by slashes, e.g. "3/main" for a repo branch.
+	ID string
+	// Key names the field or fields that ID holds, if not the
+	// entity's ID, e.g. "name" or "repo ID/branch".
+	Key string
+}
+
+func (e *NotFoundError) Error() string {
+	key := e.Key
+	if key == "" {
+		key = "ID"
+	}
+	return fmt.Sprintf("no %s found with %s %s", e.Entity, key, e.ID)
+}
+
+// Is reports whether target is ErrNotFound.
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// Unwrap returns sql.ErrNoRows, so that errors.Is(err, sql.ErrNoRows)
+// also holds for lookups that previously returned sql.ErrNoRows
+// directly.
+func (e *NotFoundError) Unwrap() error {
+	return sql.ErrNoRows
+}
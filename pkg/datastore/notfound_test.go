@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCanFormatNotFoundError(t *testing.T) {
+	tests := []struct {
+		err  *NotFoundError
+		want string
+	}{
+		{&NotFoundError{Entity: "job", ID: "17"}, "no job found with ID 17"},
+		{&NotFoundError{Entity: "agent", Key: "name", ID: "idsearcher"}, "no agent found with name idsearcher"},
+		{&NotFoundError{Entity: "repo branch", Key: "repo ID/branch", ID: "3/main"}, "no repo branch found with repo ID/branch 3/main"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestNotFoundErrorMatchesSentinels(t *testing.T) {
+	err := fmt.Errorf("couldn't load job: %w", &NotFoundError{Entity: "job", ID: "17"})
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected errors.Is(err, ErrNotFound) to be true")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected errors.Is(err, sql.ErrNoRows) to be true")
+	}
+
+	var nfe *NotFoundError
+	if !errors.As(err, &nfe) {
+		t.Fatalf("expected errors.As to find *NotFoundError")
+	}
+	if nfe.Entity != "job" || nfe.ID != "17" {
+		t.Errorf("expected job 17, got %s %s", nfe.Entity, nfe.ID)
+	}
+
+	if errors.Is(errors.New("not found"), ErrNotFound) {
+		t.Errorf("expected unrelated error not to match ErrNotFound")
+	}
+}
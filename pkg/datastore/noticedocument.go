@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Formats accepted in NoticeDocument.Format.
+const (
+	NoticeFormatText     = "text"
+	NoticeFormatHTML     = "html"
+	NoticeFormatMarkdown = "markdown"
+)
+
+// NoticeDocument describes a NOTICE or attribution document that a
+// Job generated for a RepoPull. The document itself is kept in
+// external storage; peridot records where to find it and how to
+// verify it.
+type NoticeDocument struct {
+	// ID is the unique ID for this document.
+	ID uint64 `json:"id"`
+	// RepoPullID is the ID of the RepoPull the document describes.
+	RepoPullID RepoPullID `json:"repopull_id"`
+	// JobID is the ID of the Job that generated the document.
+	JobID JobID `json:"job_id"`
+	// Format is one of the NoticeFormat values.
+	Format string `json:"format"`
+	// URI is where the document is stored, e.g. an s3:// URI.
+	URI string `json:"uri"`
+	// SHA256 is the lowercase hex SHA256 checksum of the document.
+	SHA256 string `json:"sha256"`
+	// CreatedAt is when the document was recorded.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks that the NoticeDocument's fields are well-formed.
+// It returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (nd *NoticeDocument) Validate() error {
+	switch nd.Format {
+	case NoticeFormatText, NoticeFormatHTML, NoticeFormatMarkdown:
+	default:
+		return &ValidationError{Entity: "notice document", Field: "format", Reason: "must be text, html or markdown"}
+	}
+	if err := requireNonEmpty("notice document", "uri", nd.URI); err != nil {
+		return err
+	}
+	if !isLowerHex(nd.SHA256, 64) {
+		return &ValidationError{Entity: "notice document", Field: "sha256", Reason: "must be 64 lowercase hex characters"}
+	}
+	return nil
+}
+
+const noticeDocumentColumns = "nd.id, nd.repopull_id, nd.job_id, nd.format, nd.uri, nd.sha256, nd.created_at"
+
+// scanNoticeDocument reads a NoticeDocument from a row selecting
+// noticeDocumentColumns.
+func scanNoticeDocument(rs rowScanner) (*NoticeDocument, error) {
+	nd := &NoticeDocument{}
+	err := rs.Scan(&nd.ID, &nd.RepoPullID, &nd.JobID, &nd.Format, &nd.URI, &nd.SHA256, &nd.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	nd.CreatedAt = normalizeTime(nd.CreatedAt)
+	return nd, nil
+}
+
+// queryNoticeDocuments runs a query selecting noticeDocumentColumns
+// and returns the resulting documents.
+func (db *DB) queryNoticeDocuments(query string, args ...interface{}) ([]*NoticeDocument, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := []*NoticeDocument{}
+	for rows.Next() {
+		nd, err := scanNoticeDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, nd)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// GetNoticeDocumentByID returns the NoticeDocument with the given
+// ID, or nil and an error if not found.
+func (db *DB) GetNoticeDocumentByID(id uint64) (*NoticeDocument, error) {
+	nd, err := scanNoticeDocument(db.sqldb.QueryRow("SELECT "+noticeDocumentColumns+" FROM peridot.notice_documents nd WHERE nd.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "notice document", ID: fmt.Sprint(id)}
+	}
+	return nd, err
+}
+
+// GetNoticeDocumentsForRepoPull returns a slice of all notice
+// documents for the RepoPull with the given ID, ordered from oldest
+// to newest.
+func (db *DB) GetNoticeDocumentsForRepoPull(rpID RepoPullID) ([]*NoticeDocument, error) {
+	return db.queryNoticeDocuments("SELECT "+noticeDocumentColumns+" FROM peridot.notice_documents nd WHERE nd.repopull_id = $1 ORDER BY nd.created_at, nd.id", rpID)
+}
+
+// GetNoticeDocumentsForProject returns a slice of all notice
+// documents for RepoPulls of Repos in the Project with the given
+// ID, ordered from oldest to newest. If tag is non-empty, only
+// documents for RepoPulls with that git tag, i.e. for that
+// release, are returned.
+func (db *DB) GetNoticeDocumentsForProject(projectID ProjectID, tag string) ([]*NoticeDocument, error) {
+	query := "SELECT " + noticeDocumentColumns + " FROM peridot.notice_documents nd JOIN peridot.repo_pulls rp ON rp.id = nd.repopull_id JOIN peridot.repos r ON r.id = rp.repo_id JOIN peridot.subprojects sp ON sp.id = r.subproject_id WHERE sp.project_id = $1 AND r.id IN (" + liveRepoIDs + ")"
+	args := []interface{}{projectID}
+	if tag != "" {
+		query += " AND rp.tag = $2"
+		args = append(args, tag)
+	}
+	return db.queryNoticeDocuments(query+" ORDER BY nd.created_at, nd.id", args...)
+}
+
+// AddNoticeDocument records a generated notice document. nd's ID
+// and CreatedAt fields are ignored. It returns the new document's
+// ID on success or an error if failing.
+func (db *DB) AddNoticeDocument(nd *NoticeDocument) (uint64, error) {
+	if err := nd.Validate(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.notice_documents(repopull_id, job_id, format, uri, sha256, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var docID uint64
+	err = stmt.QueryRow(nd.RepoPullID, nd.JobID, nd.Format, nd.URI, nd.SHA256, now()).Scan(&docID)
+	if err != nil {
+		return 0, err
+	}
+	return docID, nil
+}
+
+// DeleteNoticeDocument deletes the record of the existing
+// NoticeDocument with the given ID. The stored document itself is
+// not affected. It returns nil on success or an error if failing.
+func (db *DB) DeleteNoticeDocument(id uint64) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.notice_documents WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "notice document", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetNoticeDocumentsForProjectRelease(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	sha := "6bd1aaef2f0a1f0e2b1a1d1c5b8b2d7d9f3c1e1f2b5d7e8f9a0b1c2d3e4f5a6b"
+	sentRows := sqlmock.NewRows([]string{"id", "repopull_id", "job_id", "format", "uri", "sha256", "created_at"}).
+		AddRow(5, 36, 19, "text", "s3://notices/36/NOTICE", sha, createdAt)
+	mock.ExpectQuery(`SELECT nd.id, nd.repopull_id, nd.job_id, nd.format, nd.uri, nd.sha256, nd.created_at FROM peridot.notice_documents nd JOIN peridot.repo_pulls rp ON rp.id = nd.repopull_id JOIN peridot.repos r ON r.id = rp.repo_id JOIN peridot.subprojects sp ON sp.id = r.subproject_id WHERE sp.project_id = \$1 AND r.id IN \(`+liveRepoIDsRegex+`\) AND rp.tag = \$2 ORDER BY nd.created_at, nd.id`).
+		WithArgs(2, "v1.2.0").
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetNoticeDocumentsForProject(2, "v1.2.0")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*NoticeDocument{
+		&NoticeDocument{ID: 5, RepoPullID: 36, JobID: 19, Format: NoticeFormatText, URI: "s3://notices/36/NOTICE", SHA256: sha, CreatedAt: createdAt},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldAddNoticeDocument(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sha := "6bd1aaef2f0a1f0e2b1a1d1c5b8b2d7d9f3c1e1f2b5d7e8f9a0b1c2d3e4f5a6b"
+	regexStmt := `INSERT INTO peridot.notice_documents\(repopull_id, job_id, format, uri, sha256, created_at\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(36, 19, "html", "s3://notices/36/NOTICE.html", sha, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+
+	// run the tested function
+	id, err := db.AddNoticeDocument(&NoticeDocument{RepoPullID: 36, JobID: 19, Format: NoticeFormatHTML, URI: "s3://notices/36/NOTICE.html", SHA256: sha})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 6 {
+		t.Errorf("expected %v, got %v", 6, id)
+	}
+}
+
+func TestShouldFailAddNoticeDocumentWithInvalidFields(t *testing.T) {
+	sha := "6bd1aaef2f0a1f0e2b1a1d1c5b8b2d7d9f3c1e1f2b5d7e8f9a0b1c2d3e4f5a6b"
+	tests := []struct {
+		nd    *NoticeDocument
+		field string
+	}{
+		{&NoticeDocument{RepoPullID: 36, JobID: 19, Format: "pdf", URI: "s3://x", SHA256: sha}, "format"},
+		{&NoticeDocument{RepoPullID: 36, JobID: 19, Format: NoticeFormatText, SHA256: sha}, "uri"},
+		{&NoticeDocument{RepoPullID: 36, JobID: 19, Format: NoticeFormatText, URI: "s3://x", SHA256: "6BD1"}, "sha256"},
+	}
+
+	for _, tt := range tests {
+		_, err := (&DB{}).AddNoticeDocument(tt.nd)
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("for %#v: expected *ValidationError, got %v", tt.nd, err)
+			continue
+		}
+		if verr.Field != tt.field {
+			t.Errorf("expected field %v, got %v", tt.field, verr.Field)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Common values for Notification.Type.
+const (
+	// NotificationTypeScanFailed means a RepoPull or Job failed.
+	NotificationTypeScanFailed = "scan_failed"
+	// NotificationTypePolicyFailed means a RepoPull failed a
+	// Policy evaluation.
+	NotificationTypePolicyFailed = "policy_failed"
+)
+
+// Notification describes an in-app alert for a User, such as that a
+// scan of one of their repos failed.
+type Notification struct {
+	// ID is the unique ID for this notification.
+	ID uint64 `json:"id"`
+	// UserID is the ID of the user to be notified.
+	UserID UserID `json:"user_id"`
+	// Type categorizes the notification, e.g.
+	// NotificationTypeScanFailed.
+	Type string `json:"type"`
+	// Entity is the kind of entity the notification is about, such
+	// as "repopull", or "" if none.
+	Entity string `json:"entity,omitempty"`
+	// EntityID identifies the entity within its kind, as in
+	// AuditEntry.
+	EntityID string `json:"entity_id,omitempty"`
+	// Message is the text to display.
+	Message string `json:"message"`
+	// CreatedAt is when the notification was created.
+	CreatedAt time.Time `json:"created_at"`
+	// ReadAt is when the user marked the notification as read.
+	// Should be zero value if it is unread.
+	ReadAt time.Time `json:"read_at,omitempty"`
+}
+
+// MarshalJSON converts the Notification into a slice of bytes
+// containing its JSON encoding, leaving out read_at while the
+// notification is unread.
+func (n Notification) MarshalJSON() ([]byte, error) {
+	type notificationAlias Notification
+	return json.Marshal(struct {
+		notificationAlias
+		ReadAt *time.Time `json:"read_at,omitempty"`
+	}{notificationAlias: notificationAlias(n), ReadAt: jsonTimePtr(n.ReadAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of a Notification into the Notification. A missing or null read_at
+// leaves the notification unread.
+func (n *Notification) UnmarshalJSON(b []byte) error {
+	type notificationAlias Notification
+	aux := struct {
+		*notificationAlias
+		ReadAt *time.Time `json:"read_at"`
+	}{notificationAlias: (*notificationAlias)(n)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	n.ReadAt = timeFromJSONPtr(aux.ReadAt)
+	return nil
+}
+
+// Validate checks that the Notification's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (n *Notification) Validate() error {
+	if err := requireNonEmpty("notification", "type", n.Type); err != nil {
+		return err
+	}
+	if n.EntityID != "" && n.Entity == "" {
+		return &ValidationError{Entity: "notification", Field: "entity", Reason: "must be set if entity_id is set"}
+	}
+	return requireNonEmpty("notification", "message", n.Message)
+}
+
+// CreateNotification creates a new, unread notification of the given
+// type for the User with ID userID, about the entity identified by
+// entity and entityID, which may both be empty. It returns the new
+// notification's ID on success or an error if failing.
+func (db *DB) CreateNotification(userID UserID, notificationType string, entity string, entityID string, message string) (uint64, error) {
+	n := &Notification{UserID: userID, Type: notificationType, Entity: entity, EntityID: entityID, Message: message}
+	if err := n.Validate(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.notifications(user_id, type, entity, entity_id, message, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var notificationID uint64
+	err = stmt.QueryRow(userID, notificationType, entity, entityID, message, now()).Scan(&notificationID)
+	if err != nil {
+		return 0, err
+	}
+	return notificationID, nil
+}
+
+// GetUnreadNotificationsForUser returns a slice of all unread
+// notifications for the User with the given ID, ordered from newest
+// to oldest.
+func (db *DB) GetUnreadNotificationsForUser(userID UserID) ([]*Notification, error) {
+	rows, err := db.sqldb.Query("SELECT id, user_id, type, entity, entity_id, message, created_at, read_at FROM peridot.notifications WHERE user_id = $1 AND read_at IS NULL ORDER BY created_at DESC, id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*Notification{}
+	for rows.Next() {
+		n := &Notification{}
+		var readAt pq.NullTime
+		err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Entity, &n.EntityID, &n.Message, &n.CreatedAt, &readAt)
+		if err != nil {
+			return nil, err
+		}
+		n.CreatedAt = normalizeTime(n.CreatedAt)
+		if readAt.Valid {
+			n.ReadAt = normalizeTime(readAt.Time)
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// MarkNotificationRead marks the notification with the given ID,
+// which must belong to the User with ID userID, as read. Marking an
+// already-read notification leaves its read time unchanged. It
+// returns nil on success or an error if failing.
+func (db *DB) MarkNotificationRead(userID UserID, id uint64) error {
+	result, err := db.sqldb.Exec("UPDATE peridot.notifications SET read_at = COALESCE(read_at, $1) WHERE id = $2 AND user_id = $3", now(), id, userID)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "notification", Key: "user ID/ID", ID: fmt.Sprintf("%d/%d", userID, id)}
+	}
+
+	return nil
+}
+
+// MarkAllNotificationsRead marks all unread notifications for the
+// User with the given ID as read. It returns the number of
+// notifications marked on success or an error if failing.
+func (db *DB) MarkAllNotificationsRead(userID UserID) (int64, error) {
+	result, err := db.sqldb.Exec("UPDATE peridot.notifications SET read_at = $1 WHERE user_id = $2 AND read_at IS NULL", now(), userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
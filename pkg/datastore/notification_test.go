@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldCreateNotification(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.notifications\(user_id, type, entity, entity_id, message, created_at\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(10, "scan_failed", "repopull", "36", "Scan of repo-1 failed", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(92))
+
+	// run the tested function
+	id, err := db.CreateNotification(10, NotificationTypeScanFailed, "repopull", "36", "Scan of repo-1 failed")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 92 {
+		t.Errorf("expected %v, got %v", 92, id)
+	}
+}
+
+func TestShouldFailCreateNotificationWithEntityIDButNoEntity(t *testing.T) {
+	_, err := (&DB{}).CreateNotification(10, NotificationTypeScanFailed, "", "36", "Scan of repo-1 failed")
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if verr.Field != "entity" {
+		t.Errorf("expected field %v, got %v", "entity", verr.Field)
+	}
+}
+
+func TestShouldGetUnreadNotificationsForUser(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "user_id", "type", "entity", "entity_id", "message", "created_at", "read_at"}).
+		AddRow(92, 10, "scan_failed", "repopull", "36", "Scan of repo-1 failed", createdAt, nil)
+	mock.ExpectQuery(`SELECT id, user_id, type, entity, entity_id, message, created_at, read_at FROM peridot.notifications WHERE user_id = \$1 AND read_at IS NULL ORDER BY created_at DESC, id DESC`).
+		WithArgs(10).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetUnreadNotificationsForUser(10)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*Notification{
+		&Notification{ID: 92, UserID: 10, Type: NotificationTypeScanFailed, Entity: "repopull", EntityID: "36", Message: "Scan of repo-1 failed", CreatedAt: createdAt},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldFailMarkNotificationReadForOtherUser(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`UPDATE peridot.notifications SET read_at = COALESCE\(read_at, \$1\) WHERE id = \$2 AND user_id = \$3`).
+		WithArgs(sqlmock.AnyArg(), 92, 11).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.MarkNotificationRead(11, 92)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Kinds of obligation that a license can impose.
+const (
+	// ObligationAttribution means copyright and license notices
+	// must be reproduced, e.g. in a NOTICE file.
+	ObligationAttribution = "attribution"
+	// ObligationIncludeLicenseText means the full license text must
+	// accompany distributions.
+	ObligationIncludeLicenseText = "include_license_text"
+	// ObligationSourceOffer means the corresponding source code must
+	// be made available, or a written offer provided.
+	ObligationSourceOffer = "source_offer"
+	// ObligationStateChanges means modified files must carry notices
+	// stating that they were changed.
+	ObligationStateChanges = "state_changes"
+	// ObligationSameLicense means derivative works must be
+	// distributed under the same license.
+	ObligationSameLicense = "same_license"
+)
+
+// Obligation describes something that must be done when
+// distributing code under a License, as recorded in peridot's
+// obligations catalog.
+type Obligation struct {
+	// ID is the unique ID for this obligation.
+	ID uint32 `json:"id"`
+	// LicenseID is the ID of the License imposing the obligation.
+	LicenseID uint32 `json:"license_id"`
+	// Kind is the kind of obligation, e.g. ObligationAttribution.
+	// A license imposes each kind at most once.
+	Kind string `json:"kind"`
+	// Description explains what the obligation requires for this
+	// license in particular.
+	Description string `json:"description,omitempty"`
+}
+
+// Validate checks that the Obligation's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (o *Obligation) Validate() error {
+	switch o.Kind {
+	case ObligationAttribution, ObligationIncludeLicenseText, ObligationSourceOffer, ObligationStateChanges, ObligationSameLicense:
+	default:
+		return &ValidationError{Entity: "obligation", Field: "kind", Reason: fmt.Sprintf("unknown obligation kind %q", o.Kind)}
+	}
+	return nil
+}
+
+// TriggeredObligation describes an Obligation triggered by the files
+// in a RepoPull, because findings in those files reference the
+// license imposing it.
+type TriggeredObligation struct {
+	// Obligation is the triggered obligation.
+	Obligation *Obligation `json:"obligation"`
+	// SPDXID is the SPDX identifier of the license imposing the
+	// obligation.
+	SPDXID string `json:"spdx_id"`
+	// FileCount is the number of files in the repo pull with
+	// findings for that license.
+	FileCount uint32 `json:"file_count"`
+}
+
+const obligationColumns = "id, license_id, kind, description"
+
+// scanObligation reads an Obligation from a row selecting
+// obligationColumns.
+func scanObligation(rs rowScanner) (*Obligation, error) {
+	o := &Obligation{}
+	err := rs.Scan(&o.ID, &o.LicenseID, &o.Kind, &o.Description)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// GetObligationsForLicense returns a slice of all obligations imposed
+// by the License with the given ID, ordered by kind.
+func (db *DB) GetObligationsForLicense(licenseID uint32) ([]*Obligation, error) {
+	rows, err := db.sqldb.Query("SELECT "+obligationColumns+" FROM peridot.obligations WHERE license_id = $1 ORDER BY kind", licenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	obligations := []*Obligation{}
+	for rows.Next() {
+		o, err := scanObligation(rows)
+		if err != nil {
+			return nil, err
+		}
+		obligations = append(obligations, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return obligations, nil
+}
+
+// GetObligationByID returns the Obligation with the given ID, or nil
+// and an error if not found.
+func (db *DB) GetObligationByID(id uint32) (*Obligation, error) {
+	o, err := scanObligation(db.sqldb.QueryRow("SELECT "+obligationColumns+" FROM peridot.obligations WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "obligation", ID: fmt.Sprint(id)}
+	}
+	return o, err
+}
+
+// AddObligation records that the License with the given ID imposes
+// an obligation of the given kind, as explained in description. It
+// returns the new obligation's ID on success or an error if failing.
+func (db *DB) AddObligation(licenseID uint32, kind string, description string) (uint32, error) {
+	o := &Obligation{LicenseID: licenseID, Kind: kind, Description: description}
+	if err := o.Validate(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.obligations(license_id, kind, description) VALUES ($1, $2, $3) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var obligationID uint32
+	err = stmt.QueryRow(licenseID, kind, description).Scan(&obligationID)
+	if err != nil {
+		return 0, err
+	}
+	return obligationID, nil
+}
+
+// UpdateObligationDescription replaces the description of the
+// existing Obligation with the given ID. It returns nil on success
+// or an error if failing.
+func (db *DB) UpdateObligationDescription(id uint32, description string) error {
+	result, err := db.sqldb.Exec("UPDATE peridot.obligations SET description = $1 WHERE id = $2", description, id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "obligation", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// DeleteObligation deletes the existing Obligation with the given ID.
+// It returns nil on success or an error if failing.
+func (db *DB) DeleteObligation(id uint32) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.obligations WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "obligation", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// repoPullObligationsQuery selects the obligations triggered by the
+// files in repo pull $1, with the identifier of the license imposing
+// each and the number of files with findings for that license.
+const repoPullObligationsQuery = `
+SELECT o.id, o.license_id, o.kind, o.description, l.spdx_id, COUNT(DISTINCT f.fileinstance_id)
+FROM peridot.obligations o
+JOIN peridot.licenses l ON l.id = o.license_id
+JOIN peridot.finding_licenses fl ON fl.license_id = o.license_id
+JOIN peridot.findings f ON f.id = fl.finding_id
+JOIN peridot.file_instances fi ON fi.id = f.fileinstance_id
+WHERE fi.repopull_id = $1
+GROUP BY o.id, l.spdx_id
+ORDER BY o.kind, lower(l.spdx_id)
+`
+
+// GetObligationsForRepoPull returns a slice of all obligations
+// triggered by the files in the RepoPull with the given ID, ordered
+// by kind and then by license identifier.
+func (db *DB) GetObligationsForRepoPull(rpID RepoPullID) ([]*TriggeredObligation, error) {
+	rows, err := db.sqldb.Query(repoPullObligationsQuery, rpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	triggered := []*TriggeredObligation{}
+	for rows.Next() {
+		o := &Obligation{}
+		to := &TriggeredObligation{Obligation: o}
+		err := rows.Scan(&o.ID, &o.LicenseID, &o.Kind, &o.Description, &to.SPDXID, &to.FileCount)
+		if err != nil {
+			return nil, err
+		}
+		triggered = append(triggered, to)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return triggered, nil
+}
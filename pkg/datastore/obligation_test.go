@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetObligationsForLicense(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "license_id", "kind", "description"}).
+		AddRow(1, 12, "attribution", "Retain the NOTICE file").
+		AddRow(2, 12, "state_changes", "")
+	mock.ExpectQuery(`SELECT id, license_id, kind, description FROM peridot.obligations WHERE license_id = \$1 ORDER BY kind`).
+		WithArgs(12).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetObligationsForLicense(12)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantRows := []*Obligation{
+		{ID: 1, LicenseID: 12, Kind: ObligationAttribution, Description: "Retain the NOTICE file"},
+		{ID: 2, LicenseID: 12, Kind: ObligationStateChanges},
+	}
+	if !reflect.DeepEqual(wantRows, gotRows) {
+		t.Errorf("expected %#v, got %#v", wantRows, gotRows)
+	}
+}
+
+func TestShouldAddObligation(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.obligations\(license_id, kind, description\) VALUES \(\$1, \$2, \$3\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(40, "source_offer", "Offer source for three years").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	// run the tested function
+	id, err := db.AddObligation(40, ObligationSourceOffer, "Offer source for three years")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 3 {
+		t.Errorf("expected %v, got %v", 3, id)
+	}
+}
+
+func TestShouldFailAddObligationWithUnknownKind(t *testing.T) {
+	_, err := (&DB{}).AddObligation(40, "indemnify", "")
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if verr.Field != "kind" {
+		t.Errorf("expected field %v, got %v", "kind", verr.Field)
+	}
+}
+
+func TestShouldFailDeleteObligationWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`DELETE FROM peridot.obligations WHERE id = \$1`).
+		WithArgs(413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.DeleteObligation(413)
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldGetObligationsForRepoPull(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "license_id", "kind", "description", "spdx_id", "count"}).
+		AddRow(1, 12, "attribution", "Retain the NOTICE file", "Apache-2.0", 143).
+		AddRow(4, 27, "attribution", "", "MIT", 8).
+		AddRow(3, 40, "source_offer", "Offer source for three years", "GPL-2.0-only", 1)
+	mock.ExpectQuery(`SELECT o.id, o.license_id, o.kind, o.description, l.spdx_id, COUNT\(DISTINCT f.fileinstance_id\) FROM peridot.obligations o .* WHERE fi.repopull_id = \$1 GROUP BY o.id, l.spdx_id ORDER BY o.kind, lower\(l.spdx_id\)`).
+		WithArgs(36).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetObligationsForRepoPull(36)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantRows := []*TriggeredObligation{
+		{Obligation: &Obligation{ID: 1, LicenseID: 12, Kind: ObligationAttribution, Description: "Retain the NOTICE file"}, SPDXID: "Apache-2.0", FileCount: 143},
+		{Obligation: &Obligation{ID: 4, LicenseID: 27, Kind: ObligationAttribution}, SPDXID: "MIT", FileCount: 8},
+		{Obligation: &Obligation{ID: 3, LicenseID: 40, Kind: ObligationSourceOffer, Description: "Offer source for three years"}, SPDXID: "GPL-2.0-only", FileCount: 1},
+	}
+	if !reflect.DeepEqual(wantRows, gotRows) {
+		t.Errorf("expected %#v, got %#v", wantRows, gotRows)
+	}
+}
@@ -0,0 +1,318 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Organization describes a tenant of a peridot deployment. An
+// Organization owns Projects, and its members are the users who may
+// work with them, so that several organizations can share one
+// deployment while remaining isolated from each other.
+type Organization struct {
+	// ID is the unique ID for this organization.
+	ID OrgID `json:"id"`
+	// Name is this organization's short name, unique within the
+	// deployment. Typically it should be a single set of
+	// alphanumeric characters without spaces.
+	Name string `json:"name"`
+	// Fullname is this organization's full, more descriptive name.
+	Fullname string `json:"fullname"`
+	// CreatedAt is when the organization was created.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks that the Organization's fields are well-formed.
+// It returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (o *Organization) Validate() error {
+	return requireNonEmpty("organization", "name", o.Name)
+}
+
+// OrganizationMember describes a User's membership in an
+// Organization.
+type OrganizationMember struct {
+	// OrgID is the ID of the organization.
+	OrgID OrgID `json:"org_id"`
+	// UserID is the ID of the member.
+	UserID UserID `json:"user_id"`
+	// AccessLevel is the member's access level within the
+	// organization.
+	AccessLevel UserAccessLevel `json:"access"`
+}
+
+const organizationColumns = "id, name, fullname, created_at"
+
+// scanOrganization reads an Organization from a row selecting
+// organizationColumns.
+func scanOrganization(rs rowScanner) (*Organization, error) {
+	o := &Organization{}
+	err := rs.Scan(&o.ID, &o.Name, &o.Fullname, &o.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	o.CreatedAt = normalizeTime(o.CreatedAt)
+	return o, nil
+}
+
+// queryOrganizations runs a query selecting organizationColumns and
+// returns the resulting organizations.
+func (db *DB) queryOrganizations(query string, args ...interface{}) ([]*Organization, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []*Organization{}
+	for rows.Next() {
+		o, err := scanOrganization(rows)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// GetAllOrganizations returns a slice of all organizations, ordered
+// by ID.
+func (db *DB) GetAllOrganizations() ([]*Organization, error) {
+	return db.queryOrganizations("SELECT " + organizationColumns + " FROM peridot.organizations ORDER BY id")
+}
+
+// CountAllOrganizations returns the number of organizations in the
+// database.
+func (db *DB) CountAllOrganizations() (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.organizations")
+}
+
+// GetOrganizationsForUser returns a slice of all organizations of
+// which the User with the given ID is a member, ordered by ID.
+func (db *DB) GetOrganizationsForUser(userID UserID) ([]*Organization, error) {
+	return db.queryOrganizations("SELECT o.id, o.name, o.fullname, o.created_at FROM peridot.organizations o JOIN peridot.organization_members om ON om.org_id = o.id WHERE om.user_id = $1 ORDER BY o.id", userID)
+}
+
+// CountOrganizationsForUser returns the number of organizations of
+// which the User with the given ID is a member.
+func (db *DB) CountOrganizationsForUser(userID UserID) (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.organizations o JOIN peridot.organization_members om ON om.org_id = o.id WHERE om.user_id = $1", userID)
+}
+
+// GetOrganizationByID returns the Organization with the given ID, or
+// nil and an error if not found.
+func (db *DB) GetOrganizationByID(id OrgID) (*Organization, error) {
+	o, err := scanOrganization(db.sqldb.QueryRow("SELECT "+organizationColumns+" FROM peridot.organizations WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "organization", ID: fmt.Sprint(id)}
+	}
+	return o, err
+}
+
+// ExistsOrganization reports whether an Organization with the
+// given ID exists, without retrieving it.
+func (db *DB) ExistsOrganization(id OrgID) (bool, error) {
+	return db.exists("SELECT EXISTS (SELECT 1 FROM peridot.organizations WHERE id = $1)", id)
+}
+
+// AddOrganization adds a new Organization with the given short name
+// and full name. It returns the new organization's ID on success or
+// an error if failing.
+func (db *DB) AddOrganization(name string, fullname string) (OrgID, error) {
+	o := &Organization{Name: name, Fullname: fullname}
+	if err := o.Validate(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.organizations(name, fullname, created_at) VALUES ($1, $2, $3) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var orgID OrgID
+	err = stmt.QueryRow(name, fullname, now()).Scan(&orgID)
+	if err != nil {
+		return 0, translateConstraintError("organization", err)
+	}
+	return orgID, nil
+}
+
+// UpdateOrganization updates an existing Organization with the given
+// ID, changing to the specified short name and full name. If an empty
+// string is passed, the existing value will remain unchanged. It
+// returns nil on success or an error if failing.
+func (db *DB) UpdateOrganization(id OrgID, newName string, newFullname string) error {
+	var result sql.Result
+	var err error
+	if newName != "" && newFullname != "" {
+		result, err = db.sqldb.Exec("UPDATE peridot.organizations SET name = $1, fullname = $2 WHERE id = $3", newName, newFullname, id)
+	} else if newName != "" {
+		result, err = db.sqldb.Exec("UPDATE peridot.organizations SET name = $1 WHERE id = $2", newName, id)
+	} else if newFullname != "" {
+		result, err = db.sqldb.Exec("UPDATE peridot.organizations SET fullname = $1 WHERE id = $2", newFullname, id)
+	} else {
+		return fmt.Errorf("only empty strings passed to UpdateOrganization for id %v", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "organization", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// DeleteOrganization deletes the existing Organization with the given
+// ID, together with its memberships and all of its projects. It
+// returns nil on success or an error if failing.
+func (db *DB) DeleteOrganization(id OrgID) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.organizations WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "organization", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// queryOrganizationMembers runs a query selecting org_id, user_id
+// and access_level from organization_members and returns the
+// resulting memberships.
+func (db *DB) queryOrganizationMembers(query string, args ...interface{}) ([]*OrganizationMember, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*OrganizationMember{}
+	for rows.Next() {
+		om := &OrganizationMember{}
+		err := rows.Scan(&om.OrgID, &om.UserID, &om.AccessLevel)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, om)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// GetOrganizationMembers returns a slice of all members of the
+// Organization with the given ID, ordered by user ID.
+func (db *DB) GetOrganizationMembers(orgID OrgID) ([]*OrganizationMember, error) {
+	return db.queryOrganizationMembers("SELECT org_id, user_id, access_level FROM peridot.organization_members WHERE org_id = $1 ORDER BY user_id", orgID)
+}
+
+// GetOrganizationMember returns the membership of the User with the
+// given ID in the Organization with the given ID, or nil and an
+// error if the user is not a member.
+func (db *DB) GetOrganizationMember(orgID OrgID, userID UserID) (*OrganizationMember, error) {
+	om := &OrganizationMember{}
+	err := db.sqldb.QueryRow("SELECT org_id, user_id, access_level FROM peridot.organization_members WHERE org_id = $1 AND user_id = $2", orgID, userID).
+		Scan(&om.OrgID, &om.UserID, &om.AccessLevel)
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "organization member", ID: fmt.Sprintf("%d/%d", orgID, userID)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return om, nil
+}
+
+// AddOrganizationMember makes the User with the given ID a member of
+// the Organization with the given ID at the given access level,
+// replacing the access level if the user is already a member. It
+// returns nil on success or an error if failing.
+func (db *DB) AddOrganizationMember(orgID OrgID, userID UserID, accessLevel UserAccessLevel) error {
+	stmt, err := db.prepare("INSERT INTO peridot.organization_members(org_id, user_id, access_level) VALUES ($1, $2, $3) ON CONFLICT (org_id, user_id) DO UPDATE SET access_level = EXCLUDED.access_level")
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(orgID, userID, IntFromUserAccessLevel(accessLevel))
+	return err
+}
+
+// RemoveOrganizationMember removes the User with the given ID from
+// the Organization with the given ID. It returns nil on success or
+// an error if failing.
+func (db *DB) RemoveOrganizationMember(orgID OrgID, userID UserID) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.organization_members WHERE org_id = $1 AND user_id = $2", orgID, userID)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "organization member", ID: fmt.Sprintf("%d/%d", orgID, userID)}
+	}
+
+	return nil
+}
+
+// GetAllProjectsForOrganization returns a slice of all projects
+// owned by the Organization with the given ID, ordered by ID.
+func (db *DB) GetAllProjectsForOrganization(orgID OrgID) ([]*Project, error) {
+	return db.queryProjects("SELECT "+projectColumns+" FROM peridot.projects WHERE org_id = $1 AND deleted_at IS NULL ORDER BY id", orgID)
+}
+
+// CountProjectsForOrganization returns the number of projects owned
+// by the Organization with the given ID.
+func (db *DB) CountProjectsForOrganization(orgID OrgID) (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.projects WHERE org_id = $1 AND deleted_at IS NULL", orgID)
+}
+
+// GetAllSubprojectsForOrganization returns a slice of all
+// subprojects in projects owned by the Organization with the given
+// ID, ordered by ID.
+func (db *DB) GetAllSubprojectsForOrganization(orgID OrgID) ([]*Subproject, error) {
+	return db.querySubprojects("SELECT sp.id, sp.project_id, sp.name, sp.fullname, sp.created_at, sp.updated_at FROM peridot.subprojects sp JOIN peridot.projects p ON p.id = sp.project_id WHERE p.org_id = $1 AND p.deleted_at IS NULL AND sp.deleted_at IS NULL ORDER BY sp.id", orgID)
+}
+
+// CountSubprojectsForOrganization returns the number of subprojects
+// in projects owned by the Organization with the given ID.
+func (db *DB) CountSubprojectsForOrganization(orgID OrgID) (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.subprojects sp JOIN peridot.projects p ON p.id = sp.project_id WHERE p.org_id = $1 AND p.deleted_at IS NULL AND sp.deleted_at IS NULL", orgID)
+}
+
+// GetAllReposForOrganization returns a slice of all repos in
+// projects owned by the Organization with the given ID, ordered by
+// ID.
+func (db *DB) GetAllReposForOrganization(orgID OrgID) ([]*Repo, error) {
+	return db.queryRepos("SELECT r.id, r.subproject_id, r.name, r.address, r.created_at, r.updated_at FROM peridot.repos r JOIN peridot.subprojects sp ON sp.id = r.subproject_id JOIN peridot.projects p ON p.id = sp.project_id WHERE p.org_id = $1 AND p.deleted_at IS NULL AND sp.deleted_at IS NULL AND r.deleted_at IS NULL ORDER BY r.id", orgID)
+}
+
+// CountReposForOrganization returns the number of repos in projects
+// owned by the Organization with the given ID.
+func (db *DB) CountReposForOrganization(orgID OrgID) (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.repos r JOIN peridot.subprojects sp ON sp.id = r.subproject_id JOIN peridot.projects p ON p.id = sp.project_id WHERE p.org_id = $1 AND p.deleted_at IS NULL AND sp.deleted_at IS NULL AND r.deleted_at IS NULL", orgID)
+}
@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetOrganizationsForUser(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "name", "fullname", "created_at"}).
+		AddRow(1, "lf", "The Linux Foundation", createdAt).
+		AddRow(4, "apache", "Apache Software Foundation", createdAt)
+	mock.ExpectQuery(`SELECT o.id, o.name, o.fullname, o.created_at FROM peridot.organizations o JOIN peridot.organization_members om ON om.org_id = o.id WHERE om.user_id = \$1 ORDER BY o.id`).
+		WithArgs(10).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetOrganizationsForUser(10)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantRows := []*Organization{
+		{ID: 1, Name: "lf", Fullname: "The Linux Foundation", CreatedAt: createdAt},
+		{ID: 4, Name: "apache", Fullname: "Apache Software Foundation", CreatedAt: createdAt},
+	}
+	if !reflect.DeepEqual(wantRows, gotRows) {
+		t.Errorf("expected %#v, got %#v", wantRows, gotRows)
+	}
+}
+
+func TestShouldAddOrganization(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.organizations\(name, fullname, created_at\) VALUES \(\$1, \$2, \$3\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs("lf", "The Linux Foundation", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	// run the tested function
+	id, err := db.AddOrganization("lf", "The Linux Foundation")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 1 {
+		t.Errorf("expected %v, got %v", 1, id)
+	}
+}
+
+func TestShouldFailAddOrganizationWithEmptyName(t *testing.T) {
+	_, err := (&DB{}).AddOrganization("", "The Linux Foundation")
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if verr.Field != "name" {
+		t.Errorf("expected field %v, got %v", "name", verr.Field)
+	}
+}
+
+func TestShouldFailDeleteOrganizationWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`DELETE FROM peridot.organizations WHERE id = \$1`).
+		WithArgs(413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.DeleteOrganization(413)
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldAddOrganizationMember(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.organization_members\(org_id, user_id, access_level\) VALUES \(\$1, \$2, \$3\) ON CONFLICT \(org_id, user_id\) DO UPDATE SET access_level = EXCLUDED.access_level`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(1, 10, IntFromUserAccessLevel(AccessOperator)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.AddOrganizationMember(1, 10, AccessOperator)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldGetAllReposForOrganization(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "subproject_id", "name", "address", "created_at", "updated_at"}).
+		AddRow(3, 2, "kubernetes", "https://github.com/kubernetes/kubernetes", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT r.id, r.subproject_id, r.name, r.address, r.created_at, r.updated_at FROM peridot.repos r JOIN peridot.subprojects sp ON sp.id = r.subproject_id JOIN peridot.projects p ON p.id = sp.project_id WHERE p.org_id = \$1 AND p.deleted_at IS NULL AND sp.deleted_at IS NULL AND r.deleted_at IS NULL ORDER BY r.id`).
+		WithArgs(1).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetAllReposForOrganization(1)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantRows := []*Repo{
+		{ID: 3, SubprojectID: 2, Name: "kubernetes", Address: "https://github.com/kubernetes/kubernetes", CreatedAt: testCreatedAt, UpdatedAt: testUpdatedAt},
+	}
+	if !reflect.DeepEqual(wantRows, gotRows) {
+		t.Errorf("expected %#v, got %#v", wantRows, gotRows)
+	}
+}
+
+func TestShouldSetProjectOrganization(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `UPDATE peridot.projects SET org_id = \$1 WHERE id = \$2`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(nilArg{}, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("project", "2", AuditActionUpdate, []byte(`{"id":2,"org_id":0}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.SetProjectOrganization(2, 0)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldCountReposForOrganization(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM peridot.repos r JOIN peridot.subprojects sp ON sp.id = r.subproject_id JOIN peridot.projects p ON p.id = sp.project_id WHERE p.org_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(9))
+
+	// run the tested function
+	n, err := db.CountReposForOrganization(2)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if n != 9 {
+		t.Errorf("expected %v, got %v", 9, n)
+	}
+}
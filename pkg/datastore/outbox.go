@@ -0,0 +1,208 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// OutboxEvent describes a change to an entity in peridot, recorded
+// in the same transaction as the change itself so that downstream
+// consumers, such as search indexers, can reliably follow all
+// changes. Projects, subprojects, repos, repo pulls, jobs, agents
+// and users record events; see EventStore for the full scope.
+// Deleting an entity does not record events for the entities that
+// are deleted along with it by cascading.
+type OutboxEvent struct {
+	// ID is the unique ID for this event. Events are published in
+	// ID order.
+	ID uint64 `json:"id"`
+	// TxID is the ID of the database transaction that recorded the
+	// event. GetEventsSince returns events in TxID order and then
+	// in ID order.
+	TxID uint64 `json:"txid"`
+	// Entity is the kind of entity that was changed, such as
+	// "project".
+	Entity string `json:"entity"`
+	// EntityID identifies the changed entity within its kind, as
+	// in AuditEntry.
+	EntityID string `json:"entity_id"`
+	// Action is one of the AuditAction values.
+	Action string `json:"action"`
+	// Payload is the JSON encoding of the change: the new entity
+	// for an add, its ID and changed fields for an update, and its
+	// ID for a delete.
+	Payload json.RawMessage `json:"payload"`
+	// CreatedAt is when the change was made.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EventCursor marks a consumer's position in the outbox for
+// GetEventsSince. The zero EventCursor starts from the oldest event
+// still stored.
+type EventCursor struct {
+	// AfterTxID is the TxID of the last event the consumer handled.
+	AfterTxID uint64
+	// AfterID is the ID of the last event the consumer handled.
+	AfterID uint64
+}
+
+// addOutboxEvent records an OutboxEvent within tx, which should
+// also contain the change that the event describes.
+func addOutboxEvent(tx sqlConn, entity string, entityID interface{}, action string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO peridot.outbox_events(entity, entity_id, action, payload, created_at) VALUES ($1, $2, $3, $4, $5)", entity, fmt.Sprint(entityID), action, payloadJSON, now())
+	return err
+}
+
+// execWithOutboxEvent runs query, an UPDATE or DELETE of the entity
+// with the given ID, in a transaction together with recording an
+// OutboxEvent for it. The entity is named as in AuditEntry, e.g.
+// "repo_pull". If the query affects no rows, no event is recorded
+// and a *NotFoundError is returned.
+func (db *DB) execWithOutboxEvent(entity string, id interface{}, action string, payload interface{}, query string, args ...interface{}) error {
+	notFound := func() error {
+		return &NotFoundError{Entity: strings.ReplaceAll(entity, "_", " "), ID: fmt.Sprint(id)}
+	}
+	return db.execWithOutboxEventOr(notFound, entity, id, action, payload, query, args...)
+}
+
+// execWithOutboxEventOr is like execWithOutboxEvent, but if the
+// query affects no rows, it rolls back and returns the error from
+// missing instead of a *NotFoundError. This lets a method with a
+// version check, for instance, report a *ConflictError. missing runs
+// after the rollback, so it may query the DB.
+func (db *DB) execWithOutboxEventOr(missing func() error, entity string, id interface{}, action string, payload interface{}, query string, args ...interface{}) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := db.prepareIn(tx, query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	result, err := stmt.Exec(args...)
+	if err != nil {
+		tx.Rollback()
+		return translateConstraintError(strings.ReplaceAll(entity, "_", " "), err)
+	}
+
+	// check that something was actually changed
+	rows, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if rows == 0 {
+		tx.Rollback()
+		return missing()
+	}
+
+	err = addOutboxEvent(tx, entity, id, action, payload)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const outboxEventColumns = "id, txid, entity, entity_id, action, payload, created_at"
+
+// queryOutboxEvents runs a query selecting outboxEventColumns and
+// returns the resulting events.
+func (db *DB) queryOutboxEvents(query string, args ...interface{}) ([]*OutboxEvent, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*OutboxEvent{}
+	for rows.Next() {
+		ev := &OutboxEvent{}
+		var payload []byte
+		err := rows.Scan(&ev.ID, &ev.TxID, &ev.Entity, &ev.EntityID, &ev.Action, &payload, &ev.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ev.Payload = json.RawMessage(payload)
+		ev.CreatedAt = normalizeTime(ev.CreatedAt)
+		events = append(events, ev)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetUnpublishedEvents returns a slice of up to limit outbox events
+// that have not yet been marked as published, ordered by ID.
+func (db *DB) GetUnpublishedEvents(limit uint32) ([]*OutboxEvent, error) {
+	return db.queryOutboxEvents("SELECT "+outboxEventColumns+" FROM peridot.outbox_events WHERE published_at IS NULL ORDER BY id LIMIT $1", limit)
+}
+
+// GetEventsSince returns a slice of up to limit outbox events that
+// come after cursor, whether or not they have been published,
+// ordered by TxID and then by ID. It lets a consumer such as a search
+// indexer mirror changes incrementally, keeping the returned cursor
+// as its checkpoint. Like GetJobsModifiedSince, it returns a non-nil
+// cursor whenever it succeeds: the position of the last event
+// returned, or a copy of cursor if none were.
+//
+// Event IDs are assigned before the transaction recording the event
+// commits, so ordering by ID alone could let an event become visible
+// behind a consumer's checkpoint. Instead, only events recorded by
+// transactions older than every transaction still in progress are
+// returned. No further events can appear before such an event, so a
+// consumer following the cursor never skips one. Events recorded by
+// a long-running transaction hold back all later events until it
+// finishes. Consumers must also keep up with PrunePublishedEvents,
+// which deletes published events regardless of whether they have
+// been read here.
+func (db *DB) GetEventsSince(cursor EventCursor, limit uint32) ([]*OutboxEvent, *EventCursor, error) {
+	events, err := db.queryOutboxEvents("SELECT "+outboxEventColumns+" FROM peridot.outbox_events WHERE (txid, id) > ($1, $2) AND txid < txid_snapshot_xmin(txid_current_snapshot()) ORDER BY txid, id LIMIT $3", cursor.AfterTxID, cursor.AfterID, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next := cursor
+	if len(events) > 0 {
+		last := events[len(events)-1]
+		next = EventCursor{AfterTxID: last.TxID, AfterID: last.ID}
+	}
+	return events, &next, nil
+}
+
+// MarkEventsPublished marks the outbox events with the given IDs as
+// published, so that they are no longer returned by
+// GetUnpublishedEvents. IDs of events that are already published
+// or do not exist are ignored. It returns nil on success or an
+// error if failing.
+func (db *DB) MarkEventsPublished(ids []uint64) error {
+	_, err := db.sqldb.Exec("UPDATE peridot.outbox_events SET published_at = $1 WHERE id = ANY ($2) AND published_at IS NULL", now(), pq.Array(ids))
+	return err
+}
+
+// PrunePublishedEvents deletes all outbox events that were
+// published before the given time. It returns the number of events
+// deleted on success or an error if failing.
+func (db *DB) PrunePublishedEvents(before time.Time) (int64, error) {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.outbox_events WHERE published_at < $1", normalizeTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,254 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetUnpublishedEvents(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "txid", "entity", "entity_id", "action", "payload", "created_at"}).
+		AddRow(14, 802, "project", "1", "add", []byte(`{"id":1,"name":"cncf","fullname":"CNCF"}`), createdAt).
+		AddRow(15, 805, "repo", "3", "delete", []byte(`{"id":3}`), createdAt)
+	mock.ExpectQuery(`SELECT id, txid, entity, entity_id, action, payload, created_at FROM peridot.outbox_events WHERE published_at IS NULL ORDER BY id LIMIT \$1`).
+		WithArgs(100).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetUnpublishedEvents(100)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantRows := []*OutboxEvent{
+		{ID: 14, TxID: 802, Entity: "project", EntityID: "1", Action: AuditActionAdd, Payload: json.RawMessage(`{"id":1,"name":"cncf","fullname":"CNCF"}`), CreatedAt: createdAt},
+		{ID: 15, TxID: 805, Entity: "repo", EntityID: "3", Action: AuditActionDelete, Payload: json.RawMessage(`{"id":3}`), CreatedAt: createdAt},
+	}
+	if !reflect.DeepEqual(wantRows, gotRows) {
+		t.Errorf("expected %#v, got %#v", wantRows, gotRows)
+	}
+}
+
+func TestShouldGetEventsSinceCheckpointInTransactionOrder(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// event 16 was recorded by a transaction that started earlier
+	// than the one that recorded event 15
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "txid", "entity", "entity_id", "action", "payload", "created_at"}).
+		AddRow(16, 810, "repo_pull", "36", "add", []byte(`{"id":36}`), createdAt).
+		AddRow(15, 811, "repo", "3", "delete", []byte(`{"id":3}`), createdAt)
+	mock.ExpectQuery(`SELECT id, txid, entity, entity_id, action, payload, created_at FROM peridot.outbox_events WHERE \(txid, id\) > \(\$1, \$2\) AND txid < txid_snapshot_xmin\(txid_current_snapshot\(\)\) ORDER BY txid, id LIMIT \$3`).
+		WithArgs(809, 14, 500).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, next, err := db.GetEventsSince(EventCursor{AfterTxID: 809, AfterID: 14}, 500)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantRows := []*OutboxEvent{
+		{ID: 16, TxID: 810, Entity: "repo_pull", EntityID: "36", Action: AuditActionAdd, Payload: json.RawMessage(`{"id":36}`), CreatedAt: createdAt},
+		{ID: 15, TxID: 811, Entity: "repo", EntityID: "3", Action: AuditActionDelete, Payload: json.RawMessage(`{"id":3}`), CreatedAt: createdAt},
+	}
+	if !reflect.DeepEqual(wantRows, gotRows) {
+		t.Errorf("expected %#v, got %#v", wantRows, gotRows)
+	}
+	wantNext := EventCursor{AfterTxID: 811, AfterID: 15}
+	if next == nil || *next != wantNext {
+		t.Errorf("expected %+v, got %+v", wantNext, next)
+	}
+}
+
+func TestShouldKeepEventCursorWhenNoEventsSince(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	cursor := EventCursor{AfterTxID: 811, AfterID: 15}
+	mock.ExpectQuery("SELECT (.+) FROM peridot.outbox_events").
+		WithArgs(811, 15, 500).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "txid", "entity", "entity_id", "action", "payload", "created_at"}))
+
+	// run the tested function
+	gotRows, next, err := db.GetEventsSince(cursor, 500)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 0 {
+		t.Errorf("expected len %d, got %d", 0, len(gotRows))
+	}
+	if next == nil || *next != cursor {
+		t.Errorf("expected cursor %+v to be returned unchanged, got %+v", cursor, next)
+	}
+}
+
+func TestShouldNameEntityWithSpacesInOutboxNotFoundError(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("DELETE FROM peridot.repo_pulls")
+	mock.ExpectExec("DELETE FROM peridot.repo_pulls").
+		WithArgs(413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	// run the tested function
+	err = db.DeleteRepoPull(413)
+	nfe, ok := err.(*NotFoundError)
+	if !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+	if nfe.Entity != "repo pull" {
+		t.Errorf("expected %v, got %v", "repo pull", nfe.Entity)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldMarkEventsPublished(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`UPDATE peridot.outbox_events SET published_at = \$1 WHERE id = ANY \(\$2\) AND published_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), "{14,15}").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	// run the tested function
+	err = db.MarkEventsPublished([]uint64{14, 15})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldPrunePublishedEvents(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	before := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	mock.ExpectExec(`DELETE FROM peridot.outbox_events WHERE published_at < \$1`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 27))
+
+	// run the tested function
+	n, err := db.PrunePublishedEvents(before)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if n != 27 {
+		t.Errorf("expected %v, got %v", 27, n)
+	}
+}
+
+func TestShouldRollBackChangeIfOutboxEventFails(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("DELETE FROM peridot.repos")
+	mock.ExpectExec("DELETE FROM peridot.repos").
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("repo", "3", AuditActionDelete, []byte(`{"id":3}`), sqlmock.AnyArg()).
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	// run the tested function
+	err = db.DeleteRepo(3)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
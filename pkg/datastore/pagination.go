@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SortOrder defines the direction in which a list method orders its
+// results.
+type SortOrder int
+
+const (
+	// SortAscending orders results from lowest to highest. It is
+	// the default.
+	SortAscending SortOrder = 0
+
+	// SortDescending orders results from highest to lowest.
+	SortDescending SortOrder = 1
+)
+
+// PageRequest describes which page of results a list method should
+// return. The zero value requests all results in ascending order.
+//
+// PageRequest is the convention for listings that are shown to users
+// and may be sorted by a choice of fields, such as GetUsers, GetAgents,
+// GetRepoPulls, GetJobs and GetFileInstances. Methods that walk a
+// large table in a fixed order, or that sync changes incrementally,
+// take a KeysetCursor instead, since later pages stay stable as rows
+// are added.
+type PageRequest struct {
+	// Limit is the maximum number of results to return. If 0, all
+	// results are returned.
+	Limit uint32
+	// Offset is the number of results to skip before returning
+	// results.
+	Offset uint32
+	// Order is the direction in which results are sorted.
+	Order SortOrder
+	// SortBy names the field by which results are sorted, such as
+	// "name" or "started_at". Each list method accepts only its
+	// own set of fields. If empty, results are sorted by ID.
+	SortBy string
+}
+
+// sortColumns maps the SortBy field names that a list method
+// accepts to the columns they sort by.
+type sortColumns map[string]string
+
+// Page describes the page of results returned by a list method.
+type Page struct {
+	// Offset is the number of results that were skipped.
+	Offset uint32
+	// Count is the number of results returned in this page.
+	Count uint32
+	// HasMore is true if there are further results after this page.
+	HasMore bool
+}
+
+// NextPage returns the PageRequest for the page following p, using
+// the same limit and sorting as pr.
+func (p Page) NextPage(pr PageRequest) PageRequest {
+	return PageRequest{Limit: pr.Limit, Offset: p.Offset + p.Count, Order: pr.Order, SortBy: pr.SortBy}
+}
+
+// sqlClause returns the ORDER BY, LIMIT and OFFSET clauses for pr,
+// ordering by the column that columns maps pr.SortBy to, and args
+// with any parameters for those clauses appended. Rows are ordered
+// by id after any other column, so that pages are stable when that
+// column has ties. One more row than the limit is requested, so that
+// page can tell whether there are more results. It returns a
+// *ValidationError if pr.SortBy is not in columns.
+func (pr PageRequest) sqlClause(columns sortColumns, args []interface{}) (string, []interface{}, error) {
+	orderBy := []string{"id"}
+	if pr.SortBy != "" && pr.SortBy != "id" {
+		col, ok := columns[pr.SortBy]
+		if !ok {
+			return "", nil, &ValidationError{Entity: "page request", Field: "sort by", Reason: fmt.Sprintf("cannot sort by %q", pr.SortBy)}
+		}
+		orderBy = []string{col, "id"}
+	}
+
+	var dir string
+	switch pr.Order {
+	case SortAscending:
+		dir = ""
+	case SortDescending:
+		dir = " DESC"
+	default:
+		return "", nil, fmt.Errorf("invalid sort order %d", pr.Order)
+	}
+	clause := " ORDER BY " + strings.Join(orderBy, dir+", ") + dir
+
+	if pr.Limit > 0 {
+		args = append(args, pr.Limit+1)
+		clause += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if pr.Offset > 0 {
+		args = append(args, pr.Offset)
+		clause += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return clause, args, nil
+}
+
+// page returns the Page describing n rows fetched with the clause
+// from sqlClause, and the number of those rows to return to the
+// caller, dropping the extra row used to detect further results.
+func (pr PageRequest) page(n int) (Page, int) {
+	p := Page{Offset: pr.Offset}
+	if pr.Limit > 0 && n > int(pr.Limit) {
+		p.HasMore = true
+		n = int(pr.Limit)
+	}
+	p.Count = uint32(n)
+	return p, n
+}
+
+// count runs a query selecting a single COUNT(*), such as a Count
+// method's, and returns the result.
+func (db *DB) count(query string, args ...interface{}) (int, error) {
+	var n int
+	if err := db.sqldb.QueryRow(query, args...).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// exists runs a query selecting a single EXISTS, such as an Exists
+// method's, and returns the result.
+func (db *DB) exists(query string, args ...interface{}) (bool, error) {
+	var ok bool
+	if err := db.sqldb.QueryRow(query, args...).Scan(&ok); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
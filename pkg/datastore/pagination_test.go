@@ -0,0 +1,224 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestShouldGetUsersPageWithMoreResultsDescending(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// one more row than the limit is returned, indicating more results
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(8103918, "janedoe", "Jane Doe", nil, 99, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt).
+		AddRow(410952, "johndoe", "John Doe", nil, 20, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt).
+		AddRow(1, "admin", "Admin", nil, 99, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT (.+) FROM peridot.users ORDER BY id DESC LIMIT \$1`).
+		WithArgs(3).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	pr := PageRequest{Limit: 2, Order: SortDescending}
+	gotRows, page, err := db.GetUsers(UserFilter{}, pr)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	if gotRows[1].ID != 410952 {
+		t.Errorf("expected %v, got %v", 410952, gotRows[1].ID)
+	}
+	wantPage := Page{Offset: 0, Count: 2, HasMore: true}
+	if page != wantPage {
+		t.Errorf("expected %+v, got %+v", wantPage, page)
+	}
+	wantNext := PageRequest{Limit: 2, Offset: 2, Order: SortDescending}
+	if got := page.NextPage(pr); got != wantNext {
+		t.Errorf("expected %+v, got %+v", wantNext, got)
+	}
+}
+
+func TestShouldFailGetAgentsWithInvalidSortOrder(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function; no queries should be made
+	_, _, err = db.GetAgents(AgentFilter{}, PageRequest{Order: SortOrder(57)})
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldGetRepoPullsSortedByStartedAtDescending(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "repo_id", "branch", "started_at", "finished_at", "status", "health", "output", "commit", "tag", "spdx_id", "created_at", "updated_at"}).
+		AddRow(9, 4, "master", time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC), time.Time{}, 2, 1, nil, nil, nil, nil, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT (.+) FROM peridot.repo_pulls WHERE repo_id = \$1 AND branch = \$2 AND repo_id IN \(`+liveRepoIDsRegex+`\) ORDER BY started_at DESC, id DESC LIMIT \$3`).
+		WithArgs(4, "master", 11).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, page, err := db.GetRepoPulls(4, "master", PageRequest{Limit: 10, Order: SortDescending, SortBy: "started_at"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 1 {
+		t.Fatalf("expected len %d, got %d", 1, len(gotRows))
+	}
+	if page.HasMore {
+		t.Errorf("expected no more results")
+	}
+}
+
+func TestShouldFailGetUsersWithUnknownSortField(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function; no queries should be made
+	_, _, err = db.GetUsers(UserFilter{}, PageRequest{SortBy: "email; DROP TABLE peridot.users"})
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldGetJobsSortedByStatusInPageOrder(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	t9 := time.Date(2019, 5, 2, 13, 5, 0, 0, time.UTC)
+	t4 := time.Date(2019, 5, 2, 13, 10, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT id FROM peridot.jobs WHERE repopull_id = \$1 AND repopull_id IN \(`+liveRepoPullIDsRegex+`\) ORDER BY status, id LIMIT \$2 OFFSET \$3`).
+		WithArgs(7, 3, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9).AddRow(4).AddRow(12))
+	mock.ExpectQuery(jobsByIDsRegex).
+		WithArgs(pq.Array([]JobID{9, 4})).
+		WillReturnRows(sqlmock.NewRows(jobsByIDsColumns).
+			AddRow(4, 7, 2, t4, time.Time{}, StatusRunning, HealthOK, "", true, 1, testCreatedAt, testUpdatedAt, "{}", "{}", "{}", "{}", "{}").
+			AddRow(9, 7, 3, t9, time.Time{}, StatusStartup, HealthOK, "", true, 1, testCreatedAt, testUpdatedAt, "{}", "{}", "{}", "{}", "{}"))
+
+	// run the tested function
+	gotRows, page, err := db.GetJobs(7, PageRequest{Limit: 2, Offset: 2, SortBy: "status"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	if gotRows[0].ID != 9 || gotRows[1].ID != 4 {
+		t.Errorf("expected jobs in page order [9 4], got [%v %v]", gotRows[0].ID, gotRows[1].ID)
+	}
+	if page.Offset != 2 || page.Count != 2 || !page.HasMore {
+		t.Errorf("expected page {2 2 true}, got %+v", page)
+	}
+}
+
+func TestShouldGetFileInstancesSortedByPath(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "repopull_id", "filehash_id", "path"}).
+		AddRow(14, 7, 3, "/LICENSE").
+		AddRow(11, 7, 5, "/README.md")
+	mock.ExpectQuery(`SELECT id, repopull_id, filehash_id, path FROM peridot.file_instances WHERE repopull_id = \$1 ORDER BY path, id`).
+		WithArgs(7).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, page, err := db.GetFileInstances(7, PageRequest{SortBy: "path"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	if gotRows[0].Path != "/LICENSE" || gotRows[1].Path != "/README.md" {
+		t.Errorf("expected paths in order, got %q, %q", gotRows[0].Path, gotRows[1].Path)
+	}
+	if page.Count != 2 || page.HasMore {
+		t.Errorf("expected page of 2 with no more, got %+v", page)
+	}
+}
@@ -0,0 +1,230 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// JobTemplate describes one step of a pipeline of Jobs to be created
+// for a new RepoPull by AddRepoPullWithPipeline, or by
+// AddRepoPullWithDefaultPipeline from its project's default pipeline.
+// Since the jobs do not exist yet, earlier steps are referred to by
+// their 1-based position in the pipeline rather than by job ID.
+type JobTemplate struct {
+	// AgentID is the ID of the Agent that will run the job.
+	AgentID AgentID `json:"agent_id"`
+	// PriorSteps are the positions of the earlier steps whose jobs
+	// must finish before this one's can run.
+	PriorSteps []int `json:"prior_steps,omitempty"`
+	// Config configures the job. In its CodeReader and SpdxReader
+	// JobPathConfigs, a non-zero PriorJobID is the position of an
+	// earlier step whose job's output is passed along.
+	Config JobConfig `json:"config"`
+}
+
+// validatePipeline checks that every step of pipeline only refers to
+// steps before it, returning a *ValidationError if not.
+func validatePipeline(pipeline []JobTemplate) error {
+	for i, jt := range pipeline {
+		step := i + 1
+		for _, prior := range jt.PriorSteps {
+			if prior < 1 || prior >= step {
+				return &ValidationError{Entity: "pipeline", Field: fmt.Sprintf("step %d prior steps", step), Reason: "must refer to earlier steps"}
+			}
+		}
+		for _, pcs := range []map[string]JobPathConfig{jt.Config.CodeReader, jt.Config.SpdxReader} {
+			for _, pc := range pcs {
+				if pc.PriorJobID != 0 && (pc.PriorJobID < 1 || int(pc.PriorJobID) >= step) {
+					return &ValidationError{Entity: "pipeline", Field: fmt.Sprintf("step %d config", step), Reason: "must refer to earlier steps"}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resolvePathConfigs returns a copy of pcs with each PriorJobID,
+// which is a step position, replaced by the ID of that step's job.
+func resolvePathConfigs(pcs map[string]JobPathConfig, jobIDs []JobID) map[string]JobPathConfig {
+	if pcs == nil {
+		return nil
+	}
+	resolved := make(map[string]JobPathConfig, len(pcs))
+	for k, pc := range pcs {
+		if pc.PriorJobID != 0 {
+			pc.PriorJobID = jobIDs[pc.PriorJobID-1]
+		}
+		resolved[k] = pc
+	}
+	return resolved
+}
+
+// AddRepoPullWithPipeline adds a new repo pull as in AddRepoPull,
+// together with a Job for each step of pipeline, all in a single
+// transaction, so that either the pull is added with all of its jobs
+// or nothing is added at all. It returns the new repo pull's ID and
+// the IDs of its jobs, in pipeline order, on success or an error if
+// failing. It returns a *ValidationError if a step refers to a step
+// that does not come before it.
+func (db *DB) AddRepoPullWithPipeline(repoID RepoID, branch string, commit string, tag string, spdxID string, pipeline []JobTemplate) (RepoPullID, []JobID, error) {
+	return addRepoPullWithPipeline(db, repoID, branch, commit, tag, spdxID, pipeline)
+}
+
+// addRepoPullWithPipeline implements AddRepoPullWithPipeline using
+// ds's own methods, so that a wrapping Datastore audits or limits the
+// repo pull and jobs as it does when they are added one by one.
+func addRepoPullWithPipeline(ds Datastore, repoID RepoID, branch string, commit string, tag string, spdxID string, pipeline []JobTemplate) (RepoPullID, []JobID, error) {
+	if err := validatePipeline(pipeline); err != nil {
+		return 0, nil, err
+	}
+
+	var rpID RepoPullID
+	var jobIDs []JobID
+	err := ds.InTx(context.Background(), func(tx Datastore) error {
+		var err error
+		rpID, err = tx.AddRepoPull(repoID, branch, commit, tag, spdxID)
+		if err != nil {
+			return err
+		}
+		jobIDs, err = addPipelineJobs(tx, rpID, pipeline)
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return rpID, jobIDs, nil
+}
+
+// addPipelineJobs adds a Job for each step of pipeline to the
+// RepoPull with the given ID, returning their IDs in pipeline order.
+// pipeline must already have been validated.
+func addPipelineJobs(tx Datastore, rpID RepoPullID, pipeline []JobTemplate) ([]JobID, error) {
+	jobIDs := make([]JobID, len(pipeline))
+	for i, jt := range pipeline {
+		priorJobIDs := make([]JobID, len(jt.PriorSteps))
+		for k, prior := range jt.PriorSteps {
+			priorJobIDs[k] = jobIDs[prior-1]
+		}
+		var err error
+		jobIDs[i], err = tx.AddJobWithConfigs(rpID, jt.AgentID, priorJobIDs, jt.Config.KV, resolvePathConfigs(jt.Config.CodeReader, jobIDs), resolvePathConfigs(jt.Config.SpdxReader, jobIDs))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jobIDs, nil
+}
+
+// GetProjectPipeline returns the default pipeline of the Project
+// with the given ID, or nil and a *NotFoundError if it has none.
+func (db *DB) GetProjectPipeline(projectID ProjectID) ([]JobTemplate, error) {
+	var js []byte
+	err := db.sqldb.QueryRow("SELECT pipeline FROM peridot.project_pipelines WHERE project_id = $1", projectID).Scan(&js)
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "pipeline", Key: "project ID", ID: fmt.Sprint(projectID)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pipeline []JobTemplate
+	if err = json.Unmarshal(js, &pipeline); err != nil {
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+// SetProjectPipeline stores pipeline as the default pipeline of the
+// Project with the given ID, replacing any it already has. It
+// returns a *ValidationError if a step refers to a step that does
+// not come before it, nil on success or another error if failing.
+func (db *DB) SetProjectPipeline(projectID ProjectID, pipeline []JobTemplate) error {
+	if err := validatePipeline(pipeline); err != nil {
+		return err
+	}
+	if pipeline == nil {
+		pipeline = []JobTemplate{}
+	}
+	js, err := json.Marshal(pipeline)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.project_pipelines(project_id, pipeline) VALUES ($1, $2) ON CONFLICT (project_id) DO UPDATE SET pipeline = EXCLUDED.pipeline")
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(projectID, js)
+	return translateConstraintError("pipeline", err)
+}
+
+// DeleteProjectPipeline removes the default pipeline of the Project
+// with the given ID, so that AddRepoPullWithDefaultPipeline adds its
+// pulls without any jobs. It returns a *NotFoundError if it has
+// none, nil on success or another error if failing.
+func (db *DB) DeleteProjectPipeline(projectID ProjectID) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.project_pipelines WHERE project_id = $1", projectID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "pipeline", Key: "project ID", ID: fmt.Sprint(projectID)}
+	}
+	return nil
+}
+
+// AddRepoPullWithDefaultPipeline adds a new repo pull as in
+// AddRepoPull, together with a Job for each step of the default
+// pipeline of the repo's project, all in a single transaction, so
+// that a controller does not need to look up and instantiate the
+// pipeline itself. If the project has no default pipeline, the pull
+// is added without any jobs. It returns the new repo pull's ID and
+// the IDs of its jobs, in pipeline order, on success or an error if
+// failing.
+func (db *DB) AddRepoPullWithDefaultPipeline(repoID RepoID, branch string, commit string, tag string, spdxID string) (RepoPullID, []JobID, error) {
+	return addRepoPullWithDefaultPipeline(db, repoID, branch, commit, tag, spdxID)
+}
+
+// addRepoPullWithDefaultPipeline implements
+// AddRepoPullWithDefaultPipeline using ds's own methods, as
+// addRepoPullWithPipeline does. The pipeline is read within the same
+// transaction, so that the jobs match the pipeline as it was when
+// the pull was added.
+func addRepoPullWithDefaultPipeline(ds Datastore, repoID RepoID, branch string, commit string, tag string, spdxID string) (RepoPullID, []JobID, error) {
+	var rpID RepoPullID
+	var jobIDs []JobID
+	err := ds.InTx(context.Background(), func(tx Datastore) error {
+		repo, err := tx.GetRepoByID(repoID)
+		if err != nil {
+			return err
+		}
+		sp, err := tx.GetSubprojectByID(repo.SubprojectID)
+		if err != nil {
+			return err
+		}
+		pipeline, err := tx.GetProjectPipeline(sp.ProjectID)
+		var nfe *NotFoundError
+		if err != nil && !errors.As(err, &nfe) {
+			return err
+		}
+
+		rpID, err = tx.AddRepoPull(repoID, branch, commit, tag, spdxID)
+		if err != nil {
+			return err
+		}
+		jobIDs, err = addPipelineJobs(tx, rpID, pipeline)
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return rpID, jobIDs, nil
+}
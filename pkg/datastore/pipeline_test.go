@@ -0,0 +1,293 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldAddRepoPullWithPipeline(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO peridot.repo_pulls")
+	mock.ExpectQuery("INSERT INTO peridot.repo_pulls").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO peridot.jobs")
+	mock.ExpectQuery("INSERT INTO peridot.jobs").
+		WithArgs(12, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), StatusStartup, HealthOK, "", false).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO peridot.jobs")
+	mock.ExpectQuery("INSERT INTO peridot.jobs").
+		WithArgs(12, 2, sqlmock.AnyArg(), sqlmock.AnyArg(), StatusStartup, HealthOK, "", false).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(21))
+	mock.ExpectPrepare("INSERT INTO peridot.jobpriorids")
+	mock.ExpectExec("INSERT INTO peridot.jobpriorids").
+		WithArgs(21, 20).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO peridot.jobpathconfigs")
+	mock.ExpectExec("INSERT INTO peridot.jobpathconfigs").
+		WithArgs(21, IntFromJobConfigType(JobConfigCodeReader), "primary", "", 20).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	// run the tested function
+	pipeline := []JobTemplate{
+		{AgentID: 1},
+		{AgentID: 2, PriorSteps: []int{1}, Config: JobConfig{CodeReader: map[string]JobPathConfig{"primary": {PriorJobID: 1}}}},
+	}
+	rpID, jobIDs, err := db.AddRepoPullWithPipeline(5, "main", "0123456789abcdef0123456789abcdef01234567", "", "SPDXRef-main", pipeline)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if rpID != 12 {
+		t.Errorf("expected %v, got %v", 12, rpID)
+	}
+	if !reflect.DeepEqual(jobIDs, []JobID{20, 21}) {
+		t.Errorf("expected %v, got %v", []JobID{20, 21}, jobIDs)
+	}
+}
+
+func TestShouldFailAddRepoPullWithPipelineReferringToLaterStep(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	pipelines := [][]JobTemplate{
+		{{AgentID: 1, PriorSteps: []int{1}}},
+		{{AgentID: 1}, {AgentID: 2, PriorSteps: []int{0}}},
+		{{AgentID: 1, Config: JobConfig{SpdxReader: map[string]JobPathConfig{"primary": {PriorJobID: 2}}}}, {AgentID: 2}},
+	}
+	for _, pipeline := range pipelines {
+		_, _, err = db.AddRepoPullWithPipeline(5, "main", "0123456789abcdef0123456789abcdef01234567", "", "SPDXRef-main", pipeline)
+		if _, ok := err.(*ValidationError); !ok {
+			t.Errorf("expected *ValidationError for %+v, got %v", pipeline, err)
+		}
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldSetProjectPipeline(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.project_pipelines\(project_id, pipeline\) VALUES \(\$1, \$2\) ON CONFLICT \(project_id\) DO UPDATE SET pipeline = EXCLUDED.pipeline`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(2, []byte(`[{"agent_id":1,"config":{}},{"agent_id":2,"prior_steps":[1],"config":{"codereader":{"primary":{"priorjob_id":1}}}}]`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	pipeline := []JobTemplate{
+		{AgentID: 1},
+		{AgentID: 2, PriorSteps: []int{1}, Config: JobConfig{CodeReader: map[string]JobPathConfig{"primary": {PriorJobID: 1}}}},
+	}
+	err = db.SetProjectPipeline(2, pipeline)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailSetProjectPipelineReferringToLaterStep(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	err = db.SetProjectPipeline(2, []JobTemplate{{AgentID: 1, PriorSteps: []int{2}}, {AgentID: 2}})
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldAddRepoPullWithDefaultPipeline(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, subproject_id, name, address, created_at, updated_at FROM peridot.repos WHERE id = \$1`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subproject_id", "name", "address", "created_at", "updated_at"}).AddRow(5, 3, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git", testCreatedAt, testUpdatedAt))
+	mock.ExpectQuery(`SELECT id, project_id, name, fullname, created_at, updated_at FROM peridot.subprojects WHERE id = \$1`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "fullname", "created_at", "updated_at"}).AddRow(3, 2, "kubernetes", "Kubernetes", testCreatedAt, testUpdatedAt))
+	mock.ExpectQuery(`SELECT pipeline FROM peridot.project_pipelines WHERE project_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"pipeline"}).AddRow([]byte(`[{"agent_id":1},{"agent_id":2,"prior_steps":[1]}]`)))
+	mock.ExpectExec(`SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO peridot.repo_pulls")
+	mock.ExpectQuery("INSERT INTO peridot.repo_pulls").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO peridot.jobs")
+	mock.ExpectQuery("INSERT INTO peridot.jobs").
+		WithArgs(12, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), StatusStartup, HealthOK, "", false).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO peridot.jobs")
+	mock.ExpectQuery("INSERT INTO peridot.jobs").
+		WithArgs(12, 2, sqlmock.AnyArg(), sqlmock.AnyArg(), StatusStartup, HealthOK, "", false).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(21))
+	mock.ExpectPrepare("INSERT INTO peridot.jobpriorids")
+	mock.ExpectExec("INSERT INTO peridot.jobpriorids").
+		WithArgs(21, 20).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	// run the tested function
+	rpID, jobIDs, err := db.AddRepoPullWithDefaultPipeline(5, "main", "0123456789abcdef0123456789abcdef01234567", "", "SPDXRef-main")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if rpID != 12 {
+		t.Errorf("expected %v, got %v", 12, rpID)
+	}
+	if !reflect.DeepEqual(jobIDs, []JobID{20, 21}) {
+		t.Errorf("expected %v, got %v", []JobID{20, 21}, jobIDs)
+	}
+}
+
+func TestShouldAddRepoPullWithoutJobsIfProjectHasNoDefaultPipeline(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, subproject_id, name, address, created_at, updated_at FROM peridot.repos WHERE id = \$1`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subproject_id", "name", "address", "created_at", "updated_at"}).AddRow(5, 3, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git", testCreatedAt, testUpdatedAt))
+	mock.ExpectQuery(`SELECT id, project_id, name, fullname, created_at, updated_at FROM peridot.subprojects WHERE id = \$1`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "fullname", "created_at", "updated_at"}).AddRow(3, 2, "kubernetes", "Kubernetes", testCreatedAt, testUpdatedAt))
+	mock.ExpectQuery(`SELECT pipeline FROM peridot.project_pipelines WHERE project_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"pipeline"}))
+	mock.ExpectExec(`SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO peridot.repo_pulls")
+	mock.ExpectQuery("INSERT INTO peridot.repo_pulls").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	// run the tested function
+	rpID, jobIDs, err := db.AddRepoPullWithDefaultPipeline(5, "main", "0123456789abcdef0123456789abcdef01234567", "", "SPDXRef-main")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if rpID != 12 {
+		t.Errorf("expected %v, got %v", 12, rpID)
+	}
+	if len(jobIDs) != 0 {
+		t.Errorf("expected no jobs, got %v", jobIDs)
+	}
+}
@@ -0,0 +1,289 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Severity thresholds accepted in PolicyRules.SeverityThreshold, in
+// increasing order of severity.
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// PolicyRules are the rules that a Policy applies when evaluating a
+// RepoPull. They are stored as JSON, so that new kinds of rules can
+// be added without changing the table.
+type PolicyRules struct {
+	// AllowedLicenses are the SPDX IDs of licenses that are
+	// permitted. If non-empty, any other license is a violation.
+	AllowedLicenses []string `json:"allowed_licenses,omitempty"`
+	// DeniedLicenses are the SPDX IDs of licenses that are not
+	// permitted.
+	DeniedLicenses []string `json:"denied_licenses,omitempty"`
+	// SeverityThreshold is the lowest severity of security issue
+	// that is a violation, e.g. SeverityHigh. If empty, security
+	// issues are not evaluated.
+	SeverityThreshold string `json:"severity_threshold,omitempty"`
+}
+
+// Validate checks that the PolicyRules are well-formed. It returns
+// nil if so, or a *ValidationError describing the first invalid
+// field.
+func (pr *PolicyRules) Validate() error {
+	allowed := map[string]bool{}
+	for _, id := range pr.AllowedLicenses {
+		if id == "" {
+			return &ValidationError{Entity: "policy", Field: "rules.allowed_licenses", Reason: "must not contain empty license IDs"}
+		}
+		allowed[id] = true
+	}
+	for _, id := range pr.DeniedLicenses {
+		if id == "" {
+			return &ValidationError{Entity: "policy", Field: "rules.denied_licenses", Reason: "must not contain empty license IDs"}
+		}
+		if allowed[id] {
+			return &ValidationError{Entity: "policy", Field: "rules.denied_licenses", Reason: fmt.Sprintf("%s is also allowed", id)}
+		}
+	}
+
+	switch pr.SeverityThreshold {
+	case "", SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical:
+	default:
+		return &ValidationError{Entity: "policy", Field: "rules.severity_threshold", Reason: "must be low, medium, high or critical"}
+	}
+	return nil
+}
+
+// Policy describes one version of a named set of license and
+// security rules. A Policy applies either to a single Project or,
+// if ProjectID is 0, globally. Changing a Policy's rules creates a
+// new version; earlier versions are kept so that past evaluations
+// can be traced to the rules that were in force.
+type Policy struct {
+	// ID is the unique ID for this policy, shared by all of its
+	// versions.
+	ID uint32 `json:"id"`
+	// ProjectID is the ID of the Project to which the policy
+	// applies, or 0 if it applies to all projects.
+	ProjectID ProjectID `json:"project_id,omitempty"`
+	// Name is the policy's name, unique within its scope.
+	Name string `json:"name"`
+	// Version is the version number of these rules, starting at 1.
+	Version uint32 `json:"version"`
+	// Rules are the rules in this version of the policy.
+	Rules PolicyRules `json:"rules"`
+	// CreatedAt is when this version was created.
+	CreatedAt time.Time `json:"created_at"`
+	// CreatedBy is the ID of the User who created this version, or
+	// 0 if unknown.
+	CreatedBy UserID `json:"created_by,omitempty"`
+}
+
+// Validate checks that the Policy's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (p *Policy) Validate() error {
+	if err := requireNonEmpty("policy", "name", p.Name); err != nil {
+		return err
+	}
+	return p.Rules.Validate()
+}
+
+const policyColumns = "p.id, p.project_id, p.name, v.version, v.rules, v.created_at, v.created_by"
+
+// scanPolicy reads a Policy from a row selecting policyColumns.
+func scanPolicy(rs rowScanner) (*Policy, error) {
+	p := &Policy{}
+	var projectID, createdBy sql.NullInt64
+	var rules []byte
+	err := rs.Scan(&p.ID, &projectID, &p.Name, &p.Version, &rules, &p.CreatedAt, &createdBy)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(rules, &p.Rules); err != nil {
+		return nil, err
+	}
+	p.ProjectID = ProjectID(projectID.Int64)
+	p.CreatedBy = UserID(createdBy.Int64)
+	p.CreatedAt = normalizeTime(p.CreatedAt)
+	return p, nil
+}
+
+// queryPolicies runs a query selecting policyColumns and returns the
+// resulting policies.
+func (db *DB) queryPolicies(query string, args ...interface{}) ([]*Policy, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []*Policy{}
+	for rows.Next() {
+		p, err := scanPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// GetAllPolicies returns a slice of the current version of all
+// policies, global and per-project, ordered by ID.
+func (db *DB) GetAllPolicies() ([]*Policy, error) {
+	return db.queryPolicies("SELECT " + policyColumns + " FROM peridot.policies p JOIN peridot.policy_versions v ON v.policy_id = p.id AND v.version = p.current_version ORDER BY p.id")
+}
+
+// CountAllPolicies returns the number of policies in the database.
+func (db *DB) CountAllPolicies() (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.policies p JOIN peridot.policy_versions v ON v.policy_id = p.id AND v.version = p.current_version")
+}
+
+// GetPoliciesForProject returns a slice of the current version of
+// all policies that apply to the Project with the given ID, that
+// is, both global policies and the project's own, ordered by ID.
+func (db *DB) GetPoliciesForProject(projectID ProjectID) ([]*Policy, error) {
+	return db.queryPolicies("SELECT "+policyColumns+" FROM peridot.policies p JOIN peridot.policy_versions v ON v.policy_id = p.id AND v.version = p.current_version WHERE p.project_id IS NULL OR p.project_id = $1 ORDER BY p.id", projectID)
+}
+
+// GetPolicyByID returns the current version of the Policy with the
+// given ID, or nil and an error if not found.
+func (db *DB) GetPolicyByID(id uint32) (*Policy, error) {
+	p, err := scanPolicy(db.sqldb.QueryRow("SELECT "+policyColumns+" FROM peridot.policies p JOIN peridot.policy_versions v ON v.policy_id = p.id AND v.version = p.current_version WHERE p.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "policy", ID: fmt.Sprint(id)}
+	}
+	return p, err
+}
+
+// GetPolicyVersion returns the given version of the Policy with the
+// given ID, or nil and an error if not found.
+func (db *DB) GetPolicyVersion(id uint32, version uint32) (*Policy, error) {
+	p, err := scanPolicy(db.sqldb.QueryRow("SELECT "+policyColumns+" FROM peridot.policies p JOIN peridot.policy_versions v ON v.policy_id = p.id WHERE p.id = $1 AND v.version = $2", id, version))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "policy", Key: "ID/version", ID: fmt.Sprintf("%d/%d", id, version)}
+	}
+	return p, err
+}
+
+// GetPolicyHistory returns a slice of all versions of the Policy
+// with the given ID, ordered from oldest to newest.
+func (db *DB) GetPolicyHistory(id uint32) ([]*Policy, error) {
+	return db.queryPolicies("SELECT "+policyColumns+" FROM peridot.policies p JOIN peridot.policy_versions v ON v.policy_id = p.id WHERE p.id = $1 ORDER BY v.version", id)
+}
+
+// AddPolicy adds a new policy with the given name and rules, scoped
+// to the Project with the given ID or, if projectID is 0, globally.
+// Its first version is attributed to the User with ID createdBy,
+// which may be 0 if unknown. It returns the new policy's ID on
+// success or an error if failing.
+func (db *DB) AddPolicy(projectID ProjectID, name string, rules PolicyRules, createdBy UserID) (uint32, error) {
+	p := &Policy{ProjectID: projectID, Name: name, Rules: rules}
+	if err := p.Validate(); err != nil {
+		return 0, err
+	}
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+
+	var policyID uint32
+	err = tx.QueryRow("INSERT INTO peridot.policies(project_id, name, current_version) VALUES ($1, $2, 1) RETURNING id", sql.NullInt64{Int64: int64(projectID), Valid: projectID != 0}, name).Scan(&policyID)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	_, err = tx.Exec("INSERT INTO peridot.policy_versions(policy_id, version, rules, created_at, created_by) VALUES ($1, 1, $2, $3, $4)", policyID, rulesJSON, now(), sql.NullInt64{Int64: int64(createdBy), Valid: createdBy != 0})
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return policyID, nil
+}
+
+// UpdatePolicyRules creates a new version of the existing Policy
+// with the given ID, with the given rules, attributed to the User
+// with ID updatedBy. Earlier versions are retained. It returns the
+// new version number on success or an error if failing.
+func (db *DB) UpdatePolicyRules(id uint32, rules PolicyRules, updatedBy UserID) (uint32, error) {
+	if err := rules.Validate(); err != nil {
+		return 0, err
+	}
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+
+	// bumping current_version also locks the policy's row until
+	// the new version is inserted
+	var version uint32
+	err = tx.QueryRow("UPDATE peridot.policies SET current_version = current_version + 1 WHERE id = $1 RETURNING current_version", id).Scan(&version)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return 0, &NotFoundError{Entity: "policy", ID: fmt.Sprint(id)}
+	}
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	_, err = tx.Exec("INSERT INTO peridot.policy_versions(policy_id, version, rules, created_at, created_by) VALUES ($1, $2, $3, $4, $5)", id, version, rulesJSON, now(), sql.NullInt64{Int64: int64(updatedBy), Valid: updatedBy != 0})
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// DeletePolicy deletes the existing Policy with the given ID,
+// together with all of its versions. It returns nil on success or
+// an error if failing.
+func (db *DB) DeletePolicy(id uint32) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.policies WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "policy", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetPolicyByID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "project_id", "name", "version", "rules", "created_at", "created_by"}).
+		AddRow(3, nil, "default", 2, []byte(`{"denied_licenses":["GPL-3.0-only","AGPL-3.0-only"],"severity_threshold":"high"}`), createdAt, 10)
+	mock.ExpectQuery(`SELECT p.id, p.project_id, p.name, v.version, v.rules, v.created_at, v.created_by FROM peridot.policies p JOIN peridot.policy_versions v ON v.policy_id = p.id AND v.version = p.current_version WHERE p.id = \$1`).
+		WithArgs(3).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	p, err := db.GetPolicyByID(3)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := &Policy{
+		ID:      3,
+		Name:    "default",
+		Version: 2,
+		Rules: PolicyRules{
+			DeniedLicenses:    []string{"GPL-3.0-only", "AGPL-3.0-only"},
+			SeverityThreshold: SeverityHigh,
+		},
+		CreatedAt: createdAt,
+		CreatedBy: 10,
+	}
+	if !reflect.DeepEqual(want, p) {
+		t.Errorf("expected %#v, got %#v", want, p)
+	}
+}
+
+func TestShouldFailGetPolicyVersionWithUnknownVersion(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT (.+) FROM peridot.policies p JOIN peridot.policy_versions v ON v.policy_id = p.id WHERE p.id = \$1 AND v.version = \$2`).
+		WithArgs(3, 7).
+		WillReturnError(sql.ErrNoRows)
+
+	// run the tested function
+	_, err = db.GetPolicyVersion(3, 7)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err.Error() != "no policy found with ID/version 3/7" {
+		t.Errorf("unexpected error message %q", err.Error())
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldAddGlobalPolicy(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO peridot.policies\(project_id, name, current_version\) VALUES \(\$1, \$2, 1\) RETURNING id`).
+		WithArgs(nilArg{}, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectExec(`INSERT INTO peridot.policy_versions\(policy_id, version, rules, created_at, created_by\) VALUES \(\$1, 1, \$2, \$3, \$4\)`).
+		WithArgs(3, []byte(`{"allowed_licenses":["MIT","Apache-2.0"]}`), sqlmock.AnyArg(), 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	id, err := db.AddPolicy(0, "default", PolicyRules{AllowedLicenses: []string{"MIT", "Apache-2.0"}}, 10)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 3 {
+		t.Errorf("expected %v, got %v", 3, id)
+	}
+}
+
+func TestShouldUpdatePolicyRulesAsNewVersion(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE peridot.policies SET current_version = current_version \+ 1 WHERE id = \$1 RETURNING current_version`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"current_version"}).AddRow(2))
+	mock.ExpectExec(`INSERT INTO peridot.policy_versions\(policy_id, version, rules, created_at, created_by\) VALUES \(\$1, \$2, \$3, \$4, \$5\)`).
+		WithArgs(3, 2, []byte(`{"severity_threshold":"critical"}`), sqlmock.AnyArg(), nilArg{}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	version, err := db.UpdatePolicyRules(3, PolicyRules{SeverityThreshold: SeverityCritical}, 0)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if version != 2 {
+		t.Errorf("expected %v, got %v", 2, version)
+	}
+}
+
+func TestShouldFailUpdatePolicyRulesWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE peridot.policies SET current_version`).
+		WithArgs(413).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	// run the tested function
+	_, err = db.UpdatePolicyRules(413, PolicyRules{}, 10)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailPolicyRulesWithInvalidFields(t *testing.T) {
+	tests := []struct {
+		rules PolicyRules
+		field string
+	}{
+		{PolicyRules{AllowedLicenses: []string{"MIT", ""}}, "rules.allowed_licenses"},
+		{PolicyRules{AllowedLicenses: []string{"MIT"}, DeniedLicenses: []string{"MIT"}}, "rules.denied_licenses"},
+		{PolicyRules{SeverityThreshold: "severe"}, "rules.severity_threshold"},
+	}
+
+	for _, tt := range tests {
+		err := tt.rules.Validate()
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("for %#v: expected *ValidationError, got %v", tt.rules, err)
+			continue
+		}
+		if verr.Field != tt.field {
+			t.Errorf("expected field %v, got %v", tt.field, verr.Field)
+		}
+	}
+}
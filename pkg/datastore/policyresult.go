@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// PolicyViolation describes one way in which a RepoPull failed to
+// comply with a Policy.
+type PolicyViolation struct {
+	// Rule is the name of the PolicyRules field that was violated,
+	// e.g. "denied_licenses".
+	Rule string `json:"rule"`
+	// Message describes the violation for display.
+	Message string `json:"message"`
+	// FileInstanceID is the ID of the FileInstance that caused the
+	// violation, if any.
+	FileInstanceID uint64 `json:"fileinstance_id,omitempty"`
+	// ComponentID is the ID of the Component that caused the
+	// violation, if any.
+	ComponentID uint64 `json:"component_id,omitempty"`
+}
+
+// PolicyResult describes the outcome of evaluating one version of a
+// Policy against a RepoPull.
+type PolicyResult struct {
+	// ID is the unique ID for this result.
+	ID uint64 `json:"id"`
+	// PolicyID is the ID of the Policy that was evaluated.
+	PolicyID uint32 `json:"policy_id"`
+	// PolicyVersion is the version of the Policy that was
+	// evaluated.
+	PolicyVersion uint32 `json:"policy_version"`
+	// RepoPullID is the ID of the RepoPull that was evaluated.
+	RepoPullID RepoPullID `json:"repopull_id"`
+	// JobID is the ID of the Job that performed the evaluation, or
+	// 0 if it was not performed by a Job.
+	JobID JobID `json:"job_id,omitempty"`
+	// Passed is true if the RepoPull complied with the Policy.
+	Passed bool `json:"passed"`
+	// Violations lists the ways in which the RepoPull failed to
+	// comply. It is empty if Passed is true.
+	Violations []PolicyViolation `json:"violations,omitempty"`
+	// EvaluatedAt is when the result was recorded.
+	EvaluatedAt time.Time `json:"evaluated_at"`
+}
+
+// Validate checks that the PolicyResult's fields are well-formed.
+// It returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (pr *PolicyResult) Validate() error {
+	if pr.Passed && len(pr.Violations) > 0 {
+		return &ValidationError{Entity: "policy result", Field: "violations", Reason: "must be empty for a passing result"}
+	}
+	if !pr.Passed && len(pr.Violations) == 0 {
+		return &ValidationError{Entity: "policy result", Field: "violations", Reason: "must not be empty for a failing result"}
+	}
+	for _, v := range pr.Violations {
+		if err := requireNonEmpty("policy result", "violations.rule", v.Rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const policyResultColumns = "pr.id, pr.policy_id, pr.policy_version, pr.repopull_id, pr.job_id, pr.passed, pr.violations, pr.evaluated_at"
+
+// scanPolicyResult reads a PolicyResult from a row selecting
+// policyResultColumns, followed by any extra destinations.
+func scanPolicyResult(rs rowScanner, extra ...interface{}) (*PolicyResult, error) {
+	pr := &PolicyResult{}
+	var jobID sql.NullInt64
+	var violations []byte
+	dest := append([]interface{}{&pr.ID, &pr.PolicyID, &pr.PolicyVersion, &pr.RepoPullID, &jobID, &pr.Passed, &violations, &pr.EvaluatedAt}, extra...)
+	err := rs.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	if violations != nil {
+		if err = json.Unmarshal(violations, &pr.Violations); err != nil {
+			return nil, err
+		}
+	}
+	pr.JobID = JobID(jobID.Int64)
+	pr.EvaluatedAt = normalizeTime(pr.EvaluatedAt)
+	return pr, nil
+}
+
+// GetPolicyResultsForRepoPull returns a slice of all policy results
+// recorded for the RepoPull with the given ID, ordered from oldest
+// to newest.
+func (db *DB) GetPolicyResultsForRepoPull(rpID RepoPullID) ([]*PolicyResult, error) {
+	rows, err := db.sqldb.Query("SELECT "+policyResultColumns+" FROM peridot.policy_results pr WHERE pr.repopull_id = $1 ORDER BY pr.evaluated_at, pr.id", rpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*PolicyResult{}
+	for rows.Next() {
+		pr, err := scanPolicyResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, pr)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetLatestPolicyResultsForRepo returns the most recent result for
+// each Policy that has been evaluated against any RepoPull of the
+// Repo with the given ID, ordered by policy ID. This is the repo's
+// current compliance status.
+func (db *DB) GetLatestPolicyResultsForRepo(repoID RepoID) ([]*PolicyResult, error) {
+	rows, err := db.sqldb.Query("SELECT DISTINCT ON (pr.policy_id) "+policyResultColumns+" FROM peridot.policy_results pr JOIN peridot.repo_pulls rp ON rp.id = pr.repopull_id WHERE rp.repo_id = $1 AND rp.repo_id IN ("+liveRepoIDs+") ORDER BY pr.policy_id, pr.evaluated_at DESC, pr.id DESC", repoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*PolicyResult{}
+	for rows.Next() {
+		pr, err := scanPolicyResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, pr)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetLatestPolicyResultsForProject returns, for each Repo in the
+// Project with the given ID, the most recent result for each Policy
+// that has been evaluated against it, keyed by repo ID and ordered
+// by policy ID. Repos with no results are omitted.
+func (db *DB) GetLatestPolicyResultsForProject(projectID ProjectID) (map[RepoID][]*PolicyResult, error) {
+	rows, err := db.sqldb.Query("SELECT DISTINCT ON (rp.repo_id, pr.policy_id) "+policyResultColumns+", rp.repo_id FROM peridot.policy_results pr JOIN peridot.repo_pulls rp ON rp.id = pr.repopull_id JOIN peridot.repos r ON r.id = rp.repo_id JOIN peridot.subprojects sp ON sp.id = r.subproject_id WHERE sp.project_id = $1 AND r.id IN ("+liveRepoIDs+") ORDER BY rp.repo_id, pr.policy_id, pr.evaluated_at DESC, pr.id DESC", projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := map[RepoID][]*PolicyResult{}
+	for rows.Next() {
+		var repoID RepoID
+		pr, err := scanPolicyResult(rows, &repoID)
+		if err != nil {
+			return nil, err
+		}
+		results[repoID] = append(results[repoID], pr)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// AddPolicyResult records the result of evaluating a Policy against
+// a RepoPull. pr's ID and EvaluatedAt fields are ignored. It returns
+// the new result's ID on success or an error if failing.
+func (db *DB) AddPolicyResult(pr *PolicyResult) (uint64, error) {
+	if err := pr.Validate(); err != nil {
+		return 0, err
+	}
+
+	var violations interface{}
+	if len(pr.Violations) > 0 {
+		b, err := json.Marshal(pr.Violations)
+		if err != nil {
+			return 0, err
+		}
+		violations = b
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.policy_results(policy_id, policy_version, repopull_id, job_id, passed, violations, evaluated_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var resultID uint64
+	err = stmt.QueryRow(pr.PolicyID, pr.PolicyVersion, pr.RepoPullID, sql.NullInt64{Int64: int64(pr.JobID), Valid: pr.JobID != 0}, pr.Passed, violations, now()).Scan(&resultID)
+	if err != nil {
+		return 0, err
+	}
+	return resultID, nil
+}
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetLatestPolicyResultsForRepo(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	evalAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "policy_id", "policy_version", "repopull_id", "job_id", "passed", "violations", "evaluated_at"}).
+		AddRow(81, 3, 2, 36, 12, false, []byte(`[{"rule":"denied_licenses","message":"GPL-3.0-only is denied","fileinstance_id":7}]`), evalAt).
+		AddRow(79, 5, 1, 35, nil, true, nil, evalAt)
+	mock.ExpectQuery(`SELECT DISTINCT ON \(pr.policy_id\) pr.id, pr.policy_id, pr.policy_version, pr.repopull_id, pr.job_id, pr.passed, pr.violations, pr.evaluated_at FROM peridot.policy_results pr JOIN peridot.repo_pulls rp ON rp.id = pr.repopull_id WHERE rp.repo_id = \$1 AND rp.repo_id IN \(` + liveRepoIDsRegex + `\) ORDER BY pr.policy_id, pr.evaluated_at DESC, pr.id DESC`).
+		WithArgs(4).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetLatestPolicyResultsForRepo(4)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*PolicyResult{
+		&PolicyResult{
+			ID: 81, PolicyID: 3, PolicyVersion: 2, RepoPullID: 36, JobID: 12, Passed: false,
+			Violations:  []PolicyViolation{{Rule: "denied_licenses", Message: "GPL-3.0-only is denied", FileInstanceID: 7}},
+			EvaluatedAt: evalAt,
+		},
+		&PolicyResult{ID: 79, PolicyID: 5, PolicyVersion: 1, RepoPullID: 35, Passed: true, EvaluatedAt: evalAt},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldGetLatestPolicyResultsForProject(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	evalAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "policy_id", "policy_version", "repopull_id", "job_id", "passed", "violations", "evaluated_at", "repo_id"}).
+		AddRow(79, 5, 1, 35, nil, true, nil, evalAt, 4).
+		AddRow(84, 3, 2, 40, nil, true, nil, evalAt, 6).
+		AddRow(85, 5, 1, 40, nil, true, nil, evalAt, 6)
+	mock.ExpectQuery(`SELECT DISTINCT ON \(rp.repo_id, pr.policy_id\) (.+), rp.repo_id FROM peridot.policy_results pr (.+) WHERE sp.project_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	got, err := db.GetLatestPolicyResultsForProject(2)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(got) != 2 || len(got[4]) != 1 || len(got[6]) != 2 {
+		t.Fatalf("expected 1 result for repo 4 and 2 for repo 6, got %#v", got)
+	}
+	if got[6][1].ID != 85 {
+		t.Errorf("expected %v, got %v", 85, got[6][1].ID)
+	}
+}
+
+func TestShouldAddPolicyResult(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.policy_results\(policy_id, policy_version, repopull_id, job_id, passed, violations, evaluated_at\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(3, 2, 36, 12, false, []byte(`[{"rule":"denied_licenses","message":"GPL-3.0-only is denied","fileinstance_id":7}]`), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(81))
+
+	// run the tested function
+	id, err := db.AddPolicyResult(&PolicyResult{
+		PolicyID: 3, PolicyVersion: 2, RepoPullID: 36, JobID: 12, Passed: false,
+		Violations: []PolicyViolation{{Rule: "denied_licenses", Message: "GPL-3.0-only is denied", FileInstanceID: 7}},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 81 {
+		t.Errorf("expected %v, got %v", 81, id)
+	}
+}
+
+func TestShouldFailAddPolicyResultWithInconsistentViolations(t *testing.T) {
+	tests := []*PolicyResult{
+		&PolicyResult{PolicyID: 3, PolicyVersion: 2, RepoPullID: 36, Passed: true, Violations: []PolicyViolation{{Rule: "denied_licenses"}}},
+		&PolicyResult{PolicyID: 3, PolicyVersion: 2, RepoPullID: 36, Passed: false},
+		&PolicyResult{PolicyID: 3, PolicyVersion: 2, RepoPullID: 36, Passed: false, Violations: []PolicyViolation{{Message: "oops"}}},
+	}
+
+	for _, pr := range tests {
+		_, err := (&DB{}).AddPolicyResult(pr)
+		if _, ok := err.(*ValidationError); !ok {
+			t.Errorf("for %#v: expected *ValidationError, got %v", pr, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+)
+
+// sqlStmt is the part of *sql.Stmt that is used to run a prepared
+// statement, so that the DB methods can run unprepared statements in
+// the same way when server-side prepares are disabled.
+type sqlStmt interface {
+	Exec(args ...interface{}) (sql.Result, error)
+	QueryRow(args ...interface{}) *sql.Row
+	Close() error
+}
+
+// directStmt runs its query directly on its connection each time,
+// as a plain parameterized statement, rather than preparing it.
+type directStmt struct {
+	c     sqlConn
+	query string
+}
+
+// Exec runs the query with the given arguments.
+func (s *directStmt) Exec(args ...interface{}) (sql.Result, error) {
+	return s.c.Exec(s.query, args...)
+}
+
+// QueryRow runs the query with the given arguments, returning at
+// most one row.
+func (s *directStmt) QueryRow(args ...interface{}) *sql.Row {
+	return s.c.QueryRow(s.query, args...)
+}
+
+// Close does nothing, since nothing was prepared.
+func (s *directStmt) Close() error {
+	return nil
+}
+
+// SetNoPrepare sets whether the DB avoids server-side prepared
+// statements, running every statement as a plain parameterized Exec
+// or Query instead. This is needed when connecting through a pooler
+// such as PgBouncer in transaction pooling mode, where consecutive
+// statements outside a transaction may run on different server
+// connections, so that a statement prepared on one is unknown on the
+// next. It is disabled by default.
+//
+// The driver must also avoid naming prepared statements: lib/pq
+// always uses unnamed ones for parameterized statements, while pgx
+// caches named ones unless its statement_cache_mode is "describe",
+// which NewDBFromConfig sets when Config.NoPrepare is true.
+func (db *DB) SetNoPrepare(enabled bool) {
+	db.noPrepare = enabled
+}
+
+// prepare prepares query on the DB's connection, or returns a
+// statement that runs it directly if server-side prepares are
+// disabled.
+func (db *DB) prepare(query string) (sqlStmt, error) {
+	return db.prepareIn(db.sqldb, query)
+}
+
+// prepareIn prepares query on c, which is usually a transaction, or
+// returns a statement that runs it directly on c if server-side
+// prepares are disabled.
+func (db *DB) prepareIn(c sqlConn, query string) (sqlStmt, error) {
+	if db.noPrepare {
+		return &directStmt{c: c, query: query}, nil
+	}
+	return c.Prepare(query)
+}
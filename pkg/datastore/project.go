@@ -4,24 +4,91 @@ package datastore
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // Project describes a project within peridot. A Project consists
 // of Subprojects, and those Subprojects contain one or more Repos.
 type Project struct {
 	// ID is the unique ID for this project.
-	ID uint32 `json:"id"`
+	ID ProjectID `json:"id"`
 	// Name is this project's short name. Typically it should be a
 	// single set of alphanumeric characters without spaces.
 	Name string `json:"name"`
 	// Fullname is this project's full, more descriptive name.
 	Fullname string `json:"fullname"`
+	// OrgID is the ID of the Organization that owns this project,
+	// or 0 if it does not belong to an organization.
+	OrgID OrgID `json:"org_id,omitempty"`
+	// CreatedAt is when this project was added. It is set by the
+	// database.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// UpdatedAt is when this project was last changed. It is set
+	// by the database.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
 }
 
-// GetAllProjects returns a slice of all projects in the database.
-func (db *DB) GetAllProjects() ([]*Project, error) {
-	rows, err := db.sqldb.Query("SELECT id, name, fullname FROM peridot.projects ORDER BY id")
+// MarshalJSON converts the Project into a slice of bytes containing
+// its JSON encoding, leaving out created_at and updated_at if unset.
+func (p Project) MarshalJSON() ([]byte, error) {
+	type projectAlias Project
+	return json.Marshal(struct {
+		projectAlias
+		CreatedAt *time.Time `json:"created_at,omitempty"`
+		UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	}{projectAlias: projectAlias(p), CreatedAt: jsonTimePtr(p.CreatedAt), UpdatedAt: jsonTimePtr(p.UpdatedAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of a Project into the Project, leaving CreatedAt and UpdatedAt as
+// the zero time if they are missing or null.
+func (p *Project) UnmarshalJSON(b []byte) error {
+	type projectAlias Project
+	aux := struct {
+		*projectAlias
+		CreatedAt *time.Time `json:"created_at"`
+		UpdatedAt *time.Time `json:"updated_at"`
+	}{projectAlias: (*projectAlias)(p)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	p.CreatedAt = timeFromJSONPtr(aux.CreatedAt)
+	p.UpdatedAt = timeFromJSONPtr(aux.UpdatedAt)
+	return nil
+}
+
+// Validate checks that the Project's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (p *Project) Validate() error {
+	return requireNonEmpty("project", "name", p.Name)
+}
+
+const projectColumns = "id, name, fullname, org_id, created_at, updated_at"
+
+// scanProject reads a Project from a row selecting projectColumns.
+func scanProject(rs rowScanner) (*Project, error) {
+	p := &Project{}
+	var orgID sql.NullInt64
+	err := rs.Scan(&p.ID, &p.Name, &p.Fullname, &orgID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	p.OrgID = OrgID(orgID.Int64)
+	p.CreatedAt = normalizeTime(p.CreatedAt)
+	p.UpdatedAt = normalizeTime(p.UpdatedAt)
+	return p, nil
+}
+
+// queryProjects runs a query selecting projectColumns and returns
+// the resulting projects.
+func (db *DB) queryProjects(query string, args ...interface{}) ([]*Project, error) {
+	rows, err := db.sqldb.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -29,8 +96,7 @@ func (db *DB) GetAllProjects() ([]*Project, error) {
 
 	projects := []*Project{}
 	for rows.Next() {
-		p := &Project{}
-		err := rows.Scan(&p.ID, &p.Name, &p.Fullname)
+		p, err := scanProject(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -43,120 +109,112 @@ func (db *DB) GetAllProjects() ([]*Project, error) {
 	return projects, nil
 }
 
+// GetAllProjects returns a slice of all projects in the database.
+func (db *DB) GetAllProjects() ([]*Project, error) {
+	return db.queryProjects("SELECT " + projectColumns + " FROM peridot.projects WHERE deleted_at IS NULL ORDER BY id")
+}
+
+// CountAllProjects returns the number of projects in the database.
+func (db *DB) CountAllProjects() (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.projects WHERE deleted_at IS NULL")
+}
+
 // GetProjectByID returns the Project with the given ID, or nil
 // and an error if not found.
-func (db *DB) GetProjectByID(id uint32) (*Project, error) {
-	var project Project
-	err := db.sqldb.QueryRow("SELECT id, name, fullname FROM peridot.projects WHERE id = $1", id).
-		Scan(&project.ID, &project.Name, &project.Fullname)
+func (db *DB) GetProjectByID(id ProjectID) (*Project, error) {
+	p, err := scanProject(db.sqldb.QueryRow("SELECT "+projectColumns+" FROM peridot.projects WHERE id = $1 AND deleted_at IS NULL", id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no project found with ID %v", id)
+		return nil, &NotFoundError{Entity: "project", ID: fmt.Sprint(id)}
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &project, nil
+	return p, nil
+}
+
+// ExistsProject reports whether a Project with the given ID
+// exists, without retrieving it.
+func (db *DB) ExistsProject(id ProjectID) (bool, error) {
+	return db.exists("SELECT EXISTS (SELECT 1 FROM peridot.projects WHERE id = $1 AND deleted_at IS NULL)", id)
 }
 
 // AddProject adds a new Project with the given short name and
 // full name. It returns the new project's ID on success or an
 // error if failing.
-func (db *DB) AddProject(name string, fullname string) (uint32, error) {
+func (db *DB) AddProject(name string, fullname string) (ProjectID, error) {
+	p := &Project{Name: name, Fullname: fullname}
+	if err := p.Validate(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+
 	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("INSERT INTO peridot.projects(name, fullname) VALUES ($1, $2) RETURNING id")
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.projects(name, fullname) VALUES ($1, $2) RETURNING id")
 	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
 
-	var projectID uint32
-	err = stmt.QueryRow(name, fullname).Scan(&projectID)
+	err = stmt.QueryRow(name, fullname).Scan(&p.ID)
 	if err != nil {
+		tx.Rollback()
+		return 0, translateConstraintError("project", err)
+	}
+
+	err = addOutboxEvent(tx, "project", p.ID, AuditActionAdd, p)
+	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
-	return projectID, nil
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return p.ID, nil
 }
 
 // UpdateProject updates an existing Project with the given ID,
 // changing to the specified short name and full name. If an
 // empty string is passed, the existing value will remain
 // unchanged. It returns nil on success or an error if failing.
-func (db *DB) UpdateProject(id uint32, newName string, newFullname string) error {
-	var err error
-	var result sql.Result
-
+func (db *DB) UpdateProject(id ProjectID, newName string, newFullname string) error {
 	// FIXME consider whether to move out into one-time-prepared statements
 	if newName != "" && newFullname != "" {
-		stmt, err := db.sqldb.Prepare("UPDATE peridot.projects SET name = $1, fullname = $2 WHERE id = $3")
-		if err != nil {
-			return err
-		}
-		result, err = stmt.Exec(newName, newFullname, id)
-
+		return db.execWithOutboxEvent("project", id, AuditActionUpdate, map[string]interface{}{"id": id, "name": newName, "fullname": newFullname},
+			"UPDATE peridot.projects SET name = $1, fullname = $2 WHERE id = $3", newName, newFullname, id)
 	} else if newName != "" {
-		stmt, err := db.sqldb.Prepare("UPDATE peridot.projects SET name = $1 WHERE id = $2")
-		if err != nil {
-			return err
-		}
-		result, err = stmt.Exec(newName, id)
-
+		return db.execWithOutboxEvent("project", id, AuditActionUpdate, map[string]interface{}{"id": id, "name": newName},
+			"UPDATE peridot.projects SET name = $1 WHERE id = $2", newName, id)
 	} else if newFullname != "" {
-		stmt, err := db.sqldb.Prepare("UPDATE peridot.projects SET fullname = $1 WHERE id = $2")
-		if err != nil {
-			return err
-		}
-		result, err = stmt.Exec(newFullname, id)
-
-	} else {
-		return fmt.Errorf("only empty strings passed to UpdateProject for id %v", id)
+		return db.execWithOutboxEvent("project", id, AuditActionUpdate, map[string]interface{}{"id": id, "fullname": newFullname},
+			"UPDATE peridot.projects SET fullname = $1 WHERE id = $2", newFullname, id)
 	}
 
-	// check error
-	if err != nil {
-		return err
-	}
-
-	// check that something was actually updated
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return fmt.Errorf("no project found with ID %v", id)
-	}
-
-	return nil
+	return fmt.Errorf("only empty strings passed to UpdateProject for id %v", id)
 }
 
-// DeleteProject deletes an existing Project with the given ID.
-// It returns nil on success or an error if failing.
-func (db *DB) DeleteProject(id uint32) error {
-	var err error
-	var result sql.Result
+// SetProjectOrganization moves the existing Project with the given
+// ID into the Organization with the given ID, or out of any
+// organization if orgID is 0. It returns nil on success or an error
+// if failing.
+func (db *DB) SetProjectOrganization(id ProjectID, orgID OrgID) error {
+	return db.execWithOutboxEvent("project", id, AuditActionUpdate, map[string]interface{}{"id": id, "org_id": orgID},
+		"UPDATE peridot.projects SET org_id = $1 WHERE id = $2", sql.NullInt64{Int64: int64(orgID), Valid: orgID != 0}, id)
+}
 
+// DeleteProject deletes an existing Project with the given ID. If
+// soft deletion is enabled, the project is only marked as deleted;
+// see SetSoftDelete. It returns nil on success or an error if
+// failing.
+func (db *DB) DeleteProject(id ProjectID) error {
 	// FIXME consider whether need to delete sub-elements first, or
 	// FIXME whether to set up sub-elements' schemas to delete on cascade
 
 	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("DELETE FROM peridot.projects WHERE id = $1")
-	if err != nil {
-		return err
-	}
-	result, err = stmt.Exec(id)
-
-	// check error
-	if err != nil {
-		return err
-	}
-
-	// check that something was actually deleted
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return fmt.Errorf("no project found with ID %v", id)
-	}
-
-	return nil
+	return db.deleteRow("project", "projects", id)
 }
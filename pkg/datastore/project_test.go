@@ -18,11 +18,11 @@ func TestShouldGetAllProjects(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "name", "fullname"}).
-		AddRow(1, "cncf", "Cloud Native Computing Foundation (CNCF)").
-		AddRow(2, "onap", "Open Network Automation Platform (ONAP)").
-		AddRow(3, "hyperledger", "Hyperledger")
-	mock.ExpectQuery("SELECT id, name, fullname FROM peridot.projects ORDER BY id").WillReturnRows(sentRows)
+	sentRows := sqlmock.NewRows([]string{"id", "name", "fullname", "org_id", "created_at", "updated_at"}).
+		AddRow(1, "cncf", "Cloud Native Computing Foundation (CNCF)", nil, testCreatedAt, testUpdatedAt).
+		AddRow(2, "onap", "Open Network Automation Platform (ONAP)", 4, testCreatedAt, testUpdatedAt).
+		AddRow(3, "hyperledger", "Hyperledger", nil, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery("SELECT id, name, fullname, org_id, created_at, updated_at FROM peridot.projects WHERE deleted_at IS NULL ORDER BY id").WillReturnRows(sentRows)
 
 	// run the tested function
 	gotRows, err := db.GetAllProjects()
@@ -60,6 +60,9 @@ func TestShouldGetAllProjects(t *testing.T) {
 	if p1.Fullname != "Open Network Automation Platform (ONAP)" {
 		t.Errorf("expected %v, got %v", "Open Network Automation Platform (ONAP)", p1.Fullname)
 	}
+	if p1.OrgID != 4 {
+		t.Errorf("expected %v, got %v", 4, p1.OrgID)
+	}
 	p2 := gotRows[2]
 	if p2.ID != 3 {
 		t.Errorf("expected %v, got %v", 3, p2.ID)
@@ -81,9 +84,9 @@ func TestShouldGetProjectByID(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "name", "fullname"}).
-		AddRow(2, "onap", "Open Network Automation Platform (ONAP)")
-	mock.ExpectQuery(`[SELECT id, name, fullname FROM peridot.projects WHERE id = \$1]`).
+	sentRows := sqlmock.NewRows([]string{"id", "name", "fullname", "org_id", "created_at", "updated_at"}).
+		AddRow(2, "onap", "Open Network Automation Platform (ONAP)", 4, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`[SELECT id, name, fullname, org_id, created_at, updated_at FROM peridot.projects WHERE id = \$1]`).
 		WithArgs(2).
 		WillReturnRows(sentRows)
 
@@ -109,6 +112,15 @@ func TestShouldGetProjectByID(t *testing.T) {
 	if project.Fullname != "Open Network Automation Platform (ONAP)" {
 		t.Errorf("expected %v, got %v", "Open Network Automation Platform (ONAP)", project.Fullname)
 	}
+	if project.OrgID != 4 {
+		t.Errorf("expected %v, got %v", 4, project.OrgID)
+	}
+	if project.CreatedAt != testCreatedAt {
+		t.Errorf("expected %v, got %v", testCreatedAt, project.CreatedAt)
+	}
+	if project.UpdatedAt != testUpdatedAt {
+		t.Errorf("expected %v, got %v", testUpdatedAt, project.UpdatedAt)
+	}
 }
 
 func TestShouldFailGetProjectByIDForUnknownID(t *testing.T) {
@@ -120,7 +132,7 @@ func TestShouldFailGetProjectByIDForUnknownID(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	mock.ExpectQuery(`[SELECT id, name, fullname FROM peridot.projects WHERE id = \$1]`).
+	mock.ExpectQuery(`[SELECT id, name, fullname, org_id, created_at, updated_at FROM peridot.projects WHERE id = \$1]`).
 		WithArgs(413).
 		WillReturnRows(sqlmock.NewRows([]string{}))
 
@@ -150,11 +162,16 @@ func TestShouldAddProject(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[INSERT INTO peridot.projects(name, fullname) VALUES (\$1, \$2) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "INSERT INTO peridot.projects"
 	mock.ExpectQuery(stmt).
 		WithArgs("cncf", "Cloud Native Computing Foundation (CNCF)").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("project", "1", AuditActionAdd, []byte(`{"id":1,"name":"cncf","fullname":"Cloud Native Computing Foundation (CNCF)"}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	projectID, err := db.AddProject("cncf", "Cloud Native Computing Foundation (CNCF)")
@@ -184,11 +201,15 @@ func TestShouldUpdateProjectNameAndFullname(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.projects SET name = \$1, fullname = \$2 WHERE id = \$3]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.projects"
 	mock.ExpectExec(stmt).
 		WithArgs("myprj", "My Project", 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.UpdateProject(1, "myprj", "My Project")
@@ -213,11 +234,16 @@ func TestShouldUpdateProjectNameOnly(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.projects SET name = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.projects"
 	mock.ExpectExec(stmt).
 		WithArgs("myprj", 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("project", "1", AuditActionUpdate, []byte(`{"id":1,"name":"myprj"}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.UpdateProject(1, "myprj", "")
@@ -242,11 +268,15 @@ func TestShouldUpdateProjectFullnameOnly(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.projects SET fullname = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.projects"
 	mock.ExpectExec(stmt).
 		WithArgs("My Project", 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.UpdateProject(1, "", "My Project")
@@ -293,11 +323,13 @@ func TestShouldFailUpdateProjectWithUnknownID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.projects SET name = \$1, fullname = \$2 WHERE id = \$3]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.projects"
 	mock.ExpectExec(stmt).
 		WithArgs("oops", "wrong ID", 413).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	// run the tested function with an unknown project ID number
 	err = db.UpdateProject(413, "oops", "wrong ID")
@@ -322,11 +354,16 @@ func TestShouldDeleteProject(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[DELETE FROM peridot.projects WHERE id = \$1]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "DELETE FROM peridot.projects"
 	mock.ExpectExec(stmt).
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("project", "1", AuditActionDelete, []byte(`{"id":1}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.DeleteProject(1)
@@ -351,11 +388,13 @@ func TestShouldFailDeleteProjectWithUnknownID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[DELETE FROM peridot.projects WHERE id = \$1]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "DELETE FROM peridot.projects"
 	mock.ExpectExec(stmt).
 		WithArgs(413).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	// run the tested function
 	err = db.DeleteProject(413)
@@ -426,6 +465,31 @@ func TestCanUnmarshalProjectFromJSON(t *testing.T) {
 	}
 }
 
+func TestCanMarshalProjectTimestampsToJSON(t *testing.T) {
+	// unset timestamps, as for a project not yet added, are omitted
+	js, err := json.Marshal(&Project{ID: 17, Name: "cncf", Fullname: "CNCF"})
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if string(js) != `{"id":17,"name":"cncf","fullname":"CNCF"}` {
+		t.Errorf("got unexpected JSON %s", js)
+	}
+
+	// and set timestamps round-trip
+	prj := &Project{ID: 17, Name: "cncf", Fullname: "CNCF", CreatedAt: testCreatedAt, UpdatedAt: testUpdatedAt}
+	js, err = json.Marshal(prj)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	got := &Project{}
+	if err = json.Unmarshal(js, got); err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if *got != *prj {
+		t.Errorf("expected %+v, got %+v", prj, got)
+	}
+}
+
 func TestCannotUnmarshalProjectWithNegativeIDFromJSON(t *testing.T) {
 	prj := &Project{}
 	js := []byte(`{"id":-92841, "name":"OOPS", "fullname":"oops bad ID"}`)
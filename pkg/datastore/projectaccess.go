@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ProjectAccess describes a grant of an access level to a User
+// for one particular Project. It overrides the user's global
+// AccessLevel for that project, as described in EffectiveAccess.
+type ProjectAccess struct {
+	// UserID is the ID of the user receiving the grant.
+	UserID UserID `json:"user_id"`
+	// ProjectID is the ID of the project to which the grant applies.
+	ProjectID ProjectID `json:"project_id"`
+	// AccessLevel is the user's access level for this project.
+	AccessLevel UserAccessLevel `json:"access"`
+}
+
+// GetProjectAccessForUser returns a slice of all project access
+// grants for the User with the given ID, ordered by project ID.
+func (db *DB) GetProjectAccessForUser(userID UserID) ([]*ProjectAccess, error) {
+	rows, err := db.sqldb.Query("SELECT user_id, project_id, access_level FROM peridot.project_access WHERE user_id = $1 ORDER BY project_id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanProjectAccessRows(rows)
+}
+
+// GetProjectAccessForProject returns a slice of all project access
+// grants for the Project with the given ID, ordered by user ID.
+func (db *DB) GetProjectAccessForProject(projectID ProjectID) ([]*ProjectAccess, error) {
+	rows, err := db.sqldb.Query("SELECT user_id, project_id, access_level FROM peridot.project_access WHERE project_id = $1 ORDER BY user_id", projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanProjectAccessRows(rows)
+}
+
+// scanProjectAccessRows collects ProjectAccess values from rows
+// selecting user_id, project_id and access_level.
+func scanProjectAccessRows(rows *sql.Rows) ([]*ProjectAccess, error) {
+	pas := []*ProjectAccess{}
+	for rows.Next() {
+		pa := &ProjectAccess{}
+		err := rows.Scan(&pa.UserID, &pa.ProjectID, &pa.AccessLevel)
+		if err != nil {
+			return nil, err
+		}
+
+		pas = append(pas, pa)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pas, nil
+}
+
+// GrantProjectAccess grants the User with the given ID the given
+// access level for the Project with the given ID, replacing any
+// existing grant for that user and project. It returns nil on
+// success or an error if failing.
+func (db *DB) GrantProjectAccess(userID UserID, projectID ProjectID, accessLevel UserAccessLevel) error {
+	stmt, err := db.prepare("INSERT INTO peridot.project_access(user_id, project_id, access_level) VALUES ($1, $2, $3) ON CONFLICT (user_id, project_id) DO UPDATE SET access_level = EXCLUDED.access_level")
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(userID, projectID, IntFromUserAccessLevel(accessLevel))
+	return err
+}
+
+// RevokeProjectAccess removes the project access grant for the User
+// with the given ID and the Project with the given ID, so that the
+// user's global access level applies again. It returns nil on
+// success or an error if failing.
+func (db *DB) RevokeProjectAccess(userID UserID, projectID ProjectID) error {
+	stmt, err := db.prepare("DELETE FROM peridot.project_access WHERE user_id = $1 AND project_id = $2")
+	if err != nil {
+		return err
+	}
+	result, err := stmt.Exec(userID, projectID)
+
+	// check error
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "project access", Key: "user ID/project ID", ID: fmt.Sprintf("%d/%d", userID, projectID)}
+	}
+
+	return nil
+}
+
+// EffectiveAccess returns the access level that the User with the
+// given ID has for the Project with the given ID. A disabled user
+// is always AccessDisabled and an admin user is always AccessAdmin,
+// regardless of grants. Otherwise, a grant for the project takes
+// precedence, falling back to the user's global access level if
+// there is none. It returns an error if the user is not found.
+func (db *DB) EffectiveAccess(userID UserID, projectID ProjectID) (UserAccessLevel, error) {
+	var global UserAccessLevel
+	var grantInt sql.NullInt64
+	err := db.sqldb.QueryRow("SELECT u.access_level, pa.access_level FROM peridot.users u LEFT JOIN peridot.project_access pa ON pa.user_id = u.id AND pa.project_id = $2 WHERE u.id = $1", userID, projectID).
+		Scan(&global, &grantInt)
+	if err == sql.ErrNoRows {
+		return AccessDisabled, &NotFoundError{Entity: "user", ID: fmt.Sprint(userID)}
+	}
+	if err != nil {
+		return AccessDisabled, err
+	}
+
+	if global == AccessDisabled || global == AccessAdmin || !grantInt.Valid {
+		return global, nil
+	}
+
+	return UserAccessLevelFromInt(int(grantInt.Int64))
+}
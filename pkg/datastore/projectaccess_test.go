@@ -0,0 +1,279 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetProjectAccessForUser(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"user_id", "project_id", "access_level"}).
+		AddRow(410952, 2, 30).
+		AddRow(410952, 5, 10)
+	mock.ExpectQuery(`SELECT user_id, project_id, access_level FROM peridot.project_access WHERE user_id = \$1 ORDER BY project_id`).
+		WithArgs(410952).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetProjectAccessForUser(410952)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	pa0 := gotRows[0]
+	if pa0.UserID != 410952 {
+		t.Errorf("expected %v, got %v", 410952, pa0.UserID)
+	}
+	if pa0.ProjectID != 2 {
+		t.Errorf("expected %v, got %v", 2, pa0.ProjectID)
+	}
+	if pa0.AccessLevel != AccessOperator {
+		t.Errorf("expected %v, got %v", AccessOperator, pa0.AccessLevel)
+	}
+	pa1 := gotRows[1]
+	if pa1.AccessLevel != AccessViewer {
+		t.Errorf("expected %v, got %v", AccessViewer, pa1.AccessLevel)
+	}
+}
+
+func TestShouldGetProjectAccessForProject(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"user_id", "project_id", "access_level"}).
+		AddRow(410952, 2, 30).
+		AddRow(8103918, 2, 20)
+	mock.ExpectQuery(`SELECT user_id, project_id, access_level FROM peridot.project_access WHERE project_id = \$1 ORDER BY user_id`).
+		WithArgs(2).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetProjectAccessForProject(2)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	pa1 := gotRows[1]
+	if pa1.UserID != 8103918 {
+		t.Errorf("expected %v, got %v", 8103918, pa1.UserID)
+	}
+	if pa1.AccessLevel != AccessCommenter {
+		t.Errorf("expected %v, got %v", AccessCommenter, pa1.AccessLevel)
+	}
+}
+
+func TestShouldGrantProjectAccess(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.project_access\(user_id, project_id, access_level\) VALUES \(\$1, \$2, \$3\) ON CONFLICT \(user_id, project_id\) DO UPDATE SET access_level = EXCLUDED.access_level`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(410952, 2, 30).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.GrantProjectAccess(410952, 2, AccessOperator)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldRevokeProjectAccess(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `DELETE FROM peridot.project_access WHERE user_id = \$1 AND project_id = \$2`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(410952, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.RevokeProjectAccess(410952, 2)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailRevokeProjectAccessWithUnknownGrant(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `DELETE FROM peridot.project_access WHERE user_id = \$1 AND project_id = \$2`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(410952, 413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.RevokeProjectAccess(410952, 413)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldGetEffectiveAccess(t *testing.T) {
+	tests := []struct {
+		global interface{}
+		grant  interface{}
+		want   UserAccessLevel
+	}{
+		// no grant falls back to global level
+		{10, nil, AccessViewer},
+		// grant overrides global level in either direction
+		{10, 30, AccessOperator},
+		{30, 10, AccessViewer},
+		// disabled and admin users ignore grants
+		{0, 30, AccessDisabled},
+		{99, 10, AccessAdmin},
+	}
+
+	for _, tt := range tests {
+		// set up mock
+		sqldb, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("got error when creating db mock: %v", err)
+		}
+		db := DB{sqldb: sqldb}
+
+		sentRows := sqlmock.NewRows([]string{"access_level", "access_level"}).
+			AddRow(tt.global, tt.grant)
+		mock.ExpectQuery(`SELECT u.access_level, pa.access_level FROM peridot.users u LEFT JOIN peridot.project_access pa ON pa.user_id = u.id AND pa.project_id = \$2 WHERE u.id = \$1`).
+			WithArgs(410952, 2).
+			WillReturnRows(sentRows)
+
+		// run the tested function
+		got, err := db.EffectiveAccess(410952, 2)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+
+		// check sqlmock expectations
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+		sqldb.Close()
+	}
+}
+
+func TestShouldFailEffectiveAccessForUnknownUser(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT u.access_level, pa.access_level FROM peridot.users u`).
+		WithArgs(413, 2).
+		WillReturnRows(sqlmock.NewRows([]string{}))
+
+	// run the tested function
+	got, err := db.EffectiveAccess(413, 2)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+	if got != AccessDisabled {
+		t.Errorf("expected %v, got %v", AccessDisabled, got)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// ===== JSON marshalling and unmarshalling =====
+func TestCanMarshalProjectAccessToJSON(t *testing.T) {
+	pa := &ProjectAccess{
+		UserID:      410952,
+		ProjectID:   2,
+		AccessLevel: AccessOperator,
+	}
+
+	js, err := json.Marshal(pa)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+
+	want := `{"user_id":410952,"project_id":2,"access":"operator"}`
+	if string(js) != want {
+		t.Errorf("expected %v, got %v", want, string(js))
+	}
+}
@@ -0,0 +1,249 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Resources that can be limited by a Quota.
+const (
+	// QuotaResourceRepos limits the number of repos.
+	QuotaResourceRepos = "repos"
+	// QuotaResourceConcurrentJobs limits the number of jobs that
+	// are starting up or running at once.
+	QuotaResourceConcurrentJobs = "concurrent_jobs"
+	// QuotaResourceStoredPulls limits the number of repo pulls kept
+	// in the database.
+	QuotaResourceStoredPulls = "stored_pulls"
+)
+
+// ErrQuotaExceeded is matched by errors.Is for every
+// *QuotaExceededError.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaExceededError is returned when adding an entity would take a
+// project or organization over one of its quota limits.
+type QuotaExceededError struct {
+	// Resource is the resource whose limit was reached, e.g.
+	// QuotaResourceRepos.
+	Resource string
+	// Scope is "project" or "organization", for the quota that was
+	// reached.
+	Scope string
+	// ScopeID is the ID of the project or organization.
+	ScopeID uint32
+	// Limit is the quota's limit for Resource.
+	Limit uint32
+	// Current is the amount of Resource already in use.
+	Current uint32
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s quota exceeded for %s %d: %d of %d in use", e.Resource, e.Scope, e.ScopeID, e.Current, e.Limit)
+}
+
+// Is reports whether target is ErrQuotaExceeded.
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// Quota describes the limits on resources used by one Project or
+// Organization. A limit of 0 means that resource is unlimited.
+type Quota struct {
+	// ID is the unique ID for this quota.
+	ID uint32 `json:"id"`
+	// ProjectID is the ID of the Project limited, or 0 if this is
+	// an organization's quota.
+	ProjectID ProjectID `json:"project_id,omitempty"`
+	// OrgID is the ID of the Organization limited, across all of
+	// its projects, or 0 if this is a project's quota.
+	OrgID OrgID `json:"org_id,omitempty"`
+	// MaxRepos is the maximum number of repos.
+	MaxRepos uint32 `json:"max_repos"`
+	// MaxConcurrentJobs is the maximum number of jobs that are
+	// starting up or running at once.
+	MaxConcurrentJobs uint32 `json:"max_concurrent_jobs"`
+	// MaxStoredPulls is the maximum number of repo pulls.
+	MaxStoredPulls uint32 `json:"max_stored_pulls"`
+}
+
+// Validate checks that the Quota's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (q *Quota) Validate() error {
+	if (q.ProjectID == 0) == (q.OrgID == 0) {
+		return &ValidationError{Entity: "quota", Field: "project_id", Reason: "exactly one of project_id and org_id must be set"}
+	}
+	return nil
+}
+
+// limit returns the quota's limit for the given resource.
+func (q *Quota) limit(resource string) uint32 {
+	switch resource {
+	case QuotaResourceRepos:
+		return q.MaxRepos
+	case QuotaResourceConcurrentJobs:
+		return q.MaxConcurrentJobs
+	case QuotaResourceStoredPulls:
+		return q.MaxStoredPulls
+	}
+	return 0
+}
+
+const quotaColumns = "id, project_id, org_id, max_repos, max_concurrent_jobs, max_stored_pulls"
+
+// scanQuota reads a Quota from a row selecting quotaColumns.
+func scanQuota(rs rowScanner) (*Quota, error) {
+	q := &Quota{}
+	var projectID, orgID sql.NullInt64
+	err := rs.Scan(&q.ID, &projectID, &orgID, &q.MaxRepos, &q.MaxConcurrentJobs, &q.MaxStoredPulls)
+	if err != nil {
+		return nil, err
+	}
+	q.ProjectID = ProjectID(projectID.Int64)
+	q.OrgID = OrgID(orgID.Int64)
+	return q, nil
+}
+
+// GetQuotaForProject returns the Quota for the Project with the given
+// ID, or nil and an error if it has none.
+func (db *DB) GetQuotaForProject(projectID ProjectID) (*Quota, error) {
+	q, err := scanQuota(db.sqldb.QueryRow("SELECT "+quotaColumns+" FROM peridot.quotas WHERE project_id = $1", projectID))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "quota", ID: fmt.Sprint(projectID), Key: "project ID"}
+	}
+	return q, err
+}
+
+// GetQuotaForOrganization returns the Quota for the Organization with
+// the given ID, or nil and an error if it has none.
+func (db *DB) GetQuotaForOrganization(orgID OrgID) (*Quota, error) {
+	q, err := scanQuota(db.sqldb.QueryRow("SELECT "+quotaColumns+" FROM peridot.quotas WHERE org_id = $1", orgID))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "quota", ID: fmt.Sprint(orgID), Key: "org ID"}
+	}
+	return q, err
+}
+
+// SetQuota creates or replaces the Quota for q's Project or
+// Organization, ignoring q's ID. Quotas are not applied to entities
+// that already exist, only to those added later. It returns nil on
+// success or an error if failing.
+func (db *DB) SetQuota(q *Quota) error {
+	if err := q.Validate(); err != nil {
+		return err
+	}
+
+	conflict := "(project_id) WHERE project_id IS NOT NULL"
+	if q.OrgID != 0 {
+		conflict = "(org_id) WHERE org_id IS NOT NULL"
+	}
+	stmt, err := db.prepare("INSERT INTO peridot.quotas(project_id, org_id, max_repos, max_concurrent_jobs, max_stored_pulls) VALUES ($1, $2, $3, $4, $5) ON CONFLICT " + conflict + " DO UPDATE SET max_repos = EXCLUDED.max_repos, max_concurrent_jobs = EXCLUDED.max_concurrent_jobs, max_stored_pulls = EXCLUDED.max_stored_pulls")
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(sql.NullInt64{Int64: int64(q.ProjectID), Valid: q.ProjectID != 0}, sql.NullInt64{Int64: int64(q.OrgID), Valid: q.OrgID != 0}, q.MaxRepos, q.MaxConcurrentJobs, q.MaxStoredPulls)
+	return err
+}
+
+// DeleteQuota deletes the Quota with the given ID, removing its
+// limits. It returns nil on success or an error if failing.
+func (db *DB) DeleteQuota(id uint32) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.quotas WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "quota", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// quotaUsageFroms maps each resource to the FROM and WHERE clauses
+// that select the rows counted against it, leaving the subprojects
+// table aliased as sp for the caller to restrict.
+var quotaUsageFroms = map[string]string{
+	QuotaResourceRepos:          "peridot.repos r JOIN peridot.subprojects sp ON sp.id = r.subproject_id WHERE true",
+	QuotaResourceConcurrentJobs: "peridot.jobs j JOIN peridot.repo_pulls rp ON rp.id = j.repopull_id JOIN peridot.repos r ON r.id = rp.repo_id JOIN peridot.subprojects sp ON sp.id = r.subproject_id WHERE j.status IN ($2, $3)",
+	QuotaResourceStoredPulls:    "peridot.repo_pulls rp JOIN peridot.repos r ON r.id = rp.repo_id JOIN peridot.subprojects sp ON sp.id = r.subproject_id WHERE true",
+}
+
+// CheckQuota reports whether one more of the given resource can be
+// added to the Project with the given ID without exceeding its quota
+// or its organization's quota. It returns nil if so, a
+// *QuotaExceededError if not, or another error if failing.
+//
+// The check is not atomic with the subsequent add, so concurrent
+// adds may overshoot a limit slightly.
+func (db *DB) CheckQuota(projectID ProjectID, resource string) error {
+	from, ok := quotaUsageFroms[resource]
+	if !ok {
+		return fmt.Errorf("unknown quota resource %q", resource)
+	}
+
+	var orgID sql.NullInt64
+	err := db.sqldb.QueryRow("SELECT org_id FROM peridot.projects WHERE id = $1", projectID).Scan(&orgID)
+	if err == sql.ErrNoRows {
+		return &NotFoundError{Entity: "project", ID: fmt.Sprint(projectID)}
+	}
+	if err != nil {
+		return err
+	}
+
+	q, err := db.GetQuotaForProject(projectID)
+	if _, notFound := err.(*NotFoundError); err != nil && !notFound {
+		return err
+	}
+	if q != nil && q.limit(resource) > 0 {
+		err = db.checkQuotaUsage(q, resource, "project", uint32(projectID), "SELECT count(*) FROM "+from+" AND sp.project_id = $1", projectID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !orgID.Valid {
+		return nil
+	}
+	q, err = db.GetQuotaForOrganization(OrgID(orgID.Int64))
+	if _, notFound := err.(*NotFoundError); err != nil && !notFound {
+		return err
+	}
+	if q != nil && q.limit(resource) > 0 {
+		err = db.checkQuotaUsage(q, resource, "organization", uint32(orgID.Int64), "SELECT count(*) FROM "+from+" AND sp.project_id IN (SELECT id FROM peridot.projects WHERE org_id = $1)", orgID.Int64)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkQuotaUsage runs the given counting query for the given scope
+// and returns a *QuotaExceededError if q's limit for resource has
+// already been reached.
+func (db *DB) checkQuotaUsage(q *Quota, resource string, scope string, scopeID uint32, query string, id interface{}) error {
+	args := []interface{}{id}
+	if resource == QuotaResourceConcurrentJobs {
+		args = append(args, StatusStartup, StatusRunning)
+	}
+
+	var current uint32
+	err := db.sqldb.QueryRow(query, args...).Scan(&current)
+	if err != nil {
+		return err
+	}
+	if limit := q.limit(resource); current >= limit {
+		return &QuotaExceededError{Resource: resource, Scope: scope, ScopeID: scopeID, Limit: limit, Current: current}
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestShouldSetQuotaForOrganization(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.quotas\(project_id, org_id, max_repos, max_concurrent_jobs, max_stored_pulls\) VALUES \(\$1, \$2, \$3, \$4, \$5\) ON CONFLICT \(org_id\) WHERE org_id IS NOT NULL DO UPDATE SET`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(nilArg{}, 1, 100, 5, 0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.SetQuota(&Quota{OrgID: 1, MaxRepos: 100, MaxConcurrentJobs: 5})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailSetQuotaWithoutSingleScope(t *testing.T) {
+	tests := []struct {
+		name string
+		q    *Quota
+	}{
+		{"no scope", &Quota{MaxRepos: 10}},
+		{"both scopes", &Quota{ProjectID: 2, OrgID: 1, MaxRepos: 10}},
+	}
+	for _, tc := range tests {
+		err := (&DB{}).SetQuota(tc.q)
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%s: expected *ValidationError, got %v", tc.name, err)
+			continue
+		}
+		if verr.Field != "project_id" {
+			t.Errorf("%s: expected field %v, got %v", tc.name, "project_id", verr.Field)
+		}
+	}
+}
+
+func TestShouldFailCheckQuotaWhenProjectLimitReached(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT org_id FROM peridot.projects WHERE id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id"}).AddRow(nil))
+	mock.ExpectQuery(`SELECT id, project_id, org_id, max_repos, max_concurrent_jobs, max_stored_pulls FROM peridot.quotas WHERE project_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "org_id", "max_repos", "max_concurrent_jobs", "max_stored_pulls"}).AddRow(3, 2, nil, 10, 0, 0))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM peridot.repos r JOIN peridot.subprojects sp ON sp.id = r.subproject_id WHERE true AND sp.project_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+
+	// run the tested function
+	err = db.CheckQuota(2, QuotaResourceRepos)
+	qerr, ok := err.(*QuotaExceededError)
+	if !ok {
+		t.Fatalf("expected *QuotaExceededError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantErr := &QuotaExceededError{Resource: QuotaResourceRepos, Scope: "project", ScopeID: 2, Limit: 10, Current: 10}
+	if !reflect.DeepEqual(wantErr, qerr) {
+		t.Errorf("expected %#v, got %#v", wantErr, qerr)
+	}
+	if !errors.Is(qerr, ErrQuotaExceeded) {
+		t.Errorf("expected error to match ErrQuotaExceeded")
+	}
+}
+
+func TestShouldCheckConcurrentJobsQuotaForOrganization(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	quotaCols := []string{"id", "project_id", "org_id", "max_repos", "max_concurrent_jobs", "max_stored_pulls"}
+	mock.ExpectQuery(`SELECT org_id FROM peridot.projects WHERE id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id"}).AddRow(1))
+	mock.ExpectQuery(`FROM peridot.quotas WHERE project_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(quotaCols))
+	mock.ExpectQuery(`FROM peridot.quotas WHERE org_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(quotaCols).AddRow(4, nil, 1, 0, 5, 0))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM peridot.jobs j .* WHERE j.status IN \(\$2, \$3\) AND sp.project_id IN \(SELECT id FROM peridot.projects WHERE org_id = \$1\)`).
+		WithArgs(1, StatusStartup, StatusRunning).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+	// run the tested function
+	err = db.CheckQuota(2, QuotaResourceConcurrentJobs)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestQuotaDatastoreShouldNotAddRepoOverQuota(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	qds := NewQuotaDatastore(&DB{sqldb: sqldb})
+
+	// the subproject is looked up to find its project
+	mock.ExpectQuery(`SELECT id, project_id, name, fullname, created_at, updated_at FROM peridot.subprojects WHERE id = \$1`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "fullname", "created_at", "updated_at"}).AddRow(5, 2, "k8s", "Kubernetes", testCreatedAt, testUpdatedAt))
+	// then the quota is checked, and the repo is not added
+	mock.ExpectQuery(`SELECT org_id FROM peridot.projects WHERE id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id"}).AddRow(nil))
+	mock.ExpectQuery(`FROM peridot.quotas WHERE project_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "org_id", "max_repos", "max_concurrent_jobs", "max_stored_pulls"}).AddRow(3, 2, nil, 10, 0, 0))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM peridot.repos`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+
+	// run the tested function
+	_, err = qds.AddRepo(5, "kubernetes", "https://github.com/kubernetes/kubernetes")
+	if _, ok := err.(*QuotaExceededError); !ok {
+		t.Fatalf("expected *QuotaExceededError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestQuotaDatastoreShouldNotStartJobOverConcurrentJobsQuota(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	qds := NewQuotaDatastore(&DB{sqldb: sqldb})
+
+	// the queued job is looked up, then its repo pull, repo and
+	// subproject to find its project
+	mock.ExpectQuery(jobsByIDsRegex).
+		WithArgs(pq.Array([]JobID{8})).
+		WillReturnRows(sqlmock.NewRows(jobsByIDsColumns).
+			AddRow(8, 36, 2, time.Time{}, time.Time{}, StatusQueued, HealthOK, "", true, 1, testCreatedAt, testUpdatedAt, "{}", "{}", "{}", "{}", "{}"))
+	mock.ExpectQuery(`SELECT (.+) FROM peridot.repo_pulls WHERE id = \$1`).
+		WithArgs(36).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "repo_id", "branch", "started_at", "finished_at", "status", "health", "output", "commit", "tag", "spdx_id", "created_at", "updated_at"}).
+			AddRow(36, 4, "master", time.Time{}, time.Time{}, StatusRunning, HealthOK, nil, nil, nil, nil, testCreatedAt, testUpdatedAt))
+	mock.ExpectQuery(`SELECT id, subproject_id, name, address, created_at, updated_at FROM peridot.repos WHERE id = \$1`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subproject_id", "name", "address", "created_at", "updated_at"}).AddRow(4, 5, "kubernetes", "https://github.com/kubernetes/kubernetes", testCreatedAt, testUpdatedAt))
+	mock.ExpectQuery(`SELECT id, project_id, name, fullname, created_at, updated_at FROM peridot.subprojects WHERE id = \$1`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "fullname", "created_at", "updated_at"}).AddRow(5, 2, "k8s", "Kubernetes", testCreatedAt, testUpdatedAt))
+	// then the quota is checked, and the job is not started
+	mock.ExpectQuery(`SELECT org_id FROM peridot.projects WHERE id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id"}).AddRow(nil))
+	mock.ExpectQuery(`FROM peridot.quotas WHERE project_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "org_id", "max_repos", "max_concurrent_jobs", "max_stored_pulls"}).AddRow(3, 2, nil, 0, 4, 0))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM peridot.jobs`).
+		WithArgs(2, StatusStartup, StatusRunning).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+	// run the tested function
+	err = qds.UpdateJobStatus(8, 0, time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC), time.Time{}, StatusRunning, HealthOK, "")
+	if _, ok := err.(*QuotaExceededError); !ok {
+		t.Fatalf("expected *QuotaExceededError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestQuotaDatastoreShouldNotCheckQuotaForRunningJob(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	qds := NewQuotaDatastore(&DB{sqldb: sqldb})
+
+	// the job already counts against the quota, so it is updated
+	// straight away
+	mock.ExpectQuery(jobsByIDsRegex).
+		WithArgs(pq.Array([]JobID{8})).
+		WillReturnRows(sqlmock.NewRows(jobsByIDsColumns).
+			AddRow(8, 36, 2, time.Time{}, time.Time{}, StatusStartup, HealthOK, "", true, 1, testCreatedAt, testUpdatedAt, "{}", "{}", "{}", "{}", "{}"))
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE peridot.jobs")
+	mock.ExpectExec("UPDATE peridot.jobs").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = qds.UpdateJobStatus(8, 0, time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC), time.Time{}, StatusRunning, HealthOK, "")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestQuotaDatastoreShouldGetExistingRepoWithoutCheckingQuota(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	qds := NewQuotaDatastore(&DB{sqldb: sqldb})
+
+	mock.ExpectQuery(`SELECT id, subproject_id, name, address, created_at, updated_at FROM peridot.repos WHERE subproject_id = \$1`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subproject_id", "name", "address", "created_at", "updated_at"}).AddRow(4, 5, "kubernetes", "https://github.com/kubernetes/kubernetes", testCreatedAt, testUpdatedAt))
+
+	// run the tested function
+	r, created, err := qds.GetOrCreateRepo(5, "kubernetes", "https://github.com/kubernetes/kubernetes")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if created {
+		t.Errorf("expected existing repo, got created")
+	}
+	if r.ID != 4 {
+		t.Errorf("expected %v, got %v", 4, r.ID)
+	}
+}
+
+func TestQuotaDatastoreShouldReturnExistingRepoForRetriedUUIDAtLimit(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	qds := NewQuotaDatastore(&DB{sqldb: sqldb})
+
+	// the repo was already added with this UUID, so its ID is
+	// returned without checking the quota, which is now reached
+	mock.ExpectQuery(`SELECT id FROM peridot.repos WHERE external_uuid = \$1`).
+		WithArgs("3f2b8c1e-6a5d-4e0f-9b7a-1c2d3e4f5a6b").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+
+	// run the tested function
+	repoID, created, err := qds.AddRepoWithUUID("3f2b8c1e-6a5d-4e0f-9b7a-1c2d3e4f5a6b", 5, "kubernetes", "https://github.com/kubernetes/kubernetes")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if repoID != 6 {
+		t.Errorf("expected %v, got %v", 6, repoID)
+	}
+	if created {
+		t.Errorf("expected existing repo, got created")
+	}
+}
+
+func TestQuotaDatastoreShouldNotAddRepoWithNewUUIDOverQuota(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	qds := NewQuotaDatastore(&DB{sqldb: sqldb})
+
+	// no repo has this UUID yet, so the quota is checked
+	mock.ExpectQuery(`SELECT id FROM peridot.repos WHERE external_uuid = \$1`).
+		WithArgs("3f2b8c1e-6a5d-4e0f-9b7a-1c2d3e4f5a6b").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT id, project_id, name, fullname, created_at, updated_at FROM peridot.subprojects WHERE id = \$1`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "fullname", "created_at", "updated_at"}).AddRow(5, 2, "k8s", "Kubernetes", testCreatedAt, testUpdatedAt))
+	mock.ExpectQuery(`SELECT org_id FROM peridot.projects WHERE id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id"}).AddRow(nil))
+	mock.ExpectQuery(`FROM peridot.quotas WHERE project_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "org_id", "max_repos", "max_concurrent_jobs", "max_stored_pulls"}).AddRow(3, 2, nil, 10, 0, 0))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM peridot.repos`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+
+	// run the tested function
+	_, _, err = qds.AddRepoWithUUID("3f2b8c1e-6a5d-4e0f-9b7a-1c2d3e4f5a6b", 5, "kubernetes", "https://github.com/kubernetes/kubernetes")
+	if _, ok := err.(*QuotaExceededError); !ok {
+		t.Fatalf("expected *QuotaExceededError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestQuotaDatastoreShouldReturnExistingJobForRetriedUUIDAtLimit(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	qds := NewQuotaDatastore(&DB{sqldb: sqldb})
+
+	mock.ExpectQuery(`SELECT id FROM peridot.jobs WHERE external_uuid = \$1`).
+		WithArgs("5d4c3b2a-0f9e-4d8c-b7a6-958473625140").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+
+	// run the tested function
+	jobID, created, err := qds.AddJobWithUUID("5d4c3b2a-0f9e-4d8c-b7a6-958473625140", 36, 2, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if jobID != 9 {
+		t.Errorf("expected %v, got %v", 9, jobID)
+	}
+	if created {
+		t.Errorf("expected existing job, got created")
+	}
+}
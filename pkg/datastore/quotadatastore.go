@@ -0,0 +1,308 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// QuotaDatastore wraps another Datastore, calling CheckQuota before
+// each call that adds a repo, repo pull or job, or that moves a job
+// into StatusStartup or StatusRunning from any other status, and
+// returning the resulting *QuotaExceededError instead of making the
+// change if the affected project or its organization is at its
+// limit. All other calls are passed through unchanged.
+//
+// It can be combined with AuditedDatastore by wrapping one in the
+// other.
+type QuotaDatastore struct {
+	Datastore
+}
+
+// NewQuotaDatastore returns a QuotaDatastore that enforces quotas
+// on adds made through ds.
+func NewQuotaDatastore(ds Datastore) *QuotaDatastore {
+	return &QuotaDatastore{Datastore: ds}
+}
+
+// InTx calls fn within a transaction as the wrapped Datastore's InTx
+// does, with a Datastore that enforces quotas on adds made within
+// it.
+func (q *QuotaDatastore) InTx(ctx context.Context, fn func(ds Datastore) error) error {
+	return q.Datastore.InTx(ctx, func(ds Datastore) error {
+		return fn(&QuotaDatastore{Datastore: ds})
+	})
+}
+
+// projectForSubproject returns the ID of the Project containing the
+// Subproject with the given ID.
+func (q *QuotaDatastore) projectForSubproject(subprojectID uint32) (ProjectID, error) {
+	sp, err := q.Datastore.GetSubprojectByID(subprojectID)
+	if err != nil {
+		return 0, err
+	}
+	return sp.ProjectID, nil
+}
+
+// projectForRepo returns the ID of the Project containing the Repo
+// with the given ID.
+func (q *QuotaDatastore) projectForRepo(repoID RepoID) (ProjectID, error) {
+	r, err := q.Datastore.GetRepoByID(repoID)
+	if err != nil {
+		return 0, err
+	}
+	return q.projectForSubproject(r.SubprojectID)
+}
+
+// checkRepoPullQuota checks the stored pulls quota for the Project
+// containing the Repo with the given ID.
+func (q *QuotaDatastore) checkRepoPullQuota(repoID RepoID) error {
+	projectID, err := q.projectForRepo(repoID)
+	if err != nil {
+		return err
+	}
+	return q.Datastore.CheckQuota(projectID, QuotaResourceStoredPulls)
+}
+
+// isActiveStatus reports whether a job with the given status counts
+// against the concurrent jobs quota.
+func isActiveStatus(status Status) bool {
+	return status == StatusStartup || status == StatusRunning
+}
+
+// checkJobQuota checks the concurrent jobs quota for the Project
+// containing the RepoPull with the given ID.
+func (q *QuotaDatastore) checkJobQuota(repoPullID RepoPullID) error {
+	rp, err := q.Datastore.GetRepoPullByID(repoPullID)
+	if err != nil {
+		return err
+	}
+	projectID, err := q.projectForRepo(rp.RepoID)
+	if err != nil {
+		return err
+	}
+	return q.Datastore.CheckQuota(projectID, QuotaResourceConcurrentJobs)
+}
+
+// AddRepo adds a new Repo if its project is within its repos quota.
+func (q *QuotaDatastore) AddRepo(subprojectID uint32, name string, address string) (RepoID, error) {
+	projectID, err := q.projectForSubproject(subprojectID)
+	if err != nil {
+		return 0, err
+	}
+	if err = q.Datastore.CheckQuota(projectID, QuotaResourceRepos); err != nil {
+		return 0, err
+	}
+	return q.Datastore.AddRepo(subprojectID, name, address)
+}
+
+// GetOrCreateRepo gets the Repo with the given name, or adds it if
+// it does not exist and its project is within its repos quota.
+func (q *QuotaDatastore) GetOrCreateRepo(subprojectID uint32, name string, address string) (*Repo, bool, error) {
+	// an existing repo is returned even if the project is at its
+	// limit, so only check the quota if it must be created
+	repos, err := q.Datastore.GetAllReposForSubprojectID(subprojectID)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, r := range repos {
+		if r.Name == name {
+			return r, false, nil
+		}
+	}
+
+	projectID, err := q.projectForSubproject(subprojectID)
+	if err != nil {
+		return nil, false, err
+	}
+	if err = q.Datastore.CheckQuota(projectID, QuotaResourceRepos); err != nil {
+		return nil, false, err
+	}
+	return q.Datastore.GetOrCreateRepo(subprojectID, name, address)
+}
+
+// AddRepoWithUUID adds a new Repo with a client-supplied UUID if its
+// project is within its repos quota. As with GetOrCreateRepo, the
+// ID of a repo already added with the UUID is returned even if the
+// project is at its limit, so that retries still succeed.
+func (q *QuotaDatastore) AddRepoWithUUID(externalUUID string, subprojectID uint32, name string, address string) (RepoID, bool, error) {
+	id, err := q.Datastore.GetRepoIDByExternalUUID(externalUUID)
+	if _, notFound := err.(*NotFoundError); !notFound {
+		return id, false, err
+	}
+
+	projectID, err := q.projectForSubproject(subprojectID)
+	if err != nil {
+		return 0, false, err
+	}
+	if err = q.Datastore.CheckQuota(projectID, QuotaResourceRepos); err != nil {
+		return 0, false, err
+	}
+	return q.Datastore.AddRepoWithUUID(externalUUID, subprojectID, name, address)
+}
+
+// AddRepoPull adds a new RepoPull if its project is within its
+// stored pulls quota.
+func (q *QuotaDatastore) AddRepoPull(repoID RepoID, branch string, commit string, tag string, spdxID string) (RepoPullID, error) {
+	if err := q.checkRepoPullQuota(repoID); err != nil {
+		return 0, err
+	}
+	return q.Datastore.AddRepoPull(repoID, branch, commit, tag, spdxID)
+}
+
+// AddRepoPullWithPipeline adds a new RepoPull with its pipeline of
+// Jobs if its project is within its stored pulls and concurrent jobs
+// quotas.
+func (q *QuotaDatastore) AddRepoPullWithPipeline(repoID RepoID, branch string, commit string, tag string, spdxID string, pipeline []JobTemplate) (RepoPullID, []JobID, error) {
+	return addRepoPullWithPipeline(q, repoID, branch, commit, tag, spdxID, pipeline)
+}
+
+// AddRepoPullWithDefaultPipeline adds a new RepoPull with the
+// default pipeline of Jobs of its project if the project is within
+// its stored pulls and concurrent jobs quotas.
+func (q *QuotaDatastore) AddRepoPullWithDefaultPipeline(repoID RepoID, branch string, commit string, tag string, spdxID string) (RepoPullID, []JobID, error) {
+	return addRepoPullWithDefaultPipeline(q, repoID, branch, commit, tag, spdxID)
+}
+
+// AddFullRepoPull adds a new RepoPull if its project is within its
+// stored pulls quota.
+func (q *QuotaDatastore) AddFullRepoPull(repoID RepoID, branch string, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string, commit string, tag string, spdxID string) (RepoPullID, error) {
+	if err := q.checkRepoPullQuota(repoID); err != nil {
+		return 0, err
+	}
+	return q.Datastore.AddFullRepoPull(repoID, branch, startedAt, finishedAt, status, health, output, commit, tag, spdxID)
+}
+
+// AddRepoPullWithUUID adds a new RepoPull with a client-supplied UUID
+// if its project is within its stored pulls quota. The ID of a repo
+// pull already added with the UUID is returned even if the project
+// is at its limit.
+func (q *QuotaDatastore) AddRepoPullWithUUID(externalUUID string, repoID RepoID, branch string, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string, commit string, tag string, spdxID string) (RepoPullID, bool, error) {
+	id, err := q.Datastore.GetRepoPullIDByExternalUUID(externalUUID)
+	if _, notFound := err.(*NotFoundError); !notFound {
+		return id, false, err
+	}
+
+	if err := q.checkRepoPullQuota(repoID); err != nil {
+		return 0, false, err
+	}
+	return q.Datastore.AddRepoPullWithUUID(externalUUID, repoID, branch, startedAt, finishedAt, status, health, output, commit, tag, spdxID)
+}
+
+// AddJob adds a new Job if its project is within its concurrent
+// jobs quota.
+func (q *QuotaDatastore) AddJob(repoPullID RepoPullID, agentID AgentID, priorJobIDs []JobID) (JobID, error) {
+	if err := q.checkJobQuota(repoPullID); err != nil {
+		return 0, err
+	}
+	return q.Datastore.AddJob(repoPullID, agentID, priorJobIDs)
+}
+
+// AddJobWithConfigs adds a new Job with configs if its project is
+// within its concurrent jobs quota.
+func (q *QuotaDatastore) AddJobWithConfigs(repoPullID RepoPullID, agentID AgentID, priorJobIDs []JobID, configKV map[string]string, configCodeReader map[string]JobPathConfig, configSpdxReader map[string]JobPathConfig) (JobID, error) {
+	if err := q.checkJobQuota(repoPullID); err != nil {
+		return 0, err
+	}
+	return q.Datastore.AddJobWithConfigs(repoPullID, agentID, priorJobIDs, configKV, configCodeReader, configSpdxReader)
+}
+
+// AddJobWithUUID adds a new Job with a client-supplied UUID if its
+// project is within its concurrent jobs quota. The ID of a job
+// already added with the UUID is returned even if the project is at
+// its limit.
+func (q *QuotaDatastore) AddJobWithUUID(externalUUID string, repoPullID RepoPullID, agentID AgentID, priorJobIDs []JobID, configKV map[string]string, configCodeReader map[string]JobPathConfig, configSpdxReader map[string]JobPathConfig) (JobID, bool, error) {
+	id, err := q.Datastore.GetJobIDByExternalUUID(externalUUID)
+	if _, notFound := err.(*NotFoundError); !notFound {
+		return id, false, err
+	}
+
+	if err := q.checkJobQuota(repoPullID); err != nil {
+		return 0, false, err
+	}
+	return q.Datastore.AddJobWithUUID(externalUUID, repoPullID, agentID, priorJobIDs, configKV, configCodeReader, configSpdxReader)
+}
+
+// UpdateJobStatus sets the status variables for a Job, if moving it
+// into StatusStartup or StatusRunning from another status keeps its
+// project within its concurrent jobs quota.
+func (q *QuotaDatastore) UpdateJobStatus(id JobID, version uint32, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string) error {
+	if isActiveStatus(status) {
+		// a job that does not exist is reported as not found by the
+		// wrapped Datastore
+		js, err := q.Datastore.GetJobsByIDs([]JobID{id})
+		if err != nil {
+			return err
+		}
+		if len(js) == 1 && !isActiveStatus(js[0].Status) {
+			if err = q.checkJobQuota(js[0].RepoPullID); err != nil {
+				return err
+			}
+		}
+	}
+	return q.Datastore.UpdateJobStatus(id, version, startedAt, finishedAt, status, health, output)
+}
+
+// UpdateJobStatuses sets the status variables for several Jobs as
+// the wrapped Datastore does, checking the concurrent jobs quota for
+// each update that moves a job into StatusStartup or StatusRunning
+// from another status. Those updates are made one at a time, after
+// the others, so that each is checked against the jobs started
+// before it; an update that would exceed the quota is not made, and
+// its *QuotaExceededError is returned in the map.
+func (q *QuotaDatastore) UpdateJobStatuses(updates []JobStatusUpdate) (map[JobID]error, error) {
+	// updates made one at a time are checked here as well, so that
+	// invalid input still fails before any update is made
+	ids := []JobID{}
+	seen := map[JobID]bool{}
+	for _, u := range updates {
+		if err := validateStatusHealth("job", u.Status, u.Health); err != nil {
+			return nil, err
+		}
+		if seen[u.ID] {
+			return nil, &ValidationError{Entity: "job", Field: "ID", Reason: fmt.Sprintf("%d is updated more than once", u.ID)}
+		}
+		seen[u.ID] = true
+		if isActiveStatus(u.Status) {
+			ids = append(ids, u.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return q.Datastore.UpdateJobStatuses(updates)
+	}
+
+	js, err := q.Datastore.GetJobsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	starting := map[JobID]bool{}
+	for _, j := range js {
+		if !isActiveStatus(j.Status) {
+			starting[j.ID] = true
+		}
+	}
+
+	rest := []JobStatusUpdate{}
+	for _, u := range updates {
+		if !starting[u.ID] {
+			rest = append(rest, u)
+		}
+	}
+	failed, err := q.Datastore.UpdateJobStatuses(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range updates {
+		if !starting[u.ID] {
+			continue
+		}
+		err := q.UpdateJobStatus(u.ID, u.Version, u.StartedAt, u.FinishedAt, u.Status, u.Health, u.Output)
+		if err != nil {
+			failed[u.ID] = err
+		}
+	}
+	return failed, nil
+}
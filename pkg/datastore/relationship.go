@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+// Relationship describes a directed relationship from one Component
+// to another, such as a dependency. Both Components are expected to
+// belong to the same RepoPull.
+type Relationship struct {
+	// ID is the unique ID for this relationship.
+	ID uint64 `json:"id"`
+	// FromComponentID is the ID of the Component on the "from"
+	// side of the relationship.
+	FromComponentID uint64 `json:"from_component_id"`
+	// ToComponentID is the ID of the Component on the "to" side
+	// of the relationship.
+	ToComponentID uint64 `json:"to_component_id"`
+	// Type is the kind of relationship, e.g. DEPENDS_ON.
+	Type RelationshipType `json:"type"`
+}
+
+// Validate checks that the Relationship's fields are well-formed.
+// It returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (r *Relationship) Validate() error {
+	if _, err := RelationshipTypeFromInt(int(r.Type)); err != nil || r.Type == RelationshipTypeUnknown {
+		return &ValidationError{Entity: "relationship", Field: "type", Reason: "must be a known relationship type"}
+	}
+	if r.FromComponentID == r.ToComponentID {
+		return &ValidationError{Entity: "relationship", Field: "to_component_id", Reason: "must differ from from_component_id"}
+	}
+	return nil
+}
+
+// GetRelationshipsForRepoPull returns a slice of all relationships
+// between components in the RepoPull with the given ID, ordered by
+// ID.
+func (db *DB) GetRelationshipsForRepoPull(rpID RepoPullID) ([]*Relationship, error) {
+	rows, err := db.sqldb.Query("SELECT r.id, r.from_component_id, r.to_component_id, r.type FROM peridot.relationships r JOIN peridot.components c ON c.id = r.from_component_id WHERE c.repopull_id = $1 ORDER BY r.id", rpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	relationships := []*Relationship{}
+	for rows.Next() {
+		r := &Relationship{}
+		var typeInt int
+		err := rows.Scan(&r.ID, &r.FromComponentID, &r.ToComponentID, &typeInt)
+		if err != nil {
+			return nil, err
+		}
+		r.Type, err = RelationshipTypeFromInt(typeInt)
+		if err != nil {
+			return nil, err
+		}
+		relationships = append(relationships, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return relationships, nil
+}
+
+// GetRelatedComponents returns a slice of all components reachable
+// from the Component with the given ID by following one or more
+// relationships of the given type, ordered by ID. For example, with
+// RelationshipTypeDependsOn it returns the component's direct and
+// transitive dependencies. Cycles in the graph are permitted; each
+// component is returned at most once.
+func (db *DB) GetRelatedComponents(componentID uint64, relType RelationshipType) ([]*Component, error) {
+	return db.queryComponents(`
+		WITH RECURSIVE related(id) AS (
+			SELECT to_component_id FROM peridot.relationships WHERE from_component_id = $1 AND type = $2
+			UNION
+			SELECT r.to_component_id FROM peridot.relationships r JOIN related ON r.from_component_id = related.id WHERE r.type = $2
+		)
+		SELECT c.id, c.repopull_id, c.name, c.version, c.purl, c.supplier FROM peridot.components c JOIN related ON c.id = related.id ORDER BY c.id`,
+		componentID, IntFromRelationshipType(relType))
+}
+
+// GetRootComponentsForRepoPull returns a slice of all components in
+// the RepoPull with the given ID that are not on the "to" side of
+// any relationship, ordered by ID. These are the starting points
+// for traversing the RepoPull's relationship graph.
+func (db *DB) GetRootComponentsForRepoPull(rpID RepoPullID) ([]*Component, error) {
+	return db.queryComponents("SELECT c.id, c.repopull_id, c.name, c.version, c.purl, c.supplier FROM peridot.components c WHERE c.repopull_id = $1 AND NOT EXISTS (SELECT 1 FROM peridot.relationships r WHERE r.to_component_id = c.id) ORDER BY c.id", rpID)
+}
+
+// AddRelationships adds the given relationships in a single
+// transaction, so that either all or none of them are added. The
+// relationships' ID fields are ignored. It returns the new
+// relationships' IDs, in the same order, on success or an error if
+// failing.
+func (db *DB) AddRelationships(relationships []*Relationship) ([]uint64, error) {
+	for _, r := range relationships {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.relationships(from_component_id, to_component_id, type) VALUES ($1, $2, $3) RETURNING id")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	ids := make([]uint64, 0, len(relationships))
+	for _, r := range relationships {
+		var id uint64
+		err = stmt.QueryRow(r.FromComponentID, r.ToComponentID, IntFromRelationshipType(r.Type)).Scan(&id)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetRelationshipsForRepoPull(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "from_component_id", "to_component_id", "type"}).
+		AddRow(3, 14, 15, 10).
+		AddRow(4, 14, 16, 20)
+	mock.ExpectQuery(`SELECT r.id, r.from_component_id, r.to_component_id, r.type FROM peridot.relationships r JOIN peridot.components c ON c.id = r.from_component_id WHERE c.repopull_id = \$1 ORDER BY r.id`).
+		WithArgs(41).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetRelationshipsForRepoPull(41)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*Relationship{
+		&Relationship{ID: 3, FromComponentID: 14, ToComponentID: 15, Type: RelationshipTypeDependsOn},
+		&Relationship{ID: 4, FromComponentID: 14, ToComponentID: 16, Type: RelationshipTypeContains},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldGetRelatedComponents(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "repopull_id", "name", "version", "purl", "supplier"}).
+		AddRow(15, 41, "left-pad", "1.3.0", "pkg:npm/left-pad@1.3.0", "").
+		AddRow(17, 41, "is-number", "7.0.0", "pkg:npm/is-number@7.0.0", "")
+	mock.ExpectQuery(`WITH RECURSIVE related\(id\) AS`).
+		WithArgs(14, 10).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetRelatedComponents(14, RelationshipTypeDependsOn)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*Component{
+		&Component{ID: 15, RepoPullID: 41, Name: "left-pad", Version: "1.3.0", PURL: "pkg:npm/left-pad@1.3.0"},
+		&Component{ID: 17, RepoPullID: 41, Name: "is-number", Version: "7.0.0", PURL: "pkg:npm/is-number@7.0.0"},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldAddRelationships(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.relationships\(from_component_id, to_component_id, type\) VALUES \(\$1, \$2, \$3\) RETURNING id`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(14, 15, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectCommit()
+
+	// run the tested function
+	ids, err := db.AddRelationships([]*Relationship{
+		&Relationship{FromComponentID: 14, ToComponentID: 15, Type: RelationshipTypeDependsOn},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if !reflect.DeepEqual([]uint64{3}, ids) {
+		t.Errorf("expected %v, got %v", []uint64{3}, ids)
+	}
+}
+
+func TestShouldFailAddRelationshipsWithInvalidFields(t *testing.T) {
+	tests := []struct {
+		r     *Relationship
+		field string
+	}{
+		{&Relationship{FromComponentID: 14, ToComponentID: 15}, "type"},
+		{&Relationship{FromComponentID: 14, ToComponentID: 15, Type: RelationshipType(15)}, "type"},
+		{&Relationship{FromComponentID: 14, ToComponentID: 14, Type: RelationshipTypeContains}, "to_component_id"},
+	}
+
+	for _, tt := range tests {
+		_, err := (&DB{}).AddRelationships([]*Relationship{tt.r})
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("for %#v: expected *ValidationError, got %v", tt.r, err)
+			continue
+		}
+		if verr.Field != tt.field {
+			t.Errorf("expected field %v, got %v", tt.field, verr.Field)
+		}
+	}
+}
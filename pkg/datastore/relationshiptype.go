@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RelationshipType defines the different ways in which one
+// Component in peridot can be related to another. The string
+// encodings match the corresponding SPDX relationship types.
+type RelationshipType int
+
+const (
+	// RelationshipTypeUnknown is a zero value which indicates
+	// that the relationship type is not currently known.
+	RelationshipTypeUnknown RelationshipType = 0
+
+	// RelationshipTypeDependsOn means that the "from" Component
+	// depends on the "to" Component.
+	RelationshipTypeDependsOn RelationshipType = 10
+
+	// RelationshipTypeContains means that the "from" Component
+	// contains the "to" Component, e.g. as a vendored copy.
+	RelationshipTypeContains RelationshipType = 20
+)
+
+// RelationshipTypeFromInt converts an integer to its corresponding
+// RelationshipType value. It returns that value or an error if the
+// integer is invalid.
+func RelationshipTypeFromInt(typeInt int) (RelationshipType, error) {
+	switch typeInt {
+	case 0:
+		return RelationshipTypeUnknown, nil
+	case 10:
+		return RelationshipTypeDependsOn, nil
+	case 20:
+		return RelationshipTypeContains, nil
+	}
+
+	return RelationshipTypeUnknown, fmt.Errorf("invalid relationship type integer %d", typeInt)
+}
+
+// IntFromRelationshipType converts a RelationshipType value to its
+// corresponding integer value.
+func IntFromRelationshipType(relType RelationshipType) int {
+	switch relType {
+	case RelationshipTypeUnknown:
+		return 0
+	case RelationshipTypeDependsOn:
+		return 10
+	case RelationshipTypeContains:
+		return 20
+	}
+
+	return 0
+}
+
+// RelationshipTypeFromString converts a string to its corresponding
+// RelationshipType value. It returns that value or an error if the
+// string is invalid.
+func RelationshipTypeFromString(typeStr string) (RelationshipType, error) {
+	switch typeStr {
+	case "unknown":
+		return RelationshipTypeUnknown, nil
+	case "DEPENDS_ON":
+		return RelationshipTypeDependsOn, nil
+	case "CONTAINS":
+		return RelationshipTypeContains, nil
+	}
+
+	return RelationshipTypeUnknown, fmt.Errorf("invalid relationship type string %s", typeStr)
+}
+
+// StringFromRelationshipType converts a RelationshipType value to
+// its corresponding string value.
+func StringFromRelationshipType(relType RelationshipType) string {
+	switch relType {
+	case RelationshipTypeUnknown:
+		return "unknown"
+	case RelationshipTypeDependsOn:
+		return "DEPENDS_ON"
+	case RelationshipTypeContains:
+		return "CONTAINS"
+	}
+
+	return "unknown"
+}
+
+// String implements fmt.Stringer, returning the string encoding of
+// the RelationshipType value.
+func (relType RelationshipType) String() string {
+	return StringFromRelationshipType(relType)
+}
+
+// MarshalJSON converts the RelationshipType value into a slice of
+// bytes containing its string encoding.
+func (relType RelationshipType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(StringFromRelationshipType(relType))
+}
+
+// UnmarshalJSON converts a slice of bytes containing the string
+// encoding of a relationship type into the corresponding
+// RelationshipType value.
+func (relType *RelationshipType) UnmarshalJSON(b []byte) error {
+	var s string
+
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	relVal, err := RelationshipTypeFromString(s)
+	if err != nil {
+		return err
+	}
+
+	*relType = relVal
+	return nil
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCanConvertRelationshipTypes(t *testing.T) {
+	tests := []struct {
+		relType RelationshipType
+		i       int
+		s       string
+	}{
+		{RelationshipTypeUnknown, 0, "unknown"},
+		{RelationshipTypeDependsOn, 10, "DEPENDS_ON"},
+		{RelationshipTypeContains, 20, "CONTAINS"},
+	}
+
+	for _, tt := range tests {
+		if got := IntFromRelationshipType(tt.relType); got != tt.i {
+			t.Errorf("expected %v, got %v", tt.i, got)
+		}
+		if got := StringFromRelationshipType(tt.relType); got != tt.s {
+			t.Errorf("expected %v, got %v", tt.s, got)
+		}
+		if got, err := RelationshipTypeFromInt(tt.i); err != nil || got != tt.relType {
+			t.Errorf("expected %v, nil; got %v, %v", tt.relType, got, err)
+		}
+		if got, err := RelationshipTypeFromString(tt.s); err != nil || got != tt.relType {
+			t.Errorf("expected %v, nil; got %v, %v", tt.relType, got, err)
+		}
+	}
+}
+
+func TestCannotConvertInvalidRelationshipTypes(t *testing.T) {
+	if _, err := RelationshipTypeFromInt(15); err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+	if _, err := RelationshipTypeFromString("depends_on"); err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+}
+
+func TestCanMarshalRelationshipTypeToJSON(t *testing.T) {
+	js, err := json.Marshal(RelationshipTypeContains)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if string(js) != `"CONTAINS"` {
+		t.Errorf("expected %v, got %v", `"CONTAINS"`, string(js))
+	}
+
+	var relType RelationshipType
+	err = json.Unmarshal(js, &relType)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if relType != RelationshipTypeContains {
+		t.Errorf("expected %v, got %v", RelationshipTypeContains, relType)
+	}
+}
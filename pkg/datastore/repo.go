@@ -4,14 +4,16 @@ package datastore
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // Repo describes a repo within peridot. A Repo is contained within
 // one Subproject, and a Repo contains one or more RepoBranches.
 type Repo struct {
 	// ID is the unique ID for this repo.
-	ID uint32 `json:"id"`
+	ID RepoID `json:"id"`
 	// SubprojectID is the unique ID for this repo's subproject.
 	SubprojectID uint32 `json:"subproject_id"`
 	// Name is this repo's reference name.
@@ -19,36 +21,73 @@ type Repo struct {
 	// Address is the address from which this repo is pulled, e.g.
 	// whatever address would be used in a "git clone" command.
 	Address string `json:"address"`
+	// CreatedAt is when this repo was added. It is set by the
+	// database.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// UpdatedAt is when this repo was last changed. It is set by
+	// the database.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
 }
 
-// GetAllRepos returns a slice of all repos in the database.
-func (db *DB) GetAllRepos() ([]*Repo, error) {
-	rows, err := db.sqldb.Query("SELECT id, subproject_id, name, address FROM peridot.repos ORDER BY id")
+// MarshalJSON converts the Repo into a slice of bytes containing its
+// JSON encoding, leaving out created_at and updated_at if unset.
+func (r Repo) MarshalJSON() ([]byte, error) {
+	type repoAlias Repo
+	return json.Marshal(struct {
+		repoAlias
+		CreatedAt *time.Time `json:"created_at,omitempty"`
+		UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	}{repoAlias: repoAlias(r), CreatedAt: jsonTimePtr(r.CreatedAt), UpdatedAt: jsonTimePtr(r.UpdatedAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of a Repo into the Repo, leaving CreatedAt and UpdatedAt as the
+// zero time if they are missing or null.
+func (r *Repo) UnmarshalJSON(b []byte) error {
+	type repoAlias Repo
+	aux := struct {
+		*repoAlias
+		CreatedAt *time.Time `json:"created_at"`
+		UpdatedAt *time.Time `json:"updated_at"`
+	}{repoAlias: (*repoAlias)(r)}
+
+	err := json.Unmarshal(b, &aux)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
 
-	repos := []*Repo{}
-	for rows.Next() {
-		repo := &Repo{}
-		err := rows.Scan(&repo.ID, &repo.SubprojectID, &repo.Name, &repo.Address)
-		if err != nil {
-			return nil, err
-		}
-		repos = append(repos, repo)
+	r.CreatedAt = timeFromJSONPtr(aux.CreatedAt)
+	r.UpdatedAt = timeFromJSONPtr(aux.UpdatedAt)
+	return nil
+}
+
+// Validate checks that the Repo's fields are well-formed. It returns
+// nil if so, or a *ValidationError describing the first invalid field.
+func (r *Repo) Validate() error {
+	if err := requireNonEmpty("repo", "name", r.Name); err != nil {
+		return err
 	}
+	return requireNonEmpty("repo", "address", r.Address)
+}
 
-	if err = rows.Err(); err != nil {
+const repoColumns = "id, subproject_id, name, address, created_at, updated_at"
+
+// scanRepo reads a Repo from a row selecting repoColumns.
+func scanRepo(rs rowScanner) (*Repo, error) {
+	repo := &Repo{}
+	err := rs.Scan(&repo.ID, &repo.SubprojectID, &repo.Name, &repo.Address, &repo.CreatedAt, &repo.UpdatedAt)
+	if err != nil {
 		return nil, err
 	}
-	return repos, nil
+	repo.CreatedAt = normalizeTime(repo.CreatedAt)
+	repo.UpdatedAt = normalizeTime(repo.UpdatedAt)
+	return repo, nil
 }
 
-// GetAllReposForSubprojectID returns a slice of all repos in
-// the database for the given subproject ID.
-func (db *DB) GetAllReposForSubprojectID(subprojectID uint32) ([]*Repo, error) {
-	rows, err := db.sqldb.Query("SELECT id, subproject_id, name, address FROM peridot.repos WHERE subproject_id = $1 ORDER BY id", subprojectID)
+// queryRepos runs a query selecting repoColumns and returns the
+// resulting repos.
+func (db *DB) queryRepos(query string, args ...interface{}) ([]*Repo, error) {
+	rows, err := db.sqldb.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -56,8 +95,7 @@ func (db *DB) GetAllReposForSubprojectID(subprojectID uint32) ([]*Repo, error) {
 
 	repos := []*Repo{}
 	for rows.Next() {
-		repo := &Repo{}
-		err := rows.Scan(&repo.ID, &repo.SubprojectID, &repo.Name, &repo.Address)
+		repo, err := scanRepo(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -70,151 +108,123 @@ func (db *DB) GetAllReposForSubprojectID(subprojectID uint32) ([]*Repo, error) {
 	return repos, nil
 }
 
+// GetAllRepos returns a slice of all repos in the database.
+func (db *DB) GetAllRepos() ([]*Repo, error) {
+	return db.queryRepos("SELECT " + repoColumns + " FROM peridot.repos WHERE " + liveRepoCondition + " ORDER BY id")
+}
+
+// CountAllRepos returns the number of repos in the database.
+func (db *DB) CountAllRepos() (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.repos WHERE " + liveRepoCondition)
+}
+
+// GetAllReposForSubprojectID returns a slice of all repos in
+// the database for the given subproject ID.
+func (db *DB) GetAllReposForSubprojectID(subprojectID uint32) ([]*Repo, error) {
+	return db.queryRepos("SELECT "+repoColumns+" FROM peridot.repos WHERE subproject_id = $1 AND "+liveRepoCondition+" ORDER BY id", subprojectID)
+}
+
+// CountReposForSubprojectID returns the number of repos in the
+// Subproject with the given ID.
+func (db *DB) CountReposForSubprojectID(subprojectID uint32) (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.repos WHERE subproject_id = $1 AND "+liveRepoCondition, subprojectID)
+}
+
 // GetRepoByID returns the Repo with the given ID, or nil
 // and an error if not found.
-func (db *DB) GetRepoByID(id uint32) (*Repo, error) {
-	var repo Repo
-	err := db.sqldb.QueryRow("SELECT id, subproject_id, name, address FROM peridot.repos WHERE id = $1", id).
-		Scan(&repo.ID, &repo.SubprojectID, &repo.Name, &repo.Address)
+func (db *DB) GetRepoByID(id RepoID) (*Repo, error) {
+	repo, err := scanRepo(db.sqldb.QueryRow("SELECT "+repoColumns+" FROM peridot.repos WHERE id = $1 AND "+liveRepoCondition, id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no repo found with ID %v", id)
+		return nil, &NotFoundError{Entity: "repo", ID: fmt.Sprint(id)}
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &repo, nil
+	return repo, nil
+}
+
+// ExistsRepo reports whether a Repo with the given ID exists,
+// without retrieving it.
+func (db *DB) ExistsRepo(id RepoID) (bool, error) {
+	return db.exists("SELECT EXISTS (SELECT 1 FROM peridot.repos WHERE id = $1 AND "+liveRepoCondition+")", id)
 }
 
 // AddRepo adds a new repo with the given name and address,
 // referencing the designated Subproject. It returns the new
 // repo's ID on success or an error if failing.
-func (db *DB) AddRepo(subprojectID uint32, name string, address string) (uint32, error) {
+func (db *DB) AddRepo(subprojectID uint32, name string, address string) (RepoID, error) {
+	r := &Repo{SubprojectID: subprojectID, Name: name, Address: address}
+	if err := r.Validate(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+
 	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("INSERT INTO peridot.repos(subproject_id, name, address) VALUES ($1, $2, $3) RETURNING id")
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.repos(subproject_id, name, address) VALUES ($1, $2, $3) RETURNING id")
 	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
 
-	var repoID uint32
-	err = stmt.QueryRow(subprojectID, name, address).Scan(&repoID)
+	err = stmt.QueryRow(subprojectID, name, address).Scan(&r.ID)
+	if err != nil {
+		tx.Rollback()
+		return 0, translateConstraintError("repo", err)
+	}
+
+	err = addOutboxEvent(tx, "repo", r.ID, AuditActionAdd, r)
 	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
 		return 0, err
 	}
-	return repoID, nil
+	return r.ID, nil
 }
 
 // UpdateRepo updates an existing Repo with the given ID,
 // changing to the specified name and address. If an empty
 // string is passed, the existing value will remain unchanged.
 // It returns nil on success or an error if failing.
-func (db *DB) UpdateRepo(id uint32, newName string, newAddress string) error {
-	var err error
-	var result sql.Result
-
+func (db *DB) UpdateRepo(id RepoID, newName string, newAddress string) error {
 	// FIXME consider whether to move out into one-time-prepared statements
 	if newName != "" && newAddress != "" {
-		stmt, err := db.sqldb.Prepare("UPDATE peridot.repos SET name = $1, address = $2 WHERE id = $3")
-		if err != nil {
-			return err
-		}
-		result, err = stmt.Exec(newName, newAddress, id)
-
+		return db.execWithOutboxEvent("repo", id, AuditActionUpdate, map[string]interface{}{"id": id, "name": newName, "address": newAddress},
+			"UPDATE peridot.repos SET name = $1, address = $2 WHERE id = $3", newName, newAddress, id)
 	} else if newName != "" {
-		stmt, err := db.sqldb.Prepare("UPDATE peridot.repos SET name = $1 WHERE id = $2")
-		if err != nil {
-			return err
-		}
-		result, err = stmt.Exec(newName, id)
-
+		return db.execWithOutboxEvent("repo", id, AuditActionUpdate, map[string]interface{}{"id": id, "name": newName},
+			"UPDATE peridot.repos SET name = $1 WHERE id = $2", newName, id)
 	} else if newAddress != "" {
-		stmt, err := db.sqldb.Prepare("UPDATE peridot.repos SET address = $1 WHERE id = $2")
-		if err != nil {
-			return err
-		}
-		result, err = stmt.Exec(newAddress, id)
-
-	} else {
-		return fmt.Errorf("only empty strings passed to UpdateRepo for id %v", id)
-	}
-
-	// check error
-	if err != nil {
-		return err
-	}
-
-	// check that something was actually updated
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return fmt.Errorf("no repo found with ID %v", id)
+		return db.execWithOutboxEvent("repo", id, AuditActionUpdate, map[string]interface{}{"id": id, "address": newAddress},
+			"UPDATE peridot.repos SET address = $1 WHERE id = $2", newAddress, id)
 	}
 
-	return nil
+	return fmt.Errorf("only empty strings passed to UpdateRepo for id %v", id)
 }
 
 // UpdateRepoSubprojectID updates an existing Repo with the
 // given ID, changing its corresponding Subproject ID.
 // It returns nil on success or an error if failing.
-func (db *DB) UpdateRepoSubprojectID(id uint32, newSubprojectID uint32) error {
-	var err error
-	var result sql.Result
-
+func (db *DB) UpdateRepoSubprojectID(id RepoID, newSubprojectID uint32) error {
 	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("UPDATE peridot.repos SET subproject_id = $1 WHERE id = $2")
-	if err != nil {
-		return err
-	}
-
-	// run update command
-	result, err = stmt.Exec(newSubprojectID, id)
-	if err != nil {
-		return err
-	}
-
-	// check that something was actually updated
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return fmt.Errorf("no repo found with ID %v", id)
-	}
-
-	return nil
+	return db.execWithOutboxEvent("repo", id, AuditActionUpdate, map[string]interface{}{"id": id, "subproject_id": newSubprojectID},
+		"UPDATE peridot.repos SET subproject_id = $1 WHERE id = $2", newSubprojectID, id)
 }
 
-// DeleteRepo deletes an existing Repo with the given ID.
-// It returns nil on success or an error if failing.
-func (db *DB) DeleteRepo(id uint32) error {
-	var err error
-	var result sql.Result
-
+// DeleteRepo deletes an existing Repo with the given ID. If soft
+// deletion is enabled, the repo is only marked as deleted; see
+// SetSoftDelete. It returns nil on success or an error if failing.
+func (db *DB) DeleteRepo(id RepoID) error {
 	// FIXME consider whether need to delete sub-elements first, or
 	// FIXME whether to set up sub-elements' schemas to delete on cascade
 
 	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("DELETE FROM peridot.repos WHERE id = $1")
-	if err != nil {
-		return err
-	}
-	result, err = stmt.Exec(id)
-
-	// check error
-	if err != nil {
-		return err
-	}
-
-	// check that something was actually deleted
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return fmt.Errorf("no repo found with ID %v", id)
-	}
-
-	return nil
+	return db.deleteRow("repo", "repos", id)
 }
@@ -4,6 +4,7 @@ package datastore
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -19,13 +20,13 @@ func TestShouldGetAllRepos(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "subproject_id", "name", "address"}).
-		AddRow(1, 1, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git").
-		AddRow(2, 1, "kubernetes-client/python", "git@github.com:kubernetes-client/python.git").
-		AddRow(3, 3, "aai/aai-common", "https://gerrit.onap.org/r/aai/aai-common").
-		AddRow(4, 1, "kubernetes/minikube", "git@github.com:kubernetes/minikube.git").
-		AddRow(5, 3, "aai/esr-gui", "https://gerrit.onap.org/r/aai/esr-gui")
-	mock.ExpectQuery("SELECT id, subproject_id, name, address FROM peridot.repos ORDER BY id").
+	sentRows := sqlmock.NewRows([]string{"id", "subproject_id", "name", "address", "created_at", "updated_at"}).
+		AddRow(1, 1, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git", testCreatedAt, testUpdatedAt).
+		AddRow(2, 1, "kubernetes-client/python", "git@github.com:kubernetes-client/python.git", testCreatedAt, testUpdatedAt).
+		AddRow(3, 3, "aai/aai-common", "https://gerrit.onap.org/r/aai/aai-common", testCreatedAt, testUpdatedAt).
+		AddRow(4, 1, "kubernetes/minikube", "git@github.com:kubernetes/minikube.git", testCreatedAt, testUpdatedAt).
+		AddRow(5, 3, "aai/esr-gui", "https://gerrit.onap.org/r/aai/esr-gui", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery("SELECT id, subproject_id, name, address, created_at, updated_at FROM peridot.repos WHERE " + liveRepoConditionRegex + " ORDER BY id").
 		WillReturnRows(sentRows)
 
 	// run the tested function
@@ -81,10 +82,10 @@ func TestShouldGetAllReposForOneSubproject(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "subproject_id", "name", "address"}).
-		AddRow(3, 3, "aai/aai-common", "https://gerrit.onap.org/r/aai/aai-common").
-		AddRow(5, 3, "aai/esr-gui", "https://gerrit.onap.org/r/aai/esr-gui")
-	mock.ExpectQuery(`SELECT id, subproject_id, name, address FROM peridot.repos WHERE subproject_id = \$1 ORDER BY id`).
+	sentRows := sqlmock.NewRows([]string{"id", "subproject_id", "name", "address", "created_at", "updated_at"}).
+		AddRow(3, 3, "aai/aai-common", "https://gerrit.onap.org/r/aai/aai-common", testCreatedAt, testUpdatedAt).
+		AddRow(5, 3, "aai/esr-gui", "https://gerrit.onap.org/r/aai/esr-gui", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, subproject_id, name, address, created_at, updated_at FROM peridot.repos WHERE subproject_id = \$1 AND ` + liveRepoConditionRegex + ` ORDER BY id`).
 		WillReturnRows(sentRows)
 
 	// run the tested function
@@ -127,9 +128,9 @@ func TestShouldGetRepoByID(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "subproject_id", "name", "address"}).
-		AddRow(3, 3, "aai/aai-common", "https://gerrit.onap.org/r/aai/aai-common")
-	mock.ExpectQuery(`[SELECT id, subproject_id, name, address FROM peridot.repos WHERE id = \$1]`).
+	sentRows := sqlmock.NewRows([]string{"id", "subproject_id", "name", "address", "created_at", "updated_at"}).
+		AddRow(3, 3, "aai/aai-common", "https://gerrit.onap.org/r/aai/aai-common", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`[SELECT id, subproject_id, name, address, created_at, updated_at FROM peridot.repos WHERE id = \$1]`).
 		WithArgs(3).
 		WillReturnRows(sentRows)
 
@@ -169,7 +170,7 @@ func TestShouldFailGetRepoByIDForUnknownID(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	mock.ExpectQuery(`[SELECT id, subproject_id, name, address FROM peridot.repos WHERE id = \$1]`).
+	mock.ExpectQuery(`[SELECT id, subproject_id, name, address, created_at, updated_at FROM peridot.repos WHERE id = \$1]`).
 		WithArgs(413).
 		WillReturnRows(sqlmock.NewRows([]string{}))
 
@@ -178,8 +179,8 @@ func TestShouldFailGetRepoByIDForUnknownID(t *testing.T) {
 	if repo != nil {
 		t.Fatalf("expected nil repo, got %v", repo)
 	}
-	if err == nil {
-		t.Fatalf("expected non-nil error, got nil")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
 	}
 
 	// check sqlmock expectations
@@ -199,11 +200,15 @@ func TestShouldAddRepo(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[INSERT INTO peridot.repos(subproject_id, name, address) VALUES (\$1, \$2, \$3) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "INSERT INTO peridot.repos"
 	mock.ExpectQuery(stmt).
 		WithArgs(1, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	repoID, err := db.AddRepo(1, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git")
@@ -233,11 +238,13 @@ func TestShouldFailAddRepoWithUnknownSubprojectID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[INSERT INTO peridot.repos(project_id, name, fullname) VALUES (\$1, \$2, \$3) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "INSERT INTO peridot.repos"
 	mock.ExpectQuery(stmt).
 		WithArgs(17, "unknown-subproject", "https://example.com/some-repo.git").
 		WillReturnError(fmt.Errorf("pq: insert or update on table \"peridot.repos\" violates foreign key constraint \"peridot.repos_subproject_id_fkey\""))
+	mock.ExpectRollback()
 
 	// run the tested function
 	_, err = db.AddRepo(17, "unknown-subproject", "https://example.com/some-repo.git")
@@ -262,11 +269,15 @@ func TestShouldUpdateRepoNameAndAddress(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.repos SET name = \$1, address = \$2 WHERE id = \$3]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.repos"
 	mock.ExpectExec(stmt).
 		WithArgs("myrepo", "https://example.com/some-repo.git", 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.UpdateRepo(1, "myrepo", "https://example.com/some-repo.git")
@@ -291,11 +302,15 @@ func TestShouldUpdateRepoNameOnly(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.repos SET name = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.repos"
 	mock.ExpectExec(stmt).
 		WithArgs("myrepo", 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.UpdateRepo(1, "myrepo", "")
@@ -320,11 +335,15 @@ func TestShouldUpdateRepoAddressOnly(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.repos SET address = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.repos"
 	mock.ExpectExec(stmt).
 		WithArgs("https://example.com/some-repo.git", 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.UpdateRepo(1, "", "https://example.com/some-repo.git")
@@ -371,11 +390,13 @@ func TestShouldFailUpdateRepoWithUnknownID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.repos SET name = \$1, address = \$2 WHERE id = \$3]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.repos"
 	mock.ExpectExec(stmt).
 		WithArgs("oops", "https://example.com/some-repo.git", 413).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	// run the tested function with an unknown project ID number
 	err = db.UpdateRepo(413, "oops", "https://example.com/some-repo.git")
@@ -400,11 +421,15 @@ func TestShouldUpdateRepoSubprojectID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.repos SET subproject_id = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.repos"
 	mock.ExpectExec(stmt).
 		WithArgs(3, 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.UpdateRepoSubprojectID(1, 3)
@@ -429,11 +454,13 @@ func TestShouldFailUpdateRepoSubprojectIDToUnknownSubprojectID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.repos SET subproject_id = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.repos"
 	mock.ExpectExec(stmt).
 		WithArgs(17, 1).
 		WillReturnError(fmt.Errorf("pq: insert or update on table \"peridot.repos\" violates foreign key constraint \"peridot.repos_subproject_id_fkey\""))
+	mock.ExpectRollback()
 
 	// run the tested function
 	err = db.UpdateRepoSubprojectID(1, 17)
@@ -458,11 +485,13 @@ func TestShouldFailUpdateRepoSubProjectIDWithUnknownRepoID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.repos SET subproject_id = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.repos"
 	mock.ExpectExec(stmt).
 		WithArgs(413, 1).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	// run the tested function with an unknown project ID number
 	err = db.UpdateRepoSubprojectID(1, 413)
@@ -487,11 +516,15 @@ func TestShouldDeleteRepo(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[DELETE FROM peridot.repos WHERE id = \$1]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "DELETE FROM peridot.repos"
 	mock.ExpectExec(stmt).
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.DeleteRepo(1)
@@ -516,11 +549,13 @@ func TestShouldFailDeleteRepoWithUnknownID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[DELETE FROM peridot.repos WHERE id = \$1]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "DELETE FROM peridot.repos"
 	mock.ExpectExec(stmt).
 		WithArgs(413).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	// run the tested function
 	err = db.DeleteRepo(413)
@@ -607,3 +642,114 @@ func TestCannotUnmarshalRepoWithNegativeIDFromJSON(t *testing.T) {
 		t.Fatalf("expected non-nil error, got nil")
 	}
 }
+
+func TestRepoValidate(t *testing.T) {
+	tests := []struct {
+		r       Repo
+		isError bool
+	}{
+		{Repo{Name: "kubernetes/kubernetes", Address: "git@github.com:kubernetes/kubernetes.git"}, false},
+		// invalid values should fail
+		{Repo{Name: "", Address: "git@github.com:kubernetes/kubernetes.git"}, true},
+		{Repo{Name: "kubernetes/kubernetes", Address: ""}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.r.Validate()
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("for %+v: expected isError %v, got %v", tt.r, tt.isError, err)
+		}
+	}
+}
+
+func TestShouldCountAllRepos(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM peridot.repos WHERE ` + liveRepoConditionRegex + `$`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+
+	// run the tested function
+	n, err := db.CountAllRepos()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if n != 5 {
+		t.Errorf("expected %v, got %v", 5, n)
+	}
+}
+
+func TestShouldReportRepoExists(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM peridot.repos WHERE id = \$1 AND ` + liveRepoConditionRegex + `\)`).
+		WithArgs(6).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	// run the tested function
+	ok, err := db.ExistsRepo(6)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if ok != true {
+		t.Errorf("expected %v, got %v", true, ok)
+	}
+}
+
+func TestShouldReportRepoDoesNotExist(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM peridot.repos WHERE id = \$1 AND ` + liveRepoConditionRegex + `\)`).
+		WithArgs(413).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	// run the tested function
+	ok, err := db.ExistsRepo(413)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if ok != false {
+		t.Errorf("expected %v, got %v", false, ok)
+	}
+}
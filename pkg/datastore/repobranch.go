@@ -12,14 +12,21 @@ import (
 // contains one or more RepoPulls.
 type RepoBranch struct {
 	// RepoID is the unique ID for this repo.
-	RepoID uint32 `json:"repo_id"`
+	RepoID RepoID `json:"repo_id"`
 	// Branch is the branch name within this repo.
 	Branch string `json:"branch"`
 }
 
+// Validate checks that the RepoBranch's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (rb *RepoBranch) Validate() error {
+	return requireNonEmpty("repo branch", "branch", rb.Branch)
+}
+
 // GetAllRepoBranchesForRepoID returns a slice of all repo
 // branches in the database for the given Repo ID.
-func (db *DB) GetAllRepoBranchesForRepoID(repoID uint32) ([]*RepoBranch, error) {
+func (db *DB) GetAllRepoBranchesForRepoID(repoID RepoID) ([]*RepoBranch, error) {
 	rows, err := db.sqldb.Query("SELECT repo_id, branch FROM peridot.repo_branches WHERE repo_id = $1 ORDER BY branch", repoID)
 	if err != nil {
 		return nil, err
@@ -42,12 +49,29 @@ func (db *DB) GetAllRepoBranchesForRepoID(repoID uint32) ([]*RepoBranch, error)
 	return repoBranches, nil
 }
 
+// CountRepoBranchesForRepoID returns the number of branches of the
+// Repo with the given ID.
+func (db *DB) CountRepoBranchesForRepoID(repoID RepoID) (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.repo_branches WHERE repo_id = $1", repoID)
+}
+
+// ExistsRepoBranch reports whether the Repo with the given ID
+// has a branch with the given name.
+func (db *DB) ExistsRepoBranch(repoID RepoID, branch string) (bool, error) {
+	return db.exists("SELECT EXISTS (SELECT 1 FROM peridot.repo_branches WHERE repo_id = $1 AND branch = $2)", repoID, branch)
+}
+
 // AddRepoBranch adds a new repo branch as specified,
 // referencing the designated Repo. It returns nil on
 // success or an error if failing.
-func (db *DB) AddRepoBranch(repoID uint32, branch string) error {
+func (db *DB) AddRepoBranch(repoID RepoID, branch string) error {
+	rb := &RepoBranch{RepoID: repoID, Branch: branch}
+	if err := rb.Validate(); err != nil {
+		return err
+	}
+
 	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("INSERT INTO peridot.repo_branches(repo_id, branch) VALUES ($1, $2)")
+	stmt, err := db.prepare("INSERT INTO peridot.repo_branches(repo_id, branch) VALUES ($1, $2)")
 	if err != nil {
 		return err
 	}
@@ -55,7 +79,7 @@ func (db *DB) AddRepoBranch(repoID uint32, branch string) error {
 	result, err := stmt.Exec(repoID, branch)
 	// check error
 	if err != nil {
-		return err
+		return translateConstraintError("repo branch", err)
 	}
 
 	// check that something was actually inserted
@@ -64,16 +88,47 @@ func (db *DB) AddRepoBranch(repoID uint32, branch string) error {
 		return err
 	}
 	if rows == 0 {
-		return fmt.Errorf("no repo found with ID %v", repoID)
+		return &NotFoundError{Entity: "repo", ID: fmt.Sprint(repoID)}
 	}
 
 	return nil
 }
 
+// UpsertRepoBranch adds a new repo branch as specified,
+// referencing the designated Repo, unless the branch is
+// already present, in which case it does nothing. It
+// returns whether the branch was added, or an error if
+// failing.
+func (db *DB) UpsertRepoBranch(repoID RepoID, branch string) (bool, error) {
+	rb := &RepoBranch{RepoID: repoID, Branch: branch}
+	if err := rb.Validate(); err != nil {
+		return false, err
+	}
+
+	// FIXME consider whether to move out into one-time-prepared statement
+	stmt, err := db.prepare("INSERT INTO peridot.repo_branches(repo_id, branch) VALUES ($1, $2) ON CONFLICT (repo_id, branch) DO NOTHING")
+	if err != nil {
+		return false, err
+	}
+
+	result, err := stmt.Exec(repoID, branch)
+	// check error
+	if err != nil {
+		return false, translateConstraintError("repo branch", err)
+	}
+
+	// no rows inserted means the branch was already present
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
 // DeleteRepoBranch deletes an existing RepoBranch with
 // the given branch name for the given repo ID.
 // It returns nil on success or an error if failing.
-func (db *DB) DeleteRepoBranch(repoID uint32, branch string) error {
+func (db *DB) DeleteRepoBranch(repoID RepoID, branch string) error {
 	var err error
 	var result sql.Result
 
@@ -81,7 +136,7 @@ func (db *DB) DeleteRepoBranch(repoID uint32, branch string) error {
 	// FIXME whether to set up sub-elements' schemas to delete on cascade
 
 	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("DELETE FROM peridot.repo_branches WHERE repo_id = $1 AND branch = $2")
+	stmt, err := db.prepare("DELETE FROM peridot.repo_branches WHERE repo_id = $1 AND branch = $2")
 	if err != nil {
 		return err
 	}
@@ -98,7 +153,7 @@ func (db *DB) DeleteRepoBranch(repoID uint32, branch string) error {
 		return err
 	}
 	if rows == 0 {
-		return fmt.Errorf("no branch found with repoID %v, branch %s", repoID, branch)
+		return &NotFoundError{Entity: "repo branch", Key: "repo ID/branch", ID: fmt.Sprintf("%d/%s", repoID, branch)}
 	}
 
 	return nil
@@ -109,6 +109,71 @@ func TestShouldFailAddRepoBranchWithUnknownRepoID(t *testing.T) {
 	}
 }
 
+func TestShouldUpsertRepoBranch(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.repo_branches\(repo_id, branch\) VALUES \(\$1, \$2\) ON CONFLICT \(repo_id, branch\) DO NOTHING`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(3, "dev-1.5").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	added, err := db.UpsertRepoBranch(3, "dev-1.5")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if !added {
+		t.Errorf("expected branch to be added")
+	}
+}
+
+func TestShouldUpsertExistingRepoBranchAsNoOp(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectPrepare("INSERT INTO peridot.repo_branches")
+	mock.ExpectExec("INSERT INTO peridot.repo_branches").
+		WithArgs(3, "dev-1.5").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	added, err := db.UpsertRepoBranch(3, "dev-1.5")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if added {
+		t.Errorf("expected existing branch not to be reported as added")
+	}
+}
+
 func TestShouldDeleteRepoBranch(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
@@ -225,3 +290,34 @@ func TestCannotUnmarshalRepoBranchWithNegativeRepoIDFromJSON(t *testing.T) {
 		t.Fatalf("expected non-nil error, got nil")
 	}
 }
+
+func TestShouldReportRepoBranchExists(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM peridot.repo_branches WHERE repo_id = \$1 AND branch = \$2\)`).
+		WithArgs(3, "dev-1.5").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	// run the tested function
+	ok, err := db.ExistsRepoBranch(3, "dev-1.5")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if ok != true {
+		t.Errorf("expected %v, got %v", true, ok)
+	}
+}
@@ -4,6 +4,7 @@ package datastore
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -15,19 +16,19 @@ import (
 // FindingInstances.
 type RepoPull struct {
 	// ID is the unique ID for this repo pull.
-	ID uint32 `json:"id"`
+	ID RepoPullID `json:"id"`
 	// RepoID is the unique ID for this repo.
-	RepoID uint32 `json:"repo_id"`
+	RepoID RepoID `json:"repo_id"`
 	// Branch is the branch name within this repo.
 	Branch string `json:"branch"`
 	// StartedAt is when peridot began pulling code for this
 	// pull. Should be zero value if code pull has not yet
 	// been started.
-	StartedAt time.Time `json:"started_at"`
+	StartedAt time.Time `json:"started_at,omitempty"`
 	// FinishedAt is when peridot finished pulling code for
 	// this pull. Should be zero value if code pull has not
 	// yet been completed (or will not complete due to error).
-	FinishedAt time.Time `json:"finished_at"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
 	// Status is the run status of the pull.
 	Status Status `json:"status"`
 	// Health is the health of the pull.
@@ -42,12 +43,128 @@ type RepoPull struct {
 	// SPDXID is the SPDX Identifier corresponding to this
 	// pull within peridot.
 	SPDXID string `json:"spdx_id"`
+	// CreatedAt is when this pull was added. It is set by the
+	// database.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// UpdatedAt is when this pull was last changed. It is set by
+	// the database.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// MarshalJSON converts the RepoPull into a slice of bytes containing
+// its JSON encoding, leaving out started_at and finished_at until the
+// pull has started and finished, and created_at and updated_at if
+// unset.
+func (rp RepoPull) MarshalJSON() ([]byte, error) {
+	type repoPullAlias RepoPull
+	return json.Marshal(struct {
+		repoPullAlias
+		StartedAt  *time.Time `json:"started_at,omitempty"`
+		FinishedAt *time.Time `json:"finished_at,omitempty"`
+		CreatedAt  *time.Time `json:"created_at,omitempty"`
+		UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	}{repoPullAlias: repoPullAlias(rp), StartedAt: jsonTimePtr(rp.StartedAt), FinishedAt: jsonTimePtr(rp.FinishedAt), CreatedAt: jsonTimePtr(rp.CreatedAt), UpdatedAt: jsonTimePtr(rp.UpdatedAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of a RepoPull into the RepoPull, leaving any of its four timestamps
+// that are missing or null as the zero time.
+func (rp *RepoPull) UnmarshalJSON(b []byte) error {
+	type repoPullAlias RepoPull
+	aux := struct {
+		*repoPullAlias
+		StartedAt  *time.Time `json:"started_at"`
+		FinishedAt *time.Time `json:"finished_at"`
+		CreatedAt  *time.Time `json:"created_at"`
+		UpdatedAt  *time.Time `json:"updated_at"`
+	}{repoPullAlias: (*repoPullAlias)(rp)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	rp.StartedAt = timeFromJSONPtr(aux.StartedAt)
+	rp.FinishedAt = timeFromJSONPtr(aux.FinishedAt)
+	rp.CreatedAt = timeFromJSONPtr(aux.CreatedAt)
+	rp.UpdatedAt = timeFromJSONPtr(aux.UpdatedAt)
+	return nil
+}
+
+// Validate checks that the RepoPull's fields are well-formed: the
+// branch must be set, the commit must be empty or a full-length
+// lowercase SHA-1 or SHA-256 hash, and the status and health must be
+// defined values. It returns nil if so, or a *ValidationError
+// describing the first invalid field.
+func (rp *RepoPull) Validate() error {
+	if err := requireNonEmpty("repo pull", "branch", rp.Branch); err != nil {
+		return err
+	}
+	if rp.Commit != "" && !isLowerHex(rp.Commit, 40) && !isLowerHex(rp.Commit, 64) {
+		return &ValidationError{Entity: "repo pull", Field: "commit", Reason: fmt.Sprintf("%q is not a 40- or 64-character hex hash", rp.Commit)}
+	}
+	return validateStatusHealth("repo pull", rp.Status, rp.Health)
+}
+
+// Clone returns a copy of the RepoPull that can be modified without
+// affecting the original. It returns nil if rp is nil.
+func (rp *RepoPull) Clone() *RepoPull {
+	if rp == nil {
+		return nil
+	}
+	c := *rp
+	return &c
+}
+
+// Equal reports whether rp and other describe the same RepoPull,
+// comparing times at microsecond precision. Two nil RepoPulls are
+// equal.
+func (rp *RepoPull) Equal(other *RepoPull) bool {
+	if rp == nil || other == nil {
+		return rp == other
+	}
+	return rp.ID == other.ID &&
+		rp.RepoID == other.RepoID &&
+		rp.Branch == other.Branch &&
+		equalTimes(rp.StartedAt, other.StartedAt) &&
+		equalTimes(rp.FinishedAt, other.FinishedAt) &&
+		rp.Status == other.Status &&
+		rp.Health == other.Health &&
+		rp.Output == other.Output &&
+		rp.Commit == other.Commit &&
+		rp.Tag == other.Tag &&
+		rp.SPDXID == other.SPDXID &&
+		equalTimes(rp.CreatedAt, other.CreatedAt) &&
+		equalTimes(rp.UpdatedAt, other.UpdatedAt)
+}
+
+const repoPullColumns = "id, repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id, created_at, updated_at"
+
+// scanRepoPull reads a RepoPull from a row selecting
+// repoPullColumns. NULL output, commit, tag and spdx_id values, as
+// may be written by tools other than peridot, are read as "".
+func scanRepoPull(rs rowScanner) (*RepoPull, error) {
+	rp := &RepoPull{}
+	var output, commit, tag, spdxID sql.NullString
+	err := rs.Scan(&rp.ID, &rp.RepoID, &rp.Branch, &rp.StartedAt, &rp.FinishedAt, &rp.Status, &rp.Health, &output, &commit, &tag, &spdxID, &rp.CreatedAt, &rp.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	rp.StartedAt = normalizeTime(rp.StartedAt)
+	rp.FinishedAt = normalizeTime(rp.FinishedAt)
+	rp.CreatedAt = normalizeTime(rp.CreatedAt)
+	rp.UpdatedAt = normalizeTime(rp.UpdatedAt)
+	rp.Output = output.String
+	rp.Commit = commit.String
+	rp.Tag = tag.String
+	rp.SPDXID = spdxID.String
+	return rp, nil
 }
 
 // GetAllRepoPullsForRepoBranch returns a slice of all repo
 // pulls in the database for the given Repo ID and branch.
-func (db *DB) GetAllRepoPullsForRepoBranch(repoID uint32, branch string) ([]*RepoPull, error) {
-	rows, err := db.sqldb.Query("SELECT id, repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id FROM peridot.repo_pulls WHERE repo_id = $1 AND branch = $2 ORDER BY id", repoID, branch)
+func (db *DB) GetAllRepoPullsForRepoBranch(repoID RepoID, branch string) ([]*RepoPull, error) {
+	rows, err := db.sqldb.Query("SELECT "+repoPullColumns+" FROM peridot.repo_pulls WHERE repo_id = $1 AND branch = $2 AND repo_id IN ("+liveRepoIDs+") ORDER BY id", repoID, branch)
 	if err != nil {
 		return nil, err
 	}
@@ -55,8 +172,7 @@ func (db *DB) GetAllRepoPullsForRepoBranch(repoID uint32, branch string) ([]*Rep
 
 	rps := []*RepoPull{}
 	for rows.Next() {
-		rp := &RepoPull{}
-		err := rows.Scan(&rp.ID, &rp.RepoID, &rp.Branch, &rp.StartedAt, &rp.FinishedAt, &rp.Status, &rp.Health, &rp.Output, &rp.Commit, &rp.Tag, &rp.SPDXID)
+		rp, err := scanRepoPull(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -69,20 +185,68 @@ func (db *DB) GetAllRepoPullsForRepoBranch(repoID uint32, branch string) ([]*Rep
 	return rps, nil
 }
 
+// repoPullSortColumns lists the fields by which GetRepoPulls can
+// sort.
+var repoPullSortColumns = sortColumns{
+	"started_at":  "started_at",
+	"finished_at": "finished_at",
+	"status":      "status",
+	"health":      "health",
+}
+
+// GetRepoPulls returns a slice of repo pulls of the given branch of
+// the Repo with the given ID, sorted and paged as requested, along
+// with a Page describing the results. Repo pulls can be sorted by
+// id, started_at, finished_at, status or health.
+func (db *DB) GetRepoPulls(repoID RepoID, branch string, pr PageRequest) ([]*RepoPull, Page, error) {
+	clause, args, err := pr.sqlClause(repoPullSortColumns, []interface{}{repoID, branch})
+	if err != nil {
+		return nil, Page{}, err
+	}
+
+	rows, err := db.sqldb.Query("SELECT "+repoPullColumns+" FROM peridot.repo_pulls WHERE repo_id = $1 AND branch = $2 AND repo_id IN ("+liveRepoIDs+")"+clause, args...)
+	if err != nil {
+		return nil, Page{}, err
+	}
+	defer rows.Close()
+
+	rps := []*RepoPull{}
+	for rows.Next() {
+		rp, err := scanRepoPull(rows)
+		if err != nil {
+			return nil, Page{}, err
+		}
+		rps = append(rps, rp)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Page{}, err
+	}
+
+	page, n := pr.page(len(rps))
+	return rps[:n], page, nil
+}
+
+// CountRepoPullsForRepoBranch returns the number of repo pulls of
+// the given branch of the Repo with the given ID.
+func (db *DB) CountRepoPullsForRepoBranch(repoID RepoID, branch string) (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.repo_pulls WHERE repo_id = $1 AND branch = $2 AND repo_id IN ("+liveRepoIDs+")", repoID, branch)
+}
+
 // GetRepoPullByID returns the RepoPull with the given ID,
 // or nil and an error if not found.
-func (db *DB) GetRepoPullByID(id uint32) (*RepoPull, error) {
-	var rp RepoPull
-	err := db.sqldb.QueryRow("SELECT id, repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id FROM peridot.repo_pulls WHERE id = $1", id).
-		Scan(&rp.ID, &rp.RepoID, &rp.Branch, &rp.StartedAt, &rp.FinishedAt, &rp.Status, &rp.Health, &rp.Output, &rp.Commit, &rp.Tag, &rp.SPDXID)
+func (db *DB) GetRepoPullByID(id RepoPullID) (*RepoPull, error) {
+	rp, err := scanRepoPull(db.sqldb.QueryRow("SELECT "+repoPullColumns+" FROM peridot.repo_pulls WHERE id = $1 AND repo_id IN ("+liveRepoIDs+")", id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no repo pull found with ID %v", id)
-	}
-	if err != nil {
-		return nil, err
+		return nil, &NotFoundError{Entity: "repo pull", ID: fmt.Sprint(id)}
 	}
+	return rp, err
+}
 
-	return &rp, nil
+// ExistsRepoPull reports whether a RepoPull with the given ID
+// exists, without retrieving it.
+func (db *DB) ExistsRepoPull(id RepoPullID) (bool, error) {
+	return db.exists("SELECT EXISTS (SELECT 1 FROM peridot.repo_pulls WHERE id = $1 AND repo_id IN ("+liveRepoIDs+"))", id)
 }
 
 // AddRepoPull adds a new repo pull as specified,
@@ -90,7 +254,7 @@ func (db *DB) GetRepoPullByID(id uint32) (*RepoPull, error) {
 // filling in nil start/finish times and output, and
 // default startup status / health. It returns the new
 // repo pull's ID on success or an error if failing.
-func (db *DB) AddRepoPull(repoID uint32, branch string, commit string, tag string, spdxID string) (uint32, error) {
+func (db *DB) AddRepoPull(repoID RepoID, branch string, commit string, tag string, spdxID string) (RepoPullID, error) {
 	return db.AddFullRepoPull(repoID, branch, time.Time{}, time.Time{}, StatusStartup, HealthOK, "", commit, tag, spdxID)
 }
 
@@ -98,51 +262,103 @@ func (db *DB) AddRepoPull(repoID uint32, branch string, commit string, tag strin
 // data, referencing the designated Repo, branch and other
 // data. It returns the new repo pull's ID on success or an
 // error if failing.
-func (db *DB) AddFullRepoPull(repoID uint32, branch string, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string, commit string, tag string, spdxID string) (uint32, error) {
+func (db *DB) AddFullRepoPull(repoID RepoID, branch string, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string, commit string, tag string, spdxID string) (RepoPullID, error) {
+	startedAt = normalizeTime(startedAt)
+	finishedAt = normalizeTime(finishedAt)
+	rp := &RepoPull{RepoID: repoID, Branch: branch, StartedAt: startedAt, FinishedAt: finishedAt, Status: status, Health: health, Output: output, Commit: commit, Tag: tag, SPDXID: spdxID}
+	if err := rp.Validate(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+
 	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("INSERT INTO peridot.repo_pulls(repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id")
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.repo_pulls(repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id")
 	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
 
-	var rpID uint32
-	err = stmt.QueryRow(repoID, branch, startedAt, finishedAt, status, health, output, commit, tag, spdxID).Scan(&rpID)
+	err = stmt.QueryRow(repoID, branch, startedAt, finishedAt, status, health, output, commit, tag, spdxID).Scan(&rp.ID)
+	if err != nil {
+		tx.Rollback()
+		return 0, translateConstraintError("repo pull", err)
+	}
+
+	err = addOutboxEvent(tx, "repo_pull", rp.ID, AuditActionAdd, rp)
 	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
-	return rpID, nil
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return rp.ID, nil
 }
 
-// DeleteRepoPull deletes an existing RepoPull with the
-// given ID. It returns nil on success or an error if
-// failing.
-func (db *DB) DeleteRepoPull(id uint32) error {
-	var err error
-	var result sql.Result
+// UpdateRepoPullStatus sets the status variables for the
+// RepoPull with the given ID. It returns a *TransitionError
+// if the repo pull's current status cannot move to status,
+// e.g. from stopped back to running, nil on success or
+// another error if failing.
+func (db *DB) UpdateRepoPullStatus(id RepoPullID, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string) error {
+	if err := validateStatusHealth("repo pull", status, health); err != nil {
+		return err
+	}
 
-	// FIXME consider whether need to delete sub-elements first, or
-	// FIXME whether to set up sub-elements' schemas to delete on cascade
+	startedAt = normalizeTime(startedAt)
+	finishedAt = normalizeTime(finishedAt)
 
-	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("DELETE FROM peridot.repo_pulls WHERE id = $1")
+	tx, err := db.begin()
 	if err != nil {
 		return err
 	}
-	result, err = stmt.Exec(id)
 
-	// check error
+	// FIXME consider whether to move out into one-time-prepared statement
+	stmt, err := db.prepareIn(tx, "UPDATE peridot.repo_pulls SET started_at = $1, finished_at = $2, status = $3, health = $4, output = $5 WHERE id = $6 AND COALESCE(status, 0) = ANY($7)")
 	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	result, err := stmt.Exec(startedAt, finishedAt, status, health, output, id, statusPredecessors(status))
+	if err != nil {
+		tx.Rollback()
 		return err
 	}
 
-	// check that something was actually deleted
+	// check that something was actually updated
 	rows, err := result.RowsAffected()
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 	if rows == 0 {
-		return fmt.Errorf("no repo pull found with ID %v", id)
+		tx.Rollback()
+		return db.statusUpdateError("repo_pulls", "repo pull", id, 0, status)
 	}
 
-	return nil
+	payload := map[string]interface{}{"id": id, "started_at": startedAt, "finished_at": finishedAt, "status": status, "health": health, "output": output}
+	err = addOutboxEvent(tx, "repo_pull", id, AuditActionUpdate, payload)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteRepoPull deletes an existing RepoPull with the
+// given ID. It returns nil on success or an error if
+// failing.
+func (db *DB) DeleteRepoPull(id RepoPullID) error {
+	// FIXME consider whether need to delete sub-elements first, or
+	// FIXME whether to set up sub-elements' schemas to delete on cascade
+
+	// FIXME consider whether to move out into one-time-prepared statement
+	return db.execWithOutboxEvent("repo_pull", id, AuditActionDelete, map[string]interface{}{"id": id},
+		"DELETE FROM peridot.repo_pulls WHERE id = $1 AND repo_id IN ("+liveRepoIDs+")", id)
 }
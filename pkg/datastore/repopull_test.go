@@ -4,6 +4,7 @@ package datastore
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -20,8 +21,8 @@ func TestShouldGetAllRepoPullsForOneRepoBranch(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sa11 := time.Date(2019, 5, 2, 13, 53, 41, 671764, time.UTC)
-	fa11 := time.Date(2019, 5, 2, 13, 54, 17, 386417, time.UTC)
+	sa11 := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	fa11 := time.Date(2019, 5, 2, 13, 54, 17, 386417000, time.UTC)
 	st11 := StatusStopped
 	h11 := HealthOK
 
@@ -43,11 +44,11 @@ func TestShouldGetAllRepoPullsForOneRepoBranch(t *testing.T) {
 	spdxID15 := "SPDXRef-xyzzy-15"
 	spdxID16 := "SPDXRef-xyzzy-16"
 
-	sentRows := sqlmock.NewRows([]string{"id", "repo_id", "branch", "started_at", "finished_at", "status", "health", "output", "commit", "tag", "spdx_id"}).
-		AddRow(11, 3, "dev-1.1", sa11, fa11, st11, h11, "output message 11", c11, "", spdxID11).
-		AddRow(15, 3, "dev-1.1", sa15, fa15, st15, h15, "output message 15", c15, "v1.1-rc0", spdxID15).
-		AddRow(16, 3, "dev-1.1", sa16, fa16, st16, h16, "output message 16", c16, "v1.1-rc1", spdxID16)
-	mock.ExpectQuery(`SELECT id, repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id FROM peridot.repo_pulls WHERE repo_id = \$1 AND branch = \$2 ORDER BY id`).
+	sentRows := sqlmock.NewRows([]string{"id", "repo_id", "branch", "started_at", "finished_at", "status", "health", "output", "commit", "tag", "spdx_id", "created_at", "updated_at"}).
+		AddRow(11, 3, "dev-1.1", sa11, fa11, st11, h11, "output message 11", c11, "", spdxID11, testCreatedAt, testUpdatedAt).
+		AddRow(15, 3, "dev-1.1", sa15, fa15, st15, h15, "output message 15", c15, "v1.1-rc0", spdxID15, testCreatedAt, testUpdatedAt).
+		AddRow(16, 3, "dev-1.1", sa16, fa16, st16, h16, "output message 16", c16, "v1.1-rc1", spdxID16, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id, created_at, updated_at FROM peridot.repo_pulls WHERE repo_id = \$1 AND branch = \$2 AND repo_id IN \(` + liveRepoIDsRegex + `\) ORDER BY id`).
 		WillReturnRows(sentRows)
 
 	// run the tested function
@@ -152,9 +153,9 @@ func TestShouldGetRepoPullByID(t *testing.T) {
 	c15 := "4567890123456789012345678901234567890123"
 	spdxID15 := "SPDXRef-xyzzy-15"
 
-	sentRows := sqlmock.NewRows([]string{"id", "repo_id", "branch", "started_at", "finished_at", "status", "health", "output", "commit", "tag", "spdx_id"}).
-		AddRow(15, 3, "dev-1.1", sa15, fa15, st15, h15, "output message 15", c15, "v1.1-rc0", spdxID15)
-	mock.ExpectQuery(`[SELECT id, repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id FROM peridot.repo_pulls WHERE id = \$1]`).
+	sentRows := sqlmock.NewRows([]string{"id", "repo_id", "branch", "started_at", "finished_at", "status", "health", "output", "commit", "tag", "spdx_id", "created_at", "updated_at"}).
+		AddRow(15, 3, "dev-1.1", sa15, fa15, st15, h15, "output message 15", c15, "v1.1-rc0", spdxID15, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`[SELECT id, repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id, created_at, updated_at FROM peridot.repo_pulls WHERE id = \$1]`).
 		WithArgs(15).
 		WillReturnRows(sentRows)
 
@@ -206,6 +207,40 @@ func TestShouldGetRepoPullByID(t *testing.T) {
 	}
 }
 
+func TestShouldGetRepoPullByIDWithNullTextColumns(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sa15 := time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "repo_id", "branch", "started_at", "finished_at", "status", "health", "output", "commit", "tag", "spdx_id", "created_at", "updated_at"}).
+		AddRow(15, 3, "dev-1.1", sa15, sa15, StatusStopped, HealthOK, nil, nil, nil, nil, testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id, created_at, updated_at FROM peridot.repo_pulls WHERE id = \$1`).
+		WithArgs(15).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	rp, err := db.GetRepoPullByID(15)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if rp.Output != "" || rp.Commit != "" || rp.Tag != "" || rp.SPDXID != "" {
+		t.Errorf("expected empty strings for NULL columns, got %q, %q, %q, %q", rp.Output, rp.Commit, rp.Tag, rp.SPDXID)
+	}
+}
+
 func TestShouldFailGetRepoPullByIDForUnknownID(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
@@ -215,7 +250,7 @@ func TestShouldFailGetRepoPullByIDForUnknownID(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	mock.ExpectQuery(`[SELECT id, repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id FROM peridot.repo_pulls WHERE id = \$1]`).
+	mock.ExpectQuery(`[SELECT id, repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id, created_at, updated_at FROM peridot.repo_pulls WHERE id = \$1]`).
 		WithArgs(413).
 		WillReturnRows(sqlmock.NewRows([]string{}))
 
@@ -250,11 +285,15 @@ func TestShouldAddRepoPull(t *testing.T) {
 	spdxID15 := "SPDXRef-xyzzy-15"
 
 	regexStmt := `[INSERT INTO peridot.repo_pulls(repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id) VALUES (\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "INSERT INTO peridot.repo_pulls"
 	mock.ExpectQuery(stmt).
 		WithArgs(15, "master", time.Time{}, time.Time{}, StatusStartup, HealthOK, "", c15, "v1.15-rc0", spdxID15).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(36))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	rpID, err := db.AddRepoPull(15, "master", c15, "v1.15-rc0", spdxID15)
@@ -287,11 +326,13 @@ func TestShouldFailAddRepoPullWithUnknownRepoBranch(t *testing.T) {
 	spdxID0 := "SPDXRef-oops"
 
 	regexStmt := `[INSERT INTO peridot.repo_pulls(repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id) VALUES (\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "INSERT INTO peridot.repo_pulls"
 	mock.ExpectQuery(stmt).
 		WithArgs(413, "unknown-branch", time.Time{}, time.Time{}, StatusStartup, HealthOK, "", c0, "", spdxID0).
 		WillReturnError(fmt.Errorf("pq: insert or update on table \"peridot.repo_pulls\" violates foreign key constraint \"peridot.repo_pulls_repo_id_fkey\""))
+	mock.ExpectRollback()
 
 	// run the tested function
 	_, err = db.AddRepoPull(413, "unknown-branch", c0, "", spdxID0)
@@ -316,10 +357,12 @@ func TestShouldAddFullRepoPull(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	// adding full means all values are given
-	repoID := uint32(15)
+	repoID := RepoID(15)
 	branch := "master"
 	sa := time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC)
-	fa := time.Date(2019, 5, 4, 12, 0, 1, 30, time.UTC)
+	// finished time is stored in UTC at microsecond precision
+	fa := time.Date(2019, 5, 4, 8, 0, 1, 30030, time.FixedZone("EDT", -4*60*60))
+	storedFa := time.Date(2019, 5, 4, 12, 0, 1, 30000, time.UTC)
 	status := StatusStopped
 	health := HealthOK
 	output := "pull complete"
@@ -328,11 +371,15 @@ func TestShouldAddFullRepoPull(t *testing.T) {
 	spdxID := "SPDXRef-xyzzy-15"
 
 	regexStmt := `[INSERT INTO peridot.repo_pulls(repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id) VALUES (\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "INSERT INTO peridot.repo_pulls"
 	mock.ExpectQuery(stmt).
-		WithArgs(repoID, branch, sa, fa, status, health, output, commit, tag, spdxID).
+		WithArgs(repoID, branch, sa, storedFa, status, health, output, commit, tag, spdxID).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(36))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	rpID, err := db.AddFullRepoPull(repoID, branch, sa, fa, status, health, output, commit, tag, spdxID)
@@ -361,10 +408,10 @@ func TestShouldFailAddFullRepoPullWithUnknownRepoBranch(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	repoID := uint32(413)
+	repoID := RepoID(413)
 	branch := "unknown-branch"
 	sa := time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC)
-	fa := time.Date(2019, 5, 4, 12, 0, 1, 30, time.UTC)
+	fa := time.Date(2019, 5, 4, 12, 0, 1, 30000, time.UTC)
 	status := StatusStopped
 	health := HealthOK
 	output := "pull complete"
@@ -373,11 +420,13 @@ func TestShouldFailAddFullRepoPullWithUnknownRepoBranch(t *testing.T) {
 	spdxID := "SPDXRef-oops"
 
 	regexStmt := `[INSERT INTO peridot.repo_pulls(repo_id, branch, started_at, finished_at, status, health, output, commit, tag, spdx_id) VALUES (\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "INSERT INTO peridot.repo_pulls"
 	mock.ExpectQuery(stmt).
 		WithArgs(repoID, branch, sa, fa, status, health, output, commit, tag, spdxID).
 		WillReturnError(fmt.Errorf("pq: insert or update on table \"peridot.repo_pulls\" violates foreign key constraint \"peridot.repo_pulls_repo_id_fkey\""))
+	mock.ExpectRollback()
 
 	// run the tested function
 	_, err = db.AddFullRepoPull(repoID, branch, sa, fa, status, health, output, commit, tag, spdxID)
@@ -392,6 +441,104 @@ func TestShouldFailAddFullRepoPullWithUnknownRepoBranch(t *testing.T) {
 	}
 }
 
+func TestShouldUpdateRepoPullStatus(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	start := time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC)
+	finish := time.Date(2019, 5, 4, 12, 0, 1, 0, time.UTC)
+
+	regexStmt := `UPDATE peridot.repo_pulls SET started_at = \$1, finished_at = \$2, status = \$3, health = \$4, output = \$5 WHERE id = \$6 AND COALESCE\(status, 0\) = ANY\(\$7\)`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(start, finish, StatusStopped, HealthOK, "", 5, "{0,1,2,3}").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.UpdateRepoPullStatus(5, start, finish, StatusStopped, HealthOK, "")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailUpdateRepoPullStatusWithInvalidTransition(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE peridot.repo_pulls")
+	mock.ExpectExec("UPDATE peridot.repo_pulls").
+		WithArgs(time.Time{}, time.Time{}, StatusRunning, HealthOK, "", 5, "{0,1,2,4}").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT COALESCE\(status, 0\) FROM peridot.repo_pulls WHERE id = \$1`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(5))
+
+	// run the tested function, trying to restart a cancelled pull
+	err = db.UpdateRepoPullStatus(5, time.Time{}, time.Time{}, StatusRunning, HealthOK, "")
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected *TransitionError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailUpdateRepoPullStatusWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE peridot.repo_pulls")
+	mock.ExpectExec("UPDATE peridot.repo_pulls").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT COALESCE\(status, 0\) FROM peridot.repo_pulls WHERE id = \$1`).
+		WithArgs(413).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}))
+
+	// run the tested function
+	err = db.UpdateRepoPullStatus(413, time.Time{}, time.Time{}, StatusRunning, HealthOK, "")
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestShouldDeleteRepoPull(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
@@ -402,11 +549,15 @@ func TestShouldDeleteRepoPull(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[DELETE FROM peridot.repo_pulls WHERE id = \$1]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "DELETE FROM peridot.repo_pulls"
 	mock.ExpectExec(stmt).
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.DeleteRepoPull(1)
@@ -431,11 +582,13 @@ func TestShouldFailDeleteRepoPullWithUnknownID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[DELETE FROM peridot.repo_pulls WHERE id = \$1]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "DELETE FROM peridot.repo_pulls"
 	mock.ExpectExec(stmt).
 		WithArgs(413).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	// run the tested function
 	err = db.DeleteRepoPull(413)
@@ -572,3 +725,178 @@ func TestCannotUnmarshalRepoPullWithNegativeIDFromJSON(t *testing.T) {
 		t.Fatalf("expected non-nil error, got nil")
 	}
 }
+
+func TestRepoPullValidate(t *testing.T) {
+	sha1 := "4567890123456789012345678901234567890abc"
+	sha256 := "32b91a0bee702768018a1cb0df2d144c6b2ce806e504067216f44ab0fb839051"
+	tests := []struct {
+		rp      RepoPull
+		isError bool
+	}{
+		{RepoPull{Branch: "master", Commit: sha1, Status: StatusStartup, Health: HealthOK}, false},
+		{RepoPull{Branch: "master", Commit: sha256, Status: StatusRunning, Health: HealthOK}, false},
+		{RepoPull{Branch: "master", Commit: "", Status: StatusQueued, Health: HealthOK}, false},
+		// invalid values should fail
+		{RepoPull{Branch: "", Commit: sha1, Status: StatusStartup, Health: HealthOK}, true},
+		{RepoPull{Branch: "master", Commit: "abc123", Status: StatusStartup, Health: HealthOK}, true},
+		{RepoPull{Branch: "master", Commit: "4567890123456789012345678901234567890ABC", Status: StatusStartup, Health: HealthOK}, true},
+		{RepoPull{Branch: "master", Commit: sha1, Status: Status(57), Health: HealthOK}, true},
+		{RepoPull{Branch: "master", Commit: sha1, Status: StatusStartup, Health: Health(57)}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.rp.Validate()
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("for %+v: expected isError %v, got %v", tt.rp, tt.isError, err)
+		}
+		if err != nil {
+			if _, ok := err.(*ValidationError); !ok {
+				t.Errorf("expected *ValidationError, got %T", err)
+			}
+		}
+	}
+}
+
+func TestShouldFailAddRepoPullWithInvalidCommit(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function; no queries should be made
+	_, err = db.AddRepoPull(15, "master", "not-a-commit", "", "SPDXRef-oops")
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRepoPullJSONRoundTripsFinishedPull(t *testing.T) {
+	startedAt := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	finishedAt := time.Date(2019, 5, 2, 13, 54, 2, 0, time.UTC)
+	rp := RepoPull{ID: 36, RepoID: 15, Branch: "master", StartedAt: startedAt, FinishedAt: finishedAt, Status: StatusStopped, Health: HealthOK}
+
+	js, err := json.Marshal(rp)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+
+	rp2 := &RepoPull{}
+	err = json.Unmarshal(js, rp2)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if !rp2.StartedAt.Equal(startedAt) {
+		t.Errorf("expected %v, got %v", startedAt, rp2.StartedAt)
+	}
+	if !rp2.FinishedAt.Equal(finishedAt) {
+		t.Errorf("expected %v, got %v", finishedAt, rp2.FinishedAt)
+	}
+}
+
+func TestRepoPullJSONLeavesOutTimesForQueuedPull(t *testing.T) {
+	rp := RepoPull{ID: 36, RepoID: 15, Branch: "master", Status: StatusQueued, Health: HealthOK}
+
+	js, err := json.Marshal(rp)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+
+	var mGot map[string]interface{}
+	err = json.Unmarshal(js, &mGot)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	for _, k := range []string{"started_at", "finished_at", "created_at", "updated_at"} {
+		if _, ok := mGot[k]; ok {
+			t.Errorf("expected no %s key, got %v", k, mGot[k])
+		}
+	}
+}
+
+func TestRepoPullEqual(t *testing.T) {
+	sa := time.Date(2019, 5, 4, 12, 0, 0, 0, time.UTC)
+	base := &RepoPull{ID: 36, RepoID: 15, Branch: "master", StartedAt: sa, Status: StatusStopped, Health: HealthOK, Commit: "4567890123456789012345678901234567890123"}
+
+	same := base.Clone()
+	same.StartedAt = sa.Add(999 * time.Nanosecond)
+	if !base.Equal(same) {
+		t.Errorf("expected %#v to equal %#v", base, same)
+	}
+
+	other := base.Clone()
+	other.Tag = "v1.15"
+	if base.Equal(other) {
+		t.Errorf("expected %#v not to equal %#v", base, other)
+	}
+}
+
+func TestShouldCountRepoPullsForRepoBranch(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM peridot.repo_pulls WHERE repo_id = \$1 AND branch = \$2`).
+		WithArgs(3, "dev-1.1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(14))
+
+	// run the tested function
+	n, err := db.CountRepoPullsForRepoBranch(3, "dev-1.1")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if n != 14 {
+		t.Errorf("expected %v, got %v", 14, n)
+	}
+}
+
+func TestShouldReportRepoPullExists(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM peridot.repo_pulls WHERE id = \$1 AND repo_id IN \(` + liveRepoIDsRegex + `\)\)`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	// run the tested function
+	ok, err := db.ExistsRepoPull(5)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if ok != true {
+		t.Errorf("expected %v, got %v", true, ok)
+	}
+}
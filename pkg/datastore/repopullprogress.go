@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// RepoPullProgress summarizes how far the Jobs for a RepoPull have
+// got, for showing a progress bar without retrieving every Job.
+type RepoPullProgress struct {
+	// RepoPullID is the ID of the repo pull.
+	RepoPullID RepoPullID `json:"repopull_id"`
+	// Total is the number of jobs for the repo pull.
+	Total int `json:"total"`
+	// Completed is the number of jobs that have stopped or been
+	// cancelled, whether or not they succeeded.
+	Completed int `json:"completed"`
+	// Failed is the number of completed jobs whose health is
+	// HealthError.
+	Failed int `json:"failed"`
+	// Status is the overall status of the jobs: StatusStopped if
+	// every job has completed, StatusRunning if any job has
+	// started, and StatusQueued otherwise, including when there
+	// are no jobs yet.
+	Status Status `json:"status"`
+}
+
+// GetRepoPullProgress returns the RepoPullProgress for the RepoPull
+// with the given ID, counting its jobs within the database. It
+// returns a *NotFoundError if there is no such repo pull.
+func (db *DB) GetRepoPullProgress(rpID RepoPullID) (*RepoPullProgress, error) {
+	var progress RepoPullProgress
+	err := db.sqldb.QueryRow(`
+		SELECT rp.id,
+			COUNT(j.id),
+			COUNT(j.id) FILTER (WHERE j.status IN ($2, $3)),
+			COUNT(j.id) FILTER (WHERE j.status IN ($2, $3) AND j.health = $4),
+			CASE
+				WHEN COUNT(j.id) > 0 AND COUNT(j.id) FILTER (WHERE j.status IN ($2, $3)) = COUNT(j.id) THEN $2
+				WHEN COUNT(j.id) FILTER (WHERE j.status IN ($2, $3, $5, $6)) > 0 THEN $5
+				ELSE $7
+			END
+		FROM peridot.repo_pulls rp
+		LEFT JOIN peridot.jobs j ON j.repopull_id = rp.id
+		WHERE rp.id = $1 AND rp.repo_id IN (`+liveRepoIDs+`)
+		GROUP BY rp.id`,
+		rpID, StatusStopped, StatusCancelled, HealthError, StatusRunning, StatusStartup, StatusQueued).
+		Scan(&progress.RepoPullID, &progress.Total, &progress.Completed, &progress.Failed, &progress.Status)
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "repo pull", ID: fmt.Sprint(rpID)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
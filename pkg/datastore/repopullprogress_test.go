@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetRepoPullProgress(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "total", "completed", "failed", "status"}).
+		AddRow(12, 5, 3, 1, 2)
+	mock.ExpectQuery(`SELECT rp.id, .* FROM peridot.repo_pulls rp LEFT JOIN peridot.jobs j ON j.repopull_id = rp.id WHERE rp.id = \$1 AND rp.repo_id IN \(`+liveRepoIDsRegex+`\) GROUP BY rp.id`).
+		WithArgs(12, StatusStopped, StatusCancelled, HealthError, StatusRunning, StatusStartup, StatusQueued).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	progress, err := db.GetRepoPullProgress(12)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := RepoPullProgress{RepoPullID: 12, Total: 5, Completed: 3, Failed: 1, Status: StatusRunning}
+	if *progress != want {
+		t.Errorf("expected %+v, got %+v", want, *progress)
+	}
+}
+
+func TestShouldFailGetRepoPullProgressForUnknownRepoPull(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT rp.id, .* FROM peridot.repo_pulls rp`).
+		WithArgs(413, StatusStopped, StatusCancelled, HealthError, StatusRunning, StatusStartup, StatusQueued).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "total", "completed", "failed", "status"}))
+
+	// run the tested function
+	progress, err := db.GetRepoPullProgress(413)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if progress != nil {
+		t.Errorf("expected nil progress, got %+v", progress)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
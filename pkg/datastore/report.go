@@ -0,0 +1,238 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Report describes a request by a User for peridot to generate a
+// report export, such as a license summary for a project. Reports
+// are generated asynchronously, and track their progress with the
+// same Status and Health values as a Job.
+type Report struct {
+	// ID is the unique ID for this report.
+	ID uint32 `json:"id"`
+	// Type is the kind of report requested, e.g.
+	// "license_summary". Its meaning is up to the report
+	// generators.
+	Type string `json:"type"`
+	// Parameters is a JSON object of type-specific settings for
+	// the report, such as the project or repo pull to cover.
+	Parameters json.RawMessage `json:"parameters"`
+	// RequestedBy is the ID of the user who requested the report,
+	// or 0 if that user has since been deleted.
+	RequestedBy UserID `json:"requested_by,omitempty"`
+	// RequestedAt is when the report was requested.
+	RequestedAt time.Time `json:"requested_at"`
+	// StartedAt is when generation of the report began. Should be
+	// zero value if it has not yet started.
+	StartedAt time.Time `json:"started_at,omitempty"`
+	// FinishedAt is when generation of the report finished. Should
+	// be zero value if it has not yet completed.
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+	// Status is the run status of the report's generation.
+	Status Status `json:"status"`
+	// Health is the health of the report's generation.
+	Health Health `json:"health"`
+	// Output is any output or error messages from generating the
+	// report.
+	Output string `json:"output,omitempty"`
+	// ArtifactURI is where the generated report can be fetched
+	// from. Should be empty until generation has succeeded.
+	ArtifactURI string `json:"artifact_uri,omitempty"`
+}
+
+// MarshalJSON converts the Report into a slice of bytes containing its
+// JSON encoding, leaving out started_at and finished_at until the
+// report has started and finished.
+func (r Report) MarshalJSON() ([]byte, error) {
+	type reportAlias Report
+	return json.Marshal(struct {
+		reportAlias
+		StartedAt  *time.Time `json:"started_at,omitempty"`
+		FinishedAt *time.Time `json:"finished_at,omitempty"`
+	}{reportAlias: reportAlias(r), StartedAt: jsonTimePtr(r.StartedAt), FinishedAt: jsonTimePtr(r.FinishedAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of a Report into the Report. A missing or null started_at or
+// finished_at means the report has not yet started or finished.
+func (r *Report) UnmarshalJSON(b []byte) error {
+	type reportAlias Report
+	aux := struct {
+		*reportAlias
+		StartedAt  *time.Time `json:"started_at"`
+		FinishedAt *time.Time `json:"finished_at"`
+	}{reportAlias: (*reportAlias)(r)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	r.StartedAt = timeFromJSONPtr(aux.StartedAt)
+	r.FinishedAt = timeFromJSONPtr(aux.FinishedAt)
+	return nil
+}
+
+// Validate checks that the Report's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (r *Report) Validate() error {
+	if err := requireNonEmpty("report", "type", r.Type); err != nil {
+		return err
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal(r.Parameters, &params); err != nil || params == nil {
+		return &ValidationError{Entity: "report", Field: "parameters", Reason: "must be a JSON object"}
+	}
+	return validateStatusHealth("report", r.Status, r.Health)
+}
+
+const reportColumns = "id, type, parameters, requested_by, requested_at, started_at, finished_at, status, health, output, artifact_uri"
+
+// scanReport reads a Report from a row selecting reportColumns.
+func scanReport(rs rowScanner) (*Report, error) {
+	r := &Report{}
+	var params []byte
+	var requestedBy sql.NullInt64
+	var startedAt, finishedAt pq.NullTime
+	err := rs.Scan(&r.ID, &r.Type, &params, &requestedBy, &r.RequestedAt, &startedAt, &finishedAt, &r.Status, &r.Health, &r.Output, &r.ArtifactURI)
+	if err != nil {
+		return nil, err
+	}
+	r.Parameters = json.RawMessage(params)
+	r.RequestedBy = UserID(requestedBy.Int64)
+	r.RequestedAt = normalizeTime(r.RequestedAt)
+	if startedAt.Valid {
+		r.StartedAt = normalizeTime(startedAt.Time)
+	}
+	if finishedAt.Valid {
+		r.FinishedAt = normalizeTime(finishedAt.Time)
+	}
+	return r, nil
+}
+
+// queryReports runs a query selecting reportColumns and returns the
+// resulting reports.
+func (db *DB) queryReports(query string, args ...interface{}) ([]*Report, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []*Report{}
+	for rows.Next() {
+		r, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// GetReportsForUser returns a slice of all reports requested by the
+// User with the given ID, ordered from newest to oldest.
+func (db *DB) GetReportsForUser(userID UserID) ([]*Report, error) {
+	return db.queryReports("SELECT "+reportColumns+" FROM peridot.reports WHERE requested_by = $1 ORDER BY requested_at DESC, id DESC", userID)
+}
+
+// GetPendingReports returns up to n reports that are waiting to be
+// generated, i.e. that are StatusStartup or StatusQueued with
+// HealthOK, ordered from oldest to newest. If n is 0 then all
+// pending reports are returned.
+func (db *DB) GetPendingReports(n uint32) ([]*Report, error) {
+	return db.queryReports("SELECT "+reportColumns+" FROM peridot.reports WHERE status IN ($1, $2) AND health = $3 ORDER BY id LIMIT NULLIF($4, 0)", StatusStartup, StatusQueued, HealthOK, n)
+}
+
+// GetReportByID returns the Report with the given ID, or nil and an
+// error if not found.
+func (db *DB) GetReportByID(id uint32) (*Report, error) {
+	r, err := scanReport(db.sqldb.QueryRow("SELECT "+reportColumns+" FROM peridot.reports WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "report", ID: fmt.Sprint(id)}
+	}
+	return r, err
+}
+
+// AddReport adds a new report request of the given type and
+// parameters by the User with ID requestedBy, with default startup
+// status and OK health. It returns the new report's ID on success
+// or an error if failing.
+func (db *DB) AddReport(reportType string, parameters json.RawMessage, requestedBy UserID) (uint32, error) {
+	r := &Report{Type: reportType, Parameters: parameters, RequestedBy: requestedBy, Status: StatusStartup, Health: HealthOK}
+	if err := r.Validate(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.reports(type, parameters, requested_by, requested_at, status, health, output, artifact_uri) VALUES ($1, $2, $3, $4, $5, $6, '', '') RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var reportID uint32
+	err = stmt.QueryRow(reportType, []byte(parameters), sql.NullInt64{Int64: int64(requestedBy), Valid: requestedBy != 0}, now(), StatusStartup, HealthOK).Scan(&reportID)
+	if err != nil {
+		return 0, err
+	}
+	return reportID, nil
+}
+
+// UpdateReportStatus sets the status variables for the Report with
+// the given ID, including the URI of its generated artifact, if
+// any. It returns nil on success or an error if failing.
+func (db *DB) UpdateReportStatus(id uint32, startedAt time.Time, finishedAt time.Time, status Status, health Health, output string, artifactURI string) error {
+	if err := validateStatusHealth("report", status, health); err != nil {
+		return err
+	}
+
+	result, err := db.sqldb.Exec("UPDATE peridot.reports SET started_at = $1, finished_at = $2, status = $3, health = $4, output = $5, artifact_uri = $6 WHERE id = $7",
+		nullTimeFromTime(normalizeTime(startedAt)), nullTimeFromTime(normalizeTime(finishedAt)), status, health, output, artifactURI, id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "report", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// DeleteReport deletes the Report with the given ID. It does not
+// delete its generated artifact. It returns nil on success or an
+// error if failing.
+func (db *DB) DeleteReport(id uint32) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.reports WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "report", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
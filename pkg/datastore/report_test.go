@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetPendingReports(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	requestedAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "type", "parameters", "requested_by", "requested_at", "started_at", "finished_at", "status", "health", "output", "artifact_uri"}).
+		AddRow(7, "license_summary", []byte(`{"project_id":2}`), 10, requestedAt, nil, nil, StatusStartup, HealthOK, "", "").
+		AddRow(8, "license_summary", []byte(`{"project_id":3}`), nil, requestedAt, nil, nil, StatusQueued, HealthOK, "", "")
+	mock.ExpectQuery(`SELECT id, type, parameters, requested_by, requested_at, started_at, finished_at, status, health, output, artifact_uri FROM peridot.reports WHERE status IN \(\$1, \$2\) AND health = \$3 ORDER BY id LIMIT NULLIF\(\$4, 0\)`).
+		WithArgs(StatusStartup, StatusQueued, HealthOK, 5).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetPendingReports(5)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantRows := []*Report{
+		{ID: 7, Type: "license_summary", Parameters: json.RawMessage(`{"project_id":2}`), RequestedBy: 10, RequestedAt: requestedAt, Status: StatusStartup, Health: HealthOK},
+		{ID: 8, Type: "license_summary", Parameters: json.RawMessage(`{"project_id":3}`), RequestedAt: requestedAt, Status: StatusQueued, Health: HealthOK},
+	}
+	if !reflect.DeepEqual(wantRows, gotRows) {
+		t.Errorf("expected %#v, got %#v", wantRows, gotRows)
+	}
+}
+
+func TestShouldAddReport(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.reports\(type, parameters, requested_by, requested_at, status, health, output, artifact_uri\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, '', ''\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs("license_summary", []byte(`{"project_id":2}`), 10, sqlmock.AnyArg(), StatusStartup, HealthOK).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	// run the tested function
+	id, err := db.AddReport("license_summary", json.RawMessage(`{"project_id":2}`), 10)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 7 {
+		t.Errorf("expected %v, got %v", 7, id)
+	}
+}
+
+func TestShouldFailAddReportWithNonObjectParameters(t *testing.T) {
+	_, err := (&DB{}).AddReport("license_summary", json.RawMessage(`[2]`), 10)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if verr.Field != "parameters" {
+		t.Errorf("expected field %v, got %v", "parameters", verr.Field)
+	}
+}
+
+func TestShouldUpdateReportStatus(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	startedAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	mock.ExpectExec(`UPDATE peridot.reports SET started_at = \$1, finished_at = \$2, status = \$3, health = \$4, output = \$5, artifact_uri = \$6 WHERE id = \$7`).
+		WithArgs(startedAt, nilArg{}, StatusRunning, HealthOK, "generating", "", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.UpdateReportStatus(7, startedAt, time.Time{}, StatusRunning, HealthOK, "generating", "")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDeleteReportWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`DELETE FROM peridot.reports WHERE id = \$1`).
+		WithArgs(413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.DeleteReport(413)
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Kinds of data that a retention policy can govern.
+const (
+	// RetentionScopeRepoPulls means the policy governs repo pulls,
+	// counted per repo branch.
+	RetentionScopeRepoPulls = "repo_pulls"
+	// RetentionScopeJobLogs means the policy governs job output,
+	// counted per agent. Jobs themselves are kept; only their output
+	// is cleared.
+	RetentionScopeJobLogs = "job_logs"
+	// RetentionScopeAuditEntries means the policy governs audit
+	// entries, counted per audited entity.
+	RetentionScopeAuditEntries = "audit_entries"
+)
+
+// retentionBatchSize is the maximum number of rows removed by each
+// statement issued by ApplyRetentionPolicies, so that a large
+// backlog of expired data does not hold locks for too long.
+const retentionBatchSize = 1000
+
+// RetentionPolicy describes how long data of a given kind is kept
+// before ApplyRetentionPolicies removes it. A row is removed only if
+// it is not among the KeepLatestN most recent rows in its group, and
+// it finished more than MaxAge ago. A zero value for either field
+// means that condition does not protect any rows.
+type RetentionPolicy struct {
+	// ID is the unique ID for this policy.
+	ID uint32 `json:"id"`
+	// Scope is the kind of data governed, e.g.
+	// RetentionScopeRepoPulls. There is at most one policy per scope.
+	Scope string `json:"scope"`
+	// KeepLatestN is the number of most recent rows in each group
+	// that are always kept, regardless of age.
+	KeepLatestN uint32 `json:"keep_latest_n"`
+	// MaxAge is how long rows are kept after they finish, regardless
+	// of how many newer rows exist.
+	MaxAge time.Duration `json:"max_age"`
+}
+
+// Validate checks that the RetentionPolicy's fields are well-formed.
+// It returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (rp *RetentionPolicy) Validate() error {
+	switch rp.Scope {
+	case RetentionScopeRepoPulls, RetentionScopeJobLogs, RetentionScopeAuditEntries:
+	default:
+		return &ValidationError{Entity: "retention policy", Field: "scope", Reason: fmt.Sprintf("unknown scope %q", rp.Scope)}
+	}
+	if rp.MaxAge < 0 {
+		return &ValidationError{Entity: "retention policy", Field: "max_age", Reason: "must not be negative"}
+	}
+	if rp.KeepLatestN == 0 && rp.MaxAge < time.Second {
+		return &ValidationError{Entity: "retention policy", Field: "max_age", Reason: "must be at least one second if keep_latest_n is zero"}
+	}
+	return nil
+}
+
+const retentionPolicyColumns = "id, scope, keep_latest_n, max_age_seconds"
+
+// scanRetentionPolicy reads a RetentionPolicy from a row selecting
+// retentionPolicyColumns.
+func scanRetentionPolicy(rs rowScanner) (*RetentionPolicy, error) {
+	rp := &RetentionPolicy{}
+	var maxAgeSeconds int64
+	err := rs.Scan(&rp.ID, &rp.Scope, &rp.KeepLatestN, &maxAgeSeconds)
+	if err != nil {
+		return nil, err
+	}
+	rp.MaxAge = time.Duration(maxAgeSeconds) * time.Second
+	return rp, nil
+}
+
+// GetRetentionPolicies returns a slice of all retention policies,
+// ordered by scope.
+func (db *DB) GetRetentionPolicies() ([]*RetentionPolicy, error) {
+	rows, err := db.sqldb.Query("SELECT " + retentionPolicyColumns + " FROM peridot.retention_policies ORDER BY scope")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []*RetentionPolicy{}
+	for rows.Next() {
+		rp, err := scanRetentionPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, rp)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// GetRetentionPolicy returns the RetentionPolicy for the given
+// scope, or nil and an error if not found.
+func (db *DB) GetRetentionPolicy(scope string) (*RetentionPolicy, error) {
+	rp, err := scanRetentionPolicy(db.sqldb.QueryRow("SELECT "+retentionPolicyColumns+" FROM peridot.retention_policies WHERE scope = $1", scope))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "retention policy", ID: scope}
+	}
+	return rp, err
+}
+
+// SetRetentionPolicy creates or replaces the RetentionPolicy for the
+// given scope. MaxAge is stored with a resolution of one second. It
+// returns nil on success or an error if failing.
+func (db *DB) SetRetentionPolicy(scope string, keepLatestN uint32, maxAge time.Duration) error {
+	rp := &RetentionPolicy{Scope: scope, KeepLatestN: keepLatestN, MaxAge: maxAge}
+	if err := rp.Validate(); err != nil {
+		return err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.retention_policies(scope, keep_latest_n, max_age_seconds) VALUES ($1, $2, $3) ON CONFLICT (scope) DO UPDATE SET keep_latest_n = EXCLUDED.keep_latest_n, max_age_seconds = EXCLUDED.max_age_seconds")
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(scope, keepLatestN, int64(maxAge/time.Second))
+	return err
+}
+
+// DeleteRetentionPolicy deletes the RetentionPolicy for the given
+// scope, so that data in that scope is kept indefinitely. It returns
+// nil on success or an error if failing.
+func (db *DB) DeleteRetentionPolicy(scope string) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.retention_policies WHERE scope = $1", scope)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "retention policy", ID: scope}
+	}
+
+	return nil
+}
+
+// retentionStatement is the statement that removes one batch of
+// expired rows in a retention scope. Each statement takes
+// keep_latest_n, the age cutoff and the batch size as arguments. If
+// finishedOnly is set, it also takes StatusStopped and
+// StatusCancelled, and only removes rows with one of those statuses:
+// rows that have not finished record a zero finished_at, which is
+// always before the cutoff.
+type retentionStatement struct {
+	query        string
+	finishedOnly bool
+}
+
+// retentionStatements maps each scope to its retentionStatement.
+// Repo pulls and jobs that have not finished are never removed.
+var retentionStatements = map[string]retentionStatement{
+	RetentionScopeRepoPulls: {`
+		DELETE FROM peridot.repo_pulls WHERE id IN (
+			SELECT id FROM (
+				SELECT id, finished_at, status, row_number() OVER (PARTITION BY repo_id, branch ORDER BY id DESC) AS rn
+				FROM peridot.repo_pulls
+			) ranked
+			WHERE rn > $1 AND finished_at < $2 AND status IN ($4, $5)
+			LIMIT $3
+		)`, true},
+	RetentionScopeJobLogs: {`
+		UPDATE peridot.jobs SET output = '' WHERE id IN (
+			SELECT id FROM (
+				SELECT id, finished_at, status, output, row_number() OVER (PARTITION BY agent_id ORDER BY id DESC) AS rn
+				FROM peridot.jobs
+			) ranked
+			WHERE rn > $1 AND finished_at < $2 AND status IN ($4, $5) AND output <> ''
+			LIMIT $3
+		)`, true},
+	RetentionScopeAuditEntries: {`
+		DELETE FROM peridot.audit_log WHERE id IN (
+			SELECT id FROM (
+				SELECT id, created_at, row_number() OVER (PARTITION BY entity, entity_id ORDER BY id DESC) AS rn
+				FROM peridot.audit_log
+			) ranked
+			WHERE rn > $1 AND created_at < $2
+			LIMIT $3
+		)`, false},
+}
+
+// ApplyRetentionPolicies removes all data that has expired under the
+// current retention policies, in batches of at most
+// retentionBatchSize rows per statement. It returns a map from scope
+// to the number of rows removed (or, for job logs, cleared) on
+// success, or the counts so far and an error if failing.
+//
+// Repo pulls removed here do not produce outbox events, and their
+// jobs, file instances and other dependent rows are removed with
+// them by cascade.
+func (db *DB) ApplyRetentionPolicies() (map[string]int64, error) {
+	removed := map[string]int64{}
+
+	policies, err := db.GetRetentionPolicies()
+	if err != nil {
+		return removed, err
+	}
+
+	t := now()
+	for _, rp := range policies {
+		stmt, ok := retentionStatements[rp.Scope]
+		if !ok {
+			continue
+		}
+		cutoff := t
+		if rp.MaxAge > 0 {
+			cutoff = t.Add(-rp.MaxAge)
+		}
+		args := []interface{}{rp.KeepLatestN, cutoff, retentionBatchSize}
+		if stmt.finishedOnly {
+			args = append(args, StatusStopped, StatusCancelled)
+		}
+
+		for {
+			result, err := db.sqldb.Exec(stmt.query, args...)
+			if err != nil {
+				return removed, err
+			}
+			rows, err := result.RowsAffected()
+			if err != nil {
+				return removed, err
+			}
+			removed[rp.Scope] += rows
+			if rows < retentionBatchSize {
+				break
+			}
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,210 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetRetentionPolicies(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "scope", "keep_latest_n", "max_age_seconds"}).
+		AddRow(2, "audit_entries", 0, 31536000).
+		AddRow(1, "repo_pulls", 5, 7776000)
+	mock.ExpectQuery(`SELECT id, scope, keep_latest_n, max_age_seconds FROM peridot.retention_policies ORDER BY scope`).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetRetentionPolicies()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantRows := []*RetentionPolicy{
+		{ID: 2, Scope: RetentionScopeAuditEntries, MaxAge: 365 * 24 * time.Hour},
+		{ID: 1, Scope: RetentionScopeRepoPulls, KeepLatestN: 5, MaxAge: 90 * 24 * time.Hour},
+	}
+	if !reflect.DeepEqual(wantRows, gotRows) {
+		t.Errorf("expected %#v, got %#v", wantRows, gotRows)
+	}
+}
+
+func TestShouldSetRetentionPolicy(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.retention_policies\(scope, keep_latest_n, max_age_seconds\) VALUES \(\$1, \$2, \$3\) ON CONFLICT \(scope\) DO UPDATE SET keep_latest_n = EXCLUDED.keep_latest_n, max_age_seconds = EXCLUDED.max_age_seconds`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs("job_logs", 10, 2592000).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.SetRetentionPolicy(RetentionScopeJobLogs, 10, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailSetInvalidRetentionPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		scope       string
+		keepLatestN uint32
+		maxAge      time.Duration
+		wantField   string
+	}{
+		{"unknown scope", "findings", 5, 0, "scope"},
+		{"negative max age", RetentionScopeRepoPulls, 5, -time.Hour, "max_age"},
+		{"keeps nothing", RetentionScopeAuditEntries, 0, 0, "max_age"},
+	}
+	for _, tc := range tests {
+		err := (&DB{}).SetRetentionPolicy(tc.scope, tc.keepLatestN, tc.maxAge)
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%s: expected *ValidationError, got %v", tc.name, err)
+			continue
+		}
+		if verr.Field != tc.wantField {
+			t.Errorf("%s: expected field %v, got %v", tc.name, tc.wantField, verr.Field)
+		}
+	}
+}
+
+func TestShouldFailDeleteRetentionPolicyWithUnknownScope(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`DELETE FROM peridot.retention_policies WHERE scope = \$1`).
+		WithArgs("job_logs").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.DeleteRetentionPolicy(RetentionScopeJobLogs)
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldApplyRetentionPoliciesInBatches(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "scope", "keep_latest_n", "max_age_seconds"}).
+		AddRow(2, "audit_entries", 0, 31536000).
+		AddRow(3, "job_logs", 10, 0)
+	mock.ExpectQuery(`SELECT id, scope, keep_latest_n, max_age_seconds FROM peridot.retention_policies ORDER BY scope`).
+		WillReturnRows(sentRows)
+	mock.ExpectExec(`DELETE FROM peridot.audit_log WHERE id IN`).
+		WithArgs(0, sqlmock.AnyArg(), retentionBatchSize).
+		WillReturnResult(sqlmock.NewResult(0, retentionBatchSize))
+	mock.ExpectExec(`DELETE FROM peridot.audit_log WHERE id IN`).
+		WithArgs(0, sqlmock.AnyArg(), retentionBatchSize).
+		WillReturnResult(sqlmock.NewResult(0, 17))
+	mock.ExpectExec(`UPDATE peridot.jobs SET output = '' WHERE id IN`).
+		WithArgs(10, sqlmock.AnyArg(), retentionBatchSize, StatusStopped, StatusCancelled).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	// run the tested function
+	removed, err := db.ApplyRetentionPolicies()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantRemoved := map[string]int64{
+		RetentionScopeAuditEntries: retentionBatchSize + 17,
+		RetentionScopeJobLogs:      4,
+	}
+	if !reflect.DeepEqual(wantRemoved, removed) {
+		t.Errorf("expected %v, got %v", wantRemoved, removed)
+	}
+}
+
+func TestShouldKeepUnfinishedRepoPullsWhenApplyingRetentionPolicies(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// the running pull has a zero finished_at, which is before any
+	// cutoff, so only its status keeps it from being removed
+	sentRows := sqlmock.NewRows([]string{"id", "scope", "keep_latest_n", "max_age_seconds"}).
+		AddRow(1, "repo_pulls", 0, 86400)
+	mock.ExpectQuery(`SELECT id, scope, keep_latest_n, max_age_seconds FROM peridot.retention_policies ORDER BY scope`).
+		WillReturnRows(sentRows)
+	mock.ExpectExec(`DELETE FROM peridot.repo_pulls WHERE id IN \(.+WHERE rn > \$1 AND finished_at < \$2 AND status IN \(\$4, \$5\)`).
+		WithArgs(0, sqlmock.AnyArg(), retentionBatchSize, StatusStopped, StatusCancelled).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	removed, err := db.ApplyRetentionPolicies()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if removed[RetentionScopeRepoPulls] != 0 {
+		t.Errorf("expected %v, got %v", 0, removed[RetentionScopeRepoPulls])
+	}
+}
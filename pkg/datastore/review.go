@@ -0,0 +1,210 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Review describes a request for a User to sign off on a RepoPull,
+// such as before a release, and the reviewer's decision.
+type Review struct {
+	// ID is the unique ID for this review.
+	ID uint32 `json:"id"`
+	// RepoPullID is the ID of the repo pull being reviewed.
+	RepoPullID RepoPullID `json:"repopull_id"`
+	// ReviewerID is the ID of the user asked to review, or 0 if
+	// that user has since been deleted.
+	ReviewerID UserID `json:"reviewer_id,omitempty"`
+	// State is the state of the review.
+	State ReviewState `json:"state"`
+	// Notes is the reviewer's explanation of their decision, if
+	// any.
+	Notes string `json:"notes,omitempty"`
+	// RequestedAt is when the review was requested.
+	RequestedAt time.Time `json:"requested_at"`
+	// DecidedAt is when the review was approved or rejected.
+	// Should be zero value if it is still requested.
+	DecidedAt time.Time `json:"decided_at,omitempty"`
+}
+
+// MarshalJSON converts the Review into a slice of bytes containing its
+// JSON encoding, leaving out decided_at while the review is still
+// requested.
+func (r Review) MarshalJSON() ([]byte, error) {
+	type reviewAlias Review
+	return json.Marshal(struct {
+		reviewAlias
+		DecidedAt *time.Time `json:"decided_at,omitempty"`
+	}{reviewAlias: reviewAlias(r), DecidedAt: jsonTimePtr(r.DecidedAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of a Review into the Review. A missing or null decided_at means the
+// review has not been decided.
+func (r *Review) UnmarshalJSON(b []byte) error {
+	type reviewAlias Review
+	aux := struct {
+		*reviewAlias
+		DecidedAt *time.Time `json:"decided_at"`
+	}{reviewAlias: (*reviewAlias)(r)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	r.DecidedAt = timeFromJSONPtr(aux.DecidedAt)
+	return nil
+}
+
+// canTransitionReview reports whether a Review may move from one
+// state to another. A requested review may be approved or rejected;
+// a decided review is final.
+func canTransitionReview(from ReviewState, to ReviewState) bool {
+	return from == ReviewStateRequested && (to == ReviewStateApproved || to == ReviewStateRejected)
+}
+
+const reviewColumns = "id, repopull_id, reviewer_id, state, notes, requested_at, decided_at"
+
+// scanReview reads a Review from a row selecting reviewColumns.
+func scanReview(rs rowScanner) (*Review, error) {
+	r := &Review{}
+	var reviewerID sql.NullInt64
+	var stateInt int
+	var decidedAt pq.NullTime
+	err := rs.Scan(&r.ID, &r.RepoPullID, &reviewerID, &stateInt, &r.Notes, &r.RequestedAt, &decidedAt)
+	if err != nil {
+		return nil, err
+	}
+	r.State, err = ReviewStateFromInt(stateInt)
+	if err != nil {
+		return nil, err
+	}
+	r.ReviewerID = UserID(reviewerID.Int64)
+	r.RequestedAt = normalizeTime(r.RequestedAt)
+	if decidedAt.Valid {
+		r.DecidedAt = normalizeTime(decidedAt.Time)
+	}
+	return r, nil
+}
+
+// queryReviews runs a query selecting reviewColumns and returns the
+// resulting reviews.
+func (db *DB) queryReviews(query string, args ...interface{}) ([]*Review, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []*Review{}
+	for rows.Next() {
+		r, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// GetReviewsForRepoPull returns a slice of all reviews of the
+// RepoPull with the given ID, ordered by ID.
+func (db *DB) GetReviewsForRepoPull(repoPullID RepoPullID) ([]*Review, error) {
+	return db.queryReviews("SELECT "+reviewColumns+" FROM peridot.reviews WHERE repopull_id = $1 ORDER BY id", repoPullID)
+}
+
+// GetRequestedReviewsForReviewer returns a slice of all reviews
+// that the User with the given ID has been asked for and has not yet
+// decided, from oldest to newest.
+func (db *DB) GetRequestedReviewsForReviewer(reviewerID UserID) ([]*Review, error) {
+	return db.queryReviews("SELECT "+reviewColumns+" FROM peridot.reviews WHERE reviewer_id = $1 AND state = $2 ORDER BY requested_at, id", reviewerID, IntFromReviewState(ReviewStateRequested))
+}
+
+// GetReviewByID returns the Review with the given ID, or nil and an
+// error if not found.
+func (db *DB) GetReviewByID(id uint32) (*Review, error) {
+	r, err := scanReview(db.sqldb.QueryRow("SELECT "+reviewColumns+" FROM peridot.reviews WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "review", ID: fmt.Sprint(id)}
+	}
+	return r, err
+}
+
+// RequestReview asks the User with ID reviewerID to review the
+// RepoPull with ID repoPullID. A reviewer can have only one
+// undecided review of a given repo pull at a time. It returns the
+// new review's ID on success or an error if failing.
+func (db *DB) RequestReview(repoPullID RepoPullID, reviewerID UserID) (uint32, error) {
+	if reviewerID == 0 {
+		return 0, &ValidationError{Entity: "review", Field: "reviewer_id", Reason: "must not be zero"}
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.reviews(repopull_id, reviewer_id, state, notes, requested_at) VALUES ($1, $2, $3, '', $4) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var reviewID uint32
+	err = stmt.QueryRow(repoPullID, reviewerID, IntFromReviewState(ReviewStateRequested), now()).Scan(&reviewID)
+	if err != nil {
+		return 0, err
+	}
+	return reviewID, nil
+}
+
+// DecideReview records the reviewer's decision on the Review with
+// the given ID, moving it to the given state, which must be
+// ReviewStateApproved or ReviewStateRejected, with the given notes.
+// Only a requested review can be decided; deciding any other review
+// returns a *ValidationError. It returns nil on success or an error
+// if failing.
+func (db *DB) DecideReview(id uint32, state ReviewState, notes string) error {
+	if state != ReviewStateApproved && state != ReviewStateRejected {
+		return &ValidationError{Entity: "review", Field: "state", Reason: fmt.Sprintf("cannot decide a review as %s", state)}
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+
+	// lock the review so that concurrent decisions are serialized
+	var stateInt int
+	err = tx.QueryRow("SELECT state FROM peridot.reviews WHERE id = $1 FOR UPDATE", id).Scan(&stateInt)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return &NotFoundError{Entity: "review", ID: fmt.Sprint(id)}
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	current, err := ReviewStateFromInt(stateInt)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if !canTransitionReview(current, state) {
+		tx.Rollback()
+		return &ValidationError{Entity: "review", Field: "state", Reason: fmt.Sprintf("cannot change from %s to %s", current, state)}
+	}
+
+	_, err = tx.Exec("UPDATE peridot.reviews SET state = $1, notes = $2, decided_at = $3 WHERE id = $4", IntFromReviewState(state), notes, now(), id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
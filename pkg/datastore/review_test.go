@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetReviewsForRepoPull(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	requestedAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	decidedAt := time.Date(2019, 5, 3, 9, 12, 5, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "repopull_id", "reviewer_id", "state", "notes", "requested_at", "decided_at"}).
+		AddRow(1, 36, 10, 20, "LGTM", requestedAt, decidedAt).
+		AddRow(2, 36, nil, 10, "", requestedAt, nil)
+	mock.ExpectQuery(`SELECT id, repopull_id, reviewer_id, state, notes, requested_at, decided_at FROM peridot.reviews WHERE repopull_id = \$1 ORDER BY id`).
+		WithArgs(36).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetReviewsForRepoPull(36)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	wantRows := []*Review{
+		{ID: 1, RepoPullID: 36, ReviewerID: 10, State: ReviewStateApproved, Notes: "LGTM", RequestedAt: requestedAt, DecidedAt: decidedAt},
+		{ID: 2, RepoPullID: 36, State: ReviewStateRequested, RequestedAt: requestedAt},
+	}
+	if !reflect.DeepEqual(wantRows, gotRows) {
+		t.Errorf("expected %#v, got %#v", wantRows, gotRows)
+	}
+}
+
+func TestShouldRequestReview(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.reviews\(repopull_id, reviewer_id, state, notes, requested_at\) VALUES \(\$1, \$2, \$3, '', \$4\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(36, 10, 10, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	// run the tested function
+	id, err := db.RequestReview(36, 10)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 3 {
+		t.Errorf("expected %v, got %v", 3, id)
+	}
+}
+
+func TestShouldDecideRequestedReview(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT state FROM peridot.reviews WHERE id = \$1 FOR UPDATE`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(10))
+	mock.ExpectExec(`UPDATE peridot.reviews SET state = \$1, notes = \$2, decided_at = \$3 WHERE id = \$4`).
+		WithArgs(30, "GPL-3.0 file in vendor/", sqlmock.AnyArg(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.DecideReview(3, ReviewStateRejected, "GPL-3.0 file in vendor/")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDecideAlreadyDecidedReview(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT state FROM peridot.reviews WHERE id = \$1 FOR UPDATE`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(20))
+	mock.ExpectRollback()
+
+	// run the tested function
+	err = db.DecideReview(1, ReviewStateRejected, "changed my mind")
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if verr.Field != "state" {
+		t.Errorf("expected field %v, got %v", "state", verr.Field)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDecideReviewWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT state FROM peridot.reviews WHERE id = \$1 FOR UPDATE`).
+		WithArgs(413).
+		WillReturnRows(sqlmock.NewRows([]string{"state"}))
+	mock.ExpectRollback()
+
+	// run the tested function
+	err = db.DecideReview(413, ReviewStateApproved, "")
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDecideReviewAsRequested(t *testing.T) {
+	err := (&DB{}).DecideReview(3, ReviewStateRequested, "")
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if verr.Field != "state" {
+		t.Errorf("expected field %v, got %v", "state", verr.Field)
+	}
+}
@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ReviewState defines the states of a Review of a RepoPull. A
+// review starts out requested, and may then be approved or rejected
+// once.
+type ReviewState int
+
+const (
+	// ReviewStateUnknown is a zero value which indicates that the
+	// review state is not currently known.
+	ReviewStateUnknown ReviewState = 0
+
+	// ReviewStateRequested means that the reviewer has been asked
+	// to review the RepoPull and has not yet decided.
+	ReviewStateRequested ReviewState = 10
+
+	// ReviewStateApproved means that the reviewer signed off on
+	// the RepoPull.
+	ReviewStateApproved ReviewState = 20
+
+	// ReviewStateRejected means that the reviewer declined to sign
+	// off on the RepoPull.
+	ReviewStateRejected ReviewState = 30
+)
+
+// ReviewStateFromInt converts an integer to its corresponding
+// ReviewState value. It returns that value or an error if the
+// integer is invalid.
+func ReviewStateFromInt(stateInt int) (ReviewState, error) {
+	switch stateInt {
+	case 0:
+		return ReviewStateUnknown, nil
+	case 10:
+		return ReviewStateRequested, nil
+	case 20:
+		return ReviewStateApproved, nil
+	case 30:
+		return ReviewStateRejected, nil
+	}
+
+	return ReviewStateUnknown, fmt.Errorf("invalid review state integer %d", stateInt)
+}
+
+// IntFromReviewState converts a ReviewState value to its
+// corresponding integer value.
+func IntFromReviewState(state ReviewState) int {
+	switch state {
+	case ReviewStateUnknown:
+		return 0
+	case ReviewStateRequested:
+		return 10
+	case ReviewStateApproved:
+		return 20
+	case ReviewStateRejected:
+		return 30
+	}
+
+	return 0
+}
+
+// ReviewStateFromString converts a string to its corresponding
+// ReviewState value. It returns that value or an error if the
+// string is invalid.
+func ReviewStateFromString(stateStr string) (ReviewState, error) {
+	switch stateStr {
+	case "unknown":
+		return ReviewStateUnknown, nil
+	case "requested":
+		return ReviewStateRequested, nil
+	case "approved":
+		return ReviewStateApproved, nil
+	case "rejected":
+		return ReviewStateRejected, nil
+	}
+
+	return ReviewStateUnknown, fmt.Errorf("invalid review state string %s", stateStr)
+}
+
+// StringFromReviewState converts a ReviewState value to its
+// corresponding string value.
+func StringFromReviewState(state ReviewState) string {
+	switch state {
+	case ReviewStateUnknown:
+		return "unknown"
+	case ReviewStateRequested:
+		return "requested"
+	case ReviewStateApproved:
+		return "approved"
+	case ReviewStateRejected:
+		return "rejected"
+	}
+
+	return "unknown"
+}
+
+// String implements fmt.Stringer, returning the string encoding of
+// the ReviewState value.
+func (state ReviewState) String() string {
+	return StringFromReviewState(state)
+}
+
+// MarshalJSON converts the ReviewState value into a slice of bytes
+// containing its string encoding.
+func (state ReviewState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(StringFromReviewState(state))
+}
+
+// UnmarshalJSON converts a slice of bytes containing the string
+// encoding of a review state into the corresponding ReviewState
+// value.
+func (state *ReviewState) UnmarshalJSON(b []byte) error {
+	var s string
+
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	stateVal, err := ReviewStateFromString(s)
+	if err != nil {
+		return err
+	}
+
+	*state = stateVal
+	return nil
+}
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCanConvertReviewStates(t *testing.T) {
+	tests := []struct {
+		state ReviewState
+		i     int
+		s     string
+	}{
+		{ReviewStateUnknown, 0, "unknown"},
+		{ReviewStateRequested, 10, "requested"},
+		{ReviewStateApproved, 20, "approved"},
+		{ReviewStateRejected, 30, "rejected"},
+	}
+
+	for _, tt := range tests {
+		if got := IntFromReviewState(tt.state); got != tt.i {
+			t.Errorf("expected %v, got %v", tt.i, got)
+		}
+		if got := StringFromReviewState(tt.state); got != tt.s {
+			t.Errorf("expected %v, got %v", tt.s, got)
+		}
+		if got, err := ReviewStateFromInt(tt.i); err != nil || got != tt.state {
+			t.Errorf("expected %v, nil; got %v, %v", tt.state, got, err)
+		}
+		if got, err := ReviewStateFromString(tt.s); err != nil || got != tt.state {
+			t.Errorf("expected %v, nil; got %v, %v", tt.state, got, err)
+		}
+	}
+}
+
+func TestCannotConvertInvalidReviewStates(t *testing.T) {
+	if _, err := ReviewStateFromInt(15); err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+	if _, err := ReviewStateFromString("Approved"); err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+}
+
+func TestCanMarshalReviewStateToJSON(t *testing.T) {
+	js, err := json.Marshal(ReviewStateApproved)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if string(js) != `"approved"` {
+		t.Errorf("expected %v, got %v", `"approved"`, string(js))
+	}
+
+	var state ReviewState
+	err = json.Unmarshal(js, &state)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if state != ReviewStateApproved {
+		t.Errorf("expected %v, got %v", ReviewStateApproved, state)
+	}
+}
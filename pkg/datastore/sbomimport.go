@@ -0,0 +1,295 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Formats accepted in SBOMImport.Format.
+const (
+	SBOMFormatSPDX      = "spdx"
+	SBOMFormatCycloneDX = "cyclonedx"
+)
+
+// Validation statuses of an SBOMImport.
+const (
+	// SBOMValidationPending means the SBOM has not yet been
+	// validated.
+	SBOMValidationPending = "pending"
+	// SBOMValidationValid means the SBOM was validated
+	// successfully.
+	SBOMValidationValid = "valid"
+	// SBOMValidationInvalid means the SBOM failed validation; see
+	// ValidationMessage.
+	SBOMValidationInvalid = "invalid"
+)
+
+// SBOMImport describes an externally supplied SBOM, such as an SPDX
+// or CycloneDX document, that a Job imported for a RepoPull. The
+// document itself is kept in external storage; peridot records
+// where to find it, whether it was valid, and which entities were
+// created or matched from its elements.
+type SBOMImport struct {
+	// ID is the unique ID for this import.
+	ID uint32 `json:"id"`
+	// RepoPullID is the ID of the RepoPull the SBOM describes.
+	RepoPullID RepoPullID `json:"repopull_id"`
+	// JobID is the ID of the Job that imported the SBOM.
+	JobID JobID `json:"job_id"`
+	// Format is one of the SBOMFormat values.
+	Format string `json:"format"`
+	// FormatVersion is the version of the format's specification
+	// that the SBOM declares, e.g. "SPDX-2.2" or "1.4".
+	FormatVersion string `json:"format_version"`
+	// URI is where the SBOM is stored, e.g. an s3:// URI.
+	URI string `json:"uri"`
+	// ValidationStatus is one of the SBOMValidation values.
+	ValidationStatus string `json:"validation_status"`
+	// ValidationMessage explains why validation failed, or is ""
+	// if it has not.
+	ValidationMessage string `json:"validation_message,omitempty"`
+	// ImportedAt is when the import was recorded.
+	ImportedAt time.Time `json:"imported_at"`
+}
+
+// Validate checks that the SBOMImport's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (si *SBOMImport) Validate() error {
+	switch si.Format {
+	case SBOMFormatSPDX, SBOMFormatCycloneDX:
+	default:
+		return &ValidationError{Entity: "sbom import", Field: "format", Reason: "must be spdx or cyclonedx"}
+	}
+	if err := requireNonEmpty("sbom import", "format_version", si.FormatVersion); err != nil {
+		return err
+	}
+	if err := requireNonEmpty("sbom import", "uri", si.URI); err != nil {
+		return err
+	}
+	return validateSBOMValidationStatus(si.ValidationStatus)
+}
+
+// validateSBOMValidationStatus returns a *ValidationError if status
+// is not one of the SBOMValidation values.
+func validateSBOMValidationStatus(status string) error {
+	switch status {
+	case SBOMValidationPending, SBOMValidationValid, SBOMValidationInvalid:
+		return nil
+	}
+	return &ValidationError{Entity: "sbom import", Field: "validation_status", Reason: "must be pending, valid or invalid"}
+}
+
+// SBOMImportLink records that an element of an imported SBOM, such as
+// an SPDX package or file, corresponds to an existing Component or
+// FileInstance.
+type SBOMImportLink struct {
+	// SBOMImportID is the ID of the SBOMImport containing the
+	// element.
+	SBOMImportID uint32 `json:"sbom_import_id"`
+	// ElementID identifies the element within the SBOM, e.g. its
+	// SPDX identifier or CycloneDX bom-ref.
+	ElementID string `json:"element_id"`
+	// ComponentID is the ID of the corresponding Component, or 0 if
+	// the element corresponds to a file.
+	ComponentID uint64 `json:"component_id,omitempty"`
+	// FileInstanceID is the ID of the corresponding FileInstance,
+	// or 0 if the element corresponds to a component.
+	FileInstanceID uint64 `json:"fileinstance_id,omitempty"`
+}
+
+// Validate checks that the SBOMImportLink's fields are well-formed.
+// It returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (sil *SBOMImportLink) Validate() error {
+	if err := requireNonEmpty("sbom import link", "element_id", sil.ElementID); err != nil {
+		return err
+	}
+	if (sil.ComponentID == 0) == (sil.FileInstanceID == 0) {
+		return &ValidationError{Entity: "sbom import link", Field: "component_id", Reason: "exactly one of component_id and fileinstance_id must be set"}
+	}
+	return nil
+}
+
+const sbomImportColumns = "id, repopull_id, job_id, format, format_version, uri, validation_status, validation_message, imported_at"
+
+// scanSBOMImport reads an SBOMImport from a row selecting
+// sbomImportColumns.
+func scanSBOMImport(rs rowScanner) (*SBOMImport, error) {
+	si := &SBOMImport{}
+	err := rs.Scan(&si.ID, &si.RepoPullID, &si.JobID, &si.Format, &si.FormatVersion, &si.URI, &si.ValidationStatus, &si.ValidationMessage, &si.ImportedAt)
+	if err != nil {
+		return nil, err
+	}
+	si.ImportedAt = normalizeTime(si.ImportedAt)
+	return si, nil
+}
+
+// GetSBOMImportsForRepoPull returns a slice of all SBOMs imported for
+// the RepoPull with the given ID, ordered by ID.
+func (db *DB) GetSBOMImportsForRepoPull(rpID RepoPullID) ([]*SBOMImport, error) {
+	rows, err := db.sqldb.Query("SELECT "+sbomImportColumns+" FROM peridot.sbom_imports WHERE repopull_id = $1 ORDER BY id", rpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imports := []*SBOMImport{}
+	for rows.Next() {
+		si, err := scanSBOMImport(rows)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, si)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
+
+// GetSBOMImportByID returns the SBOMImport with the given ID, or nil
+// and an error if not found.
+func (db *DB) GetSBOMImportByID(id uint32) (*SBOMImport, error) {
+	si, err := scanSBOMImport(db.sqldb.QueryRow("SELECT "+sbomImportColumns+" FROM peridot.sbom_imports WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "sbom import", ID: fmt.Sprint(id)}
+	}
+	return si, err
+}
+
+// AddSBOMImport records that the Job with the given ID imported the
+// SBOM of the given format and version, stored at uri, for the
+// RepoPull with the given ID. The import starts with validation
+// status SBOMValidationPending. It returns the new import's ID on
+// success or an error if failing.
+func (db *DB) AddSBOMImport(rpID RepoPullID, jobID JobID, format string, formatVersion string, uri string) (uint32, error) {
+	si := &SBOMImport{RepoPullID: rpID, JobID: jobID, Format: format, FormatVersion: formatVersion, URI: uri, ValidationStatus: SBOMValidationPending}
+	if err := si.Validate(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.sbom_imports(repopull_id, job_id, format, format_version, uri, validation_status, validation_message, imported_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var importID uint32
+	err = stmt.QueryRow(rpID, jobID, format, formatVersion, uri, SBOMValidationPending, "", now()).Scan(&importID)
+	if err != nil {
+		return 0, err
+	}
+	return importID, nil
+}
+
+// UpdateSBOMImportValidation sets the validation status of the
+// existing SBOMImport with the given ID, with a message explaining
+// any failure. It returns nil on success or an error if failing.
+func (db *DB) UpdateSBOMImportValidation(id uint32, status string, message string) error {
+	if err := validateSBOMValidationStatus(status); err != nil {
+		return err
+	}
+
+	result, err := db.sqldb.Exec("UPDATE peridot.sbom_imports SET validation_status = $1, validation_message = $2 WHERE id = $3", status, message, id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "sbom import", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// DeleteSBOMImport deletes the record of the existing SBOMImport
+// with the given ID, along with its links. The linked entities and
+// the stored SBOM itself are not affected. It returns nil on success
+// or an error if failing.
+func (db *DB) DeleteSBOMImport(id uint32) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.sbom_imports WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "sbom import", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
+
+// GetSBOMImportLinks returns a slice of all links from elements of
+// the SBOMImport with the given ID, ordered by element ID.
+func (db *DB) GetSBOMImportLinks(id uint32) ([]*SBOMImportLink, error) {
+	rows, err := db.sqldb.Query("SELECT sbom_import_id, element_id, component_id, fileinstance_id FROM peridot.sbom_import_links WHERE sbom_import_id = $1 ORDER BY element_id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*SBOMImportLink{}
+	for rows.Next() {
+		sil := &SBOMImportLink{}
+		var componentID, fileInstanceID sql.NullInt64
+		err := rows.Scan(&sil.SBOMImportID, &sil.ElementID, &componentID, &fileInstanceID)
+		if err != nil {
+			return nil, err
+		}
+		sil.ComponentID = uint64(componentID.Int64)
+		sil.FileInstanceID = uint64(fileInstanceID.Int64)
+		links = append(links, sil)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// AddSBOMImportLinks adds the given links in a single transaction,
+// so that either all or none of them are added. A link for an
+// element that is already linked replaces the earlier one. It
+// returns nil on success or an error if failing.
+func (db *DB) AddSBOMImportLinks(links []*SBOMImportLink) error {
+	for _, sil := range links {
+		if err := sil.Validate(); err != nil {
+			return err
+		}
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.sbom_import_links(sbom_import_id, element_id, component_id, fileinstance_id) VALUES ($1, $2, $3, $4) ON CONFLICT (sbom_import_id, element_id) DO UPDATE SET component_id = EXCLUDED.component_id, fileinstance_id = EXCLUDED.fileinstance_id")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, sil := range links {
+		_, err = stmt.Exec(sil.SBOMImportID, sil.ElementID, sql.NullInt64{Int64: int64(sil.ComponentID), Valid: sil.ComponentID != 0}, sql.NullInt64{Int64: int64(sil.FileInstanceID), Valid: sil.FileInstanceID != 0})
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetSBOMImportsForRepoPull(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	importedAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "repopull_id", "job_id", "format", "format_version", "uri", "validation_status", "validation_message", "imported_at"}).
+		AddRow(1, 36, 12, "spdx", "SPDX-2.2", "s3://sboms/36/upstream.spdx.json", "valid", "", importedAt).
+		AddRow(2, 36, 13, "cyclonedx", "1.4", "s3://sboms/36/vendor.cdx.json", "invalid", "missing bom-ref", importedAt)
+	mock.ExpectQuery(`SELECT id, repopull_id, job_id, format, format_version, uri, validation_status, validation_message, imported_at FROM peridot.sbom_imports WHERE repopull_id = \$1 ORDER BY id`).
+		WithArgs(36).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetSBOMImportsForRepoPull(36)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*SBOMImport{
+		{ID: 1, RepoPullID: 36, JobID: 12, Format: SBOMFormatSPDX, FormatVersion: "SPDX-2.2", URI: "s3://sboms/36/upstream.spdx.json", ValidationStatus: SBOMValidationValid, ImportedAt: importedAt},
+		{ID: 2, RepoPullID: 36, JobID: 13, Format: SBOMFormatCycloneDX, FormatVersion: "1.4", URI: "s3://sboms/36/vendor.cdx.json", ValidationStatus: SBOMValidationInvalid, ValidationMessage: "missing bom-ref", ImportedAt: importedAt},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldAddSBOMImportAsPending(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.sbom_imports\(repopull_id, job_id, format, format_version, uri, validation_status, validation_message, imported_at\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(36, 12, "spdx", "SPDX-2.2", "s3://sboms/36/upstream.spdx.json", "pending", "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	// run the tested function
+	id, err := db.AddSBOMImport(36, 12, SBOMFormatSPDX, "SPDX-2.2", "s3://sboms/36/upstream.spdx.json")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 3 {
+		t.Errorf("expected %v, got %v", 3, id)
+	}
+}
+
+func TestShouldFailAddSBOMImportWithInvalidFields(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        string
+		formatVersion string
+		uri           string
+		field         string
+	}{
+		{"unknown format", "swid", "1.0", "s3://x", "format"},
+		{"no version", SBOMFormatSPDX, "", "s3://x", "format_version"},
+		{"no uri", SBOMFormatCycloneDX, "1.4", "", "uri"},
+	}
+	for _, tc := range tests {
+		_, err := (&DB{}).AddSBOMImport(36, 12, tc.format, tc.formatVersion, tc.uri)
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%s: expected *ValidationError, got %v", tc.name, err)
+			continue
+		}
+		if verr.Field != tc.field {
+			t.Errorf("%s: expected field %v, got %v", tc.name, tc.field, verr.Field)
+		}
+	}
+}
+
+func TestShouldFailUpdateSBOMImportValidationWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`UPDATE peridot.sbom_imports SET validation_status = \$1, validation_message = \$2 WHERE id = \$3`).
+		WithArgs("valid", "", 413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.UpdateSBOMImportValidation(413, SBOMValidationValid, "")
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected *NotFoundError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldAddSBOMImportLinks(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.sbom_import_links\(sbom_import_id, element_id, component_id, fileinstance_id\) VALUES \(\$1, \$2, \$3, \$4\) ON CONFLICT \(sbom_import_id, element_id\) DO UPDATE SET`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(1, "SPDXRef-Package-zlib", 88, nilArg{}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexStmt).
+		WithArgs(1, "SPDXRef-File-inflate.c", nilArg{}, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.AddSBOMImportLinks([]*SBOMImportLink{
+		{SBOMImportID: 1, ElementID: "SPDXRef-Package-zlib", ComponentID: 88},
+		{SBOMImportID: 1, ElementID: "SPDXRef-File-inflate.c", FileInstanceID: 7},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailAddSBOMImportLinksWithoutSingleTarget(t *testing.T) {
+	err := (&DB{}).AddSBOMImportLinks([]*SBOMImportLink{
+		{SBOMImportID: 1, ElementID: "SPDXRef-Package-zlib", ComponentID: 88, FileInstanceID: 7},
+	})
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if verr.Field != "component_id" {
+		t.Errorf("expected field %v, got %v", "component_id", verr.Field)
+	}
+}
@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ScanDelta describes the differences in scan results between two
+// RepoPulls, typically the base and head of a pull request, as
+// computed by a comparison Job.
+type ScanDelta struct {
+	// ID is the unique ID for this delta.
+	ID uint64 `json:"id"`
+	// BaseRepoPullID is the ID of the earlier RepoPull being
+	// compared against.
+	BaseRepoPullID RepoPullID `json:"base_repopull_id"`
+	// HeadRepoPullID is the ID of the later RepoPull being
+	// compared.
+	HeadRepoPullID RepoPullID `json:"head_repopull_id"`
+	// JobID is the ID of the Job that computed the delta.
+	JobID JobID `json:"job_id"`
+	// NewFindingIDs are the IDs of findings in the head RepoPull
+	// with no counterpart in the base.
+	NewFindingIDs []uint64 `json:"new_finding_ids"`
+	// ResolvedFindingIDs are the IDs of findings in the base
+	// RepoPull with no counterpart in the head.
+	ResolvedFindingIDs []uint64 `json:"resolved_finding_ids"`
+	// NewLicenseIDs are the IDs of licenses found in the head
+	// RepoPull but not in the base.
+	NewLicenseIDs []uint32 `json:"new_license_ids"`
+	// ComputedAt is when the delta was stored.
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// Validate checks that the ScanDelta's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (sd *ScanDelta) Validate() error {
+	if sd.BaseRepoPullID == sd.HeadRepoPullID {
+		return &ValidationError{Entity: "scan delta", Field: "head_repopull_id", Reason: "must differ from base_repopull_id"}
+	}
+	return nil
+}
+
+const scanDeltaColumns = "id, base_repopull_id, head_repopull_id, job_id, new_finding_ids, resolved_finding_ids, new_license_ids, computed_at"
+
+// scanScanDelta reads a ScanDelta from a row selecting
+// scanDeltaColumns.
+func scanScanDelta(rs rowScanner) (*ScanDelta, error) {
+	sd := &ScanDelta{}
+	var newFindings, resolvedFindings, newLicenses pq.Int64Array
+	err := rs.Scan(&sd.ID, &sd.BaseRepoPullID, &sd.HeadRepoPullID, &sd.JobID, &newFindings, &resolvedFindings, &newLicenses, &sd.ComputedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	sd.NewFindingIDs = make([]uint64, len(newFindings))
+	for i, id := range newFindings {
+		sd.NewFindingIDs[i] = uint64(id)
+	}
+	sd.ResolvedFindingIDs = make([]uint64, len(resolvedFindings))
+	for i, id := range resolvedFindings {
+		sd.ResolvedFindingIDs[i] = uint64(id)
+	}
+	sd.NewLicenseIDs = make([]uint32, len(newLicenses))
+	for i, id := range newLicenses {
+		sd.NewLicenseIDs[i] = uint32(id)
+	}
+	sd.ComputedAt = normalizeTime(sd.ComputedAt)
+	return sd, nil
+}
+
+// GetScanDelta returns the ScanDelta comparing the RepoPull with ID
+// headRPID against the one with ID baseRPID, or nil and an error if
+// none has been stored.
+func (db *DB) GetScanDelta(baseRPID RepoPullID, headRPID RepoPullID) (*ScanDelta, error) {
+	sd, err := scanScanDelta(db.sqldb.QueryRow("SELECT "+scanDeltaColumns+" FROM peridot.scan_deltas WHERE base_repopull_id = $1 AND head_repopull_id = $2", baseRPID, headRPID))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "scan delta", Key: "base/head repo pull IDs", ID: fmt.Sprintf("%d/%d", baseRPID, headRPID)}
+	}
+	return sd, err
+}
+
+// GetScanDeltasForHeadRepoPull returns a slice of all ScanDeltas
+// comparing the RepoPull with the given ID against an earlier one,
+// ordered by base repo pull ID.
+func (db *DB) GetScanDeltasForHeadRepoPull(headRPID RepoPullID) ([]*ScanDelta, error) {
+	rows, err := db.sqldb.Query("SELECT "+scanDeltaColumns+" FROM peridot.scan_deltas WHERE head_repopull_id = $1 ORDER BY base_repopull_id", headRPID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deltas := []*ScanDelta{}
+	for rows.Next() {
+		sd, err := scanScanDelta(rows)
+		if err != nil {
+			return nil, err
+		}
+		deltas = append(deltas, sd)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return deltas, nil
+}
+
+// SetScanDelta stores sd, replacing any existing ScanDelta for the
+// same pair of RepoPulls, e.g. when a comparison Job is re-run.
+// sd's ID and ComputedAt fields are ignored. It returns the delta's
+// ID on success or an error if failing.
+func (db *DB) SetScanDelta(sd *ScanDelta) (uint64, error) {
+	if err := sd.Validate(); err != nil {
+		return 0, err
+	}
+
+	newFindings := make(pq.Int64Array, len(sd.NewFindingIDs))
+	for i, id := range sd.NewFindingIDs {
+		newFindings[i] = int64(id)
+	}
+	resolvedFindings := make(pq.Int64Array, len(sd.ResolvedFindingIDs))
+	for i, id := range sd.ResolvedFindingIDs {
+		resolvedFindings[i] = int64(id)
+	}
+	newLicenses := make(pq.Int64Array, len(sd.NewLicenseIDs))
+	for i, id := range sd.NewLicenseIDs {
+		newLicenses[i] = int64(id)
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.scan_deltas(base_repopull_id, head_repopull_id, job_id, new_finding_ids, resolved_finding_ids, new_license_ids, computed_at) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (base_repopull_id, head_repopull_id) DO UPDATE SET job_id = EXCLUDED.job_id, new_finding_ids = EXCLUDED.new_finding_ids, resolved_finding_ids = EXCLUDED.resolved_finding_ids, new_license_ids = EXCLUDED.new_license_ids, computed_at = EXCLUDED.computed_at RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var deltaID uint64
+	err = stmt.QueryRow(sd.BaseRepoPullID, sd.HeadRepoPullID, sd.JobID, newFindings, resolvedFindings, newLicenses, now()).Scan(&deltaID)
+	if err != nil {
+		return 0, err
+	}
+	return deltaID, nil
+}
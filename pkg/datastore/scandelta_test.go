@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestShouldGetScanDelta(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	computedAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "base_repopull_id", "head_repopull_id", "job_id", "new_finding_ids", "resolved_finding_ids", "new_license_ids", "computed_at"}).
+		AddRow(9, 35, 36, 14, "{1812,1815}", "{}", "{87}", computedAt)
+	mock.ExpectQuery(`SELECT id, base_repopull_id, head_repopull_id, job_id, new_finding_ids, resolved_finding_ids, new_license_ids, computed_at FROM peridot.scan_deltas WHERE base_repopull_id = \$1 AND head_repopull_id = \$2`).
+		WithArgs(35, 36).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	sd, err := db.GetScanDelta(35, 36)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := &ScanDelta{
+		ID:                 9,
+		BaseRepoPullID:     35,
+		HeadRepoPullID:     36,
+		JobID:              14,
+		NewFindingIDs:      []uint64{1812, 1815},
+		ResolvedFindingIDs: []uint64{},
+		NewLicenseIDs:      []uint32{87},
+		ComputedAt:         computedAt,
+	}
+	if !reflect.DeepEqual(want, sd) {
+		t.Errorf("expected %#v, got %#v", want, sd)
+	}
+}
+
+func TestShouldFailGetScanDeltaForUnknownPair(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT (.+) FROM peridot.scan_deltas WHERE base_repopull_id = \$1 AND head_repopull_id = \$2`).
+		WithArgs(35, 413).
+		WillReturnError(sql.ErrNoRows)
+
+	// run the tested function
+	_, err = db.GetScanDelta(35, 413)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldSetScanDelta(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.scan_deltas\(base_repopull_id, head_repopull_id, job_id, new_finding_ids, resolved_finding_ids, new_license_ids, computed_at\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\) ON CONFLICT \(base_repopull_id, head_repopull_id\) DO UPDATE SET (.+) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(35, 36, 14, pq.Int64Array{1812, 1815}, pq.Int64Array{}, pq.Int64Array{87}, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+
+	// run the tested function
+	id, err := db.SetScanDelta(&ScanDelta{
+		BaseRepoPullID: 35,
+		HeadRepoPullID: 36,
+		JobID:          14,
+		NewFindingIDs:  []uint64{1812, 1815},
+		NewLicenseIDs:  []uint32{87},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 9 {
+		t.Errorf("expected %v, got %v", 9, id)
+	}
+}
+
+func TestShouldFailSetScanDeltaForSameRepoPull(t *testing.T) {
+	_, err := (&DB{}).SetScanDelta(&ScanDelta{BaseRepoPullID: 36, HeadRepoPullID: 36, JobID: 14})
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("expected *ValidationError, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+)
+
+// DefaultSchema is the Postgres schema that holds the tables unless
+// Config.Schema names another one.
+const DefaultSchema = "peridot"
+
+// schemaName returns the name of the schema holding the DB's tables.
+func (db *DB) schemaName() string {
+	if db.schema == "" {
+		return DefaultSchema
+	}
+	return db.schema
+}
+
+// validSchemaName reports whether name can be used as Config.Schema:
+// lowercase letters, digits and underscores, not starting with a
+// digit, and no longer than Postgres's 63-byte identifier limit.
+// Since it is written into statements unquoted, nothing else is
+// allowed.
+func validSchemaName(name string) bool {
+	return name != "" && len(name) <= 63 &&
+		strings.TrimLeft(name, "abcdefghijklmnopqrstuvwxyz0123456789_") == "" &&
+		(name[0] < '0' || name[0] > '9')
+}
+
+// openInSchema opens a *sql.DB like sql.Open, but whose connections
+// run every statement against the given schema in place of
+// DefaultSchema. The DB methods all name their tables as
+// "peridot.<table>", so rather than building each statement with
+// the schema, the connections rewrite that prefix as statements are
+// sent.
+func openInSchema(driverName string, dsn string, schema string) (*sql.DB, error) {
+	probe, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := probe.Driver()
+	probe.Close()
+
+	var connector driver.Connector
+	if dc, ok := d.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	} else {
+		connector = &dsnConnector{driver: d, dsn: dsn}
+	}
+
+	replacer := strings.NewReplacer(
+		DefaultSchema+".", schema+".",
+		`"`+DefaultSchema+`".`, `"`+schema+`".`,
+	)
+	return sql.OpenDB(&schemaConnector{Connector: connector, replacer: replacer}), nil
+}
+
+// dsnConnector is a driver.Connector for drivers that do not
+// implement driver.DriverContext, opening each connection by DSN.
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+// Connect opens a new connection.
+func (c *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+// Driver returns the underlying driver.
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// schemaConnector wraps another driver.Connector, returning
+// connections that rewrite the schema named in each statement.
+type schemaConnector struct {
+	driver.Connector
+	replacer *strings.Replacer
+}
+
+// Connect opens a new connection with the wrapped connector.
+func (c *schemaConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &schemaConn{Conn: conn, replacer: c.replacer}, nil
+}
+
+// schemaConn wraps a driver.Conn, rewriting each statement with its
+// replacer before passing it on. The optional interfaces that
+// database/sql looks for are passed through to the wrapped
+// connection where it implements them, and otherwise report
+// driver.ErrSkip or their defaults, so that database/sql behaves as
+// it would with the wrapped connection itself.
+type schemaConn struct {
+	driver.Conn
+	replacer *strings.Replacer
+}
+
+// Prepare prepares the rewritten query.
+func (c *schemaConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(c.replacer.Replace(query))
+}
+
+// PrepareContext prepares the rewritten query.
+func (c *schemaConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	query = c.replacer.Replace(query)
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return pc.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+// ExecContext runs the rewritten query without preparing it, if the
+// wrapped connection can.
+func (c *schemaConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if ec, ok := c.Conn.(driver.ExecerContext); ok {
+		return ec.ExecContext(ctx, c.replacer.Replace(query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+// QueryContext runs the rewritten query without preparing it, if
+// the wrapped connection can.
+func (c *schemaConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if qc, ok := c.Conn.(driver.QueryerContext); ok {
+		return qc.QueryContext(ctx, c.replacer.Replace(query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+// BeginTx starts a transaction on the wrapped connection.
+func (c *schemaConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+// Ping checks the wrapped connection, if it can be checked.
+func (c *schemaConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession resets the wrapped connection, if it can be reset.
+func (c *schemaConn) ResetSession(ctx context.Context) error {
+	if sr, ok := c.Conn.(driver.SessionResetter); ok {
+		return sr.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid reports whether the wrapped connection can be reused.
+func (c *schemaConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue lets the wrapped connection convert arguments, if
+// it converts them itself.
+func (c *schemaConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import "fmt"
+
+// SnippetMatch describes a range of a FileInstance that a snippet
+// scanner Job matched against code from another source, such as an
+// open source package, rather than identifying the file as a whole.
+type SnippetMatch struct {
+	// ID is the unique ID for this snippet match.
+	ID uint64 `json:"id"`
+	// FileInstanceID is the ID of the FileInstance containing the
+	// matched snippet.
+	FileInstanceID uint64 `json:"fileinstance_id"`
+	// JobID is the ID of the Job that found the match.
+	JobID JobID `json:"job_id"`
+	// StartByte is the offset of the first byte of the snippet,
+	// counting from 0.
+	StartByte int64 `json:"start_byte"`
+	// EndByte is the offset just past the last byte of the snippet.
+	EndByte int64 `json:"end_byte"`
+	// StartLine is the first line of the snippet, counting from 1.
+	StartLine int `json:"start_line"`
+	// EndLine is the last line of the snippet.
+	EndLine int `json:"end_line"`
+	// MatchedSource identifies where the matching code comes from,
+	// e.g. a package URL such as "pkg:github/madler/zlib@v1.2.11".
+	MatchedSource string `json:"matched_source"`
+	// MatchedPath is the path of the matching file within
+	// MatchedSource, if known.
+	MatchedPath string `json:"matched_path,omitempty"`
+	// LicenseExpression is the SPDX license expression of the
+	// matching code, or "" if unknown.
+	LicenseExpression string `json:"license_expression,omitempty"`
+}
+
+// Validate checks that the SnippetMatch's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (sm *SnippetMatch) Validate() error {
+	if sm.StartByte < 0 || sm.EndByte <= sm.StartByte {
+		return &ValidationError{Entity: "snippet match", Field: "start_byte", Reason: fmt.Sprintf("bytes %d to %d are not a valid range", sm.StartByte, sm.EndByte)}
+	}
+	if sm.StartLine < 1 || sm.EndLine < sm.StartLine {
+		return &ValidationError{Entity: "snippet match", Field: "start_line", Reason: fmt.Sprintf("lines %d to %d are not a valid range", sm.StartLine, sm.EndLine)}
+	}
+	return requireNonEmpty("snippet match", "matched_source", sm.MatchedSource)
+}
+
+const snippetMatchColumns = "id, fileinstance_id, job_id, start_byte, end_byte, start_line, end_line, matched_source, matched_path, license_expression"
+
+// GetSnippetMatchesForFileInstance returns a slice of all snippet
+// matches for the FileInstance with the given ID, ordered by their
+// position in the file and then by ID.
+func (db *DB) GetSnippetMatchesForFileInstance(fileInstanceID uint64) ([]*SnippetMatch, error) {
+	rows, err := db.sqldb.Query("SELECT "+snippetMatchColumns+" FROM peridot.snippet_matches WHERE fileinstance_id = $1 ORDER BY start_byte, end_byte, id", fileInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sms := []*SnippetMatch{}
+	for rows.Next() {
+		sm := &SnippetMatch{}
+		err := rows.Scan(&sm.ID, &sm.FileInstanceID, &sm.JobID, &sm.StartByte, &sm.EndByte, &sm.StartLine, &sm.EndLine, &sm.MatchedSource, &sm.MatchedPath, &sm.LicenseExpression)
+		if err != nil {
+			return nil, err
+		}
+		sms = append(sms, sm)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sms, nil
+}
+
+// AddSnippetMatches adds the given snippet matches in a single
+// transaction, so that either all or none of them are added. The
+// matches' ID fields are ignored. It returns the new matches' IDs,
+// in the same order, on success or an error if failing.
+func (db *DB) AddSnippetMatches(matches []*SnippetMatch) ([]uint64, error) {
+	for _, sm := range matches {
+		if err := sm.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.snippet_matches(fileinstance_id, job_id, start_byte, end_byte, start_line, end_line, matched_source, matched_path, license_expression) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	ids := make([]uint64, 0, len(matches))
+	for _, sm := range matches {
+		var id uint64
+		err = stmt.QueryRow(sm.FileInstanceID, sm.JobID, sm.StartByte, sm.EndByte, sm.StartLine, sm.EndLine, sm.MatchedSource, sm.MatchedPath, sm.LicenseExpression).Scan(&id)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// DeleteSnippetMatchesForJob deletes all snippet matches found by the
+// Job with the given ID, e.g. before re-running it. It returns the
+// number of matches deleted on success or an error if failing.
+func (db *DB) DeleteSnippetMatchesForJob(jobID JobID) (int64, error) {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.snippet_matches WHERE job_id = $1", jobID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
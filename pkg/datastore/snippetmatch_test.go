@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetSnippetMatchesForFileInstance(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "fileinstance_id", "job_id", "start_byte", "end_byte", "start_line", "end_line", "matched_source", "matched_path", "license_expression"}).
+		AddRow(31, 7, 12, 0, 1520, 1, 48, "pkg:github/madler/zlib@v1.2.11", "inflate.c", "Zlib").
+		AddRow(32, 7, 12, 4096, 4410, 130, 141, "pkg:npm/left-pad@1.3.0", "", "")
+	mock.ExpectQuery(`SELECT id, fileinstance_id, job_id, start_byte, end_byte, start_line, end_line, matched_source, matched_path, license_expression FROM peridot.snippet_matches WHERE fileinstance_id = \$1 ORDER BY start_byte, end_byte, id`).
+		WithArgs(7).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetSnippetMatchesForFileInstance(7)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*SnippetMatch{
+		{ID: 31, FileInstanceID: 7, JobID: 12, StartByte: 0, EndByte: 1520, StartLine: 1, EndLine: 48, MatchedSource: "pkg:github/madler/zlib@v1.2.11", MatchedPath: "inflate.c", LicenseExpression: "Zlib"},
+		{ID: 32, FileInstanceID: 7, JobID: 12, StartByte: 4096, EndByte: 4410, StartLine: 130, EndLine: 141, MatchedSource: "pkg:npm/left-pad@1.3.0"},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldAddSnippetMatches(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.snippet_matches\(fileinstance_id, job_id, start_byte, end_byte, start_line, end_line, matched_source, matched_path, license_expression\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9\) RETURNING id`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(7, 12, 0, 1520, 1, 48, "pkg:github/madler/zlib@v1.2.11", "inflate.c", "Zlib").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(31))
+	mock.ExpectQuery(regexStmt).
+		WithArgs(8, 12, 200, 310, 9, 12, "pkg:npm/left-pad@1.3.0", "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(32))
+	mock.ExpectCommit()
+
+	// run the tested function
+	ids, err := db.AddSnippetMatches([]*SnippetMatch{
+		{FileInstanceID: 7, JobID: 12, StartByte: 0, EndByte: 1520, StartLine: 1, EndLine: 48, MatchedSource: "pkg:github/madler/zlib@v1.2.11", MatchedPath: "inflate.c", LicenseExpression: "Zlib"},
+		{FileInstanceID: 8, JobID: 12, StartByte: 200, EndByte: 310, StartLine: 9, EndLine: 12, MatchedSource: "pkg:npm/left-pad@1.3.0"},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if !reflect.DeepEqual([]uint64{31, 32}, ids) {
+		t.Errorf("expected %v, got %v", []uint64{31, 32}, ids)
+	}
+}
+
+func TestShouldFailAddSnippetMatchesWithInvalidMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		sm    *SnippetMatch
+		field string
+	}{
+		{"empty byte range", &SnippetMatch{StartByte: 20, EndByte: 20, StartLine: 1, EndLine: 1, MatchedSource: "x"}, "start_byte"},
+		{"negative start byte", &SnippetMatch{StartByte: -1, EndByte: 20, StartLine: 1, EndLine: 1, MatchedSource: "x"}, "start_byte"},
+		{"zero start line", &SnippetMatch{StartByte: 0, EndByte: 20, StartLine: 0, EndLine: 1, MatchedSource: "x"}, "start_line"},
+		{"reversed lines", &SnippetMatch{StartByte: 0, EndByte: 20, StartLine: 5, EndLine: 4, MatchedSource: "x"}, "start_line"},
+		{"no source", &SnippetMatch{StartByte: 0, EndByte: 20, StartLine: 1, EndLine: 1}, "matched_source"},
+	}
+	for _, tc := range tests {
+		_, err := (&DB{}).AddSnippetMatches([]*SnippetMatch{tc.sm})
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%s: expected *ValidationError, got %v", tc.name, err)
+			continue
+		}
+		if verr.Field != tc.field {
+			t.Errorf("%s: expected field %v, got %v", tc.name, tc.field, verr.Field)
+		}
+	}
+}
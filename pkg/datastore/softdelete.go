@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetSoftDelete sets whether DeleteProject, DeleteSubproject and
+// DeleteRepo only mark their entity as deleted rather than removing
+// it. Soft-deleted entities are left out of every Get, Count and
+// Exists method, but can be brought back with the matching Restore
+// method until they are removed for good with the matching Purge
+// method or PurgeDeletedBefore. Soft deletion is disabled by default.
+//
+// Soft-deleting an entity also hides everything it contains: the
+// subprojects, repos, repo pulls and jobs of a soft-deleted project
+// are left out of the same methods, so that its jobs are no longer
+// returned by GetReadyJobs, and come back when it is restored.
+// They are deleted with it when it is purged.
+func (db *DB) SetSoftDelete(enabled bool) {
+	db.softDelete = enabled
+}
+
+// liveSubprojectCondition matches subprojects that are neither
+// soft-deleted themselves nor in a soft-deleted project.
+const liveSubprojectCondition = "deleted_at IS NULL AND project_id IN (SELECT id FROM peridot.projects WHERE deleted_at IS NULL)"
+
+// liveSubprojectIDs selects the IDs of subprojects matching
+// liveSubprojectCondition.
+const liveSubprojectIDs = "SELECT id FROM peridot.subprojects WHERE " + liveSubprojectCondition
+
+// liveRepoCondition matches repos that are neither soft-deleted
+// themselves nor in a soft-deleted subproject or project.
+const liveRepoCondition = "deleted_at IS NULL AND subproject_id IN (" + liveSubprojectIDs + ")"
+
+// liveRepoIDs selects the IDs of repos matching liveRepoCondition.
+// Repo pulls, and the results recorded for them, are hidden by
+// requiring their repo_id to be among these.
+const liveRepoIDs = "SELECT id FROM peridot.repos WHERE " + liveRepoCondition
+
+// liveRepoPullIDs selects the IDs of repo pulls whose repo is among
+// liveRepoIDs. Jobs are hidden by requiring their repopull_id to be
+// among these.
+const liveRepoPullIDs = "SELECT id FROM peridot.repo_pulls WHERE repo_id IN (" + liveRepoIDs + ")"
+
+// deleteRow deletes the row with the given ID from the given table,
+// or marks it as deleted if soft deletion is enabled, and records an
+// outbox event for it either way. A row that has already been
+// soft-deleted is reported as not found.
+func (db *DB) deleteRow(entity string, table string, id interface{}) error {
+	query := "DELETE FROM peridot." + table + " WHERE id = $1"
+	if db.softDelete {
+		query = "UPDATE peridot." + table + " SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL"
+	}
+	return db.execWithOutboxEvent(entity, id, AuditActionDelete, map[string]interface{}{"id": id}, query, id)
+}
+
+// restoreRow clears the deletion mark of the soft-deleted row with
+// the given ID in the given table, and records an outbox event
+// announcing it as added again.
+func (db *DB) restoreRow(entity string, table string, id interface{}) error {
+	return db.execWithOutboxEvent(entity, id, AuditActionAdd, map[string]interface{}{"id": id},
+		"UPDATE peridot."+table+" SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL", id)
+}
+
+// purgeRow permanently deletes the soft-deleted row with the given
+// ID from the given table. No outbox event is recorded, since one
+// was already recorded when the row was soft-deleted.
+func (db *DB) purgeRow(entity string, table string, id interface{}) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot."+table+" WHERE id = $1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: entity, ID: fmt.Sprint(id)}
+	}
+	return nil
+}
+
+// RestoreProject restores the soft-deleted Project with the given
+// ID. It returns a *NotFoundError if there is no such soft-deleted
+// project, or a *ConflictError if another project has since been
+// added with the same name.
+func (db *DB) RestoreProject(id ProjectID) error {
+	return db.restoreRow("project", "projects", id)
+}
+
+// RestoreSubproject restores the soft-deleted Subproject with the
+// given ID. It returns a *NotFoundError if there is no such
+// soft-deleted subproject, or a *ConflictError if another subproject
+// in the same project has since been added with the same name.
+func (db *DB) RestoreSubproject(id uint32) error {
+	return db.restoreRow("subproject", "subprojects", id)
+}
+
+// RestoreRepo restores the soft-deleted Repo with the given ID. It
+// returns a *NotFoundError if there is no such soft-deleted repo, or
+// a *ConflictError if another repo in the same subproject has since
+// been added with the same name.
+func (db *DB) RestoreRepo(id RepoID) error {
+	return db.restoreRow("repo", "repos", id)
+}
+
+// PurgeProject permanently deletes the soft-deleted Project with the
+// given ID, along with everything it contains. It returns a
+// *NotFoundError if there is no such soft-deleted project.
+func (db *DB) PurgeProject(id ProjectID) error {
+	return db.purgeRow("project", "projects", id)
+}
+
+// PurgeSubproject permanently deletes the soft-deleted Subproject
+// with the given ID, along with everything it contains. It returns a
+// *NotFoundError if there is no such soft-deleted subproject.
+func (db *DB) PurgeSubproject(id uint32) error {
+	return db.purgeRow("subproject", "subprojects", id)
+}
+
+// PurgeRepo permanently deletes the soft-deleted Repo with the given
+// ID, along with its repo pulls and their contents. It returns a
+// *NotFoundError if there is no such soft-deleted repo.
+func (db *DB) PurgeRepo(id RepoID) error {
+	return db.purgeRow("repo", "repos", id)
+}
+
+// PurgeDeletedBefore permanently deletes all projects, subprojects
+// and repos that were soft-deleted before the given time, along with
+// everything they contain. It returns the number of soft-deleted
+// projects, subprojects and repos deleted on success or an error if
+// failing.
+func (db *DB) PurgeDeletedBefore(before time.Time) (int64, error) {
+	before = normalizeTime(before)
+	var purged int64
+	for _, table := range []string{"repos", "subprojects", "projects"} {
+		result, err := db.sqldb.Exec("DELETE FROM peridot."+table+" WHERE deleted_at < $1", before)
+		if err != nil {
+			return purged, err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+		purged += rows
+	}
+	return purged, nil
+}
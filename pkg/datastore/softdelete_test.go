@@ -0,0 +1,188 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// regexes matching the conditions that hide soft-deleted rows and
+// the contents of soft-deleted parents
+var (
+	liveSubprojectConditionRegex = regexp.QuoteMeta(liveSubprojectCondition)
+	liveRepoConditionRegex       = regexp.QuoteMeta(liveRepoCondition)
+	liveRepoIDsRegex             = regexp.QuoteMeta(liveRepoIDs)
+	liveRepoPullIDsRegex         = regexp.QuoteMeta(liveRepoPullIDs)
+)
+
+func TestShouldSoftDeleteRepoWhenEnabled(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+	db.SetSoftDelete(true)
+
+	regexStmt := `UPDATE peridot.repos SET deleted_at = now\(\) WHERE id = \$1 AND deleted_at IS NULL`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("repo", "4", AuditActionDelete, []byte(`{"id":4}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.DeleteRepo(4)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldRestoreSoftDeletedProject(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `UPDATE peridot.projects SET deleted_at = NULL WHERE id = \$1 AND deleted_at IS NOT NULL`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("project", "2", AuditActionAdd, []byte(`{"id":2}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.RestoreProject(2)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailPurgeSubprojectThatIsNotSoftDeleted(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`DELETE FROM peridot.subprojects WHERE id = \$1 AND deleted_at IS NOT NULL`).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.PurgeSubproject(3)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldPurgeDeletedBefore(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	before := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	mock.ExpectExec(`DELETE FROM peridot.repos WHERE deleted_at < \$1`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectExec(`DELETE FROM peridot.subprojects WHERE deleted_at < \$1`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM peridot.projects WHERE deleted_at < \$1`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	n, err := db.PurgeDeletedBefore(before)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if n != 6 {
+		t.Errorf("expected %v, got %v", 6, n)
+	}
+}
+
+func TestShouldNotGetReadyJobsOfSoftDeletedRepo(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+	db.SetSoftDelete(true)
+
+	// the only waiting job belongs to a soft-deleted repo's pull, so
+	// the live repo pulls filter leaves no candidates
+	mock.ExpectQuery(`WITH candidates AS \( SELECT id FROM peridot.jobs WHERE status IN \(1, 4\) AND health = 1 AND is_ready = true AND repopull_id IN \(` + liveRepoPullIDsRegex + `\) \)`).
+		WithArgs(0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(jobsByIDsRegex).
+		WillReturnRows(sqlmock.NewRows(jobsByIDsColumns))
+
+	// run the tested function
+	gotRows, err := db.GetReadyJobs(0)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 0 {
+		t.Errorf("expected no ready jobs, got %d", len(gotRows))
+	}
+}
@@ -107,6 +107,12 @@ func StringFromSPDXElementType(eltType SPDXElementType) string {
 	return "unknown"
 }
 
+// String returns the name of the kind of SPDX element, such as
+// "file", as given by StringFromSPDXElementType.
+func (eltType SPDXElementType) String() string {
+	return StringFromSPDXElementType(eltType)
+}
+
 // MarshalJSON converts the SPDXElementType value into a slice of bytes
 // containing the string encoding of the access level.
 func (eltType SPDXElementType) MarshalJSON() ([]byte, error) {
@@ -4,6 +4,7 @@ package datastore
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 )
 
@@ -249,3 +250,20 @@ func TestCanUnmarshalJSONToSPDXElementType(t *testing.T) {
 		t.Errorf("expected %v, got %v", want, got)
 	}
 }
+
+func TestCanFormatSPDXElementType(t *testing.T) {
+	tests := []struct {
+		in   SPDXElementType
+		want string
+	}{
+		{SPDXElementTypeRepoPull, "repopull"},
+		{SPDXElementTypeFile, "file"},
+	}
+
+	for _, tt := range tests {
+		got := fmt.Sprintf("%v", tt.in)
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
@@ -3,8 +3,10 @@
 package datastore
 
 import (
+	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strconv"
 )
 
 // ===== Status =====
@@ -32,6 +34,14 @@ const (
 	// regardless of whether it has completed successfully
 	// or has encountered an error.
 	StatusStopped Status = 3
+
+	// StatusQueued means that the operation is waiting in a
+	// queue to be started, and has not yet begun setup.
+	StatusQueued Status = 4
+
+	// StatusCancelled means that the operation was cancelled
+	// before it completed, and will not proceed further.
+	StatusCancelled Status = 5
 )
 
 // StatusFromInt converts an integer to its corresponding
@@ -47,6 +57,10 @@ func StatusFromInt(stInt int) (Status, error) {
 		return StatusRunning, nil
 	case 3:
 		return StatusStopped, nil
+	case 4:
+		return StatusQueued, nil
+	case 5:
+		return StatusCancelled, nil
 	}
 
 	return StatusSame, fmt.Errorf("invalid status integer %d", stInt)
@@ -64,6 +78,10 @@ func IntFromStatus(st Status) int {
 		return 2
 	case StatusStopped:
 		return 3
+	case StatusQueued:
+		return 4
+	case StatusCancelled:
+		return 5
 	}
 
 	// shouldn't be possible to fall through since all values
@@ -85,6 +103,10 @@ func StatusFromString(stStr string) (Status, error) {
 		return StatusRunning, nil
 	case "stopped":
 		return StatusStopped, nil
+	case "queued":
+		return StatusQueued, nil
+	case "cancelled":
+		return StatusCancelled, nil
 	}
 
 	return StatusSame, fmt.Errorf("invalid status string %s", stStr)
@@ -102,6 +124,10 @@ func StringFromStatus(st Status) string {
 		return "running"
 	case StatusStopped:
 		return "stopped"
+	case StatusQueued:
+		return "queued"
+	case StatusCancelled:
+		return "cancelled"
 	}
 
 	// shouldn't be possible to fall through since all values
@@ -110,6 +136,12 @@ func StringFromStatus(st Status) string {
 	return "same"
 }
 
+// String returns the Status's string value, such as "running", as
+// given by StringFromStatus.
+func (st Status) String() string {
+	return StringFromStatus(st)
+}
+
 // MarshalJSON converts the UserAccessLevel value into a slice of bytes
 // containing the string encoding of the access level.
 func (st Status) MarshalJSON() ([]byte, error) {
@@ -135,6 +167,34 @@ func (st *Status) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// Scan implements sql.Scanner, converting an integer column value
+// into the corresponding Status value. It returns an error if the
+// stored value is NULL or is not a valid status integer.
+func (st *Status) Scan(src interface{}) error {
+	n, err := scanEnumInt(src, "status")
+	if err != nil {
+		return err
+	}
+
+	stVal, err := StatusFromInt(n)
+	if err != nil {
+		return err
+	}
+
+	*st = stVal
+	return nil
+}
+
+// Value implements driver.Valuer, converting the Status value into
+// its integer encoding for storage. It returns an error if the
+// Status value is not valid.
+func (st Status) Value() (driver.Value, error) {
+	if _, err := StatusFromInt(int(st)); err != nil {
+		return nil, err
+	}
+	return int64(IntFromStatus(st)), nil
+}
+
 // ===== Health =====
 
 // Health defines the different health values that can apply
@@ -162,6 +222,12 @@ const (
 	// should be treated as failed, and will not proceed
 	// further.
 	HealthError Health = 3
+
+	// HealthUnknown means that the outcome of the operation
+	// is not known, for example because its agent disappeared
+	// before reporting. It is distinct from HealthOK, and
+	// should not be treated as a success.
+	HealthUnknown Health = 4
 )
 
 // HealthFromInt converts an integer to its corresponding
@@ -177,6 +243,8 @@ func HealthFromInt(hInt int) (Health, error) {
 		return HealthDegraded, nil
 	case 3:
 		return HealthError, nil
+	case 4:
+		return HealthUnknown, nil
 	}
 
 	return HealthSame, fmt.Errorf("invalid health integer %d", hInt)
@@ -194,6 +262,8 @@ func IntFromHealth(h Health) int {
 		return 2
 	case HealthError:
 		return 3
+	case HealthUnknown:
+		return 4
 	}
 
 	// shouldn't be possible to fall through since all values
@@ -215,6 +285,8 @@ func HealthFromString(hStr string) (Health, error) {
 		return HealthDegraded, nil
 	case "error":
 		return HealthError, nil
+	case "unknown":
+		return HealthUnknown, nil
 	}
 
 	return HealthSame, fmt.Errorf("invalid health string %s", hStr)
@@ -232,6 +304,8 @@ func StringFromHealth(h Health) string {
 		return "degraded"
 	case HealthError:
 		return "error"
+	case HealthUnknown:
+		return "unknown"
 	}
 
 	// shouldn't be possible to fall through since all values
@@ -240,6 +314,12 @@ func StringFromHealth(h Health) string {
 	return "same"
 }
 
+// String returns the Health's string value, such as "degraded", as
+// given by StringFromHealth.
+func (h Health) String() string {
+	return StringFromHealth(h)
+}
+
 // MarshalJSON converts the UserAccessLevel value into a slice of bytes
 // containing the string encoding of the access level.
 func (h Health) MarshalJSON() ([]byte, error) {
@@ -264,3 +344,59 @@ func (h *Health) UnmarshalJSON(b []byte) error {
 	*h = hVal
 	return nil
 }
+
+// Scan implements sql.Scanner, converting an integer column value
+// into the corresponding Health value. It returns an error if the
+// stored value is NULL or is not a valid health integer.
+func (h *Health) Scan(src interface{}) error {
+	n, err := scanEnumInt(src, "health")
+	if err != nil {
+		return err
+	}
+
+	hVal, err := HealthFromInt(n)
+	if err != nil {
+		return err
+	}
+
+	*h = hVal
+	return nil
+}
+
+// Value implements driver.Valuer, converting the Health value into
+// its integer encoding for storage. It returns an error if the
+// Health value is not valid.
+func (h Health) Value() (driver.Value, error) {
+	if _, err := HealthFromInt(int(h)); err != nil {
+		return nil, err
+	}
+	return int64(IntFromHealth(h)), nil
+}
+
+// ===== Helpers =====
+
+// scanEnumInt extracts the integer from a column value being scanned
+// into one of the integer-backed enum types. The name is used in
+// error messages to describe which kind of value was being scanned.
+func scanEnumInt(src interface{}, name string) (int, error) {
+	switch v := src.(type) {
+	case int64:
+		return int(v), nil
+	case []byte:
+		n, err := strconv.Atoi(string(v))
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s value %q: %v", name, v, err)
+		}
+		return n, nil
+	case string:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s value %q: %v", name, v, err)
+		}
+		return n, nil
+	case nil:
+		return 0, fmt.Errorf("cannot scan NULL into %s", name)
+	}
+
+	return 0, fmt.Errorf("cannot scan %T into %s", src, name)
+}
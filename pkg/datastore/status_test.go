@@ -4,6 +4,7 @@ package datastore
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 )
 
@@ -49,6 +50,24 @@ func TestCanChangeIntToStatus(t *testing.T) {
 		t.Errorf("expected %v, got %v", want, got)
 	}
 
+	got, err = StatusFromInt(4)
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	want = StatusQueued
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	got, err = StatusFromInt(5)
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	want = StatusCancelled
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
 	// and invalid values should return error
 	got, err = StatusFromInt(57)
 	if err == nil {
@@ -83,6 +102,18 @@ func TestCanChangeStatusToInt(t *testing.T) {
 		t.Errorf("expected %v, got %v", want, got)
 	}
 
+	got = IntFromStatus(StatusQueued)
+	want = 4
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	got = IntFromStatus(StatusCancelled)
+	want = 5
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
 }
 
 func TestCanChangeStringToStatus(t *testing.T) {
@@ -125,6 +156,24 @@ func TestCanChangeStringToStatus(t *testing.T) {
 		t.Errorf("expected %v, got %v", want, got)
 	}
 
+	got, err = StatusFromString("queued")
+	want = StatusQueued
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	got, err = StatusFromString("cancelled")
+	want = StatusCancelled
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
 	// and invalid values should return error
 	got, err = StatusFromString("oops")
 	if err == nil {
@@ -158,6 +207,19 @@ func TestCanChangeStatusToString(t *testing.T) {
 	if got != want {
 		t.Errorf("expected %v, got %v", want, got)
 	}
+
+	got = StringFromStatus(StatusQueued)
+	want = "queued"
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	got = StringFromStatus(StatusCancelled)
+	want = "cancelled"
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
 }
 
 func TestCanMarshalStatusToJSON(t *testing.T) {
@@ -205,6 +267,26 @@ func TestCanMarshalStatusToJSON(t *testing.T) {
 		t.Errorf("expected %T %v, got %T %v", want, want, got, got)
 	}
 
+	gotBytes, err = json.Marshal(StatusQueued)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	got = string(gotBytes)
+	want = "\"queued\""
+	if got != want {
+		t.Errorf("expected %T %v, got %T %v", want, want, got, got)
+	}
+
+	gotBytes, err = json.Marshal(StatusCancelled)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	got = string(gotBytes)
+	want = "\"cancelled\""
+	if got != want {
+		t.Errorf("expected %T %v, got %T %v", want, want, got, got)
+	}
+
 }
 
 func TestCanUnmarshalJSONToStatus(t *testing.T) {
@@ -252,6 +334,26 @@ func TestCanUnmarshalJSONToStatus(t *testing.T) {
 		t.Errorf("expected %v, got %v", want, got)
 	}
 
+	stBytes = []byte("\"queued\"")
+	err = json.Unmarshal(stBytes, &got)
+	want = StatusQueued
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	stBytes = []byte("\"cancelled\"")
+	err = json.Unmarshal(stBytes, &got)
+	want = StatusCancelled
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
 	// and invalid values should return error
 	stBytes = []byte("\"oops\"")
 	err = json.Unmarshal(stBytes, &got)
@@ -302,6 +404,15 @@ func TestCanChangeIntToHealth(t *testing.T) {
 		t.Errorf("expected %v, got %v", want, got)
 	}
 
+	got, err = HealthFromInt(4)
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	want = HealthUnknown
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
 	// and invalid values should return error
 	got, err = HealthFromInt(57)
 	if err == nil {
@@ -336,6 +447,11 @@ func TestCanChangeHealthToInt(t *testing.T) {
 		t.Errorf("expected %v, got %v", want, got)
 	}
 
+	got = IntFromHealth(HealthUnknown)
+	want = 4
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
 }
 
 func TestCanChangeStringToHealth(t *testing.T) {
@@ -378,6 +494,15 @@ func TestCanChangeStringToHealth(t *testing.T) {
 		t.Errorf("expected %v, got %v", want, got)
 	}
 
+	got, err = HealthFromString("unknown")
+	want = HealthUnknown
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
 	// and invalid values should return error
 	got, err = HealthFromString("oops")
 	if err == nil {
@@ -411,6 +536,12 @@ func TestCanChangeHealthToString(t *testing.T) {
 	if got != want {
 		t.Errorf("expected %v, got %v", want, got)
 	}
+
+	got = StringFromHealth(HealthUnknown)
+	want = "unknown"
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
 }
 
 func TestCanMarshalHealthToJSON(t *testing.T) {
@@ -458,6 +589,15 @@ func TestCanMarshalHealthToJSON(t *testing.T) {
 		t.Errorf("expected %T %v, got %T %v", want, want, got, got)
 	}
 
+	gotBytes, err = json.Marshal(HealthUnknown)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	got = string(gotBytes)
+	want = "\"unknown\""
+	if got != want {
+		t.Errorf("expected %T %v, got %T %v", want, want, got, got)
+	}
 }
 
 func TestCanUnmarshalJSONToHealth(t *testing.T) {
@@ -505,6 +645,16 @@ func TestCanUnmarshalJSONToHealth(t *testing.T) {
 		t.Errorf("expected %v, got %v", want, got)
 	}
 
+	stBytes = []byte("\"unknown\"")
+	err = json.Unmarshal(stBytes, &got)
+	want = HealthUnknown
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
 	// and invalid values should return error
 	stBytes = []byte("\"oops\"")
 	err = json.Unmarshal(stBytes, &got)
@@ -512,3 +662,158 @@ func TestCanUnmarshalJSONToHealth(t *testing.T) {
 		t.Errorf("expected non-nil error, got nil")
 	}
 }
+
+// ===== Scanner and Valuer tests =====
+
+func TestCanScanStatus(t *testing.T) {
+	tests := []struct {
+		src     interface{}
+		want    Status
+		isError bool
+	}{
+		{int64(2), StatusRunning, false},
+		{int64(5), StatusCancelled, false},
+		{[]byte("3"), StatusStopped, false},
+		{"4", StatusQueued, false},
+		// invalid values should fail and leave the value unchanged
+		{int64(57), StatusSame, true},
+		{[]byte("oops"), StatusSame, true},
+		{nil, StatusSame, true},
+		{1.5, StatusSame, true},
+	}
+
+	for _, tt := range tests {
+		var got Status
+		err := got.Scan(tt.src)
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("for %v: expected isError %v, got %v", tt.src, tt.isError, err)
+		}
+		if got != tt.want {
+			t.Errorf("for %v: expected %v, got %v", tt.src, tt.want, got)
+		}
+	}
+}
+
+func TestCanValueStatus(t *testing.T) {
+	got, err := StatusQueued.Value()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got != int64(4) {
+		t.Errorf("expected %v, got %v", int64(4), got)
+	}
+
+	// and invalid values should return error
+	_, err = Status(57).Value()
+	if err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+}
+
+func TestCanScanHealth(t *testing.T) {
+	tests := []struct {
+		src     interface{}
+		want    Health
+		isError bool
+	}{
+		{int64(1), HealthOK, false},
+		{int64(4), HealthUnknown, false},
+		{[]byte("3"), HealthError, false},
+		{"2", HealthDegraded, false},
+		// invalid values should fail and leave the value unchanged
+		{int64(57), HealthSame, true},
+		{[]byte("oops"), HealthSame, true},
+		{nil, HealthSame, true},
+		{1.5, HealthSame, true},
+	}
+
+	for _, tt := range tests {
+		var got Health
+		err := got.Scan(tt.src)
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("for %v: expected isError %v, got %v", tt.src, tt.isError, err)
+		}
+		if got != tt.want {
+			t.Errorf("for %v: expected %v, got %v", tt.src, tt.want, got)
+		}
+	}
+}
+
+func TestCanValueHealth(t *testing.T) {
+	got, err := HealthDegraded.Value()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got != int64(2) {
+		t.Errorf("expected %v, got %v", int64(2), got)
+	}
+
+	// and invalid values should return error
+	_, err = Health(57).Value()
+	if err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+}
+
+func TestCanFormatStatus(t *testing.T) {
+	tests := []struct {
+		in   Status
+		want string
+	}{
+		{StatusStartup, "startup"},
+		{StatusRunning, "running"},
+		{StatusCancelled, "cancelled"},
+	}
+
+	for _, tt := range tests {
+		got := fmt.Sprintf("%v", tt.in)
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestCanFormatHealth(t *testing.T) {
+	tests := []struct {
+		in   Health
+		want string
+	}{
+		{HealthOK, "ok"},
+		{HealthError, "error"},
+		{HealthUnknown, "unknown"},
+	}
+
+	for _, tt := range tests {
+		got := fmt.Sprintf("%v", tt.in)
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestIsValidStatusTransition(t *testing.T) {
+	tests := []struct {
+		from Status
+		to   Status
+		want bool
+	}{
+		{StatusQueued, StatusStartup, true},
+		{StatusStartup, StatusRunning, true},
+		{StatusRunning, StatusStopped, true},
+		{StatusRunning, StatusRunning, true},
+		{StatusRunning, StatusCancelled, true},
+		{StatusSame, StatusStopped, true},
+		{StatusStopped, StatusRunning, false},
+		{StatusStopped, StatusStartup, false},
+		{StatusCancelled, StatusRunning, false},
+		{StatusRunning, StatusQueued, false},
+		{StatusRunning, StatusSame, false},
+	}
+
+	for _, tt := range tests {
+		got := IsValidStatusTransition(tt.from, tt.to)
+		if tt.want != got {
+			t.Errorf("%v to %v: expected %v, got %v", tt.from, tt.to, tt.want, got)
+		}
+	}
+}
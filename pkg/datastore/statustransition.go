@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// ErrInvalidTransition is matched by errors.Is for every
+// *TransitionError.
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// TransitionError is returned when a status update is rejected
+// because the entity's current status cannot move to the requested
+// one, e.g. from stopped back to running.
+type TransitionError struct {
+	// Entity is the kind of entity being updated, such as "job" or
+	// "repo pull".
+	Entity string
+	// ID identifies the entity being updated.
+	ID string
+	// From is the entity's current status.
+	From Status
+	// To is the requested status.
+	To Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s with ID %s cannot move from status %s to %s", e.Entity, e.ID, e.From, e.To)
+}
+
+// Is reports whether target is ErrInvalidTransition.
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// statusTransitions lists, for each status, the other statuses that
+// an operation in that status may move to. Stopped and cancelled
+// operations are finished and may not move anywhere else.
+var statusTransitions = map[Status][]Status{
+	StatusQueued:  {StatusStartup, StatusRunning, StatusCancelled},
+	StatusStartup: {StatusRunning, StatusStopped, StatusCancelled},
+	StatusRunning: {StatusStopped, StatusCancelled},
+}
+
+// IsValidStatusTransition reports whether an operation may move from
+// status from to status to. An operation may always stay in its
+// current status, e.g. to update its health or output. An operation
+// whose status is StatusSame, i.e. unknown, may move to any status.
+func IsValidStatusTransition(from Status, to Status) bool {
+	if from == to || from == StatusSame {
+		return true
+	}
+	for _, st := range statusTransitions[from] {
+		if st == to {
+			return true
+		}
+	}
+	return false
+}
+
+// statusPredecessors returns the integer encodings of every status
+// that may move to status to, for use in an update's WHERE clause.
+func statusPredecessors(to Status) interface{} {
+	preds := []int64{}
+	for _, from := range []Status{StatusSame, StatusStartup, StatusRunning, StatusStopped, StatusQueued, StatusCancelled} {
+		if IsValidStatusTransition(from, to) {
+			preds = append(preds, int64(IntFromStatus(from)))
+		}
+	}
+	return pq.Array(preds)
+}
+
+// statusUpdateError returns the error for a status update of the row
+// with the given ID in the given table that affected no rows: a
+// *NotFoundError if the row does not exist, a *TransitionError if its
+// current status cannot move to status to, or otherwise a
+// *ConflictError since the row must have changed since the caller's
+// version was read or since the update was made.
+func (db *DB) statusUpdateError(table string, entity string, id interface{}, version uint32, to Status) error {
+	var from int
+	err := db.sqldb.QueryRow("SELECT COALESCE(status, 0) FROM peridot."+table+" WHERE id = $1", id).Scan(&from)
+	if err == sql.ErrNoRows {
+		return &NotFoundError{Entity: entity, ID: fmt.Sprint(id)}
+	}
+	if err != nil {
+		return err
+	}
+	if !IsValidStatusTransition(Status(from), to) {
+		return &TransitionError{Entity: entity, ID: fmt.Sprint(id), From: Status(from), To: to}
+	}
+	return &ConflictError{Entity: entity, ID: fmt.Sprint(id), Version: version}
+}
@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/swinslow/peridot-db/pkg/internal/jsontag"
+)
+
+// UnmarshalStrict converts a slice of bytes containing the JSON
+// encoding of an entity, such as a Job or RepoPull, into v, which
+// must be a pointer. Unlike json.Unmarshal, it returns a
+// *ValidationError if the JSON contains a field that v's type does
+// not define, or omits a field whose json tag does not include
+// omitempty. Nested objects are checked in the same way.
+//
+// It is intended for validating requests from API clients; internal
+// callers should continue to use json.Unmarshal, which is lenient.
+func UnmarshalStrict(data []byte, v interface{}) error {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return fmt.Errorf("UnmarshalStrict requires a non-nil pointer, got %T", v)
+	}
+
+	if err := checkStrictJSON(data, t.Elem(), ""); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+var (
+	strictTimeType       = reflect.TypeOf(time.Time{})
+	strictRawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// checkStrictJSON checks data against type t, recursing into struct
+// fields, slice elements and map values. path identifies data within
+// the top-level document, for error messages. Malformed JSON is left
+// for json.Unmarshal to report.
+func checkStrictJSON(data []byte, t reflect.Type, path string) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == strictTimeType || t == strictRawMessageType || bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil
+		}
+
+		known := map[string]bool{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name, omitEmpty := jsontag.Parse(f)
+			if name == "-" {
+				continue
+			}
+			known[name] = true
+
+			fieldPath := joinStrictPath(path, name)
+			raw, ok := fields[name]
+			if !ok {
+				if !omitEmpty {
+					return &ValidationError{Entity: strings.ToLower(t.Name()), Field: fieldPath, Reason: "missing required field"}
+				}
+				continue
+			}
+			if err := checkStrictJSON(raw, f.Type, fieldPath); err != nil {
+				return err
+			}
+		}
+
+		for name := range fields {
+			if !known[name] {
+				return &ValidationError{Entity: strings.ToLower(t.Name()), Field: joinStrictPath(path, name), Reason: "unknown field"}
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		var elems []json.RawMessage
+		if err := json.Unmarshal(data, &elems); err != nil {
+			return nil
+		}
+		for i, raw := range elems {
+			if err := checkStrictJSON(raw, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		var elems map[string]json.RawMessage
+		if err := json.Unmarshal(data, &elems); err != nil {
+			return nil
+		}
+		for k, raw := range elems {
+			if err := checkStrictJSON(raw, t.Elem(), joinStrictPath(path, k)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// joinStrictPath appends name to a dotted field path.
+func joinStrictPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
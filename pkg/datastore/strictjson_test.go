@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"testing"
+)
+
+func TestCanUnmarshalStrictJob(t *testing.T) {
+	j := &Job{}
+	js := []byte(`{"id":17, "repopull_id":3, "agent_id":8, "started_at":"2019-01-02T15:04:05Z", "status":"running", "health":"ok", "is_ready":true,
+	"config":{"codereader": {"primary": {"priorjob_id": 4}}}}`)
+
+	err := UnmarshalStrict(js, j)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if j.ID != 17 {
+		t.Errorf("expected %v, got %v", 17, j.ID)
+	}
+	if j.Status != StatusRunning {
+		t.Errorf("expected %v, got %v", StatusRunning, j.Status)
+	}
+	if j.Config.CodeReader["primary"].PriorJobID != 4 {
+		t.Errorf("expected %v, got %v", 4, j.Config.CodeReader["primary"].PriorJobID)
+	}
+}
+
+func TestShouldFailUnmarshalStrictWithInvalidFields(t *testing.T) {
+	tests := []struct {
+		js    string
+		field string
+	}{
+		// unknown top-level field
+		{`{"id":17, "repopull_id":3, "agent_id":8, "status":"running", "health":"ok", "is_ready":true, "oops":1}`, "oops"},
+		// missing required field
+		{`{"id":17, "repopull_id":3, "status":"running", "health":"ok", "is_ready":true}`, "agent_id"},
+		// unknown nested field
+		{`{"id":17, "repopull_id":3, "agent_id":8, "status":"running", "health":"ok", "is_ready":true,
+		"config":{"spdxreader": {"primary": {"prior_job": 4}}}}`, "config.spdxreader.primary.prior_job"},
+	}
+
+	for _, tt := range tests {
+		err := UnmarshalStrict([]byte(tt.js), &Job{})
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("for %s: expected *ValidationError, got %v", tt.js, err)
+			continue
+		}
+		if verr.Field != tt.field {
+			t.Errorf("expected field %v, got %v", tt.field, verr.Field)
+		}
+	}
+}
+
+func TestUnmarshalRemainsLenient(t *testing.T) {
+	// the same unknown field is accepted by UnmarshalJSON
+	rp := &RepoPull{}
+	js := []byte(`{"id":36, "branch":"master", "oops":1}`)
+
+	err := rp.UnmarshalJSON(js)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// but rejected by UnmarshalStrict
+	err = UnmarshalStrict(js, &RepoPull{})
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("expected *ValidationError, got %v", err)
+	}
+}
+
+func TestShouldFailUnmarshalStrictWithNonPointer(t *testing.T) {
+	err := UnmarshalStrict([]byte(`{}`), Job{})
+	if err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+}
@@ -4,7 +4,9 @@ package datastore
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // Subproject describes a subproject within peridot. A Subproject
@@ -14,42 +16,78 @@ type Subproject struct {
 	// ID is the unique ID for this subproject.
 	ID uint32 `json:"id"`
 	// ProjectID is the unique ID for this subproject's project.
-	ProjectID uint32 `json:"project_id"`
+	ProjectID ProjectID `json:"project_id"`
 	// Name is this subproject's short name. Typically it should be
 	// a single set of alphanumeric characters without spaces.
 	Name string `json:"name"`
 	// Fullname is this subproject's full, more descriptive name.
 	Fullname string `json:"fullname"`
+	// CreatedAt is when this subproject was added. It is set by the
+	// database.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// UpdatedAt is when this subproject was last changed. It is set
+	// by the database.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
 }
 
-// GetAllSubprojects returns a slice of all subprojects in the database.
-func (db *DB) GetAllSubprojects() ([]*Subproject, error) {
-	rows, err := db.sqldb.Query("SELECT id, project_id, name, fullname FROM peridot.subprojects ORDER BY id")
+// MarshalJSON converts the Subproject into a slice of bytes containing
+// its JSON encoding, leaving out created_at and updated_at if unset.
+func (sp Subproject) MarshalJSON() ([]byte, error) {
+	type subprojectAlias Subproject
+	return json.Marshal(struct {
+		subprojectAlias
+		CreatedAt *time.Time `json:"created_at,omitempty"`
+		UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	}{subprojectAlias: subprojectAlias(sp), CreatedAt: jsonTimePtr(sp.CreatedAt), UpdatedAt: jsonTimePtr(sp.UpdatedAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of a Subproject into the Subproject, leaving CreatedAt and UpdatedAt
+// as the zero time if they are missing or null.
+func (sp *Subproject) UnmarshalJSON(b []byte) error {
+	type subprojectAlias Subproject
+	aux := struct {
+		*subprojectAlias
+		CreatedAt *time.Time `json:"created_at"`
+		UpdatedAt *time.Time `json:"updated_at"`
+	}{subprojectAlias: (*subprojectAlias)(sp)}
+
+	err := json.Unmarshal(b, &aux)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
 
-	subprojects := []*Subproject{}
-	for rows.Next() {
-		sp := &Subproject{}
-		err := rows.Scan(&sp.ID, &sp.ProjectID, &sp.Name, &sp.Fullname)
-		if err != nil {
-			return nil, err
-		}
-		subprojects = append(subprojects, sp)
-	}
+	sp.CreatedAt = timeFromJSONPtr(aux.CreatedAt)
+	sp.UpdatedAt = timeFromJSONPtr(aux.UpdatedAt)
+	return nil
+}
 
-	if err = rows.Err(); err != nil {
+// Validate checks that the Subproject's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (sp *Subproject) Validate() error {
+	return requireNonEmpty("subproject", "name", sp.Name)
+}
+
+const subprojectColumns = "id, project_id, name, fullname, created_at, updated_at"
+
+// scanSubproject reads a Subproject from a row selecting
+// subprojectColumns.
+func scanSubproject(rs rowScanner) (*Subproject, error) {
+	sp := &Subproject{}
+	err := rs.Scan(&sp.ID, &sp.ProjectID, &sp.Name, &sp.Fullname, &sp.CreatedAt, &sp.UpdatedAt)
+	if err != nil {
 		return nil, err
 	}
-	return subprojects, nil
+	sp.CreatedAt = normalizeTime(sp.CreatedAt)
+	sp.UpdatedAt = normalizeTime(sp.UpdatedAt)
+	return sp, nil
 }
 
-// GetAllSubprojectsForProjectID returns a slice of all
-// subprojects in the database for the given project ID.
-func (db *DB) GetAllSubprojectsForProjectID(projectID uint32) ([]*Subproject, error) {
-	rows, err := db.sqldb.Query("SELECT id, project_id, name, fullname FROM peridot.subprojects WHERE project_id = $1 ORDER BY id", projectID)
+// querySubprojects runs a query selecting subprojectColumns and
+// returns the resulting subprojects.
+func (db *DB) querySubprojects(query string, args ...interface{}) ([]*Subproject, error) {
+	rows, err := db.sqldb.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -57,8 +95,7 @@ func (db *DB) GetAllSubprojectsForProjectID(projectID uint32) ([]*Subproject, er
 
 	subprojects := []*Subproject{}
 	for rows.Next() {
-		sp := &Subproject{}
-		err := rows.Scan(&sp.ID, &sp.ProjectID, &sp.Name, &sp.Fullname)
+		sp, err := scanSubproject(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -71,38 +108,86 @@ func (db *DB) GetAllSubprojectsForProjectID(projectID uint32) ([]*Subproject, er
 	return subprojects, nil
 }
 
+// GetAllSubprojects returns a slice of all subprojects in the database.
+func (db *DB) GetAllSubprojects() ([]*Subproject, error) {
+	return db.querySubprojects("SELECT " + subprojectColumns + " FROM peridot.subprojects WHERE " + liveSubprojectCondition + " ORDER BY id")
+}
+
+// CountAllSubprojects returns the number of subprojects in the
+// database.
+func (db *DB) CountAllSubprojects() (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.subprojects WHERE " + liveSubprojectCondition)
+}
+
+// GetAllSubprojectsForProjectID returns a slice of all
+// subprojects in the database for the given project ID.
+func (db *DB) GetAllSubprojectsForProjectID(projectID ProjectID) ([]*Subproject, error) {
+	return db.querySubprojects("SELECT "+subprojectColumns+" FROM peridot.subprojects WHERE project_id = $1 AND "+liveSubprojectCondition+" ORDER BY id", projectID)
+}
+
+// CountSubprojectsForProjectID returns the number of subprojects in
+// the Project with the given ID.
+func (db *DB) CountSubprojectsForProjectID(projectID ProjectID) (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.subprojects WHERE project_id = $1 AND "+liveSubprojectCondition, projectID)
+}
+
 // GetSubprojectByID returns the Subproject with the given ID, or nil
 // and an error if not found.
 func (db *DB) GetSubprojectByID(id uint32) (*Subproject, error) {
-	var sp Subproject
-	err := db.sqldb.QueryRow("SELECT id, project_id, name, fullname FROM peridot.subprojects WHERE id = $1", id).
-		Scan(&sp.ID, &sp.ProjectID, &sp.Name, &sp.Fullname)
+	sp, err := scanSubproject(db.sqldb.QueryRow("SELECT "+subprojectColumns+" FROM peridot.subprojects WHERE id = $1 AND "+liveSubprojectCondition, id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no subproject found with ID %v", id)
+		return nil, &NotFoundError{Entity: "subproject", ID: fmt.Sprint(id)}
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &sp, nil
+	return sp, nil
+}
+
+// ExistsSubproject reports whether a Subproject with the given
+// ID exists, without retrieving it.
+func (db *DB) ExistsSubproject(id uint32) (bool, error) {
+	return db.exists("SELECT EXISTS (SELECT 1 FROM peridot.subprojects WHERE id = $1 AND "+liveSubprojectCondition+")", id)
 }
 
 // AddSubproject adds a new subproject with the given short name and
 // full name, referencing the designated Project. It returns the new
 // subproject's ID on success or an error if failing.
-func (db *DB) AddSubproject(projectID uint32, name string, fullname string) (uint32, error) {
+func (db *DB) AddSubproject(projectID ProjectID, name string, fullname string) (uint32, error) {
+	sp := &Subproject{ProjectID: projectID, Name: name, Fullname: fullname}
+	if err := sp.Validate(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+
 	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("INSERT INTO peridot.subprojects(project_id, name, fullname) VALUES ($1, $2, $3) RETURNING id")
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.subprojects(project_id, name, fullname) VALUES ($1, $2, $3) RETURNING id")
 	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
 
-	var subprojectID uint32
-	err = stmt.QueryRow(projectID, name, fullname).Scan(&subprojectID)
+	err = stmt.QueryRow(projectID, name, fullname).Scan(&sp.ID)
 	if err != nil {
+		tx.Rollback()
+		return 0, translateConstraintError("subproject", err)
+	}
+
+	err = addOutboxEvent(tx, "subproject", sp.ID, AuditActionAdd, sp)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
 		return 0, err
 	}
-	return subprojectID, nil
+	return sp.ID, nil
 }
 
 // UpdateSubproject updates an existing Subproject with the
@@ -111,112 +196,38 @@ func (db *DB) AddSubproject(projectID uint32, name string, fullname string) (uin
 // remain unchanged. It returns nil on success or an error if
 // failing.
 func (db *DB) UpdateSubproject(id uint32, newName string, newFullname string) error {
-	var err error
-	var result sql.Result
-
 	// FIXME consider whether to move out into one-time-prepared statements
 	if newName != "" && newFullname != "" {
-		stmt, err := db.sqldb.Prepare("UPDATE peridot.subprojects SET name = $1, fullname = $2 WHERE id = $3")
-		if err != nil {
-			return err
-		}
-		result, err = stmt.Exec(newName, newFullname, id)
-
+		return db.execWithOutboxEvent("subproject", id, AuditActionUpdate, map[string]interface{}{"id": id, "name": newName, "fullname": newFullname},
+			"UPDATE peridot.subprojects SET name = $1, fullname = $2 WHERE id = $3", newName, newFullname, id)
 	} else if newName != "" {
-		stmt, err := db.sqldb.Prepare("UPDATE peridot.subprojects SET name = $1 WHERE id = $2")
-		if err != nil {
-			return err
-		}
-		result, err = stmt.Exec(newName, id)
-
+		return db.execWithOutboxEvent("subproject", id, AuditActionUpdate, map[string]interface{}{"id": id, "name": newName},
+			"UPDATE peridot.subprojects SET name = $1 WHERE id = $2", newName, id)
 	} else if newFullname != "" {
-		stmt, err := db.sqldb.Prepare("UPDATE peridot.subprojects SET fullname = $1 WHERE id = $2")
-		if err != nil {
-			return err
-		}
-		result, err = stmt.Exec(newFullname, id)
-
-	} else {
-		return fmt.Errorf("only empty strings passed to UpdateSubproject for id %v", id)
+		return db.execWithOutboxEvent("subproject", id, AuditActionUpdate, map[string]interface{}{"id": id, "fullname": newFullname},
+			"UPDATE peridot.subprojects SET fullname = $1 WHERE id = $2", newFullname, id)
 	}
 
-	// check error
-	if err != nil {
-		return err
-	}
-
-	// check that something was actually updated
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return fmt.Errorf("no subproject found with ID %v", id)
-	}
-
-	return nil
+	return fmt.Errorf("only empty strings passed to UpdateSubproject for id %v", id)
 }
 
 // UpdateSubprojectProjectID updates an existing Subproject
 // with the given ID, changing its corresponding Project iD.
 // It returns nil on success or an error if failing.
-func (db *DB) UpdateSubprojectProjectID(id uint32, newProjectID uint32) error {
-	var err error
-	var result sql.Result
-
+func (db *DB) UpdateSubprojectProjectID(id uint32, newProjectID ProjectID) error {
 	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("UPDATE peridot.subprojects SET project_id = $1 WHERE id = $2")
-	if err != nil {
-		return err
-	}
-
-	// run update command
-	result, err = stmt.Exec(newProjectID, id)
-	if err != nil {
-		return err
-	}
-
-	// check that something was actually updated
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return fmt.Errorf("no subproject found with ID %v", id)
-	}
-
-	return nil
+	return db.execWithOutboxEvent("subproject", id, AuditActionUpdate, map[string]interface{}{"id": id, "project_id": newProjectID},
+		"UPDATE peridot.subprojects SET project_id = $1 WHERE id = $2", newProjectID, id)
 }
 
 // DeleteSubproject deletes an existing Subproject with the
-// given ID. It returns nil on success or an error if failing.
+// given ID. If soft deletion is enabled, the subproject is only
+// marked as deleted; see SetSoftDelete. It returns nil on success
+// or an error if failing.
 func (db *DB) DeleteSubproject(id uint32) error {
-	var err error
-	var result sql.Result
-
 	// FIXME consider whether need to delete sub-elements first, or
 	// FIXME whether to set up sub-elements' schemas to delete on cascade
 
 	// FIXME consider whether to move out into one-time-prepared statement
-	stmt, err := db.sqldb.Prepare("DELETE FROM peridot.subprojects WHERE id = $1")
-	if err != nil {
-		return err
-	}
-	result, err = stmt.Exec(id)
-
-	// check error
-	if err != nil {
-		return err
-	}
-
-	// check that something was actually deleted
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return fmt.Errorf("no subproject found with ID %v", id)
-	}
-
-	return nil
+	return db.deleteRow("subproject", "subprojects", id)
 }
@@ -19,14 +19,14 @@ func TestShouldGetAllSubprojects(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "project_id", "name", "fullname"}).
-		AddRow(1, 1, "kubernetes", "Kubernetes").
-		AddRow(2, 1, "prometheus", "Prometheus").
-		AddRow(3, 2, "aai", "Active and Available Inventory (AAI)").
-		AddRow(4, 1, "grpc", "gRPC").
-		AddRow(5, 2, "sdnc", "Software Defined Network Controller (SDNC)").
-		AddRow(6, 3, "fabric", "Hyperledger Fabric")
-	mock.ExpectQuery("SELECT id, project_id, name, fullname FROM peridot.subprojects ORDER BY id").WillReturnRows(sentRows)
+	sentRows := sqlmock.NewRows([]string{"id", "project_id", "name", "fullname", "created_at", "updated_at"}).
+		AddRow(1, 1, "kubernetes", "Kubernetes", testCreatedAt, testUpdatedAt).
+		AddRow(2, 1, "prometheus", "Prometheus", testCreatedAt, testUpdatedAt).
+		AddRow(3, 2, "aai", "Active and Available Inventory (AAI)", testCreatedAt, testUpdatedAt).
+		AddRow(4, 1, "grpc", "gRPC", testCreatedAt, testUpdatedAt).
+		AddRow(5, 2, "sdnc", "Software Defined Network Controller (SDNC)", testCreatedAt, testUpdatedAt).
+		AddRow(6, 3, "fabric", "Hyperledger Fabric", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery("SELECT id, project_id, name, fullname, created_at, updated_at FROM peridot.subprojects WHERE " + liveSubprojectConditionRegex + " ORDER BY id").WillReturnRows(sentRows)
 
 	// run the tested function
 	gotRows, err := db.GetAllSubprojects()
@@ -81,11 +81,11 @@ func TestShouldGetAllSubprojectsForOneProject(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "project_id", "name", "fullname"}).
-		AddRow(1, 1, "kubernetes", "Kubernetes").
-		AddRow(2, 1, "prometheus", "Prometheus").
-		AddRow(4, 1, "grpc", "gRPC")
-	mock.ExpectQuery(`SELECT id, project_id, name, fullname FROM peridot.subprojects WHERE project_id = \$1 ORDER BY id`).
+	sentRows := sqlmock.NewRows([]string{"id", "project_id", "name", "fullname", "created_at", "updated_at"}).
+		AddRow(1, 1, "kubernetes", "Kubernetes", testCreatedAt, testUpdatedAt).
+		AddRow(2, 1, "prometheus", "Prometheus", testCreatedAt, testUpdatedAt).
+		AddRow(4, 1, "grpc", "gRPC", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, project_id, name, fullname, created_at, updated_at FROM peridot.subprojects WHERE project_id = \$1 AND ` + liveSubprojectConditionRegex + ` ORDER BY id`).
 		WillReturnRows(sentRows)
 
 	// run the tested function
@@ -141,9 +141,9 @@ func TestShouldGetSubprojectByID(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "project_id", "name", "fullname"}).
-		AddRow(2, 1, "prometheus", "Prometheus")
-	mock.ExpectQuery(`[SELECT id, project_id, name, fullname FROM peridot.subprojects WHERE id = \$1]`).
+	sentRows := sqlmock.NewRows([]string{"id", "project_id", "name", "fullname", "created_at", "updated_at"}).
+		AddRow(2, 1, "prometheus", "Prometheus", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`[SELECT id, project_id, name, fullname, created_at, updated_at FROM peridot.subprojects WHERE id = \$1]`).
 		WithArgs(2).
 		WillReturnRows(sentRows)
 
@@ -183,7 +183,7 @@ func TestShouldFailGetSubprojectByIDForUnknownID(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	mock.ExpectQuery(`[SELECT id, project_id, name, fullname FROM peridot.subprojects WHERE id = \$1]`).
+	mock.ExpectQuery(`[SELECT id, project_id, name, fullname, created_at, updated_at FROM peridot.subprojects WHERE id = \$1]`).
 		WithArgs(413).
 		WillReturnRows(sqlmock.NewRows([]string{}))
 
@@ -213,11 +213,15 @@ func TestShouldAddSubproject(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[INSERT INTO peridot.subprojects(project_id, name, fullname) VALUES (\$1, \$2, \$3) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "INSERT INTO peridot.subprojects"
 	mock.ExpectQuery(stmt).
 		WithArgs(1, "grpc", "gRPC").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	subprojectID, err := db.AddSubproject(1, "grpc", "gRPC")
@@ -247,11 +251,13 @@ func TestShouldFailAddSubprojectWithUnknownProjectID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[INSERT INTO peridot.subprojects(project_id, name, fullname) VALUES (\$1, \$2, \$3) RETURNING id]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "INSERT INTO peridot.subprojects"
 	mock.ExpectQuery(stmt).
 		WithArgs(17, "oops", "Unknown Project").
 		WillReturnError(fmt.Errorf("pq: insert or update on table \"peridot.subprojects\" violates foreign key constraint \"peridot.subprojects_project_id_fkey\""))
+	mock.ExpectRollback()
 
 	// run the tested function
 	_, err = db.AddSubproject(17, "oops", "Unknown Project")
@@ -276,11 +282,15 @@ func TestShouldUpdateSubprojectNameAndFullname(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.subprojects SET name = \$1, fullname = \$2 WHERE id = \$3]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.subprojects"
 	mock.ExpectExec(stmt).
 		WithArgs("mysubprj", "My Subproject", 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.UpdateSubproject(1, "mysubprj", "My Subproject")
@@ -305,11 +315,15 @@ func TestShouldUpdateSubprojectNameOnly(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.subprojects SET name = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.subprojects"
 	mock.ExpectExec(stmt).
 		WithArgs("mysubprj", 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.UpdateSubproject(1, "mysubprj", "")
@@ -334,11 +348,15 @@ func TestShouldUpdateSubprojectFullnameOnly(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.subprojects SET fullname = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.subprojects"
 	mock.ExpectExec(stmt).
 		WithArgs("My Subproject", 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.UpdateSubproject(1, "", "My Subproject")
@@ -385,11 +403,13 @@ func TestShouldFailUpdateSubprojectWithUnknownID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.subprojects SET name = \$1, fullname = \$2 WHERE id = \$3]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.subprojects"
 	mock.ExpectExec(stmt).
 		WithArgs("oops", "wrong ID", 413).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	// run the tested function with an unknown project ID number
 	err = db.UpdateSubproject(413, "oops", "wrong ID")
@@ -414,11 +434,15 @@ func TestShouldUpdateSubprojectProjectID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.subprojects SET project_id = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.subprojects"
 	mock.ExpectExec(stmt).
 		WithArgs(3, 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.UpdateSubprojectProjectID(1, 3)
@@ -443,11 +467,13 @@ func TestShouldFailUpdateSubprojectProjectIDToUnknownProjectID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.subprojects SET project_id = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.subprojects"
 	mock.ExpectExec(stmt).
 		WithArgs(17, 1).
 		WillReturnError(fmt.Errorf("pq: insert or update on table \"peridot.subprojects\" violates foreign key constraint \"peridot.subprojects_project_id_fkey\""))
+	mock.ExpectRollback()
 
 	// run the tested function
 	err = db.UpdateSubprojectProjectID(1, 17)
@@ -472,11 +498,13 @@ func TestShouldFailUpdateSubprojectProjectIDWithUnknownSubprojectID(t *testing.T
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[UPDATE peridot.subprojects SET project_id = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "UPDATE peridot.subprojects"
 	mock.ExpectExec(stmt).
 		WithArgs(413, 1).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	// run the tested function with an unknown project ID number
 	err = db.UpdateSubprojectProjectID(1, 413)
@@ -501,11 +529,15 @@ func TestShouldDeleteSubproject(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[DELETE FROM peridot.subprojects WHERE id = \$1]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "DELETE FROM peridot.subprojects"
 	mock.ExpectExec(stmt).
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
 	err = db.DeleteSubproject(1)
@@ -530,11 +562,13 @@ func TestShouldFailDeleteSubprojectWithUnknownID(t *testing.T) {
 	db := DB{sqldb: sqldb}
 
 	regexStmt := `[DELETE FROM peridot.subprojects WHERE id = \$1]`
+	mock.ExpectBegin()
 	mock.ExpectPrepare(regexStmt)
 	stmt := "DELETE FROM peridot.subprojects"
 	mock.ExpectExec(stmt).
 		WithArgs(413).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	// run the tested function
 	err = db.DeleteSubproject(413)
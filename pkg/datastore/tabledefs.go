@@ -7,7 +7,18 @@ import "os"
 func createTables(db *DB) error {
 	createFuncs := []func(db *DB) error{
 		createTableUsersAndAddInitialAdminUser,
+		createSequenceUserAutoID,
+		createFunctionSetUpdatedAt,
+		createTableUserTokens,
+		createTableTokenRateLimits,
+		createTableUserIdentities,
+		createTableUserPreferences,
+		createTableInvitations,
+		createTableOrganizations,
+		createTableOrganizationMembers,
 		createTableProjects,
+		createTableProjectAccess,
+		createTableProjectPipelines,
 		createTableSubprojects,
 		createTableRepos,
 		createTableRepoBranches,
@@ -15,9 +26,43 @@ func createTables(db *DB) error {
 		createTableFileHashes,
 		createTableFileInstances,
 		createTableAgents,
+		createTableAgentHealthEvents,
 		createTableJobs,
 		createTableJobPathConfigs,
 		createTableJobPriorIDs,
+		createTableAuditLog,
+		createTableLicenses,
+		createTableFindings,
+		createTableFindingLicenses,
+		createTableConclusions,
+		createTableCopyrights,
+		createTableComponents,
+		createTableRelationships,
+		createTablePolicies,
+		createTablePolicyVersions,
+		createTablePolicyResults,
+		createTableScanDeltas,
+		createTableNoticeDocuments,
+		createTableWebhooks,
+		createTableWebhookDeliveries,
+		createTableNotifications,
+		createTableOutboxEvents,
+		createTableComments,
+		createTableReviews,
+		createTableReports,
+		createTableIssueLinks,
+		createTableLabels,
+		createTableMetricsSnapshots,
+		createTableRetentionPolicies,
+		createTableQuotas,
+		createTableObligations,
+		createTableFindingOverrides,
+		createTableSnippetMatches,
+		createTableSBOMImports,
+		createTableSBOMImportLinks,
+		createTableAttestations,
+		addTimestampColumns,
+		addSoftDeleteColumns,
 	}
 
 	for _, f := range createFuncs {
@@ -32,32 +77,305 @@ func createTables(db *DB) error {
 
 // createTableUsersAndAddInitialAdminUser creates the users table
 // if it does not already exist. Also, if there are not yet any
-// users, AND the environment variable INITIALADMINGITHUB is set,
-// then it creates an initial admin user with ID 1 and the Github
-// user name specified in that variable.
+// users, AND an initial admin GitHub user name was given in the
+// Config or the environment variable INITIALADMINGITHUB is set,
+// then it creates an initial admin user with ID 1 and that Github
+// user name.
 func createTableUsersAndAddInitialAdminUser(db *DB) error {
 	_, err := db.sqldb.Exec(`
 		CREATE TABLE IF NOT EXISTS peridot.users (
 			id INTEGER NOT NULL PRIMARY KEY,
 			github TEXT NOT NULL,
 			name TEXT NOT NULL,
-			access_level INTEGER NOT NULL
+			email TEXT,
+			access_level INTEGER NOT NULL,
+			kind INTEGER NOT NULL DEFAULT 0,
+			last_login_at TIMESTAMP WITH TIME ZONE,
+			login_count INTEGER NOT NULL DEFAULT 0,
+			avatar_url TEXT NOT NULL DEFAULT '',
+			pronouns TEXT NOT NULL DEFAULT '',
+			title TEXT NOT NULL DEFAULT '',
+			organization TEXT NOT NULL DEFAULT ''
 		)
 	`)
 	if err != nil {
 		return err
 	}
 
-	// if there are no users yet, and if INITIALADMINGITHUB env var
-	// is also set, we'll create an initial administrative user
+	err = addColumnsIfMissing(db, "users",
+		"email TEXT",
+		"kind INTEGER NOT NULL DEFAULT 0",
+		"last_login_at TIMESTAMP WITH TIME ZONE",
+		"login_count INTEGER NOT NULL DEFAULT 0",
+		"avatar_url TEXT NOT NULL DEFAULT ''",
+		"pronouns TEXT NOT NULL DEFAULT ''",
+		"title TEXT NOT NULL DEFAULT ''",
+		"organization TEXT NOT NULL DEFAULT ''",
+	)
+	if err != nil {
+		return err
+	}
+
+	// if there are no users yet, and if an initial admin is
+	// configured, we'll create an initial administrative user
 	// with ID 1
 	users, err := db.GetAllUsers()
 	if err == nil && len(users) == 0 {
-		INITIALADMINGITHUB := os.Getenv("INITIALADMINGITHUB")
+		INITIALADMINGITHUB := db.initialAdminGithub
+		if INITIALADMINGITHUB == "" {
+			INITIALADMINGITHUB = os.Getenv(EnvInitialAdminGithub)
+		}
 		if INITIALADMINGITHUB != "" {
-			err = db.AddUser(1, "Admin", INITIALADMINGITHUB, AccessAdmin)
+			err = db.AddUser(1, "Admin", INITIALADMINGITHUB, "", AccessAdmin)
+		}
+	}
+	return err
+}
+
+// createSequenceUserAutoID creates the user_auto_id_seq sequence
+// used by AddUserAutoID if it does not already exist. Its range is
+// kept separate from caller-supplied (Github) user IDs, though it
+// may include IDs of users added before the range was reserved.
+func createSequenceUserAutoID(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE SEQUENCE IF NOT EXISTS peridot.user_auto_id_seq
+			AS INTEGER
+			MINVALUE 2000000000
+			MAXVALUE 2147483647
+			START WITH 2000000000
+			NO CYCLE
+	`)
+	return err
+}
+
+// createFunctionSetUpdatedAt creates or replaces the trigger
+// function that stamps a row's updated_at column whenever the row is
+// updated, so that every UPDATE keeps it current without having to
+// set it explicitly.
+func createFunctionSetUpdatedAt(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE OR REPLACE FUNCTION peridot.set_updated_at() RETURNS trigger AS $$
+		BEGIN
+			NEW.updated_at = clock_timestamp();
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql
+	`)
+	return err
+}
+
+// timestampedTables lists every table in the peridot schema, all of
+// which are given created_at and updated_at columns by
+// addTimestampColumns.
+var timestampedTables = []string{
+	"users", "user_tokens", "token_rate_limits", "user_identities", "user_preferences", "invitations",
+	"organizations", "organization_members", "projects", "project_access", "subprojects",
+	"repos", "repo_branches", "repo_pulls", "file_hashes", "file_instances",
+	"agents", "agent_health_events", "jobs", "jobpathconfigs", "jobpriorids", "audit_log",
+	"licenses", "findings", "finding_licenses", "conclusions", "copyrights", "components", "relationships",
+	"policies", "policy_versions", "policy_results", "scan_deltas", "notice_documents",
+	"webhooks", "webhook_deliveries", "notifications", "outbox_events", "comments", "reviews", "reports",
+	"issue_links", "labels", "metrics_snapshots", "retention_policies", "quotas", "obligations",
+	"finding_overrides", "snippet_matches", "sbom_imports", "sbom_import_links",
+	"attestations",
+}
+
+// addTimestampColumns adds created_at and updated_at columns to each
+// of timestampedTables that does not already have them, defaulting
+// to the time the row is added, and attaches the set_updated_at
+// trigger to keep updated_at current. Tables that already record a
+// created_at of their own keep it. Since the columns are added with
+// ADD COLUMN IF NOT EXISTS, this also brings tables created by
+// earlier versions of peridot up to date; their existing rows are
+// stamped with the time of the upgrade. Other columns added since
+// those versions are added by each table's create function, with
+// addColumnsIfMissing.
+func addTimestampColumns(db *DB) error {
+	for _, table := range timestampedTables {
+		_, err := db.sqldb.Exec(`
+			ALTER TABLE peridot.` + table + `
+			ADD COLUMN IF NOT EXISTS created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+			ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+		`)
+		if err != nil {
+			return err
+		}
+
+		err = createTriggerSetUpdatedAt(db, table)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// softDeleteIndexes lists, for each table that supports soft
+// deletion, the name of the unique index that used to cover all of
+// its rows and the columns whose values must be unique among the rows
+// that have not been soft-deleted.
+var softDeleteIndexes = []struct {
+	table    string
+	oldIndex string
+	columns  string
+}{
+	{"projects", "projects_name", "name"},
+	{"subprojects", "subprojects_project_id_name", "project_id, name"},
+	{"repos", "repos_subproject_id_name", "subproject_id, name"},
+}
+
+// addSoftDeleteColumns adds a deleted_at column to each table that
+// supports soft deletion, if it does not already have one, and
+// replaces its unique name index with one that ignores soft-deleted
+// rows, so that a name can be reused once its previous owner has
+// been deleted. It also indexes the soft-deleted rows by deletion
+// time for PurgeDeletedBefore.
+func addSoftDeleteColumns(db *DB) error {
+	for _, idx := range softDeleteIndexes {
+		_, err := db.sqldb.Exec("ALTER TABLE peridot." + idx.table + " ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE")
+		if err != nil {
+			return err
+		}
+
+		_, err = db.sqldb.Exec("DROP INDEX IF EXISTS peridot." + idx.oldIndex)
+		if err != nil {
+			return err
+		}
+
+		_, err = db.sqldb.Exec(`
+			CREATE UNIQUE INDEX IF NOT EXISTS ` + idx.oldIndex + `_live
+			ON peridot.` + idx.table + ` (` + idx.columns + `)
+			WHERE deleted_at IS NULL
+		`)
+		if err != nil {
+			return err
+		}
+
+		_, err = db.sqldb.Exec(`
+			CREATE INDEX IF NOT EXISTS ` + idx.table + `_deleted_at
+			ON peridot.` + idx.table + ` (deleted_at)
+			WHERE deleted_at IS NOT NULL
+		`)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumnsIfMissing adds each of the given column definitions to
+// the given table unless it already has a column of that name, so
+// that a table created by an earlier version of peridot gains the
+// columns added since. It must be called before anything that refers
+// to those columns, such as an index on them.
+func addColumnsIfMissing(db *DB, table string, columns ...string) error {
+	for _, col := range columns {
+		_, err := db.sqldb.Exec("ALTER TABLE peridot." + table + " ADD COLUMN IF NOT EXISTS " + col)
+		if err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// createTriggerSetUpdatedAt attaches peridot.set_updated_at to the
+// given table, replacing the trigger if it already exists.
+func createTriggerSetUpdatedAt(db *DB, table string) error {
+	_, err := db.sqldb.Exec("DROP TRIGGER IF EXISTS " + table + "_set_updated_at ON peridot." + table)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE TRIGGER ` + table + `_set_updated_at
+		BEFORE UPDATE ON peridot.` + table + `
+		FOR EACH ROW EXECUTE PROCEDURE peridot.set_updated_at()
+	`)
+	return err
+}
+
+// createTableUserTokens creates the user_tokens table if it
+// does not already exist.
+func createTableUserTokens(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.user_tokens (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			scopes TEXT[] NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			expires_at TIMESTAMP WITH TIME ZONE,
+			last_used_at TIMESTAMP WITH TIME ZONE,
+			FOREIGN KEY (user_id) REFERENCES peridot.users (id) ON DELETE CASCADE
+		)
+	`)
+	return err
+}
+
+// createTableTokenRateLimits creates the token_rate_limits table
+// if it does not already exist.
+func createTableTokenRateLimits(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.token_rate_limits (
+			token_id INTEGER NOT NULL,
+			window_start TIMESTAMP WITH TIME ZONE NOT NULL,
+			count INTEGER NOT NULL,
+			PRIMARY KEY (token_id, window_start),
+			FOREIGN KEY (token_id) REFERENCES peridot.user_tokens (id) ON DELETE CASCADE
+		)
+	`)
+	return err
+}
+
+// createTableUserIdentities creates the user_identities table
+// if it does not already exist.
+func createTableUserIdentities(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.user_identities (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			provider TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			email TEXT,
+			UNIQUE (provider, subject),
+			FOREIGN KEY (user_id) REFERENCES peridot.users (id) ON DELETE CASCADE
+		)
+	`)
+	return err
+}
+
+// createTableUserPreferences creates the user_preferences table
+// if it does not already exist.
+func createTableUserPreferences(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.user_preferences (
+			user_id INTEGER NOT NULL,
+			key TEXT NOT NULL,
+			value JSONB NOT NULL,
+			PRIMARY KEY (user_id, key),
+			FOREIGN KEY (user_id) REFERENCES peridot.users (id) ON DELETE CASCADE
+		)
+	`)
+	return err
+}
+
+// createTableInvitations creates the invitations table if it
+// does not already exist. inviter_id and accepted_user_id are not
+// foreign keys, so that invitations survive deleting a user.
+func createTableInvitations(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.invitations (
+			id SERIAL PRIMARY KEY,
+			email TEXT,
+			github TEXT,
+			access_level INTEGER NOT NULL,
+			inviter_id INTEGER NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			accepted_at TIMESTAMP WITH TIME ZONE,
+			accepted_user_id INTEGER
+		)
+	`)
 	return err
 }
 
@@ -68,7 +386,54 @@ func createTableProjects(db *DB) error {
 		CREATE TABLE IF NOT EXISTS peridot.projects (
 			id SERIAL PRIMARY KEY,
 			name TEXT NOT NULL,
-			fullname TEXT NOT NULL
+			fullname TEXT NOT NULL,
+			org_id INTEGER,
+			deleted_at TIMESTAMP WITH TIME ZONE,
+			FOREIGN KEY (org_id) REFERENCES peridot.organizations (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	err = addColumnsIfMissing(db, "projects",
+		"org_id INTEGER REFERENCES peridot.organizations (id) ON DELETE CASCADE",
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS projects_org_id
+		ON peridot.projects (org_id)
+	`)
+	return err
+}
+
+// createTableProjectAccess creates the project_access table
+// if it does not already exist.
+func createTableProjectAccess(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.project_access (
+			user_id INTEGER NOT NULL,
+			project_id INTEGER NOT NULL,
+			access_level INTEGER NOT NULL,
+			PRIMARY KEY (user_id, project_id),
+			FOREIGN KEY (user_id) REFERENCES peridot.users (id) ON DELETE CASCADE,
+			FOREIGN KEY (project_id) REFERENCES peridot.projects (id) ON DELETE CASCADE
+		)
+	`)
+	return err
+}
+
+// createTableProjectPipelines creates the project_pipelines table
+// if it does not already exist.
+func createTableProjectPipelines(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.project_pipelines (
+			project_id INTEGER PRIMARY KEY,
+			pipeline JSONB NOT NULL,
+			FOREIGN KEY (project_id) REFERENCES peridot.projects (id) ON DELETE CASCADE
 		)
 	`)
 	return err
@@ -83,6 +448,7 @@ func createTableSubprojects(db *DB) error {
 			project_id INTEGER NOT NULL,
 			name TEXT NOT NULL,
 			fullname TEXT NOT NULL,
+			deleted_at TIMESTAMP WITH TIME ZONE,
 			FOREIGN KEY (project_id) REFERENCES peridot.projects (id) ON DELETE CASCADE
 		)
 	`)
@@ -98,10 +464,18 @@ func createTableRepos(db *DB) error {
 			subproject_id INTEGER NOT NULL,
 			name TEXT NOT NULL,
 			address TEXT NOT NULL,
+			external_uuid UUID UNIQUE,
+			deleted_at TIMESTAMP WITH TIME ZONE,
 			FOREIGN KEY (subproject_id) REFERENCES peridot.subprojects (id) ON DELETE CASCADE
 		)
 	`)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return addColumnsIfMissing(db, "repos",
+		"external_uuid UUID UNIQUE",
+	)
 }
 
 // createTableRepoBranches creates the repo_branches table
@@ -134,9 +508,27 @@ func createTableRepoPulls(db *DB) error {
 			commit TEXT,
 			tag TEXT,
 			spdx_id TEXT,
+			external_uuid UUID UNIQUE,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT clock_timestamp(),
 			FOREIGN KEY (repo_id, branch) REFERENCES peridot.repo_branches (repo_id, branch) ON DELETE CASCADE
 		)
 	`)
+	if err != nil {
+		return err
+	}
+
+	err = addColumnsIfMissing(db, "repo_pulls",
+		"external_uuid UUID UNIQUE",
+		"updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT clock_timestamp()",
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS repo_pulls_updated_at_id
+		ON peridot.repo_pulls (updated_at, id)
+	`)
 	return err
 }
 
@@ -166,6 +558,14 @@ func createTableFileInstances(db *DB) error {
 			FOREIGN KEY (filehash_id) REFERENCES peridot.file_hashes (id) ON DELETE CASCADE
 		)
 	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS file_instances_repopull_id
+		ON peridot.file_instances (repopull_id)
+	`)
 	return err
 }
 
@@ -182,9 +582,42 @@ func createTableAgents(db *DB) error {
 			is_codereader BOOLEAN,
 			is_spdxreader BOOLEAN,
 			is_codewriter BOOLEAN,
-			is_spdxwriter BOOLEAN
+			is_spdxwriter BOOLEAN,
+			health INTEGER NOT NULL DEFAULT 0,
+			version INTEGER NOT NULL DEFAULT 1
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	return addColumnsIfMissing(db, "agents",
+		"health INTEGER NOT NULL DEFAULT 0",
+		"version INTEGER NOT NULL DEFAULT 1",
+	)
+}
+
+// createTableAgentHealthEvents creates the agent_health_events
+// table if it does not already exist.
+func createTableAgentHealthEvents(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.agent_health_events (
+			id SERIAL PRIMARY KEY,
+			agent_id INTEGER NOT NULL,
+			health INTEGER NOT NULL,
+			output TEXT,
+			recorded_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			FOREIGN KEY (agent_id) REFERENCES peridot.agents (id) ON DELETE CASCADE
 		)
 	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS agent_health_events_agent_id_recorded_at
+		ON peridot.agent_health_events (agent_id, recorded_at)
+	`)
 	return err
 }
 
@@ -202,10 +635,46 @@ func createTableJobs(db *DB) error {
 			health INTEGER,
 			output TEXT,
 			is_ready BOOLEAN,
+			version INTEGER NOT NULL DEFAULT 1,
+			external_uuid UUID UNIQUE,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT clock_timestamp(),
 			FOREIGN KEY (repopull_id) REFERENCES peridot.repo_pulls (id) ON DELETE CASCADE,
 			FOREIGN KEY (agent_id) REFERENCES peridot.agents (id) ON DELETE CASCADE
 		)
 	`)
+	if err != nil {
+		return err
+	}
+
+	err = addColumnsIfMissing(db, "jobs",
+		"version INTEGER NOT NULL DEFAULT 1",
+		"external_uuid UUID UNIQUE",
+		"updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT clock_timestamp()",
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS jobs_status_health_is_ready
+		ON peridot.jobs (status, health, is_ready)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS jobs_started_at_id
+		ON peridot.jobs (COALESCE(started_at, '0001-01-01 00:00:00+00'), id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS jobs_updated_at_id
+		ON peridot.jobs (updated_at, id)
+	`)
 	return err
 }
 
@@ -239,5 +708,936 @@ func createTableJobPriorIDs(db *DB) error {
 			UNIQUE (job_id, priorjob_id)
 		)
 	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS jobpriorids_priorjob_id
+		ON peridot.jobpriorids (priorjob_id)
+	`)
+	return err
+}
+
+// createTableAuditLog creates the audit_log table if it
+// does not already exist. actor_id is deliberately not a
+// foreign key, so that history survives deleting a user.
+func createTableAuditLog(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.audit_log (
+			id BIGSERIAL PRIMARY KEY,
+			actor_id INTEGER NOT NULL,
+			entity TEXT NOT NULL,
+			entity_id TEXT NOT NULL,
+			action TEXT NOT NULL,
+			before JSONB,
+			after JSONB,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS audit_log_entity_created_at
+		ON peridot.audit_log (entity, entity_id, created_at)
+	`)
+	return err
+}
+
+// createTableLicenses creates the licenses table if it does not
+// already exist, and seeds it with any licenses from the SPDX
+// License List that it does not already contain.
+func createTableLicenses(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.licenses (
+			id SERIAL PRIMARY KEY,
+			spdx_id TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL,
+			is_osi_approved BOOLEAN NOT NULL DEFAULT false,
+			is_deprecated BOOLEAN NOT NULL DEFAULT false,
+			is_custom BOOLEAN NOT NULL DEFAULT false
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// look up licenses case-insensitively, as SPDX expressions do
+	_, err = db.sqldb.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS licenses_spdx_id_lower
+		ON peridot.licenses (lower(spdx_id))
+	`)
+	if err != nil {
+		return err
+	}
+
+	return seedLicenses(db)
+}
+
+// createTableFindings creates the findings table if it does not
+// already exist.
+func createTableFindings(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.findings (
+			id BIGSERIAL PRIMARY KEY,
+			fileinstance_id INTEGER NOT NULL,
+			job_id INTEGER NOT NULL,
+			license_expression TEXT NOT NULL,
+			score DOUBLE PRECISION NOT NULL,
+			start_line INTEGER NOT NULL DEFAULT 0,
+			end_line INTEGER NOT NULL DEFAULT 0,
+			snippet TEXT NOT NULL DEFAULT '',
+			FOREIGN KEY (fileinstance_id) REFERENCES peridot.file_instances (id) ON DELETE CASCADE,
+			FOREIGN KEY (job_id) REFERENCES peridot.jobs (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS findings_fileinstance_id
+		ON peridot.findings (fileinstance_id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS findings_job_id
+		ON peridot.findings (job_id)
+	`)
+	return err
+}
+
+// createTableFindingLicenses creates the finding_licenses table,
+// linking findings to the licenses in their expressions, if it does
+// not already exist.
+func createTableFindingLicenses(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.finding_licenses (
+			finding_id BIGINT NOT NULL,
+			license_id INTEGER NOT NULL,
+			PRIMARY KEY (finding_id, license_id),
+			FOREIGN KEY (finding_id) REFERENCES peridot.findings (id) ON DELETE CASCADE,
+			FOREIGN KEY (license_id) REFERENCES peridot.licenses (id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS finding_licenses_license_id
+		ON peridot.finding_licenses (license_id)
+	`)
+	return err
+}
+
+// createTableConclusions creates the conclusions table if it does
+// not already exist.
+func createTableConclusions(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.conclusions (
+			id BIGSERIAL PRIMARY KEY,
+			filehash_id INTEGER NOT NULL UNIQUE,
+			license_expression TEXT NOT NULL,
+			concluded_by INTEGER,
+			concluded_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			justification TEXT NOT NULL,
+			FOREIGN KEY (filehash_id) REFERENCES peridot.file_hashes (id) ON DELETE CASCADE,
+			FOREIGN KEY (concluded_by) REFERENCES peridot.users (id) ON DELETE SET NULL
+		)
+	`)
+	return err
+}
+
+// createTableCopyrights creates the copyrights table if it does not
+// already exist.
+func createTableCopyrights(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.copyrights (
+			id BIGSERIAL PRIMARY KEY,
+			fileinstance_id INTEGER NOT NULL,
+			job_id INTEGER NOT NULL,
+			text TEXT NOT NULL,
+			holder TEXT NOT NULL DEFAULT '',
+			years TEXT NOT NULL DEFAULT '',
+			FOREIGN KEY (fileinstance_id) REFERENCES peridot.file_instances (id) ON DELETE CASCADE,
+			FOREIGN KEY (job_id) REFERENCES peridot.jobs (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS copyrights_fileinstance_id
+		ON peridot.copyrights (fileinstance_id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS copyrights_job_id
+		ON peridot.copyrights (job_id)
+	`)
+	return err
+}
+
+// createTableComponents creates the components table if it does not
+// already exist.
+func createTableComponents(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.components (
+			id BIGSERIAL PRIMARY KEY,
+			repopull_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			version TEXT NOT NULL DEFAULT '',
+			purl TEXT NOT NULL DEFAULT '',
+			supplier TEXT NOT NULL DEFAULT '',
+			FOREIGN KEY (repopull_id) REFERENCES peridot.repo_pulls (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS components_repopull_id
+		ON peridot.components (repopull_id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS components_purl
+		ON peridot.components (purl text_pattern_ops)
+	`)
+	return err
+}
+
+// createTableRelationships creates the relationships table if it
+// does not already exist.
+func createTableRelationships(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.relationships (
+			id BIGSERIAL PRIMARY KEY,
+			from_component_id BIGINT NOT NULL,
+			to_component_id BIGINT NOT NULL,
+			type INTEGER NOT NULL,
+			UNIQUE (from_component_id, to_component_id, type),
+			FOREIGN KEY (from_component_id) REFERENCES peridot.components (id) ON DELETE CASCADE,
+			FOREIGN KEY (to_component_id) REFERENCES peridot.components (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS relationships_to_component_id
+		ON peridot.relationships (to_component_id)
+	`)
+	return err
+}
+
+// createTablePolicies creates the policies table if it does not
+// already exist. A NULL project_id means the policy is global.
+func createTablePolicies(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.policies (
+			id SERIAL PRIMARY KEY,
+			project_id INTEGER,
+			name TEXT NOT NULL,
+			current_version INTEGER NOT NULL,
+			FOREIGN KEY (project_id) REFERENCES peridot.projects (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS policies_scope_name
+		ON peridot.policies (COALESCE(project_id, 0), name)
+	`)
+	return err
+}
+
+// createTablePolicyVersions creates the policy_versions table if it
+// does not already exist.
+func createTablePolicyVersions(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.policy_versions (
+			policy_id INTEGER NOT NULL,
+			version INTEGER NOT NULL,
+			rules JSONB NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_by INTEGER,
+			PRIMARY KEY (policy_id, version),
+			FOREIGN KEY (policy_id) REFERENCES peridot.policies (id) ON DELETE CASCADE,
+			FOREIGN KEY (created_by) REFERENCES peridot.users (id) ON DELETE SET NULL
+		)
+	`)
+	return err
+}
+
+// createTablePolicyResults creates the policy_results table if it
+// does not already exist.
+func createTablePolicyResults(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.policy_results (
+			id BIGSERIAL PRIMARY KEY,
+			policy_id INTEGER NOT NULL,
+			policy_version INTEGER NOT NULL,
+			repopull_id INTEGER NOT NULL,
+			job_id INTEGER,
+			passed BOOLEAN NOT NULL,
+			violations JSONB,
+			evaluated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			FOREIGN KEY (policy_id, policy_version) REFERENCES peridot.policy_versions (policy_id, version) ON DELETE CASCADE,
+			FOREIGN KEY (repopull_id) REFERENCES peridot.repo_pulls (id) ON DELETE CASCADE,
+			FOREIGN KEY (job_id) REFERENCES peridot.jobs (id) ON DELETE SET NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS policy_results_repopull_id_policy_id
+		ON peridot.policy_results (repopull_id, policy_id, evaluated_at)
+	`)
+	return err
+}
+
+// createTableScanDeltas creates the scan_deltas table if it does not
+// already exist.
+func createTableScanDeltas(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.scan_deltas (
+			id BIGSERIAL PRIMARY KEY,
+			base_repopull_id INTEGER NOT NULL,
+			head_repopull_id INTEGER NOT NULL,
+			job_id INTEGER NOT NULL,
+			new_finding_ids BIGINT[] NOT NULL,
+			resolved_finding_ids BIGINT[] NOT NULL,
+			new_license_ids INTEGER[] NOT NULL,
+			computed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			UNIQUE (base_repopull_id, head_repopull_id),
+			FOREIGN KEY (base_repopull_id) REFERENCES peridot.repo_pulls (id) ON DELETE CASCADE,
+			FOREIGN KEY (head_repopull_id) REFERENCES peridot.repo_pulls (id) ON DELETE CASCADE,
+			FOREIGN KEY (job_id) REFERENCES peridot.jobs (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS scan_deltas_head_repopull_id
+		ON peridot.scan_deltas (head_repopull_id)
+	`)
+	return err
+}
+
+// createTableNoticeDocuments creates the notice_documents table if
+// it does not already exist.
+func createTableNoticeDocuments(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.notice_documents (
+			id BIGSERIAL PRIMARY KEY,
+			repopull_id INTEGER NOT NULL,
+			job_id INTEGER NOT NULL,
+			format TEXT NOT NULL,
+			uri TEXT NOT NULL,
+			sha256 TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			FOREIGN KEY (repopull_id) REFERENCES peridot.repo_pulls (id) ON DELETE CASCADE,
+			FOREIGN KEY (job_id) REFERENCES peridot.jobs (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS notice_documents_repopull_id
+		ON peridot.notice_documents (repopull_id)
+	`)
+	return err
+}
+
+// createTableWebhooks creates the webhooks table if it does not
+// already exist. A NULL project_id means the webhook receives
+// events for all projects.
+func createTableWebhooks(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.webhooks (
+			id SERIAL PRIMARY KEY,
+			project_id INTEGER,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			event_types TEXT[] NOT NULL,
+			is_enabled BOOLEAN NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			FOREIGN KEY (project_id) REFERENCES peridot.projects (id) ON DELETE CASCADE
+		)
+	`)
+	return err
+}
+
+// createTableWebhookDeliveries creates the webhook_deliveries table
+// if it does not already exist.
+func createTableWebhookDeliveries(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.webhook_deliveries (
+			id BIGSERIAL PRIMARY KEY,
+			webhook_id INTEGER NOT NULL,
+			event_type TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			payload_sha256 TEXT NOT NULL,
+			attempts INTEGER NOT NULL,
+			last_response_code INTEGER NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			next_attempt_at TIMESTAMP WITH TIME ZONE,
+			delivered_at TIMESTAMP WITH TIME ZONE,
+			FOREIGN KEY (webhook_id) REFERENCES peridot.webhooks (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_pending
+		ON peridot.webhook_deliveries (next_attempt_at)
+		WHERE delivered_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id
+		ON peridot.webhook_deliveries (webhook_id, created_at)
+	`)
+	return err
+}
+
+// createTableNotifications creates the notifications table if it
+// does not already exist.
+func createTableNotifications(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.notifications (
+			id BIGSERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			type TEXT NOT NULL,
+			entity TEXT NOT NULL,
+			entity_id TEXT NOT NULL,
+			message TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			read_at TIMESTAMP WITH TIME ZONE,
+			FOREIGN KEY (user_id) REFERENCES peridot.users (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS notifications_user_id_unread
+		ON peridot.notifications (user_id, created_at)
+		WHERE read_at IS NULL
+	`)
+	return err
+}
+
+// createTableOutboxEvents creates the outbox_events table if it does
+// not already exist.
+func createTableOutboxEvents(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.outbox_events (
+			id BIGSERIAL PRIMARY KEY,
+			txid BIGINT NOT NULL DEFAULT txid_current(),
+			entity TEXT NOT NULL,
+			entity_id TEXT NOT NULL,
+			action TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			published_at TIMESTAMP WITH TIME ZONE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// tables created before events recorded their transaction get
+	// the column too; their existing events all share the upgrading
+	// transaction's ID, and so keep their ID order
+	_, err = db.sqldb.Exec("ALTER TABLE peridot.outbox_events ADD COLUMN IF NOT EXISTS txid BIGINT NOT NULL DEFAULT txid_current()")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS outbox_events_txid_id
+		ON peridot.outbox_events (txid, id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS outbox_events_unpublished
+		ON peridot.outbox_events (id)
+		WHERE published_at IS NULL
+	`)
+	return err
+}
+
+// createTableComments creates the comments table if it does not
+// already exist.
+func createTableComments(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.comments (
+			id BIGSERIAL PRIMARY KEY,
+			author_id INTEGER,
+			target_type TEXT NOT NULL,
+			repopull_id INTEGER,
+			fileinstance_id INTEGER,
+			body TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			edited_at TIMESTAMP WITH TIME ZONE,
+			is_resolved BOOLEAN NOT NULL,
+			CHECK ((target_type = 'repopull' AND repopull_id IS NOT NULL AND fileinstance_id IS NULL) OR
+				(target_type = 'fileinstance' AND fileinstance_id IS NOT NULL AND repopull_id IS NULL)),
+			FOREIGN KEY (author_id) REFERENCES peridot.users (id) ON DELETE SET NULL,
+			FOREIGN KEY (repopull_id) REFERENCES peridot.repo_pulls (id) ON DELETE CASCADE,
+			FOREIGN KEY (fileinstance_id) REFERENCES peridot.file_instances (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS comments_repopull_id
+		ON peridot.comments (repopull_id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS comments_fileinstance_id
+		ON peridot.comments (fileinstance_id)
+	`)
+	return err
+}
+
+// createTableReviews creates the reviews table if it does not
+// already exist.
+func createTableReviews(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.reviews (
+			id SERIAL PRIMARY KEY,
+			repopull_id INTEGER NOT NULL,
+			reviewer_id INTEGER,
+			state INTEGER NOT NULL,
+			notes TEXT NOT NULL,
+			requested_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			decided_at TIMESTAMP WITH TIME ZONE,
+			FOREIGN KEY (repopull_id) REFERENCES peridot.repo_pulls (id) ON DELETE CASCADE,
+			FOREIGN KEY (reviewer_id) REFERENCES peridot.users (id) ON DELETE SET NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// at most one undecided review per reviewer and repo pull
+	_, err = db.sqldb.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS reviews_repopull_id_reviewer_id_requested
+		ON peridot.reviews (repopull_id, reviewer_id)
+		WHERE state = 10
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS reviews_reviewer_id
+		ON peridot.reviews (reviewer_id)
+	`)
+	return err
+}
+
+// createTableReports creates the reports table if it does not
+// already exist.
+func createTableReports(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.reports (
+			id SERIAL PRIMARY KEY,
+			type TEXT NOT NULL,
+			parameters JSONB NOT NULL,
+			requested_by INTEGER,
+			requested_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			started_at TIMESTAMP WITH TIME ZONE,
+			finished_at TIMESTAMP WITH TIME ZONE,
+			status INTEGER NOT NULL,
+			health INTEGER NOT NULL,
+			output TEXT NOT NULL,
+			artifact_uri TEXT NOT NULL,
+			FOREIGN KEY (requested_by) REFERENCES peridot.users (id) ON DELETE SET NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS reports_requested_by
+		ON peridot.reports (requested_by)
+	`)
+	return err
+}
+
+// createTableIssueLinks creates the issue_links table if it does
+// not already exist.
+func createTableIssueLinks(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.issue_links (
+			id SERIAL PRIMARY KEY,
+			entity TEXT NOT NULL,
+			entity_id TEXT NOT NULL,
+			url TEXT NOT NULL,
+			state TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			last_synced_at TIMESTAMP WITH TIME ZONE,
+			UNIQUE (entity, entity_id, url)
+		)
+	`)
+	return err
+}
+
+// createTableLabels creates the labels table if it does not already
+// exist.
+func createTableLabels(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.labels (
+			entity_type TEXT NOT NULL,
+			entity_id BIGINT NOT NULL,
+			key TEXT NOT NULL,
+			value TEXT NOT NULL,
+			PRIMARY KEY (entity_type, entity_id, key)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS labels_entity_type_key_value
+		ON peridot.labels (entity_type, key, value)
+	`)
+	return err
+}
+
+// createTableMetricsSnapshots creates the metrics_snapshots table if
+// it does not already exist.
+func createTableMetricsSnapshots(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.metrics_snapshots (
+			id BIGSERIAL PRIMARY KEY,
+			project_id INTEGER NOT NULL,
+			recorded_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			repos_scanned INTEGER NOT NULL,
+			failing_pulls INTEGER NOT NULL,
+			open_violations INTEGER NOT NULL,
+			FOREIGN KEY (project_id) REFERENCES peridot.projects (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS metrics_snapshots_project_id_recorded_at
+		ON peridot.metrics_snapshots (project_id, recorded_at)
+	`)
+	return err
+}
+
+// createTableRetentionPolicies creates the retention_policies table
+// if it does not already exist.
+func createTableRetentionPolicies(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.retention_policies (
+			id SERIAL PRIMARY KEY,
+			scope TEXT NOT NULL UNIQUE,
+			keep_latest_n INTEGER NOT NULL,
+			max_age_seconds BIGINT NOT NULL
+		)
+	`)
+	return err
+}
+
+// createTableOrganizations creates the organizations table if it
+// does not already exist.
+func createTableOrganizations(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.organizations (
+			id SERIAL PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			fullname TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)
+	`)
+	return err
+}
+
+// createTableOrganizationMembers creates the organization_members
+// table if it does not already exist.
+func createTableOrganizationMembers(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.organization_members (
+			org_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			access_level INTEGER NOT NULL,
+			PRIMARY KEY (org_id, user_id),
+			FOREIGN KEY (org_id) REFERENCES peridot.organizations (id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES peridot.users (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS organization_members_user_id
+		ON peridot.organization_members (user_id)
+	`)
+	return err
+}
+
+// createTableQuotas creates the quotas table if it does not already
+// exist.
+func createTableQuotas(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.quotas (
+			id SERIAL PRIMARY KEY,
+			project_id INTEGER,
+			org_id INTEGER,
+			max_repos INTEGER NOT NULL,
+			max_concurrent_jobs INTEGER NOT NULL,
+			max_stored_pulls INTEGER NOT NULL,
+			CHECK ((project_id IS NULL) <> (org_id IS NULL)),
+			FOREIGN KEY (project_id) REFERENCES peridot.projects (id) ON DELETE CASCADE,
+			FOREIGN KEY (org_id) REFERENCES peridot.organizations (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS quotas_project_id
+		ON peridot.quotas (project_id)
+		WHERE project_id IS NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS quotas_org_id
+		ON peridot.quotas (org_id)
+		WHERE org_id IS NOT NULL
+	`)
+	return err
+}
+
+// createTableObligations creates the obligations table if it does
+// not already exist.
+func createTableObligations(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.obligations (
+			id SERIAL PRIMARY KEY,
+			license_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			description TEXT NOT NULL,
+			UNIQUE (license_id, kind),
+			FOREIGN KEY (license_id) REFERENCES peridot.licenses (id) ON DELETE CASCADE
+		)
+	`)
+	return err
+}
+
+// createTableFindingOverrides creates the finding_overrides table if
+// it does not already exist.
+func createTableFindingOverrides(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.finding_overrides (
+			id BIGSERIAL PRIMARY KEY,
+			fileinstance_id INTEGER,
+			filehash_id INTEGER,
+			match_expression TEXT NOT NULL,
+			action TEXT NOT NULL,
+			corrected_expression TEXT NOT NULL,
+			justification TEXT NOT NULL,
+			created_by INTEGER,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			expires_at TIMESTAMP WITH TIME ZONE,
+			CHECK ((fileinstance_id IS NULL) <> (filehash_id IS NULL)),
+			FOREIGN KEY (fileinstance_id) REFERENCES peridot.file_instances (id) ON DELETE CASCADE,
+			FOREIGN KEY (filehash_id) REFERENCES peridot.file_hashes (id) ON DELETE CASCADE,
+			FOREIGN KEY (created_by) REFERENCES peridot.users (id) ON DELETE SET NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS finding_overrides_fileinstance_id
+		ON peridot.finding_overrides (fileinstance_id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS finding_overrides_filehash_id
+		ON peridot.finding_overrides (filehash_id)
+	`)
+	return err
+}
+
+// createTableSnippetMatches creates the snippet_matches table if it
+// does not already exist.
+func createTableSnippetMatches(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.snippet_matches (
+			id BIGSERIAL PRIMARY KEY,
+			fileinstance_id INTEGER NOT NULL,
+			job_id INTEGER NOT NULL,
+			start_byte BIGINT NOT NULL,
+			end_byte BIGINT NOT NULL,
+			start_line INTEGER NOT NULL,
+			end_line INTEGER NOT NULL,
+			matched_source TEXT NOT NULL,
+			matched_path TEXT NOT NULL DEFAULT '',
+			license_expression TEXT NOT NULL DEFAULT '',
+			FOREIGN KEY (fileinstance_id) REFERENCES peridot.file_instances (id) ON DELETE CASCADE,
+			FOREIGN KEY (job_id) REFERENCES peridot.jobs (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS snippet_matches_fileinstance_id
+		ON peridot.snippet_matches (fileinstance_id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS snippet_matches_job_id
+		ON peridot.snippet_matches (job_id)
+	`)
+	return err
+}
+
+// createTableSBOMImports creates the sbom_imports table if it does
+// not already exist.
+func createTableSBOMImports(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.sbom_imports (
+			id SERIAL PRIMARY KEY,
+			repopull_id INTEGER NOT NULL,
+			job_id INTEGER NOT NULL,
+			format TEXT NOT NULL,
+			format_version TEXT NOT NULL,
+			uri TEXT NOT NULL,
+			validation_status TEXT NOT NULL,
+			validation_message TEXT NOT NULL DEFAULT '',
+			imported_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			FOREIGN KEY (repopull_id) REFERENCES peridot.repo_pulls (id) ON DELETE CASCADE,
+			FOREIGN KEY (job_id) REFERENCES peridot.jobs (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS sbom_imports_repopull_id
+		ON peridot.sbom_imports (repopull_id)
+	`)
+	return err
+}
+
+// createTableSBOMImportLinks creates the sbom_import_links table if
+// it does not already exist.
+func createTableSBOMImportLinks(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.sbom_import_links (
+			sbom_import_id INTEGER NOT NULL,
+			element_id TEXT NOT NULL,
+			component_id BIGINT,
+			fileinstance_id INTEGER,
+			PRIMARY KEY (sbom_import_id, element_id),
+			CHECK ((component_id IS NULL) <> (fileinstance_id IS NULL)),
+			FOREIGN KEY (sbom_import_id) REFERENCES peridot.sbom_imports (id) ON DELETE CASCADE,
+			FOREIGN KEY (component_id) REFERENCES peridot.components (id) ON DELETE CASCADE,
+			FOREIGN KEY (fileinstance_id) REFERENCES peridot.file_instances (id) ON DELETE CASCADE
+		)
+	`)
+	return err
+}
+
+// createTableAttestations creates the attestations table if it does
+// not already exist.
+func createTableAttestations(db *DB) error {
+	_, err := db.sqldb.Exec(`
+		CREATE TABLE IF NOT EXISTS peridot.attestations (
+			id SERIAL PRIMARY KEY,
+			repopull_id INTEGER NOT NULL,
+			type TEXT NOT NULL,
+			subject_digest TEXT NOT NULL,
+			statement BYTEA,
+			uri TEXT NOT NULL DEFAULT '',
+			verification_status TEXT NOT NULL,
+			verification_message TEXT NOT NULL DEFAULT '',
+			recorded_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			CHECK ((statement IS NULL) <> (uri = '')),
+			FOREIGN KEY (repopull_id) REFERENCES peridot.repo_pulls (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS attestations_repopull_id
+		ON peridot.attestations (repopull_id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.sqldb.Exec(`
+		CREATE INDEX IF NOT EXISTS attestations_subject_digest
+		ON peridot.attestations (subject_digest)
+	`)
 	return err
 }
@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import "time"
+
+// normalizeTime returns t in UTC, truncated to microseconds, which is
+// the precision Postgres stores. The zero value is returned unchanged,
+// since it is used throughout to mean "not set".
+func normalizeTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Time{}
+	}
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// now returns the current time, normalized as by normalizeTime.
+func now() time.Time {
+	return normalizeTime(time.Now())
+}
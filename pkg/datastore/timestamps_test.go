@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCanNormalizeTime(t *testing.T) {
+	edt := time.FixedZone("EDT", -4*60*60)
+	tests := []struct {
+		t    time.Time
+		want time.Time
+	}{
+		{time.Date(2019, 5, 2, 9, 53, 41, 671764389, edt), time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)},
+		{time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC), time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)},
+		{time.Time{}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		got := normalizeTime(tt.t)
+		if got != tt.want {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestNowIsNormalized(t *testing.T) {
+	n := now()
+	if n.Location() != time.UTC {
+		t.Errorf("expected UTC, got %v", n.Location())
+	}
+	if n.Nanosecond()%1000 != 0 {
+		t.Errorf("expected microsecond precision, got %v", n)
+	}
+}
+
+// testCreatedAt and testUpdatedAt are returned as the created_at and
+// updated_at columns of mocked rows.
+var (
+	testCreatedAt = time.Date(2019, 4, 1, 9, 0, 0, 0, time.UTC)
+	testUpdatedAt = time.Date(2019, 5, 1, 9, 0, 0, 0, time.UTC)
+)
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"fmt"
+	"time"
+)
+
+// IncrementAndCheck counts one request made with the API token with
+// the given ID in the current rate limit window of the given length,
+// and reports whether the token is still within limit requests for
+// that window. Windows are aligned to multiples of window since the
+// zero time, so all callers agree on window boundaries. It also
+// returns the number of requests counted so far in the window,
+// including this one.
+//
+// The increment and read happen in a single atomic statement, so
+// concurrent callers each see a distinct count.
+func (db *DB) IncrementAndCheck(tokenID uint32, limit uint32, window time.Duration) (bool, uint32, error) {
+	if window <= 0 {
+		return false, 0, fmt.Errorf("rate limit window must be positive; received %v", window)
+	}
+	windowStart := now().Truncate(window)
+
+	var count uint32
+	err := db.sqldb.QueryRow("INSERT INTO peridot.token_rate_limits(token_id, window_start, count) VALUES ($1, $2, 1) ON CONFLICT (token_id, window_start) DO UPDATE SET count = peridot.token_rate_limits.count + 1 RETURNING count", tokenID, windowStart).
+		Scan(&count)
+	if err != nil {
+		return false, 0, err
+	}
+	return count <= limit, count, nil
+}
+
+// PruneTokenRateLimits deletes rate limit counts for all windows
+// that started before the given time. It returns the number of
+// windows deleted on success or an error if failing.
+func (db *DB) PruneTokenRateLimits(before time.Time) (int64, error) {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.token_rate_limits WHERE window_start < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldIncrementAndCheckWithinLimit(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`INSERT INTO peridot.token_rate_limits\(token_id, window_start, count\) VALUES \(\$1, \$2, 1\) ON CONFLICT \(token_id, window_start\) DO UPDATE SET count = peridot.token_rate_limits.count \+ 1 RETURNING count`).
+		WithArgs(3, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(100))
+
+	// run the tested function
+	allowed, count, err := db.IncrementAndCheck(3, 100, time.Minute)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if !allowed {
+		t.Errorf("expected allowed, got not allowed")
+	}
+	if count != 100 {
+		t.Errorf("expected %v, got %v", 100, count)
+	}
+}
+
+func TestShouldIncrementAndCheckOverLimit(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`INSERT INTO peridot.token_rate_limits`).
+		WithArgs(3, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(101))
+
+	// run the tested function
+	allowed, count, err := db.IncrementAndCheck(3, 100, time.Minute)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if allowed {
+		t.Errorf("expected not allowed, got allowed")
+	}
+	if count != 101 {
+		t.Errorf("expected %v, got %v", 101, count)
+	}
+}
+
+func TestShouldFailIncrementAndCheckWithNonPositiveWindow(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	_, _, err = db.IncrementAndCheck(3, 100, 0)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldPruneTokenRateLimits(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	before := time.Date(2019, 5, 2, 13, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`DELETE FROM peridot.token_rate_limits WHERE window_start < \$1`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 12))
+
+	// run the tested function
+	n, err := db.PruneTokenRateLimits(before)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if n != 12 {
+		t.Errorf("expected %v, got %v", 12, n)
+	}
+}
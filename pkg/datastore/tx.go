@@ -0,0 +1,225 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sqlConn is the part of *sql.DB and *sql.Tx that is used to run
+// statements, so that the same DB methods can run either on their
+// own or within a transaction.
+type sqlConn interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// sqlTx is a transaction begun by DB.begin: either a *sql.Tx or, for
+// a DB that is already bound to a transaction, a savepoint within it.
+type sqlTx interface {
+	sqlConn
+	Commit() error
+	Rollback() error
+}
+
+// savepointName names the savepoints made by nested transactions.
+// Since nested transactions always finish in the reverse order to
+// which they began, and releasing or rolling back to a savepoint
+// affects the most recent one with its name, one name suffices.
+const savepointName = "peridot_nested"
+
+// savepoint is a nested transaction within a *sql.Tx. Committing it
+// releases the savepoint, keeping its changes as part of the outer
+// transaction; rolling it back undoes only its own changes.
+type savepoint struct {
+	*sql.Tx
+}
+
+// beginSavepoint starts a nested transaction within tx.
+func beginSavepoint(tx *sql.Tx) (*savepoint, error) {
+	if _, err := tx.Exec("SAVEPOINT " + savepointName); err != nil {
+		return nil, err
+	}
+	return &savepoint{Tx: tx}, nil
+}
+
+// Commit releases the savepoint.
+func (sp *savepoint) Commit() error {
+	_, err := sp.Tx.Exec("RELEASE SAVEPOINT " + savepointName)
+	return err
+}
+
+// Rollback undoes the changes made since the savepoint and releases
+// it.
+func (sp *savepoint) Rollback() error {
+	if _, err := sp.Tx.Exec("ROLLBACK TO SAVEPOINT " + savepointName); err != nil {
+		return err
+	}
+	_, err := sp.Tx.Exec("RELEASE SAVEPOINT " + savepointName)
+	return err
+}
+
+// begin starts a transaction for a method that must make several
+// changes atomically. If the DB is bound to a transaction, a nested
+// transaction is started within it instead, so that the method's
+// changes can still be rolled back on their own.
+func (db *DB) begin() (sqlTx, error) {
+	switch c := db.sqldb.(type) {
+	case *sql.Tx:
+		return beginSavepoint(c)
+	case *sql.DB:
+		tx, err := c.Begin()
+		if err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}
+	return nil, fmt.Errorf("cannot begin a transaction on %T", db.sqldb)
+}
+
+// inTx reports whether the DB is bound to a transaction.
+func (db *DB) inTx() bool {
+	_, ok := db.sqldb.(*sql.Tx)
+	return ok
+}
+
+// InTx calls fn with a Datastore whose methods all run within a
+// single transaction, so that a caller can combine several of them,
+// e.g. AddRepo and AddRepoPull, into one atomic change. The
+// transaction is committed if fn returns nil, and rolled back if it
+// returns an error or panics. The Datastore must not be used after
+// fn returns, nor by more than one goroutine at a time.
+//
+// Methods that make several changes themselves still do so
+// atomically, within a savepoint, so one that fails leaves the
+// transaction as it was. Other failures, such as a rejected
+// statement, abort the transaction, so fn should return once any
+// method returns an unexpected error. If InTx is called on a
+// Datastore that is already bound to a transaction, fn runs within
+// a savepoint of that transaction, and ctx is not used.
+func (db *DB) InTx(ctx context.Context, fn func(ds Datastore) error) error {
+	return db.runInTx(ctx, nil, fn)
+}
+
+// InSnapshot calls fn with a Datastore whose methods all run within
+// a single read-only REPEATABLE READ transaction, so that they all
+// see the database as it was when the first of them ran, even while
+// other clients keep writing. It is meant for exports and reports
+// that read from several tables, such as a pull's files and their
+// findings, which could otherwise see a mix of older and newer data.
+// Methods that make changes fail within fn.
+//
+// The snapshot is held until fn returns, so fn should not run for
+// longer than it needs to. If InSnapshot is called on a Datastore
+// that is already bound to a transaction, fn runs within a savepoint
+// of that transaction, and sees whatever its isolation level allows.
+func (db *DB) InSnapshot(ctx context.Context, fn func(ds Datastore) error) error {
+	return db.runInTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}, fn)
+}
+
+// runInTx implements InTx and InSnapshot, starting any new
+// transaction with the given options.
+func (db *DB) runInTx(ctx context.Context, opts *sql.TxOptions, fn func(ds Datastore) error) (err error) {
+	var tx sqlTx
+	var bound *DB
+	switch c := db.sqldb.(type) {
+	case *sql.Tx:
+		tx, err = beginSavepoint(c)
+		bound = db
+	case *sql.DB:
+		var outer *sql.Tx
+		outer, err = c.BeginTx(ctx, opts)
+		tx = outer
+		copied := *db
+		copied.sqldb = outer
+		bound = &copied
+	default:
+		err = fmt.Errorf("cannot begin a transaction on %T", db.sqldb)
+	}
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(bound); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ErrNotInTx is returned by the savepoint methods of a Datastore that
+// is not bound to a transaction by InTx.
+var ErrNotInTx = errors.New("not in a transaction")
+
+// savepointTx returns the transaction that the DB is bound to, after
+// checking that name can be used as a savepoint name. Since name is
+// part of the statement, it is limited to lowercase letters, digits
+// and underscores, and may not begin with "peridot_", which is
+// reserved for the savepoints made by nested transactions.
+func (db *DB) savepointTx(name string) (*sql.Tx, error) {
+	tx, ok := db.sqldb.(*sql.Tx)
+	if !ok {
+		return nil, ErrNotInTx
+	}
+	if name == "" || strings.TrimLeft(name, "abcdefghijklmnopqrstuvwxyz0123456789_") != "" || (name[0] >= '0' && name[0] <= '9') {
+		return nil, &ValidationError{Entity: "savepoint", Field: "name", Reason: "must be lowercase letters, digits and underscores, not starting with a digit"}
+	}
+	if strings.HasPrefix(name, "peridot_") {
+		return nil, &ValidationError{Entity: "savepoint", Field: "name", Reason: "must not start with peridot_"}
+	}
+	return tx, nil
+}
+
+// Savepoint marks the current point in the transaction with the
+// given name, so that the changes made after it can later be undone
+// by RollbackToSavepoint without abandoning the whole transaction.
+// For example, an import can set a savepoint before adding each
+// repo, and roll back to it and carry on if adding one fails. It
+// returns ErrNotInTx if the DB is not bound to a transaction.
+func (db *DB) Savepoint(name string) error {
+	tx, err := db.savepointTx(name)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("SAVEPOINT " + name)
+	return err
+}
+
+// RollbackToSavepoint undoes the changes made since the savepoint
+// with the given name was set, including any that failed and
+// aborted the transaction, so that the transaction can be used
+// again. The savepoint remains set and can be rolled back to again.
+// It returns ErrNotInTx if the DB is not bound to a transaction.
+func (db *DB) RollbackToSavepoint(name string) error {
+	tx, err := db.savepointTx(name)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("ROLLBACK TO SAVEPOINT " + name)
+	return err
+}
+
+// ReleaseSavepoint forgets the savepoint with the given name, and any
+// set after it, keeping the changes made since it was set. It
+// returns ErrNotInTx if the DB is not bound to a transaction.
+func (db *DB) ReleaseSavepoint(name string) error {
+	tx, err := db.savepointTx(name)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("RELEASE SAVEPOINT " + name)
+	return err
+}
@@ -0,0 +1,301 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestShouldCommitChangesMadeInTx(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO peridot.repos")
+	mock.ExpectQuery("INSERT INTO peridot.repos").
+		WithArgs(3, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("UPDATE peridot.repos")
+	mock.ExpectExec("UPDATE peridot.repos").
+		WithArgs("https://github.com/kubernetes/kubernetes", 8).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	// run the tested function
+	var id RepoID
+	err = db.InTx(context.Background(), func(ds Datastore) error {
+		var err error
+		id, err = ds.AddRepo(3, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git")
+		if err != nil {
+			return err
+		}
+		return ds.UpdateRepo(id, "", "https://github.com/kubernetes/kubernetes")
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if id != 8 {
+		t.Errorf("expected %v, got %v", 8, id)
+	}
+}
+
+func TestShouldRollBackTxWhenFnFails(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	fkErr := &pq.Error{Code: "23503", Table: "repos", Constraint: "repos_subproject_id_fkey"}
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO peridot.repos")
+	mock.ExpectQuery("INSERT INTO peridot.repos").
+		WithArgs(17, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git").
+		WillReturnError(fkErr)
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`RELEASE SAVEPOINT peridot_nested`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	// run the tested function
+	err = db.InTx(context.Background(), func(ds Datastore) error {
+		_, err := ds.AddRepo(17, "kubernetes/kubernetes", "git@github.com:kubernetes/kubernetes.git")
+		return err
+	})
+	if !errors.Is(err, ErrForeignKey) {
+		t.Fatalf("expected foreign key error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldRollBackTxWhenFnPanics(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	// run the tested function
+	func() {
+		defer func() {
+			if p := recover(); p != "oops" {
+				t.Errorf("expected panic %q, got %v", "oops", p)
+			}
+		}()
+		db.InTx(context.Background(), func(ds Datastore) error {
+			panic("oops")
+		})
+	}()
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldRetryStepAfterRollingBackToSavepoint(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^SAVEPOINT repo_1$`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`^SAVEPOINT peridot_nested$`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("UPDATE peridot.jobs SET is_ready")
+	mock.ExpectExec("UPDATE peridot.jobs SET is_ready").
+		WithArgs(true, 4).
+		WillReturnError(&pq.Error{Code: "40P01"})
+	mock.ExpectExec(`^ROLLBACK TO SAVEPOINT peridot_nested$`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`^RELEASE SAVEPOINT peridot_nested$`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`^ROLLBACK TO SAVEPOINT repo_1$`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`^SAVEPOINT peridot_nested$`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("UPDATE peridot.jobs SET is_ready")
+	mock.ExpectExec("UPDATE peridot.jobs SET is_ready").
+		WithArgs(true, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`^RELEASE SAVEPOINT peridot_nested$`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`^RELEASE SAVEPOINT repo_1$`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.InTx(context.Background(), func(ds Datastore) error {
+		if err := ds.Savepoint("repo_1"); err != nil {
+			return err
+		}
+		if err := ds.UpdateJobIsReady(4, true); err != nil {
+			if err := ds.RollbackToSavepoint("repo_1"); err != nil {
+				return err
+			}
+			if err := ds.UpdateJobIsReady(4, true); err != nil {
+				return err
+			}
+		}
+		return ds.ReleaseSavepoint("repo_1")
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailSavepointOutsideTx(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	err = db.Savepoint("repo_1")
+	if err != ErrNotInTx {
+		t.Fatalf("expected ErrNotInTx, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailSavepointWithInvalidName(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	tx, err := sqldb.Begin()
+	if err != nil {
+		t.Fatalf("got error when beginning transaction: %v", err)
+	}
+	db := DB{sqldb: tx}
+
+	// run the tested function
+	for _, name := range []string{"", "1st", "Repo", "repo; DROP SCHEMA peridot", "peridot_nested"} {
+		err = db.Savepoint(name)
+		if _, ok := err.(*ValidationError); !ok {
+			t.Errorf("expected *ValidationError for %q, got %v", name, err)
+		}
+	}
+	tx.Rollback()
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldReadInSnapshot(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM peridot.repos`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM peridot.repo_pulls`).
+		WithArgs(5, "main").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+	mock.ExpectCommit()
+
+	// run the tested function
+	var repos, pulls int
+	err = db.InSnapshot(context.Background(), func(ds Datastore) error {
+		var err error
+		if repos, err = ds.CountAllRepos(); err != nil {
+			return err
+		}
+		pulls, err = ds.CountRepoPullsForRepoBranch(5, "main")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if repos != 2 || pulls != 7 {
+		t.Errorf("expected %v and %v, got %v and %v", 2, 7, repos, pulls)
+	}
+}
@@ -2,23 +2,157 @@
 
 package datastore
 
-import "fmt"
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
 
 // User describes a registered user of the platform.
 type User struct {
 	// ID is the unique ID for this user.
-	ID uint32 `json:"id"`
+	ID UserID `json:"id"`
 	// Name is this user's name.
 	Name string `json:"name"`
 	// Github is this user's Github user name.
 	Github string `json:"github"`
+	// Email is this user's email address. Should be empty if
+	// no email address is known.
+	Email string `json:"email"`
 	// AccessLevel is this user's access level.
 	AccessLevel UserAccessLevel `json:"access"`
+	// Kind is whether this user is a human or a service account.
+	Kind UserKind `json:"kind"`
+	// LastLoginAt is when this user last logged in. Should be
+	// zero value if the user has never logged in.
+	LastLoginAt time.Time `json:"last_login_at,omitempty"`
+	// LoginCount is the number of times this user has logged in.
+	LoginCount uint32 `json:"login_count"`
+	// AvatarURL is the URL of this user's avatar image, or empty if
+	// none is set.
+	AvatarURL string `json:"avatar_url"`
+	// Pronouns is this user's preferred pronouns, for display.
+	Pronouns string `json:"pronouns"`
+	// Title is this user's job title, for display.
+	Title string `json:"title"`
+	// Organization is the organization this user belongs to, for
+	// display.
+	Organization string `json:"organization"`
+	// CreatedAt is when this user was added. It is set by the
+	// database.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// UpdatedAt is when this user was last changed. It is set by
+	// the database.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
 }
 
-// GetAllUsers returns a slice of all users in the database.
-func (db *DB) GetAllUsers() ([]*User, error) {
-	rows, err := db.sqldb.Query("SELECT id, github, name, access_level FROM peridot.users ORDER BY id")
+// MarshalJSON converts the User into a slice of bytes containing its
+// JSON encoding, leaving out last_login_at for a user who has never
+// logged in and created_at and updated_at if unset.
+func (user User) MarshalJSON() ([]byte, error) {
+	type userAlias User
+	return json.Marshal(struct {
+		userAlias
+		LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+		CreatedAt   *time.Time `json:"created_at,omitempty"`
+		UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	}{userAlias: userAlias(user), LastLoginAt: jsonTimePtr(user.LastLoginAt), CreatedAt: jsonTimePtr(user.CreatedAt), UpdatedAt: jsonTimePtr(user.UpdatedAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of a User into the User. A missing or null last_login_at means the
+// user has never logged in; CreatedAt and UpdatedAt are left as the
+// zero time if they are missing or null.
+func (user *User) UnmarshalJSON(b []byte) error {
+	type userAlias User
+	aux := struct {
+		*userAlias
+		LastLoginAt *time.Time `json:"last_login_at"`
+		CreatedAt   *time.Time `json:"created_at"`
+		UpdatedAt   *time.Time `json:"updated_at"`
+	}{userAlias: (*userAlias)(user)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	user.LastLoginAt = timeFromJSONPtr(aux.LastLoginAt)
+	user.CreatedAt = timeFromJSONPtr(aux.CreatedAt)
+	user.UpdatedAt = timeFromJSONPtr(aux.UpdatedAt)
+	return nil
+}
+
+// Validate checks that the User's fields are well-formed: its access
+// level and kind must be defined values, its email and avatar URL
+// must be empty or valid, and a service account must have a name and
+// no Github user name. It returns nil if so, or a *ValidationError
+// describing the first invalid field.
+func (u *User) Validate() error {
+	if _, err := UserAccessLevelFromInt(int(u.AccessLevel)); err != nil {
+		return &ValidationError{Entity: "user", Field: "access level", Reason: err.Error()}
+	}
+	if _, err := UserKindFromInt(int(u.Kind)); err != nil {
+		return &ValidationError{Entity: "user", Field: "kind", Reason: err.Error()}
+	}
+	if u.Kind == UserKindService {
+		if err := requireNonEmpty("user", "name", u.Name); err != nil {
+			return err
+		}
+		if u.Github != "" {
+			return &ValidationError{Entity: "user", Field: "github", Reason: "service accounts cannot have a Github user name"}
+		}
+	}
+	if err := validateEmail(u.Email); err != nil {
+		return err
+	}
+	return validateAvatarURL(u.AvatarURL)
+}
+
+// userColumns is the list of columns selected for a User, in the
+// order expected by scanUser.
+const userColumns = "id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at"
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser reads a User from a row selecting userColumns, validating
+// its access level and kind.
+func scanUser(rs rowScanner) (*User, error) {
+	user := &User{}
+	var ukInt int
+	var email sql.NullString
+	var lastLoginAt pq.NullTime
+	err := rs.Scan(&user.ID, &user.Github, &user.Name, &email, &user.AccessLevel, &ukInt, &lastLoginAt, &user.LoginCount, &user.AvatarURL, &user.Pronouns, &user.Title, &user.Organization, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// convert integer to UserKind
+	user.Kind, err = UserKindFromInt(ukInt)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Email = email.String
+	user.LastLoginAt = normalizeTime(lastLoginAt.Time)
+	user.CreatedAt = normalizeTime(user.CreatedAt)
+	user.UpdatedAt = normalizeTime(user.UpdatedAt)
+	return user, nil
+}
+
+// queryUsers runs the given query, which must select userColumns,
+// and returns the resulting users.
+func (db *DB) queryUsers(query string, args ...interface{}) ([]*User, error) {
+	rows, err := db.sqldb.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -26,8 +160,7 @@ func (db *DB) GetAllUsers() ([]*User, error) {
 
 	users := []*User{}
 	for rows.Next() {
-		user := &User{}
-		err := rows.Scan(&user.ID, &user.Github, &user.Name, &user.AccessLevel)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -40,84 +173,366 @@ func (db *DB) GetAllUsers() ([]*User, error) {
 	return users, nil
 }
 
-// GetUserByID returns the User with the given user ID, or nil
-// and an error if not found.
-func (db *DB) GetUserByID(id uint32) (*User, error) {
-	var user User
-	var ualInt int
-	err := db.sqldb.QueryRow("SELECT id, github, name, access_level FROM peridot.users WHERE id = $1", id).
-		Scan(&user.ID, &user.Github, &user.Name, &ualInt)
-	if err != nil {
-		return nil, err
+// GetAllUsers returns a slice of all users in the database.
+func (db *DB) GetAllUsers() ([]*User, error) {
+	return db.queryUsers("SELECT " + userColumns + " FROM peridot.users ORDER BY id")
+}
+
+// CountAllUsers returns the number of users in the database.
+func (db *DB) CountAllUsers() (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.users")
+}
+
+// UserFilter describes the criteria used to select users in
+// GetUsers. Zero values mean the corresponding
+// criterion is not applied.
+type UserFilter struct {
+	// Search limits results to users whose name or Github user
+	// name contains this string, ignoring case.
+	Search string
+}
+
+// sqlWhere returns the WHERE clause selecting users that match the
+// filter, or "" if it matches all users, and the parameters for it.
+func (filter UserFilter) sqlWhere() (string, []interface{}) {
+	conds := []string{}
+	args := []interface{}{}
+
+	if filter.Search != "" {
+		args = append(args, "%"+escapeLikePattern(filter.Search)+"%")
+		conds = append(conds, fmt.Sprintf("(name ILIKE $%d OR github ILIKE $%d)", len(args), len(args)))
 	}
 
-	// convert integer to UserAccessLevel
-	ual, err := UserAccessLevelFromInt(ualInt)
-	if err != nil {
-		return nil, err
+	if len(conds) == 0 {
+		return "", args
 	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
 
-	user.AccessLevel = ual
-	return &user, nil
+// userSortColumns lists the fields by which GetUsers can sort.
+var userSortColumns = sortColumns{
+	"name":          "name",
+	"github":        "github",
+	"last_login_at": "last_login_at",
 }
 
-// GetUserByGithub returns the User with the given Github user
-// name, or nil and an error if not found.
-func (db *DB) GetUserByGithub(github string) (*User, error) {
-	var user User
-	var ualInt int
-	err := db.sqldb.QueryRow("SELECT id, github, name, access_level FROM peridot.users WHERE github = $1", github).
-		Scan(&user.ID, &user.Github, &user.Name, &ualInt)
+// GetUsers returns a slice of users in the database matching the
+// given filter, sorted and paged as requested, along with a Page
+// describing the results. Users can be sorted by id, name, github
+// or last_login_at.
+func (db *DB) GetUsers(filter UserFilter, pr PageRequest) ([]*User, Page, error) {
+	where, args := filter.sqlWhere()
+	query := "SELECT " + userColumns + " FROM peridot.users" + where
+	clause, args, err := pr.sqlClause(userSortColumns, args)
 	if err != nil {
-		return nil, err
+		return nil, Page{}, err
 	}
+	query += clause
 
-	// convert integer to UserAccessLevel
-	ual, err := UserAccessLevelFromInt(ualInt)
+	users, err := db.queryUsers(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, Page{}, err
 	}
 
-	user.AccessLevel = ual
-	return &user, nil
+	page, n := pr.page(len(users))
+	return users[:n], page, nil
+}
+
+// CountUsers returns the number of users in the database matching
+// the given filter.
+func (db *DB) CountUsers(filter UserFilter) (int, error) {
+	where, args := filter.sqlWhere()
+	return db.count("SELECT COUNT(*) FROM peridot.users"+where, args...)
+}
+
+// GetUsersByAccessLevel returns a slice of all users in the database
+// with the given access level.
+func (db *DB) GetUsersByAccessLevel(accessLevel UserAccessLevel) ([]*User, error) {
+	return db.queryUsers("SELECT "+userColumns+" FROM peridot.users WHERE access_level = $1 ORDER BY id", IntFromUserAccessLevel(accessLevel))
+}
+
+// GetInactiveUsers returns a slice of all human users who are not
+// disabled and who have not logged in at or after the given time,
+// including users who have never logged in. Service accounts never
+// log in, so they are not included.
+func (db *DB) GetInactiveUsers(since time.Time) ([]*User, error) {
+	return db.queryUsers("SELECT "+userColumns+" FROM peridot.users WHERE access_level != $1 AND kind = $2 AND (last_login_at IS NULL OR last_login_at < $3) ORDER BY id", IntFromUserAccessLevel(AccessDisabled), IntFromUserKind(UserKindHuman), since)
+}
+
+// GetServiceAccounts returns a slice of all service accounts in the
+// database.
+func (db *DB) GetServiceAccounts() ([]*User, error) {
+	return db.queryUsers("SELECT "+userColumns+" FROM peridot.users WHERE kind = $1 ORDER BY id", IntFromUserKind(UserKindService))
+}
+
+// GetUserByID returns the User with the given user ID, or nil
+// and an error if not found.
+func (db *DB) GetUserByID(id UserID) (*User, error) {
+	user, err := scanUser(db.sqldb.QueryRow("SELECT "+userColumns+" FROM peridot.users WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "user", ID: fmt.Sprint(id)}
+	}
+	return user, err
 }
 
-// AddUser adds a new User with the given user ID, name, Github user
-// name, and access level. It returns nil on success or an error if failing.
-// Due to PostgreSQL limits on integer size, id must be less than 2147483647.
-// It should typically be created via math/rand's Int31() function and then
-// cast to uint32.
-func (db *DB) AddUser(id uint32, name string, github string, accessLevel UserAccessLevel) error {
-	var maxUserID uint32
-	maxUserID = 2147483647
+// ExistsUser reports whether a User with the given user ID
+// exists, without retrieving it.
+func (db *DB) ExistsUser(id UserID) (bool, error) {
+	return db.exists("SELECT EXISTS (SELECT 1 FROM peridot.users WHERE id = $1)", id)
+}
 
+// GetUserByGithub returns the User with the given Github user
+// name, or nil and an error if not found. Service accounts have no
+// Github user name, so an empty name never matches.
+func (db *DB) GetUserByGithub(github string) (*User, error) {
+	notFound := &NotFoundError{Entity: "user", Key: "Github user name", ID: github}
+	if github == "" {
+		return nil, notFound
+	}
+	user, err := scanUser(db.sqldb.QueryRow("SELECT "+userColumns+" FROM peridot.users WHERE github = $1", github))
+	if err == sql.ErrNoRows {
+		return nil, notFound
+	}
+	return user, err
+}
+
+// maxUserID is the largest user ID that fits in the users table's
+// PostgreSQL INTEGER id column.
+const maxUserID UserID = 2147483647
+
+// autoUserIDStart is the first user ID handed out by AddUserAutoID.
+// IDs from here up to maxUserID are reserved for the user_auto_id_seq
+// sequence, well above the range of numeric Github user IDs, so that
+// new caller-supplied IDs can never collide with generated ones.
+// Users added before the range was reserved, with IDs from
+// math/rand's Int31(), may already hold some of these IDs;
+// AddUserAutoID skips over them.
+const autoUserIDStart UserID = 2000000000
+
+// checkCallerUserID checks that id is a valid user ID for a caller to
+// supply, i.e. that it fits in the database and is not reserved for
+// auto-generated IDs.
+func checkCallerUserID(id UserID) error {
 	if id > maxUserID {
 		return fmt.Errorf("User id cannot be greater than %d; received %d", maxUserID, id)
 	}
+	if id >= autoUserIDStart {
+		return fmt.Errorf("User ids from %d are reserved for auto-generated IDs; received %d", autoUserIDStart, id)
+	}
+	return nil
+}
+
+// validateEmail checks that email is either empty or a single bare
+// email address, such as "jane@example.com", without a display name.
+// It returns a *ValidationError if not.
+func validateEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return &ValidationError{Entity: "user", Field: "email", Reason: fmt.Sprintf("%q is not a bare email address", email)}
+	}
+	return nil
+}
+
+// nullStringFromString converts a string to a sql.NullString,
+// treating the empty string as NULL.
+func nullStringFromString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// AddUser adds a new human User with the given user ID, name, Github
+// user name, email address, and access level. It returns nil on
+// success or an error if failing. The Github user name is required;
+// the email address may be empty if not known.
+// The id is typically the user's numeric Github user ID, and must be
+// less than 2000000000, since higher IDs are reserved for AddUserAutoID.
+// The earlier advice to create IDs with math/rand's Int31() no longer
+// applies; use AddUserAutoID when there is no Github user ID.
+func (db *DB) AddUser(id UserID, name string, github string, email string, accessLevel UserAccessLevel) error {
+	if err := checkCallerUserID(id); err != nil {
+		return err
+	}
+	if github == "" {
+		return &ValidationError{Entity: "user", Field: "github", Reason: "Github user name is required for human users"}
+	}
+	u := &User{ID: id, Name: name, Github: github, Email: email, AccessLevel: accessLevel, Kind: UserKindHuman}
+	if err := u.Validate(); err != nil {
+		return err
+	}
 
 	ualInt := IntFromUserAccessLevel(accessLevel)
 
 	// move out into one-time-prepared statement?
-	stmt, err := db.sqldb.Prepare("INSERT INTO peridot.users(id, github, name, access_level) VALUES ($1, $2, $3, $4)")
+	return db.insertUserWithOutboxEvent(u, "INSERT INTO peridot.users(id, github, name, email, access_level) VALUES ($1, $2, $3, $4, $5)", id, github, name, nullStringFromString(email), ualInt)
+}
+
+// insertUserWithOutboxEvent runs query, an INSERT of the given User,
+// in a transaction together with recording an OutboxEvent for it.
+func (db *DB) insertUserWithOutboxEvent(u *User, query string, args ...interface{}) error {
+	tx, err := db.begin()
 	if err != nil {
 		return err
 	}
-	_, err = stmt.Exec(id, github, name, ualInt)
+
+	stmt, err := db.prepareIn(tx, query)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
-	return nil
+	_, err = stmt.Exec(args...)
+	if err != nil {
+		tx.Rollback()
+		return translateConstraintError("user", err)
+	}
+
+	err = addOutboxEvent(tx, "user", u.ID, AuditActionAdd, u)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AddServiceAccount adds a new service account User with the given
+// user ID, name and access level. Service accounts have no Github
+// user name or email address, and authenticate only with API tokens.
+// It returns nil on success or an error if failing.
+func (db *DB) AddServiceAccount(id UserID, name string, accessLevel UserAccessLevel) error {
+	if err := checkCallerUserID(id); err != nil {
+		return err
+	}
+	u := &User{ID: id, Name: name, AccessLevel: accessLevel, Kind: UserKindService}
+	if err := u.Validate(); err != nil {
+		return err
+	}
+
+	return db.insertUserWithOutboxEvent(u, "INSERT INTO peridot.users(id, github, name, access_level, kind) VALUES ($1, '', $2, $3, $4)", id, name, IntFromUserAccessLevel(accessLevel), IntFromUserKind(UserKindService))
+}
+
+// AddUserAutoID adds a new User of the given kind with the given
+// name, Github user name, email address and access level, assigning
+// it the next ID from a sequence reserved above the Github ID range.
+// IDs in that range already held by older users are skipped.
+// Unlike AddUser, the Github user name may be empty for human users
+// who log in with another identity, and must be empty for service
+// accounts. It returns the new user's ID on success or an error if
+// failing.
+func (db *DB) AddUserAutoID(name string, github string, email string, accessLevel UserAccessLevel, kind UserKind) (UserID, error) {
+	u := &User{Name: name, Github: github, Email: email, AccessLevel: accessLevel, Kind: kind}
+	if err := u.Validate(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.users(id, github, name, email, access_level, kind) VALUES (nextval('peridot.user_auto_id_seq'), $1, $2, $3, $4, $5) ON CONFLICT (id) DO NOTHING RETURNING id")
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	// an ID taken by a user added before the range was reserved
+	// inserts nothing, so try again with the sequence's next value;
+	// once the sequence runs out, nextval fails instead
+	for {
+		err = stmt.QueryRow(github, name, nullStringFromString(email), IntFromUserAccessLevel(accessLevel), IntFromUserKind(kind)).Scan(&u.ID)
+		if err != sql.ErrNoRows {
+			break
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return 0, translateConstraintError("user", err)
+	}
+
+	err = addOutboxEvent(tx, "user", u.ID, AuditActionAdd, u)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return u.ID, nil
 }
 
 // UpdateUser updates an existing User with the given ID,
-// changing to the specified username, Github ID and and access
-// level. It returns nil on success or an error if failing.
-func (db *DB) UpdateUser(id uint32, newName string, newGithub string, newAccessLevel UserAccessLevel) error {
-	stmt, err := db.sqldb.Prepare("UPDATE peridot.users SET name = $1, github = $2, access_level = $3 WHERE id = $4")
+// changing to the specified username, Github ID, email address
+// and access level. It returns nil on success or an error if
+// failing.
+func (db *DB) UpdateUser(id UserID, newName string, newGithub string, newEmail string, newAccessLevel UserAccessLevel) error {
+	u := &User{ID: id, Name: newName, Github: newGithub, Email: newEmail, AccessLevel: newAccessLevel}
+	if err := u.Validate(); err != nil {
+		return err
+	}
+
+	return db.execWithOutboxEvent("user", id, AuditActionUpdate, map[string]interface{}{"id": id, "name": newName, "github": newGithub, "email": newEmail, "access_level": newAccessLevel},
+		"UPDATE peridot.users SET name = $1, github = $2, email = $3, access_level = $4 WHERE id = $5", newName, newGithub, nullStringFromString(newEmail), newAccessLevel, id)
+}
+
+// UpdateUserNameOnly updates an existing User with the given ID,
+// changing to the specified username. It returns nil on success
+// or an error if failing.
+func (db *DB) UpdateUserNameOnly(id UserID, newName string) error {
+	return db.execWithOutboxEvent("user", id, AuditActionUpdate, map[string]interface{}{"id": id, "name": newName},
+		"UPDATE peridot.users SET name = $1 WHERE id = $2", newName, id)
+}
+
+// validateAvatarURL checks that avatarURL is either empty or an
+// absolute http or https URL. It returns a *ValidationError if not.
+func validateAvatarURL(avatarURL string) error {
+	if avatarURL == "" {
+		return nil
+	}
+	u, err := url.Parse(avatarURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &ValidationError{Entity: "user", Field: "avatar URL", Reason: fmt.Sprintf("%q is not an absolute http or https URL", avatarURL)}
+	}
+	return nil
+}
+
+// UpdateUserProfile updates an existing User with the given ID,
+// changing to the specified avatar URL, pronouns, title and
+// organization. Any of these may be empty to clear them. It returns
+// nil on success or an error if failing.
+func (db *DB) UpdateUserProfile(id UserID, avatarURL string, pronouns string, title string, organization string) error {
+	if err := validateAvatarURL(avatarURL); err != nil {
+		return err
+	}
+
+	return db.execWithOutboxEvent("user", id, AuditActionUpdate, map[string]interface{}{"id": id, "avatar_url": avatarURL, "pronouns": pronouns, "title": title, "organization": organization},
+		"UPDATE peridot.users SET avatar_url = $1, pronouns = $2, title = $3, organization = $4 WHERE id = $5", avatarURL, pronouns, title, organization, id)
+}
+
+// UpdateUserAvatarOnly updates an existing User with the given ID,
+// changing to the specified avatar URL, which may be empty to clear
+// it. It returns nil on success or an error if failing.
+func (db *DB) UpdateUserAvatarOnly(id UserID, avatarURL string) error {
+	if err := validateAvatarURL(avatarURL); err != nil {
+		return err
+	}
+
+	return db.execWithOutboxEvent("user", id, AuditActionUpdate, map[string]interface{}{"id": id, "avatar_url": avatarURL},
+		"UPDATE peridot.users SET avatar_url = $1 WHERE id = $2", avatarURL, id)
+}
+
+// RecordUserLogin records that the User with the given ID has just
+// logged in, updating the user's last login time and login count.
+// Unlike the user's other changes, logins do not record an outbox
+// event. It returns nil on success or an error if failing.
+func (db *DB) RecordUserLogin(id UserID) error {
+	stmt, err := db.prepare("UPDATE peridot.users SET last_login_at = $1, login_count = login_count + 1 WHERE id = $2")
 	if err != nil {
 		return err
 	}
-	result, err := stmt.Exec(newName, newGithub, newAccessLevel, id)
+	result, err := stmt.Exec(now(), id)
 
 	// check error
 	if err != nil {
@@ -130,35 +545,113 @@ func (db *DB) UpdateUser(id uint32, newName string, newGithub string, newAccessL
 		return err
 	}
 	if rows == 0 {
-		return fmt.Errorf("no user found with ID %v", id)
+		return &NotFoundError{Entity: "user", ID: fmt.Sprint(id)}
 	}
 
 	return nil
 }
 
-// UpdateUserNameOnly updates an existing User with the given ID,
-// changing to the specified username. It returns nil on success
-// or an error if failing.
-func (db *DB) UpdateUserNameOnly(id uint32, newName string) error {
-	stmt, err := db.sqldb.Prepare("UPDATE peridot.users SET name = $1 WHERE id = $2")
+// UserDeleteBlockedError is returned when a user cannot be deleted
+// because doing so would leave the platform in an unusable state.
+type UserDeleteBlockedError struct {
+	// UserID is the ID of the user that could not be deleted.
+	UserID UserID
+	// Reason describes why deletion was blocked.
+	Reason string
+}
+
+func (e *UserDeleteBlockedError) Error() string {
+	return fmt.Sprintf("cannot delete user with ID %v: %s", e.UserID, e.Reason)
+}
+
+// lockUserForRemoval locks the rows for all admin users and for the
+// User with the given ID within tx, and checks that the user can be
+// removed (deleted or anonymized) without leaving the platform with
+// no admin user. It returns a *UserDeleteBlockedError if not. The
+// caller is responsible for rolling back tx on error.
+func lockUserForRemoval(tx sqlConn, id UserID) error {
+	// lock every admin's row first, always in the same order, so that
+	// concurrent removals of different admins wait for each other
+	// rather than each counting the other as a remaining admin
+	rows, err := tx.Query("SELECT id FROM peridot.users WHERE access_level = $1 ORDER BY id FOR UPDATE", IntFromUserAccessLevel(AccessAdmin))
 	if err != nil {
 		return err
 	}
-	result, err := stmt.Exec(newName, id)
+	otherAdmins := 0
+	for rows.Next() {
+		var adminID UserID
+		if err := rows.Scan(&adminID); err != nil {
+			rows.Close()
+			return err
+		}
+		if adminID != id {
+			otherAdmins++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
 
-	// check error
+	// then lock the user's own row, in case it is not an admin
+	var ual UserAccessLevel
+	err = tx.QueryRow("SELECT access_level FROM peridot.users WHERE id = $1 FOR UPDATE", id).Scan(&ual)
+	if err == sql.ErrNoRows {
+		return &NotFoundError{Entity: "user", ID: fmt.Sprint(id)}
+	}
 	if err != nil {
 		return err
 	}
 
-	// check that something was actually updated
-	rows, err := result.RowsAffected()
+	if ual == AccessAdmin && otherAdmins == 0 {
+		return &UserDeleteBlockedError{UserID: id, Reason: "user is the only remaining admin"}
+	}
+
+	return nil
+}
+
+// DeleteUser deletes an existing User with the given ID, along with
+// the user's API tokens, linked identities, preferences, project
+// access grants and notifications. Audit log entries are kept, both
+// those the user made and those recording changes to the user, and
+// still refer to the deleted user's ID. Deleting the only remaining
+// admin user is refused with a *UserDeleteBlockedError. It returns
+// nil on success or an error if failing.
+func (db *DB) DeleteUser(id UserID) error {
+	tx, err := db.begin()
 	if err != nil {
 		return err
 	}
-	if rows == 0 {
-		return fmt.Errorf("no user found with ID %v", id)
+
+	err = lockUserForRemoval(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
 	}
 
-	return nil
+	// remove dependent records explicitly rather than relying on
+	// cascading deletes, so that the order of removal is clear
+	for _, q := range []string{
+		"DELETE FROM peridot.user_tokens WHERE user_id = $1",
+		"DELETE FROM peridot.user_identities WHERE user_id = $1",
+		"DELETE FROM peridot.user_preferences WHERE user_id = $1",
+		"DELETE FROM peridot.project_access WHERE user_id = $1",
+		"DELETE FROM peridot.notifications WHERE user_id = $1",
+		"DELETE FROM peridot.users WHERE id = $1",
+	} {
+		_, err = tx.Exec(q, id)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = addOutboxEvent(tx, "user", id, AuditActionDelete, map[string]interface{}{"id": id})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
 }
@@ -3,9 +3,12 @@
 package datastore
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 )
@@ -19,10 +22,10 @@ func TestShouldGetAllUsers(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "access_level"}).
-		AddRow(410952, "johndoe@example.com", "John Doe", AccessCommenter).
-		AddRow(8103918, "janedoe@example.com", "Jane Doe", AccessAdmin)
-	mock.ExpectQuery("SELECT id, github, name, access_level FROM peridot.users ORDER BY id").WillReturnRows(sentRows)
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(410952, "johndoe", "John Doe", "johndoe@example.com", AccessCommenter, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt).
+		AddRow(8103918, "janedoe", "Jane Doe", "janedoe@example.com", AccessAdmin, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery("SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users ORDER BY id").WillReturnRows(sentRows)
 
 	// run the tested function
 	gotRows, err := db.GetAllUsers()
@@ -44,8 +47,8 @@ func TestShouldGetAllUsers(t *testing.T) {
 	if user0.ID != 410952 {
 		t.Errorf("expected %v, got %v", 410952, user0.ID)
 	}
-	if user0.Github != "johndoe@example.com" {
-		t.Errorf("expected %v, got %v", "johndoe@example.com", user0.Github)
+	if user0.Github != "johndoe" {
+		t.Errorf("expected %v, got %v", "johndoe", user0.Github)
 	}
 	if user0.Name != "John Doe" {
 		t.Errorf("expected %v, got %v", "John Doe", user0.Name)
@@ -56,6 +59,180 @@ func TestShouldGetAllUsers(t *testing.T) {
 
 }
 
+func TestShouldGetUsersWithSearchAndPagination(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(8103918, "janedoe", "Jane Doe", nil, 99, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users WHERE \(name ILIKE \$1 OR github ILIKE \$1\) ORDER BY id LIMIT \$2 OFFSET \$3`).
+		WithArgs("%doe%", 51, 100).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, _, err := db.GetUsers(UserFilter{Search: "doe"}, PageRequest{Limit: 50, Offset: 100})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 1 {
+		t.Fatalf("expected len %d, got %d", 1, len(gotRows))
+	}
+	if gotRows[0].Name != "Jane Doe" {
+		t.Errorf("expected %v, got %v", "Jane Doe", gotRows[0].Name)
+	}
+}
+
+func TestShouldGetUsersWithEmptyFilter(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(410952, "johndoe", "John Doe", nil, 20, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt).
+		AddRow(8103918, "janedoe", "Jane Doe", nil, 99, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users ORDER BY id$`).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, _, err := db.GetUsers(UserFilter{}, PageRequest{})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+}
+
+func TestShouldGetUsersByAccessLevel(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(1, "admin", "Admin", nil, 99, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt).
+		AddRow(8103918, "janedoe", "Jane Doe", nil, 99, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users WHERE access_level = \$1 ORDER BY id`).
+		WithArgs(99).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetUsersByAccessLevel(AccessAdmin)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	user1 := gotRows[1]
+	if user1.ID != 8103918 {
+		t.Errorf("expected %v, got %v", 8103918, user1.ID)
+	}
+	if user1.AccessLevel != AccessAdmin {
+		t.Errorf("expected %v, got %v", AccessAdmin, user1.AccessLevel)
+	}
+}
+
+func TestShouldFailToGetUsersByAccessLevelIfInvalidAccessLevelInteger(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(8103918, "janedoe", "Jane Doe", nil, 6, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users WHERE access_level = \$1 ORDER BY id`).
+		WithArgs(0).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetUsersByAccessLevel(AccessDisabled)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+	if gotRows != nil {
+		t.Fatalf("expected nil users, got %v", gotRows)
+	}
+}
+
+func TestShouldGetServiceAccounts(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(2000000001, "", "CI pipeline", nil, 30, 1, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users WHERE kind = \$1 ORDER BY id`).
+		WithArgs(1).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetServiceAccounts()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 1 {
+		t.Fatalf("expected len %d, got %d", 1, len(gotRows))
+	}
+	sa := gotRows[0]
+	if sa.Kind != UserKindService {
+		t.Errorf("expected %v, got %v", UserKindService, sa.Kind)
+	}
+	if sa.Github != "" {
+		t.Errorf("expected empty github, got %v", sa.Github)
+	}
+}
+
 func TestShouldGetUserByID(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
@@ -65,14 +242,501 @@ func TestShouldGetUserByID(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "access_level"}).
-		AddRow(8103918, "janedoe@example.com", "Jane Doe", AccessAdmin)
-	mock.ExpectQuery(`[SELECT id, github, name, access_level FROM peridot.users WHERE id = \$1]`).
-		WithArgs(8103918).
-		WillReturnRows(sentRows)
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(8103918, "janedoe", "Jane Doe", "janedoe@example.com", AccessAdmin, 0, nil, 0, "https://avatars.example.com/u/8103918", "she/her", "Open Source Lead", "Example Corp", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`[SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users WHERE id = \$1]`).
+		WithArgs(8103918).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	user, err := db.GetUserByID(8103918)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if user.ID != 8103918 {
+		t.Errorf("expected %v, got %v", 8103918, user.ID)
+	}
+	if user.Github != "janedoe" {
+		t.Errorf("expected %v, got %v", "janedoe", user.Github)
+	}
+	if user.Name != "Jane Doe" {
+		t.Errorf("expected %v, got %v", "Jane Doe", user.Name)
+	}
+	if user.AccessLevel != AccessAdmin {
+		t.Errorf("expected %v, got %v", AccessAdmin, user.AccessLevel)
+	}
+	if user.AvatarURL != "https://avatars.example.com/u/8103918" {
+		t.Errorf("expected %v, got %v", "https://avatars.example.com/u/8103918", user.AvatarURL)
+	}
+	if user.Pronouns != "she/her" {
+		t.Errorf("expected %v, got %v", "she/her", user.Pronouns)
+	}
+	if user.Title != "Open Source Lead" {
+		t.Errorf("expected %v, got %v", "Open Source Lead", user.Title)
+	}
+	if user.Organization != "Example Corp" {
+		t.Errorf("expected %v, got %v", "Example Corp", user.Organization)
+	}
+	if !user.CreatedAt.Equal(testCreatedAt) {
+		t.Errorf("expected %v, got %v", testCreatedAt, user.CreatedAt)
+	}
+	if !user.UpdatedAt.Equal(testUpdatedAt) {
+		t.Errorf("expected %v, got %v", testUpdatedAt, user.UpdatedAt)
+	}
+
+}
+
+func TestShouldFailToGetUserByIDIfInvalidAccessLevelInteger(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(8103918, "janedoe", "Jane Doe", "janedoe@example.com", 6, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`[SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users WHERE id = \$1]`).
+		WithArgs(8103918).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	user, err := db.GetUserByID(8103918)
+	// error should be set, and user should be nil, because access level 6 is invalid
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+	if user != nil {
+		t.Fatalf("expected nil user, got %v", user)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldGetUserByGithub(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(8103918, "janedoe", "Jane Doe", "janedoe@example.com", AccessAdmin, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`[SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users WHERE github = \$1]`).
+		WithArgs("janedoe").
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	user, err := db.GetUserByGithub("janedoe")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if user.ID != 8103918 {
+		t.Errorf("expected %v, got %v", 8103918, user.ID)
+	}
+	if user.Github != "janedoe" {
+		t.Errorf("expected %v, got %v", "janedoe", user.Github)
+	}
+	if user.Name != "Jane Doe" {
+		t.Errorf("expected %v, got %v", "Jane Doe", user.Name)
+	}
+	if user.AccessLevel != AccessAdmin {
+		t.Errorf("expected %v, got %v", AccessAdmin, user.AccessLevel)
+	}
+
+}
+
+func TestShouldNotGetUserByEmptyGithub(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	user, err := db.GetUserByGithub("")
+	if user != nil {
+		t.Fatalf("expected nil user, got %v", user)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailToGetUserByGithubIfInvalidAccessLevelInteger(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(8103918, "janedoe", "Jane Doe", "janedoe@example.com", 6, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`[SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users WHERE github = \$1]`).
+		WithArgs("janedoe").
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	user, err := db.GetUserByGithub("janedoe")
+	// error should be set, and user should be nil, because access level 6 is invalid
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+	if user != nil {
+		t.Fatalf("expected nil user, got %v", user)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldAddUser(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `[INSERT INTO peridot.users(id, github, name, email, access_level) VALUES (\$1, \$2, \$3, \$4, \$5)]`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	stmt := "INSERT INTO peridot.users"
+	mock.ExpectExec(stmt).
+		WithArgs(192304, "johndoe", "John Doe", sql.NullString{String: "johndoe@example.com", Valid: true}, AccessCommenter).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.AddUser(192304, "John Doe", "johndoe", "johndoe@example.com", AccessCommenter)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldNotAddUserWithGreaterThanMaxID(t *testing.T) {
+	// set up mock
+	sqldb, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	err = db.AddUser(2147483648, "OOPS", "oops", "", AccessDisabled)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+	// a non-nil error that's related to sqlmock errors is still wrong
+	if err != nil && strings.Contains(err.Error(), "all expectations were already fulfilled, call to Prepare") {
+		t.Fatalf("didn't expect sqlmock error: %v", err)
+	}
+}
+
+func TestShouldNotAddUserWithoutGithub(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	err = db.AddUser(192304, "John Doe", "", "", AccessCommenter)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldAddServiceAccount(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.users\(id, github, name, access_level, kind\) VALUES \(\$1, '', \$2, \$3, \$4\)`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(9001, "CI pipeline", 30, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.AddServiceAccount(9001, "CI pipeline", AccessOperator)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldNotAddUserWithReservedAutoID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	err = db.AddUser(2000000000, "John Doe", "johndoe", "", AccessCommenter)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldAddUserAutoID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.users\(id, github, name, email, access_level, kind\) VALUES \(nextval\('peridot.user_auto_id_seq'\), \$1, \$2, \$3, \$4, \$5\) ON CONFLICT \(id\) DO NOTHING RETURNING id`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs("", "Jane Doe", sql.NullString{String: "janedoe@example.com", Valid: true}, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2000000004))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	userID, err := db.AddUserAutoID("Jane Doe", "", "janedoe@example.com", AccessViewer, UserKindHuman)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// check returned value
+	if userID != 2000000004 {
+		t.Errorf("expected %v, got %v", 2000000004, userID)
+	}
+}
+
+func TestShouldAddUserAutoIDSkippingTakenID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.users\(id, github, name, email, access_level, kind\) VALUES \(nextval\('peridot.user_auto_id_seq'\), \$1, \$2, \$3, \$4, \$5\) ON CONFLICT \(id\) DO NOTHING RETURNING id`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	// first ID from the sequence is already held by an older user
+	mock.ExpectQuery(regexStmt).
+		WithArgs("", "Jane Doe", sql.NullString{}, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexStmt).
+		WithArgs("", "Jane Doe", sql.NullString{}, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2000000005))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	userID, err := db.AddUserAutoID("Jane Doe", "", "", AccessViewer, UserKindHuman)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// check returned value
+	if userID != 2000000005 {
+		t.Errorf("expected %v, got %v", 2000000005, userID)
+	}
+}
+
+func TestShouldNotAddServiceAccountAutoIDWithGithub(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	_, err = db.AddUserAutoID("CI pipeline", "janedoe", "", AccessOperator, UserKindService)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldUpdateUserAllDetails(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `[UPDATE peridot.users SET name = \$1, github = \$2, email = \$3, access_level = \$4 WHERE id = \$5]`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	stmt := "UPDATE peridot.users"
+	mock.ExpectExec(stmt).
+		WithArgs("Updated Name", "github-id", sql.NullString{}, AccessViewer, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.UpdateUser(4, "Updated Name", "github-id", "", AccessViewer)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldNotAddOrUpdateUserWithInvalidEmail(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	for _, email := range []string{"johndoe", "John Doe <johndoe@example.com>", "johndoe@example.com, janedoe@example.com"} {
+		err = db.AddUser(192304, "John Doe", "johndoe", email, AccessCommenter)
+		if err == nil {
+			t.Errorf("expected non-nil error for AddUser with %q, got nil", email)
+		}
+		err = db.UpdateUser(192304, "John Doe", "johndoe", email, AccessCommenter)
+		if err == nil {
+			t.Errorf("expected non-nil error for UpdateUser with %q, got nil", email)
+		}
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldUpdateUserNameOnly(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `[UPDATE peridot.users SET name = \$1 WHERE id = \$2]`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	stmt := "UPDATE peridot.users"
+	mock.ExpectExec(stmt).
+		WithArgs("Updated Name", 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
-	user, err := db.GetUserByID(8103918)
+	err = db.UpdateUserNameOnly(4, "Updated Name")
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -82,24 +746,41 @@ func TestShouldGetUserByID(t *testing.T) {
 	if err != nil {
 		t.Errorf("unfulfilled expectations: %v", err)
 	}
+}
 
-	// and check returned values
-	if user.ID != 8103918 {
-		t.Errorf("expected %v, got %v", 8103918, user.ID)
-	}
-	if user.Github != "janedoe@example.com" {
-		t.Errorf("expected %v, got %v", "janedoe@example.com", user.Github)
-	}
-	if user.Name != "Jane Doe" {
-		t.Errorf("expected %v, got %v", "Jane Doe", user.Name)
+func TestShouldUpdateUserProfile(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
 	}
-	if user.AccessLevel != AccessAdmin {
-		t.Errorf("expected %v, got %v", AccessAdmin, user.AccessLevel)
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `UPDATE peridot.users SET avatar_url = \$1, pronouns = \$2, title = \$3, organization = \$4 WHERE id = \$5`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs("https://avatars.example.com/u/4", "they/them", "", "Example Corp", 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.UpdateUserProfile(4, "https://avatars.example.com/u/4", "they/them", "", "Example Corp")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
 	}
 
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
 }
 
-func TestShouldFailToGetUserByIDIfInvalidAccessLevelInteger(t *testing.T) {
+func TestShouldUpdateUserAvatarOnly(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
 	if err != nil {
@@ -108,20 +789,47 @@ func TestShouldFailToGetUserByIDIfInvalidAccessLevelInteger(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "access_level"}).
-		AddRow(8103918, "janedoe@example.com", "Jane Doe", 6)
-	mock.ExpectQuery(`[SELECT id, github, name, access_level FROM peridot.users WHERE id = \$1]`).
-		WithArgs(8103918).
-		WillReturnRows(sentRows)
+	regexStmt := `UPDATE peridot.users SET avatar_url = \$1 WHERE id = \$2`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs("", 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
-	user, err := db.GetUserByID(8103918)
-	// error should be set, and user should be nil, because access level 6 is invalid
-	if err == nil {
-		t.Fatalf("expected non-nil error, got nil")
+	err = db.UpdateUserAvatarOnly(4, "")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
 	}
-	if user != nil {
-		t.Fatalf("expected nil user, got %v", user)
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldNotUpdateUserProfileWithInvalidAvatarURL(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	for _, avatarURL := range []string{"avatar.png", "javascript:alert(1)", "ftp://example.com/a.png"} {
+		err = db.UpdateUserProfile(4, avatarURL, "", "", "")
+		if err == nil {
+			t.Errorf("expected non-nil error for UpdateUserProfile with %q, got nil", avatarURL)
+		}
+		err = db.UpdateUserAvatarOnly(4, avatarURL)
+		if err == nil {
+			t.Errorf("expected non-nil error for UpdateUserAvatarOnly with %q, got nil", avatarURL)
+		}
 	}
 
 	// check sqlmock expectations
@@ -131,7 +839,7 @@ func TestShouldFailToGetUserByIDIfInvalidAccessLevelInteger(t *testing.T) {
 	}
 }
 
-func TestShouldGetUserByGithub(t *testing.T) {
+func TestShouldGetInactiveUsers(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
 	if err != nil {
@@ -140,14 +848,17 @@ func TestShouldGetUserByGithub(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "access_level"}).
-		AddRow(8103918, "janedoe@example.com", "Jane Doe", AccessAdmin)
-	mock.ExpectQuery(`[SELECT id, github, name, access_level FROM peridot.users WHERE github = \$1]`).
-		WithArgs("janedoe@example.com").
+	since := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastLogin := time.Date(2018, 6, 2, 13, 53, 41, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(410952, "johndoe", "John Doe", nil, 20, 0, lastLogin, 7, "", "", "", "", testCreatedAt, testUpdatedAt).
+		AddRow(8103918, "janedoe", "Jane Doe", nil, 10, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users WHERE access_level != \$1 AND kind = \$2 AND \(last_login_at IS NULL OR last_login_at < \$3\) ORDER BY id`).
+		WithArgs(0, 0, since).
 		WillReturnRows(sentRows)
 
 	// run the tested function
-	user, err := db.GetUserByGithub("janedoe@example.com")
+	gotRows, err := db.GetInactiveUsers(since)
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -159,22 +870,51 @@ func TestShouldGetUserByGithub(t *testing.T) {
 	}
 
 	// and check returned values
-	if user.ID != 8103918 {
-		t.Errorf("expected %v, got %v", 8103918, user.ID)
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
 	}
-	if user.Github != "janedoe@example.com" {
-		t.Errorf("expected %v, got %v", "janedoe@example.com", user.Github)
+	u0 := gotRows[0]
+	if u0.LastLoginAt != lastLogin {
+		t.Errorf("expected %v, got %v", lastLogin, u0.LastLoginAt)
 	}
-	if user.Name != "Jane Doe" {
-		t.Errorf("expected %v, got %v", "Jane Doe", user.Name)
+	if u0.LoginCount != 7 {
+		t.Errorf("expected %v, got %v", 7, u0.LoginCount)
 	}
-	if user.AccessLevel != AccessAdmin {
-		t.Errorf("expected %v, got %v", AccessAdmin, user.AccessLevel)
+	u1 := gotRows[1]
+	if !u1.LastLoginAt.IsZero() {
+		t.Errorf("expected zero time, got %v", u1.LastLoginAt)
+	}
+}
+
+func TestShouldRecordUserLogin(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `UPDATE peridot.users SET last_login_at = \$1, login_count = login_count \+ 1 WHERE id = \$2`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(sqlmock.AnyArg(), 8103918).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.RecordUserLogin(8103918)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
 	}
 
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
 }
 
-func TestShouldFailToGetUserByGithubIfInvalidAccessLevelInteger(t *testing.T) {
+func TestShouldFailRecordUserLoginWithUnknownID(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
 	if err != nil {
@@ -183,21 +923,17 @@ func TestShouldFailToGetUserByGithubIfInvalidAccessLevelInteger(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "access_level"}).
-		AddRow(8103918, "janedoe@example.com", "Jane Doe", 6)
-	mock.ExpectQuery(`[SELECT id, github, name, access_level FROM peridot.users WHERE github = \$1]`).
-		WithArgs("janedoe@example.com").
-		WillReturnRows(sentRows)
+	regexStmt := `UPDATE peridot.users SET last_login_at = \$1, login_count = login_count \+ 1 WHERE id = \$2`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(sqlmock.AnyArg(), 413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
 
 	// run the tested function
-	user, err := db.GetUserByGithub("janedoe@example.com")
-	// error should be set, and user should be nil, because access level 6 is invalid
+	err = db.RecordUserLogin(413)
 	if err == nil {
 		t.Fatalf("expected non-nil error, got nil")
 	}
-	if user != nil {
-		t.Fatalf("expected nil user, got %v", user)
-	}
 
 	// check sqlmock expectations
 	err = mock.ExpectationsWereMet()
@@ -206,7 +942,7 @@ func TestShouldFailToGetUserByGithubIfInvalidAccessLevelInteger(t *testing.T) {
 	}
 }
 
-func TestShouldAddUser(t *testing.T) {
+func TestShouldDeleteUser(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
 	if err != nil {
@@ -215,15 +951,37 @@ func TestShouldAddUser(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	regexStmt := `[INSERT INTO peridot.users(id, github, name, access_level) VALUES (\$1, \$2, \$3, \$4)]`
-	mock.ExpectPrepare(regexStmt)
-	stmt := "INSERT INTO peridot.users"
-	mock.ExpectExec(stmt).
-		WithArgs(192304, "johndoe@example.com", "John Doe", AccessCommenter).
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM peridot.users WHERE access_level = \$1 ORDER BY id FOR UPDATE`).
+		WithArgs(99).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT access_level FROM peridot.users WHERE id = \$1 FOR UPDATE`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"access_level"}).AddRow(20))
+	mock.ExpectExec(`DELETE FROM peridot.user_tokens WHERE user_id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM peridot.user_identities WHERE user_id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM peridot.user_preferences WHERE user_id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM peridot.project_access WHERE user_id = \$1`).
+		WithArgs(4).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM peridot.notifications WHERE user_id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM peridot.users WHERE id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// run the tested function
-	err = db.AddUser(192304, "John Doe", "johndoe@example.com", AccessCommenter)
+	err = db.DeleteUser(4)
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -235,27 +993,58 @@ func TestShouldAddUser(t *testing.T) {
 	}
 }
 
-func TestShouldNotAddUserWithGreaterThanMaxID(t *testing.T) {
+func TestShouldDeleteAdminUserIfOtherAdminsExist(t *testing.T) {
 	// set up mock
-	sqldb, _, err := sqlmock.New()
+	sqldb, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("got error when creating db mock: %v", err)
 	}
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM peridot.users WHERE access_level = \$1 ORDER BY id FOR UPDATE`).
+		WithArgs(99).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(4))
+	mock.ExpectQuery(`SELECT access_level FROM peridot.users WHERE id = \$1 FOR UPDATE`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"access_level"}).AddRow(99))
+	mock.ExpectExec(`DELETE FROM peridot.user_tokens WHERE user_id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM peridot.user_identities WHERE user_id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM peridot.user_preferences WHERE user_id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM peridot.project_access WHERE user_id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM peridot.notifications WHERE user_id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM peridot.users WHERE id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
 	// run the tested function
-	err = db.AddUser(2147483648, "oops@example.com", "OOPS", AccessDisabled)
-	if err == nil {
-		t.Fatalf("expected non-nil error, got nil")
+	err = db.DeleteUser(4)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
 	}
-	// a non-nil error that's related to sqlmock errors is still wrong
-	if err != nil && strings.Contains(err.Error(), "all expectations were already fulfilled, call to Prepare") {
-		t.Fatalf("didn't expect sqlmock error: %v", err)
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
 	}
 }
 
-func TestShouldUpdateUserAllDetails(t *testing.T) {
+func TestShouldFailDeleteUserIfOnlyAdmin(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
 	if err != nil {
@@ -264,17 +1053,26 @@ func TestShouldUpdateUserAllDetails(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	regexStmt := `[UPDATE peridot.users SET name = \$1, github = \$2, access_level = \$3 WHERE id = \$4]`
-	mock.ExpectPrepare(regexStmt)
-	stmt := "UPDATE peridot.users"
-	mock.ExpectExec(stmt).
-		WithArgs("Updated Name", "github-id", AccessViewer, 4).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM peridot.users WHERE access_level = \$1 ORDER BY id FOR UPDATE`).
+		WithArgs(99).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT access_level FROM peridot.users WHERE id = \$1 FOR UPDATE`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"access_level"}).AddRow(99))
+	mock.ExpectRollback()
 
 	// run the tested function
-	err = db.UpdateUser(4, "Updated Name", "github-id", AccessViewer)
-	if err != nil {
-		t.Fatalf("expected nil error, got %v", err)
+	err = db.DeleteUser(1)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+	blockedErr, ok := err.(*UserDeleteBlockedError)
+	if !ok {
+		t.Fatalf("expected *UserDeleteBlockedError, got %T %v", err, err)
+	}
+	if blockedErr.UserID != 1 {
+		t.Errorf("expected %v, got %v", 1, blockedErr.UserID)
 	}
 
 	// check sqlmock expectations
@@ -284,7 +1082,7 @@ func TestShouldUpdateUserAllDetails(t *testing.T) {
 	}
 }
 
-func TestShouldUpdateUserNameOnly(t *testing.T) {
+func TestShouldFailDeleteUserWithUnknownID(t *testing.T) {
 	// set up mock
 	sqldb, mock, err := sqlmock.New()
 	if err != nil {
@@ -293,17 +1091,19 @@ func TestShouldUpdateUserNameOnly(t *testing.T) {
 	defer sqldb.Close()
 	db := DB{sqldb: sqldb}
 
-	regexStmt := `[UPDATE peridot.users SET name = \$1 WHERE id = \$2]`
-	mock.ExpectPrepare(regexStmt)
-	stmt := "UPDATE peridot.users"
-	mock.ExpectExec(stmt).
-		WithArgs("Updated Name", 4).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM peridot.users WHERE access_level = \$1 ORDER BY id FOR UPDATE`).
+		WithArgs(99).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT access_level FROM peridot.users WHERE id = \$1 FOR UPDATE`).
+		WithArgs(413).
+		WillReturnRows(sqlmock.NewRows([]string{}))
+	mock.ExpectRollback()
 
 	// run the tested function
-	err = db.UpdateUserNameOnly(4, "Updated Name")
-	if err != nil {
-		t.Fatalf("expected nil error, got %v", err)
+	err = db.DeleteUser(413)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
 	}
 
 	// check sqlmock expectations
@@ -317,7 +1117,8 @@ func TestShouldUpdateUserNameOnly(t *testing.T) {
 func TestCanMarshalAdminUserToJSON(t *testing.T) {
 	user := &User{
 		ID:          85010942,
-		Github:      "janedoe@example.com",
+		Github:      "janedoe",
+		Email:       "janedoe@example.com",
 		Name:        "Jane Doe",
 		AccessLevel: AccessAdmin,
 	}
@@ -344,6 +1145,9 @@ func TestCanMarshalAdminUserToJSON(t *testing.T) {
 	if user.Github != mGot["github"].(string) {
 		t.Errorf("expected %v, got %v", user.Github, mGot["github"].(string))
 	}
+	if user.Email != mGot["email"].(string) {
+		t.Errorf("expected %v, got %v", user.Email, mGot["email"].(string))
+	}
 	if user.Name != mGot["name"].(string) {
 		t.Errorf("expected %v, got %v", user.Name, mGot["name"].(string))
 	}
@@ -356,7 +1160,8 @@ func TestCanMarshalAdminUserToJSON(t *testing.T) {
 func TestCanMarshalNonAdminUserToJSON(t *testing.T) {
 	user := &User{
 		ID:          16923941,
-		Github:      "johndoe@example.com",
+		Github:      "johndoe",
+		Email:       "johndoe@example.com",
 		Name:        "John Doe",
 		AccessLevel: AccessCommenter,
 	}
@@ -383,6 +1188,9 @@ func TestCanMarshalNonAdminUserToJSON(t *testing.T) {
 	if user.Github != mGot["github"].(string) {
 		t.Errorf("expected %v, got %v", user.Github, mGot["github"].(string))
 	}
+	if user.Email != mGot["email"].(string) {
+		t.Errorf("expected %v, got %v", user.Email, mGot["email"].(string))
+	}
 	if user.Name != mGot["name"].(string) {
 		t.Errorf("expected %v, got %v", user.Name, mGot["name"].(string))
 	}
@@ -394,7 +1202,7 @@ func TestCanMarshalNonAdminUserToJSON(t *testing.T) {
 
 func TestCanUnmarshalAdminUserFromJSON(t *testing.T) {
 	user := &User{}
-	js := []byte(`{"id":1920, "name":"Jane Doe", "github":"janedoe@example.com", "access":"admin"}`)
+	js := []byte(`{"id":1920, "name":"Jane Doe", "github":"janedoe", "email":"janedoe@example.com", "access":"admin"}`)
 
 	err := json.Unmarshal(js, user)
 	if err != nil {
@@ -405,8 +1213,11 @@ func TestCanUnmarshalAdminUserFromJSON(t *testing.T) {
 	if user.ID != 1920 {
 		t.Errorf("expected %v, got %v", 1920, user.ID)
 	}
-	if user.Github != "janedoe@example.com" {
-		t.Errorf("expected %v, got %v", "janedoe@example.com", user.Github)
+	if user.Github != "janedoe" {
+		t.Errorf("expected %v, got %v", "janedoe", user.Github)
+	}
+	if user.Email != "janedoe@example.com" {
+		t.Errorf("expected %v, got %v", "janedoe@example.com", user.Email)
 	}
 	if user.Name != "Jane Doe" {
 		t.Errorf("expected %v, got %v", "Jane Doe", user.Name)
@@ -418,7 +1229,7 @@ func TestCanUnmarshalAdminUserFromJSON(t *testing.T) {
 
 func TestCanUnmarshalNonAdminUserFromJSON(t *testing.T) {
 	user := &User{}
-	js := []byte(`{"id":92841, "name":"John Doe", "github":"johndoe@example.com", "access":"commenter"}`)
+	js := []byte(`{"id":92841, "name":"John Doe", "github":"johndoe", "email":"johndoe@example.com", "access":"commenter"}`)
 
 	err := json.Unmarshal(js, user)
 	if err != nil {
@@ -429,8 +1240,8 @@ func TestCanUnmarshalNonAdminUserFromJSON(t *testing.T) {
 	if user.ID != 92841 {
 		t.Errorf("expected %v, got %v", 92841, user.ID)
 	}
-	if user.Github != "johndoe@example.com" {
-		t.Errorf("expected %v, got %v", "johndoe@example.com", user.Github)
+	if user.Github != "johndoe" {
+		t.Errorf("expected %v, got %v", "johndoe", user.Github)
 	}
 	if user.Name != "John Doe" {
 		t.Errorf("expected %v, got %v", "John Doe", user.Name)
@@ -440,12 +1251,146 @@ func TestCanUnmarshalNonAdminUserFromJSON(t *testing.T) {
 	}
 }
 
+func TestUserJSONRoundTripsLastLoginTime(t *testing.T) {
+	lastLoginAt := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	user := User{ID: 92841, Name: "John Doe", Github: "johndoe", AccessLevel: AccessViewer, LastLoginAt: lastLoginAt, LoginCount: 3}
+
+	js, err := json.Marshal(user)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+
+	user2 := &User{}
+	err = json.Unmarshal(js, user2)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if !user2.LastLoginAt.Equal(lastLoginAt) {
+		t.Errorf("expected %v, got %v", lastLoginAt, user2.LastLoginAt)
+	}
+	if user2.LoginCount != 3 {
+		t.Errorf("expected %v, got %v", 3, user2.LoginCount)
+	}
+}
+
+func TestUserJSONLeavesOutLastLoginTimeIfNeverLoggedIn(t *testing.T) {
+	user := User{ID: 92841, Name: "John Doe", Github: "johndoe", AccessLevel: AccessViewer}
+
+	js, err := json.Marshal(user)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if strings.Contains(string(js), "last_login_at") {
+		t.Errorf("expected no last_login_at key, got %s", js)
+	}
+
+	// and a null value reads back as never logged in
+	user2 := &User{}
+	err = json.Unmarshal([]byte(`{"id":92841, "name":"John Doe", "github":"johndoe", "access":"viewer", "last_login_at":null}`), user2)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if !user2.LastLoginAt.IsZero() {
+		t.Errorf("expected zero time, got %v", user2.LastLoginAt)
+	}
+}
+
+func TestCanMarshalUserTimestampsToJSON(t *testing.T) {
+	// unset timestamps, as for a user not yet added, are omitted
+	user := User{ID: 92841, Name: "John Doe", Github: "johndoe", AccessLevel: AccessViewer}
+	js, err := json.Marshal(user)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if strings.Contains(string(js), "created_at") || strings.Contains(string(js), "updated_at") {
+		t.Errorf("expected no created_at or updated_at keys, got %s", js)
+	}
+
+	// and set timestamps round-trip
+	user.CreatedAt = testCreatedAt
+	user.UpdatedAt = testUpdatedAt
+	js, err = json.Marshal(user)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	user2 := &User{}
+	if err = json.Unmarshal(js, user2); err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if !user2.CreatedAt.Equal(testCreatedAt) {
+		t.Errorf("expected %v, got %v", testCreatedAt, user2.CreatedAt)
+	}
+	if !user2.UpdatedAt.Equal(testUpdatedAt) {
+		t.Errorf("expected %v, got %v", testUpdatedAt, user2.UpdatedAt)
+	}
+}
+
 func TestCannotUnmarshalUserWithNegativeIDFromJSON(t *testing.T) {
 	user := &User{}
-	js := []byte(`{"id":-92841, "name":"OOPS", "github":"oops@example.com", "access":"disabled"}`)
+	js := []byte(`{"id":-92841, "name":"OOPS", "github":"oops", "access":"disabled"}`)
 
 	err := json.Unmarshal(js, user)
 	if err == nil {
 		t.Fatalf("expected non-nil error, got nil")
 	}
 }
+
+func TestUserValidate(t *testing.T) {
+	tests := []struct {
+		u       User
+		isError bool
+	}{
+		{User{Name: "John Doe", Github: "johndoe", Email: "johndoe@example.com", AccessLevel: AccessViewer}, false},
+		{User{Name: "ci-bot", AccessLevel: AccessOperator, Kind: UserKindService}, false},
+		// invalid values should fail
+		{User{Name: "John Doe", Github: "johndoe", AccessLevel: UserAccessLevel(6)}, true},
+		{User{Name: "John Doe", Github: "johndoe", AccessLevel: AccessViewer, Kind: UserKind(57)}, true},
+		{User{Name: "", AccessLevel: AccessOperator, Kind: UserKindService}, true},
+		{User{Name: "ci-bot", Github: "cibot", AccessLevel: AccessOperator, Kind: UserKindService}, true},
+		{User{Name: "John Doe", Github: "johndoe", Email: "John <johndoe@example.com>", AccessLevel: AccessViewer}, true},
+		{User{Name: "John Doe", Github: "johndoe", AvatarURL: "ftp://example.com/a.png", AccessLevel: AccessViewer}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.u.Validate()
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("for %+v: expected isError %v, got %v", tt.u, tt.isError, err)
+		}
+		if err != nil {
+			if _, ok := err.(*ValidationError); !ok {
+				t.Errorf("expected *ValidationError, got %T", err)
+			}
+		}
+	}
+}
+
+func TestShouldCountUsersWithFilter(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM peridot.users WHERE \(name ILIKE \$1 OR github ILIKE \$1\)`).
+		WithArgs("%ali%").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+	// run the tested function
+	n, err := db.CountUsers(UserFilter{Search: "ali"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if n != 4 {
+		t.Errorf("expected %v, got %v", 4, n)
+	}
+}
@@ -3,6 +3,7 @@
 package datastore
 
 import (
+	"database/sql/driver"
 	"encoding/json"
 	"fmt"
 )
@@ -120,6 +121,12 @@ func StringFromUserAccessLevel(ual UserAccessLevel) string {
 	return "disabled"
 }
 
+// String returns the access level's name, such as "operator", as
+// given by StringFromUserAccessLevel.
+func (ual UserAccessLevel) String() string {
+	return StringFromUserAccessLevel(ual)
+}
+
 // MarshalJSON converts the UserAccessLevel value into a slice of bytes
 // containing the string encoding of the access level.
 func (ual UserAccessLevel) MarshalJSON() ([]byte, error) {
@@ -144,3 +151,31 @@ func (ual *UserAccessLevel) UnmarshalJSON(b []byte) error {
 	*ual = ualVal
 	return nil
 }
+
+// Scan implements sql.Scanner, converting an integer column value
+// into the corresponding UserAccessLevel value. It returns an error
+// if the stored value is NULL or is not a valid access level integer.
+func (ual *UserAccessLevel) Scan(src interface{}) error {
+	n, err := scanEnumInt(src, "user access level")
+	if err != nil {
+		return err
+	}
+
+	ualVal, err := UserAccessLevelFromInt(n)
+	if err != nil {
+		return err
+	}
+
+	*ual = ualVal
+	return nil
+}
+
+// Value implements driver.Valuer, converting the UserAccessLevel value
+// into its integer encoding for storage. It returns an error if the
+// UserAccessLevel value is not valid.
+func (ual UserAccessLevel) Value() (driver.Value, error) {
+	if _, err := UserAccessLevelFromInt(int(ual)); err != nil {
+		return nil, err
+	}
+	return int64(IntFromUserAccessLevel(ual)), nil
+}
@@ -4,6 +4,7 @@ package datastore
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 )
 
@@ -305,3 +306,68 @@ func TestCanUnmarshalJSONToUserAccessLevel(t *testing.T) {
 		t.Errorf("expected non-nil error, got nil")
 	}
 }
+
+// ===== Scanner and Valuer tests =====
+
+func TestCanScanUserAccessLevel(t *testing.T) {
+	tests := []struct {
+		src     interface{}
+		want    UserAccessLevel
+		isError bool
+	}{
+		{int64(10), AccessViewer, false},
+		{int64(99), AccessAdmin, false},
+		{[]byte("20"), AccessCommenter, false},
+		{"30", AccessOperator, false},
+		// invalid values should fail and leave the value unchanged
+		{int64(6), AccessDisabled, true},
+		{[]byte("oops"), AccessDisabled, true},
+		{nil, AccessDisabled, true},
+		{1.5, AccessDisabled, true},
+	}
+
+	for _, tt := range tests {
+		var got UserAccessLevel
+		err := got.Scan(tt.src)
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("for %v: expected isError %v, got %v", tt.src, tt.isError, err)
+		}
+		if got != tt.want {
+			t.Errorf("for %v: expected %v, got %v", tt.src, tt.want, got)
+		}
+	}
+}
+
+func TestCanValueUserAccessLevel(t *testing.T) {
+	got, err := AccessOperator.Value()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got != int64(30) {
+		t.Errorf("expected %v, got %v", int64(30), got)
+	}
+
+	// and invalid values should return error
+	_, err = UserAccessLevel(6).Value()
+	if err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+}
+
+func TestCanFormatUserAccessLevel(t *testing.T) {
+	tests := []struct {
+		in   UserAccessLevel
+		want string
+	}{
+		{AccessViewer, "viewer"},
+		{AccessOperator, "operator"},
+		{AccessAdmin, "admin"},
+	}
+
+	for _, tt := range tests {
+		got := fmt.Sprintf("%v", tt.in)
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
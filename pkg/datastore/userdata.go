@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// UserDataExport collects all records tied to a single User, for
+// responding to data-subject access requests.
+type UserDataExport struct {
+	// User is the user's own record.
+	User *User `json:"user"`
+	// Identities is the user's linked external identities.
+	Identities []*UserIdentity `json:"identities"`
+	// Preferences is the user's stored preferences.
+	Preferences map[string]json.RawMessage `json:"preferences"`
+	// Tokens is the user's API tokens, without their secrets.
+	Tokens []*UserToken `json:"tokens"`
+	// ProjectAccess is the user's per-project access grants.
+	ProjectAccess []*ProjectAccess `json:"project_access"`
+	// OrganizationMemberships is the user's organization memberships.
+	OrganizationMemberships []*OrganizationMember `json:"organization_memberships"`
+	// Invitations is the invitations created or accepted by the user.
+	Invitations []*Invitation `json:"invitations"`
+	// Comments is the comments written by the user.
+	Comments []*Comment `json:"comments"`
+	// Reviews is the reviews the user has been asked for.
+	Reviews []*Review `json:"reviews"`
+	// Reports is the reports requested by the user.
+	Reports []*Report `json:"reports"`
+	// AuditEntries is the audit log entries for changes the user made.
+	AuditEntries []*AuditEntry `json:"audit_entries"`
+}
+
+// ExportUserData returns all records tied to the User with the given
+// ID, or nil and an error if the user is not found or any lookup
+// fails.
+func (db *DB) ExportUserData(id UserID) (*UserDataExport, error) {
+	var err error
+	export := &UserDataExport{}
+
+	export.User, err = db.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if export.Identities, err = db.GetIdentitiesForUser(id); err != nil {
+		return nil, err
+	}
+	if export.Preferences, err = db.GetUserPreferences(id); err != nil {
+		return nil, err
+	}
+	if export.Tokens, err = db.ListTokensForUser(id); err != nil {
+		return nil, err
+	}
+	if export.ProjectAccess, err = db.GetProjectAccessForUser(id); err != nil {
+		return nil, err
+	}
+	if export.OrganizationMemberships, err = db.queryOrganizationMembers("SELECT org_id, user_id, access_level FROM peridot.organization_members WHERE user_id = $1 ORDER BY org_id", id); err != nil {
+		return nil, err
+	}
+	if export.Invitations, err = db.GetInvitationsForUser(id); err != nil {
+		return nil, err
+	}
+	if export.Comments, err = db.queryComments("SELECT "+commentColumns+" FROM peridot.comments WHERE author_id = $1 ORDER BY created_at, id", id); err != nil {
+		return nil, err
+	}
+	if export.Reviews, err = db.queryReviews("SELECT "+reviewColumns+" FROM peridot.reviews WHERE reviewer_id = $1 ORDER BY id", id); err != nil {
+		return nil, err
+	}
+	if export.Reports, err = db.GetReportsForUser(id); err != nil {
+		return nil, err
+	}
+	if export.AuditEntries, err = db.GetAuditEntriesByActor(id); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// anonymizedCommentBody replaces the text of comments written by a
+// user who has been anonymized.
+const anonymizedCommentBody = "[removed]"
+
+// AnonymizeUser scrubs the personal fields of the User with the
+// given ID, in a single transaction. The user's row and ID are kept,
+// so that audit entries and other references to it remain valid, but
+// it is disabled, its name is replaced with a placeholder and all
+// other personal fields are cleared. The user's identities, tokens,
+// preferences, project access grants, organization memberships and
+// notifications are deleted, invitee details are cleared from
+// invitations the user accepted, and the text of the user's comments
+// and review notes is removed, keeping the comments and reviews
+// themselves. Personal fields, comment and note text, and preference
+// values are removed from the corresponding audit log snapshots. The
+// user's reports are kept. As with DeleteUser, anonymizing the only
+// remaining admin user is refused with a *UserDeleteBlockedError. It
+// returns nil on success or an error if failing.
+func (db *DB) AnonymizeUser(id UserID) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+
+	err = lockUserForRemoval(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// audit log entity IDs and snapshot user IDs are stored as text
+	idStr := fmt.Sprint(id)
+	steps := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"UPDATE peridot.users SET github = '', name = $2, email = NULL, access_level = $3, last_login_at = NULL, login_count = 0, avatar_url = '', pronouns = '', title = '', organization = '' WHERE id = $1", []interface{}{id, fmt.Sprintf("Anonymized user %d", id), IntFromUserAccessLevel(AccessDisabled)}},
+		{"DELETE FROM peridot.user_tokens WHERE user_id = $1", []interface{}{id}},
+		{"DELETE FROM peridot.user_identities WHERE user_id = $1", []interface{}{id}},
+		{"DELETE FROM peridot.user_preferences WHERE user_id = $1", []interface{}{id}},
+		{"DELETE FROM peridot.project_access WHERE user_id = $1", []interface{}{id}},
+		{"DELETE FROM peridot.notifications WHERE user_id = $1", []interface{}{id}},
+		{"DELETE FROM peridot.organization_members WHERE user_id = $1", []interface{}{id}},
+		{"UPDATE peridot.invitations SET email = NULL, github = NULL WHERE accepted_user_id = $1", []interface{}{id}},
+		{"UPDATE peridot.comments SET body = $2 WHERE author_id = $1", []interface{}{id, anonymizedCommentBody}},
+		{"UPDATE peridot.reviews SET notes = '' WHERE reviewer_id = $1", []interface{}{id}},
+		{"UPDATE peridot.audit_log SET before = before - 'name' - 'github' - 'email' - 'avatar_url' - 'pronouns' - 'title' - 'organization', after = after - 'name' - 'github' - 'email' - 'avatar_url' - 'pronouns' - 'title' - 'organization' WHERE entity = 'user' AND entity_id = $1", []interface{}{idStr}},
+		{"UPDATE peridot.audit_log SET before = before - 'subject' - 'email', after = after - 'subject' - 'email' WHERE entity = 'user_identity' AND (before->>'user_id' = $1 OR after->>'user_id' = $1)", []interface{}{idStr}},
+		{"UPDATE peridot.audit_log SET before = NULL, after = NULL WHERE entity = 'user_preference' AND entity_id LIKE $1", []interface{}{idStr + "/%"}},
+		{"UPDATE peridot.audit_log SET before = before - 'body', after = after - 'body' WHERE entity = 'comment' AND (before->>'author_id' = $1 OR after->>'author_id' = $1)", []interface{}{idStr}},
+		{"UPDATE peridot.audit_log SET before = before - 'notes', after = after - 'notes' WHERE entity = 'review' AND (before->>'reviewer_id' = $1 OR after->>'reviewer_id' = $1)", []interface{}{idStr}},
+	}
+	for _, step := range steps {
+		_, err = tx.Exec(step.query, step.args...)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	payload := map[string]interface{}{"id": id, "name": fmt.Sprintf("Anonymized user %d", id), "github": "", "email": "", "access_level": AccessDisabled, "avatar_url": "", "pronouns": "", "title": "", "organization": ""}
+	err = addOutboxEvent(tx, "user", id, AuditActionUpdate, payload)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,236 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldExportUserData(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	mock.ExpectQuery(`SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users WHERE id = \$1`).
+		WithArgs(8103918).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+			AddRow(8103918, "janedoe", "Jane Doe", "janedoe@example.com", 99, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt))
+	mock.ExpectQuery(`SELECT id, user_id, provider, subject, email FROM peridot.user_identities WHERE user_id = \$1`).
+		WithArgs(8103918).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "provider", "subject", "email"}).
+			AddRow(1, 8103918, "github", "8103918", "janedoe@example.com"))
+	mock.ExpectQuery(`SELECT key, value FROM peridot.user_preferences WHERE user_id = \$1`).
+		WithArgs(8103918).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).
+			AddRow("timezone", []byte(`"Europe/Berlin"`)))
+	mock.ExpectQuery(`SELECT id, user_id, scopes, created_at, expires_at, last_used_at, updated_at FROM peridot.user_tokens WHERE user_id = \$1`).
+		WithArgs(8103918).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "scopes", "created_at", "expires_at", "last_used_at", "updated_at"}).
+			AddRow(3, 8103918, "{ci}", createdAt, nil, nil, createdAt))
+	mock.ExpectQuery(`SELECT user_id, project_id, access_level FROM peridot.project_access WHERE user_id = \$1`).
+		WithArgs(8103918).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "project_id", "access_level"}))
+	mock.ExpectQuery(`SELECT org_id, user_id, access_level FROM peridot.organization_members WHERE user_id = \$1 ORDER BY org_id`).
+		WithArgs(8103918).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id", "user_id", "access_level"}).
+			AddRow(2, 8103918, 20))
+	mock.ExpectQuery(`SELECT id, email, github, access_level, inviter_id, created_at, expires_at, accepted_at, accepted_user_id FROM peridot.invitations WHERE inviter_id = \$1 OR accepted_user_id = \$1 ORDER BY id`).
+		WithArgs(8103918).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "github", "access_level", "inviter_id", "created_at", "expires_at", "accepted_at", "accepted_user_id"}).
+			AddRow(1, "johndoe@example.com", nil, 20, 8103918, createdAt, createdAt, nil, nil))
+	mock.ExpectQuery(`SELECT id, author_id, target_type, COALESCE\(repopull_id, fileinstance_id\), body, created_at, edited_at, is_resolved FROM peridot.comments WHERE author_id = \$1 ORDER BY created_at, id`).
+		WithArgs(8103918).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "target_type", "target_id", "body", "created_at", "edited_at", "is_resolved"}).
+			AddRow(5, 8103918, "repopull", 12, "Looks good to me", createdAt, nil, false))
+	mock.ExpectQuery(`SELECT id, repopull_id, reviewer_id, state, notes, requested_at, decided_at FROM peridot.reviews WHERE reviewer_id = \$1 ORDER BY id`).
+		WithArgs(8103918).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "repopull_id", "reviewer_id", "state", "notes", "requested_at", "decided_at"}).
+			AddRow(3, 12, 8103918, 10, "", createdAt, nil))
+	mock.ExpectQuery(`SELECT id, type, parameters, requested_by, requested_at, started_at, finished_at, status, health, output, artifact_uri FROM peridot.reports WHERE requested_by = \$1`).
+		WithArgs(8103918).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "parameters", "requested_by", "requested_at", "started_at", "finished_at", "status", "health", "output", "artifact_uri"}))
+	mock.ExpectQuery(`SELECT id, actor_id, entity, entity_id, action, before, after, created_at FROM peridot.audit_log WHERE actor_id = \$1 ORDER BY created_at, id`).
+		WithArgs(8103918).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor_id", "entity", "entity_id", "action", "before", "after", "created_at"}).
+			AddRow(17, 8103918, "project", "2", "add", nil, []byte(`{"id":2}`), createdAt))
+
+	// run the tested function
+	export, err := db.ExportUserData(8103918)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if export.User.ID != 8103918 {
+		t.Errorf("expected %v, got %v", 8103918, export.User.ID)
+	}
+	if len(export.Identities) != 1 {
+		t.Errorf("expected %d identities, got %d", 1, len(export.Identities))
+	}
+	if string(export.Preferences["timezone"]) != `"Europe/Berlin"` {
+		t.Errorf("expected %v, got %v", `"Europe/Berlin"`, string(export.Preferences["timezone"]))
+	}
+	if len(export.Tokens) != 1 {
+		t.Errorf("expected %d tokens, got %d", 1, len(export.Tokens))
+	}
+	if len(export.ProjectAccess) != 0 {
+		t.Errorf("expected %d grants, got %d", 0, len(export.ProjectAccess))
+	}
+	if len(export.OrganizationMemberships) != 1 || export.OrganizationMemberships[0].OrgID != 2 {
+		t.Errorf("expected membership of organization 2, got %v", export.OrganizationMemberships)
+	}
+	if len(export.Invitations) != 1 {
+		t.Errorf("expected %d invitations, got %d", 1, len(export.Invitations))
+	}
+	if len(export.Comments) != 1 || export.Comments[0].Body != "Looks good to me" {
+		t.Errorf("expected comment 5, got %v", export.Comments)
+	}
+	if len(export.Reviews) != 1 || export.Reviews[0].ID != 3 {
+		t.Errorf("expected review 3, got %v", export.Reviews)
+	}
+	if len(export.Reports) != 0 {
+		t.Errorf("expected %d reports, got %d", 0, len(export.Reports))
+	}
+	if len(export.AuditEntries) != 1 || export.AuditEntries[0].ID != 17 {
+		t.Errorf("expected audit entry 17, got %v", export.AuditEntries)
+	}
+}
+
+func TestShouldFailExportUserDataForUnknownUser(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT id, github, name, email, access_level`).
+		WithArgs(413).
+		WillReturnRows(sqlmock.NewRows([]string{}))
+
+	// run the tested function
+	export, err := db.ExportUserData(413)
+	if export != nil {
+		t.Fatalf("expected nil export, got %v", export)
+	}
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldAnonymizeUser(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM peridot.users WHERE access_level = \$1 ORDER BY id FOR UPDATE`).
+		WithArgs(99).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT access_level FROM peridot.users WHERE id = \$1 FOR UPDATE`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"access_level"}).AddRow(20))
+	mock.ExpectExec(`UPDATE peridot.users SET github = '', name = \$2, email = NULL, access_level = \$3, last_login_at = NULL, login_count = 0, avatar_url = '', pronouns = '', title = '', organization = '' WHERE id = \$1`).
+		WithArgs(4, "Anonymized user 4", 0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	for _, table := range []string{"user_tokens", "user_identities", "user_preferences", "project_access", "notifications", "organization_members"} {
+		mock.ExpectExec(`DELETE FROM peridot.` + table + ` WHERE user_id = \$1`).
+			WithArgs(4).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectExec(`UPDATE peridot.invitations SET email = NULL, github = NULL WHERE accepted_user_id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE peridot.comments SET body = \$2 WHERE author_id = \$1`).
+		WithArgs(4, "[removed]").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE peridot.reviews SET notes = '' WHERE reviewer_id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE peridot.audit_log SET before = before - 'name'.* WHERE entity = 'user' AND entity_id = \$1`).
+		WithArgs("4").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`UPDATE peridot.audit_log SET before = before - 'subject' - 'email', after = after - 'subject' - 'email' WHERE entity = 'user_identity'`).
+		WithArgs("4").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE peridot.audit_log SET before = NULL, after = NULL WHERE entity = 'user_preference' AND entity_id LIKE \$1`).
+		WithArgs("4/%").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE peridot.audit_log SET before = before - 'body', after = after - 'body' WHERE entity = 'comment'`).
+		WithArgs("4").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE peridot.audit_log SET before = before - 'notes', after = after - 'notes' WHERE entity = 'review'`).
+		WithArgs("4").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	err = db.AnonymizeUser(4)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailAnonymizeUserIfOnlyAdmin(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM peridot.users WHERE access_level = \$1 ORDER BY id FOR UPDATE`).
+		WithArgs(99).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT access_level FROM peridot.users WHERE id = \$1 FOR UPDATE`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"access_level"}).AddRow(99))
+	mock.ExpectRollback()
+
+	// run the tested function
+	err = db.AnonymizeUser(1)
+	if _, ok := err.(*UserDeleteBlockedError); !ok {
+		t.Fatalf("expected *UserDeleteBlockedError, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
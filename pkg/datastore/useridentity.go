@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Identity providers recorded in UserIdentity.Provider. Other
+// provider names may also be used.
+const (
+	// IdentityProviderGithub is a GitHub account; Subject is the
+	// numeric GitHub user ID.
+	IdentityProviderGithub = "github"
+	// IdentityProviderGitlab is a GitLab account; Subject is the
+	// numeric GitLab user ID.
+	IdentityProviderGitlab = "gitlab"
+	// IdentityProviderOIDC is an OpenID Connect account; Subject is
+	// the issuer's "sub" claim.
+	IdentityProviderOIDC = "oidc"
+)
+
+// UserIdentity describes an external identity linked to a User,
+// which the user can use to log in. A user may have several linked
+// identities, but each provider and subject pair belongs to only
+// one user.
+type UserIdentity struct {
+	// ID is the unique ID for this identity.
+	ID uint32 `json:"id"`
+	// UserID is the ID of the user to whom this identity is linked.
+	UserID UserID `json:"user_id"`
+	// Provider is the name of the identity provider, such as one of
+	// the IdentityProvider values.
+	Provider string `json:"provider"`
+	// Subject is the provider's stable identifier for the account.
+	Subject string `json:"subject"`
+	// Email is the email address reported by the provider, or empty
+	// if none was reported.
+	Email string `json:"email"`
+}
+
+// GetIdentitiesForUser returns a slice of all identities linked to
+// the User with the given ID, ordered by ID.
+func (db *DB) GetIdentitiesForUser(userID UserID) ([]*UserIdentity, error) {
+	rows, err := db.sqldb.Query("SELECT id, user_id, provider, subject, email FROM peridot.user_identities WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uis := []*UserIdentity{}
+	for rows.Next() {
+		ui := &UserIdentity{}
+		var email sql.NullString
+		err := rows.Scan(&ui.ID, &ui.UserID, &ui.Provider, &ui.Subject, &email)
+		if err != nil {
+			return nil, err
+		}
+		ui.Email = email.String
+		uis = append(uis, ui)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return uis, nil
+}
+
+// GetUserByIdentity returns the User to whom the identity with the
+// given provider and subject is linked, or nil and an error if not
+// found.
+func (db *DB) GetUserByIdentity(provider string, subject string) (*User, error) {
+	user, err := scanUser(db.sqldb.QueryRow("SELECT "+userColumns+" FROM peridot.users WHERE id = (SELECT user_id FROM peridot.user_identities WHERE provider = $1 AND subject = $2)", provider, subject))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "user", Key: "identity", ID: provider + "/" + subject}
+	}
+	return user, err
+}
+
+// AddIdentity links a new identity with the given provider, subject
+// and email to the User with the given ID. An empty email is stored
+// as NULL. It returns the new identity's ID on success or an error
+// if failing.
+func (db *DB) AddIdentity(userID UserID, provider string, subject string, email string) (uint32, error) {
+	if provider == "" || subject == "" {
+		return 0, fmt.Errorf("provider and subject must both be non-empty")
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.user_identities(user_id, provider, subject, email) VALUES ($1, $2, $3, $4) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var identityID uint32
+	err = stmt.QueryRow(userID, provider, subject, sql.NullString{String: email, Valid: email != ""}).Scan(&identityID)
+	if err != nil {
+		return 0, err
+	}
+	return identityID, nil
+}
+
+// DeleteIdentity unlinks the identity with the given ID from its
+// user. It returns nil on success or an error if failing.
+func (db *DB) DeleteIdentity(id uint32) error {
+	stmt, err := db.prepare("DELETE FROM peridot.user_identities WHERE id = $1")
+	if err != nil {
+		return err
+	}
+	result, err := stmt.Exec(id)
+
+	// check error
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "identity", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
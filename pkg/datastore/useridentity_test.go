@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetIdentitiesForUser(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "user_id", "provider", "subject", "email"}).
+		AddRow(1, 8103918, "github", "8103918", "janedoe@example.com").
+		AddRow(4, 8103918, "oidc", "auth0|5d8c", nil)
+	mock.ExpectQuery(`SELECT id, user_id, provider, subject, email FROM peridot.user_identities WHERE user_id = \$1 ORDER BY id`).
+		WithArgs(8103918).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetIdentitiesForUser(8103918)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	ui0 := gotRows[0]
+	if ui0.Provider != IdentityProviderGithub {
+		t.Errorf("expected %v, got %v", IdentityProviderGithub, ui0.Provider)
+	}
+	if ui0.Email != "janedoe@example.com" {
+		t.Errorf("expected %v, got %v", "janedoe@example.com", ui0.Email)
+	}
+	ui1 := gotRows[1]
+	if ui1.Subject != "auth0|5d8c" {
+		t.Errorf("expected %v, got %v", "auth0|5d8c", ui1.Subject)
+	}
+	if ui1.Email != "" {
+		t.Errorf("expected empty email, got %v", ui1.Email)
+	}
+}
+
+func TestShouldGetUserByIdentity(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"id", "github", "name", "email", "access_level", "kind", "last_login_at", "login_count", "avatar_url", "pronouns", "title", "organization", "created_at", "updated_at"}).
+		AddRow(8103918, "janedoe", "Jane Doe", nil, 99, 0, nil, 0, "", "", "", "", testCreatedAt, testUpdatedAt)
+	mock.ExpectQuery(`SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users WHERE id = \(SELECT user_id FROM peridot.user_identities WHERE provider = \$1 AND subject = \$2\)`).
+		WithArgs("gitlab", "2291").
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	user, err := db.GetUserByIdentity(IdentityProviderGitlab, "2291")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if user.ID != 8103918 {
+		t.Errorf("expected %v, got %v", 8103918, user.ID)
+	}
+	if user.AccessLevel != AccessAdmin {
+		t.Errorf("expected %v, got %v", AccessAdmin, user.AccessLevel)
+	}
+}
+
+func TestShouldFailGetUserByUnknownIdentity(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT id, github, name, email, access_level, kind, last_login_at, login_count, avatar_url, pronouns, title, organization, created_at, updated_at FROM peridot.users WHERE id = \(SELECT user_id FROM peridot.user_identities`).
+		WithArgs("gitlab", "413").
+		WillReturnRows(sqlmock.NewRows([]string{}))
+
+	// run the tested function
+	user, err := db.GetUserByIdentity(IdentityProviderGitlab, "413")
+	if user != nil {
+		t.Fatalf("expected nil user, got %v", user)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldAddIdentity(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.user_identities\(user_id, provider, subject, email\) VALUES \(\$1, \$2, \$3, \$4\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery("INSERT INTO peridot.user_identities").
+		WithArgs(8103918, "oidc", "auth0|5d8c", sql.NullString{}).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+
+	// run the tested function
+	identityID, err := db.AddIdentity(8103918, IdentityProviderOIDC, "auth0|5d8c", "")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// check returned value
+	if identityID != 4 {
+		t.Errorf("expected %v, got %v", 4, identityID)
+	}
+}
+
+func TestShouldFailAddIdentityWithEmptySubject(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	_, err = db.AddIdentity(8103918, IdentityProviderOIDC, "", "")
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldDeleteIdentity(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `DELETE FROM peridot.user_identities WHERE id = \$1`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.DeleteIdentity(4)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDeleteIdentityWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `DELETE FROM peridot.user_identities WHERE id = \$1`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.DeleteIdentity(413)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
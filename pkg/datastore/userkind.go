@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// UserKind defines the different kinds of User: human users, who
+// log in with an external identity, and service accounts, which
+// authenticate only with API tokens.
+type UserKind int
+
+const (
+	// UserKindHuman is a default zero value, and means that the
+	// user is a person who logs in with a Github (or other) identity.
+	UserKindHuman UserKind = 0
+
+	// UserKindService means that the user is a service account,
+	// such as for a CI pipeline. It has no Github identity and
+	// authenticates only with API tokens.
+	UserKindService UserKind = 1
+)
+
+// UserKindFromInt converts an integer to its corresponding
+// UserKind value. It returns that value or an error if the
+// integer is invalid.
+func UserKindFromInt(ukInt int) (UserKind, error) {
+	switch ukInt {
+	case 0:
+		return UserKindHuman, nil
+	case 1:
+		return UserKindService, nil
+	}
+
+	return UserKindHuman, fmt.Errorf("invalid user kind integer %d", ukInt)
+}
+
+// IntFromUserKind converts a UserKind value to its corresponding
+// integer value.
+func IntFromUserKind(uk UserKind) int {
+	switch uk {
+	case UserKindHuman:
+		return 0
+	case UserKindService:
+		return 1
+	}
+
+	// shouldn't be possible to fall through since all values
+	// are captured above, but we'll return 0 here because go
+	// requires a final return
+	return 0
+}
+
+// UserKindFromString converts a string to its corresponding
+// UserKind value. It returns that value or an error if the
+// string is invalid.
+func UserKindFromString(ukStr string) (UserKind, error) {
+	switch ukStr {
+	case "human":
+		return UserKindHuman, nil
+	case "service":
+		return UserKindService, nil
+	}
+
+	return UserKindHuman, fmt.Errorf("invalid user kind string %s", ukStr)
+}
+
+// StringFromUserKind converts a UserKind value to its
+// corresponding string value.
+func StringFromUserKind(uk UserKind) string {
+	switch uk {
+	case UserKindHuman:
+		return "human"
+	case UserKindService:
+		return "service"
+	}
+
+	// shouldn't be possible to fall through since all values
+	// are captured above, but we'll return 'human' here because
+	// go requires a final return
+	return "human"
+}
+
+// String returns "human" or "service", as given by
+// StringFromUserKind.
+func (uk UserKind) String() string {
+	return StringFromUserKind(uk)
+}
+
+// MarshalJSON converts the UserKind value into a slice of bytes
+// containing the string encoding of the user kind.
+func (uk UserKind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(StringFromUserKind(uk))
+}
+
+// UnmarshalJSON converts a slice of bytes containing the string encoding
+// of the user kind into the corresponding UserKind value.
+func (uk *UserKind) UnmarshalJSON(b []byte) error {
+	var s string
+
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	ukVal, err := UserKindFromString(s)
+	if err != nil {
+		return err
+	}
+
+	*uk = ukVal
+	return nil
+}
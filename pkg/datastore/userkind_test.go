@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestCanChangeIntToUserKind(t *testing.T) {
+	tests := []struct {
+		in      int
+		want    UserKind
+		isError bool
+	}{
+		{0, UserKindHuman, false},
+		{1, UserKindService, false},
+		// invalid values should return UserKindHuman
+		{7, UserKindHuman, true},
+	}
+
+	for _, tt := range tests {
+		got, err := UserKindFromInt(tt.in)
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestCanChangeUserKindToInt(t *testing.T) {
+	tests := []struct {
+		in   UserKind
+		want int
+	}{
+		{UserKindHuman, 0},
+		{UserKindService, 1},
+	}
+
+	for _, tt := range tests {
+		got := IntFromUserKind(tt.in)
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestCanChangeStringToUserKind(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    UserKind
+		isError bool
+	}{
+		{"human", UserKindHuman, false},
+		{"service", UserKindService, false},
+		// invalid values should return UserKindHuman
+		{"oops", UserKindHuman, true},
+	}
+
+	for _, tt := range tests {
+		got, err := UserKindFromString(tt.in)
+		if (tt.isError && err == nil) || (!tt.isError && err != nil) {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestCanChangeUserKindToString(t *testing.T) {
+	tests := []struct {
+		in   UserKind
+		want string
+	}{
+		{UserKindHuman, "human"},
+		{UserKindService, "service"},
+	}
+
+	for _, tt := range tests {
+		got := StringFromUserKind(tt.in)
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestCanMarshalAndUnmarshalUserKindJSON(t *testing.T) {
+	tests := []struct {
+		in   UserKind
+		want string
+	}{
+		{UserKindHuman, "\"human\""},
+		{UserKindService, "\"service\""},
+	}
+
+	for _, tt := range tests {
+		gotBytes, err := json.Marshal(tt.in)
+		if err != nil {
+			t.Fatalf("got non-nil error: %v", err)
+		}
+		if string(gotBytes) != tt.want {
+			t.Errorf("expected %v, got %v", tt.want, string(gotBytes))
+		}
+
+		var got UserKind
+		err = json.Unmarshal([]byte(tt.want), &got)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if got != tt.in {
+			t.Errorf("expected %v, got %v", tt.in, got)
+		}
+	}
+
+	// and invalid strings should fail to unmarshal
+	var got UserKind
+	err := json.Unmarshal([]byte("\"oops\""), &got)
+	if err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+}
+
+func TestCanFormatUserKind(t *testing.T) {
+	tests := []struct {
+		in   UserKind
+		want string
+	}{
+		{UserKindHuman, "human"},
+		{UserKindService, "service"},
+	}
+
+	for _, tt := range tests {
+		got := fmt.Sprintf("%v", tt.in)
+		if tt.want != got {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// GetUserPreferences returns all preferences stored for the User
+// with the given ID, as a map from preference key to its JSON
+// value. The map is empty if the user has no stored preferences.
+func (db *DB) GetUserPreferences(userID UserID) (map[string]json.RawMessage, error) {
+	rows, err := db.sqldb.Query("SELECT key, value FROM peridot.user_preferences WHERE user_id = $1 ORDER BY key", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := map[string]json.RawMessage{}
+	for rows.Next() {
+		var key string
+		var value []byte
+		err := rows.Scan(&key, &value)
+		if err != nil {
+			return nil, err
+		}
+		prefs[key] = json.RawMessage(value)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// GetUserPreference returns the JSON value of the preference with
+// the given key for the User with the given ID, or nil and an error
+// if not found.
+func (db *DB) GetUserPreference(userID UserID, key string) (json.RawMessage, error) {
+	var value []byte
+	err := db.sqldb.QueryRow("SELECT value FROM peridot.user_preferences WHERE user_id = $1 AND key = $2", userID, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "preference", Key: "user ID/key", ID: fmt.Sprintf("%d/%s", userID, key)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(value), nil
+}
+
+// SetUserPreference stores the JSON encoding of value as the
+// preference with the given key for the User with the given ID,
+// replacing any existing value. It returns nil on success or an
+// error if failing.
+func (db *DB) SetUserPreference(userID UserID, key string, value interface{}) error {
+	if key == "" {
+		return fmt.Errorf("preference key must be non-empty")
+	}
+	js, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.user_preferences(user_id, key, value) VALUES ($1, $2, $3) ON CONFLICT (user_id, key) DO UPDATE SET value = EXCLUDED.value")
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(userID, key, js)
+	return err
+}
+
+// DeleteUserPreference removes the preference with the given key
+// for the User with the given ID. It returns nil on success or an
+// error if failing.
+func (db *DB) DeleteUserPreference(userID UserID, key string) error {
+	stmt, err := db.prepare("DELETE FROM peridot.user_preferences WHERE user_id = $1 AND key = $2")
+	if err != nil {
+		return err
+	}
+	result, err := stmt.Exec(userID, key)
+
+	// check error
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "preference", Key: "user ID/key", ID: fmt.Sprintf("%d/%s", userID, key)}
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetUserPreferences(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	sentRows := sqlmock.NewRows([]string{"key", "value"}).
+		AddRow("defaultProject", []byte(`2`)).
+		AddRow("timezone", []byte(`"Europe/Berlin"`))
+	mock.ExpectQuery(`SELECT key, value FROM peridot.user_preferences WHERE user_id = \$1 ORDER BY key`).
+		WithArgs(8103918).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	prefs, err := db.GetUserPreferences(8103918)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(prefs) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(prefs))
+	}
+	if string(prefs["defaultProject"]) != `2` {
+		t.Errorf("expected %v, got %v", `2`, string(prefs["defaultProject"]))
+	}
+	if string(prefs["timezone"]) != `"Europe/Berlin"` {
+		t.Errorf("expected %v, got %v", `"Europe/Berlin"`, string(prefs["timezone"]))
+	}
+}
+
+func TestShouldGetUserPreference(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT value FROM peridot.user_preferences WHERE user_id = \$1 AND key = \$2`).
+		WithArgs(8103918, "timezone").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow([]byte(`"Europe/Berlin"`)))
+
+	// run the tested function
+	value, err := db.GetUserPreference(8103918, "timezone")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned value
+	if string(value) != `"Europe/Berlin"` {
+		t.Errorf("expected %v, got %v", `"Europe/Berlin"`, string(value))
+	}
+}
+
+func TestShouldFailGetUserPreferenceWithUnknownKey(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectQuery(`SELECT value FROM peridot.user_preferences WHERE user_id = \$1 AND key = \$2`).
+		WithArgs(8103918, "oops").
+		WillReturnRows(sqlmock.NewRows([]string{}))
+
+	// run the tested function
+	value, err := db.GetUserPreference(8103918, "oops")
+	if value != nil {
+		t.Fatalf("expected nil value, got %v", value)
+	}
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldSetUserPreference(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.user_preferences\(user_id, key, value\) VALUES \(\$1, \$2, \$3\) ON CONFLICT \(user_id, key\) DO UPDATE SET value = EXCLUDED.value`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(8103918, "notifications", []byte(`{"email":true}`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.SetUserPreference(8103918, "notifications", map[string]bool{"email": true})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailSetUserPreferenceWithEmptyKey(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	// run the tested function
+	err = db.SetUserPreference(8103918, "", 2)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldDeleteUserPreference(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `DELETE FROM peridot.user_preferences WHERE user_id = \$1 AND key = \$2`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(8103918, "timezone").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.DeleteUserPreference(8103918, "timezone")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailDeleteUserPreferenceWithUnknownKey(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `DELETE FROM peridot.user_preferences WHERE user_id = \$1 AND key = \$2`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(8103918, "oops").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.DeleteUserPreference(8103918, "oops")
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// UserSpec describes a human user as known to an external source of
+// membership, such as a GitHub organization or an LDAP export, for
+// use with SyncUsers.
+type UserSpec struct {
+	// ID is the user's ID, typically the numeric Github user ID.
+	ID UserID
+	// Name is the user's name.
+	Name string
+	// Github is the user's Github user name.
+	Github string
+	// Email is the user's email address, or empty if not known.
+	Email string
+	// AccessLevel is the access level given to the user if it is
+	// newly created. It does not change existing users.
+	AccessLevel UserAccessLevel
+}
+
+// SyncUsersResult reports the changes made by SyncUsers.
+type SyncUsersResult struct {
+	// Added is the IDs of users that were created.
+	Added []UserID
+	// Updated is the IDs of existing users whose name, Github user
+	// name or email address changed.
+	Updated []UserID
+	// Disabled is the IDs of users that were disabled because they
+	// were absent from the specs.
+	Disabled []UserID
+}
+
+// SyncUsers brings the users table in line with the given specs in
+// a single transaction. Users that do not yet exist are created with
+// the spec's access level; existing users have their name, Github
+// user name and email address updated if changed, but keep their
+// access level. If disableAbsent is true, human users that are not
+// in specs are disabled, except for admins, who must be demoted
+// explicitly. Service accounts are never disabled. If any spec is
+// invalid or any change fails, nothing is changed. It returns the
+// changes made on success or an error if failing.
+func (db *DB) SyncUsers(specs []UserSpec, disableAbsent bool) (*SyncUsersResult, error) {
+	ids := make([]int64, 0, len(specs))
+	for _, spec := range specs {
+		if err := checkCallerUserID(spec.ID); err != nil {
+			return nil, err
+		}
+		if spec.Github == "" {
+			return nil, fmt.Errorf("Github user name is required for user ID %d", spec.ID)
+		}
+		if err := validateEmail(spec.Email); err != nil {
+			return nil, err
+		}
+		ids = append(ids, int64(spec.ID))
+	}
+
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+
+	// the WHERE clause skips the update, and so returns no row, if
+	// nothing has changed; xmax is 0 only for a newly inserted row
+	stmt, err := db.prepareIn(tx, "INSERT INTO peridot.users(id, github, name, email, access_level) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (id) DO UPDATE SET github = EXCLUDED.github, name = EXCLUDED.name, email = EXCLUDED.email WHERE (peridot.users.github, peridot.users.name, peridot.users.email) IS DISTINCT FROM (EXCLUDED.github, EXCLUDED.name, EXCLUDED.email) RETURNING (xmax = 0)")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	result := &SyncUsersResult{Added: []UserID{}, Updated: []UserID{}, Disabled: []UserID{}}
+	for _, spec := range specs {
+		var inserted bool
+		err = stmt.QueryRow(spec.ID, spec.Github, spec.Name, nullStringFromString(spec.Email), IntFromUserAccessLevel(spec.AccessLevel)).Scan(&inserted)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if inserted {
+			result.Added = append(result.Added, spec.ID)
+			err = addOutboxEvent(tx, "user", spec.ID, AuditActionAdd, &User{ID: spec.ID, Name: spec.Name, Github: spec.Github, Email: spec.Email, AccessLevel: spec.AccessLevel, Kind: UserKindHuman})
+		} else {
+			result.Updated = append(result.Updated, spec.ID)
+			err = addOutboxEvent(tx, "user", spec.ID, AuditActionUpdate, map[string]interface{}{"id": spec.ID, "name": spec.Name, "github": spec.Github, "email": spec.Email})
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if disableAbsent {
+		rows, err := tx.Query("UPDATE peridot.users SET access_level = $1 WHERE kind = $2 AND access_level NOT IN ($1, $3) AND NOT (id = ANY($4)) RETURNING id", IntFromUserAccessLevel(AccessDisabled), IntFromUserKind(UserKindHuman), IntFromUserAccessLevel(AccessAdmin), pq.Array(ids))
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		for rows.Next() {
+			var id UserID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				tx.Rollback()
+				return nil, err
+			}
+			result.Disabled = append(result.Disabled, id)
+		}
+		if err := rows.Err(); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		rows.Close()
+
+		for _, id := range result.Disabled {
+			err = addOutboxEvent(tx, "user", id, AuditActionUpdate, map[string]interface{}{"id": id, "access_level": AccessDisabled})
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestShouldSyncUsers(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	specs := []UserSpec{
+		{ID: 410952, Name: "John Doe", Github: "johndoe", AccessLevel: AccessViewer},
+		{ID: 8103918, Name: "Jane Doe", Github: "janedoe", Email: "janedoe@example.com", AccessLevel: AccessViewer},
+		{ID: 192304, Name: "New Person", Github: "newperson", AccessLevel: AccessViewer},
+	}
+
+	regexStmt := `INSERT INTO peridot.users\(id, github, name, email, access_level\) VALUES \(\$1, \$2, \$3, \$4, \$5\) ON CONFLICT \(id\) DO UPDATE SET github = EXCLUDED.github, name = EXCLUDED.name, email = EXCLUDED.email WHERE \(peridot.users.github, peridot.users.name, peridot.users.email\) IS DISTINCT FROM \(EXCLUDED.github, EXCLUDED.name, EXCLUDED.email\) RETURNING \(xmax = 0\)`
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexStmt)
+	// unchanged
+	mock.ExpectQuery(regexStmt).
+		WithArgs(410952, "johndoe", "John Doe", sql.NullString{}, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}))
+	// updated
+	mock.ExpectQuery(regexStmt).
+		WithArgs(8103918, "janedoe", "Jane Doe", sql.NullString{String: "janedoe@example.com", Valid: true}, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(false))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("user", "8103918", AuditActionUpdate, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// added
+	mock.ExpectQuery(regexStmt).
+		WithArgs(192304, "newperson", "New Person", sql.NullString{}, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("user", "192304", AuditActionAdd, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE peridot.users SET access_level = \$1 WHERE kind = \$2 AND access_level NOT IN \(\$1, \$3\) AND NOT \(id = ANY\(\$4\)\) RETURNING id`).
+		WithArgs(0, 0, 99, pq.Array([]int64{410952, 8103918, 192304})).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("user", "7", AuditActionUpdate, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	result, err := db.SyncUsers(specs, true)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(result.Added) != 1 || result.Added[0] != 192304 {
+		t.Errorf("expected %v, got %v", []uint32{192304}, result.Added)
+	}
+	if len(result.Updated) != 1 || result.Updated[0] != 8103918 {
+		t.Errorf("expected %v, got %v", []uint32{8103918}, result.Updated)
+	}
+	if len(result.Disabled) != 1 || result.Disabled[0] != 7 {
+		t.Errorf("expected %v, got %v", []uint32{7}, result.Disabled)
+	}
+}
+
+func TestShouldSyncUsersWithoutDisablingAbsent(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	specs := []UserSpec{
+		{ID: 192304, Name: "New Person", Github: "newperson", AccessLevel: AccessViewer},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`INSERT INTO peridot.users`)
+	mock.ExpectQuery(`INSERT INTO peridot.users`).
+		WithArgs(192304, "newperson", "New Person", sql.NullString{}, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO peridot.outbox_events").
+		WithArgs("user", "192304", AuditActionAdd, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// run the tested function
+	result, err := db.SyncUsers(specs, false)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if len(result.Disabled) != 0 {
+		t.Errorf("expected no disabled users, got %v", result.Disabled)
+	}
+}
+
+func TestShouldRollBackSyncUsersOnError(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	specs := []UserSpec{
+		{ID: 192304, Name: "New Person", Github: "newperson", AccessLevel: AccessViewer},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`INSERT INTO peridot.users`)
+	mock.ExpectQuery(`INSERT INTO peridot.users`).
+		WithArgs(192304, "newperson", "New Person", sql.NullString{}, 10).
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	// run the tested function
+	result, err := db.SyncUsers(specs, true)
+	if result != nil {
+		t.Fatalf("expected nil result, got %v", result)
+	}
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldNotSyncUsersWithInvalidSpec(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	specs := []UserSpec{
+		{ID: 192304, Name: "New Person", Github: "newperson", AccessLevel: AccessViewer},
+		{ID: 410952, Name: "John Doe", Github: "", AccessLevel: AccessViewer},
+	}
+
+	// run the tested function
+	_, err = db.SyncUsers(specs, true)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
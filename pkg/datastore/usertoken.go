@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// UserToken describes an API token issued to a User, which can be
+// used for token-based authentication (e.g. from CI integrations)
+// instead of a Github identity. The token itself is never stored;
+// only its SHA256 hash is kept in the database.
+type UserToken struct {
+	// ID is the unique ID for this token.
+	ID uint32 `json:"id"`
+	// UserID is the ID of the user to whom this token was issued.
+	UserID UserID `json:"user_id"`
+	// Scopes is the set of scopes that this token grants.
+	Scopes []string `json:"scopes"`
+	// CreatedAt is when this token was created.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// ExpiresAt is when this token expires. Should be zero value
+	// if this token does not expire.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	// LastUsedAt is when this token was last successfully
+	// validated. Should be zero value if it has never been used.
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+	// UpdatedAt is when this token was last changed, including
+	// being used. It is set by the database.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// MarshalJSON converts the UserToken into a slice of bytes containing
+// its JSON encoding, leaving out expires_at for a token that never
+// expires, last_used_at for one that has never been used, and
+// created_at and updated_at if unset.
+func (ut UserToken) MarshalJSON() ([]byte, error) {
+	type userTokenAlias UserToken
+	return json.Marshal(struct {
+		userTokenAlias
+		CreatedAt  *time.Time `json:"created_at,omitempty"`
+		ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+		LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+		UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	}{userTokenAlias: userTokenAlias(ut), CreatedAt: jsonTimePtr(ut.CreatedAt), ExpiresAt: jsonTimePtr(ut.ExpiresAt), LastUsedAt: jsonTimePtr(ut.LastUsedAt), UpdatedAt: jsonTimePtr(ut.UpdatedAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of a UserToken into the UserToken. A missing or null expires_at
+// means the token never expires, and a missing or null last_used_at
+// means it has never been used; CreatedAt and UpdatedAt are left as
+// the zero time if they are missing or null.
+func (ut *UserToken) UnmarshalJSON(b []byte) error {
+	type userTokenAlias UserToken
+	aux := struct {
+		*userTokenAlias
+		CreatedAt  *time.Time `json:"created_at"`
+		ExpiresAt  *time.Time `json:"expires_at"`
+		LastUsedAt *time.Time `json:"last_used_at"`
+		UpdatedAt  *time.Time `json:"updated_at"`
+	}{userTokenAlias: (*userTokenAlias)(ut)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	ut.CreatedAt = timeFromJSONPtr(aux.CreatedAt)
+	ut.ExpiresAt = timeFromJSONPtr(aux.ExpiresAt)
+	ut.LastUsedAt = timeFromJSONPtr(aux.LastUsedAt)
+	ut.UpdatedAt = timeFromJSONPtr(aux.UpdatedAt)
+	return nil
+}
+
+// tokenByteLength is the number of random bytes in a new token,
+// before hex encoding.
+const tokenByteLength = 32
+
+// hashToken returns the hex-encoded SHA256 hash of the given token,
+// which is the form in which tokens are stored and looked up.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// nullTimeFromTime converts a time.Time to a pq.NullTime, treating
+// the zero value as NULL.
+func nullTimeFromTime(t time.Time) pq.NullTime {
+	return pq.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// CreateToken creates a new API token for the User with the given
+// ID, granting the given scopes. If expiresAt is the zero value, the
+// token does not expire. It returns the new token's ID and the token
+// itself on success, or an error if failing. The token is not
+// retrievable again after this call returns.
+func (db *DB) CreateToken(userID UserID, scopes []string, expiresAt time.Time) (uint32, string, error) {
+	b := make([]byte, tokenByteLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return 0, "", err
+	}
+	token := hex.EncodeToString(b)
+
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.user_tokens(user_id, token_hash, scopes, created_at, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING id")
+	if err != nil {
+		return 0, "", err
+	}
+
+	var tokenID uint32
+	err = stmt.QueryRow(userID, hashToken(token), pq.Array(scopes), now(), nullTimeFromTime(normalizeTime(expiresAt))).Scan(&tokenID)
+	if err != nil {
+		return 0, "", err
+	}
+	return tokenID, token, nil
+}
+
+// ValidateToken looks up the given API token, and if it exists and
+// has not expired, records that it was used and returns it. It
+// returns nil and an error if the token is unknown or expired.
+func (db *DB) ValidateToken(token string) (*UserToken, error) {
+	var ut UserToken
+	var expiresAt, lastUsedAt pq.NullTime
+	err := db.sqldb.QueryRow("UPDATE peridot.user_tokens SET last_used_at = $2 WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > $2) RETURNING id, user_id, scopes, created_at, expires_at, last_used_at, updated_at", hashToken(token), now()).
+		Scan(&ut.ID, &ut.UserID, pq.Array(&ut.Scopes), &ut.CreatedAt, &expiresAt, &lastUsedAt, &ut.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no valid token found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ut.CreatedAt = normalizeTime(ut.CreatedAt)
+	ut.ExpiresAt = normalizeTime(expiresAt.Time)
+	ut.LastUsedAt = normalizeTime(lastUsedAt.Time)
+	ut.UpdatedAt = normalizeTime(ut.UpdatedAt)
+	return &ut, nil
+}
+
+// ListTokensForUser returns a slice of all API tokens issued to the
+// User with the given ID, including expired tokens.
+func (db *DB) ListTokensForUser(userID UserID) ([]*UserToken, error) {
+	rows, err := db.sqldb.Query("SELECT id, user_id, scopes, created_at, expires_at, last_used_at, updated_at FROM peridot.user_tokens WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uts := []*UserToken{}
+	for rows.Next() {
+		ut := &UserToken{}
+		var expiresAt, lastUsedAt pq.NullTime
+		err := rows.Scan(&ut.ID, &ut.UserID, pq.Array(&ut.Scopes), &ut.CreatedAt, &expiresAt, &lastUsedAt, &ut.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ut.CreatedAt = normalizeTime(ut.CreatedAt)
+		ut.ExpiresAt = normalizeTime(expiresAt.Time)
+		ut.LastUsedAt = normalizeTime(lastUsedAt.Time)
+		ut.UpdatedAt = normalizeTime(ut.UpdatedAt)
+		uts = append(uts, ut)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return uts, nil
+}
+
+// RevokeToken deletes the API token with the given ID, so that it
+// can no longer be validated. It returns nil on success or an error
+// if failing.
+func (db *DB) RevokeToken(id uint32) error {
+	stmt, err := db.prepare("DELETE FROM peridot.user_tokens WHERE id = $1")
+	if err != nil {
+		return err
+	}
+	result, err := stmt.Exec(id)
+
+	// check error
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "token", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
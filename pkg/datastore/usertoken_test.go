@@ -0,0 +1,338 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+// tokenHashArg matches a hashed-token argument against the hash of
+// whatever token string is stored in it at match time.
+type tokenHashArg struct {
+	token *string
+}
+
+func (a tokenHashArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	return ok && s == hashToken(*a.token)
+}
+
+func TestShouldCreateToken(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	regexStmt := `INSERT INTO peridot.user_tokens\(user_id, token_hash, scopes, created_at, expires_at\) VALUES \(\$1, \$2, \$3, \$4, \$5\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery("INSERT INTO peridot.user_tokens").
+		WithArgs(8103918, sqlmock.AnyArg(), pq.Array([]string{"ci", "read"}), sqlmock.AnyArg(), pq.NullTime{Time: expiresAt, Valid: true}).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	// run the tested function
+	tokenID, token, err := db.CreateToken(8103918, []string{"ci", "read"}, expiresAt)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// check returned values
+	if tokenID != 3 {
+		t.Errorf("expected %v, got %v", 3, tokenID)
+	}
+	if len(token) != tokenByteLength*2 {
+		t.Errorf("expected token of length %d, got %d", tokenByteLength*2, len(token))
+	}
+}
+
+func TestShouldValidateToken(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	token := "0123456789abcdef"
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	lastUsedAt := time.Date(2019, 6, 2, 13, 53, 41, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "user_id", "scopes", "created_at", "expires_at", "last_used_at", "updated_at"}).
+		AddRow(3, 8103918, "{ci,read}", createdAt, nil, lastUsedAt, lastUsedAt)
+	mock.ExpectQuery(`UPDATE peridot.user_tokens SET last_used_at = \$2 WHERE token_hash = \$1 AND \(expires_at IS NULL OR expires_at > \$2\) RETURNING id, user_id, scopes, created_at, expires_at, last_used_at, updated_at`).
+		WithArgs(tokenHashArg{token: &token}, sqlmock.AnyArg()).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	ut, err := db.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if ut.ID != 3 {
+		t.Errorf("expected %v, got %v", 3, ut.ID)
+	}
+	if ut.UserID != 8103918 {
+		t.Errorf("expected %v, got %v", 8103918, ut.UserID)
+	}
+	if len(ut.Scopes) != 2 || ut.Scopes[0] != "ci" || ut.Scopes[1] != "read" {
+		t.Errorf("expected %v, got %v", []string{"ci", "read"}, ut.Scopes)
+	}
+	if ut.CreatedAt != createdAt {
+		t.Errorf("expected %v, got %v", createdAt, ut.CreatedAt)
+	}
+	if !ut.ExpiresAt.IsZero() {
+		t.Errorf("expected zero time, got %v", ut.ExpiresAt)
+	}
+	if ut.LastUsedAt != lastUsedAt {
+		t.Errorf("expected %v, got %v", lastUsedAt, ut.LastUsedAt)
+	}
+	if ut.UpdatedAt != lastUsedAt {
+		t.Errorf("expected %v, got %v", lastUsedAt, ut.UpdatedAt)
+	}
+}
+
+func TestShouldFailValidateTokenForUnknownOrExpiredToken(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	token := "oops"
+	mock.ExpectQuery(`UPDATE peridot.user_tokens SET last_used_at`).
+		WithArgs(tokenHashArg{token: &token}, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{}))
+
+	// run the tested function
+	ut, err := db.ValidateToken(token)
+	if ut != nil {
+		t.Fatalf("expected nil token, got %v", ut)
+	}
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldListTokensForUser(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "user_id", "scopes", "created_at", "expires_at", "last_used_at", "updated_at"}).
+		AddRow(3, 8103918, "{ci}", createdAt, expiresAt, nil, createdAt).
+		AddRow(5, 8103918, "{}", createdAt, nil, nil, createdAt)
+	mock.ExpectQuery(`SELECT id, user_id, scopes, created_at, expires_at, last_used_at, updated_at FROM peridot.user_tokens WHERE user_id = \$1 ORDER BY id`).
+		WithArgs(8103918).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.ListTokensForUser(8103918)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	if len(gotRows) != 2 {
+		t.Fatalf("expected len %d, got %d", 2, len(gotRows))
+	}
+	ut0 := gotRows[0]
+	if ut0.ID != 3 {
+		t.Errorf("expected %v, got %v", 3, ut0.ID)
+	}
+	if ut0.ExpiresAt != expiresAt {
+		t.Errorf("expected %v, got %v", expiresAt, ut0.ExpiresAt)
+	}
+	if !ut0.LastUsedAt.IsZero() {
+		t.Errorf("expected zero time, got %v", ut0.LastUsedAt)
+	}
+	ut1 := gotRows[1]
+	if len(ut1.Scopes) != 0 {
+		t.Errorf("expected empty scopes, got %v", ut1.Scopes)
+	}
+}
+
+func TestShouldRevokeToken(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `DELETE FROM peridot.user_tokens WHERE id = \$1`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.RevokeToken(3)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailRevokeTokenWithUnknownID(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `DELETE FROM peridot.user_tokens WHERE id = \$1`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectExec(regexStmt).
+		WithArgs(413).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.RevokeToken(413)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// ===== JSON marshalling and unmarshalling =====
+func TestCanMarshalUserTokenToJSON(t *testing.T) {
+	ut := &UserToken{
+		ID:        3,
+		UserID:    8103918,
+		Scopes:    []string{"ci"},
+		CreatedAt: time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC),
+	}
+
+	js, err := json.Marshal(ut)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+
+	// read back in as empty interface to check values
+	var mapGot interface{}
+	err = json.Unmarshal(js, &mapGot)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	mGot := mapGot.(map[string]interface{})
+
+	// check for expected values, and that no hash is exposed
+	if float64(ut.ID) != mGot["id"].(float64) {
+		t.Errorf("expected %v, got %v", float64(ut.ID), mGot["id"].(float64))
+	}
+	if float64(ut.UserID) != mGot["user_id"].(float64) {
+		t.Errorf("expected %v, got %v", float64(ut.UserID), mGot["user_id"].(float64))
+	}
+	if _, ok := mGot["token_hash"]; ok {
+		t.Errorf("expected no token_hash key, got %v", mGot["token_hash"])
+	}
+}
+
+func TestUserTokenJSONRoundTripsExpiryOfUnusedToken(t *testing.T) {
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC)
+	expiresAt := time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)
+	ut := UserToken{ID: 3, UserID: 1, Scopes: []string{"read"}, CreatedAt: createdAt, ExpiresAt: expiresAt}
+
+	js, err := json.Marshal(ut)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+
+	// the token has never been used, so has no last use time
+	var mGot map[string]interface{}
+	err = json.Unmarshal(js, &mGot)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if _, ok := mGot["last_used_at"]; ok {
+		t.Errorf("expected no last_used_at key, got %v", mGot["last_used_at"])
+	}
+	if _, ok := mGot["updated_at"]; ok {
+		t.Errorf("expected no updated_at key, got %v", mGot["updated_at"])
+	}
+
+	ut2 := &UserToken{}
+	err = json.Unmarshal(js, ut2)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if !ut2.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("expected %v, got %v", expiresAt, ut2.ExpiresAt)
+	}
+	if !ut2.LastUsedAt.IsZero() {
+		t.Errorf("expected zero time, got %v", ut2.LastUsedAt)
+	}
+}
+
+func TestCanUnmarshalNonExpiringUserTokenWithNullExpiry(t *testing.T) {
+	ut := &UserToken{}
+	js := []byte(`{"id":3, "user_id":1, "scopes":["read"], "created_at":"2019-05-02T13:53:41Z", "expires_at":null, "last_used_at":"2019-05-03T08:00:00Z"}`)
+
+	err := json.Unmarshal(js, ut)
+	if err != nil {
+		t.Fatalf("got non-nil error: %v", err)
+	}
+	if !ut.ExpiresAt.IsZero() {
+		t.Errorf("expected zero time, got %v", ut.ExpiresAt)
+	}
+	want := time.Date(2019, 5, 3, 8, 0, 0, 0, time.UTC)
+	if !ut.LastUsedAt.Equal(want) {
+		t.Errorf("expected %v, got %v", want, ut.LastUsedAt)
+	}
+}
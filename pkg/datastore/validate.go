@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import "fmt"
+
+// ValidationError is returned when a value fails validation before
+// it is written to the database.
+type ValidationError struct {
+	// Entity is the kind of value that failed validation, such as
+	// "repo" or "agent".
+	Entity string
+	// Field is the name of the field that failed validation.
+	Field string
+	// Reason describes why the field's value is invalid.
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s %s: %s", e.Entity, e.Field, e.Reason)
+}
+
+// requireNonEmpty returns a *ValidationError if value is empty.
+func requireNonEmpty(entity string, field string, value string) error {
+	if value == "" {
+		return &ValidationError{Entity: entity, Field: field, Reason: "must not be empty"}
+	}
+	return nil
+}
+
+// isLowerHex reports whether s consists of exactly n lowercase
+// hexadecimal digits.
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateStatusHealth returns a *ValidationError if st or h is not
+// one of the defined Status or Health values.
+func validateStatusHealth(entity string, st Status, h Health) error {
+	if _, err := StatusFromInt(int(st)); err != nil {
+		return &ValidationError{Entity: entity, Field: "status", Reason: err.Error()}
+	}
+	if _, err := HealthFromInt(int(h)); err != nil {
+		return &ValidationError{Entity: entity, Field: "health", Reason: err.Error()}
+	}
+	return nil
+}
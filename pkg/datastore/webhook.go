@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Event types to which a Webhook can subscribe.
+const (
+	// WebhookEventRepoPullFinished is sent when a RepoPull has
+	// finished being pulled.
+	WebhookEventRepoPullFinished = "repopull.finished"
+	// WebhookEventJobFinished is sent when a Job has finished.
+	WebhookEventJobFinished = "job.finished"
+	// WebhookEventPolicyFailed is sent when a RepoPull fails a
+	// Policy evaluation.
+	WebhookEventPolicyFailed = "policy.failed"
+)
+
+// Webhook describes a subscription by an external system to be
+// notified of events in peridot.
+type Webhook struct {
+	// ID is the unique ID for this webhook.
+	ID uint32 `json:"id"`
+	// ProjectID is the ID of the Project whose events are sent, or
+	// 0 if events for all projects are sent.
+	ProjectID ProjectID `json:"project_id,omitempty"`
+	// URL is the http or https URL to which events are posted.
+	URL string `json:"url"`
+	// Secret is used to sign payloads so that the receiver can
+	// verify them. It is never included in the JSON encoding, and is
+	// encrypted at rest if the DB has a KeyProvider.
+	Secret string `json:"-"`
+	// EventTypes are the WebhookEvent values to send.
+	EventTypes []string `json:"event_types"`
+	// IsEnabled is false if deliveries are paused.
+	IsEnabled bool `json:"is_enabled"`
+	// CreatedAt is when the webhook was created.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks that the Webhook's fields are well-formed. It
+// returns nil if so, or a *ValidationError describing the first
+// invalid field.
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &ValidationError{Entity: "webhook", Field: "url", Reason: "must be an absolute http or https URL"}
+	}
+	if len(w.EventTypes) == 0 {
+		return &ValidationError{Entity: "webhook", Field: "event_types", Reason: "must not be empty"}
+	}
+	for _, et := range w.EventTypes {
+		switch et {
+		case WebhookEventRepoPullFinished, WebhookEventJobFinished, WebhookEventPolicyFailed:
+		default:
+			return &ValidationError{Entity: "webhook", Field: "event_types", Reason: fmt.Sprintf("unknown event type %q", et)}
+		}
+	}
+	return nil
+}
+
+const webhookColumns = "id, project_id, url, secret, event_types, is_enabled, created_at"
+
+// scanWebhook reads a Webhook from a row selecting webhookColumns.
+func scanWebhook(rs rowScanner) (*Webhook, error) {
+	w := &Webhook{}
+	var projectID sql.NullInt64
+	err := rs.Scan(&w.ID, &projectID, &w.URL, &w.Secret, pq.Array(&w.EventTypes), &w.IsEnabled, &w.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	w.ProjectID = ProjectID(projectID.Int64)
+	w.CreatedAt = normalizeTime(w.CreatedAt)
+	return w, nil
+}
+
+// queryWebhooks runs a query selecting webhookColumns and returns
+// the resulting webhooks.
+func (db *DB) queryWebhooks(query string, args ...interface{}) ([]*Webhook, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		w.Secret, err = db.decryptSecret(w.Secret)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetAllWebhooks returns a slice of all webhooks, ordered by ID.
+func (db *DB) GetAllWebhooks() ([]*Webhook, error) {
+	return db.queryWebhooks("SELECT " + webhookColumns + " FROM peridot.webhooks ORDER BY id")
+}
+
+// CountAllWebhooks returns the number of webhooks in the database.
+func (db *DB) CountAllWebhooks() (int, error) {
+	return db.count("SELECT COUNT(*) FROM peridot.webhooks")
+}
+
+// GetWebhooksForEvent returns a slice of all enabled webhooks that
+// subscribe to the given event type for the Project with the given
+// ID, including those for all projects, ordered by ID.
+func (db *DB) GetWebhooksForEvent(projectID ProjectID, eventType string) ([]*Webhook, error) {
+	return db.queryWebhooks("SELECT "+webhookColumns+" FROM peridot.webhooks WHERE is_enabled AND $1 = ANY (event_types) AND (project_id IS NULL OR project_id = $2) ORDER BY id", eventType, projectID)
+}
+
+// GetWebhookByID returns the Webhook with the given ID, or nil and
+// an error if not found.
+func (db *DB) GetWebhookByID(id uint32) (*Webhook, error) {
+	w, err := scanWebhook(db.sqldb.QueryRow("SELECT "+webhookColumns+" FROM peridot.webhooks WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{Entity: "webhook", ID: fmt.Sprint(id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	w.Secret, err = db.decryptSecret(w.Secret)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// AddWebhook adds a new, enabled webhook posting the given event
+// types to url, signed with secret. If projectID is 0, events for
+// all projects are sent. The secret is encrypted at rest if a
+// KeyProvider is set. It returns the new webhook's ID on success or
+// an error if failing.
+func (db *DB) AddWebhook(projectID ProjectID, url string, secret string, eventTypes []string) (uint32, error) {
+	w := &Webhook{ProjectID: projectID, URL: url, Secret: secret, EventTypes: eventTypes}
+	if err := w.Validate(); err != nil {
+		return 0, err
+	}
+
+	storedSecret, err := db.encryptSecret(secret)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := db.prepare("INSERT INTO peridot.webhooks(project_id, url, secret, event_types, is_enabled, created_at) VALUES ($1, $2, $3, $4, true, $5) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var webhookID uint32
+	err = stmt.QueryRow(sql.NullInt64{Int64: int64(projectID), Valid: projectID != 0}, url, storedSecret, pq.Array(eventTypes), now()).Scan(&webhookID)
+	if err != nil {
+		return 0, err
+	}
+	return webhookID, nil
+}
+
+// UpdateWebhook updates the existing Webhook with w's ID, replacing
+// its URL, event types and enabled flag with w's. Its secret is
+// replaced only if w.Secret is non-empty. Its ProjectID cannot be
+// changed. It returns nil on success or an error if failing.
+func (db *DB) UpdateWebhook(w *Webhook) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+
+	var result sql.Result
+	var err error
+	if w.Secret != "" {
+		var storedSecret string
+		storedSecret, err = db.encryptSecret(w.Secret)
+		if err != nil {
+			return err
+		}
+		result, err = db.sqldb.Exec("UPDATE peridot.webhooks SET url = $1, event_types = $2, is_enabled = $3, secret = $4 WHERE id = $5", w.URL, pq.Array(w.EventTypes), w.IsEnabled, storedSecret, w.ID)
+	} else {
+		result, err = db.sqldb.Exec("UPDATE peridot.webhooks SET url = $1, event_types = $2, is_enabled = $3 WHERE id = $4", w.URL, pq.Array(w.EventTypes), w.IsEnabled, w.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "webhook", ID: fmt.Sprint(w.ID)}
+	}
+
+	return nil
+}
+
+// DeleteWebhook deletes the existing Webhook with the given ID,
+// together with its delivery log. It returns nil on success or an
+// error if failing.
+func (db *DB) DeleteWebhook(id uint32) error {
+	result, err := db.sqldb.Exec("DELETE FROM peridot.webhooks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually deleted
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "webhook", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestShouldGetWebhooksForEvent(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	sentRows := sqlmock.NewRows([]string{"id", "project_id", "url", "secret", "event_types", "is_enabled", "created_at"}).
+		AddRow(1, nil, "https://ci.example.com/hooks/peridot", "s3cr3t", "{policy.failed}", true, createdAt).
+		AddRow(4, 2, "https://chat.example.com/hook", "hunter2", "{repopull.finished,policy.failed}", true, createdAt)
+	mock.ExpectQuery(`SELECT id, project_id, url, secret, event_types, is_enabled, created_at FROM peridot.webhooks WHERE is_enabled AND \$1 = ANY \(event_types\) AND \(project_id IS NULL OR project_id = \$2\) ORDER BY id`).
+		WithArgs("policy.failed", 2).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetWebhooksForEvent(2, WebhookEventPolicyFailed)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*Webhook{
+		&Webhook{ID: 1, URL: "https://ci.example.com/hooks/peridot", Secret: "s3cr3t", EventTypes: []string{"policy.failed"}, IsEnabled: true, CreatedAt: createdAt},
+		&Webhook{ID: 4, ProjectID: 2, URL: "https://chat.example.com/hook", Secret: "hunter2", EventTypes: []string{"repopull.finished", "policy.failed"}, IsEnabled: true, CreatedAt: createdAt},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldAddWebhook(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	regexStmt := `INSERT INTO peridot.webhooks\(project_id, url, secret, event_types, is_enabled, created_at\) VALUES \(\$1, \$2, \$3, \$4, true, \$5\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(nilArg{}, "https://ci.example.com/hooks/peridot", "s3cr3t", pq.Array([]string{"policy.failed"}), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	// run the tested function
+	id, err := db.AddWebhook(0, "https://ci.example.com/hooks/peridot", "s3cr3t", []string{WebhookEventPolicyFailed})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 1 {
+		t.Errorf("expected %v, got %v", 1, id)
+	}
+}
+
+func TestShouldUpdateWebhookKeepingSecret(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`UPDATE peridot.webhooks SET url = \$1, event_types = \$2, is_enabled = \$3 WHERE id = \$4`).
+		WithArgs("https://ci.example.com/hooks/peridot", pq.Array([]string{"policy.failed"}), false, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.UpdateWebhook(&Webhook{ID: 1, URL: "https://ci.example.com/hooks/peridot", EventTypes: []string{WebhookEventPolicyFailed}, IsEnabled: false})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailAddWebhookWithInvalidFields(t *testing.T) {
+	tests := []struct {
+		url        string
+		eventTypes []string
+		field      string
+	}{
+		{"ftp://example.com/hook", []string{WebhookEventJobFinished}, "url"},
+		{"/hooks/peridot", []string{WebhookEventJobFinished}, "url"},
+		{"https://example.com/hook", nil, "event_types"},
+		{"https://example.com/hook", []string{"job.started"}, "event_types"},
+	}
+
+	for _, tt := range tests {
+		_, err := (&DB{}).AddWebhook(0, tt.url, "s3cr3t", tt.eventTypes)
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("for %s %v: expected *ValidationError, got %v", tt.url, tt.eventTypes, err)
+			continue
+		}
+		if verr.Field != tt.field {
+			t.Errorf("expected field %v, got %v", tt.field, verr.Field)
+		}
+	}
+}
+
+func TestWebhookJSONOmitsSecret(t *testing.T) {
+	js, err := json.Marshal(&Webhook{ID: 1, URL: "https://example.com/hook", Secret: "s3cr3t", EventTypes: []string{WebhookEventJobFinished}})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if strings.Contains(string(js), "s3cr3t") {
+		t.Errorf("expected secret to be omitted, got %s", js)
+	}
+}
@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WebhookDelivery describes the delivery of one event to a Webhook,
+// including any retries.
+type WebhookDelivery struct {
+	// ID is the unique ID for this delivery.
+	ID uint64 `json:"id"`
+	// WebhookID is the ID of the Webhook to which the event is
+	// delivered.
+	WebhookID uint32 `json:"webhook_id"`
+	// EventType is the WebhookEvent value of the event.
+	EventType string `json:"event_type"`
+	// Payload is the JSON body to be posted.
+	Payload json.RawMessage `json:"payload"`
+	// PayloadSHA256 is the lowercase hex SHA256 hash of Payload.
+	PayloadSHA256 string `json:"payload_sha256"`
+	// Attempts is the number of delivery attempts made so far.
+	Attempts int `json:"attempts"`
+	// LastResponseCode is the HTTP status code returned by the most
+	// recent attempt, or 0 if there was no response.
+	LastResponseCode int `json:"last_response_code,omitempty"`
+	// CreatedAt is when the delivery was queued.
+	CreatedAt time.Time `json:"created_at"`
+	// NextAttemptAt is when the delivery should next be attempted.
+	// Should be zero value if no further attempts will be made.
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"`
+	// DeliveredAt is when the delivery succeeded. Should be zero
+	// value if it has not succeeded.
+	DeliveredAt time.Time `json:"delivered_at,omitempty"`
+}
+
+// MarshalJSON converts the WebhookDelivery into a slice of bytes
+// containing its JSON encoding, leaving out next_attempt_at once no
+// further attempts will be made and delivered_at until delivery
+// succeeds.
+func (wd WebhookDelivery) MarshalJSON() ([]byte, error) {
+	type webhookDeliveryAlias WebhookDelivery
+	return json.Marshal(struct {
+		webhookDeliveryAlias
+		NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+		DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	}{webhookDeliveryAlias: webhookDeliveryAlias(wd), NextAttemptAt: jsonTimePtr(wd.NextAttemptAt), DeliveredAt: jsonTimePtr(wd.DeliveredAt)})
+}
+
+// UnmarshalJSON converts a slice of bytes containing the JSON encoding
+// of a WebhookDelivery into the WebhookDelivery. A missing or null
+// next_attempt_at or delivered_at is read as the zero time.
+func (wd *WebhookDelivery) UnmarshalJSON(b []byte) error {
+	type webhookDeliveryAlias WebhookDelivery
+	aux := struct {
+		*webhookDeliveryAlias
+		NextAttemptAt *time.Time `json:"next_attempt_at"`
+		DeliveredAt   *time.Time `json:"delivered_at"`
+	}{webhookDeliveryAlias: (*webhookDeliveryAlias)(wd)}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	wd.NextAttemptAt = timeFromJSONPtr(aux.NextAttemptAt)
+	wd.DeliveredAt = timeFromJSONPtr(aux.DeliveredAt)
+	return nil
+}
+
+const webhookDeliveryColumns = "id, webhook_id, event_type, payload, payload_sha256, attempts, last_response_code, created_at, next_attempt_at, delivered_at"
+
+// scanWebhookDelivery reads a WebhookDelivery from a row selecting
+// webhookDeliveryColumns.
+func scanWebhookDelivery(rs rowScanner) (*WebhookDelivery, error) {
+	wd := &WebhookDelivery{}
+	var payload []byte
+	var nextAttemptAt, deliveredAt pq.NullTime
+	err := rs.Scan(&wd.ID, &wd.WebhookID, &wd.EventType, &payload, &wd.PayloadSHA256, &wd.Attempts, &wd.LastResponseCode, &wd.CreatedAt, &nextAttemptAt, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	wd.Payload = json.RawMessage(payload)
+	wd.CreatedAt = normalizeTime(wd.CreatedAt)
+	if nextAttemptAt.Valid {
+		wd.NextAttemptAt = normalizeTime(nextAttemptAt.Time)
+	}
+	if deliveredAt.Valid {
+		wd.DeliveredAt = normalizeTime(deliveredAt.Time)
+	}
+	return wd, nil
+}
+
+// queryWebhookDeliveries runs a query selecting
+// webhookDeliveryColumns and returns the resulting deliveries.
+func (db *DB) queryWebhookDeliveries(query string, args ...interface{}) ([]*WebhookDelivery, error) {
+	rows, err := db.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+	for rows.Next() {
+		wd, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, wd)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// GetWebhookDeliveries returns a slice of all deliveries queued for
+// the Webhook with the given ID, ordered from newest to oldest.
+func (db *DB) GetWebhookDeliveries(webhookID uint32) ([]*WebhookDelivery, error) {
+	return db.queryWebhookDeliveries("SELECT "+webhookDeliveryColumns+" FROM peridot.webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC, id DESC", webhookID)
+}
+
+// GetPendingWebhookDeliveries returns a slice of up to limit
+// deliveries to enabled webhooks that have not yet succeeded and
+// are due to be attempted, ordered by when they are due.
+func (db *DB) GetPendingWebhookDeliveries(limit uint32) ([]*WebhookDelivery, error) {
+	return db.queryWebhookDeliveries("SELECT wd.id, wd.webhook_id, wd.event_type, wd.payload, wd.payload_sha256, wd.attempts, wd.last_response_code, wd.created_at, wd.next_attempt_at, wd.delivered_at FROM peridot.webhook_deliveries wd JOIN peridot.webhooks w ON w.id = wd.webhook_id WHERE w.is_enabled AND wd.delivered_at IS NULL AND wd.next_attempt_at <= $1 ORDER BY wd.next_attempt_at, wd.id LIMIT $2", now(), limit)
+}
+
+// AddWebhookDelivery queues the delivery of an event of the given
+// type, with the given JSON payload, to the Webhook with the given
+// ID. It is due to be attempted immediately. It returns the new
+// delivery's ID on success or an error if failing.
+func (db *DB) AddWebhookDelivery(webhookID uint32, eventType string, payload json.RawMessage) (uint64, error) {
+	if !json.Valid(payload) {
+		return 0, &ValidationError{Entity: "webhook delivery", Field: "payload", Reason: "must be valid JSON"}
+	}
+	sum := sha256.Sum256(payload)
+
+	stmt, err := db.prepare("INSERT INTO peridot.webhook_deliveries(webhook_id, event_type, payload, payload_sha256, attempts, last_response_code, created_at, next_attempt_at) VALUES ($1, $2, $3, $4, 0, 0, $5, $5) RETURNING id")
+	if err != nil {
+		return 0, err
+	}
+
+	var deliveryID uint64
+	err = stmt.QueryRow(webhookID, eventType, []byte(payload), hex.EncodeToString(sum[:]), now()).Scan(&deliveryID)
+	if err != nil {
+		return 0, err
+	}
+	return deliveryID, nil
+}
+
+// RecordWebhookDeliveryAttempt records an attempt to deliver the
+// WebhookDelivery with the given ID, which received the given HTTP
+// status code (or 0 if there was no response). If succeeded is
+// true, the delivery is marked as delivered. Otherwise it is due to
+// be retried at nextAttemptAt or, if that is the zero value, it
+// will not be retried. It returns nil on success or an error if
+// failing.
+func (db *DB) RecordWebhookDeliveryAttempt(id uint64, responseCode int, succeeded bool, nextAttemptAt time.Time) error {
+	var deliveredAt pq.NullTime
+	if succeeded {
+		deliveredAt = nullTimeFromTime(now())
+		nextAttemptAt = time.Time{}
+	}
+
+	result, err := db.sqldb.Exec("UPDATE peridot.webhook_deliveries SET attempts = attempts + 1, last_response_code = $1, delivered_at = $2, next_attempt_at = $3 WHERE id = $4 AND delivered_at IS NULL", responseCode, deliveredAt, nullTimeFromTime(normalizeTime(nextAttemptAt)), id)
+	if err != nil {
+		return err
+	}
+
+	// check that something was actually updated
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &NotFoundError{Entity: "undelivered webhook delivery", ID: fmt.Sprint(id)}
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastore
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShouldGetPendingWebhookDeliveries(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	createdAt := time.Date(2019, 5, 2, 13, 53, 41, 671764000, time.UTC)
+	nextAt := time.Date(2019, 5, 2, 13, 58, 41, 671764000, time.UTC)
+	sha := "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	sentRows := sqlmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "payload_sha256", "attempts", "last_response_code", "created_at", "next_attempt_at", "delivered_at"}).
+		AddRow(17, 1, "policy.failed", []byte(`{"repopull_id":36}`), sha, 1, 503, createdAt, nextAt, nil)
+	mock.ExpectQuery(`SELECT (.+) FROM peridot.webhook_deliveries wd JOIN peridot.webhooks w ON w.id = wd.webhook_id WHERE w.is_enabled AND wd.delivered_at IS NULL AND wd.next_attempt_at <= \$1 ORDER BY wd.next_attempt_at, wd.id LIMIT \$2`).
+		WithArgs(sqlmock.AnyArg(), 50).
+		WillReturnRows(sentRows)
+
+	// run the tested function
+	gotRows, err := db.GetPendingWebhookDeliveries(50)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	// and check returned values
+	want := []*WebhookDelivery{
+		&WebhookDelivery{
+			ID: 17, WebhookID: 1, EventType: WebhookEventPolicyFailed,
+			Payload: json.RawMessage(`{"repopull_id":36}`), PayloadSHA256: sha,
+			Attempts: 1, LastResponseCode: 503, CreatedAt: createdAt, NextAttemptAt: nextAt,
+		},
+	}
+	if !reflect.DeepEqual(want, gotRows) {
+		t.Errorf("expected %#v, got %#v", want, gotRows)
+	}
+}
+
+func TestShouldAddWebhookDeliveryWithPayloadHash(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	payload := []byte(`{"repopull_id":36}`)
+	regexStmt := `INSERT INTO peridot.webhook_deliveries\(webhook_id, event_type, payload, payload_sha256, attempts, last_response_code, created_at, next_attempt_at\) VALUES \(\$1, \$2, \$3, \$4, 0, 0, \$5, \$5\) RETURNING id`
+	mock.ExpectPrepare(regexStmt)
+	mock.ExpectQuery(regexStmt).
+		WithArgs(1, "policy.failed", payload, "9140ae84526968f1febb84dd56a88015d1b0e91cdaac04100002d96aa8ec2fd3", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(17))
+
+	// run the tested function
+	id, err := db.AddWebhookDelivery(1, WebhookEventPolicyFailed, payload)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+
+	if id != 17 {
+		t.Errorf("expected %v, got %v", 17, id)
+	}
+}
+
+func TestShouldFailAddWebhookDeliveryWithInvalidPayload(t *testing.T) {
+	_, err := (&DB{}).AddWebhookDelivery(1, WebhookEventPolicyFailed, json.RawMessage(`{"repopull_id":`))
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("expected *ValidationError, got %v", err)
+	}
+}
+
+func TestShouldRecordFailedWebhookDeliveryAttempt(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	nextAt := time.Date(2019, 5, 2, 13, 58, 41, 671764000, time.UTC)
+	mock.ExpectExec(`UPDATE peridot.webhook_deliveries SET attempts = attempts \+ 1, last_response_code = \$1, delivered_at = \$2, next_attempt_at = \$3 WHERE id = \$4 AND delivered_at IS NULL`).
+		WithArgs(503, nilArg{}, nextAt, 17).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// run the tested function
+	err = db.RecordWebhookDeliveryAttempt(17, 503, false, nextAt)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestShouldFailRecordAttemptForDeliveredWebhookDelivery(t *testing.T) {
+	// set up mock
+	sqldb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("got error when creating db mock: %v", err)
+	}
+	defer sqldb.Close()
+	db := DB{sqldb: sqldb}
+
+	mock.ExpectExec(`UPDATE peridot.webhook_deliveries SET attempts = attempts \+ 1`).
+		WithArgs(200, sqlmock.AnyArg(), nilArg{}, 17).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// run the tested function
+	err = db.RecordWebhookDeliveryAttempt(17, 200, true, time.Time{})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// check sqlmock expectations
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,444 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+// Package datastorepb contains protocol buffer messages mirroring
+// the peridot datastore entities, together with converters between
+// those messages and the corresponding datastore types.
+//
+// datastore.pb.go is generated from datastore.proto; regenerate it
+// after changing the .proto file.
+package datastorepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative datastore.proto
+
+import (
+	"time"
+
+	"github.com/swinslow/peridot-db/pkg/datastore"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ===== Helpers =====
+
+// protoFromTime converts a time.Time to a Timestamp, returning nil
+// for the zero value.
+func protoFromTime(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// timeFromProto converts a Timestamp to a time.Time in UTC, returning
+// the zero value for nil.
+func timeFromProto(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// ===== Enums =====
+
+// ProtoFromStatus converts a datastore Status to its message value.
+func ProtoFromStatus(st datastore.Status) Status {
+	return Status(datastore.IntFromStatus(st))
+}
+
+// StatusFromProto converts a Status message value to the datastore
+// Status. It returns an error if the value is not a valid Status.
+func StatusFromProto(st Status) (datastore.Status, error) {
+	return datastore.StatusFromInt(int(st))
+}
+
+// ProtoFromHealth converts a datastore Health to its message value.
+func ProtoFromHealth(h datastore.Health) Health {
+	return Health(datastore.IntFromHealth(h))
+}
+
+// HealthFromProto converts a Health message value to the datastore
+// Health. It returns an error if the value is not a valid Health.
+func HealthFromProto(h Health) (datastore.Health, error) {
+	return datastore.HealthFromInt(int(h))
+}
+
+// ProtoFromAgentHealth converts a datastore AgentHealth to its
+// message value.
+func ProtoFromAgentHealth(ah datastore.AgentHealth) AgentHealth {
+	return AgentHealth(datastore.IntFromAgentHealth(ah))
+}
+
+// AgentHealthFromProto converts an AgentHealth message value to the
+// datastore AgentHealth. It returns an error if the value is not a
+// valid AgentHealth.
+func AgentHealthFromProto(ah AgentHealth) (datastore.AgentHealth, error) {
+	return datastore.AgentHealthFromInt(int(ah))
+}
+
+// ProtoFromUserAccessLevel converts a datastore UserAccessLevel to
+// its message value.
+func ProtoFromUserAccessLevel(ual datastore.UserAccessLevel) UserAccessLevel {
+	return UserAccessLevel(datastore.IntFromUserAccessLevel(ual))
+}
+
+// UserAccessLevelFromProto converts a UserAccessLevel message value to
+// the datastore UserAccessLevel. It returns an error if the value is
+// not a valid UserAccessLevel.
+func UserAccessLevelFromProto(ual UserAccessLevel) (datastore.UserAccessLevel, error) {
+	return datastore.UserAccessLevelFromInt(int(ual))
+}
+
+// ProtoFromUserKind converts a datastore UserKind to its message
+// value.
+func ProtoFromUserKind(uk datastore.UserKind) UserKind {
+	return UserKind(datastore.IntFromUserKind(uk))
+}
+
+// UserKindFromProto converts a UserKind message value to the datastore
+// UserKind. It returns an error if the value is not a valid UserKind.
+func UserKindFromProto(uk UserKind) (datastore.UserKind, error) {
+	return datastore.UserKindFromInt(int(uk))
+}
+
+// ===== Projects and repos =====
+
+// ProtoFromProject converts a datastore Project to a message.
+func ProtoFromProject(p *datastore.Project) *Project {
+	if p == nil {
+		return nil
+	}
+	return &Project{Id: uint32(p.ID), Name: p.Name, Fullname: p.Fullname}
+}
+
+// ProjectFromProto converts a Project message to a datastore Project.
+func ProjectFromProto(m *Project) *datastore.Project {
+	if m == nil {
+		return nil
+	}
+	return &datastore.Project{ID: datastore.ProjectID(m.GetId()), Name: m.GetName(), Fullname: m.GetFullname()}
+}
+
+// ProtoFromSubproject converts a datastore Subproject to a message.
+func ProtoFromSubproject(sp *datastore.Subproject) *Subproject {
+	if sp == nil {
+		return nil
+	}
+	return &Subproject{Id: sp.ID, ProjectId: uint32(sp.ProjectID), Name: sp.Name, Fullname: sp.Fullname}
+}
+
+// SubprojectFromProto converts a Subproject message to a datastore
+// Subproject.
+func SubprojectFromProto(m *Subproject) *datastore.Subproject {
+	if m == nil {
+		return nil
+	}
+	return &datastore.Subproject{ID: m.GetId(), ProjectID: datastore.ProjectID(m.GetProjectId()), Name: m.GetName(), Fullname: m.GetFullname()}
+}
+
+// ProtoFromRepo converts a datastore Repo to a message.
+func ProtoFromRepo(r *datastore.Repo) *Repo {
+	if r == nil {
+		return nil
+	}
+	return &Repo{Id: uint32(r.ID), SubprojectId: r.SubprojectID, Name: r.Name, Address: r.Address}
+}
+
+// RepoFromProto converts a Repo message to a datastore Repo.
+func RepoFromProto(m *Repo) *datastore.Repo {
+	if m == nil {
+		return nil
+	}
+	return &datastore.Repo{ID: datastore.RepoID(m.GetId()), SubprojectID: m.GetSubprojectId(), Name: m.GetName(), Address: m.GetAddress()}
+}
+
+// ProtoFromRepoBranch converts a datastore RepoBranch to a message.
+func ProtoFromRepoBranch(rb *datastore.RepoBranch) *RepoBranch {
+	if rb == nil {
+		return nil
+	}
+	return &RepoBranch{RepoId: uint32(rb.RepoID), Branch: rb.Branch}
+}
+
+// RepoBranchFromProto converts a RepoBranch message to a datastore
+// RepoBranch.
+func RepoBranchFromProto(m *RepoBranch) *datastore.RepoBranch {
+	if m == nil {
+		return nil
+	}
+	return &datastore.RepoBranch{RepoID: datastore.RepoID(m.GetRepoId()), Branch: m.GetBranch()}
+}
+
+// ProtoFromRepoPull converts a datastore RepoPull to a message.
+func ProtoFromRepoPull(rp *datastore.RepoPull) *RepoPull {
+	if rp == nil {
+		return nil
+	}
+	return &RepoPull{
+		Id:         uint32(rp.ID),
+		RepoId:     uint32(rp.RepoID),
+		Branch:     rp.Branch,
+		StartedAt:  protoFromTime(rp.StartedAt),
+		FinishedAt: protoFromTime(rp.FinishedAt),
+		Status:     ProtoFromStatus(rp.Status),
+		Health:     ProtoFromHealth(rp.Health),
+		Output:     rp.Output,
+		Commit:     rp.Commit,
+		Tag:        rp.Tag,
+		SpdxId:     rp.SPDXID,
+	}
+}
+
+// RepoPullFromProto converts a RepoPull message to a datastore
+// RepoPull. It returns an error if the message's status or health is
+// invalid.
+func RepoPullFromProto(m *RepoPull) (*datastore.RepoPull, error) {
+	if m == nil {
+		return nil, nil
+	}
+	status, err := StatusFromProto(m.GetStatus())
+	if err != nil {
+		return nil, err
+	}
+	health, err := HealthFromProto(m.GetHealth())
+	if err != nil {
+		return nil, err
+	}
+	return &datastore.RepoPull{
+		ID:         datastore.RepoPullID(m.GetId()),
+		RepoID:     datastore.RepoID(m.GetRepoId()),
+		Branch:     m.GetBranch(),
+		StartedAt:  timeFromProto(m.GetStartedAt()),
+		FinishedAt: timeFromProto(m.GetFinishedAt()),
+		Status:     status,
+		Health:     health,
+		Output:     m.GetOutput(),
+		Commit:     m.GetCommit(),
+		Tag:        m.GetTag(),
+		SPDXID:     m.GetSpdxId(),
+	}, nil
+}
+
+// ===== Files =====
+
+// ProtoFromFileHash converts a datastore FileHash to a message.
+func ProtoFromFileHash(fh *datastore.FileHash) *FileHash {
+	if fh == nil {
+		return nil
+	}
+	return &FileHash{Id: fh.ID, Sha256: fh.HashSHA256, Sha1: fh.HashSHA1}
+}
+
+// FileHashFromProto converts a FileHash message to a datastore
+// FileHash.
+func FileHashFromProto(m *FileHash) *datastore.FileHash {
+	if m == nil {
+		return nil
+	}
+	return &datastore.FileHash{ID: m.GetId(), HashSHA256: m.GetSha256(), HashSHA1: m.GetSha1()}
+}
+
+// ProtoFromFileInstance converts a datastore FileInstance to a
+// message.
+func ProtoFromFileInstance(fi *datastore.FileInstance) *FileInstance {
+	if fi == nil {
+		return nil
+	}
+	return &FileInstance{Id: fi.ID, RepopullId: uint32(fi.RepoPullID), FilehashId: fi.FileHashID, Path: fi.Path}
+}
+
+// FileInstanceFromProto converts a FileInstance message to a
+// datastore FileInstance.
+func FileInstanceFromProto(m *FileInstance) *datastore.FileInstance {
+	if m == nil {
+		return nil
+	}
+	return &datastore.FileInstance{ID: m.GetId(), RepoPullID: datastore.RepoPullID(m.GetRepopullId()), FileHashID: m.GetFilehashId(), Path: m.GetPath()}
+}
+
+// ===== Agents and jobs =====
+
+// ProtoFromAgent converts a datastore Agent to a message.
+func ProtoFromAgent(a *datastore.Agent) *Agent {
+	if a == nil {
+		return nil
+	}
+	return &Agent{
+		Id:           uint32(a.ID),
+		Name:         a.Name,
+		IsActive:     a.IsActive,
+		Address:      a.Address,
+		Port:         int32(a.Port),
+		IsCodereader: a.IsCodeReader,
+		IsSpdxreader: a.IsSpdxReader,
+		IsCodewriter: a.IsCodeWriter,
+		IsSpdxwriter: a.IsSpdxWriter,
+		Health:       ProtoFromAgentHealth(a.Health),
+	}
+}
+
+// AgentFromProto converts an Agent message to a datastore Agent. It
+// returns an error if the message's health is invalid.
+func AgentFromProto(m *Agent) (*datastore.Agent, error) {
+	if m == nil {
+		return nil, nil
+	}
+	health, err := AgentHealthFromProto(m.GetHealth())
+	if err != nil {
+		return nil, err
+	}
+	return &datastore.Agent{
+		ID:           datastore.AgentID(m.GetId()),
+		Name:         m.GetName(),
+		IsActive:     m.GetIsActive(),
+		Address:      m.GetAddress(),
+		Port:         int(m.GetPort()),
+		IsCodeReader: m.GetIsCodereader(),
+		IsSpdxReader: m.GetIsSpdxreader(),
+		IsCodeWriter: m.GetIsCodewriter(),
+		IsSpdxWriter: m.GetIsSpdxwriter(),
+		Health:       health,
+	}, nil
+}
+
+// protoFromPathConfigs converts a map of datastore JobPathConfigs to
+// messages, returning nil for an empty map.
+func protoFromPathConfigs(pcs map[string]datastore.JobPathConfig) map[string]*JobPathConfig {
+	if len(pcs) == 0 {
+		return nil
+	}
+	m := map[string]*JobPathConfig{}
+	for k, pc := range pcs {
+		if pc.PriorJobID > 0 {
+			m[k] = &JobPathConfig{Source: &JobPathConfig_PriorjobId{PriorjobId: uint32(pc.PriorJobID)}}
+		} else {
+			m[k] = &JobPathConfig{Source: &JobPathConfig_Path{Path: pc.Value}}
+		}
+	}
+	return m
+}
+
+// pathConfigsFromProto converts a map of JobPathConfig messages to
+// datastore JobPathConfigs.
+func pathConfigsFromProto(m map[string]*JobPathConfig) map[string]datastore.JobPathConfig {
+	pcs := map[string]datastore.JobPathConfig{}
+	for k, pc := range m {
+		pcs[k] = datastore.JobPathConfig{Value: pc.GetPath(), PriorJobID: datastore.JobID(pc.GetPriorjobId())}
+	}
+	return pcs
+}
+
+// ProtoFromJob converts a datastore Job to a message.
+func ProtoFromJob(j *datastore.Job) *Job {
+	if j == nil {
+		return nil
+	}
+	return &Job{
+		Id:          uint32(j.ID),
+		RepopullId:  uint32(j.RepoPullID),
+		AgentId:     uint32(j.AgentID),
+		PriorjobIds: datastore.Uint32sFromJobIDs(j.PriorJobIDs),
+		StartedAt:   protoFromTime(j.StartedAt),
+		FinishedAt:  protoFromTime(j.FinishedAt),
+		Status:      ProtoFromStatus(j.Status),
+		Health:      ProtoFromHealth(j.Health),
+		Output:      j.Output,
+		IsReady:     j.IsReady,
+		Config: &JobConfig{
+			Kv:         j.Config.KV,
+			Codereader: protoFromPathConfigs(j.Config.CodeReader),
+			Spdxreader: protoFromPathConfigs(j.Config.SpdxReader),
+		},
+	}
+}
+
+// JobFromProto converts a Job message to a datastore Job. Its config
+// maps are always non-nil, matching jobs read from the database. It
+// returns an error if the message's status or health is invalid.
+func JobFromProto(m *Job) (*datastore.Job, error) {
+	if m == nil {
+		return nil, nil
+	}
+	status, err := StatusFromProto(m.GetStatus())
+	if err != nil {
+		return nil, err
+	}
+	health, err := HealthFromProto(m.GetHealth())
+	if err != nil {
+		return nil, err
+	}
+
+	kv := map[string]string{}
+	for k, v := range m.GetConfig().GetKv() {
+		kv[k] = v
+	}
+
+	return &datastore.Job{
+		ID:          datastore.JobID(m.GetId()),
+		RepoPullID:  datastore.RepoPullID(m.GetRepopullId()),
+		AgentID:     datastore.AgentID(m.GetAgentId()),
+		PriorJobIDs: datastore.JobIDsFromUint32s(m.GetPriorjobIds()),
+		StartedAt:   timeFromProto(m.GetStartedAt()),
+		FinishedAt:  timeFromProto(m.GetFinishedAt()),
+		Status:      status,
+		Health:      health,
+		Output:      m.GetOutput(),
+		IsReady:     m.GetIsReady(),
+		Config: datastore.JobConfig{
+			KV:         kv,
+			CodeReader: pathConfigsFromProto(m.GetConfig().GetCodereader()),
+			SpdxReader: pathConfigsFromProto(m.GetConfig().GetSpdxreader()),
+		},
+	}, nil
+}
+
+// ===== Users =====
+
+// ProtoFromUser converts a datastore User to a message.
+func ProtoFromUser(u *datastore.User) *User {
+	if u == nil {
+		return nil
+	}
+	return &User{
+		Id:           uint32(u.ID),
+		Name:         u.Name,
+		Github:       u.Github,
+		Email:        u.Email,
+		Access:       ProtoFromUserAccessLevel(u.AccessLevel),
+		Kind:         ProtoFromUserKind(u.Kind),
+		LastLoginAt:  protoFromTime(u.LastLoginAt),
+		LoginCount:   u.LoginCount,
+		AvatarUrl:    u.AvatarURL,
+		Pronouns:     u.Pronouns,
+		Title:        u.Title,
+		Organization: u.Organization,
+	}
+}
+
+// UserFromProto converts a User message to a datastore User. It
+// returns an error if the message's access level or kind is invalid.
+func UserFromProto(m *User) (*datastore.User, error) {
+	if m == nil {
+		return nil, nil
+	}
+	accessLevel, err := UserAccessLevelFromProto(m.GetAccess())
+	if err != nil {
+		return nil, err
+	}
+	kind, err := UserKindFromProto(m.GetKind())
+	if err != nil {
+		return nil, err
+	}
+	return &datastore.User{
+		ID:           datastore.UserID(m.GetId()),
+		Name:         m.GetName(),
+		Github:       m.GetGithub(),
+		Email:        m.GetEmail(),
+		AccessLevel:  accessLevel,
+		Kind:         kind,
+		LastLoginAt:  timeFromProto(m.GetLastLoginAt()),
+		LoginCount:   m.GetLoginCount(),
+		AvatarURL:    m.GetAvatarUrl(),
+		Pronouns:     m.GetPronouns(),
+		Title:        m.GetTitle(),
+		Organization: m.GetOrganization(),
+	}, nil
+}
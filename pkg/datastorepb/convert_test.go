@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package datastorepb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/swinslow/peridot-db/pkg/datastore"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestCanRoundTripJob(t *testing.T) {
+	j := &datastore.Job{
+		ID:          4,
+		RepoPullID:  14,
+		AgentID:     6,
+		PriorJobIDs: []datastore.JobID{1, 2},
+		StartedAt:   time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC),
+		Status:      datastore.StatusRunning,
+		Health:      datastore.HealthDegraded,
+		Output:      "scanning",
+		IsReady:     true,
+		Config: datastore.JobConfig{
+			KV: map[string]string{"hello": "world"},
+			CodeReader: map[string]datastore.JobPathConfig{
+				"primary": datastore.JobPathConfig{PriorJobID: 1},
+			},
+			SpdxReader: map[string]datastore.JobPathConfig{
+				"historical": datastore.JobPathConfig{Value: "/spdx/prior/lastbest.spdx"},
+			},
+		},
+	}
+
+	// round trip through the wire encoding too
+	b, err := proto.Marshal(ProtoFromJob(j))
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	m := &Job{}
+	err = proto.Unmarshal(b, m)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if m.GetFinishedAt() != nil {
+		t.Errorf("expected nil finished_at, got %v", m.GetFinishedAt())
+	}
+
+	got, err := JobFromProto(m)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !reflect.DeepEqual(j, got) {
+		t.Errorf("expected %#v, got %#v", j, got)
+	}
+}
+
+func TestCanRoundTripUser(t *testing.T) {
+	u := &datastore.User{
+		ID:          192304,
+		Name:        "John Doe",
+		Github:      "johndoe",
+		Email:       "johndoe@example.com",
+		AccessLevel: datastore.AccessOperator,
+		Kind:        datastore.UserKindHuman,
+		LastLoginAt: time.Date(2019, 5, 2, 13, 53, 41, 0, time.UTC),
+		LoginCount:  3,
+	}
+
+	got, err := UserFromProto(ProtoFromUser(u))
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !reflect.DeepEqual(u, got) {
+		t.Errorf("expected %#v, got %#v", u, got)
+	}
+}
+
+func TestShouldFailToConvertInvalidEnumsFromProto(t *testing.T) {
+	_, err := RepoPullFromProto(&RepoPull{Status: Status(57)})
+	if err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+
+	_, err = UserFromProto(&User{Access: UserAccessLevel(6)})
+	if err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+
+	_, err = AgentFromProto(&Agent{Health: AgentHealth(57)})
+	if err == nil {
+		t.Errorf("expected non-nil error, got nil")
+	}
+}
+
+func TestCanConvertNilMessages(t *testing.T) {
+	if ProtoFromRepo(nil) != nil {
+		t.Errorf("expected nil message")
+	}
+	got, err := JobFromProto(nil)
+	if got != nil || err != nil {
+		t.Errorf("expected nil, nil; got %v, %v", got, err)
+	}
+}
@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+// Package jsontag reads encoding/json struct tags, for code that
+// needs to know how a struct is encoded without encoding it.
+package jsontag
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Parse returns the JSON field name for a struct field and whether
+// its tag includes omitempty. The name is "-" if the field is never
+// encoded.
+func Parse(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "-", false
+	}
+
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = f.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			return name, true
+		}
+	}
+	return name, false
+}
@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package jsontag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCanParseJSONTags(t *testing.T) {
+	type tagged struct {
+		ID       uint32 `json:"id"`
+		Note     string `json:"note,omitempty"`
+		Secret   string `json:"-"`
+		Dash     string `json:"-,"`
+		Untagged string
+		Renamed  string `json:",omitempty"`
+	}
+	tests := []struct {
+		field     string
+		name      string
+		omitEmpty bool
+	}{
+		{"ID", "id", false},
+		{"Note", "note", true},
+		{"Secret", "-", false},
+		{"Dash", "-", false},
+		{"Untagged", "Untagged", false},
+		{"Renamed", "Renamed", true},
+	}
+
+	typ := reflect.TypeOf(tagged{})
+	for _, tt := range tests {
+		f, _ := typ.FieldByName(tt.field)
+		name, omitEmpty := Parse(f)
+		if name != tt.name || omitEmpty != tt.omitEmpty {
+			t.Errorf("%s: expected %q, %v, got %q, %v", tt.field, tt.name, tt.omitEmpty, name, omitEmpty)
+		}
+	}
+}
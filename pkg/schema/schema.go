@@ -0,0 +1,276 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+// Package schema generates JSON Schema documents describing the JSON
+// encoding of the peridot datastore entity types. The schemas are
+// derived from the Go structs in package datastore, so that API
+// consumers can validate payloads against the authoritative model.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/swinslow/peridot-db/pkg/datastore"
+	"github.com/swinslow/peridot-db/pkg/internal/jsontag"
+)
+
+// Draft is the JSON Schema dialect used for generated documents.
+const Draft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is a JSON Schema document, ready to be marshalled to JSON.
+type Schema map[string]interface{}
+
+// entities lists the entity types for which All generates schemas,
+// keyed by the name used for each document.
+var entities = map[string]interface{}{
+	"agent":              datastore.Agent{},
+	"agenthealthevent":   datastore.AgentHealthEvent{},
+	"attestation":        datastore.Attestation{},
+	"auditentry":         datastore.AuditEntry{},
+	"comment":            datastore.Comment{},
+	"component":          datastore.Component{},
+	"conclusion":         datastore.Conclusion{},
+	"copyright":          datastore.Copyright{},
+	"filehash":           datastore.FileHash{},
+	"fileinstance":       datastore.FileInstance{},
+	"finding":            datastore.Finding{},
+	"findingoverride":    datastore.FindingOverride{},
+	"invitation":         datastore.Invitation{},
+	"issuelink":          datastore.IssueLink{},
+	"job":                datastore.Job{},
+	"license":            datastore.License{},
+	"metricssnapshot":    datastore.MetricsSnapshot{},
+	"noticedocument":     datastore.NoticeDocument{},
+	"notification":       datastore.Notification{},
+	"obligation":         datastore.Obligation{},
+	"organization":       datastore.Organization{},
+	"organizationmember": datastore.OrganizationMember{},
+	"outboxevent":        datastore.OutboxEvent{},
+	"policy":             datastore.Policy{},
+	"policyresult":       datastore.PolicyResult{},
+	"project":            datastore.Project{},
+	"projectaccess":      datastore.ProjectAccess{},
+	"quota":              datastore.Quota{},
+	"relationship":       datastore.Relationship{},
+	"repo":               datastore.Repo{},
+	"repobranch":         datastore.RepoBranch{},
+	"repopull":           datastore.RepoPull{},
+	"report":             datastore.Report{},
+	"retentionpolicy":    datastore.RetentionPolicy{},
+	"review":             datastore.Review{},
+	"sbomimport":         datastore.SBOMImport{},
+	"sbomimportlink":     datastore.SBOMImportLink{},
+	"scandelta":          datastore.ScanDelta{},
+	"snippetmatch":       datastore.SnippetMatch{},
+	"subproject":         datastore.Subproject{},
+	"user":               datastore.User{},
+	"useridentity":       datastore.UserIdentity{},
+	"usertoken":          datastore.UserToken{},
+	"webhook":            datastore.Webhook{},
+	"webhookdelivery":    datastore.WebhookDelivery{},
+}
+
+// enums maps each enum type that marshals to a string onto its valid
+// string values.
+var enums = map[reflect.Type][]string{
+	reflect.TypeOf(datastore.StatusSame): {
+		datastore.StringFromStatus(datastore.StatusSame),
+		datastore.StringFromStatus(datastore.StatusStartup),
+		datastore.StringFromStatus(datastore.StatusRunning),
+		datastore.StringFromStatus(datastore.StatusStopped),
+		datastore.StringFromStatus(datastore.StatusQueued),
+		datastore.StringFromStatus(datastore.StatusCancelled),
+	},
+	reflect.TypeOf(datastore.HealthSame): {
+		datastore.StringFromHealth(datastore.HealthSame),
+		datastore.StringFromHealth(datastore.HealthOK),
+		datastore.StringFromHealth(datastore.HealthDegraded),
+		datastore.StringFromHealth(datastore.HealthError),
+		datastore.StringFromHealth(datastore.HealthUnknown),
+	},
+	reflect.TypeOf(datastore.AccessDisabled): {
+		datastore.StringFromUserAccessLevel(datastore.AccessDisabled),
+		datastore.StringFromUserAccessLevel(datastore.AccessViewer),
+		datastore.StringFromUserAccessLevel(datastore.AccessCommenter),
+		datastore.StringFromUserAccessLevel(datastore.AccessOperator),
+		datastore.StringFromUserAccessLevel(datastore.AccessAdmin),
+	},
+	reflect.TypeOf(datastore.UserKindHuman): {
+		datastore.StringFromUserKind(datastore.UserKindHuman),
+		datastore.StringFromUserKind(datastore.UserKindService),
+	},
+	reflect.TypeOf(datastore.AgentHealthUnknown): {
+		datastore.StringFromAgentHealth(datastore.AgentHealthUnknown),
+		datastore.StringFromAgentHealth(datastore.AgentHealthOK),
+		datastore.StringFromAgentHealth(datastore.AgentHealthDegraded),
+		datastore.StringFromAgentHealth(datastore.AgentHealthUnreachable),
+	},
+	reflect.TypeOf(datastore.SPDXElementTypeUnknown): {
+		datastore.StringFromSPDXElementType(datastore.SPDXElementTypeUnknown),
+		datastore.StringFromSPDXElementType(datastore.SPDXElementTypeRepoPull),
+		datastore.StringFromSPDXElementType(datastore.SPDXElementTypeComponent),
+		datastore.StringFromSPDXElementType(datastore.SPDXElementTypeFile),
+	},
+	reflect.TypeOf(datastore.RelationshipTypeUnknown): {
+		datastore.StringFromRelationshipType(datastore.RelationshipTypeUnknown),
+		datastore.StringFromRelationshipType(datastore.RelationshipTypeDependsOn),
+		datastore.StringFromRelationshipType(datastore.RelationshipTypeContains),
+	},
+	reflect.TypeOf(datastore.ReviewStateUnknown): {
+		datastore.StringFromReviewState(datastore.ReviewStateUnknown),
+		datastore.StringFromReviewState(datastore.ReviewStateRequested),
+		datastore.StringFromReviewState(datastore.ReviewStateApproved),
+		datastore.StringFromReviewState(datastore.ReviewStateRejected),
+	},
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	marshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Names returns the names of the entities for which All generates
+// schemas, in sorted order.
+func Names() []string {
+	names := make([]string, 0, len(entities))
+	for name := range entities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// All returns a JSON Schema document for each datastore entity type,
+// keyed by the names returned by Names.
+func All() (map[string]Schema, error) {
+	schemas := map[string]Schema{}
+	for name, v := range entities {
+		s, err := For(v)
+		if err != nil {
+			return nil, fmt.Errorf("generating schema for %s: %v", name, err)
+		}
+		schemas[name] = s
+	}
+	return schemas, nil
+}
+
+// For returns a JSON Schema document describing the JSON encoding of
+// v, which must be a struct or a pointer to one. It returns an error
+// if v's type, or the type of any of its fields, cannot be described.
+func For(v interface{}) (Schema, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot generate schema for non-struct type %v", reflect.TypeOf(v))
+	}
+
+	s, err := schemaForType(t)
+	if err != nil {
+		return nil, err
+	}
+	s["$schema"] = Draft
+	s["title"] = t.Name()
+	return s, nil
+}
+
+// schemaForType returns the schema for a single Go type.
+func schemaForType(t reflect.Type) (Schema, error) {
+	if values, ok := enums[t]; ok {
+		return Schema{"type": "string", "enum": values}, nil
+	}
+
+	switch t {
+	case timeType:
+		return Schema{"type": "string", "format": "date-time"}, nil
+	case rawMessageType:
+		// arbitrary JSON
+		return Schema{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return Schema{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if t.Implements(marshalerType) {
+			// an enum that marshals to a string, but is missing
+			// from enums
+			return nil, fmt.Errorf("no enum values listed for %v", t)
+		}
+		return Schema{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}, nil
+	case reflect.String:
+		return Schema{"type": "string"}, nil
+	case reflect.Ptr:
+		return schemaForType(t.Elem())
+	case reflect.Interface:
+		return Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return Schema{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %v", t.Key())
+		}
+		values, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return Schema{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return schemaForStruct(t)
+	}
+
+	return nil, fmt.Errorf("unsupported type %v", t)
+}
+
+// schemaForStruct returns the schema for a struct type, with one
+// property per JSON-encoded field. Fields without omitempty in their
+// json tag are listed as required.
+func schemaForStruct(t reflect.Type) (Schema, error) {
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		name, omitEmpty := jsontag.Parse(f)
+		if name == "-" {
+			continue
+		}
+
+		fs, err := schemaForType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", f.Name, err)
+		}
+		properties[name] = fs
+		if !omitEmpty {
+			required = append(required, name)
+		}
+	}
+
+	s := Schema{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s, nil
+}
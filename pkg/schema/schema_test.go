@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Apache-2.0 OR GPL-2.0-or-later
+
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/swinslow/peridot-db/pkg/datastore"
+)
+
+func TestCanGenerateAllSchemas(t *testing.T) {
+	schemas, err := All()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(schemas) != len(Names()) {
+		t.Errorf("expected %v schemas, got %v", len(Names()), len(schemas))
+	}
+
+	// every schema should be marshallable to JSON
+	for name, s := range schemas {
+		if _, err := json.Marshal(s); err != nil {
+			t.Errorf("for %s: expected nil error, got %v", name, err)
+		}
+	}
+}
+
+func TestCanGenerateJobSchema(t *testing.T) {
+	s, err := For(datastore.Job{})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if s["$schema"] != Draft {
+		t.Errorf("expected %v, got %v", Draft, s["$schema"])
+	}
+	if s["title"] != "Job" {
+		t.Errorf("expected %v, got %v", "Job", s["title"])
+	}
+
+	props := s["properties"].(map[string]interface{})
+	if got := props["repopull_id"].(Schema)["type"]; got != "integer" {
+		t.Errorf("expected %v, got %v", "integer", got)
+	}
+	if got := props["started_at"].(Schema)["format"]; got != "date-time" {
+		t.Errorf("expected %v, got %v", "date-time", got)
+	}
+	if got := props["priorjob_ids"].(Schema)["type"]; got != "array" {
+		t.Errorf("expected %v, got %v", "array", got)
+	}
+
+	wantStatus := []string{"same", "startup", "running", "stopped", "queued", "cancelled"}
+	if got := props["status"].(Schema)["enum"]; !reflect.DeepEqual(wantStatus, got) {
+		t.Errorf("expected %v, got %v", wantStatus, got)
+	}
+
+	// nested path configs should be described as objects
+	config := props["config"].(Schema)
+	codeReader := config["properties"].(map[string]interface{})["codereader"].(Schema)
+	pathConfig := codeReader["additionalProperties"].(Schema)
+	if _, ok := pathConfig["properties"].(map[string]interface{})["priorjob_id"]; !ok {
+		t.Errorf("expected priorjob_id property, got none")
+	}
+
+	// omitempty fields should not be required
+	wantRequired := []string{"id", "repopull_id", "agent_id", "status", "health", "is_ready"}
+	if got := s["required"]; !reflect.DeepEqual(wantRequired, got) {
+		t.Errorf("expected %v, got %v", wantRequired, got)
+	}
+}
+
+func TestReviewSchemaListsStateValuesThatRoundTrip(t *testing.T) {
+	s, err := For(datastore.Review{})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	state := s["properties"].(map[string]interface{})["state"].(Schema)
+	if state["type"] != "string" {
+		t.Errorf("expected %v, got %v", "string", state["type"])
+	}
+
+	// each listed value should unmarshal to a ReviewState that
+	// marshals back to the same value
+	values := state["enum"].([]string)
+	if len(values) != 4 {
+		t.Fatalf("expected %d values, got %v", 4, values)
+	}
+	for _, v := range values {
+		var rs datastore.ReviewState
+		if err := json.Unmarshal([]byte(`"`+v+`"`), &rs); err != nil {
+			t.Errorf("for %s: expected nil error, got %v", v, err)
+			continue
+		}
+		b, err := json.Marshal(rs)
+		if err != nil || string(b) != `"`+v+`"` {
+			t.Errorf("for %s: expected %q, got %s (%v)", v, `"`+v+`"`, b, err)
+		}
+	}
+}
+
+func TestShouldFailToGenerateSchemaForUnlistedEnum(t *testing.T) {
+	_, err := For(struct {
+		Type datastore.JobConfigType `json:"type"`
+	}{})
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+}
+
+func TestShouldFailToGenerateSchemaForNonStruct(t *testing.T) {
+	_, err := For(17)
+	if err == nil {
+		t.Fatalf("expected non-nil error, got nil")
+	}
+}